@@ -18,6 +18,14 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Run schema migrations instead of the server when asked to
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := app.Migrate(context.Background(), cfg, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	// Create application
 	application, err := app.New(cfg)
 	if err != nil {
@@ -2,7 +2,10 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/streamforge/distributed-tracing-system/internal/config"
 	"github.com/streamforge/distributed-tracing-system/internal/domain"
@@ -14,9 +17,21 @@ import (
 
 // App represents the application
 type App struct {
-	config *config.Config
-	server *interfaces.ServerWithTelemetry
-	logger domain.Logger
+	config        *config.Config
+	server        *interfaces.ServerWithTelemetry
+	otlpServer    *interfaces.OTLPGRPCServer
+	traceService  domain.TraceService
+	traceRepo     domain.TraceRepository
+	kafkaConsumer domain.KafkaConsumer
+	assembler     domain.SpanAssembler
+	spanConsumer  domain.KafkaSpanConsumer
+	retention     domain.RetentionManager
+	writeQueue    domain.TraceWriteQueue
+	exportFanout  domain.ExportFanout
+	dependencies  domain.DependencyService
+	slos          domain.SLOService
+	anomalies     domain.AnomalyDetector
+	logger        domain.Logger
 }
 
 // New creates a new application instance
@@ -51,16 +66,25 @@ func New(cfg *config.Config) (*App, error) {
 
 	logger.Info("Telemetry manager initialized successfully")
 
-	// Initialize Jaeger exporter with complete OpenTelemetry SDK
-	jaegerExporter, err := infrastructure.NewJaegerExporterComplete(telemetryManager)
-	if err != nil {
-		logger.Error("Failed to create Jaeger exporter", domain.NewField("error", err.Error()))
-		return nil, fmt.Errorf("failed to create Jaeger exporter: %w", err)
+	exporterOptions := []infrastructure.PrometheusExporterOption{
+		infrastructure.WithErrorRateWindow(cfg.Prometheus.ErrorRateWindow),
+	}
+	if cfg.SpanMetrics.Enabled {
+		spanMetrics := infrastructure.SpanMetricsConfig{
+			MaxServices:   cfg.SpanMetrics.MaxServices,
+			MaxOperations: cfg.SpanMetrics.MaxOperations,
+		}
+		if cfg.SpanMetrics.Buckets != "" {
+			spanMetrics.Buckets, err = infrastructure.ParseHistogramBuckets(cfg.SpanMetrics.Buckets)
+			if err != nil {
+				logger.Error("Invalid span metrics buckets", domain.NewField("error", err.Error()))
+				return nil, fmt.Errorf("invalid span metrics buckets: %w", err)
+			}
+		}
+		exporterOptions = append(exporterOptions, infrastructure.WithSpanMetrics(spanMetrics))
 	}
 
-	logger.Info("Jaeger exporter initialized successfully")
-
-	prometheusExporter, err := infrastructure.NewPrometheusExporter(cfg.Prometheus.Port, cfg.Prometheus.Path)
+	prometheusExporter, err := infrastructure.NewPrometheusExporter(cfg.Prometheus.Port, cfg.Prometheus.Path, exporterOptions...)
 	if err != nil {
 		logger.Error("Failed to create Prometheus exporter", domain.NewField("error", err.Error()))
 		return nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
@@ -68,6 +92,21 @@ func New(cfg *config.Config) (*App, error) {
 
 	logger.Info("Prometheus exporter initialized successfully")
 
+	// Initialize the fan-out exporting stored traces to every backend
+	backends, err := exportBackends(cfg)
+	if err != nil {
+		logger.Error("Failed to create export backends", domain.NewField("error", err.Error()))
+		return nil, fmt.Errorf("failed to create export backends: %w", err)
+	}
+
+	exportFanout, err := usecases.NewExportFanout(backends, prometheusExporter)
+	if err != nil {
+		logger.Error("Failed to create export fan-out", domain.NewField("error", err.Error()))
+		return nil, fmt.Errorf("failed to create export fan-out: %w", err)
+	}
+
+	logger.Info("Export fan-out initialized successfully", domain.NewField("backends", len(backends)))
+
 	kafkaProducer, err := infrastructure.NewKafkaProducer(cfg.Kafka.Brokers, cfg.Kafka.TopicTraces)
 	if err != nil {
 		logger.Error("Failed to create Kafka producer", domain.NewField("error", err.Error()))
@@ -76,7 +115,10 @@ func New(cfg *config.Config) (*App, error) {
 
 	logger.Info("Kafka producer initialized successfully")
 
-	kafkaConsumer, err := infrastructure.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.TopicTraces, cfg.Kafka.GroupID)
+	kafkaConsumer, err := infrastructure.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.TopicTraces, cfg.Kafka.GroupID,
+		infrastructure.WithConsumerConcurrency(cfg.Kafka.ConsumerConcurrency),
+		infrastructure.WithBackpressureBackoff(cfg.Kafka.BackpressureBackoff, cfg.Kafka.MaxBackpressureBackoff),
+	)
 	if err != nil {
 		logger.Error("Failed to create Kafka consumer", domain.NewField("error", err.Error()))
 		return nil, fmt.Errorf("failed to create Kafka consumer: %w", err)
@@ -84,20 +126,276 @@ func New(cfg *config.Config) (*App, error) {
 
 	logger.Info("Kafka consumer initialized successfully")
 
+	var retentionPolicy domain.RetentionPolicy
+	if cfg.Retention.Enabled {
+		defaultMaxAge, err := usecases.ParseRetentionAge(cfg.Retention.DefaultMaxAge)
+		if err != nil {
+			logger.Error("Invalid default retention", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("invalid default retention: %w", err)
+		}
+
+		rules, err := usecases.ParseRetentionRules(cfg.Retention.Rules)
+		if err != nil {
+			logger.Error("Invalid retention rules", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("invalid retention rules: %w", err)
+		}
+		retentionPolicy = domain.RetentionPolicy{DefaultMaxAge: defaultMaxAge, Rules: rules}
+	}
+
+	// Dropping partitions must not delete traces the retention policy keeps
+	if cfg.Storage.Backend == config.StorageBackendPostgres && cfg.Retention.Enabled && cfg.Database.PartitionRetention > 0 {
+		if longest := retentionPolicy.LongestMaxAge(); longest == 0 || cfg.Database.PartitionRetention < longest {
+			logger.Error("Partition retention is shorter than the retention policy",
+				domain.NewField("partition_retention", cfg.Database.PartitionRetention.String()))
+			return nil, fmt.Errorf("DB_PARTITION_RETENTION %s is shorter than the longest retention age", cfg.Database.PartitionRetention)
+		}
+	}
+
 	// Initialize repositories
-	traceRepo, err := infrastructure.NewTraceRepositoryPostgres(cfg.Database.GetDSN(), jaegerExporter)
+	var traceRepo domain.TraceRepository
+	switch cfg.Storage.Backend {
+	case config.StorageBackendMemory:
+		traceRepo, err = infrastructure.NewTraceRepositoryMemory(infrastructure.MemoryRepositoryConfig{
+			MaxTraces: cfg.Storage.MemoryMaxTraces,
+			MaxBytes:  cfg.Storage.MemoryMaxBytes,
+		}, exportFanout)
+	case config.StorageBackendBolt:
+		traceRepo, err = infrastructure.NewTraceRepositoryBolt(infrastructure.BoltRepositoryConfig{
+			Path:               cfg.Storage.BoltPath,
+			TTL:                cfg.Storage.BoltTTL,
+			ExpiryInterval:     cfg.Storage.BoltExpiryInterval,
+			CompactionInterval: cfg.Storage.BoltCompactionInterval,
+		}, exportFanout)
+	default:
+		traceRepo, err = infrastructure.NewTraceRepositoryPostgres(infrastructure.PostgresRepositoryConfig{
+			DSN:                  cfg.Database.GetDSN(),
+			AutoMigrate:          cfg.Database.AutoMigrate,
+			PartitionPremakeDays: cfg.Database.PartitionPremakeDays,
+			PartitionRetention:   cfg.Database.PartitionRetention,
+			PartitionInterval:    cfg.Database.PartitionInterval,
+		}, exportFanout)
+	}
 	if err != nil {
 		logger.Error("Failed to create trace repository", domain.NewField("error", err.Error()))
 		return nil, fmt.Errorf("failed to create trace repository: %w", err)
 	}
 
-	logger.Info("Trace repository initialized successfully")
+	logger.Info("Trace repository initialized successfully", domain.NewField("backend", cfg.Storage.Backend))
 
 	// Initialize use cases
-	traceService := usecases.NewTraceService(traceRepo, prometheusExporter, kafkaProducer)
+	var serviceOptions []usecases.TraceServiceOption
+	if cfg.Sampling.PolicyFile != "" {
+		policies, err := usecases.LoadSamplingPolicyFile(cfg.Sampling.PolicyFile)
+		if err != nil {
+			logger.Error("Failed to load sampling policies", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to load sampling policies: %w", err)
+		}
+
+		sampler, err := usecases.NewTailSampler(policies, prometheusExporter)
+		if err != nil {
+			logger.Error("Failed to create tail sampler", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create tail sampler: %w", err)
+		}
+		serviceOptions = append(serviceOptions, usecases.WithTailSampler(sampler))
+
+		logger.Info("Tail sampler initialized successfully", domain.NewField("policies", len(policies)))
+	}
+
+	if cfg.ClockSkew.Enabled {
+		adjuster, err := usecases.NewSkewAdjuster(usecases.SkewAdjusterConfig{
+			Services:        usecases.ParseServiceList(cfg.ClockSkew.Services),
+			ExcludeServices: usecases.ParseServiceList(cfg.ClockSkew.ExcludeServices),
+			MaxAdjustment:   cfg.ClockSkew.MaxAdjustment,
+		}, prometheusExporter)
+		if err != nil {
+			logger.Error("Failed to create clock skew adjuster", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create clock skew adjuster: %w", err)
+		}
+		serviceOptions = append(serviceOptions, usecases.WithSkewAdjuster(adjuster))
+
+		logger.Info("Clock skew adjuster initialized successfully")
+	}
+
+	var dependencies domain.DependencyService
+	if cfg.Dependencies.Enabled {
+		store, ok := traceRepo.(domain.DependencyStore)
+		if !ok {
+			logger.Error("Trace repository does not store dependencies", domain.NewField("backend", cfg.Storage.Backend))
+			return nil, fmt.Errorf("trace repository does not store dependencies")
+		}
+
+		dependencies, err = usecases.NewDependencyService(store, usecases.DependencyConfig{
+			Window:        cfg.Dependencies.Window,
+			FlushInterval: cfg.Dependencies.FlushInterval,
+		})
+		if err != nil {
+			logger.Error("Failed to create dependency service", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create dependency service: %w", err)
+		}
+		serviceOptions = append(serviceOptions, usecases.WithDependencyService(dependencies))
+
+		logger.Info("Dependency service initialized successfully", domain.NewField("window", cfg.Dependencies.Window.String()))
+	}
+
+	var slos domain.SLOService
+	if cfg.SLOs.Enabled {
+		store, ok := traceRepo.(domain.SLOStore)
+		if !ok {
+			logger.Error("Trace repository does not store SLOs", domain.NewField("backend", cfg.Storage.Backend))
+			return nil, fmt.Errorf("trace repository does not store SLOs")
+		}
+
+		alertProducer, err := infrastructure.NewKafkaAlertProducer(cfg.Kafka.Brokers, cfg.Kafka.TopicAlerts)
+		if err != nil {
+			logger.Error("Failed to create Kafka alert producer", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create Kafka alert producer: %w", err)
+		}
+		publishers := []domain.AlertPublisher{alertProducer}
+
+		if cfg.SLOs.WebhookURL != "" {
+			webhook, err := infrastructure.NewWebhookAlertPublisher(cfg.SLOs.WebhookURL, cfg.SLOs.WebhookTimeout)
+			if err != nil {
+				logger.Error("Failed to create alert webhook", domain.NewField("error", err.Error()))
+				return nil, fmt.Errorf("failed to create alert webhook: %w", err)
+			}
+			publishers = append(publishers, webhook)
+		}
+
+		slos, err = usecases.NewSLOService(store, publishers, usecases.SLOConfig{
+			EvaluationInterval: cfg.SLOs.EvaluationInterval,
+		})
+		if err != nil {
+			logger.Error("Failed to create SLO service", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create SLO service: %w", err)
+		}
+		serviceOptions = append(serviceOptions, usecases.WithSLOService(slos))
+
+		logger.Info("SLO service initialized successfully", domain.NewField("publishers", len(publishers)))
+	}
+
+	var anomalies domain.AnomalyDetector
+	if cfg.Anomalies.Enabled {
+		anomalyProducer, err := infrastructure.NewKafkaAnomalyProducer(cfg.Kafka.Brokers, cfg.Kafka.TopicAnomalies)
+		if err != nil {
+			logger.Error("Failed to create Kafka anomaly producer", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create Kafka anomaly producer: %w", err)
+		}
+
+		anomalies, err = usecases.NewAnomalyDetector(prometheusExporter, []domain.AnomalyPublisher{anomalyProducer}, usecases.AnomalyConfig{
+			Window:        cfg.Anomalies.Window,
+			Alpha:         cfg.Anomalies.Alpha,
+			Threshold:     cfg.Anomalies.Threshold,
+			MinTraces:     cfg.Anomalies.MinTraces,
+			WarmupWindows: cfg.Anomalies.WarmupWindows,
+			SeasonDays:    cfg.Anomalies.SeasonDays,
+			MaxExamples:   cfg.Anomalies.MaxExamples,
+			MaxSeries:     cfg.Anomalies.MaxSeries,
+			MaxAnomalies:  cfg.Anomalies.MaxAnomalies,
+		})
+		if err != nil {
+			logger.Error("Failed to create anomaly detector", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create anomaly detector: %w", err)
+		}
+		serviceOptions = append(serviceOptions, usecases.WithAnomalyDetector(anomalies))
+
+		logger.Info("Anomaly detector initialized successfully", domain.NewField("window", cfg.Anomalies.Window.String()))
+	}
+
+	var tagCatalog domain.TagCatalog
+	if cfg.TagCatalog.Enabled {
+		tagCatalog, err = usecases.NewTagCatalog(usecases.TagCatalogConfig{
+			MaxTags:         cfg.TagCatalog.MaxTags,
+			MaxValuesPerTag: cfg.TagCatalog.MaxValuesPerTag,
+		})
+		if err != nil {
+			logger.Error("Failed to create tag catalog", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create tag catalog: %w", err)
+		}
+		if err := usecases.LoadTagCatalog(context.Background(), traceRepo, tagCatalog, cfg.TagCatalog.LoadTraces); err != nil {
+			// Tags are still learned from new traces
+			logger.Warn("Failed to load tag catalog", domain.NewField("error", err.Error()))
+		}
+		serviceOptions = append(serviceOptions, usecases.WithTagCatalog(tagCatalog))
+
+		logger.Info("Tag catalog initialized successfully", domain.NewField("tags", len(tagCatalog.TagNames())))
+	}
+
+	var writeQueue domain.TraceWriteQueue
+	if cfg.WriteQueue.Enabled {
+		writeQueue, err = usecases.NewTraceWriteQueue(traceRepo, prometheusExporter, usecases.WriteQueueConfig{
+			Capacity:  cfg.WriteQueue.Capacity,
+			BatchSize: cfg.WriteQueue.BatchSize,
+			Linger:    cfg.WriteQueue.Linger,
+		})
+		if err != nil {
+			logger.Error("Failed to create write queue", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create write queue: %w", err)
+		}
+		serviceOptions = append(serviceOptions, usecases.WithWriteQueue(writeQueue))
+
+		logger.Info("Write queue initialized successfully", domain.NewField("capacity", cfg.WriteQueue.Capacity))
+	}
+
+	traceService := usecases.NewTraceService(traceRepo, prometheusExporter, kafkaProducer, serviceOptions...)
+
+	var assembler domain.SpanAssembler
+	var spanConsumer domain.KafkaSpanConsumer
+	if cfg.Assembler.Enabled {
+		assembler, err = usecases.NewSpanAssembler(traceService, usecases.SpanAssemblerConfig{
+			IdleTimeout:      cfg.Assembler.IdleTimeout,
+			MaxPendingTraces: cfg.Assembler.MaxPendingTraces,
+			CompletedTTL:     cfg.Assembler.CompletedTTL,
+		})
+		if err != nil {
+			logger.Error("Failed to create span assembler", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create span assembler: %w", err)
+		}
+
+		spanConsumer, err = infrastructure.NewKafkaSpanConsumer(cfg.Kafka.Brokers, cfg.Kafka.TopicSpans, cfg.Kafka.GroupID)
+		if err != nil {
+			logger.Error("Failed to create Kafka span consumer", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create Kafka span consumer: %w", err)
+		}
+
+		logger.Info("Span assembler initialized successfully")
+	}
+
+	var retention domain.RetentionManager
+	var serverOptions []interfaces.ServerOption
+	if dependencies != nil {
+		serverOptions = append(serverOptions, interfaces.WithDependencyService(dependencies))
+	}
+	if slos != nil {
+		serverOptions = append(serverOptions, interfaces.WithSLOService(slos))
+	}
+	if anomalies != nil {
+		serverOptions = append(serverOptions, interfaces.WithAnomalyDetector(anomalies))
+	}
+	if tagCatalog != nil {
+		serverOptions = append(serverOptions, interfaces.WithTagCatalog(tagCatalog))
+	}
+	if assembler != nil {
+		serverOptions = append(serverOptions, interfaces.WithSpanAssembler(assembler))
+	}
+	if cfg.Retention.Enabled {
+		retention, err = usecases.NewRetentionManager(traceRepo, prometheusExporter, usecases.RetentionConfig{
+			Policy:            retentionPolicy,
+			Interval:          cfg.Retention.Interval,
+			BatchSize:         cfg.Retention.BatchSize,
+			BatchPause:        cfg.Retention.BatchPause,
+			MaxBatchesPerRule: cfg.Retention.MaxBatchesPerRule,
+		})
+		if err != nil {
+			logger.Error("Failed to create retention manager", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create retention manager: %w", err)
+		}
+		serverOptions = append(serverOptions, interfaces.WithRetentionManager(retention))
+
+		logger.Info("Retention manager initialized successfully", domain.NewField("rules", len(retentionPolicy.Rules)))
+	}
 
 	// Initialize interfaces with telemetry
-	server, err := interfaces.NewServerWithTelemetry(cfg, traceService, telemetryManager)
+	server, err := interfaces.NewServerWithTelemetry(cfg, traceService, telemetryManager, serverOptions...)
 	if err != nil {
 		logger.Error("Failed to create server", domain.NewField("error", err.Error()))
 		return nil, fmt.Errorf("failed to create server: %w", err)
@@ -105,29 +403,118 @@ func New(cfg *config.Config) (*App, error) {
 
 	logger.Info("Server initialized successfully")
 
-	// Start Kafka consumer
-	go func() {
-		if err := kafkaConsumer.Start(context.Background(), traceService); err != nil {
-			logger.Error("Kafka consumer error", domain.NewField("error", err.Error()))
+	var otlpServer *interfaces.OTLPGRPCServer
+	if cfg.OTLP.GRPCEnabled {
+		otlpServer, err = interfaces.NewOTLPGRPCServer(cfg, traceService, assembler)
+		if err != nil {
+			logger.Error("Failed to create OTLP gRPC receiver", domain.NewField("error", err.Error()))
+			return nil, fmt.Errorf("failed to create OTLP gRPC receiver: %w", err)
 		}
-	}()
+
+		logger.Info("OTLP gRPC receiver initialized successfully")
+	}
 
 	logger.Info("Application initialized successfully")
 
 	return &App{
-		config: cfg,
-		server: server,
-		logger: logger,
+		config:        cfg,
+		server:        server,
+		otlpServer:    otlpServer,
+		traceService:  traceService,
+		traceRepo:     traceRepo,
+		kafkaConsumer: kafkaConsumer,
+		assembler:     assembler,
+		spanConsumer:  spanConsumer,
+		retention:     retention,
+		writeQueue:    writeQueue,
+		exportFanout:  exportFanout,
+		dependencies:  dependencies,
+		slos:          slos,
+		anomalies:     anomalies,
+		logger:        logger,
 	}, nil
 }
 
-// Run starts the application
+// Run starts the application and blocks until the context is cancelled.
+// Components are stopped in stages so that each one drains into the next:
+// first the receivers and consumers, then the span assembler with its final
+// flush, then the write queue, and last the fan-out and the services fed by
+// stored traces. The repository is closed once everything has stopped.
 func (a *App) Run(ctx context.Context) error {
 	a.logger.Info("Starting distributed tracing system", 
 		domain.NewField("port", a.config.Server.Port),
 		domain.NewField("environment", "development"),
 	)
-	
-	return a.server.Start(ctx)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	storageCtx, stopStorage := context.WithCancel(context.Background())
+	defer stopStorage()
+	assemblerCtx, stopAssembler := context.WithCancel(context.Background())
+	defer stopAssembler()
+
+	var background, storage, assembly, receivers sync.WaitGroup
+
+	a.start(backgroundCtx, &background, "Export fan-out", a.exportFanout.Start)
+	if a.dependencies != nil {
+		a.start(backgroundCtx, &background, "Dependency service", a.dependencies.Start)
+	}
+	if a.slos != nil {
+		a.start(backgroundCtx, &background, "SLO service", a.slos.Start)
+	}
+	if a.anomalies != nil {
+		a.start(backgroundCtx, &background, "Anomaly detector", a.anomalies.Start)
+	}
+	if a.retention != nil {
+		a.start(backgroundCtx, &background, "Retention worker", a.retention.Start)
+	}
+
+	if a.writeQueue != nil {
+		a.start(storageCtx, &storage, "Write queue", a.writeQueue.Start)
+	}
+
+	if a.assembler != nil {
+		a.start(assemblerCtx, &assembly, "Span assembler", a.assembler.Start)
+		a.start(ctx, &receivers, "Kafka span consumer", func(ctx context.Context) error {
+			return a.spanConsumer.Start(ctx, a.assembler)
+		})
+	}
+
+	a.start(ctx, &receivers, "Kafka consumer", func(ctx context.Context) error {
+		return a.kafkaConsumer.Start(ctx, a.traceService)
+	})
+
+	if a.otlpServer != nil {
+		a.start(ctx, &receivers, "OTLP gRPC receiver", a.otlpServer.Start)
+	}
+
+	err := a.server.Start(ctx)
+
+	receivers.Wait()
+	stopAssembler()
+	assembly.Wait()
+	stopStorage()
+	storage.Wait()
+	stopBackground()
+	background.Wait()
+
+	if closer, ok := a.traceRepo.(io.Closer); ok {
+		if closeErr := closer.Close(); closeErr != nil {
+			a.logger.Error("Failed to close trace repository", domain.NewField("error", closeErr.Error()))
+		}
+	}
+
+	return err
+}
+
+// start runs a component in the background until its context is cancelled
+func (a *App) start(ctx context.Context, wg *sync.WaitGroup, name string, run func(ctx context.Context) error) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			a.logger.Error(name+" error", domain.NewField("error", err.Error()))
+		}
+	}()
 }
 
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/streamforge/distributed-tracing-system/internal/config"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+	"github.com/streamforge/distributed-tracing-system/internal/usecases"
+)
+
+// exportBackends creates the configured export backends. Without any, the
+// traces are forwarded to the Jaeger endpoint alone.
+func exportBackends(cfg *config.Config) ([]usecases.ExportBackend, error) {
+	targets, err := usecases.ParseExportTargets(cfg.Export.Backends)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		targets = []usecases.ExportTarget{{Kind: cfg.Jaeger.Protocol, Target: cfg.Jaeger.Endpoint}}
+	}
+
+	exportConfig := usecases.ExportConfig{
+		QueueSize:        cfg.Export.QueueSize,
+		DropPolicy:       domain.DropPolicy(cfg.Export.DropPolicy),
+		Timeout:          cfg.Export.Timeout,
+		MaxRetries:       cfg.Export.MaxRetries,
+		InitialBackoff:   cfg.Export.InitialBackoff,
+		MaxBackoff:       cfg.Export.MaxBackoff,
+		FailureThreshold: cfg.Export.FailureThreshold,
+		Cooldown:         cfg.Export.Cooldown,
+	}
+
+	backends := make([]usecases.ExportBackend, 0, len(targets))
+	for _, target := range targets {
+		exporter, err := newExporter(cfg, target)
+		if err != nil {
+			return nil, fmt.Errorf("export backend %s: %w", target.Kind, err)
+		}
+		backends = append(backends, usecases.ExportBackend{
+			Name:     target.Kind,
+			Exporter: exporter,
+			Config:   exportConfig,
+		})
+	}
+	return backends, nil
+}
+
+// newExporter creates the exporter of a single backend
+func newExporter(cfg *config.Config, target usecases.ExportTarget) (domain.JaegerExporter, error) {
+	switch target.Kind {
+	case infrastructure.ForwardProtocolJaeger, infrastructure.ForwardProtocolOTLP:
+		return infrastructure.NewTraceForwarder(infrastructure.TraceForwarderConfig{
+			Protocol: target.Kind,
+			Endpoint: target.Target,
+			Timeout:  cfg.Export.Timeout,
+		})
+	case "zipkin":
+		return infrastructure.NewZipkinExporter(target.Target, cfg.Export.Timeout)
+	case "file":
+		return infrastructure.NewFileExporter(target.Target)
+	case "kafka":
+		return infrastructure.NewKafkaTraceExporter(cfg.Kafka.Brokers, target.Target)
+	default:
+		return nil, fmt.Errorf("unknown export backend kind %q", target.Kind)
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/streamforge/distributed-tracing-system/internal/config"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+)
+
+// MigrateUsage describes the arguments of the migrate command
+const MigrateUsage = "usage: server migrate [up | down [steps] | status]"
+
+// Migrate runs the migrate command against the configured PostgreSQL
+// database: "up" applies pending migrations (the default), "down" reverts
+// the given number of migrations (one by default) and "status" lists them
+func Migrate(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	if len(args) > 2 || (command != "down" && len(args) > 1) {
+		return errors.New(MigrateUsage)
+	}
+
+	steps := 1
+	if command == "down" && len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of steps %q", args[1])
+		}
+		steps = n
+	}
+
+	db, err := sqlx.Connect("postgres", cfg.Database.GetDSN())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	migrator, err := infrastructure.NewPostgresMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migrations\n", applied)
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Reverted %d migrations\n", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d %-30s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		return errors.New(MigrateUsage)
+	}
+
+	return nil
+}
@@ -7,14 +7,35 @@ import (
 	"time"
 )
 
+// Supported storage backends
+const (
+	StorageBackendPostgres = "postgres"
+	StorageBackendMemory   = "memory"
+	StorageBackendBolt     = "bolt"
+)
+
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Jaeger   JaegerConfig
-	Kafka    KafkaConfig
-	Prometheus PrometheusConfig
-	Logging  LoggingConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Jaeger       JaegerConfig
+	Kafka        KafkaConfig
+	Prometheus   PrometheusConfig
+	Logging      LoggingConfig
+	OTLP         OTLPConfig
+	Ingest       IngestConfig
+	Assembler    AssemblerConfig
+	Sampling     SamplingConfig
+	Storage      StorageConfig
+	Retention    RetentionConfig
+	WriteQueue   WriteQueueConfig
+	ClockSkew    ClockSkewConfig
+	Dependencies DependenciesConfig
+	SpanMetrics  SpanMetricsConfig
+	SLOs         SLOConfig
+	Anomalies    AnomalyConfig
+	Export       ExportConfig
+	TagCatalog   TagCatalogConfig
 }
 
 // ServerConfig holds server configuration
@@ -35,27 +56,204 @@ type DatabaseConfig struct {
 	SSLMode  string
 	MaxConns int
 	MinConns int
+	// AutoMigrate applies pending schema migrations on startup
+	AutoMigrate bool
+	// PartitionPremakeDays is how many days of partitions are created ahead
+	PartitionPremakeDays int
+	// PartitionRetention drops daily partitions older than this; zero keeps them
+	PartitionRetention time.Duration
+	PartitionInterval  time.Duration
 }
 
 // JaegerConfig holds Jaeger configuration
 type JaegerConfig struct {
+	// Protocol is how stored traces are forwarded: "jaeger" posts thrift
+	// batches to a Jaeger collector, "otlp" posts OTLP/HTTP protobuf
+	Protocol string
 	Endpoint string
 	Timeout  time.Duration
 }
 
 // KafkaConfig holds Kafka configuration
 type KafkaConfig struct {
-	Brokers       []string
-	TopicTraces   string
-	GroupID       string
-	RetryAttempts int
-	RetryDelay    time.Duration
+	Brokers        []string
+	TopicTraces    string
+	TopicSpans     string
+	TopicAlerts    string
+	TopicAnomalies string
+	GroupID        string
+	RetryAttempts  int
+	RetryDelay     time.Duration
+	// ConsumerConcurrency bounds the trace messages processed at once
+	ConsumerConcurrency int
+	// BackpressureBackoff and MaxBackpressureBackoff pace retries of
+	// traces refused by a full write queue
+	BackpressureBackoff    time.Duration
+	MaxBackpressureBackoff time.Duration
 }
 
 // PrometheusConfig holds Prometheus configuration
 type PrometheusConfig struct {
 	Port string
 	Path string
+	// ErrorRateWindow is the sliding window of the error_rate gauge
+	ErrorRateWindow time.Duration
+}
+
+// OTLPConfig holds OTLP receiver configuration
+type OTLPConfig struct {
+	GRPCEnabled     bool
+	GRPCPort        string
+	MaxMessageBytes int
+}
+
+// IngestConfig holds configuration for the native HTTP ingestion API
+type IngestConfig struct {
+	MaxPayloadBytes int
+	MaxBatchSize    int
+}
+
+// AssemblerConfig holds configuration for assembling traces from individual spans
+type AssemblerConfig struct {
+	Enabled          bool
+	IdleTimeout      time.Duration
+	MaxPendingTraces int
+	CompletedTTL     time.Duration
+}
+
+// SamplingConfig holds tail-based sampling configuration
+type SamplingConfig struct {
+	// PolicyFile is the path to the YAML policy file; empty keeps every trace
+	PolicyFile string
+}
+
+// StorageConfig selects and configures the trace storage backend
+type StorageConfig struct {
+	// Backend is "postgres", "memory" or "bolt"
+	Backend         string
+	MemoryMaxTraces int
+	MemoryMaxBytes  int64
+	// BoltPath is the database file of the embedded backend
+	BoltPath               string
+	BoltTTL                time.Duration
+	BoltExpiryInterval     time.Duration
+	BoltCompactionInterval time.Duration
+}
+
+// RetentionConfig holds data retention configuration
+type RetentionConfig struct {
+	Enabled bool
+	// DefaultMaxAge applies to traces matching no rule, as a Go duration or
+	// days ("7d"); zero keeps them forever
+	DefaultMaxAge string
+	// Rules are per-service overrides, e.g. "*:error=30d,*:success=3d"
+	Rules             string
+	Interval          time.Duration
+	BatchSize         int
+	BatchPause        time.Duration
+	MaxBatchesPerRule int
+}
+
+// WriteQueueConfig holds trace write queue configuration
+type WriteQueueConfig struct {
+	Enabled bool
+	// Capacity bounds the queued traces; beyond it writes are refused
+	Capacity  int
+	BatchSize int
+	// Linger is how long a batch waits to fill up; zero writes right away
+	Linger time.Duration
+}
+
+// ClockSkewConfig holds clock skew adjustment configuration
+type ClockSkewConfig struct {
+	Enabled bool
+	// Services and ExcludeServices are comma-separated service names;
+	// an empty Services adjusts every service
+	Services        string
+	ExcludeServices string
+	// MaxAdjustment skips spans needing a larger shift; zero allows any
+	MaxAdjustment time.Duration
+}
+
+// DependenciesConfig holds service dependency graph configuration
+type DependenciesConfig struct {
+	Enabled bool
+	// Window is the time bucket calls between services are aggregated in
+	Window        time.Duration
+	FlushInterval time.Duration
+}
+
+// SpanMetricsConfig holds configuration for span-derived RED metrics
+type SpanMetricsConfig struct {
+	Enabled bool
+	// Buckets are comma-separated duration histogram bounds in seconds;
+	// empty keeps the default buckets
+	Buckets string
+	// MaxServices and MaxOperations (per service) bound label cardinality;
+	// zero means no limit
+	MaxServices   int
+	MaxOperations int
+}
+
+// SLOConfig holds latency SLO and burn rate alerting configuration
+type SLOConfig struct {
+	Enabled bool
+	// EvaluationInterval is how often burn rates are checked for alerts
+	EvaluationInterval time.Duration
+	// WebhookURL also delivers alerts as JSON POST requests; empty disables it
+	WebhookURL     string
+	WebhookTimeout time.Duration
+}
+
+// AnomalyConfig holds latency and error rate anomaly detection configuration
+type AnomalyConfig struct {
+	Enabled bool
+	// Window is the detection window traces are aggregated in
+	Window time.Duration
+	// Alpha is the weight of each window in the moving baselines
+	Alpha float64
+	// Threshold is the deviation score that flags a window
+	Threshold float64
+	// MinTraces is the number of traces a window needs to be evaluated
+	MinTraces int
+	// WarmupWindows are evaluated before an operation's anomalies are flagged
+	WarmupWindows int
+	// SeasonDays of hourly history form the seasonal baseline; zero disables it
+	SeasonDays   int
+	MaxExamples  int
+	MaxSeries    int
+	MaxAnomalies int
+}
+
+// ExportConfig holds configuration for exporting traces to external backends
+type ExportConfig struct {
+	// Backends are comma-separated "kind=target" entries, the kind being
+	// jaeger, otlp, zipkin, file or kafka and the target an endpoint, file
+	// path or topic; empty exports to the Jaeger endpoint alone
+	Backends string
+	// QueueSize bounds the traces waiting per backend
+	QueueSize int
+	// DropPolicy is "newest" or "oldest"
+	DropPolicy     string
+	Timeout        time.Duration
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// FailureThreshold consecutive failures open a backend's circuit for
+	// Cooldown; zero disables the circuit breaker
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// TagCatalogConfig holds configuration for the catalog of tag names and
+// values served to Tempo search clients
+type TagCatalogConfig struct {
+	Enabled bool
+	// MaxTags and MaxValuesPerTag bound the catalog; further tags and values are ignored
+	MaxTags         int
+	MaxValuesPerTag int
+	// LoadTraces recent stored traces are cataloged on startup
+	LoadTraces int
 }
 
 // LoggingConfig holds logging configuration
@@ -68,40 +266,152 @@ type LoggingConfig struct {
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
+			Port:                 getEnv("SERVER_PORT", "8080"),
 			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
 			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
 			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
+			Host:                 getEnv("DB_HOST", "localhost"),
 			Port:     getIntEnv("DB_PORT", 5432),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "tracing_system"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
-			MaxConns: getIntEnv("DB_MAX_CONNS", 10),
-			MinConns: getIntEnv("DB_MIN_CONNS", 1),
+			User:                 getEnv("DB_USER", "postgres"),
+			Password:             getEnv("DB_PASSWORD", "postgres"),
+			DBName:               getEnv("DB_NAME", "tracing_system"),
+			SSLMode:              getEnv("DB_SSLMODE", "disable"),
+			MaxConns:             getIntEnv("DB_MAX_CONNS", 10),
+			MinConns:             getIntEnv("DB_MIN_CONNS", 1),
+			AutoMigrate:          getBoolEnv("DB_AUTO_MIGRATE", true),
+			PartitionPremakeDays: getIntEnv("DB_PARTITION_PREMAKE_DAYS", 3),
+			PartitionRetention:   getDurationEnv("DB_PARTITION_RETENTION", 0),
+			PartitionInterval:    getDurationEnv("DB_PARTITION_INTERVAL", time.Hour),
 		},
 		Jaeger: JaegerConfig{
+			Protocol: getEnv("JAEGER_PROTOCOL", "jaeger"),
 			Endpoint: getEnv("JAEGER_ENDPOINT", "http://jaeger:14268/api/traces"),
 			Timeout:  getDurationEnv("JAEGER_TIMEOUT", 30*time.Second),
 		},
 		Kafka: KafkaConfig{
-			Brokers:       getStringSliceEnv("KAFKA_BROKERS", []string{"localhost:9092"}),
-			TopicTraces:   getEnv("KAFKA_TOPIC_TRACES", "trace-events"),
-			GroupID:       getEnv("KAFKA_GROUP_ID", "tracing-system"),
-			RetryAttempts: getIntEnv("KAFKA_RETRY_ATTEMPTS", 3),
-			RetryDelay:    getDurationEnv("KAFKA_RETRY_DELAY", 1*time.Second),
+			Brokers:                getStringSliceEnv("KAFKA_BROKERS", []string{"localhost:9092"}),
+			TopicTraces:            getEnv("KAFKA_TOPIC_TRACES", "trace-events"),
+			TopicSpans:             getEnv("KAFKA_TOPIC_SPANS", "span-events"),
+			TopicAlerts:            getEnv("KAFKA_TOPIC_ALERTS", "slo-alerts"),
+			TopicAnomalies:         getEnv("KAFKA_TOPIC_ANOMALIES", "trace-anomalies"),
+			GroupID:                getEnv("KAFKA_GROUP_ID", "tracing-system"),
+			RetryAttempts:          getIntEnv("KAFKA_RETRY_ATTEMPTS", 3),
+			RetryDelay:             getDurationEnv("KAFKA_RETRY_DELAY", 1*time.Second),
+			ConsumerConcurrency:    getIntEnv("KAFKA_CONSUMER_CONCURRENCY", 32),
+			BackpressureBackoff:    getDurationEnv("KAFKA_BACKPRESSURE_BACKOFF", 100*time.Millisecond),
+			MaxBackpressureBackoff: getDurationEnv("KAFKA_MAX_BACKPRESSURE_BACKOFF", 5*time.Second),
 		},
 		Prometheus: PrometheusConfig{
-			Port: getEnv("PROMETHEUS_PORT", "9091"),
-			Path: getEnv("PROMETHEUS_PATH", "/metrics"),
+			Port:            getEnv("PROMETHEUS_PORT", "9091"),
+			Path:            getEnv("PROMETHEUS_PATH", "/metrics"),
+			ErrorRateWindow: getDurationEnv("PROMETHEUS_ERROR_RATE_WINDOW", time.Minute),
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		OTLP: OTLPConfig{
+			GRPCEnabled:     getBoolEnv("OTLP_GRPC_ENABLED", true),
+			GRPCPort:        getEnv("OTLP_GRPC_PORT", "4317"),
+			MaxMessageBytes: getIntEnv("OTLP_MAX_MESSAGE_BYTES", 4*1024*1024),
+		},
+		Ingest: IngestConfig{
+			MaxPayloadBytes: getIntEnv("INGEST_MAX_PAYLOAD_BYTES", 5*1024*1024),
+			MaxBatchSize:    getIntEnv("INGEST_MAX_BATCH_SIZE", 1000),
+		},
+		Assembler: AssemblerConfig{
+			Enabled:          getBoolEnv("ASSEMBLER_ENABLED", true),
+			IdleTimeout:      getDurationEnv("ASSEMBLER_IDLE_TIMEOUT", 10*time.Second),
+			MaxPendingTraces: getIntEnv("ASSEMBLER_MAX_PENDING_TRACES", 10000),
+			CompletedTTL:     getDurationEnv("ASSEMBLER_COMPLETED_TTL", 10*time.Minute),
+		},
+		Sampling: SamplingConfig{
+			PolicyFile: getEnv("TAIL_SAMPLING_POLICY_FILE", ""),
+		},
+		Storage: StorageConfig{
+			Backend:                getEnv("STORAGE_BACKEND", StorageBackendPostgres),
+			MemoryMaxTraces:        getIntEnv("STORAGE_MEMORY_MAX_TRACES", 100000),
+			MemoryMaxBytes:         int64(getIntEnv("STORAGE_MEMORY_MAX_BYTES", 512*1024*1024)),
+			BoltPath:               getEnv("STORAGE_BOLT_PATH", "data/traces.db"),
+			BoltTTL:                getDurationEnv("STORAGE_BOLT_TTL", 72*time.Hour),
+			BoltExpiryInterval:     getDurationEnv("STORAGE_BOLT_EXPIRY_INTERVAL", 5*time.Minute),
+			BoltCompactionInterval: getDurationEnv("STORAGE_BOLT_COMPACTION_INTERVAL", 24*time.Hour),
+		},
+		Retention: RetentionConfig{
+			Enabled:           getBoolEnv("RETENTION_ENABLED", false),
+			DefaultMaxAge:     getEnv("RETENTION_DEFAULT_MAX_AGE", "7d"),
+			Rules:             getEnv("RETENTION_RULES", ""),
+			Interval:          getDurationEnv("RETENTION_INTERVAL", 10*time.Minute),
+			BatchSize:         getIntEnv("RETENTION_BATCH_SIZE", 1000),
+			BatchPause:        getDurationEnv("RETENTION_BATCH_PAUSE", 100*time.Millisecond),
+			MaxBatchesPerRule: getIntEnv("RETENTION_MAX_BATCHES_PER_RULE", 100),
+		},
+		WriteQueue: WriteQueueConfig{
+			Enabled:   getBoolEnv("WRITE_QUEUE_ENABLED", true),
+			Capacity:  getIntEnv("WRITE_QUEUE_CAPACITY", 10000),
+			BatchSize: getIntEnv("WRITE_QUEUE_BATCH_SIZE", 500),
+			Linger:    getDurationEnv("WRITE_QUEUE_LINGER", 0),
+		},
+		ClockSkew: ClockSkewConfig{
+			Enabled:         getBoolEnv("CLOCK_SKEW_ADJUSTMENT_ENABLED", true),
+			Services:        getEnv("CLOCK_SKEW_SERVICES", ""),
+			ExcludeServices: getEnv("CLOCK_SKEW_EXCLUDE_SERVICES", ""),
+			MaxAdjustment:   getDurationEnv("CLOCK_SKEW_MAX_ADJUSTMENT", 0),
+		},
+		Dependencies: DependenciesConfig{
+			Enabled:       getBoolEnv("DEPENDENCIES_ENABLED", true),
+			Window:        getDurationEnv("DEPENDENCIES_WINDOW", 5*time.Minute),
+			FlushInterval: getDurationEnv("DEPENDENCIES_FLUSH_INTERVAL", 30*time.Second),
+		},
+		SpanMetrics: SpanMetricsConfig{
+			Enabled:       getBoolEnv("SPAN_METRICS_ENABLED", true),
+			Buckets:       getEnv("SPAN_METRICS_BUCKETS", ""),
+			MaxServices:   getIntEnv("SPAN_METRICS_MAX_SERVICES", 200),
+			MaxOperations: getIntEnv("SPAN_METRICS_MAX_OPERATIONS", 500),
+		},
+		SLOs: SLOConfig{
+			Enabled:            getBoolEnv("SLO_ENABLED", true),
+			EvaluationInterval: getDurationEnv("SLO_EVALUATION_INTERVAL", 30*time.Second),
+			WebhookURL:         getEnv("SLO_WEBHOOK_URL", ""),
+			WebhookTimeout:     getDurationEnv("SLO_WEBHOOK_TIMEOUT", 5*time.Second),
+		},
+		Anomalies: AnomalyConfig{
+			Enabled:       getBoolEnv("ANOMALY_ENABLED", true),
+			Window:        getDurationEnv("ANOMALY_WINDOW", time.Minute),
+			Alpha:         getFloatEnv("ANOMALY_ALPHA", 0.1),
+			Threshold:     getFloatEnv("ANOMALY_THRESHOLD", 4),
+			MinTraces:     getIntEnv("ANOMALY_MIN_TRACES", 20),
+			WarmupWindows: getIntEnv("ANOMALY_WARMUP_WINDOWS", 15),
+			SeasonDays:    getIntEnv("ANOMALY_SEASON_DAYS", 7),
+			MaxExamples:   getIntEnv("ANOMALY_MAX_EXAMPLES", 5),
+			MaxSeries:     getIntEnv("ANOMALY_MAX_SERIES", 1000),
+			MaxAnomalies:  getIntEnv("ANOMALY_MAX_ANOMALIES", 1000),
+		},
+		Export: ExportConfig{
+			Backends:         getEnv("EXPORT_BACKENDS", ""),
+			QueueSize:        getIntEnv("EXPORT_QUEUE_SIZE", 10000),
+			DropPolicy:       getEnv("EXPORT_DROP_POLICY", "oldest"),
+			Timeout:          getDurationEnv("EXPORT_TIMEOUT", 10*time.Second),
+			MaxRetries:       getIntEnv("EXPORT_MAX_RETRIES", 3),
+			InitialBackoff:   getDurationEnv("EXPORT_INITIAL_BACKOFF", 200*time.Millisecond),
+			MaxBackoff:       getDurationEnv("EXPORT_MAX_BACKOFF", 5*time.Second),
+			FailureThreshold: getIntEnv("EXPORT_FAILURE_THRESHOLD", 5),
+			Cooldown:         getDurationEnv("EXPORT_COOLDOWN", 30*time.Second),
+		},
+		TagCatalog: TagCatalogConfig{
+			Enabled:         getBoolEnv("TAG_CATALOG_ENABLED", true),
+			MaxTags:         getIntEnv("TAG_CATALOG_MAX_TAGS", 1000),
+			MaxValuesPerTag: getIntEnv("TAG_CATALOG_MAX_VALUES_PER_TAG", 1000),
+			LoadTraces:      getIntEnv("TAG_CATALOG_LOAD_TRACES", 1000),
+		},
+	}
+
+	switch cfg.Storage.Backend {
+	case StorageBackendPostgres, StorageBackendMemory, StorageBackendBolt:
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
 	}
 
 	return cfg, nil
@@ -124,6 +434,24 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
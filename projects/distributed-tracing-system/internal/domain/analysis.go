@@ -0,0 +1,420 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// SpanNode is a span in the span tree of a trace
+type SpanNode struct {
+	SpanID    SpanID        `json:"span_id"`
+	ParentID  *SpanID       `json:"parent_id,omitempty"`
+	Service   ServiceName   `json:"service"`
+	Operation OperationName `json:"operation"`
+	StartTime time.Time     `json:"start_time"`
+	Duration  time.Duration `json:"duration"`
+	// SelfTime is the part of the span not covered by any of its children.
+	// Concurrent children are counted once, so it is never negative.
+	SelfTime       time.Duration `json:"self_time"`
+	Status         SpanStatus    `json:"status"`
+	OnCriticalPath bool          `json:"on_critical_path"`
+	Children       []*SpanNode   `json:"children,omitempty"`
+
+	endTime time.Time
+}
+
+// CriticalPathSegment is a stretch of time the trace spent waiting on a span
+type CriticalPathSegment struct {
+	SpanID    SpanID        `json:"span_id"`
+	Service   ServiceName   `json:"service"`
+	Operation OperationName `json:"operation"`
+	StartTime time.Time     `json:"start_time"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// ServiceTime is the time a trace spent in one service
+type ServiceTime struct {
+	Service   ServiceName   `json:"service"`
+	SpanCount int           `json:"span_count"`
+	SelfTime  time.Duration `json:"self_time"`
+	// CriticalPathTime is the service's share of the critical path
+	CriticalPathTime time.Duration `json:"critical_path_time"`
+	// Percentage is SelfTime relative to the self time of all services
+	Percentage float64 `json:"percentage"`
+}
+
+// ClockSkewKind describes how a span disagrees with its parent's timing
+type ClockSkewKind string
+
+const (
+	ClockSkewStartsBeforeParent ClockSkewKind = "starts_before_parent"
+	ClockSkewEndsAfterParent    ClockSkewKind = "ends_after_parent"
+)
+
+// ClockSkewWarning flags a span whose timing does not fit in its parent,
+// usually because the hosts reporting them have skewed clocks
+type ClockSkewWarning struct {
+	SpanID   SpanID        `json:"span_id"`
+	ParentID SpanID        `json:"parent_id"`
+	Service  ServiceName   `json:"service"`
+	Kind     ClockSkewKind `json:"kind"`
+	Skew     time.Duration `json:"skew"`
+}
+
+// TraceAnalysis describes where a trace spent its time
+type TraceAnalysis struct {
+	TraceID   TraceID       `json:"trace_id"`
+	Duration  time.Duration `json:"duration"`
+	SpanCount int           `json:"span_count"`
+	Depth     int           `json:"depth"`
+	// Roots holds the root span first, followed by the subtrees of orphan
+	// spans whose parent is missing from the trace
+	Roots        []*SpanNode           `json:"roots"`
+	CriticalPath []CriticalPathSegment `json:"critical_path"`
+	Services     []ServiceTime         `json:"services"`
+	Orphans      []SpanID              `json:"orphans"`
+	Warnings     []ClockSkewWarning    `json:"warnings"`
+}
+
+// AnalyzeTrace rebuilds the span tree of a trace and computes its critical
+// path, the self time of every span, the time spent per service, orphan
+// spans and clock skew between parents and children. Spans repeating an
+// earlier span ID are ignored.
+func AnalyzeTrace(trace *Trace) *TraceAnalysis {
+	analysis := &TraceAnalysis{
+		TraceID:      trace.ID,
+		Duration:     trace.Duration,
+		Roots:        []*SpanNode{},
+		CriticalPath: []CriticalPathSegment{},
+		Services:     []ServiceTime{},
+		Orphans:      []SpanID{},
+		Warnings:     []ClockSkewWarning{},
+	}
+
+	nodes, order := buildSpanNodes(trace.Spans)
+	analysis.SpanCount = len(order)
+	if len(order) == 0 {
+		return analysis
+	}
+
+	var trueRoots, orphans []*SpanNode
+	for _, node := range order {
+		switch {
+		case node.ParentID == nil:
+			trueRoots = append(trueRoots, node)
+		case nodes[*node.ParentID] == nil:
+			orphans = append(orphans, node)
+		default:
+			parent := nodes[*node.ParentID]
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	// Spans in parent cycles are unreachable from any root; cut each cycle
+	// at its earliest span, which then counts as an orphan
+	reached := make(map[*SpanNode]bool, len(order))
+	for _, root := range append(trueRoots, orphans...) {
+		markSubtree(root, reached)
+	}
+	for _, node := range order {
+		if reached[node] {
+			continue
+		}
+		cycleRoot := earliestUnreached(nodes, node, reached)
+		parent := nodes[*cycleRoot.ParentID]
+		parent.Children = removeNode(parent.Children, cycleRoot)
+		orphans = append(orphans, cycleRoot)
+		markSubtree(cycleRoot, reached)
+	}
+
+	sortNodes(trueRoots)
+	sortNodes(orphans)
+	analysis.Roots = append(trueRoots, orphans...)
+	for _, orphan := range orphans {
+		analysis.Orphans = append(analysis.Orphans, orphan.SpanID)
+	}
+
+	for _, root := range analysis.Roots {
+		if depth := analyzeSubtree(root, 1, &analysis.Warnings); depth > analysis.Depth {
+			analysis.Depth = depth
+		}
+	}
+
+	// The critical path follows the root span; orphan subtrees hang off
+	// spans that are missing, so their place in it is unknown
+	analysis.CriticalPath = criticalPath(analysis.Roots[0], analysis.Roots[0].endTime)
+	for _, segment := range analysis.CriticalPath {
+		nodes[segment.SpanID].OnCriticalPath = true
+	}
+
+	analysis.Services = serviceTimes(order, analysis.CriticalPath)
+	if analysis.Duration == 0 {
+		analysis.Duration = traceSpan(order)
+	}
+
+	return analysis
+}
+
+// buildSpanNodes creates a node per distinct span ID, in span order
+func buildSpanNodes(spans []Span) (map[SpanID]*SpanNode, []*SpanNode) {
+	nodes := make(map[SpanID]*SpanNode, len(spans))
+	order := make([]*SpanNode, 0, len(spans))
+	for _, span := range spans {
+		if _, ok := nodes[span.ID]; ok {
+			continue
+		}
+
+		end := span.EndTime
+		if end.IsZero() || end.Before(span.StartTime) {
+			end = span.StartTime.Add(span.Duration)
+		}
+		if end.Before(span.StartTime) {
+			end = span.StartTime
+		}
+
+		node := &SpanNode{
+			SpanID:    span.ID,
+			Service:   span.Service,
+			Operation: span.Operation,
+			StartTime: span.StartTime,
+			Duration:  end.Sub(span.StartTime),
+			Status:    span.Status,
+			endTime:   end,
+		}
+		if span.ParentID != nil && *span.ParentID != "" && *span.ParentID != span.ID {
+			parentID := *span.ParentID
+			node.ParentID = &parentID
+		}
+
+		nodes[span.ID] = node
+		order = append(order, node)
+	}
+	return nodes, order
+}
+
+// markSubtree marks a node and its descendants as reached
+func markSubtree(node *SpanNode, reached map[*SpanNode]bool) {
+	reached[node] = true
+	for _, child := range node.Children {
+		markSubtree(child, reached)
+	}
+}
+
+// earliestUnreached walks up the parents of an unreached node, which must
+// end in a cycle, and returns the earliest span of that cycle
+func earliestUnreached(nodes map[SpanID]*SpanNode, node *SpanNode, reached map[*SpanNode]bool) *SpanNode {
+	seen := make(map[*SpanNode]bool)
+	for !seen[node] {
+		seen[node] = true
+		node = nodes[*node.ParentID]
+	}
+
+	// node is now on the cycle
+	earliest := node
+	for current := nodes[*node.ParentID]; current != node; current = nodes[*current.ParentID] {
+		if current.StartTime.Before(earliest.StartTime) {
+			earliest = current
+		}
+	}
+	return earliest
+}
+
+// removeNode removes a node from a list of children
+func removeNode(children []*SpanNode, node *SpanNode) []*SpanNode {
+	for i, child := range children {
+		if child == node {
+			return append(children[:i], children[i+1:]...)
+		}
+	}
+	return children
+}
+
+// sortNodes orders nodes by start time
+func sortNodes(nodes []*SpanNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].StartTime.Before(nodes[j].StartTime)
+	})
+}
+
+// analyzeSubtree orders children, computes self times and clock skew
+// warnings below node and returns the depth of the subtree
+func analyzeSubtree(node *SpanNode, depth int, warnings *[]ClockSkewWarning) int {
+	sortNodes(node.Children)
+	node.SelfTime = node.Duration - coveredTime(node)
+
+	maxDepth := depth
+	for _, child := range node.Children {
+		if child.StartTime.Before(node.StartTime) {
+			*warnings = append(*warnings, ClockSkewWarning{
+				SpanID:   child.SpanID,
+				ParentID: node.SpanID,
+				Service:  child.Service,
+				Kind:     ClockSkewStartsBeforeParent,
+				Skew:     node.StartTime.Sub(child.StartTime),
+			})
+		}
+		if child.endTime.After(node.endTime) {
+			*warnings = append(*warnings, ClockSkewWarning{
+				SpanID:   child.SpanID,
+				ParentID: node.SpanID,
+				Service:  child.Service,
+				Kind:     ClockSkewEndsAfterParent,
+				Skew:     child.endTime.Sub(node.endTime),
+			})
+		}
+
+		if childDepth := analyzeSubtree(child, depth+1, warnings); childDepth > maxDepth {
+			maxDepth = childDepth
+		}
+	}
+	return maxDepth
+}
+
+// coveredTime returns how much of a span is covered by its children,
+// clipped to the span and counting overlapping children once. Children
+// must be ordered by start time.
+func coveredTime(node *SpanNode) time.Duration {
+	var covered time.Duration
+	var cursor time.Time
+	for _, child := range node.Children {
+		start, end := child.StartTime, child.endTime
+		if start.Before(node.StartTime) {
+			start = node.StartTime
+		}
+		if end.After(node.endTime) {
+			end = node.endTime
+		}
+		if start.Before(cursor) {
+			start = cursor
+		}
+		if !end.After(start) {
+			continue
+		}
+		covered += end.Sub(start)
+		cursor = end
+	}
+	return covered
+}
+
+// criticalPath returns the critical path of a span up to end. Walking back
+// from the end, the path runs through the child that finished last, then
+// through whichever child finished last before that one started, and
+// through the span itself whenever no child was running.
+func criticalPath(node *SpanNode, end time.Time) []CriticalPathSegment {
+	children := make([]*SpanNode, len(node.Children))
+	copy(children, node.Children)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].endTime.After(children[j].endTime)
+	})
+
+	// Segments are collected from the end backwards
+	var reversed []CriticalPathSegment
+	self := func(from, to time.Time) {
+		if to.After(from) {
+			reversed = append(reversed, CriticalPathSegment{
+				SpanID:    node.SpanID,
+				Service:   node.Service,
+				Operation: node.Operation,
+				StartTime: from,
+				Duration:  to.Sub(from),
+			})
+		}
+	}
+
+	cursor := end
+	for _, child := range children {
+		if !child.StartTime.Before(cursor) || !child.endTime.After(node.StartTime) {
+			continue
+		}
+
+		childEnd := child.endTime
+		if childEnd.After(cursor) {
+			childEnd = cursor
+		}
+		self(childEnd, cursor)
+
+		childPath := criticalPath(child, childEnd)
+		for i := len(childPath) - 1; i >= 0; i-- {
+			if childPath[i].StartTime.Before(node.StartTime) {
+				// Clip skewed children to the parent
+				cut := node.StartTime.Sub(childPath[i].StartTime)
+				if cut >= childPath[i].Duration {
+					continue
+				}
+				childPath[i].StartTime = node.StartTime
+				childPath[i].Duration -= cut
+			}
+			reversed = append(reversed, childPath[i])
+		}
+
+		cursor = child.StartTime
+		if cursor.Before(node.StartTime) {
+			cursor = node.StartTime
+		}
+	}
+	self(node.StartTime, cursor)
+
+	path := make([]CriticalPathSegment, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		segment := reversed[i]
+		if n := len(path); n > 0 && path[n-1].SpanID == segment.SpanID &&
+			path[n-1].StartTime.Add(path[n-1].Duration).Equal(segment.StartTime) {
+			path[n-1].Duration += segment.Duration
+			continue
+		}
+		path = append(path, segment)
+	}
+	return path
+}
+
+// serviceTimes sums self time and critical path time per service, busiest
+// service first
+func serviceTimes(nodes []*SpanNode, path []CriticalPathSegment) []ServiceTime {
+	index := make(map[ServiceName]int)
+	var times []ServiceTime
+	var total time.Duration
+
+	entry := func(service ServiceName) *ServiceTime {
+		i, ok := index[service]
+		if !ok {
+			i = len(times)
+			index[service] = i
+			times = append(times, ServiceTime{Service: service})
+		}
+		return &times[i]
+	}
+
+	for _, node := range nodes {
+		st := entry(node.Service)
+		st.SpanCount++
+		st.SelfTime += node.SelfTime
+		total += node.SelfTime
+	}
+	for _, segment := range path {
+		entry(segment.Service).CriticalPathTime += segment.Duration
+	}
+
+	for i := range times {
+		if total > 0 {
+			times[i].Percentage = float64(times[i].SelfTime) / float64(total) * 100
+		}
+	}
+	sort.SliceStable(times, func(i, j int) bool {
+		return times[i].SelfTime > times[j].SelfTime
+	})
+	return times
+}
+
+// traceSpan returns the time from the earliest span start to the latest end
+func traceSpan(nodes []*SpanNode) time.Duration {
+	start, end := nodes[0].StartTime, nodes[0].endTime
+	for _, node := range nodes[1:] {
+		if node.StartTime.Before(start) {
+			start = node.StartTime
+		}
+		if node.endTime.After(end) {
+			end = node.endTime
+		}
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analysisTestSpan(id SpanID, parent SpanID, service ServiceName, start time.Time, from, to int) Span {
+	span := Span{
+		ID:        id,
+		TraceID:   "trace1",
+		Service:   service,
+		Operation: OperationName(id),
+		StartTime: start.Add(time.Duration(from) * time.Millisecond),
+		EndTime:   start.Add(time.Duration(to) * time.Millisecond),
+		Duration:  time.Duration(to-from) * time.Millisecond,
+		Status:    SpanStatusOK,
+	}
+	if parent != "" {
+		span.ParentID = spanIDPtr(parent)
+	}
+	return span
+}
+
+func TestAnalyzeTrace(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ms := time.Millisecond
+	trace := BuildTrace("trace1", []Span{
+		analysisTestSpan("db", "inventory", "postgres", start, 40, 60),
+		analysisTestSpan("root", "", "checkout", start, 0, 100),
+		analysisTestSpan("payments", "root", "payments", start, 10, 40),
+		analysisTestSpan("inventory", "root", "inventory", start, 30, 90),
+	})
+
+	analysis := AnalyzeTrace(trace)
+
+	assert.Equal(t, TraceID("trace1"), analysis.TraceID)
+	assert.Equal(t, 100*ms, analysis.Duration)
+	assert.Equal(t, 4, analysis.SpanCount)
+	assert.Equal(t, 3, analysis.Depth)
+	assert.Empty(t, analysis.Orphans)
+	assert.Empty(t, analysis.Warnings)
+
+	// Tree with children ordered by start time
+	require.Len(t, analysis.Roots, 1)
+	root := analysis.Roots[0]
+	assert.Equal(t, SpanID("root"), root.SpanID)
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, SpanID("payments"), root.Children[0].SpanID)
+	assert.Equal(t, SpanID("inventory"), root.Children[1].SpanID)
+	require.Len(t, root.Children[1].Children, 1)
+
+	// Self time counts overlapping children once
+	assert.Equal(t, 20*ms, root.SelfTime)
+	assert.Equal(t, 30*ms, root.Children[0].SelfTime)
+	assert.Equal(t, 40*ms, root.Children[1].SelfTime)
+	assert.Equal(t, 20*ms, root.Children[1].Children[0].SelfTime)
+
+	// The critical path follows the last finishing child backwards
+	var path []SpanID
+	var pathTime time.Duration
+	for _, segment := range analysis.CriticalPath {
+		path = append(path, segment.SpanID)
+		pathTime += segment.Duration
+	}
+	assert.Equal(t, []SpanID{"root", "payments", "inventory", "db", "inventory", "root"}, path)
+	assert.Equal(t, 100*ms, pathTime)
+	assert.Equal(t, start.Add(10*ms), analysis.CriticalPath[1].StartTime)
+	assert.Equal(t, 20*ms, analysis.CriticalPath[1].Duration)
+	assert.True(t, root.Children[0].OnCriticalPath)
+
+	// Services, busiest first
+	require.Len(t, analysis.Services, 4)
+	assert.Equal(t, ServiceTime{
+		Service:          "inventory",
+		SpanCount:        1,
+		SelfTime:         40 * ms,
+		CriticalPathTime: 40 * ms,
+		Percentage:       40.0 / 110 * 100,
+	}, analysis.Services[0])
+	assert.Equal(t, ServiceName("payments"), analysis.Services[1].Service)
+	assert.Equal(t, 20*ms, analysis.Services[1].CriticalPathTime)
+}
+
+func TestAnalyzeTrace_OrphansAndClockSkew(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := &Trace{
+		ID: "trace1",
+		Spans: []Span{
+			analysisTestSpan("root", "", "checkout", start, 0, 100),
+			// Reported by a host whose clock runs 5ms behind
+			analysisTestSpan("skewed", "root", "payments", start, -5, 105),
+			analysisTestSpan("orphan", "missing", "emails", start, 20, 30),
+			analysisTestSpan("orphan-child", "orphan", "emails", start, 22, 28),
+		},
+	}
+
+	analysis := AnalyzeTrace(trace)
+
+	assert.Equal(t, []SpanID{"orphan"}, analysis.Orphans)
+	require.Len(t, analysis.Roots, 2)
+	assert.Equal(t, SpanID("root"), analysis.Roots[0].SpanID)
+	assert.Equal(t, SpanID("orphan"), analysis.Roots[1].SpanID)
+	assert.Len(t, analysis.Roots[1].Children, 1)
+	assert.Equal(t, 110*time.Millisecond, analysis.Duration)
+
+	assert.ElementsMatch(t, []ClockSkewWarning{
+		{SpanID: "skewed", ParentID: "root", Service: "payments", Kind: ClockSkewStartsBeforeParent, Skew: 5 * time.Millisecond},
+		{SpanID: "skewed", ParentID: "root", Service: "payments", Kind: ClockSkewEndsAfterParent, Skew: 5 * time.Millisecond},
+	}, analysis.Warnings)
+
+	// The skewed child is clipped to its parent
+	assert.Equal(t, time.Duration(0), analysis.Roots[0].SelfTime)
+	require.Len(t, analysis.CriticalPath, 1)
+	assert.Equal(t, CriticalPathSegment{
+		SpanID:    "skewed",
+		Service:   "payments",
+		Operation: "skewed",
+		StartTime: start,
+		Duration:  100 * time.Millisecond,
+	}, analysis.CriticalPath[0])
+}
+
+func TestAnalyzeTrace_ParentCycle(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := &Trace{
+		ID: "trace1",
+		Spans: []Span{
+			analysisTestSpan("root", "", "checkout", start, 0, 100),
+			analysisTestSpan("a", "b", "loop", start, 10, 20),
+			analysisTestSpan("b", "a", "loop", start, 5, 30),
+			analysisTestSpan("a", "root", "duplicate", start, 0, 1),
+		},
+	}
+
+	analysis := AnalyzeTrace(trace)
+
+	// The cycle is cut at its earliest span and the duplicate is ignored
+	assert.Equal(t, 3, analysis.SpanCount)
+	assert.Equal(t, []SpanID{"b"}, analysis.Orphans)
+	require.Len(t, analysis.Roots, 2)
+	require.Len(t, analysis.Roots[1].Children, 1)
+	assert.Equal(t, SpanID("a"), analysis.Roots[1].Children[0].SpanID)
+	assert.Empty(t, analysis.Roots[0].Children)
+}
+
+func TestAnalyzeTrace_Empty(t *testing.T) {
+	analysis := AnalyzeTrace(&Trace{ID: "trace1"})
+
+	assert.Equal(t, 0, analysis.SpanCount)
+	assert.Empty(t, analysis.Roots)
+	assert.Empty(t, analysis.CriticalPath)
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// AnomalyKind is the signal an anomaly was detected on
+type AnomalyKind string
+
+const (
+	AnomalyKindLatency   AnomalyKind = "latency"
+	AnomalyKindErrorRate AnomalyKind = "error_rate"
+)
+
+// Anomaly is a detection window in which the latency or the error rate of
+// an operation deviated from its baseline
+type Anomaly struct {
+	ID          string        `json:"id"`
+	Service     ServiceName   `json:"service"`
+	Operation   OperationName `json:"operation"`
+	Kind        AnomalyKind   `json:"kind"`
+	WindowStart time.Time     `json:"window_start"`
+	WindowEnd   time.Time     `json:"window_end"`
+	// Observed is the mean trace duration in seconds or the error rate of
+	// the window
+	Observed float64 `json:"observed"`
+	// Baseline is the moving average the window was compared against
+	Baseline float64 `json:"baseline"`
+	// Score is how many deviations Observed is from Baseline; negative
+	// when latency dropped
+	Score float64 `json:"score"`
+	// SeasonalBaseline is the median at the same hour of previous days,
+	// when enough days were seen
+	SeasonalBaseline *float64 `json:"seasonal_baseline,omitempty"`
+	TraceCount       int64    `json:"trace_count"`
+	// ExampleTraceIDs are the slowest traces of a latency anomaly or the
+	// failed traces of an error rate anomaly
+	ExampleTraceIDs []TraceID `json:"example_trace_ids"`
+}
+
+// AnomalyQuery selects detected anomalies. Empty fields match everything.
+type AnomalyQuery struct {
+	Service   ServiceName
+	Operation OperationName
+	Kind      AnomalyKind
+	// Start and End bound the end of the detection window
+	Start  time.Time
+	End    time.Time
+	Limit  int
+	Offset int
+}
+
+// Matches reports whether the anomaly satisfies the query filters
+func (q AnomalyQuery) Matches(anomaly *Anomaly) bool {
+	if q.Service != "" && anomaly.Service != q.Service {
+		return false
+	}
+	if q.Operation != "" && anomaly.Operation != q.Operation {
+		return false
+	}
+	if q.Kind != "" && anomaly.Kind != q.Kind {
+		return false
+	}
+	if !q.Start.IsZero() && anomaly.WindowEnd.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && anomaly.WindowEnd.After(q.End) {
+		return false
+	}
+	return true
+}
+
+// EWMA is an exponentially weighted moving average and variance. Alpha is
+// the weight of each new value.
+type EWMA struct {
+	Alpha    float64
+	Mean     float64
+	Variance float64
+	Count    int
+}
+
+// Update adds a value; the first value sets the mean
+func (e *EWMA) Update(value float64) {
+	if e.Count == 0 {
+		e.Mean = value
+		e.Variance = 0
+	} else {
+		diff := value - e.Mean
+		increment := e.Alpha * diff
+		e.Mean += increment
+		e.Variance = (1 - e.Alpha) * (e.Variance + diff*increment)
+	}
+	e.Count++
+}
+
+// StdDev returns the standard deviation
+func (e *EWMA) StdDev() float64 {
+	return math.Sqrt(e.Variance)
+}
+
+// MedianMAD returns the median of the values and their median absolute
+// deviation from it, a spread estimate robust to outliers
+func MedianMAD(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	median := medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, value := range values {
+		deviations[i] = math.Abs(value - median)
+	}
+	return median, medianOf(deviations)
+}
+
+// medianOf returns the median of the values without reordering them
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// AnomalyDetector learns latency and error rate baselines per service
+// operation from ingested traces and flags the windows deviating from them
+type AnomalyDetector interface {
+	// Record counts a trace towards the current detection window
+	Record(trace *Trace)
+	// FindAnomalies returns the detected anomalies matching the query,
+	// newest first
+	FindAnomalies(query AnomalyQuery) []*Anomaly
+	// Start closes a detection window every interval until the context
+	// is cancelled
+	Start(ctx context.Context) error
+}
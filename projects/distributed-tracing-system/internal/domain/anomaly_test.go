@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEWMA_Update(t *testing.T) {
+	ewma := EWMA{Alpha: 0.5}
+	ewma.Update(10)
+	assert.Equal(t, 10.0, ewma.Mean)
+	assert.Zero(t, ewma.StdDev())
+
+	ewma.Update(20)
+	assert.Equal(t, 15.0, ewma.Mean)
+	assert.InDelta(t, 25, ewma.Variance, 1e-9)
+	assert.Equal(t, 2, ewma.Count)
+}
+
+func TestMedianMAD(t *testing.T) {
+	median, mad := MedianMAD([]float64{1, 2, 3, 4, 100})
+	assert.Equal(t, 3.0, median)
+	assert.Equal(t, 1.0, mad)
+
+	median, mad = MedianMAD([]float64{4, 1, 3, 2})
+	assert.Equal(t, 2.5, median)
+	assert.Equal(t, 1.0, mad)
+
+	median, mad = MedianMAD(nil)
+	assert.Zero(t, median)
+	assert.Zero(t, mad)
+}
+
+func TestAnomalyQuery_Matches(t *testing.T) {
+	end := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	anomaly := &Anomaly{Service: "checkout", Operation: "pay", Kind: AnomalyKindLatency, WindowEnd: end}
+
+	assert.True(t, AnomalyQuery{}.Matches(anomaly))
+	assert.True(t, AnomalyQuery{Service: "checkout", Operation: "pay", Kind: AnomalyKindLatency, Start: end, End: end}.Matches(anomaly))
+	assert.False(t, AnomalyQuery{Service: "payments"}.Matches(anomaly))
+	assert.False(t, AnomalyQuery{Operation: "refund"}.Matches(anomaly))
+	assert.False(t, AnomalyQuery{Kind: AnomalyKindErrorRate}.Matches(anomaly))
+	assert.False(t, AnomalyQuery{Start: end.Add(time.Second)}.Matches(anomaly))
+	assert.False(t, AnomalyQuery{End: end.Add(-time.Second)}.Matches(anomaly))
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or does not belong to the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TraceSortField is the field trace search results are ordered by
+type TraceSortField string
+
+const (
+	SortByStartTime TraceSortField = "start_time"
+	SortByDuration  TraceSortField = "duration"
+)
+
+// SortOrder is the direction results are ordered in
+type SortOrder string
+
+const (
+	SortDescending SortOrder = "desc"
+	SortAscending  SortOrder = "asc"
+)
+
+// CountMode selects how the total number of matching traces is computed
+type CountMode string
+
+const (
+	CountNone     CountMode = "none"
+	CountExact    CountMode = "exact"
+	CountEstimate CountMode = "estimate"
+)
+
+// TraceCount is the total number of traces matching search criteria
+type TraceCount struct {
+	Value     int64 `json:"value"`
+	Estimated bool  `json:"estimated"`
+}
+
+// TraceCountEstimator is implemented by repositories that can estimate the
+// number of matching traces more cheaply than counting them
+type TraceCountEstimator interface {
+	EstimateCount(ctx context.Context, criteria *SearchCriteria) (int64, error)
+}
+
+// TraceCursor marks the position after the last trace of a page for keyset
+// pagination on (sort field, trace ID)
+type TraceCursor struct {
+	SortBy    TraceSortField `json:"s"`
+	SortOrder SortOrder      `json:"o"`
+	// Value is the sort key of the last trace: start time in Unix
+	// nanoseconds or duration in nanoseconds
+	Value   int64   `json:"v"`
+	TraceID TraceID `json:"id"`
+}
+
+// NewTraceCursor returns the cursor positioned after the given trace
+func NewTraceCursor(trace *Trace, sortBy TraceSortField, order SortOrder) TraceCursor {
+	cursor := TraceCursor{SortBy: sortBy, SortOrder: order, TraceID: trace.ID}
+	if sortBy == SortByDuration {
+		cursor.Value = trace.Duration.Nanoseconds()
+	} else {
+		cursor.Value = trace.StartTime.UnixNano()
+	}
+	return cursor
+}
+
+// StartTime returns the cursor value as a start time
+func (c TraceCursor) StartTime() time.Time {
+	return time.Unix(0, c.Value).UTC()
+}
+
+// Encode returns the opaque token form of the cursor
+func (c TraceCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTraceCursor parses an opaque cursor token
+func DecodeTraceCursor(token string) (*TraceCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+
+	var cursor TraceCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+	if cursor.SortBy != SortByStartTime && cursor.SortBy != SortByDuration {
+		return nil, fmt.Errorf("%w: unknown sort field", ErrInvalidCursor)
+	}
+	if cursor.SortOrder != SortAscending && cursor.SortOrder != SortDescending {
+		return nil, fmt.Errorf("%w: unknown sort order", ErrInvalidCursor)
+	}
+	if cursor.TraceID == "" {
+		return nil, fmt.Errorf("%w: missing trace ID", ErrInvalidCursor)
+	}
+
+	return &cursor, nil
+}
+
+// NextTraceCursor returns the cursor for the page following the given
+// results, or nil when the results did not fill the page
+func NextTraceCursor(criteria *SearchCriteria, traces []*Trace) *TraceCursor {
+	if criteria == nil || criteria.Limit <= 0 || len(traces) < criteria.Limit {
+		return nil
+	}
+	cursor := NewTraceCursor(traces[len(traces)-1], criteria.SortField(), criteria.SortDirection())
+	return &cursor
+}
+
+// SortField returns the effective sort field, defaulting to start time
+func (sc *SearchCriteria) SortField() TraceSortField {
+	if sc.SortBy == "" {
+		return SortByStartTime
+	}
+	return sc.SortBy
+}
+
+// SortDirection returns the effective sort order, defaulting to newest first
+func (sc *SearchCriteria) SortDirection() SortOrder {
+	if sc.SortOrder == "" {
+		return SortDescending
+	}
+	return sc.SortOrder
+}
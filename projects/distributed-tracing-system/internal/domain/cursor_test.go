@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceCursor_RoundTrip(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 123456000, time.UTC)
+	trace := &Trace{ID: "trace1", StartTime: start, Duration: 250 * time.Millisecond}
+
+	cursor := NewTraceCursor(trace, SortByStartTime, SortDescending)
+	decoded, err := DecodeTraceCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+	assert.Equal(t, start, decoded.StartTime())
+
+	cursor = NewTraceCursor(trace, SortByDuration, SortAscending)
+	decoded, err = DecodeTraceCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, (250 * time.Millisecond).Nanoseconds(), decoded.Value)
+}
+
+func TestDecodeTraceCursor_Invalid(t *testing.T) {
+	tests := []string{
+		"not base64!",
+		"bm90IGpzb24",
+		TraceCursor{SortBy: "name", SortOrder: SortAscending, TraceID: "t"}.Encode(),
+		TraceCursor{SortBy: SortByDuration, SortOrder: "up", TraceID: "t"}.Encode(),
+		TraceCursor{SortBy: SortByDuration, SortOrder: SortAscending}.Encode(),
+	}
+
+	for _, token := range tests {
+		_, err := DecodeTraceCursor(token)
+		assert.True(t, errors.Is(err, ErrInvalidCursor), token)
+	}
+}
+
+func TestNextTraceCursor(t *testing.T) {
+	traces := []*Trace{
+		{ID: "a", StartTime: time.Unix(200, 0)},
+		{ID: "b", StartTime: time.Unix(100, 0)},
+	}
+
+	// A partial page has no next page
+	assert.Nil(t, NextTraceCursor(&SearchCriteria{Limit: 3}, traces))
+
+	cursor := NextTraceCursor(&SearchCriteria{Limit: 2}, traces)
+	require.NotNil(t, cursor)
+	assert.Equal(t, TraceID("b"), cursor.TraceID)
+	assert.Equal(t, SortByStartTime, cursor.SortBy)
+	assert.Equal(t, SortDescending, cursor.SortOrder)
+	assert.Equal(t, time.Unix(100, 0).UnixNano(), cursor.Value)
+}
@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DependencyCall is a span calling into a child span of another service
+type DependencyCall struct {
+	Caller    ServiceName
+	Callee    ServiceName
+	StartTime time.Time
+	Duration  time.Duration
+	Error     bool
+}
+
+// ExtractDependencyCalls returns a call for every span whose parent span in
+// the trace belongs to another service. The call takes the timing and
+// status of the child span, which is what the caller waited on.
+func ExtractDependencyCalls(trace *Trace) []DependencyCall {
+	services := make(map[SpanID]ServiceName, len(trace.Spans))
+	for _, span := range trace.Spans {
+		if _, ok := services[span.ID]; !ok {
+			services[span.ID] = span.Service
+		}
+	}
+
+	var calls []DependencyCall
+	for _, span := range trace.Spans {
+		if span.ParentID == nil {
+			continue
+		}
+		caller, ok := services[*span.ParentID]
+		if !ok || caller == span.Service {
+			continue
+		}
+
+		duration := span.Duration
+		if duration == 0 && span.EndTime.After(span.StartTime) {
+			duration = span.EndTime.Sub(span.StartTime)
+		}
+		calls = append(calls, DependencyCall{
+			Caller:    caller,
+			Callee:    span.Service,
+			StartTime: span.StartTime,
+			Duration:  duration,
+			Error:     span.Status == SpanStatusError,
+		})
+	}
+	return calls
+}
+
+// DependencyLink aggregates the calls from one service to another that
+// started within one time window
+type DependencyLink struct {
+	WindowStart time.Time     `json:"window_start"`
+	Caller      ServiceName   `json:"caller"`
+	Callee      ServiceName   `json:"callee"`
+	CallCount   int64         `json:"call_count"`
+	ErrorCount  int64         `json:"error_count"`
+	DurationSum time.Duration `json:"duration_sum"`
+	// Latencies counts the calls per latency bucket
+	Latencies LatencyHistogram `json:"latency_buckets"`
+}
+
+// NewDependencyLink returns an empty link for a window
+func NewDependencyLink(windowStart time.Time, caller, callee ServiceName) *DependencyLink {
+	return &DependencyLink{
+		WindowStart: windowStart,
+		Caller:      caller,
+		Callee:      callee,
+		Latencies:   NewLatencyHistogram(),
+	}
+}
+
+// Record adds a call to the link
+func (l *DependencyLink) Record(duration time.Duration, isError bool) {
+	l.CallCount++
+	if isError {
+		l.ErrorCount++
+	}
+	l.DurationSum += duration
+	l.Latencies.Observe(duration)
+}
+
+// Merge adds the calls of another link between the same services
+func (l *DependencyLink) Merge(other *DependencyLink) {
+	l.CallCount += other.CallCount
+	l.ErrorCount += other.ErrorCount
+	l.DurationSum += other.DurationSum
+	l.Latencies.Merge(other.Latencies)
+}
+
+// Clone returns a copy of the link that does not share its buckets
+func (l *DependencyLink) Clone() *DependencyLink {
+	clone := *l
+	clone.Latencies = l.Latencies.Clone()
+	return &clone
+}
+
+// Percentile estimates the latency below which the fraction q of the calls
+// completed
+func (l *DependencyLink) Percentile(q float64) time.Duration {
+	return l.Latencies.Percentile(q)
+}
+
+// DependencyStore is implemented by repositories that persist dependency
+// links
+type DependencyStore interface {
+	// SaveDependencies adds the links to the stored links of the same
+	// window, caller and callee
+	SaveDependencies(ctx context.Context, links []*DependencyLink) error
+	// FindDependencies returns the stored links whose window starts within
+	// [start, end)
+	FindDependencies(ctx context.Context, start, end time.Time) ([]*DependencyLink, error)
+}
+
+// DependencyEdge describes the calls from one service to another
+type DependencyEdge struct {
+	Caller      ServiceName   `json:"caller"`
+	Callee      ServiceName   `json:"callee"`
+	CallCount   int64         `json:"call_count"`
+	ErrorCount  int64         `json:"error_count"`
+	ErrorRate   float64       `json:"error_rate"`
+	MeanLatency time.Duration `json:"mean_latency"`
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+	P99         time.Duration `json:"p99"`
+}
+
+// DependencyNode is a service in the dependency graph
+type DependencyNode struct {
+	Service ServiceName `json:"service"`
+	// CallsIn and CallsOut count the calls received from and made to other services
+	CallsIn  int64 `json:"calls_in"`
+	CallsOut int64 `json:"calls_out"`
+}
+
+// DependencyGraph is the graph of calls between services over a time range
+type DependencyGraph struct {
+	Start time.Time        `json:"start"`
+	End   time.Time        `json:"end"`
+	Nodes []DependencyNode `json:"nodes"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+// BuildDependencyGraph merges the windows of each caller and callee pair
+// into one edge. Nodes and edges are ordered by service name.
+func BuildDependencyGraph(start, end time.Time, links []*DependencyLink) *DependencyGraph {
+	type edgeKey struct{ caller, callee ServiceName }
+
+	merged := make(map[edgeKey]*DependencyLink)
+	var keys []edgeKey
+	for _, link := range links {
+		key := edgeKey{link.Caller, link.Callee}
+		total, ok := merged[key]
+		if !ok {
+			total = NewDependencyLink(start, link.Caller, link.Callee)
+			merged[key] = total
+			keys = append(keys, key)
+		}
+		total.Merge(link)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].caller != keys[j].caller {
+			return keys[i].caller < keys[j].caller
+		}
+		return keys[i].callee < keys[j].callee
+	})
+
+	graph := &DependencyGraph{
+		Start: start,
+		End:   end,
+		Nodes: []DependencyNode{},
+		Edges: []DependencyEdge{},
+	}
+	nodes := make(map[ServiceName]*DependencyNode)
+	node := func(service ServiceName) *DependencyNode {
+		if n, ok := nodes[service]; ok {
+			return n
+		}
+		n := &DependencyNode{Service: service}
+		nodes[service] = n
+		return n
+	}
+
+	for _, key := range keys {
+		link := merged[key]
+		edge := DependencyEdge{
+			Caller:     link.Caller,
+			Callee:     link.Callee,
+			CallCount:  link.CallCount,
+			ErrorCount: link.ErrorCount,
+			P50:        link.Percentile(0.50),
+			P95:        link.Percentile(0.95),
+			P99:        link.Percentile(0.99),
+		}
+		if link.CallCount > 0 {
+			edge.ErrorRate = float64(link.ErrorCount) / float64(link.CallCount)
+			edge.MeanLatency = link.DurationSum / time.Duration(link.CallCount)
+		}
+		graph.Edges = append(graph.Edges, edge)
+
+		node(link.Caller).CallsOut += link.CallCount
+		node(link.Callee).CallsIn += link.CallCount
+	}
+
+	for _, n := range nodes {
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Service < graph.Nodes[j].Service
+	})
+
+	return graph
+}
+
+// DOT renders the graph in the Graphviz DOT language, labelling each edge
+// with its call count, error rate and p95 latency
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box];\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "\t%q;\n", string(node.Service))
+	}
+	for _, edge := range g.Edges {
+		label := fmt.Sprintf("%d calls\\n%.1f%% errors\\np95 %s", edge.CallCount, edge.ErrorRate*100, edge.P95)
+		attrs := fmt.Sprintf("label=\"%s\"", label)
+		if edge.ErrorCount > 0 {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [%s];\n", string(edge.Caller), string(edge.Callee), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// DependencyService derives service dependencies from traces and serves
+// the dependency graph
+type DependencyService interface {
+	// Record adds the cross-service calls of a trace to the current windows
+	Record(trace *Trace)
+	// GetDependencies returns the graph of the calls that started within [start, end)
+	GetDependencies(ctx context.Context, start, end time.Time) (*DependencyGraph, error)
+	// Start persists the recorded windows periodically until the context is cancelled
+	Start(ctx context.Context) error
+}
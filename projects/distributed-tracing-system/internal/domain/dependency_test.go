@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractDependencyCalls(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	payments := analysisTestSpan("payments", "root", "payments", start, 10, 40)
+	payments.Status = SpanStatusError
+	trace := &Trace{
+		ID: "trace1",
+		Spans: []Span{
+			analysisTestSpan("root", "", "checkout", start, 0, 100),
+			analysisTestSpan("render", "root", "checkout", start, 0, 5),
+			payments,
+			analysisTestSpan("db", "payments", "postgres", start, 15, 25),
+			analysisTestSpan("orphan", "missing", "emails", start, 20, 30),
+		},
+	}
+
+	calls := ExtractDependencyCalls(trace)
+
+	assert.Equal(t, []DependencyCall{
+		{Caller: "checkout", Callee: "payments", StartTime: start.Add(10 * time.Millisecond), Duration: 30 * time.Millisecond, Error: true},
+		{Caller: "payments", Callee: "postgres", StartTime: start.Add(15 * time.Millisecond), Duration: 10 * time.Millisecond},
+	}, calls)
+}
+
+func TestDependencyLink_Percentile(t *testing.T) {
+	link := NewDependencyLink(time.Time{}, "checkout", "payments")
+	assert.Zero(t, link.Percentile(0.5))
+
+	// 90 calls in (5ms, 10ms] and 10 in (100ms, 250ms]
+	for i := 0; i < 90; i++ {
+		link.Record(8*time.Millisecond, false)
+	}
+	for i := 0; i < 10; i++ {
+		link.Record(200*time.Millisecond, i == 0)
+	}
+
+	assert.Equal(t, int64(100), link.CallCount)
+	assert.Equal(t, int64(1), link.ErrorCount)
+	// The median is interpolated 50/90 of the way into its bucket
+	assert.InDelta(t, float64(7778*time.Microsecond), float64(link.Percentile(0.5)), float64(time.Microsecond))
+	assert.Equal(t, 175*time.Millisecond, link.Percentile(0.95))
+	assert.Equal(t, 250*time.Millisecond, link.Percentile(1))
+
+	// Calls above the last bound are reported at the last bound
+	slow := NewDependencyLink(time.Time{}, "checkout", "payments")
+	slow.Record(5*time.Minute, false)
+	assert.Equal(t, time.Minute, slow.Percentile(0.99))
+}
+
+func TestDependencyLink_Merge(t *testing.T) {
+	link := NewDependencyLink(time.Time{}, "checkout", "payments")
+	link.Record(time.Millisecond, false)
+
+	// Links loaded with fewer buckets are padded
+	other := &DependencyLink{CallCount: 2, ErrorCount: 1, DurationSum: 3 * time.Millisecond, Latencies: LatencyHistogram{2}}
+	link.Merge(other)
+
+	assert.Equal(t, int64(3), link.CallCount)
+	assert.Equal(t, int64(1), link.ErrorCount)
+	assert.Equal(t, 4*time.Millisecond, link.DurationSum)
+	assert.Equal(t, int64(3), link.Latencies[0])
+	assert.Len(t, link.Latencies, len(LatencyBounds)+1)
+}
+
+func TestBuildDependencyGraph(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	first := NewDependencyLink(start, "checkout", "payments")
+	first.Record(10*time.Millisecond, false)
+	second := NewDependencyLink(start.Add(time.Minute), "checkout", "payments")
+	second.Record(30*time.Millisecond, true)
+	inventory := NewDependencyLink(start, "checkout", "inventory")
+	inventory.Record(2*time.Millisecond, false)
+
+	graph := BuildDependencyGraph(start, end, []*DependencyLink{first, inventory, second})
+
+	assert.Equal(t, start, graph.Start)
+	assert.Equal(t, end, graph.End)
+	assert.Equal(t, []DependencyNode{
+		{Service: "checkout", CallsOut: 3},
+		{Service: "inventory", CallsIn: 1},
+		{Service: "payments", CallsIn: 2},
+	}, graph.Nodes)
+
+	require.Len(t, graph.Edges, 2)
+	assert.Equal(t, ServiceName("inventory"), graph.Edges[0].Callee)
+	edge := graph.Edges[1]
+	assert.Equal(t, ServiceName("payments"), edge.Callee)
+	assert.Equal(t, int64(2), edge.CallCount)
+	assert.Equal(t, int64(1), edge.ErrorCount)
+	assert.Equal(t, 0.5, edge.ErrorRate)
+	assert.Equal(t, 20*time.Millisecond, edge.MeanLatency)
+	assert.Equal(t, 10*time.Millisecond, edge.P50)
+	assert.Equal(t, 50*time.Millisecond, edge.P99.Round(time.Millisecond))
+
+	// Merging does not modify the stored links
+	assert.Equal(t, int64(1), first.CallCount)
+}
+
+func TestDependencyGraph_DOT(t *testing.T) {
+	link := NewDependencyLink(time.Time{}, "checkout", "payments")
+	link.Record(10*time.Millisecond, true)
+	graph := BuildDependencyGraph(time.Time{}, time.Time{}, []*DependencyLink{link})
+
+	dot := graph.DOT()
+
+	assert.True(t, strings.HasPrefix(dot, "digraph dependencies {\n"))
+	assert.Contains(t, dot, "\t\"checkout\";\n")
+	assert.Contains(t, dot, "\t\"payments\";\n")
+	assert.Contains(t, dot, `"checkout" -> "payments" [label="1 calls\n100.0% errors\np95 9.75ms", color=red];`)
+	assert.True(t, strings.HasSuffix(dot, "}\n"))
+}
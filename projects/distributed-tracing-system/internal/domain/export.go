@@ -0,0 +1,47 @@
+package domain
+
+import "context"
+
+// ExportResult is the outcome of exporting a trace to one backend
+type ExportResult string
+
+const (
+	// ExportResultSuccess means the backend accepted the trace
+	ExportResultSuccess ExportResult = "success"
+	// ExportResultFailed means the backend refused the trace after every retry
+	ExportResultFailed ExportResult = "failed"
+	// ExportResultDropped means the trace was dropped before it was sent,
+	// because the backend's queue was full or its circuit was open
+	ExportResultDropped ExportResult = "dropped"
+)
+
+// DropPolicy decides which trace is dropped when an export queue is full
+type DropPolicy string
+
+const (
+	// DropNewest drops the trace being queued
+	DropNewest DropPolicy = "newest"
+	// DropOldest drops the trace that has waited longest to make room
+	DropOldest DropPolicy = "oldest"
+)
+
+// CircuitState is the state of a backend's circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed sends traces to the backend
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen drops traces until the cooldown has passed
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen sends a single trace to probe whether the backend recovered
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// ExportFanout exports every trace to several backends. ExportTrace only
+// queues the trace, so a slow or failing backend never blocks the caller.
+type ExportFanout interface {
+	JaegerExporter
+	// Start sends queued traces until the context is cancelled, then sends
+	// whatever is still queued once
+	Start(ctx context.Context) error
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// LatencyBounds are the upper bounds of the buckets of a LatencyHistogram.
+// A final bucket counts the durations above the last bound.
+var LatencyBounds = []time.Duration{
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// LatencyHistogram counts durations per LatencyBounds bucket. Histograms
+// are mergeable, so percentiles can be estimated over any set of them.
+type LatencyHistogram []int64
+
+// NewLatencyHistogram returns an empty histogram
+func NewLatencyHistogram() LatencyHistogram {
+	return make(LatencyHistogram, len(LatencyBounds)+1)
+}
+
+// Observe counts a duration
+func (h *LatencyHistogram) Observe(duration time.Duration) {
+	bucket := sort.Search(len(LatencyBounds), func(i int) bool {
+		return duration <= LatencyBounds[i]
+	})
+	h.grow()
+	(*h)[bucket]++
+}
+
+// Merge adds the counts of another histogram. Negative counts subtract.
+func (h *LatencyHistogram) Merge(other LatencyHistogram) {
+	h.grow()
+	for i, count := range other {
+		if i < len(*h) {
+			(*h)[i] += count
+		}
+	}
+}
+
+// grow makes room for every bucket, for histograms loaded with fewer
+// buckets than are currently kept
+func (h *LatencyHistogram) grow() {
+	for len(*h) < len(LatencyBounds)+1 {
+		*h = append(*h, 0)
+	}
+}
+
+// Count returns the number of durations in the histogram
+func (h LatencyHistogram) Count() int64 {
+	var count int64
+	for _, n := range h {
+		count += n
+	}
+	return count
+}
+
+// Percentile estimates the duration below which the fraction q of the
+// durations fall, interpolating within the bucket it falls in. Durations
+// above the last bound are reported at the last bound.
+func (h LatencyHistogram) Percentile(q float64) time.Duration {
+	total := h.Count()
+	if total <= 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative int64
+	for i, count := range h {
+		if count <= 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if i >= len(LatencyBounds) {
+			break
+		}
+
+		var lower time.Duration
+		if i > 0 {
+			lower = LatencyBounds[i-1]
+		}
+		upper := LatencyBounds[i]
+		fraction := (rank - float64(cumulative)) / float64(count)
+		if fraction < 0 {
+			fraction = 0
+		}
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+	return LatencyBounds[len(LatencyBounds)-1]
+}
+
+// Clone returns a copy of the histogram
+func (h LatencyHistogram) Clone() LatencyHistogram {
+	return append(LatencyHistogram(nil), h...)
+}
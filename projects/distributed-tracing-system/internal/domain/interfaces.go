@@ -1,10 +1,26 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // PrometheusExporter defines the interface for Prometheus metrics export
 type PrometheusExporter interface {
 	RecordTraceMetrics(trace *Trace) error
+	RecordSamplingDecision(policy string, decision SamplingDecision)
+	RecordSampledTrace(decision SamplingDecision)
+	RecordTracesPurged(rule string, count int64)
+	RecordRetentionLag(lag time.Duration)
+	RecordWriteBatch(size int, duration time.Duration)
+	RecordWriteQueueDepth(depth int)
+	RecordWriteRejected()
+	RecordClockSkewAdjustment(service string, adjustment time.Duration)
+	RecordAnomaly(anomaly *Anomaly)
+	RecordExport(backend string, result ExportResult, duration time.Duration)
+	RecordExportRetry(backend string)
+	RecordExportQueueDepth(backend string, depth int)
+	RecordCircuitState(backend string, state CircuitState)
 }
 
 // KafkaProducer defines the interface for Kafka message publishing
@@ -12,11 +28,26 @@ type KafkaProducer interface {
 	PublishTraceEvent(ctx context.Context, trace *Trace) error
 }
 
+// AlertPublisher defines the interface for delivering SLO alerts
+type AlertPublisher interface {
+	PublishAlert(ctx context.Context, alert *SLOAlert) error
+}
+
+// AnomalyPublisher defines the interface for delivering detected anomalies
+type AnomalyPublisher interface {
+	PublishAnomaly(ctx context.Context, anomaly *Anomaly) error
+}
+
 // KafkaConsumer defines the interface for Kafka message consumption
 type KafkaConsumer interface {
 	Start(ctx context.Context, traceService TraceService) error
 }
 
+// KafkaSpanConsumer defines the interface for consuming individual span messages
+type KafkaSpanConsumer interface {
+	Start(ctx context.Context, assembler SpanAssembler) error
+}
+
 // JaegerExporter defines the interface for Jaeger trace export
 type JaegerExporter interface {
 	ExportTrace(ctx context.Context, trace *Trace) error
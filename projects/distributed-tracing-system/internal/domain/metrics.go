@@ -0,0 +1,237 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Resolutions of the trace rollups kept by repositories
+const (
+	RollupMinute = time.Minute
+	RollupHour   = time.Hour
+)
+
+// RollupResolutions lists every rollup resolution, finest first
+var RollupResolutions = []time.Duration{RollupMinute, RollupHour}
+
+// MetricsQuery selects the traces aggregated into trace metrics
+type MetricsQuery struct {
+	// Start and End select traces that started within [Start, End)
+	Start time.Time
+	End   time.Time
+	// Service restricts the metrics to one service when set
+	Service ServiceName
+}
+
+// TraceRollup aggregates the traces of one service and operation that
+// started within one bucket of a rollup resolution
+type TraceRollup struct {
+	Resolution  time.Duration
+	BucketStart time.Time
+	Service     ServiceName
+	Operation   OperationName
+	TraceCount  int64
+	SpanCount   int64
+	ErrorCount  int64
+	DurationSum time.Duration
+	Durations   LatencyHistogram
+}
+
+// NewTraceRollup returns an empty rollup for the bucket containing t
+func NewTraceRollup(resolution time.Duration, t time.Time, service ServiceName, operation OperationName) *TraceRollup {
+	return &TraceRollup{
+		Resolution:  resolution,
+		BucketStart: t.UTC().Truncate(resolution),
+		Service:     service,
+		Operation:   operation,
+		Durations:   NewLatencyHistogram(),
+	}
+}
+
+// TraceRollups returns the rollups a trace contributes to, one per
+// resolution. Traces that failed or timed out count as errors.
+func TraceRollups(trace *Trace) []*TraceRollup {
+	rollups := make([]*TraceRollup, 0, len(RollupResolutions))
+	for _, resolution := range RollupResolutions {
+		rollup := NewTraceRollup(resolution, trace.StartTime, trace.Service, trace.Operation)
+		rollup.TraceCount = 1
+		rollup.SpanCount = int64(len(trace.Spans))
+		if trace.Status == TraceStatusError || trace.Status == TraceStatusTimeout {
+			rollup.ErrorCount = 1
+		}
+		rollup.DurationSum = trace.Duration
+		rollup.Durations.Observe(trace.Duration)
+		rollups = append(rollups, rollup)
+	}
+	return rollups
+}
+
+// Merge adds the traces of another rollup of the same bucket
+func (r *TraceRollup) Merge(other *TraceRollup) {
+	r.TraceCount += other.TraceCount
+	r.SpanCount += other.SpanCount
+	r.ErrorCount += other.ErrorCount
+	r.DurationSum += other.DurationSum
+	r.Durations.Merge(other.Durations)
+}
+
+// Negate returns a rollup that removes the traces of r when merged, used
+// to take back the contribution of a trace that is saved again
+func (r *TraceRollup) Negate() *TraceRollup {
+	negated := *r
+	negated.TraceCount = -r.TraceCount
+	negated.SpanCount = -r.SpanCount
+	negated.ErrorCount = -r.ErrorCount
+	negated.DurationSum = -r.DurationSum
+	negated.Durations = make(LatencyHistogram, len(r.Durations))
+	for i, count := range r.Durations {
+		negated.Durations[i] = -count
+	}
+	return &negated
+}
+
+// TraceRollupStore is implemented by repositories that maintain trace
+// rollups as traces are saved
+type TraceRollupStore interface {
+	// FindRollups returns the rollups of a resolution whose bucket starts
+	// within [query.Start, query.End), restricted to query.Service when set
+	FindRollups(ctx context.Context, resolution time.Duration, query MetricsQuery) ([]*TraceRollup, error)
+}
+
+// traceStats accumulates the statistics shared by every level of the metrics
+type traceStats struct {
+	traces      int64
+	spans       int64
+	errors      int64
+	durationSum time.Duration
+	durations   LatencyHistogram
+}
+
+func (s *traceStats) add(rollup *TraceRollup) {
+	s.traces += rollup.TraceCount
+	s.spans += rollup.SpanCount
+	s.errors += rollup.ErrorCount
+	s.durationSum += rollup.DurationSum
+	s.durations.Merge(rollup.Durations)
+}
+
+// summary holds the statistics in their reported form
+type summary struct {
+	average    time.Duration
+	p50        time.Duration
+	p90        time.Duration
+	p99        time.Duration
+	errorRate  float64
+	throughput float64
+}
+
+func (s *traceStats) summarize(window time.Duration) summary {
+	var sum summary
+	if s.traces > 0 {
+		sum.average = s.durationSum / time.Duration(s.traces)
+		sum.errorRate = float64(s.errors) / float64(s.traces)
+	}
+	if window > 0 {
+		sum.throughput = float64(s.traces) / window.Seconds()
+	}
+	sum.p50 = s.durations.Percentile(0.50)
+	sum.p90 = s.durations.Percentile(0.90)
+	sum.p99 = s.durations.Percentile(0.99)
+	return sum
+}
+
+// BuildTraceMetrics aggregates rollups into metrics for the whole query
+// window, per service and per operation. Throughput is in traces per
+// second over the window; services are ordered by name and operations
+// busiest first.
+func BuildTraceMetrics(query MetricsQuery, rollups []*TraceRollup) *TraceMetrics {
+	window := query.End.Sub(query.Start)
+
+	var total traceStats
+	services := make(map[ServiceName]*traceStats)
+	operations := make(map[ServiceName]map[OperationName]*traceStats)
+	for _, rollup := range rollups {
+		if query.Service != "" && rollup.Service != query.Service {
+			continue
+		}
+		total.add(rollup)
+
+		if services[rollup.Service] == nil {
+			services[rollup.Service] = &traceStats{}
+			operations[rollup.Service] = make(map[OperationName]*traceStats)
+		}
+		services[rollup.Service].add(rollup)
+
+		if operations[rollup.Service][rollup.Operation] == nil {
+			operations[rollup.Service][rollup.Operation] = &traceStats{}
+		}
+		operations[rollup.Service][rollup.Operation].add(rollup)
+	}
+
+	overall := total.summarize(window)
+	metrics := &TraceMetrics{
+		Start:           query.Start,
+		End:             query.End,
+		TotalTraces:     total.traces,
+		TotalSpans:      total.spans,
+		AverageDuration: overall.average,
+		P50Duration:     overall.p50,
+		P90Duration:     overall.p90,
+		P99Duration:     overall.p99,
+		ErrorRate:       overall.errorRate,
+		Throughput:      overall.throughput,
+		Services:        []ServiceMetrics{},
+	}
+
+	for service, stats := range services {
+		if stats.traces <= 0 {
+			continue
+		}
+		sum := stats.summarize(window)
+		serviceMetrics := ServiceMetrics{
+			Service:         service,
+			TotalTraces:     stats.traces,
+			TotalSpans:      stats.spans,
+			AverageDuration: sum.average,
+			P50Duration:     sum.p50,
+			P90Duration:     sum.p90,
+			P99Duration:     sum.p99,
+			ErrorRate:       sum.errorRate,
+			Throughput:      sum.throughput,
+			Operations:      []OperationMetrics{},
+		}
+
+		for operation, opStats := range operations[service] {
+			if opStats.traces <= 0 {
+				continue
+			}
+			opSum := opStats.summarize(window)
+			serviceMetrics.Operations = append(serviceMetrics.Operations, OperationMetrics{
+				Operation:       operation,
+				TotalTraces:     opStats.traces,
+				TotalSpans:      opStats.spans,
+				AverageDuration: opSum.average,
+				P50Duration:     opSum.p50,
+				P90Duration:     opSum.p90,
+				P99Duration:     opSum.p99,
+				ErrorRate:       opSum.errorRate,
+				Throughput:      opSum.throughput,
+			})
+		}
+		sort.Slice(serviceMetrics.Operations, func(i, j int) bool {
+			a, b := serviceMetrics.Operations[i], serviceMetrics.Operations[j]
+			if a.TotalTraces != b.TotalTraces {
+				return a.TotalTraces > b.TotalTraces
+			}
+			return a.Operation < b.Operation
+		})
+
+		metrics.Services = append(metrics.Services, serviceMetrics)
+	}
+	sort.Slice(metrics.Services, func(i, j int) bool {
+		return metrics.Services[i].Service < metrics.Services[j].Service
+	})
+
+	return metrics
+}
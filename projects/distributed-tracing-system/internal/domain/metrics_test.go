@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricsTestRollup returns a minute rollup of count traces with the same duration
+func metricsTestRollup(start time.Time, service ServiceName, operation OperationName, count, errors int64, duration time.Duration) *TraceRollup {
+	rollup := NewTraceRollup(RollupMinute, start, service, operation)
+	for i := int64(0); i < count; i++ {
+		rollup.TraceCount++
+		rollup.SpanCount += 2
+		rollup.DurationSum += duration
+		rollup.Durations.Observe(duration)
+	}
+	rollup.ErrorCount = errors
+	return rollup
+}
+
+func TestTraceRollups(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 42, 17, 0, time.FixedZone("CET", 3600))
+	trace := &Trace{
+		Service:   "checkout",
+		Operation: "pay",
+		StartTime: start,
+		Duration:  30 * time.Millisecond,
+		Status:    TraceStatusTimeout,
+		Spans:     []Span{{ID: "a"}, {ID: "b"}},
+	}
+
+	rollups := TraceRollups(trace)
+
+	require.Len(t, rollups, len(RollupResolutions))
+	assert.Equal(t, time.Date(2024, 1, 1, 9, 42, 0, 0, time.UTC), rollups[0].BucketStart)
+	assert.Equal(t, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), rollups[1].BucketStart)
+	for _, rollup := range rollups {
+		assert.Equal(t, int64(1), rollup.TraceCount)
+		assert.Equal(t, int64(2), rollup.SpanCount)
+		assert.Equal(t, int64(1), rollup.ErrorCount)
+		assert.Equal(t, 30*time.Millisecond, rollup.DurationSum)
+		assert.Equal(t, int64(1), rollup.Durations.Count())
+	}
+}
+
+func TestTraceRollup_Negate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	rollup := metricsTestRollup(start, "checkout", "pay", 3, 1, 20*time.Millisecond)
+
+	total := metricsTestRollup(start, "checkout", "pay", 1, 0, time.Second)
+	total.Merge(rollup)
+	total.Merge(rollup.Negate())
+
+	assert.Equal(t, int64(1), total.TraceCount)
+	assert.Equal(t, int64(2), total.SpanCount)
+	assert.Equal(t, int64(0), total.ErrorCount)
+	assert.Equal(t, time.Second, total.DurationSum)
+	assert.Equal(t, int64(1), total.Durations.Count())
+	assert.Equal(t, int64(3), rollup.TraceCount, "negating should not modify the rollup")
+}
+
+func TestBuildTraceMetrics(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	query := MetricsQuery{Start: start, End: start.Add(10 * time.Second)}
+	rollups := []*TraceRollup{
+		metricsTestRollup(start, "payments", "charge", 10, 5, 50*time.Millisecond),
+		metricsTestRollup(start, "checkout", "pay", 20, 0, 10*time.Millisecond),
+		metricsTestRollup(start.Add(time.Minute), "checkout", "pay", 10, 0, 10*time.Millisecond),
+		metricsTestRollup(start, "checkout", "cart", 10, 2, 100*time.Millisecond),
+	}
+
+	metrics := BuildTraceMetrics(query, rollups)
+
+	assert.Equal(t, start, metrics.Start)
+	assert.Equal(t, int64(50), metrics.TotalTraces)
+	assert.Equal(t, int64(100), metrics.TotalSpans)
+	assert.InDelta(t, 7.0/50, metrics.ErrorRate, 1e-9)
+	assert.InDelta(t, 5.0, metrics.Throughput, 1e-9)
+	assert.Equal(t, 36*time.Millisecond, metrics.AverageDuration)
+	assert.True(t, metrics.P50Duration > 5*time.Millisecond && metrics.P50Duration <= 10*time.Millisecond)
+	assert.True(t, metrics.P99Duration > 50*time.Millisecond && metrics.P99Duration <= 100*time.Millisecond)
+
+	require.Len(t, metrics.Services, 2)
+	checkout := metrics.Services[0]
+	assert.Equal(t, ServiceName("checkout"), checkout.Service)
+	assert.Equal(t, int64(40), checkout.TotalTraces)
+	assert.InDelta(t, 2.0/40, checkout.ErrorRate, 1e-9)
+	require.Len(t, checkout.Operations, 2)
+	assert.Equal(t, OperationName("pay"), checkout.Operations[0].Operation)
+	assert.Equal(t, int64(30), checkout.Operations[0].TotalTraces)
+	assert.InDelta(t, 3.0, checkout.Operations[0].Throughput, 1e-9)
+	assert.Equal(t, OperationName("cart"), checkout.Operations[1].Operation)
+	assert.Equal(t, ServiceName("payments"), metrics.Services[1].Service)
+	assert.InDelta(t, 0.5, metrics.Services[1].ErrorRate, 1e-9)
+
+	query.Service = "payments"
+	metrics = BuildTraceMetrics(query, rollups)
+	assert.Equal(t, int64(10), metrics.TotalTraces)
+	require.Len(t, metrics.Services, 1)
+}
+
+func TestBuildTraceMetrics_Empty(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	metrics := BuildTraceMetrics(MetricsQuery{Start: start, End: start.Add(time.Hour)}, nil)
+
+	assert.Zero(t, metrics.TotalTraces)
+	assert.Zero(t, metrics.ErrorRate)
+	assert.Zero(t, metrics.P99Duration)
+	assert.NotNil(t, metrics.Services)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MigrationStatus describes a versioned schema migration
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	// AppliedAt is when the migration was applied, nil while it is pending
+	AppliedAt *time.Time
+}
+
+// SchemaMigrator applies and reverts versioned schema migrations
+type SchemaMigrator interface {
+	// Up applies all pending migrations in order and returns how many were applied
+	Up(ctx context.Context) (int, error)
+	// Down reverts up to steps applied migrations, newest first, and
+	// returns how many were reverted
+	Down(ctx context.Context, steps int) (int, error)
+	// Status lists the known migrations in version order
+	Status(ctx context.Context) ([]MigrationStatus, error)
+}
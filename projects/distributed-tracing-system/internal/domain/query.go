@@ -0,0 +1,572 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TraceQuery is a parsed trace query such as
+//
+//	service=checkout AND duration>500ms AND span{service="payments" status=error}
+//
+// Top-level comparisons apply to the trace; comparisons inside span{...}
+// apply to individual spans, and the block matches when any span of the
+// trace satisfies all of them. Adjacent terms without an operator are
+// combined with AND.
+type TraceQuery struct {
+	Raw  string
+	Root QueryNode
+}
+
+// QueryNode is a node of the trace query AST
+type QueryNode interface {
+	isQueryNode()
+}
+
+// QueryLogicalOperator combines two query nodes
+type QueryLogicalOperator string
+
+const (
+	QueryAnd QueryLogicalOperator = "AND"
+	QueryOr  QueryLogicalOperator = "OR"
+)
+
+// QueryOperator compares a field with a value
+type QueryOperator string
+
+const (
+	QueryOpEqual        QueryOperator = "="
+	QueryOpNotEqual     QueryOperator = "!="
+	QueryOpGreater      QueryOperator = ">"
+	QueryOpGreaterEqual QueryOperator = ">="
+	QueryOpLess         QueryOperator = "<"
+	QueryOpLessEqual    QueryOperator = "<="
+	QueryOpMatch        QueryOperator = "=~"
+	QueryOpNotMatch     QueryOperator = "!~"
+)
+
+// QueryField identifies the attribute a comparison applies to
+type QueryField string
+
+const (
+	QueryFieldService   QueryField = "service"
+	QueryFieldOperation QueryField = "operation"
+	QueryFieldStatus    QueryField = "status"
+	QueryFieldDuration  QueryField = "duration"
+	QueryFieldTag       QueryField = "tag"
+)
+
+// QueryLogical is a binary AND/OR node
+type QueryLogical struct {
+	Operator QueryLogicalOperator
+	Left     QueryNode
+	Right    QueryNode
+}
+
+// QueryNot negates its operand
+type QueryNot struct {
+	Operand QueryNode
+}
+
+// QuerySpanMatch matches traces with at least one span satisfying Condition.
+// A nil Condition matches any trace with spans.
+type QuerySpanMatch struct {
+	Condition QueryNode
+}
+
+// QueryComparison compares a trace or span field with a literal value
+type QueryComparison struct {
+	Field    QueryField
+	TagKey   string
+	Operator QueryOperator
+	Value    string
+	Duration time.Duration
+	Regex    *regexp.Regexp
+	Position int
+}
+
+func (*QueryLogical) isQueryNode()    {}
+func (*QueryNot) isQueryNode()        {}
+func (*QuerySpanMatch) isQueryNode()  {}
+func (*QueryComparison) isQueryNode() {}
+
+// QuerySyntaxError reports a malformed query and where the problem was found
+type QuerySyntaxError struct {
+	// Position is the zero-based byte offset in the query
+	Position int
+	Message  string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Position, e.Message)
+}
+
+// ParseTraceQuery parses a trace query
+func ParseTraceQuery(input string) (*TraceQuery, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == queryTokenEOF {
+		return nil, &QuerySyntaxError{Position: 0, Message: "query is empty"}
+	}
+
+	root, err := p.parseOr(false)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != queryTokenEOF {
+		return nil, &QuerySyntaxError{Position: tok.pos, Message: fmt.Sprintf("unexpected %s", tok.describe())}
+	}
+
+	return &TraceQuery{Raw: input, Root: root}, nil
+}
+
+// Matches reports whether the trace satisfies the query
+func (q *TraceQuery) Matches(trace *Trace) bool {
+	if q == nil || q.Root == nil || trace == nil {
+		return false
+	}
+	return evalQueryNode(q.Root, trace, nil)
+}
+
+// HasSpanMatch reports whether evaluating the query requires span data
+func (q *TraceQuery) HasSpanMatch() bool {
+	return q != nil && queryNodeHasSpanMatch(q.Root)
+}
+
+func queryNodeHasSpanMatch(node QueryNode) bool {
+	switch n := node.(type) {
+	case *QueryLogical:
+		return queryNodeHasSpanMatch(n.Left) || queryNodeHasSpanMatch(n.Right)
+	case *QueryNot:
+		return queryNodeHasSpanMatch(n.Operand)
+	case *QuerySpanMatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// evalQueryNode evaluates a node against a trace, or against a single span
+// of that trace when span is not nil
+func evalQueryNode(node QueryNode, trace *Trace, span *Span) bool {
+	switch n := node.(type) {
+	case *QueryLogical:
+		if n.Operator == QueryAnd {
+			return evalQueryNode(n.Left, trace, span) && evalQueryNode(n.Right, trace, span)
+		}
+		return evalQueryNode(n.Left, trace, span) || evalQueryNode(n.Right, trace, span)
+	case *QueryNot:
+		return !evalQueryNode(n.Operand, trace, span)
+	case *QuerySpanMatch:
+		for i := range trace.Spans {
+			if n.Condition == nil || evalQueryNode(n.Condition, trace, &trace.Spans[i]) {
+				return true
+			}
+		}
+		return false
+	case *QueryComparison:
+		return n.matches(trace, span)
+	default:
+		return false
+	}
+}
+
+// matches evaluates the comparison against the span, or the trace if span is nil
+func (c *QueryComparison) matches(trace *Trace, span *Span) bool {
+	var value string
+	var present = true
+	var duration time.Duration
+
+	if span != nil {
+		switch c.Field {
+		case QueryFieldService:
+			value = string(span.Service)
+		case QueryFieldOperation:
+			value = string(span.Operation)
+		case QueryFieldStatus:
+			value = string(span.Status)
+		case QueryFieldDuration:
+			duration = span.Duration
+			if duration == 0 {
+				duration = span.EndTime.Sub(span.StartTime)
+			}
+		case QueryFieldTag:
+			value, present = span.Tags[c.TagKey]
+		}
+	} else {
+		switch c.Field {
+		case QueryFieldService:
+			value = string(trace.Service)
+		case QueryFieldOperation:
+			value = string(trace.Operation)
+		case QueryFieldStatus:
+			value = string(trace.Status)
+		case QueryFieldDuration:
+			duration = trace.Duration
+			if duration == 0 {
+				duration = trace.EndTime.Sub(trace.StartTime)
+			}
+		case QueryFieldTag:
+			value, present = trace.Tags[c.TagKey]
+		}
+	}
+
+	if c.Field == QueryFieldDuration {
+		switch c.Operator {
+		case QueryOpEqual:
+			return duration == c.Duration
+		case QueryOpNotEqual:
+			return duration != c.Duration
+		case QueryOpGreater:
+			return duration > c.Duration
+		case QueryOpGreaterEqual:
+			return duration >= c.Duration
+		case QueryOpLess:
+			return duration < c.Duration
+		case QueryOpLessEqual:
+			return duration <= c.Duration
+		}
+		return false
+	}
+
+	// A missing tag never equals a value and always differs from one
+	switch c.Operator {
+	case QueryOpEqual:
+		return present && value == c.Value
+	case QueryOpNotEqual:
+		return !present || value != c.Value
+	case QueryOpMatch:
+		return present && c.Regex.MatchString(value)
+	case QueryOpNotMatch:
+		return !present || !c.Regex.MatchString(value)
+	}
+	return false
+}
+
+// queryTokenKind identifies a lexical token of the query language
+type queryTokenKind int
+
+const (
+	queryTokenEOF queryTokenKind = iota
+	queryTokenWord
+	queryTokenString
+	queryTokenOperator
+	queryTokenLParen
+	queryTokenRParen
+	queryTokenLBrace
+	queryTokenRBrace
+)
+
+// queryToken is a lexical token with its position in the input
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+// describe returns a human readable description used in error messages
+func (t queryToken) describe() string {
+	switch t.kind {
+	case queryTokenEOF:
+		return "end of query"
+	case queryTokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// isKeyword reports whether the token is the given case-insensitive keyword
+func (t queryToken) isKeyword(keyword string) bool {
+	return t.kind == queryTokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// lexQuery splits the input into tokens
+func lexQuery(input string) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+
+	for i < len(input) {
+		ch := rune(input[i])
+		switch {
+		case unicode.IsSpace(ch) || ch == ',':
+			i++
+		case ch == '(':
+			tokens = append(tokens, queryToken{kind: queryTokenLParen, text: "(", pos: i})
+			i++
+		case ch == ')':
+			tokens = append(tokens, queryToken{kind: queryTokenRParen, text: ")", pos: i})
+			i++
+		case ch == '{':
+			tokens = append(tokens, queryToken{kind: queryTokenLBrace, text: "{", pos: i})
+			i++
+		case ch == '}':
+			tokens = append(tokens, queryToken{kind: queryTokenRBrace, text: "}", pos: i})
+			i++
+		case ch == '"' || ch == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(input) {
+				if input[i] == '\\' && i+1 < len(input) {
+					sb.WriteByte(input[i+1])
+					i += 2
+					continue
+				}
+				if rune(input[i]) == ch {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			if !closed {
+				return nil, &QuerySyntaxError{Position: start, Message: "unterminated string"}
+			}
+			tokens = append(tokens, queryToken{kind: queryTokenString, text: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>", ch):
+			start := i
+			op := string(ch)
+			if i+1 < len(input) && (input[i+1] == '=' || input[i+1] == '~') {
+				op += string(input[i+1])
+			}
+			i += len(op)
+			if op == "==" {
+				op = string(QueryOpEqual)
+			}
+			switch QueryOperator(op) {
+			case QueryOpEqual, QueryOpNotEqual, QueryOpGreater, QueryOpGreaterEqual,
+				QueryOpLess, QueryOpLessEqual, QueryOpMatch, QueryOpNotMatch:
+			default:
+				return nil, &QuerySyntaxError{Position: start, Message: fmt.Sprintf("unknown operator %q", input[start:i])}
+			}
+			tokens = append(tokens, queryToken{kind: queryTokenOperator, text: op, pos: start})
+		default:
+			start := i
+			for i < len(input) && !isQueryDelimiter(rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: queryTokenWord, text: input[start:i], pos: start})
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: queryTokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+// isQueryDelimiter reports whether the character ends a bare word
+func isQueryDelimiter(ch rune) bool {
+	return unicode.IsSpace(ch) || strings.ContainsRune("(){}\"'=!<>,", ch)
+}
+
+// queryParser is a recursive descent parser over query tokens
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != queryTokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *queryParser) parseOr(inSpan bool) (QueryNode, error) {
+	left, err := p.parseAnd(inSpan)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd(inSpan)
+		if err != nil {
+			return nil, err
+		}
+		left = &QueryLogical{Operator: QueryOr, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses: unary (["AND"] unary)*
+func (p *queryParser) parseAnd(inSpan bool) (QueryNode, error) {
+	left, err := p.parseUnary(inSpan)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.isKeyword("AND") {
+			p.next()
+		} else if tok.kind == queryTokenEOF || tok.kind == queryTokenRParen || tok.kind == queryTokenRBrace || tok.isKeyword("OR") {
+			return left, nil
+		}
+
+		right, err := p.parseUnary(inSpan)
+		if err != nil {
+			return nil, err
+		}
+		left = &QueryLogical{Operator: QueryAnd, Left: left, Right: right}
+	}
+}
+
+// parseUnary parses: "NOT" unary | primary
+func (p *queryParser) parseUnary(inSpan bool) (QueryNode, error) {
+	if p.peek().isKeyword("NOT") {
+		p.next()
+		operand, err := p.parseUnary(inSpan)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNot{Operand: operand}, nil
+	}
+	return p.parsePrimary(inSpan)
+}
+
+// parsePrimary parses: "(" or ")" | "span" "{" [or] "}" | comparison
+func (p *queryParser) parsePrimary(inSpan bool) (QueryNode, error) {
+	tok := p.peek()
+
+	switch {
+	case tok.kind == queryTokenLParen:
+		p.next()
+		node, err := p.parseOr(inSpan)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != queryTokenRParen {
+			return nil, &QuerySyntaxError{Position: closing.pos, Message: fmt.Sprintf("expected \")\" but found %s", closing.describe())}
+		}
+		return node, nil
+
+	case tok.isKeyword("span") && p.tokens[p.pos+1].kind == queryTokenLBrace:
+		if inSpan {
+			return nil, &QuerySyntaxError{Position: tok.pos, Message: "span blocks cannot be nested"}
+		}
+		p.next()
+		p.next()
+		match := &QuerySpanMatch{}
+		if p.peek().kind != queryTokenRBrace {
+			condition, err := p.parseOr(true)
+			if err != nil {
+				return nil, err
+			}
+			match.Condition = condition
+		}
+		if closing := p.next(); closing.kind != queryTokenRBrace {
+			return nil, &QuerySyntaxError{Position: closing.pos, Message: fmt.Sprintf("expected \"}\" but found %s", closing.describe())}
+		}
+		return match, nil
+
+	case tok.kind == queryTokenWord:
+		return p.parseComparison(inSpan)
+
+	default:
+		return nil, &QuerySyntaxError{Position: tok.pos, Message: fmt.Sprintf("expected a condition but found %s", tok.describe())}
+	}
+}
+
+// parseComparison parses: field operator value
+func (p *queryParser) parseComparison(inSpan bool) (QueryNode, error) {
+	fieldTok := p.next()
+	comparison := &QueryComparison{Position: fieldTok.pos}
+
+	name := fieldTok.text
+	switch {
+	case strings.HasPrefix(name, "tag."):
+		comparison.Field = QueryFieldTag
+		comparison.TagKey = strings.TrimPrefix(name, "tag.")
+		if comparison.TagKey == "" {
+			return nil, &QuerySyntaxError{Position: fieldTok.pos, Message: "tag key is required after \"tag.\""}
+		}
+	case name == string(QueryFieldService), name == string(QueryFieldOperation),
+		name == string(QueryFieldStatus), name == string(QueryFieldDuration):
+		comparison.Field = QueryField(name)
+	default:
+		return nil, &QuerySyntaxError{
+			Position: fieldTok.pos,
+			Message:  fmt.Sprintf("unknown field %q: expected service, operation, status, duration or tag.<key>", name),
+		}
+	}
+
+	opTok := p.next()
+	if opTok.kind != queryTokenOperator {
+		return nil, &QuerySyntaxError{Position: opTok.pos, Message: fmt.Sprintf("expected an operator after %q but found %s", name, opTok.describe())}
+	}
+	comparison.Operator = QueryOperator(opTok.text)
+
+	valueTok := p.next()
+	if valueTok.kind != queryTokenWord && valueTok.kind != queryTokenString {
+		return nil, &QuerySyntaxError{Position: valueTok.pos, Message: fmt.Sprintf("expected a value after %q but found %s", opTok.text, valueTok.describe())}
+	}
+	comparison.Value = valueTok.text
+
+	if err := comparison.resolve(inSpan, opTok.pos, valueTok.pos); err != nil {
+		return nil, err
+	}
+
+	return comparison, nil
+}
+
+// resolve validates the operator and value for the field and pre-parses them
+func (c *QueryComparison) resolve(inSpan bool, opPos, valuePos int) error {
+	if c.Field == QueryFieldDuration {
+		if c.Operator == QueryOpMatch || c.Operator == QueryOpNotMatch {
+			return &QuerySyntaxError{Position: opPos, Message: fmt.Sprintf("operator %s is not supported for duration", c.Operator)}
+		}
+		duration, err := time.ParseDuration(c.Value)
+		if err != nil || duration < 0 {
+			return &QuerySyntaxError{Position: valuePos, Message: fmt.Sprintf("invalid duration %q: use values such as 250ms or 2s", c.Value)}
+		}
+		c.Duration = duration
+		return nil
+	}
+
+	switch c.Operator {
+	case QueryOpEqual, QueryOpNotEqual:
+	case QueryOpMatch, QueryOpNotMatch:
+		re, err := regexp.Compile(c.Value)
+		if err != nil {
+			return &QuerySyntaxError{Position: valuePos, Message: fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		c.Regex = re
+	default:
+		return &QuerySyntaxError{Position: opPos, Message: fmt.Sprintf("operator %s is only supported for duration", c.Operator)}
+	}
+
+	if c.Field == QueryFieldStatus && c.Regex == nil {
+		if inSpan {
+			switch SpanStatus(c.Value) {
+			case SpanStatusOK, SpanStatusError:
+			default:
+				return &QuerySyntaxError{Position: valuePos, Message: fmt.Sprintf("invalid span status %q: expected ok or error", c.Value)}
+			}
+		} else {
+			switch TraceStatus(c.Value) {
+			case TraceStatusSuccess, TraceStatusError, TraceStatusTimeout:
+			default:
+				return &QuerySyntaxError{Position: valuePos, Message: fmt.Sprintf("invalid trace status %q: expected success, error or timeout", c.Value)}
+			}
+		}
+	}
+
+	return nil
+}
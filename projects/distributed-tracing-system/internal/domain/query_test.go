@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queryTestTrace() *Trace {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &Trace{
+		ID:        "trace1",
+		Service:   "checkout",
+		Operation: "POST /checkout",
+		StartTime: start,
+		EndTime:   start.Add(800 * time.Millisecond),
+		Duration:  800 * time.Millisecond,
+		Status:    TraceStatusError,
+		Tags:      map[string]string{"env": "prod"},
+		Spans: []Span{
+			{
+				ID: "a", TraceID: "trace1", Service: "checkout", Operation: "POST /checkout",
+				Duration: 800 * time.Millisecond, Status: SpanStatusOK,
+			},
+			{
+				ID: "b", TraceID: "trace1", Service: "payments", Operation: "charge",
+				Duration: 300 * time.Millisecond, Status: SpanStatusError,
+				Tags: map[string]string{"http.method": "POST"},
+			},
+		},
+	}
+}
+
+func TestParseTraceQuery_Matches(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`service=checkout AND duration>500ms AND span{service="payments" status=error}`, true},
+		{`service=checkout duration>500ms`, true},
+		{`service=checkout AND duration>1s`, false},
+		{`service=inventory OR status=error`, true},
+		{`NOT service=checkout`, false},
+		{`(service=inventory OR operation="POST /checkout") AND tag.env=prod`, true},
+		{`tag.env!=staging`, true},
+		{`tag.missing!=x`, true},
+		{`tag.missing=x`, false},
+		{`operation=~"^POST "`, true},
+		{`operation!~checkout`, false},
+		{`span{service=payments status=ok}`, false},
+		{`span{service=payments} AND span{status=ok}`, true},
+		{`span{tag.http.method=POST duration>=300ms}`, true},
+		{`span{duration<100ms}`, false},
+		{`span{}`, true},
+		{`status=success`, false},
+	}
+
+	trace := queryTestTrace()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := ParseTraceQuery(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query.Matches(trace))
+		})
+	}
+}
+
+func TestParseTraceQuery_AST(t *testing.T) {
+	query, err := ParseTraceQuery(`service=checkout OR duration>500ms AND span{status=error}`)
+	require.NoError(t, err)
+
+	// AND binds tighter than OR
+	or, ok := query.Root.(*QueryLogical)
+	require.True(t, ok)
+	assert.Equal(t, QueryOr, or.Operator)
+
+	left, ok := or.Left.(*QueryComparison)
+	require.True(t, ok)
+	assert.Equal(t, QueryFieldService, left.Field)
+	assert.Equal(t, "checkout", left.Value)
+
+	and, ok := or.Right.(*QueryLogical)
+	require.True(t, ok)
+	assert.Equal(t, QueryAnd, and.Operator)
+
+	duration, ok := and.Left.(*QueryComparison)
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, duration.Duration)
+
+	_, ok = and.Right.(*QuerySpanMatch)
+	assert.True(t, ok)
+	assert.True(t, query.HasSpanMatch())
+}
+
+func TestParseTraceQuery_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		query    string
+		position int
+		message  string
+	}{
+		{``, 0, "query is empty"},
+		{`service=`, 8, "expected a value"},
+		{`service checkout`, 8, "expected an operator"},
+		{`servce=checkout`, 0, "unknown field"},
+		{`service=checkout AND (status=error`, 34, `expected ")"`},
+		{`span{service=payments`, 21, `expected "}"`},
+		{`span{span{status=error}}`, 5, "cannot be nested"},
+		{`duration>fast`, 9, "invalid duration"},
+		{`service>checkout`, 7, "only supported for duration"},
+		{`status=broken`, 7, "invalid trace status"},
+		{`span{status=success}`, 12, "invalid span status"},
+		{`service="checkout`, 8, "unterminated string"},
+		{`service=checkout )`, 17, `unexpected ")"`},
+		{`operation=~"("`, 11, "invalid regular expression"},
+		{`service<~checkout`, 7, "unknown operator"},
+		{`service<>checkout`, 8, "expected a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseTraceQuery(tt.query)
+			require.Error(t, err)
+
+			var syntaxErr *QuerySyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+			assert.Equal(t, tt.position, syntaxErr.Position)
+			assert.Contains(t, syntaxErr.Message, tt.message)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPurgeInProgress is returned when a purge is requested while another
+// one is still deleting traces
+var ErrPurgeInProgress = errors.New("purge already in progress")
+
+// RetentionRule keeps the traces of a service and status for MaxAge. An
+// empty Service or Status matches any value; a zero MaxAge keeps the
+// matching traces forever.
+type RetentionRule struct {
+	Service ServiceName
+	Status  TraceStatus
+	MaxAge  time.Duration
+}
+
+// Matches reports whether the rule applies to the trace
+func (r RetentionRule) Matches(trace *Trace) bool {
+	if r.Service != "" && trace.Service != r.Service {
+		return false
+	}
+	return r.Status == "" || trace.Status == r.Status
+}
+
+// Name identifies the rule in reports and metrics as "service:status",
+// with "*" standing for any value
+func (r RetentionRule) Name() string {
+	service, status := "*", "*"
+	if r.Service != "" {
+		service = string(r.Service)
+	}
+	if r.Status != "" {
+		status = string(r.Status)
+	}
+	return service + ":" + status
+}
+
+// RetentionPolicy decides how long traces are kept. Rules are evaluated in
+// order and the first matching rule wins; traces matching no rule are kept
+// for DefaultMaxAge, or forever when it is zero.
+type RetentionPolicy struct {
+	DefaultMaxAge time.Duration
+	Rules         []RetentionRule
+}
+
+// MaxAge returns how long the trace is kept, zero meaning forever
+func (p RetentionPolicy) MaxAge(trace *Trace) time.Duration {
+	for _, rule := range p.Rules {
+		if rule.Matches(trace) {
+			return rule.MaxAge
+		}
+	}
+	return p.DefaultMaxAge
+}
+
+// LongestMaxAge returns the longest time any trace is kept, zero meaning
+// some traces are kept forever
+func (p RetentionPolicy) LongestMaxAge() time.Duration {
+	if p.DefaultMaxAge <= 0 {
+		return 0
+	}
+	longest := p.DefaultMaxAge
+	for _, rule := range p.Rules {
+		if rule.MaxAge <= 0 {
+			return 0
+		}
+		if rule.MaxAge > longest {
+			longest = rule.MaxAge
+		}
+	}
+	return longest
+}
+
+// PurgeTarget is the set of expired traces governed by one retention rule
+type PurgeTarget struct {
+	Rule     string
+	Criteria PurgeCriteria
+}
+
+// PurgeTargets returns the expired trace sets of every rule that does not
+// keep traces forever, as of now. Each target excludes the traces governed
+// by earlier rules so a trace is only ever purged under its own rule.
+func (p RetentionPolicy) PurgeTargets(now time.Time) []PurgeTarget {
+	var targets []PurgeTarget
+	for i, rule := range p.Rules {
+		if rule.MaxAge <= 0 {
+			continue
+		}
+		targets = append(targets, PurgeTarget{
+			Rule: rule.Name(),
+			Criteria: PurgeCriteria{
+				Service: rule.Service,
+				Status:  rule.Status,
+				Exclude: p.Rules[:i],
+				Before:  now.Add(-rule.MaxAge),
+			},
+		})
+	}
+
+	if p.DefaultMaxAge > 0 {
+		targets = append(targets, PurgeTarget{
+			Rule: "default",
+			Criteria: PurgeCriteria{
+				Exclude: p.Rules,
+				Before:  now.Add(-p.DefaultMaxAge),
+			},
+		})
+	}
+
+	return targets
+}
+
+// PurgeCriteria selects traces to delete
+type PurgeCriteria struct {
+	// Service and Status restrict the selection when set
+	Service ServiceName
+	Status  TraceStatus
+	// Exclude keeps traces matching any of these rules
+	Exclude []RetentionRule
+	// Before selects traces that started strictly before this time
+	Before time.Time
+	// Limit bounds how many traces a single purge call deletes, oldest
+	// first; zero means no limit
+	Limit int
+}
+
+// Matches reports whether the trace is selected by the criteria
+func (c PurgeCriteria) Matches(trace *Trace) bool {
+	if !trace.StartTime.Before(c.Before) {
+		return false
+	}
+	if c.Service != "" && trace.Service != c.Service {
+		return false
+	}
+	if c.Status != "" && trace.Status != c.Status {
+		return false
+	}
+	for _, rule := range c.Exclude {
+		if rule.Matches(trace) {
+			return false
+		}
+	}
+	return true
+}
+
+// PurgeStats summarizes the traces selected by purge criteria
+type PurgeStats struct {
+	Count int64
+	// Oldest is the start time of the oldest selected trace, zero when none is selected
+	Oldest time.Time
+}
+
+// TracePurger is implemented by repositories that can delete traces in
+// bounded batches for data retention
+type TracePurger interface {
+	// PurgeTraces deletes up to criteria.Limit selected traces with their
+	// spans, oldest first, and returns how many were deleted
+	PurgeTraces(ctx context.Context, criteria PurgeCriteria) (int64, error)
+	// PurgeStats counts the selected traces without deleting them,
+	// ignoring criteria.Limit
+	PurgeStats(ctx context.Context, criteria PurgeCriteria) (PurgeStats, error)
+}
+
+// PurgeReport describes the outcome of a purge run
+type PurgeReport struct {
+	DryRun     bool              `json:"dry_run"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Rules      []PurgeRuleReport `json:"rules"`
+	// Total is the number of traces purged, or that would be purged on a dry run
+	Total int64 `json:"total"`
+}
+
+// PurgeRuleReport describes the outcome of a purge run for one retention rule
+type PurgeRuleReport struct {
+	Rule   string    `json:"rule"`
+	Cutoff time.Time `json:"cutoff"`
+	// Purged is the number of traces deleted, or that would be deleted on a dry run
+	Purged int64 `json:"purged"`
+	// Remaining is the number of expired traces left after the run, when
+	// the run stopped at its batch limit
+	Remaining int64 `json:"remaining"`
+}
+
+// RetentionManager purges traces that are older than the retention policy allows
+type RetentionManager interface {
+	// Purge runs a purge immediately; a dry run only reports what would be deleted
+	Purge(ctx context.Context, dryRun bool) (*PurgeReport, error)
+	// Start purges periodically until the context is cancelled
+	Start(ctx context.Context) error
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionPolicy_MaxAge(t *testing.T) {
+	policy := RetentionPolicy{
+		DefaultMaxAge: 7 * 24 * time.Hour,
+		Rules: []RetentionRule{
+			{Service: "audit", MaxAge: 0},
+			{Status: TraceStatusError, MaxAge: 30 * 24 * time.Hour},
+			{Status: TraceStatusSuccess, MaxAge: 3 * 24 * time.Hour},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		trace    *Trace
+		expected time.Duration
+	}{
+		{"first matching rule wins", &Trace{Service: "audit", Status: TraceStatusError}, 0},
+		{"status rule", &Trace{Service: "checkout", Status: TraceStatusError}, 30 * 24 * time.Hour},
+		{"other status rule", &Trace{Service: "checkout", Status: TraceStatusSuccess}, 3 * 24 * time.Hour},
+		{"default", &Trace{Service: "checkout", Status: TraceStatusTimeout}, 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, policy.MaxAge(tt.trace))
+		})
+	}
+}
+
+func TestRetentionPolicy_LongestMaxAge(t *testing.T) {
+	week, month := 7*24*time.Hour, 30*24*time.Hour
+
+	tests := []struct {
+		name     string
+		policy   RetentionPolicy
+		expected time.Duration
+	}{
+		{"default only", RetentionPolicy{DefaultMaxAge: week}, week},
+		{"longer rule", RetentionPolicy{DefaultMaxAge: week, Rules: []RetentionRule{{Status: TraceStatusError, MaxAge: month}}}, month},
+		{"default keeps forever", RetentionPolicy{Rules: []RetentionRule{{Status: TraceStatusError, MaxAge: month}}}, 0},
+		{"rule keeps forever", RetentionPolicy{DefaultMaxAge: week, Rules: []RetentionRule{{Service: "audit"}}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.LongestMaxAge())
+		})
+	}
+}
+
+func TestRetentionPolicy_PurgeTargets(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	policy := RetentionPolicy{
+		DefaultMaxAge: 7 * 24 * time.Hour,
+		Rules: []RetentionRule{
+			{Service: "audit", MaxAge: 0},
+			{Service: "checkout", Status: TraceStatusError, MaxAge: 30 * 24 * time.Hour},
+			{Status: TraceStatusSuccess, MaxAge: 3 * 24 * time.Hour},
+		},
+	}
+
+	targets := policy.PurgeTargets(now)
+	require.Len(t, targets, 3)
+	assert.Equal(t, "checkout:error", targets[0].Rule)
+	assert.Equal(t, "*:success", targets[1].Rule)
+	assert.Equal(t, "default", targets[2].Rule)
+	assert.Equal(t, now.Add(-3*24*time.Hour), targets[1].Criteria.Before)
+
+	old := now.Add(-60 * 24 * time.Hour)
+	traces := map[string]*Trace{
+		"audit":          {Service: "audit", Status: TraceStatusSuccess, StartTime: old},
+		"checkout-error": {Service: "checkout", Status: TraceStatusError, StartTime: old},
+		"other-success":  {Service: "payments", Status: TraceStatusSuccess, StartTime: old},
+		"other-error":    {Service: "payments", Status: TraceStatusError, StartTime: old},
+	}
+
+	// Every expired trace is selected by exactly the target of its own rule
+	expected := map[string]string{
+		"checkout-error": "checkout:error",
+		"other-success":  "*:success",
+		"other-error":    "default",
+	}
+	for name, trace := range traces {
+		var selectedBy []string
+		for _, target := range targets {
+			if target.Criteria.Matches(trace) {
+				selectedBy = append(selectedBy, target.Rule)
+			}
+		}
+		if rule, ok := expected[name]; ok {
+			assert.Equal(t, []string{rule}, selectedBy, name)
+		} else {
+			assert.Empty(t, selectedBy, name)
+		}
+	}
+
+	// Traces newer than the cutoff are kept
+	recent := &Trace{Service: "payments", Status: TraceStatusSuccess, StartTime: now.Add(-time.Hour)}
+	assert.False(t, targets[1].Criteria.Matches(recent))
+}
@@ -0,0 +1,20 @@
+package domain
+
+// SamplingDecision is the outcome of evaluating a sampling policy
+type SamplingDecision string
+
+const (
+	SamplingDecisionSample    SamplingDecision = "sample"
+	SamplingDecisionNotSample SamplingDecision = "not_sample"
+)
+
+// SamplingPolicy evaluates a completed trace for tail-based sampling
+type SamplingPolicy interface {
+	Name() string
+	Evaluate(trace *Trace) SamplingDecision
+}
+
+// TailSampler decides whether a completed trace is persisted
+type TailSampler interface {
+	ShouldSample(trace *Trace) bool
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// Matches reports whether the trace satisfies the filters of the criteria.
+// Sorting, cursor and pagination are not taken into account. Repositories
+// that filter in memory use it to stay consistent with the SQL backend.
+func (sc *SearchCriteria) Matches(trace *Trace) bool {
+	if trace == nil {
+		return false
+	}
+	if sc.Service != nil && trace.Service != *sc.Service {
+		return false
+	}
+	if sc.Operation != nil && trace.Operation != *sc.Operation {
+		return false
+	}
+	if sc.StartTime != nil && trace.StartTime.Before(*sc.StartTime) {
+		return false
+	}
+	if sc.EndTime != nil && trace.StartTime.After(*sc.EndTime) {
+		return false
+	}
+	if sc.Status != nil && trace.Status != *sc.Status {
+		return false
+	}
+	if sc.MinDuration != nil && trace.Duration < *sc.MinDuration {
+		return false
+	}
+	if sc.MaxDuration != nil && trace.Duration > *sc.MaxDuration {
+		return false
+	}
+	if !containsTags(trace.Tags, sc.Tags) {
+		return false
+	}
+
+	for _, filter := range sc.SpanFilters {
+		matched := false
+		for i := range trace.Spans {
+			if filter.Matches(&trace.Spans[i]) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches reports whether the span satisfies every condition of the filter
+func (f SpanFilter) Matches(span *Span) bool {
+	if span == nil {
+		return false
+	}
+	if f.Service != nil && span.Service != *f.Service {
+		return false
+	}
+	if f.Operation != nil && span.Operation != *f.Operation {
+		return false
+	}
+	if f.Status != nil && span.Status != *f.Status {
+		return false
+	}
+	if f.MinDuration != nil && span.Duration < *f.MinDuration {
+		return false
+	}
+	if f.MaxDuration != nil && span.Duration > *f.MaxDuration {
+		return false
+	}
+	return containsTags(span.Tags, f.Tags)
+}
+
+// containsTags reports whether tags contains every key/value pair of wanted
+func containsTags(tags, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if actual, ok := tags[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// compareTraces orders two traces by the sort field with the trace ID as
+// tie-breaker, returning a negative number when a sorts first in ascending order
+func compareTraces(a, b *Trace, sortBy TraceSortField) int {
+	var av, bv int64
+	if sortBy == SortByDuration {
+		av, bv = a.Duration.Nanoseconds(), b.Duration.Nanoseconds()
+	} else {
+		av, bv = a.StartTime.UnixNano(), b.StartTime.UnixNano()
+	}
+
+	switch {
+	case av < bv:
+		return -1
+	case av > bv:
+		return 1
+	case a.ID < b.ID:
+		return -1
+	case a.ID > b.ID:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SortTraces sorts traces in the order used for search results and cursors
+func SortTraces(traces []*Trace, sortBy TraceSortField, order SortOrder) {
+	sort.Slice(traces, func(i, j int) bool {
+		cmp := compareTraces(traces[i], traces[j], sortBy)
+		if order == SortAscending {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+}
+
+// IsAfter reports whether the trace sorts strictly after the cursor position
+func (c TraceCursor) IsAfter(trace *Trace) bool {
+	position := &Trace{ID: c.TraceID}
+	if c.SortBy == SortByDuration {
+		position.Duration = time.Duration(c.Value)
+	} else {
+		position.StartTime = c.StartTime()
+	}
+
+	cmp := compareTraces(trace, position, c.SortBy)
+	if c.SortOrder == SortAscending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
@@ -0,0 +1,12 @@
+package domain
+
+// SkewAdjustmentTag is the span tag recording the clock skew correction
+// applied to a span, as the Go duration added to its timestamps
+const SkewAdjustmentTag = "clock_skew.adjustment"
+
+// SkewAdjuster corrects clock skew between the spans of a trace reported
+// by different services
+type SkewAdjuster interface {
+	// Adjust shifts skewed spans in place and returns how many were moved
+	Adjust(trace *Trace) int
+}
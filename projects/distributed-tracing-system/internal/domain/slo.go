@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrSLONotFound is returned when an SLO does not exist
+var ErrSLONotFound = errors.New("slo not found")
+
+// SLOBucketWidth is the time bucket traces are counted in when evaluating
+// SLOs, and so the finest burn rate window
+const SLOBucketWidth = time.Minute
+
+// SLO is a latency objective: the fraction of the traces of a service (and
+// optionally one operation) that must complete within Threshold over Window
+type SLO struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name"`
+	Service ServiceName `json:"service"`
+	// Operation restricts the SLO to one operation; empty covers every
+	// operation of the service
+	Operation OperationName `json:"operation,omitempty"`
+	// Threshold is the latency a trace must not exceed to count as good
+	Threshold time.Duration `json:"threshold"`
+	// Target is the objective ratio of good traces, e.g. 0.99
+	Target float64 `json:"target"`
+	// Window is the period the objective applies over, e.g. 30 days
+	Window    time.Duration `json:"window"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Validate checks that the SLO can be evaluated
+func (s *SLO) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("slo name is required")
+	}
+	if s.Service == "" {
+		return fmt.Errorf("slo service is required")
+	}
+	if s.Threshold <= 0 {
+		return fmt.Errorf("slo threshold must be positive")
+	}
+	if s.Target <= 0 || s.Target >= 1 {
+		return fmt.Errorf("slo target must be between 0 and 1, exclusive")
+	}
+	if s.Window < SLOBucketWidth {
+		return fmt.Errorf("slo window must be at least %s", SLOBucketWidth)
+	}
+	return nil
+}
+
+// Matches reports whether the trace counts towards the SLO
+func (s *SLO) Matches(trace *Trace) bool {
+	if trace.Service != s.Service {
+		return false
+	}
+	return s.Operation == "" || trace.Operation == s.Operation
+}
+
+// Good reports whether the trace met the latency threshold
+func (s *SLO) Good(trace *Trace) bool {
+	return trace.Duration <= s.Threshold
+}
+
+// SLOStore is implemented by repositories that persist SLO definitions
+// and their trace counts
+type SLOStore interface {
+	// SaveSLO creates or replaces the SLO with the same ID
+	SaveSLO(ctx context.Context, slo *SLO) error
+	// FindSLO returns ErrSLONotFound when the SLO does not exist
+	FindSLO(ctx context.Context, id string) (*SLO, error)
+	// ListSLOs returns every SLO ordered by name
+	ListSLOs(ctx context.Context) ([]*SLO, error)
+	// DeleteSLO removes the SLO and its buckets, and returns
+	// ErrSLONotFound when the SLO does not exist
+	DeleteSLO(ctx context.Context, id string) error
+	// AddSLOBuckets adds the counts of the buckets to the stored buckets
+	// of the SLO with the same start, so instances can add their counts
+	// to the same buckets
+	AddSLOBuckets(ctx context.Context, id string, buckets []SLOBucket) error
+	// FindSLOBuckets returns the stored buckets of the SLO starting at or
+	// after since, ordered by start
+	FindSLOBuckets(ctx context.Context, id string, since time.Time) ([]SLOBucket, error)
+	// DeleteSLOBuckets removes the stored buckets of the SLO starting
+	// before the given time; the zero time removes every bucket
+	DeleteSLOBuckets(ctx context.Context, id string, before time.Time) error
+}
+
+// SortSLOs orders SLOs by name, then by ID
+func SortSLOs(slos []*SLO) {
+	sort.Slice(slos, func(i, j int) bool {
+		if slos[i].Name != slos[j].Name {
+			return slos[i].Name < slos[j].Name
+		}
+		return slos[i].ID < slos[j].ID
+	})
+}
+
+// AlertSeverity tells how urgently a burning error budget needs attention
+type AlertSeverity string
+
+const (
+	AlertSeverityPage   AlertSeverity = "page"
+	AlertSeverityTicket AlertSeverity = "ticket"
+)
+
+// BurnRateRule fires when the error budget burns at least Threshold times
+// faster than sustainable over both the long and the short window. The
+// short window makes the alert stop soon after the burning stops.
+type BurnRateRule struct {
+	Severity    AlertSeverity `json:"severity"`
+	LongWindow  time.Duration `json:"long_window"`
+	ShortWindow time.Duration `json:"short_window"`
+	Threshold   float64       `json:"threshold"`
+}
+
+// BurnRateRules returns the multi-window rules for an SLO window. They
+// scale the usual rules of a 30 day objective (2% of the budget spent in
+// 1h, 5% in 6h, 10% in 3d, each checked against a window 12 times
+// shorter) to the given window, never going below one bucket.
+func BurnRateRules(window time.Duration) []BurnRateRule {
+	scale := func(fraction float64) time.Duration {
+		d := time.Duration(float64(window) * fraction).Truncate(SLOBucketWidth)
+		if d < SLOBucketWidth {
+			d = SLOBucketWidth
+		}
+		return d
+	}
+	return []BurnRateRule{
+		{Severity: AlertSeverityPage, LongWindow: scale(1.0 / 720), ShortWindow: scale(1.0 / 8640), Threshold: 14.4},
+		{Severity: AlertSeverityPage, LongWindow: scale(1.0 / 120), ShortWindow: scale(1.0 / 1440), Threshold: 6},
+		{Severity: AlertSeverityTicket, LongWindow: scale(1.0 / 10), ShortWindow: scale(1.0 / 120), Threshold: 1},
+	}
+}
+
+// BurnRate returns how many times faster than sustainable the error budget
+// is spent: the bad ratio divided by the ratio the target allows
+func BurnRate(total, good int64, target float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	bad := float64(total-good) / float64(total)
+	return bad / (1 - target)
+}
+
+// SLOBucket counts the traces of an SLO that started within one bucket
+type SLOBucket struct {
+	Start time.Time
+	Total int64
+	Good  int64
+}
+
+// BurnRateStatus is the current state of one burn rate rule
+type BurnRateStatus struct {
+	BurnRateRule
+	LongBurnRate  float64 `json:"long_burn_rate"`
+	ShortBurnRate float64 `json:"short_burn_rate"`
+	Firing        bool    `json:"firing"`
+}
+
+// SLOStatus is the evaluation of an SLO at a point in time
+type SLOStatus struct {
+	SLO         *SLO      `json:"slo"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+	TotalCount  int64     `json:"total_count"`
+	GoodCount   int64     `json:"good_count"`
+	// SLI is the ratio of good traces over the SLO window; 1 without traces
+	SLI float64 `json:"sli"`
+	// ErrorBudgetRemaining is the unspent fraction of the error budget,
+	// negative once the objective is missed
+	ErrorBudgetRemaining float64          `json:"error_budget_remaining"`
+	BurnRates            []BurnRateStatus `json:"burn_rates"`
+}
+
+// EvaluateSLO computes the status of an SLO at now from its buckets.
+// Buckets starting before the SLO window are ignored.
+func EvaluateSLO(slo *SLO, now time.Time, buckets []SLOBucket) *SLOStatus {
+	sum := func(window time.Duration) (int64, int64) {
+		cutoff := now.Add(-window)
+		var total, good int64
+		for _, bucket := range buckets {
+			if bucket.Start.Before(cutoff) || bucket.Start.After(now) {
+				continue
+			}
+			total += bucket.Total
+			good += bucket.Good
+		}
+		return total, good
+	}
+
+	status := &SLOStatus{
+		SLO:                  slo,
+		EvaluatedAt:          now,
+		SLI:                  1,
+		ErrorBudgetRemaining: 1,
+		BurnRates:            []BurnRateStatus{},
+	}
+	status.TotalCount, status.GoodCount = sum(slo.Window)
+	if status.TotalCount > 0 {
+		status.SLI = float64(status.GoodCount) / float64(status.TotalCount)
+		status.ErrorBudgetRemaining = 1 - BurnRate(status.TotalCount, status.GoodCount, slo.Target)
+	}
+
+	for _, rule := range BurnRateRules(slo.Window) {
+		rate := BurnRateStatus{BurnRateRule: rule}
+		total, good := sum(rule.LongWindow)
+		rate.LongBurnRate = BurnRate(total, good, slo.Target)
+		total, good = sum(rule.ShortWindow)
+		rate.ShortBurnRate = BurnRate(total, good, slo.Target)
+		rate.Firing = rate.LongBurnRate >= rule.Threshold && rate.ShortBurnRate >= rule.Threshold
+		status.BurnRates = append(status.BurnRates, rate)
+	}
+	return status
+}
+
+// AlertState is whether an alert started or stopped firing
+type AlertState string
+
+const (
+	AlertStateFiring   AlertState = "firing"
+	AlertStateResolved AlertState = "resolved"
+)
+
+// SLOAlert is published when a burn rate rule of an SLO starts or stops firing
+type SLOAlert struct {
+	SLOID                string        `json:"slo_id"`
+	SLOName              string        `json:"slo_name"`
+	Service              ServiceName   `json:"service"`
+	Operation            OperationName `json:"operation,omitempty"`
+	State                AlertState    `json:"state"`
+	Severity             AlertSeverity `json:"severity"`
+	LongWindow           time.Duration `json:"long_window"`
+	ShortWindow          time.Duration `json:"short_window"`
+	Threshold            float64       `json:"threshold"`
+	LongBurnRate         float64       `json:"long_burn_rate"`
+	ShortBurnRate        float64       `json:"short_burn_rate"`
+	ErrorBudgetRemaining float64       `json:"error_budget_remaining"`
+	Timestamp            time.Time     `json:"timestamp"`
+}
+
+// NewSLOAlert returns the alert for a burn rate rule of an evaluated SLO
+func NewSLOAlert(status *SLOStatus, rate BurnRateStatus, state AlertState) *SLOAlert {
+	return &SLOAlert{
+		SLOID:                status.SLO.ID,
+		SLOName:              status.SLO.Name,
+		Service:              status.SLO.Service,
+		Operation:            status.SLO.Operation,
+		State:                state,
+		Severity:             rate.Severity,
+		LongWindow:           rate.LongWindow,
+		ShortWindow:          rate.ShortWindow,
+		Threshold:            rate.Threshold,
+		LongBurnRate:         rate.LongBurnRate,
+		ShortBurnRate:        rate.ShortBurnRate,
+		ErrorBudgetRemaining: status.ErrorBudgetRemaining,
+		Timestamp:            status.EvaluatedAt,
+	}
+}
+
+// SLOService manages SLOs and evaluates them against ingested traces
+type SLOService interface {
+	CreateSLO(ctx context.Context, slo *SLO) (*SLO, error)
+	UpdateSLO(ctx context.Context, slo *SLO) (*SLO, error)
+	DeleteSLO(ctx context.Context, id string) error
+	GetSLO(ctx context.Context, id string) (*SLO, error)
+	ListSLOs(ctx context.Context) ([]*SLO, error)
+	// GetStatus evaluates an SLO against the traces counted so far
+	GetStatus(ctx context.Context, id string) (*SLOStatus, error)
+	// Record counts a trace towards the SLOs it matches
+	Record(trace *Trace)
+	// Start evaluates the SLOs periodically and publishes alerts until the
+	// context is cancelled
+	Start(ctx context.Context) error
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sloTestSLO() *SLO {
+	return &SLO{
+		ID:        "slo-1",
+		Name:      "checkout latency",
+		Service:   "checkout",
+		Threshold: 300 * time.Millisecond,
+		Target:    0.99,
+		Window:    30 * 24 * time.Hour,
+	}
+}
+
+func TestSLO_Validate(t *testing.T) {
+	require.NoError(t, sloTestSLO().Validate())
+
+	invalid := map[string]func(slo *SLO){
+		"name":      func(slo *SLO) { slo.Name = "" },
+		"service":   func(slo *SLO) { slo.Service = "" },
+		"threshold": func(slo *SLO) { slo.Threshold = 0 },
+		"target":    func(slo *SLO) { slo.Target = 1 },
+		"window":    func(slo *SLO) { slo.Window = time.Second },
+	}
+	for name, mutate := range invalid {
+		slo := sloTestSLO()
+		mutate(slo)
+		assert.Error(t, slo.Validate(), name)
+	}
+}
+
+func TestSLO_MatchesAndGood(t *testing.T) {
+	slo := sloTestSLO()
+	trace := &Trace{Service: "checkout", Operation: "pay", Duration: 300 * time.Millisecond}
+
+	assert.True(t, slo.Matches(trace))
+	assert.True(t, slo.Good(trace))
+	assert.False(t, slo.Matches(&Trace{Service: "payments", Operation: "pay"}))
+	assert.False(t, slo.Good(&Trace{Duration: 301 * time.Millisecond}))
+
+	slo.Operation = "refund"
+	assert.False(t, slo.Matches(trace))
+}
+
+func TestBurnRateRules(t *testing.T) {
+	rules := BurnRateRules(30 * 24 * time.Hour)
+	assert.Equal(t, []BurnRateRule{
+		{Severity: AlertSeverityPage, LongWindow: time.Hour, ShortWindow: 5 * time.Minute, Threshold: 14.4},
+		{Severity: AlertSeverityPage, LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, Threshold: 6},
+		{Severity: AlertSeverityTicket, LongWindow: 72 * time.Hour, ShortWindow: 6 * time.Hour, Threshold: 1},
+	}, rules)
+
+	// Short objectives never go below one bucket
+	for _, rule := range BurnRateRules(time.Hour) {
+		assert.GreaterOrEqual(t, rule.ShortWindow, SLOBucketWidth)
+	}
+}
+
+func TestBurnRate(t *testing.T) {
+	assert.Zero(t, BurnRate(0, 0, 0.99))
+	assert.InDelta(t, 1, BurnRate(100, 99, 0.99), 1e-9)
+	assert.InDelta(t, 10, BurnRate(100, 90, 0.99), 1e-9)
+}
+
+func TestEvaluateSLO(t *testing.T) {
+	slo := sloTestSLO()
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	buckets := []SLOBucket{
+		// Outside the window
+		{Start: now.Add(-31 * 24 * time.Hour), Total: 1000, Good: 0},
+		// Within the window, and only within the ticket rule's long window
+		{Start: now.Add(-48 * time.Hour), Total: 900, Good: 900},
+		// Within every burn rate window: all bad
+		{Start: now.Add(-2 * time.Minute), Total: 100, Good: 0},
+	}
+
+	status := EvaluateSLO(slo, now, buckets)
+
+	assert.Equal(t, int64(1000), status.TotalCount)
+	assert.Equal(t, int64(900), status.GoodCount)
+	assert.InDelta(t, 0.9, status.SLI, 1e-9)
+	assert.InDelta(t, -9, status.ErrorBudgetRemaining, 1e-9)
+	require.Len(t, status.BurnRates, 3)
+	for _, rate := range status.BurnRates[:2] {
+		assert.InDelta(t, 100, rate.LongBurnRate, 1e-9)
+		assert.InDelta(t, 100, rate.ShortBurnRate, 1e-9)
+		assert.True(t, rate.Firing)
+	}
+	assert.InDelta(t, 10, status.BurnRates[2].LongBurnRate, 1e-9)
+	assert.True(t, status.BurnRates[2].Firing)
+}
+
+func TestEvaluateSLO_NeedsBothWindows(t *testing.T) {
+	slo := sloTestSLO()
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	// Bad traces 30 minutes ago burn the long window, the short one is clean
+	buckets := []SLOBucket{
+		{Start: now.Add(-30 * time.Minute), Total: 100, Good: 0},
+		{Start: now.Add(-time.Minute), Total: 100, Good: 100},
+	}
+
+	status := EvaluateSLO(slo, now, buckets)
+
+	assert.InDelta(t, 50, status.BurnRates[0].LongBurnRate, 1e-9)
+	assert.Zero(t, status.BurnRates[0].ShortBurnRate)
+	assert.False(t, status.BurnRates[0].Firing)
+}
+
+func TestEvaluateSLO_NoTraces(t *testing.T) {
+	status := EvaluateSLO(sloTestSLO(), time.Now(), nil)
+	assert.Equal(t, 1.0, status.SLI)
+	assert.Equal(t, 1.0, status.ErrorBudgetRemaining)
+	for _, rate := range status.BurnRates {
+		assert.False(t, rate.Firing)
+	}
+}
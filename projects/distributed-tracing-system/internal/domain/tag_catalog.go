@@ -0,0 +1,17 @@
+package domain
+
+// ServiceNameTag is the tag name the service of a span is listed and
+// searched under, as in OpenTelemetry resources
+const ServiceNameTag = "service.name"
+
+// TagCatalog keeps the tag names and values seen in ingested traces, so
+// that search UIs can offer them without scanning the repository
+type TagCatalog interface {
+	// Record adds the tags of a trace and its spans, and the services of
+	// its spans as ServiceNameTag
+	Record(trace *Trace)
+	// TagNames returns the known tag names in alphabetical order
+	TagNames() []string
+	// TagValues returns the known values of a tag in alphabetical order
+	TagValues(name string) []string
+}
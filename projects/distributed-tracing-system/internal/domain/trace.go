@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -69,13 +70,45 @@ const (
 	SpanStatusError SpanStatus = "error"
 )
 
-// TraceRepository defines the interface for trace persistence
+// SpanKindTag is the span tag holding the span kind (server, client, producer, consumer, internal)
+const SpanKindTag = "span.kind"
+
+// ErrTraceNotFound is returned by TraceRepository.FindByID when no trace
+// with the given ID is stored
+var ErrTraceNotFound = errors.New("trace not found")
+
+// TraceRepository defines the interface for trace persistence. Save
+// upserts a trace: its trace-level fields overwrite the stored ones and its
+// spans are merged into the stored spans, so a trace can be saved in parts.
 type TraceRepository interface {
 	Save(ctx context.Context, trace *Trace) error
 	FindByID(ctx context.Context, id TraceID) (*Trace, error)
 	Search(ctx context.Context, criteria *SearchCriteria) ([]*Trace, error)
 	GetServices(ctx context.Context) ([]ServiceName, error)
 	GetOperations(ctx context.Context, service ServiceName) ([]OperationName, error)
+	Count(ctx context.Context, criteria *SearchCriteria) (int64, error)
+}
+
+// TraceReplacer is implemented by repositories that can replace a stored
+// trace as a whole, dropping the spans of previous saves
+type TraceReplacer interface {
+	Replace(ctx context.Context, trace *Trace) error
+}
+
+// TraceQuerier is implemented by repositories that evaluate trace queries
+// natively; other repositories are served by matching traces in memory
+type TraceQuerier interface {
+	QueryTraces(ctx context.Context, query *TraceQuery, page TracePage) ([]*Trace, error)
+	CountQuery(ctx context.Context, query *TraceQuery) (int64, error)
+}
+
+// TracePage selects a page of trace query results, which are ordered newest
+// first like a default search. Cursor continues right after the last trace
+// of a previous page.
+type TracePage struct {
+	Limit  int
+	Offset int
+	Cursor *TraceCursor
 }
 
 // SearchCriteria defines search parameters for traces
@@ -88,35 +121,96 @@ type SearchCriteria struct {
 	MaxDuration *time.Duration `json:"max_duration,omitempty"`
 	Status      *TraceStatus   `json:"status,omitempty"`
 	Tags        map[string]string `json:"tags,omitempty"`
+	SpanFilters []SpanFilter   `json:"span_filters,omitempty"`
+	SortBy      TraceSortField `json:"sort_by,omitempty"`
+	SortOrder   SortOrder      `json:"sort_order,omitempty"`
+	Cursor      *TraceCursor   `json:"-"`
 	Limit       int            `json:"limit,omitempty"`
 	Offset      int            `json:"offset,omitempty"`
 }
 
+// SpanFilter matches traces containing at least one span that satisfies
+// every set field. Multiple filters in SearchCriteria must all match,
+// each possibly by a different span.
+type SpanFilter struct {
+	Service     *ServiceName      `json:"service,omitempty"`
+	Operation   *OperationName    `json:"operation,omitempty"`
+	Status      *SpanStatus       `json:"status,omitempty"`
+	MinDuration *time.Duration    `json:"min_duration,omitempty"`
+	MaxDuration *time.Duration    `json:"max_duration,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// IsEmpty reports whether the filter has no conditions
+func (f SpanFilter) IsEmpty() bool {
+	return f.Service == nil && f.Operation == nil && f.Status == nil &&
+		f.MinDuration == nil && f.MaxDuration == nil && len(f.Tags) == 0
+}
+
 // TraceService defines the business logic for trace operations
 type TraceService interface {
 	ProcessTrace(ctx context.Context, trace *Trace) error
+	UpdateTrace(ctx context.Context, trace *Trace) error
+	ValidateTrace(trace *Trace) error
 	SearchTraces(ctx context.Context, criteria *SearchCriteria) ([]*Trace, error)
+	CountTraces(ctx context.Context, criteria *SearchCriteria, mode CountMode) (*TraceCount, error)
+	QueryTraces(ctx context.Context, query *TraceQuery, page TracePage) ([]*Trace, error)
+	CountQueryTraces(ctx context.Context, query *TraceQuery, mode CountMode) (*TraceCount, error)
 	GetTrace(ctx context.Context, id TraceID) (*Trace, error)
+	AnalyzeTrace(ctx context.Context, id TraceID) (*TraceAnalysis, error)
 	GetServices(ctx context.Context) ([]ServiceName, error)
 	GetOperations(ctx context.Context, service ServiceName) ([]OperationName, error)
-	GetMetrics(ctx context.Context) (*TraceMetrics, error)
+	GetMetrics(ctx context.Context, query MetricsQuery) (*TraceMetrics, error)
+}
+
+// SpanAssembler buffers individually reported spans and assembles them into traces
+type SpanAssembler interface {
+	AddSpan(ctx context.Context, span *Span) error
+	// AddSpanWithAck buffers a span like AddSpan and calls ack once the
+	// trace holding it is stored, or dropped by sampling
+	AddSpanWithAck(ctx context.Context, span *Span, ack func()) error
+	Flush(ctx context.Context) error
+	Start(ctx context.Context) error
 }
 
 // TraceMetrics represents aggregated metrics for traces
 type TraceMetrics struct {
-	TotalTraces     int64         `json:"total_traces"`
-	TotalSpans      int64         `json:"total_spans"`
-	AverageDuration time.Duration `json:"average_duration"`
-	ErrorRate       float64       `json:"error_rate"`
-	Throughput      float64       `json:"throughput"`
+	Start           time.Time        `json:"start"`
+	End             time.Time        `json:"end"`
+	TotalTraces     int64            `json:"total_traces"`
+	TotalSpans      int64            `json:"total_spans"`
+	AverageDuration time.Duration    `json:"average_duration"`
+	P50Duration     time.Duration    `json:"p50_duration"`
+	P90Duration     time.Duration    `json:"p90_duration"`
+	P99Duration     time.Duration    `json:"p99_duration"`
+	ErrorRate       float64          `json:"error_rate"`
+	Throughput      float64          `json:"throughput"`
 	Services        []ServiceMetrics `json:"services"`
 }
 
 // ServiceMetrics represents metrics for a specific service
 type ServiceMetrics struct {
-	Service         ServiceName   `json:"service"`
+	Service         ServiceName        `json:"service"`
+	TotalTraces     int64              `json:"total_traces"`
+	TotalSpans      int64              `json:"total_spans"`
+	AverageDuration time.Duration      `json:"average_duration"`
+	P50Duration     time.Duration      `json:"p50_duration"`
+	P90Duration     time.Duration      `json:"p90_duration"`
+	P99Duration     time.Duration      `json:"p99_duration"`
+	ErrorRate       float64            `json:"error_rate"`
+	Throughput      float64            `json:"throughput"`
+	Operations      []OperationMetrics `json:"operations"`
+}
+
+// OperationMetrics represents metrics for an operation of a service
+type OperationMetrics struct {
+	Operation       OperationName `json:"operation"`
 	TotalTraces     int64         `json:"total_traces"`
+	TotalSpans      int64         `json:"total_spans"`
 	AverageDuration time.Duration `json:"average_duration"`
+	P50Duration     time.Duration `json:"p50_duration"`
+	P90Duration     time.Duration `json:"p90_duration"`
+	P99Duration     time.Duration `json:"p99_duration"`
 	ErrorRate       float64       `json:"error_rate"`
 	Throughput      float64       `json:"throughput"`
 }
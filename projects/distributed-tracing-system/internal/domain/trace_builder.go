@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrInvalidTrace is returned when a trace fails validation
+var ErrInvalidTrace = errors.New("invalid trace")
+
+// BuildTrace assembles a trace from its spans, deriving the trace-level
+// fields (service, operation, timing and status) from the root span
+func BuildTrace(id TraceID, spans []Span) *Trace {
+	trace := &Trace{
+		ID:     id,
+		Spans:  spans,
+		Tags:   map[string]string{},
+		Status: TraceStatusSuccess,
+	}
+	if len(spans) == 0 {
+		return trace
+	}
+
+	// Keep spans ordered by start time so the root lookup is deterministic
+	sort.SliceStable(trace.Spans, func(i, j int) bool {
+		return trace.Spans[i].StartTime.Before(trace.Spans[j].StartTime)
+	})
+
+	root := FindRootSpan(trace.Spans)
+	trace.Service = root.Service
+	trace.Operation = root.Operation
+	for key, value := range root.Tags {
+		trace.Tags[key] = value
+	}
+
+	trace.StartTime = trace.Spans[0].StartTime
+	trace.EndTime = trace.Spans[0].EndTime
+	for _, span := range trace.Spans {
+		if span.StartTime.Before(trace.StartTime) {
+			trace.StartTime = span.StartTime
+		}
+		if span.EndTime.After(trace.EndTime) {
+			trace.EndTime = span.EndTime
+		}
+		if span.Status == SpanStatusError {
+			trace.Status = TraceStatusError
+		}
+	}
+	trace.Duration = trace.EndTime.Sub(trace.StartTime)
+
+	return trace
+}
+
+// MergeSpans returns the stored spans with the added ones merged in. An
+// added span replaces the stored span with the same ID in place; other
+// added spans follow the stored ones.
+func MergeSpans(stored, added []Span) []Span {
+	index := make(map[SpanID]int, len(stored)+len(added))
+	merged := make([]Span, 0, len(stored)+len(added))
+	for _, spans := range [][]Span{stored, added} {
+		for _, span := range spans {
+			if i, ok := index[span.ID]; ok {
+				merged[i] = span
+				continue
+			}
+			index[span.ID] = len(merged)
+			merged = append(merged, span)
+		}
+	}
+	return merged
+}
+
+// FindRootSpan returns the span without a parent in the given set. When
+// several candidates exist (or the real root has not arrived yet) the
+// earliest one wins. spans must not be empty.
+func FindRootSpan(spans []Span) *Span {
+	ids := make(map[SpanID]struct{}, len(spans))
+	for _, span := range spans {
+		ids[span.ID] = struct{}{}
+	}
+
+	var root *Span
+	for i := range spans {
+		span := &spans[i]
+		isRoot := span.ParentID == nil || *span.ParentID == ""
+		if !isRoot {
+			_, hasParent := ids[*span.ParentID]
+			isRoot = !hasParent
+		}
+		if !isRoot {
+			continue
+		}
+		if root == nil || isBetterRoot(span, root) {
+			root = span
+		}
+	}
+
+	if root == nil {
+		// Cyclic parent references; fall back to the earliest span
+		root = &spans[0]
+		for i := range spans {
+			if spans[i].StartTime.Before(root.StartTime) {
+				root = &spans[i]
+			}
+		}
+	}
+
+	return root
+}
+
+// isBetterRoot prefers spans without any parent reference, then the earliest one
+func isBetterRoot(candidate, current *Span) bool {
+	candidateTrueRoot := candidate.ParentID == nil || *candidate.ParentID == ""
+	currentTrueRoot := current.ParentID == nil || *current.ParentID == ""
+	if candidateTrueRoot != currentTrueRoot {
+		return candidateTrueRoot
+	}
+	return candidate.StartTime.Before(current.StartTime)
+}
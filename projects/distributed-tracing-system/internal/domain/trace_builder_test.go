@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spanIDPtr(id SpanID) *SpanID {
+	return &id
+}
+
+func TestBuildTrace(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	spans := []Span{
+		{
+			ID:        "child",
+			TraceID:   "trace1",
+			ParentID:  spanIDPtr("root"),
+			Service:   "payments",
+			Operation: "charge",
+			StartTime: start.Add(10 * time.Millisecond),
+			EndTime:   start.Add(90 * time.Millisecond),
+			Status:    SpanStatusError,
+		},
+		{
+			ID:        "root",
+			TraceID:   "trace1",
+			Service:   "checkout",
+			Operation: "POST /checkout",
+			StartTime: start,
+			EndTime:   start.Add(100 * time.Millisecond),
+			Tags:      map[string]string{"region": "eu"},
+			Status:    SpanStatusOK,
+		},
+	}
+
+	trace := BuildTrace("trace1", spans)
+
+	assert.Equal(t, TraceID("trace1"), trace.ID)
+	assert.Equal(t, ServiceName("checkout"), trace.Service)
+	assert.Equal(t, OperationName("POST /checkout"), trace.Operation)
+	assert.Equal(t, start, trace.StartTime)
+	assert.Equal(t, start.Add(100*time.Millisecond), trace.EndTime)
+	assert.Equal(t, 100*time.Millisecond, trace.Duration)
+	assert.Equal(t, TraceStatusError, trace.Status)
+	assert.Equal(t, "eu", trace.Tags["region"])
+	require.Len(t, trace.Spans, 2)
+	assert.Equal(t, SpanID("root"), trace.Spans[0].ID)
+}
+
+func TestBuildTrace_Empty(t *testing.T) {
+	trace := BuildTrace("trace1", nil)
+
+	assert.Equal(t, TraceID("trace1"), trace.ID)
+	assert.Equal(t, TraceStatusSuccess, trace.Status)
+	assert.Empty(t, trace.Spans)
+}
+
+func TestFindRootSpan(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spans    []Span
+		expected SpanID
+	}{
+		{
+			name: "span without parent",
+			spans: []Span{
+				{ID: "b", ParentID: spanIDPtr("a"), StartTime: start},
+				{ID: "a", StartTime: start.Add(time.Millisecond)},
+			},
+			expected: "a",
+		},
+		{
+			name: "missing root falls back to earliest orphan",
+			spans: []Span{
+				{ID: "c", ParentID: spanIDPtr("missing"), StartTime: start.Add(2 * time.Millisecond)},
+				{ID: "b", ParentID: spanIDPtr("missing"), StartTime: start.Add(time.Millisecond)},
+			},
+			expected: "b",
+		},
+		{
+			name: "true root preferred over orphan",
+			spans: []Span{
+				{ID: "orphan", ParentID: spanIDPtr("missing"), StartTime: start},
+				{ID: "root", StartTime: start.Add(time.Millisecond)},
+			},
+			expected: "root",
+		},
+		{
+			name: "cyclic references",
+			spans: []Span{
+				{ID: "a", ParentID: spanIDPtr("b"), StartTime: start.Add(time.Millisecond)},
+				{ID: "b", ParentID: spanIDPtr("a"), StartTime: start},
+			},
+			expected: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := FindRootSpan(tt.spans)
+			assert.Equal(t, tt.expected, root.ID)
+		})
+	}
+}
+
+func TestMergeSpans(t *testing.T) {
+	stored := []Span{
+		{ID: "root", Operation: "GET /cart"},
+		{ID: "db", Operation: "SELECT"},
+	}
+	added := []Span{
+		{ID: "cache", Operation: "GET"},
+		{ID: "db", Operation: "UPDATE"},
+	}
+
+	merged := MergeSpans(stored, added)
+
+	require.Len(t, merged, 3)
+	assert.Equal(t, SpanID("root"), merged[0].ID)
+	assert.Equal(t, SpanID("db"), merged[1].ID)
+	assert.Equal(t, OperationName("UPDATE"), merged[1].Operation)
+	assert.Equal(t, SpanID("cache"), merged[2].ID)
+	assert.Equal(t, OperationName("SELECT"), stored[1].Operation, "inputs should not be modified")
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseTraceQL parses a TraceQL-lite query, the subset of Tempo's TraceQL
+// made of span sets combined with && and ||, such as
+//
+//	{ resource.service.name = "checkout" && .http.status_code = "500" } && { duration > 1s }
+//
+// Conditions inside a span set, combined with && and || and grouped with
+// parentheses, must hold for the same span. Supported fields are name,
+// status (error, ok or unset), kind, duration, and span or resource
+// attributes (.key, span.key or resource.key); resource.service.name is the
+// span's service and other resource attributes are matched against span tags.
+// The query is translated into a TraceQuery.
+func ParseTraceQL(input string) (*TraceQuery, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == queryTokenEOF {
+		return nil, &QuerySyntaxError{Position: 0, Message: "query is empty"}
+	}
+
+	translated := make([]queryToken, 0, len(tokens)+len(tokens)/4)
+	depth := 0
+	field := ""
+	for i, tok := range tokens {
+		switch {
+		case tok.kind == queryTokenLBrace:
+			if depth > 0 {
+				return nil, &QuerySyntaxError{Position: tok.pos, Message: "span sets cannot be nested"}
+			}
+			depth++
+			translated = append(translated, queryToken{kind: queryTokenWord, text: "span", pos: tok.pos}, tok)
+			continue
+		case tok.kind == queryTokenRBrace:
+			depth--
+		case tok.kind == queryTokenWord && tok.text == "&&":
+			tok.text = string(QueryAnd)
+		case tok.kind == queryTokenWord && tok.text == "||":
+			tok.text = string(QueryOr)
+		case tok.kind == queryTokenWord && depth == 0:
+			return nil, &QuerySyntaxError{Position: tok.pos, Message: fmt.Sprintf("expected a span set but found %s", tok.describe())}
+		case tok.kind == queryTokenWord && tokens[i+1].kind == queryTokenOperator:
+			field = tok.text
+			if tok.text, err = traceQLField(tok); err != nil {
+				return nil, err
+			}
+		case tok.kind == queryTokenWord && field == "status" && tok.text == "unset":
+			// Spans without an error status are stored as ok
+			tok.text = string(SpanStatusOK)
+		}
+		translated = append(translated, tok)
+	}
+
+	p := &queryParser{tokens: translated}
+	root, err := p.parseOr(false)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != queryTokenEOF {
+		return nil, &QuerySyntaxError{Position: tok.pos, Message: fmt.Sprintf("unexpected %s", tok.describe())}
+	}
+
+	return &TraceQuery{Raw: input, Root: root}, nil
+}
+
+// traceQLField returns the query language field for a TraceQL field
+func traceQLField(tok queryToken) (string, error) {
+	name := tok.text
+	switch {
+	case name == "name":
+		return string(QueryFieldOperation), nil
+	case name == "status", name == "duration":
+		return name, nil
+	case name == "kind":
+		return "tag." + SpanKindTag, nil
+	case name == "resource."+ServiceNameTag:
+		return string(QueryFieldService), nil
+	case strings.HasPrefix(name, "."):
+		name = strings.TrimPrefix(name, ".")
+	case strings.HasPrefix(name, "span."):
+		name = strings.TrimPrefix(name, "span.")
+	case strings.HasPrefix(name, "resource."):
+		name = strings.TrimPrefix(name, "resource.")
+	default:
+		return "", &QuerySyntaxError{
+			Position: tok.pos,
+			Message:  fmt.Sprintf("unknown field %q: expected name, status, kind, duration or an attribute such as .key", name),
+		}
+	}
+
+	if name == "" {
+		return "", &QuerySyntaxError{Position: tok.pos, Message: "attribute name is required"}
+	}
+	return "tag." + name, nil
+}
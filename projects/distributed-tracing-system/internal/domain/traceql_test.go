@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceQL_Matches(t *testing.T) {
+	trace := queryTestTrace()
+	trace.Spans[1].Tags[SpanKindTag] = "client"
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`{ resource.service.name = "payments" && status = error }`, true},
+		{`{resource.service.name="payments"}&&{name="POST /checkout"}`, true},
+		{`{ .http.method = "POST" && duration >= 300ms }`, true},
+		{`{ span.http.method = "GET" }`, false},
+		{`{ resource.http.method = "POST" }`, true},
+		{`{ kind = client }`, true},
+		{`{ status = unset && resource.service.name = "checkout" }`, true},
+		{`{ duration > 1s } || { name =~ "^charge" }`, true},
+		{`{ (name = "charge" || name = "refund") && status = ok }`, false},
+		{`{ resource.service.name = "payments" } && { resource.service.name = "inventory" }`, false},
+		{`{}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := ParseTraceQL(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query.Matches(trace))
+			assert.Equal(t, tt.query, query.Raw)
+		})
+	}
+}
+
+func TestParseTraceQL_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		query    string
+		position int
+		message  string
+	}{
+		{``, 0, "query is empty"},
+		{`name = "charge"`, 0, "expected a span set"},
+		{`{ { name = "charge" } }`, 2, "cannot be nested"},
+		{`{ service = "payments" }`, 2, "unknown field"},
+		{`{ . = "x" }`, 2, "attribute name is required"},
+		{`{ status = broken }`, 11, "invalid span status"},
+		{`{ duration > fast }`, 13, "invalid duration"},
+		{`{ name = "charge"`, 17, `expected "}"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseTraceQL(tt.query)
+			require.Error(t, err)
+
+			var syntaxErr *QuerySyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+			assert.Equal(t, tt.position, syntaxErr.Position)
+			assert.Contains(t, syntaxErr.Message, tt.message)
+		})
+	}
+}
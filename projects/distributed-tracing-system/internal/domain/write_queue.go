@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBackpressure is returned when a trace is refused because the write
+// queue is full; the caller should slow down and retry later
+var ErrBackpressure = errors.New("trace write queue is full")
+
+// BackpressureError is returned by TraceService.ProcessTrace when the write
+// queue refuses a trace that already went through the pipeline. Retry
+// stores that trace again without processing it a second time, so callers
+// that wait for storage, like the Kafka consumer, do not sample or record
+// it twice. It matches ErrBackpressure.
+type BackpressureError struct {
+	Retry func(ctx context.Context) error
+}
+
+func (e *BackpressureError) Error() string {
+	return ErrBackpressure.Error()
+}
+
+func (e *BackpressureError) Unwrap() error {
+	return ErrBackpressure
+}
+
+// TraceBatchSaver is implemented by repositories that save many traces in
+// one round trip. Saving a batch has the same effect as saving each trace
+// in order.
+type TraceBatchSaver interface {
+	SaveBatch(ctx context.Context, traces []*Trace) error
+}
+
+// TraceWriteQueue batches the trace writes of concurrent callers
+type TraceWriteQueue interface {
+	// Save queues the trace and waits until the batch holding it is
+	// stored. It fails with ErrBackpressure, without waiting, when the
+	// queue is full.
+	Save(ctx context.Context, trace *Trace) error
+	// Start writes queued traces until the context is cancelled, then
+	// writes whatever is still queued
+	Start(ctx context.Context) error
+}
@@ -0,0 +1,102 @@
+package infrastructure
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// errorRateSlots is the number of slots a sliding error rate window is split into
+const errorRateSlots = 12
+
+// errorRateSlot counts the traces of one slot of the window
+type errorRateSlot struct {
+	// index is the slot's position since the epoch, telling stale slots apart
+	index  int64
+	total  int64
+	errors int64
+}
+
+// errorRateCollector reports the fraction of failed traces per service
+// over a sliding window. The rate is computed when scraped, so it decays
+// as the window moves on instead of keeping the status of the last trace.
+// Services without traces in the window are no longer reported.
+type errorRateCollector struct {
+	desc *prometheus.Desc
+	slot time.Duration
+	now  func() time.Time
+
+	mu       sync.Mutex
+	services map[string]*[errorRateSlots]errorRateSlot
+}
+
+// newErrorRateCollector creates a collector for the given window
+func newErrorRateCollector(window time.Duration) *errorRateCollector {
+	slot := window / errorRateSlots
+	if slot <= 0 {
+		slot = time.Nanosecond
+	}
+	return &errorRateCollector{
+		desc: prometheus.NewDesc(
+			"error_rate",
+			"Fraction of traces that failed per service over the error rate window",
+			[]string{"service"},
+			nil,
+		),
+		slot:     slot,
+		now:      time.Now,
+		services: make(map[string]*[errorRateSlots]errorRateSlot),
+	}
+}
+
+// Record counts a trace of a service
+func (c *errorRateCollector) Record(service string, isError bool) {
+	index := c.now().UnixNano() / int64(c.slot)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slots, ok := c.services[service]
+	if !ok {
+		slots = &[errorRateSlots]errorRateSlot{}
+		c.services[service] = slots
+	}
+
+	s := &slots[index%errorRateSlots]
+	if s.index != index {
+		*s = errorRateSlot{index: index}
+	}
+	s.total++
+	if isError {
+		s.errors++
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *errorRateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *errorRateCollector) Collect(ch chan<- prometheus.Metric) {
+	current := c.now().UnixNano() / int64(c.slot)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for service, slots := range c.services {
+		var total, errors int64
+		for _, s := range slots {
+			if current-s.index < errorRateSlots {
+				total += s.total
+				errors += s.errors
+			}
+		}
+		if total == 0 {
+			delete(c.services, service)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(errors)/float64(total), service)
+	}
+}
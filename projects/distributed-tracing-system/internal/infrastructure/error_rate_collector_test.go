@@ -0,0 +1,40 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorRateCollector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	collector := newErrorRateCollector(time.Minute)
+	collector.now = func() time.Time { return now }
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	collector.Record("checkout", true)
+	collector.Record("checkout", false)
+	collector.Record("checkout", false)
+	collector.Record("checkout", false)
+	collector.Record("payments", false)
+
+	series := gatherSeries(t, registry)
+	assert.Equal(t, 0.25, series["error_rate{checkout}"])
+	assert.Equal(t, 0.0, series["error_rate{payments}"])
+
+	// Later traces do not overwrite the rate of earlier ones in the window
+	now = now.Add(30 * time.Second)
+	collector.Record("checkout", true)
+	series = gatherSeries(t, registry)
+	assert.Equal(t, 0.4, series["error_rate{checkout}"])
+
+	// Traces leave the rate once the window moved past them
+	now = now.Add(45 * time.Second)
+	series = gatherSeries(t, registry)
+	assert.Equal(t, 1.0, series["error_rate{checkout}"])
+	assert.NotContains(t, series, "error_rate{payments}")
+}
@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// fileExporter implements the JaegerExporter interface by appending traces
+// to a file as JSON lines
+type fileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter creates an exporter appending to the file at path,
+// creating it and its directory when missing
+func NewFileExporter(path string) (domain.JaegerExporter, error) {
+	if path == "" {
+		return nil, fmt.Errorf("export file path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export file: %w", err)
+	}
+	return &fileExporter{file: file}, nil
+}
+
+// ExportTrace appends the trace as a single JSON line
+func (fe *fileExporter) ExportTrace(ctx context.Context, trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("trace cannot be nil")
+	}
+
+	line, err := json.Marshal(trace)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %w", err)
+	}
+	line = append(line, '\n')
+
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	if _, err := fe.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+// Close closes the export file
+func (fe *fileExporter) Close() error {
+	return fe.file.Close()
+}
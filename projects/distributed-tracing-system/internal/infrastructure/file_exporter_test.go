@@ -0,0 +1,44 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileExporter_ExportTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exports", "traces.jsonl")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+
+	first, second := testForwardTrace(), testForwardTrace()
+	second.ID = "second"
+	require.NoError(t, exporter.ExportTrace(context.Background(), first))
+	require.NoError(t, exporter.ExportTrace(context.Background(), second))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []domain.TraceID
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var trace domain.Trace
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &trace))
+		ids = append(ids, trace.ID)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []domain.TraceID{first.ID, second.ID}, ids)
+}
+
+func TestNewFileExporter_EmptyPath(t *testing.T) {
+	_, err := NewFileExporter("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package infrastructure
+
+import (
+	"fmt"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+const (
+	// jaegerChildOf is the Jaeger reference type linking a span to its parent
+	jaegerChildOf = "CHILD_OF"
+	// jaegerStringType and jaegerBoolType are the Jaeger tag value types
+	jaegerStringType = "string"
+	jaegerBoolType   = "bool"
+	// jaegerLogMessageKey is the log field holding the message of a span log
+	jaegerLogMessageKey = "event"
+)
+
+// JaegerTrace is a trace in the JSON model of the Jaeger query API
+type JaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []JaegerSpan             `json:"spans"`
+	Processes map[string]JaegerProcess `json:"processes"`
+	Warnings  []string                 `json:"warnings"`
+}
+
+// JaegerSpan is a span in the Jaeger JSON model. Times are in microseconds.
+type JaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []JaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []JaegerKeyValue  `json:"tags"`
+	Logs          []JaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+	Warnings      []string          `json:"warnings"`
+}
+
+// JaegerReference links a span to another span, usually its parent
+type JaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+// JaegerKeyValue is a typed tag or log field
+type JaegerKeyValue struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// JaegerLog is a timestamped span event in microseconds
+type JaegerLog struct {
+	Timestamp int64            `json:"timestamp"`
+	Fields    []JaegerKeyValue `json:"fields"`
+}
+
+// JaegerProcess is the service emitting spans, referenced by process ID
+type JaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []JaegerKeyValue `json:"tags"`
+}
+
+// JaegerDependencyLink is an edge of the Jaeger dependency graph
+type JaegerDependencyLink struct {
+	Parent    string `json:"parent"`
+	Child     string `json:"child"`
+	CallCount int64  `json:"callCount"`
+}
+
+// TraceToJaeger converts a domain trace into the Jaeger JSON model. IDs are
+// kept as they are so that the trace can be looked up again by the ID the
+// Jaeger UI shows. Each service becomes a process numbered in the order it
+// first appears, and parent links become CHILD_OF references.
+func TraceToJaeger(trace *domain.Trace) JaegerTrace {
+	jaegerTrace := JaegerTrace{
+		TraceID:   string(trace.ID),
+		Spans:     []JaegerSpan{},
+		Processes: make(map[string]JaegerProcess),
+	}
+
+	processIDs := make(map[domain.ServiceName]string)
+	for _, span := range forwardedSpans(trace) {
+		processID, ok := processIDs[span.Service]
+		if !ok {
+			processID = fmt.Sprintf("p%d", len(processIDs)+1)
+			processIDs[span.Service] = processID
+			jaegerTrace.Processes[processID] = JaegerProcess{
+				ServiceName: string(span.Service),
+				Tags:        []JaegerKeyValue{},
+			}
+		}
+
+		span.TraceID = trace.ID
+		jaegerTrace.Spans = append(jaegerTrace.Spans, domainSpanToJaeger(span, processID))
+	}
+
+	return jaegerTrace
+}
+
+// domainSpanToJaeger converts a single domain span into a Jaeger span
+func domainSpanToJaeger(span domain.Span, processID string) JaegerSpan {
+	jaegerSpan := JaegerSpan{
+		TraceID:       string(span.TraceID),
+		SpanID:        string(span.ID),
+		OperationName: string(span.Operation),
+		References:    []JaegerReference{},
+		StartTime:     span.StartTime.UnixMicro(),
+		Duration:      span.EndTime.Sub(span.StartTime).Microseconds(),
+		Tags:          jaegerTags(span.Tags),
+		Logs:          []JaegerLog{},
+		ProcessID:     processID,
+	}
+
+	if span.ParentID != nil {
+		jaegerSpan.References = append(jaegerSpan.References, JaegerReference{
+			RefType: jaegerChildOf,
+			TraceID: string(span.TraceID),
+			SpanID:  string(*span.ParentID),
+		})
+	}
+
+	// The Jaeger UI marks spans as failed by their error tag
+	if span.Status == domain.SpanStatusError {
+		if _, ok := span.Tags[zipkinErrorTag]; !ok {
+			jaegerSpan.Tags = append(jaegerSpan.Tags, JaegerKeyValue{Key: zipkinErrorTag, Type: jaegerBoolType, Value: true})
+		}
+	}
+
+	for _, log := range span.Logs {
+		fields := []JaegerKeyValue{}
+		if log.Message != "" {
+			fields = append(fields, JaegerKeyValue{Key: jaegerLogMessageKey, Type: jaegerStringType, Value: log.Message})
+		}
+		fields = append(fields, jaegerTags(log.Fields)...)
+		jaegerSpan.Logs = append(jaegerSpan.Logs, JaegerLog{
+			Timestamp: log.Timestamp.UnixMicro(),
+			Fields:    fields,
+		})
+	}
+
+	return jaegerSpan
+}
+
+// jaegerTags converts string tags into Jaeger key-values ordered by key
+func jaegerTags(tags map[string]string) []JaegerKeyValue {
+	keyValues := make([]JaegerKeyValue, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		keyValues = append(keyValues, JaegerKeyValue{Key: key, Type: jaegerStringType, Value: tags[key]})
+	}
+	return keyValues
+}
+
+// DependencyGraphToJaeger converts the edges of a dependency graph into
+// Jaeger dependency links
+func DependencyGraphToJaeger(graph *domain.DependencyGraph) []JaegerDependencyLink {
+	links := make([]JaegerDependencyLink, 0, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		links = append(links, JaegerDependencyLink{
+			Parent:    string(edge.Caller),
+			Child:     string(edge.Callee),
+			CallCount: edge.CallCount,
+		})
+	}
+	return links
+}
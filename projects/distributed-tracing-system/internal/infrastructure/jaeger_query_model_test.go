@@ -0,0 +1,77 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceToJaeger(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rootID := domain.SpanID("root")
+	trace := &domain.Trace{
+		ID:        "trace-1",
+		Service:   "frontend",
+		Operation: "checkout",
+		StartTime: start,
+		EndTime:   start.Add(100 * time.Millisecond),
+		Status:    domain.TraceStatusError,
+		Spans: []domain.Span{
+			{ID: rootID, Service: "frontend", Operation: "checkout", StartTime: start, Duration: 100 * time.Millisecond, Tags: map[string]string{"http.method": "GET"}, Status: domain.SpanStatusOK},
+			{
+				ID: "charge", ParentID: &rootID, Service: "payments", Operation: "charge",
+				StartTime: start.Add(10 * time.Millisecond), Duration: 50 * time.Millisecond, Status: domain.SpanStatusError,
+				Logs: []domain.Log{{Timestamp: start.Add(20 * time.Millisecond), Message: "retry", Fields: map[string]string{"attempt": "2"}}},
+			},
+			{ID: "render", ParentID: &rootID, Service: "frontend", Operation: "render", StartTime: start.Add(60 * time.Millisecond), Duration: 10 * time.Millisecond, Status: domain.SpanStatusOK},
+		},
+	}
+
+	jaegerTrace := TraceToJaeger(trace)
+
+	assert.Equal(t, "trace-1", jaegerTrace.TraceID)
+	assert.Equal(t, map[string]JaegerProcess{
+		"p1": {ServiceName: "frontend", Tags: []JaegerKeyValue{}},
+		"p2": {ServiceName: "payments", Tags: []JaegerKeyValue{}},
+	}, jaegerTrace.Processes)
+	require.Len(t, jaegerTrace.Spans, 3)
+
+	root := jaegerTrace.Spans[0]
+	assert.Equal(t, "trace-1", root.TraceID)
+	assert.Equal(t, "p1", root.ProcessID)
+	assert.Empty(t, root.References)
+	assert.Equal(t, start.UnixMicro(), root.StartTime)
+	assert.Equal(t, int64(100000), root.Duration)
+	assert.Equal(t, []JaegerKeyValue{{Key: "http.method", Type: "string", Value: "GET"}}, root.Tags)
+
+	charge := jaegerTrace.Spans[1]
+	assert.Equal(t, "p2", charge.ProcessID)
+	assert.Equal(t, []JaegerReference{{RefType: "CHILD_OF", TraceID: "trace-1", SpanID: "root"}}, charge.References)
+	assert.Equal(t, []JaegerKeyValue{{Key: "error", Type: "bool", Value: true}}, charge.Tags)
+	require.Len(t, charge.Logs, 1)
+	assert.Equal(t, start.Add(20*time.Millisecond).UnixMicro(), charge.Logs[0].Timestamp)
+	assert.Equal(t, []JaegerKeyValue{
+		{Key: "event", Type: "string", Value: "retry"},
+		{Key: "attempt", Type: "string", Value: "2"},
+	}, charge.Logs[0].Fields)
+
+	assert.Equal(t, "p1", jaegerTrace.Spans[2].ProcessID)
+}
+
+func TestDependencyGraphToJaeger(t *testing.T) {
+	graph := &domain.DependencyGraph{
+		Edges: []domain.DependencyEdge{
+			{Caller: "frontend", Callee: "payments", CallCount: 12},
+			{Caller: "payments", Callee: "ledger", CallCount: 3},
+		},
+	}
+
+	assert.Equal(t, []JaegerDependencyLink{
+		{Parent: "frontend", Child: "payments", CallCount: 12},
+		{Parent: "payments", Child: "ledger", CallCount: 3},
+	}, DependencyGraphToJaeger(graph))
+	assert.Empty(t, DependencyGraphToJaeger(&domain.DependencyGraph{}))
+}
@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// kafkaAlertProducer publishes SLO alerts to a dedicated Kafka topic
+type kafkaAlertProducer struct {
+	writer *kafka.Writer
+	topic  string
+}
+
+// NewKafkaAlertProducer creates a Kafka producer for SLO alerts
+func NewKafkaAlertProducer(brokers []string, topic string) (domain.AlertPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("brokers list cannot be empty")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("topic cannot be empty")
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		Async:        false,
+		BatchTimeout: 10 * time.Millisecond,
+	}
+
+	return &kafkaAlertProducer{
+		writer: writer,
+		topic:  topic,
+	}, nil
+}
+
+// PublishAlert publishes an SLO alert. Alerts are keyed by SLO so the
+// alerts of one SLO stay ordered within a partition.
+func (p *kafkaAlertProducer) PublishAlert(ctx context.Context, alert *domain.SLOAlert) error {
+	message, err := alertMessage(alert)
+	if err != nil {
+		return err
+	}
+
+	if err := p.writer.WriteMessages(ctx, message); err != nil {
+		return fmt.Errorf("failed to publish slo alert: %w", err)
+	}
+	return nil
+}
+
+// alertMessage converts an SLO alert to a Kafka message
+func alertMessage(alert *domain.SLOAlert) (kafka.Message, error) {
+	if alert == nil || alert.SLOID == "" {
+		return kafka.Message{}, fmt.Errorf("alert SLO ID is required")
+	}
+
+	value, err := json.Marshal(alert)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal slo alert: %w", err)
+	}
+
+	return kafka.Message{
+		Key:   []byte(alert.SLOID),
+		Value: value,
+		Time:  alert.Timestamp,
+		Headers: []kafka.Header{
+			{Key: "service", Value: []byte(string(alert.Service))},
+			{Key: "severity", Value: []byte(string(alert.Severity))},
+			{Key: "state", Value: []byte(string(alert.State))},
+		},
+	}, nil
+}
+
+// Close closes the Kafka alert producer
+func (p *kafkaAlertProducer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// kafkaAnomalyProducer publishes detected anomalies to a dedicated Kafka topic
+type kafkaAnomalyProducer struct {
+	writer *kafka.Writer
+	topic  string
+}
+
+// NewKafkaAnomalyProducer creates a Kafka producer for detected anomalies
+func NewKafkaAnomalyProducer(brokers []string, topic string) (domain.AnomalyPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("brokers list cannot be empty")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("topic cannot be empty")
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		Async:        false,
+		BatchTimeout: 10 * time.Millisecond,
+	}
+
+	return &kafkaAnomalyProducer{
+		writer: writer,
+		topic:  topic,
+	}, nil
+}
+
+// PublishAnomaly publishes an anomaly. Anomalies are keyed by service so
+// the anomalies of one service stay ordered within a partition.
+func (p *kafkaAnomalyProducer) PublishAnomaly(ctx context.Context, anomaly *domain.Anomaly) error {
+	message, err := anomalyMessage(anomaly)
+	if err != nil {
+		return err
+	}
+
+	if err := p.writer.WriteMessages(ctx, message); err != nil {
+		return fmt.Errorf("failed to publish anomaly: %w", err)
+	}
+	return nil
+}
+
+// anomalyMessage converts an anomaly to a Kafka message
+func anomalyMessage(anomaly *domain.Anomaly) (kafka.Message, error) {
+	if anomaly == nil || anomaly.Service == "" {
+		return kafka.Message{}, fmt.Errorf("anomaly service is required")
+	}
+
+	value, err := json.Marshal(anomaly)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal anomaly: %w", err)
+	}
+
+	return kafka.Message{
+		Key:   []byte(anomaly.Service),
+		Value: value,
+		Time:  anomaly.WindowEnd,
+		Headers: []kafka.Header{
+			{Key: "service", Value: []byte(string(anomaly.Service))},
+			{Key: "operation", Value: []byte(string(anomaly.Operation))},
+			{Key: "kind", Value: []byte(string(anomaly.Kind))},
+		},
+	}, nil
+}
+
+// Close closes the Kafka anomaly producer
+func (p *kafkaAnomalyProducer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,45 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalyMessage(t *testing.T) {
+	anomaly := &domain.Anomaly{
+		ID:              "anomaly-1",
+		Service:         "checkout",
+		Operation:       "pay",
+		Kind:            domain.AnomalyKindLatency,
+		WindowStart:     time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		WindowEnd:       time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC),
+		Observed:        0.9,
+		Baseline:        0.1,
+		Score:           8,
+		TraceCount:      42,
+		ExampleTraceIDs: []domain.TraceID{"trace-1", "trace-2"},
+	}
+
+	message, err := anomalyMessage(anomaly)
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte("checkout"), message.Key)
+	assert.Equal(t, anomaly.WindowEnd, message.Time)
+	headers := make(map[string]string)
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	assert.Equal(t, map[string]string{"service": "checkout", "operation": "pay", "kind": "latency"}, headers)
+
+	var decoded domain.Anomaly
+	require.NoError(t, json.Unmarshal(message.Value, &decoded))
+	assert.Equal(t, *anomaly, decoded)
+
+	_, err = anomalyMessage(&domain.Anomaly{})
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package infrastructure
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -11,15 +12,48 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// kafkaMessageReader is the part of kafka.Reader used by the consumer
+type kafkaMessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 // kafkaConsumer implements the KafkaConsumer interface
 type kafkaConsumer struct {
-	reader *kafka.Reader
-	topic  string
+	reader  kafkaMessageReader
+	topic   string
 	groupID string
+	// concurrency bounds the messages processed at the same time
+	concurrency int
+	// retryBackoff and maxRetryBackoff pace retries of traces refused
+	// with domain.ErrBackpressure
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+}
+
+// KafkaConsumerOption configures optional Kafka consumer behaviour
+type KafkaConsumerOption func(*kafkaConsumer)
+
+// WithConsumerConcurrency sets how many messages are processed at the same
+// time; one keeps the consumer sequential
+func WithConsumerConcurrency(concurrency int) KafkaConsumerOption {
+	return func(kc *kafkaConsumer) {
+		kc.concurrency = concurrency
+	}
+}
+
+// WithBackpressureBackoff sets the first and the longest wait before
+// retrying a trace refused because storage is saturated
+func WithBackpressureBackoff(initial, max time.Duration) KafkaConsumerOption {
+	return func(kc *kafkaConsumer) {
+		kc.retryBackoff = initial
+		kc.maxRetryBackoff = max
+	}
 }
 
 // NewKafkaConsumer creates a new Kafka consumer
-func NewKafkaConsumer(brokers []string, topic, groupID string) (domain.KafkaConsumer, error) {
+func NewKafkaConsumer(brokers []string, topic, groupID string, opts ...KafkaConsumerOption) (domain.KafkaConsumer, error) {
 	if len(brokers) == 0 {
 		return nil, fmt.Errorf("brokers list cannot be empty")
 	}
@@ -30,7 +64,25 @@ func NewKafkaConsumer(brokers []string, topic, groupID string) (domain.KafkaCons
 		return nil, fmt.Errorf("group ID cannot be empty")
 	}
 
-	reader := kafka.NewReader(kafka.ReaderConfig{
+	kc := &kafkaConsumer{
+		topic:           topic,
+		groupID:         groupID,
+		concurrency:     1,
+		retryBackoff:    100 * time.Millisecond,
+		maxRetryBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(kc)
+	}
+
+	if kc.concurrency <= 0 {
+		return nil, fmt.Errorf("consumer concurrency must be positive")
+	}
+	if kc.retryBackoff <= 0 || kc.maxRetryBackoff < kc.retryBackoff {
+		return nil, fmt.Errorf("retry backoff must be positive and not exceed the max backoff")
+	}
+
+	kc.reader = kafka.NewReader(kafka.ReaderConfig{
 		Brokers:        brokers,
 		Topic:          topic,
 		GroupID:        groupID,
@@ -40,39 +92,118 @@ func NewKafkaConsumer(brokers []string, topic, groupID string) (domain.KafkaCons
 		StartOffset:    kafka.LastOffset,
 	})
 
-	return &kafkaConsumer{
-		reader:  reader,
-		topic:   topic,
-		groupID: groupID,
-	}, nil
+	return kc, nil
+}
+
+// inflightMessage is a fetched message and the outcome of its processing
+type inflightMessage struct {
+	message kafka.Message
+	done    chan struct{}
+	// commit is set when the message may be committed
+	commit bool
 }
 
-// Start starts the Kafka consumer
+// Start starts the Kafka consumer. Up to the configured concurrency of
+// messages are processed at once and offsets are committed in fetch order,
+// so a message is only committed once it and every message before it are
+// done. While storage applies backpressure, refused traces are retried and
+// no new messages are fetched, which leaves the backlog in Kafka.
 func (kc *kafkaConsumer) Start(ctx context.Context, traceService domain.TraceService) error {
 	log.Printf("Starting Kafka consumer for topic: %s, group: %s", kc.topic, kc.groupID)
 
+	// The committer holds one message while the channel holds the rest
+	inflight := make(chan *inflightMessage, kc.concurrency-1)
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		kc.commitInOrder(inflight)
+	}()
+	defer func() {
+		close(inflight)
+		<-committed
+	}()
+
 	for {
+		message, err := kc.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("Kafka consumer stopping due to context cancellation")
+				return ctx.Err()
+			}
+			log.Printf("Error reading Kafka message: %v", err)
+			continue
+		}
+
+		msg := &inflightMessage{message: message, done: make(chan struct{})}
 		select {
+		case inflight <- msg:
 		case <-ctx.Done():
 			log.Println("Kafka consumer stopping due to context cancellation")
 			return ctx.Err()
-		default:
-			// Read message with timeout
-			message, err := kc.reader.ReadMessage(ctx)
-			if err != nil {
-				if err == context.Canceled {
-					return nil
-				}
-				log.Printf("Error reading Kafka message: %v", err)
-				continue
-			}
+		}
 
-			// Process message
-			if err := kc.processMessage(ctx, message, traceService); err != nil {
-				log.Printf("Error processing message: %v", err)
-				// Continue processing other messages
-				continue
-			}
+		go func() {
+			defer close(msg.done)
+			msg.commit = kc.processWithRetry(ctx, msg.message, traceService)
+		}()
+	}
+}
+
+// processWithRetry processes a message, retrying with exponential backoff
+// while storage applies backpressure. Only the save is retried when the
+// trace service allows it, so the trace is not processed twice. Messages
+// failing for other reasons are logged and skipped. It reports whether the
+// message may be committed, which is not the case when the consumer
+// stopped before processing it.
+func (kc *kafkaConsumer) processWithRetry(ctx context.Context, message kafka.Message, traceService domain.TraceService) bool {
+	process := func(ctx context.Context) error {
+		return kc.processMessage(ctx, message, traceService)
+	}
+
+	backoff := kc.retryBackoff
+	for {
+		err := process(ctx)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if !errors.Is(err, domain.ErrBackpressure) {
+			log.Printf("Error processing message: %v", err)
+			// Continue processing other messages
+			return true
+		}
+
+		var backpressure *domain.BackpressureError
+		if errors.As(err, &backpressure) {
+			process = backpressure.Retry
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, kc.maxRetryBackoff)
+	}
+}
+
+// commitInOrder commits processed messages in fetch order. Once a message
+// cannot be committed, neither can the ones after it, so they are
+// redelivered after a restart.
+func (kc *kafkaConsumer) commitInOrder(inflight <-chan *inflightMessage) {
+	stopped := false
+	for msg := range inflight {
+		<-msg.done
+		if stopped || !msg.commit {
+			stopped = true
+			continue
+		}
+
+		// Commit even while stopping, since the message was processed
+		if err := kc.reader.CommitMessages(context.Background(), msg.message); err != nil {
+			log.Printf("Error committing Kafka message: %v", err)
 		}
 	}
 }
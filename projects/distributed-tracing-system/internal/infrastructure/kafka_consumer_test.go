@@ -0,0 +1,236 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMessageReader serves a fixed list of messages and records commits
+type fakeMessageReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+}
+
+func (r *fakeMessageReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		message := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return message, nil
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeMessageReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (r *fakeMessageReader) Close() error {
+	return nil
+}
+
+func (r *fakeMessageReader) commits() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.committed...)
+}
+
+// fakeTraceService processes traces with a test function
+type fakeTraceService struct {
+	domain.TraceService
+	process func(ctx context.Context, trace *domain.Trace) error
+}
+
+func (s *fakeTraceService) ProcessTrace(ctx context.Context, trace *domain.Trace) error {
+	return s.process(ctx, trace)
+}
+
+func newConsumerTestMessages(t *testing.T, count int) []kafka.Message {
+	messages := make([]kafka.Message, count)
+	for i := range messages {
+		value, err := json.Marshal(domain.Trace{
+			ID:        domain.TraceID(fmt.Sprintf("trace-%d", i)),
+			Service:   "api",
+			Operation: "GET /users",
+		})
+		require.NoError(t, err)
+		messages[i] = kafka.Message{Offset: int64(i), Value: value}
+	}
+	return messages
+}
+
+func newTestKafkaConsumer(reader kafkaMessageReader, concurrency int) *kafkaConsumer {
+	return &kafkaConsumer{
+		reader:          reader,
+		topic:           "trace-events",
+		groupID:         "tracing-system",
+		concurrency:     concurrency,
+		retryBackoff:    time.Millisecond,
+		maxRetryBackoff: 4 * time.Millisecond,
+	}
+}
+
+// runConsumer runs the consumer until stop is called, which waits for it
+// to return
+func runConsumer(consumer *kafkaConsumer, service domain.TraceService) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Start(ctx, service)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestNewKafkaConsumer_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []KafkaConsumerOption
+	}{
+		{name: "zero concurrency", opts: []KafkaConsumerOption{WithConsumerConcurrency(0)}},
+		{name: "zero backoff", opts: []KafkaConsumerOption{WithBackpressureBackoff(0, time.Second)}},
+		{name: "max below initial backoff", opts: []KafkaConsumerOption{WithBackpressureBackoff(time.Second, time.Millisecond)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKafkaConsumer([]string{"localhost:9092"}, "trace-events", "tracing-system", tt.opts...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKafkaConsumer_CommitsInFetchOrder(t *testing.T) {
+	// Arrange: later messages finish first, and one message is invalid
+	messages := newConsumerTestMessages(t, 8)
+	messages[3].Value = []byte("not json")
+	reader := &fakeMessageReader{messages: messages}
+
+	var active, maxActive int32
+	service := &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+
+		var index int
+		fmt.Sscanf(string(trace.ID), "trace-%d", &index)
+		time.Sleep(time.Duration(8-index) * time.Millisecond)
+		return nil
+	}}
+
+	// Act
+	stop := runConsumer(newTestKafkaConsumer(reader, 3), service)
+	require.Eventually(t, func() bool {
+		return len(reader.commits()) == len(messages)
+	}, 5*time.Second, time.Millisecond)
+	stop()
+
+	// Assert
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7}, reader.commits())
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxActive), int32(3))
+}
+
+func TestKafkaConsumer_RetriesOnBackpressure(t *testing.T) {
+	// Arrange: storage refuses the first attempts
+	reader := &fakeMessageReader{messages: newConsumerTestMessages(t, 1)}
+
+	var attempts int32
+	service := &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		if atomic.AddInt32(&attempts, 1) <= 3 {
+			return fmt.Errorf("failed to save trace: %w", domain.ErrBackpressure)
+		}
+		return nil
+	}}
+
+	// Act
+	stop := runConsumer(newTestKafkaConsumer(reader, 1), service)
+	require.Eventually(t, func() bool {
+		return len(reader.commits()) == 1
+	}, 5*time.Second, time.Millisecond)
+	stop()
+
+	// Assert
+	assert.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+}
+
+func TestKafkaConsumer_RetriesOnlyTheSave(t *testing.T) {
+	// Arrange: the trace service hands back a retry of the refused save
+	reader := &fakeMessageReader{messages: newConsumerTestMessages(t, 1)}
+
+	var processed, saves int32
+	var save func(ctx context.Context) error
+	save = func(ctx context.Context) error {
+		if atomic.AddInt32(&saves, 1) <= 3 {
+			return fmt.Errorf("failed to save trace: %w", &domain.BackpressureError{Retry: save})
+		}
+		return nil
+	}
+	service := &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		atomic.AddInt32(&processed, 1)
+		return save(ctx)
+	}}
+
+	// Act
+	stop := runConsumer(newTestKafkaConsumer(reader, 1), service)
+	require.Eventually(t, func() bool {
+		return len(reader.commits()) == 1
+	}, 5*time.Second, time.Millisecond)
+	stop()
+
+	// Assert
+	assert.Equal(t, int32(1), atomic.LoadInt32(&processed))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&saves))
+}
+
+func TestKafkaConsumer_StopsCommittingAtUnprocessedMessage(t *testing.T) {
+	// Arrange: the second message is refused until the consumer stops
+	reader := &fakeMessageReader{messages: newConsumerTestMessages(t, 3)}
+
+	var processed sync.Map
+	service := &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		if trace.ID == "trace-1" {
+			return domain.ErrBackpressure
+		}
+		processed.Store(trace.ID, true)
+		return nil
+	}}
+
+	// Act
+	stop := runConsumer(newTestKafkaConsumer(reader, 3), service)
+	require.Eventually(t, func() bool {
+		_, ok := processed.Load(domain.TraceID("trace-2"))
+		return ok && len(reader.commits()) == 1
+	}, 5*time.Second, time.Millisecond)
+	stop()
+
+	// Assert: the refused message and the ones after it are redelivered
+	assert.Equal(t, []int64{0}, reader.commits())
+}
@@ -0,0 +1,168 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// maxInflightSpans bounds the span messages fetched but not yet committed
+const maxInflightSpans = 10000
+
+// kafkaSpanConsumer implements the KafkaSpanConsumer interface
+type kafkaSpanConsumer struct {
+	reader  kafkaMessageReader
+	topic   string
+	groupID string
+	// retryDelay paces retries of spans the assembler refused
+	retryDelay time.Duration
+}
+
+// NewKafkaSpanConsumer creates a new Kafka consumer for individual span messages
+func NewKafkaSpanConsumer(brokers []string, topic, groupID string) (domain.KafkaSpanConsumer, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("brokers list cannot be empty")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("topic cannot be empty")
+	}
+	if groupID == "" {
+		return nil, fmt.Errorf("group ID cannot be empty")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        brokers,
+		Topic:          topic,
+		GroupID:        groupID,
+		MinBytes:       10e3, // 10KB
+		MaxBytes:       10e6, // 10MB
+		CommitInterval: time.Second,
+		StartOffset:    kafka.LastOffset,
+	})
+
+	return &kafkaSpanConsumer{
+		reader:     reader,
+		topic:      topic,
+		groupID:    groupID,
+		retryDelay: time.Second,
+	}, nil
+}
+
+// Start starts consuming spans and hands them to the assembler. A message
+// is only committed once the trace holding its span is stored and every
+// message before it is committed, so spans still buffered by the assembler
+// are redelivered after a crash or a restart.
+func (kc *kafkaSpanConsumer) Start(ctx context.Context, assembler domain.SpanAssembler) error {
+	log.Printf("Starting Kafka span consumer for topic: %s, group: %s", kc.topic, kc.groupID)
+
+	inflight := make(chan *inflightMessage, maxInflightSpans)
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		kc.commitInOrder(ctx, inflight)
+	}()
+	defer func() {
+		close(inflight)
+		<-committed
+	}()
+
+	for {
+		message, err := kc.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("Kafka span consumer stopping due to context cancellation")
+				return ctx.Err()
+			}
+			log.Printf("Error reading Kafka span message: %v", err)
+			continue
+		}
+
+		msg := &inflightMessage{message: message, done: make(chan struct{})}
+		select {
+		case inflight <- msg:
+		case <-ctx.Done():
+			log.Println("Kafka span consumer stopping due to context cancellation")
+			return ctx.Err()
+		}
+
+		if !kc.processWithRetry(ctx, msg, assembler) {
+			log.Println("Kafka span consumer stopping due to context cancellation")
+			return ctx.Err()
+		}
+	}
+}
+
+// processWithRetry hands a message to the assembler, retrying while it
+// refuses the span. Messages that cannot be decoded or hold an invalid
+// span are logged and acknowledged right away. It returns false when the
+// consumer stopped before the span was accepted.
+func (kc *kafkaSpanConsumer) processWithRetry(ctx context.Context, msg *inflightMessage, assembler domain.SpanAssembler) bool {
+	var once sync.Once
+	ack := func() {
+		once.Do(func() {
+			msg.commit = true
+			close(msg.done)
+		})
+	}
+
+	var span domain.Span
+	if err := json.Unmarshal(msg.message.Value, &span); err != nil {
+		log.Printf("Error processing span message: failed to unmarshal span: %v", err)
+		ack()
+		return true
+	}
+
+	for {
+		err := assembler.AddSpanWithAck(ctx, &span, ack)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if errors.Is(err, domain.ErrInvalidTrace) {
+			log.Printf("Error processing span message: %v", err)
+			ack()
+			return true
+		}
+
+		log.Printf("Retrying span %s: %v", span.ID, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(kc.retryDelay):
+		}
+	}
+}
+
+// commitInOrder commits acknowledged messages in fetch order. It stops at
+// the first message not yet acknowledged when the consumer stops, so that
+// message and the ones after it are redelivered after a restart.
+func (kc *kafkaSpanConsumer) commitInOrder(ctx context.Context, inflight <-chan *inflightMessage) {
+	for msg := range inflight {
+		select {
+		case <-msg.done:
+		case <-ctx.Done():
+			return
+		}
+		if !msg.commit {
+			return
+		}
+
+		if err := kc.reader.CommitMessages(context.Background(), msg.message); err != nil {
+			log.Printf("Error committing Kafka span message: %v", err)
+		}
+	}
+}
+
+// Close closes the Kafka span consumer
+func (kc *kafkaSpanConsumer) Close() error {
+	return kc.reader.Close()
+}
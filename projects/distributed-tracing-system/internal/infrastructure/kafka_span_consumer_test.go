@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSpanAssembler keeps the acks of added spans until the test stores
+// their traces
+type fakeSpanAssembler struct {
+	domain.SpanAssembler
+
+	mu      sync.Mutex
+	acks    map[domain.TraceID][]func()
+	refusal error
+}
+
+func (a *fakeSpanAssembler) AddSpanWithAck(ctx context.Context, span *domain.Span, ack func()) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.refusal != nil {
+		err := a.refusal
+		a.refusal = nil
+		return err
+	}
+	a.acks[span.TraceID] = append(a.acks[span.TraceID], ack)
+	return nil
+}
+
+// store acknowledges the spans of a trace
+func (a *fakeSpanAssembler) store(traceID domain.TraceID) {
+	a.mu.Lock()
+	acks := a.acks[traceID]
+	delete(a.acks, traceID)
+	a.mu.Unlock()
+
+	for _, ack := range acks {
+		ack()
+	}
+}
+
+func (a *fakeSpanAssembler) added() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	count := 0
+	for _, acks := range a.acks {
+		count += len(acks)
+	}
+	return count
+}
+
+func newSpanTestMessage(t *testing.T, offset int64, traceID domain.TraceID, spanID domain.SpanID) kafka.Message {
+	value, err := json.Marshal(domain.Span{ID: spanID, TraceID: traceID})
+	require.NoError(t, err)
+	return kafka.Message{Offset: offset, Value: value}
+}
+
+// runSpanConsumer runs the consumer until stop is called, which waits for
+// it to return
+func runSpanConsumer(consumer *kafkaSpanConsumer, assembler domain.SpanAssembler) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Start(ctx, assembler)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestKafkaSpanConsumer_CommitsOnceTracesAreStored(t *testing.T) {
+	// Arrange: spans of two traces around an undecodable message
+	reader := &fakeMessageReader{messages: []kafka.Message{
+		newSpanTestMessage(t, 0, "trace-a", "a1"),
+		newSpanTestMessage(t, 1, "trace-b", "b1"),
+		{Offset: 2, Value: []byte("not json")},
+		newSpanTestMessage(t, 3, "trace-a", "a2"),
+	}}
+	assembler := &fakeSpanAssembler{acks: make(map[domain.TraceID][]func())}
+	consumer := &kafkaSpanConsumer{reader: reader, topic: "span-events", groupID: "tracing-system", retryDelay: time.Millisecond}
+
+	stop := runSpanConsumer(consumer, assembler)
+	defer stop()
+	require.Eventually(t, func() bool {
+		return assembler.added() == 3
+	}, 5*time.Second, time.Millisecond)
+
+	// Act & Assert: nothing is committed while the first trace is buffered
+	assembler.store("trace-b")
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, reader.commits())
+
+	assembler.store("trace-a")
+	require.Eventually(t, func() bool {
+		return len(reader.commits()) == 4
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, []int64{0, 1, 2, 3}, reader.commits())
+}
+
+func TestKafkaSpanConsumer_RetriesRefusedSpans(t *testing.T) {
+	// Arrange: the assembler refuses the span once
+	reader := &fakeMessageReader{messages: []kafka.Message{newSpanTestMessage(t, 0, "trace-a", "a1")}}
+	assembler := &fakeSpanAssembler{acks: make(map[domain.TraceID][]func()), refusal: domain.ErrBackpressure}
+	consumer := &kafkaSpanConsumer{reader: reader, topic: "span-events", groupID: "tracing-system", retryDelay: time.Millisecond}
+
+	// Act
+	stop := runSpanConsumer(consumer, assembler)
+	require.Eventually(t, func() bool {
+		return assembler.added() == 1
+	}, 5*time.Second, time.Millisecond)
+	assembler.store("trace-a")
+	require.Eventually(t, func() bool {
+		return len(reader.commits()) == 1
+	}, 5*time.Second, time.Millisecond)
+	stop()
+
+	// Assert
+	assert.Equal(t, []int64{0}, reader.commits())
+}
+
+func TestKafkaSpanConsumer_LeavesBufferedSpansUncommittedOnStop(t *testing.T) {
+	// Arrange
+	reader := &fakeMessageReader{messages: []kafka.Message{newSpanTestMessage(t, 0, "trace-a", "a1")}}
+	assembler := &fakeSpanAssembler{acks: make(map[domain.TraceID][]func())}
+	consumer := &kafkaSpanConsumer{reader: reader, topic: "span-events", groupID: "tracing-system", retryDelay: time.Millisecond}
+
+	// Act: the consumer stops before the trace is stored
+	stop := runSpanConsumer(consumer, assembler)
+	require.Eventually(t, func() bool {
+		return assembler.added() == 1
+	}, 5*time.Second, time.Millisecond)
+	stop()
+	assembler.store("trace-a")
+
+	// Assert: the span is redelivered after a restart
+	assert.Empty(t, reader.commits())
+}
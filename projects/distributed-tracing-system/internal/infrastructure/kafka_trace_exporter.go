@@ -0,0 +1,27 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// kafkaTraceExporter implements the JaegerExporter interface by publishing
+// traces to a Kafka topic, in the same format as trace events
+type kafkaTraceExporter struct {
+	producer domain.KafkaProducer
+}
+
+// NewKafkaTraceExporter creates an exporter publishing to a Kafka topic
+func NewKafkaTraceExporter(brokers []string, topic string) (domain.JaegerExporter, error) {
+	producer, err := NewKafkaProducer(brokers, topic)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaTraceExporter{producer: producer}, nil
+}
+
+// ExportTrace publishes the trace
+func (ke *kafkaTraceExporter) ExportTrace(ctx context.Context, trace *domain.Trace) error {
+	return ke.producer.PublishTraceEvent(ctx, trace)
+}
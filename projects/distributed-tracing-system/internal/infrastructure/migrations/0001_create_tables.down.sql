@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS spans;
+DROP TABLE IF EXISTS traces;
@@ -0,0 +1,40 @@
+-- Initial schema. IF NOT EXISTS adopts databases created before migrations
+-- were versioned.
+CREATE TABLE IF NOT EXISTS traces (
+	id VARCHAR(255) PRIMARY KEY,
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	tags JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS spans (
+	id VARCHAR(255) PRIMARY KEY,
+	trace_id VARCHAR(255) NOT NULL,
+	parent_id VARCHAR(255),
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	tags JSONB,
+	logs JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (trace_id) REFERENCES traces(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_traces_service ON traces(service);
+CREATE INDEX IF NOT EXISTS idx_traces_operation ON traces(operation);
+CREATE INDEX IF NOT EXISTS idx_traces_start_time ON traces(start_time);
+CREATE INDEX IF NOT EXISTS idx_traces_status ON traces(status);
+CREATE INDEX IF NOT EXISTS idx_spans_trace_id ON spans(trace_id);
+CREATE INDEX IF NOT EXISTS idx_spans_service ON spans(service);
+CREATE INDEX IF NOT EXISTS idx_traces_start_time_id ON traces(start_time, id);
+CREATE INDEX IF NOT EXISTS idx_traces_duration_id ON traces(duration, id);
+CREATE INDEX IF NOT EXISTS idx_traces_tags ON traces USING GIN (tags jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_spans_tags ON spans USING GIN (tags jsonb_path_ops);
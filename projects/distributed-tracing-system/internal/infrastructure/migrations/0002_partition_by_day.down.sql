@@ -0,0 +1,62 @@
+-- Fold the daily partitions back into plain tables. Trace IDs must be
+-- unique again, so only the latest version of a duplicated ID is kept.
+
+CREATE TABLE traces_unpartitioned (
+	id VARCHAR(255) PRIMARY KEY,
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	tags JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE spans_unpartitioned (
+	id VARCHAR(255) PRIMARY KEY,
+	trace_id VARCHAR(255) NOT NULL,
+	parent_id VARCHAR(255),
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	tags JSONB,
+	logs JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (trace_id) REFERENCES traces_unpartitioned(id) ON DELETE CASCADE
+);
+
+INSERT INTO traces_unpartitioned (id, service, operation, start_time, end_time, duration, status, tags, created_at)
+SELECT DISTINCT ON (id) id, service, operation, start_time, end_time, duration, status, tags, created_at
+FROM traces
+ORDER BY id, created_at DESC;
+
+INSERT INTO spans_unpartitioned (id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs, created_at)
+SELECT DISTINCT ON (id) id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs, created_at
+FROM spans
+WHERE trace_id IN (SELECT id FROM traces_unpartitioned)
+ORDER BY id, created_at DESC;
+
+-- Dropping the partitioned tables drops all of their partitions
+DROP TABLE spans;
+DROP TABLE traces;
+
+ALTER TABLE traces_unpartitioned RENAME TO traces;
+ALTER TABLE spans_unpartitioned RENAME TO spans;
+ALTER INDEX traces_unpartitioned_pkey RENAME TO traces_pkey;
+ALTER INDEX spans_unpartitioned_pkey RENAME TO spans_pkey;
+ALTER TABLE spans RENAME CONSTRAINT spans_unpartitioned_trace_id_fkey TO spans_trace_id_fkey;
+
+CREATE INDEX idx_traces_service ON traces(service);
+CREATE INDEX idx_traces_operation ON traces(operation);
+CREATE INDEX idx_traces_start_time ON traces(start_time);
+CREATE INDEX idx_traces_status ON traces(status);
+CREATE INDEX idx_spans_trace_id ON spans(trace_id);
+CREATE INDEX idx_spans_service ON spans(service);
+CREATE INDEX idx_traces_start_time_id ON traces(start_time, id);
+CREATE INDEX idx_traces_duration_id ON traces(duration, id);
+CREATE INDEX idx_traces_tags ON traces USING GIN (tags jsonb_path_ops);
+CREATE INDEX idx_spans_tags ON spans USING GIN (tags jsonb_path_ops);
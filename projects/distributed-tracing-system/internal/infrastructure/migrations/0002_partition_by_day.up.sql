@@ -0,0 +1,81 @@
+-- Range-partition traces and spans by day on start_time so that expired
+-- days can be dropped whole. Partitions are named traces_pYYYYMMDD and
+-- spans_pYYYYMMDD; the repository creates upcoming ones in the background.
+--
+-- A partitioned table's primary key must include the partition key, so
+-- trace IDs are no longer unique on their own and the spans foreign key
+-- goes away: the repository replaces and purges spans explicitly.
+
+ALTER TABLE spans RENAME TO spans_unpartitioned;
+ALTER TABLE traces RENAME TO traces_unpartitioned;
+ALTER INDEX spans_pkey RENAME TO spans_unpartitioned_pkey;
+ALTER INDEX traces_pkey RENAME TO traces_unpartitioned_pkey;
+
+CREATE TABLE traces (
+	id VARCHAR(255) NOT NULL,
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	tags JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id, start_time)
+) PARTITION BY RANGE (start_time);
+
+CREATE TABLE spans (
+	id VARCHAR(255) NOT NULL,
+	trace_id VARCHAR(255) NOT NULL,
+	parent_id VARCHAR(255),
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	tags JSONB,
+	logs JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (trace_id, id, start_time)
+) PARTITION BY RANGE (start_time);
+
+-- Create a partition for every day that holds existing data
+DO $$
+DECLARE
+	partition_day DATE;
+BEGIN
+	FOR partition_day IN
+		SELECT start_time::date FROM traces_unpartitioned
+		UNION
+		SELECT start_time::date FROM spans_unpartitioned
+	LOOP
+		EXECUTE format('CREATE TABLE %I PARTITION OF traces FOR VALUES FROM (%L) TO (%L)',
+			'traces_p' || to_char(partition_day, 'YYYYMMDD'), partition_day, partition_day + 1);
+		EXECUTE format('CREATE TABLE %I PARTITION OF spans FOR VALUES FROM (%L) TO (%L)',
+			'spans_p' || to_char(partition_day, 'YYYYMMDD'), partition_day, partition_day + 1);
+	END LOOP;
+END
+$$;
+
+INSERT INTO traces (id, service, operation, start_time, end_time, duration, status, tags, created_at)
+SELECT id, service, operation, start_time, end_time, duration, status, tags, created_at
+FROM traces_unpartitioned;
+
+INSERT INTO spans (id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs, created_at)
+SELECT id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs, created_at
+FROM spans_unpartitioned;
+
+DROP TABLE spans_unpartitioned;
+DROP TABLE traces_unpartitioned;
+
+-- Lookups of spans by trace use the primary key
+CREATE INDEX idx_traces_service ON traces(service);
+CREATE INDEX idx_traces_operation ON traces(operation);
+CREATE INDEX idx_traces_start_time ON traces(start_time);
+CREATE INDEX idx_traces_status ON traces(status);
+CREATE INDEX idx_spans_service ON spans(service);
+CREATE INDEX idx_traces_start_time_id ON traces(start_time, id);
+CREATE INDEX idx_traces_duration_id ON traces(duration, id);
+CREATE INDEX idx_traces_tags ON traces USING GIN (tags jsonb_path_ops);
+CREATE INDEX idx_spans_tags ON spans USING GIN (tags jsonb_path_ops);
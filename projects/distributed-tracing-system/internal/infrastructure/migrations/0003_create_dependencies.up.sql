@@ -0,0 +1,14 @@
+-- Calls between services aggregated per time window. Latency buckets
+-- follow domain.LatencyBounds with a final overflow bucket, so
+-- windows can be merged and percentiles estimated over any range.
+
+CREATE TABLE dependencies (
+	window_start TIMESTAMP NOT NULL,
+	caller VARCHAR(255) NOT NULL,
+	callee VARCHAR(255) NOT NULL,
+	call_count BIGINT NOT NULL,
+	error_count BIGINT NOT NULL,
+	duration_sum BIGINT NOT NULL,
+	latency_buckets BIGINT[] NOT NULL,
+	PRIMARY KEY (window_start, caller, callee)
+);
@@ -0,0 +1 @@
+DROP TABLE trace_rollups;
@@ -0,0 +1,19 @@
+-- Traces aggregated per service, operation and time bucket, maintained as
+-- traces are saved. resolution_seconds is 60 for minute rollups and 3600
+-- for hourly ones. Duration buckets follow domain.LatencyBounds with a
+-- final overflow bucket.
+
+CREATE TABLE trace_rollups (
+	resolution_seconds INTEGER NOT NULL,
+	bucket_start TIMESTAMP NOT NULL,
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL,
+	trace_count BIGINT NOT NULL,
+	span_count BIGINT NOT NULL,
+	error_count BIGINT NOT NULL,
+	duration_sum BIGINT NOT NULL,
+	duration_buckets BIGINT[] NOT NULL,
+	PRIMARY KEY (resolution_seconds, bucket_start, service, operation)
+);
+
+CREATE INDEX idx_trace_rollups_service ON trace_rollups(service, resolution_seconds, bucket_start);
@@ -0,0 +1,14 @@
+-- Latency objectives evaluated against ingested traces. Durations are in
+-- nanoseconds; an empty operation covers every operation of the service.
+
+CREATE TABLE slos (
+	id VARCHAR(64) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	service VARCHAR(255) NOT NULL,
+	operation VARCHAR(255) NOT NULL DEFAULT '',
+	threshold BIGINT NOT NULL,
+	target DOUBLE PRECISION NOT NULL,
+	objective_window BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE slo_buckets;
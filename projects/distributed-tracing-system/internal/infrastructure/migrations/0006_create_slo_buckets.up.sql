@@ -0,0 +1,10 @@
+-- Traces counted per SLO and minute bucket. Instances add their counts to
+-- the same rows, and the buckets of a deleted SLO are deleted with it.
+
+CREATE TABLE slo_buckets (
+	slo_id VARCHAR(64) NOT NULL REFERENCES slos(id) ON DELETE CASCADE,
+	bucket_start TIMESTAMP NOT NULL,
+	total_count BIGINT NOT NULL,
+	good_count BIGINT NOT NULL,
+	PRIMARY KEY (slo_id, bucket_start)
+);
@@ -0,0 +1,508 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+const (
+	// otlpServiceNameKey is the resource attribute holding the service name
+	otlpServiceNameKey = "service.name"
+	// otlpUnknownService is used when a resource carries no service name
+	otlpUnknownService = "unknown_service"
+	// otlpScopeNameTag is the span tag holding the instrumentation scope name
+	otlpScopeNameTag = "otel.scope.name"
+	// otlpStatusDescriptionTag is the span tag holding the error status message
+	otlpStatusDescriptionTag = "otel.status_description"
+	// originalTraceIDKey and originalSpanIDKey keep IDs that are not hex
+	// encoded and had to be hashed into OTLP IDs
+	originalTraceIDKey = "tracing.original_trace_id"
+	originalSpanIDKey  = "tracing.original_span_id"
+)
+
+// OTLPToTraces converts OTLP resource spans into domain traces grouped by
+// trace ID. It returns the assembled traces and the number of spans that
+// were rejected because they could not be mapped.
+func OTLPToTraces(resourceSpans []*tracepb.ResourceSpans) ([]*domain.Trace, int) {
+	spansByTrace := make(map[domain.TraceID][]domain.Span)
+	var order []domain.TraceID
+	rejected := 0
+
+	for _, rs := range resourceSpans {
+		if rs == nil {
+			continue
+		}
+
+		serviceName, resourceTags := otlpResourceTags(rs)
+
+		for _, ss := range rs.GetScopeSpans() {
+			scopeName := ss.GetScope().GetName()
+
+			for _, otlpSpan := range ss.GetSpans() {
+				span, err := otlpSpanToDomain(otlpSpan, serviceName, resourceTags, scopeName)
+				if err != nil {
+					rejected++
+					continue
+				}
+
+				if _, ok := spansByTrace[span.TraceID]; !ok {
+					order = append(order, span.TraceID)
+				}
+				spansByTrace[span.TraceID] = append(spansByTrace[span.TraceID], span)
+			}
+		}
+	}
+
+	traces := make([]*domain.Trace, 0, len(order))
+	for _, traceID := range order {
+		traces = append(traces, domain.BuildTrace(traceID, spansByTrace[traceID]))
+	}
+
+	return traces, rejected
+}
+
+// otlpResourceTags extracts the service name and the remaining resource attributes
+func otlpResourceTags(rs *tracepb.ResourceSpans) (domain.ServiceName, map[string]string) {
+	serviceName := domain.ServiceName(otlpUnknownService)
+	tags := make(map[string]string)
+
+	for _, attr := range rs.GetResource().GetAttributes() {
+		value := otlpValueToString(attr.GetValue())
+		if attr.GetKey() == otlpServiceNameKey {
+			if value != "" {
+				serviceName = domain.ServiceName(value)
+			}
+			continue
+		}
+		tags[attr.GetKey()] = value
+	}
+
+	return serviceName, tags
+}
+
+// otlpSpanToDomain converts a single OTLP span into a domain span
+func otlpSpanToDomain(otlpSpan *tracepb.Span, service domain.ServiceName, resourceTags map[string]string, scopeName string) (domain.Span, error) {
+	if otlpSpan == nil {
+		return domain.Span{}, fmt.Errorf("span cannot be nil")
+	}
+	if isZeroID(otlpSpan.GetTraceId()) {
+		return domain.Span{}, fmt.Errorf("trace ID is required")
+	}
+	if isZeroID(otlpSpan.GetSpanId()) {
+		return domain.Span{}, fmt.Errorf("span ID is required")
+	}
+
+	startTime := time.Unix(0, int64(otlpSpan.GetStartTimeUnixNano())).UTC()
+	endTime := time.Unix(0, int64(otlpSpan.GetEndTimeUnixNano())).UTC()
+	if otlpSpan.GetEndTimeUnixNano() < otlpSpan.GetStartTimeUnixNano() {
+		endTime = startTime
+	}
+
+	span := domain.Span{
+		ID:        domain.SpanID(hex.EncodeToString(otlpSpan.GetSpanId())),
+		TraceID:   domain.TraceID(hex.EncodeToString(otlpSpan.GetTraceId())),
+		Service:   service,
+		Operation: domain.OperationName(otlpSpan.GetName()),
+		StartTime: startTime,
+		EndTime:   endTime,
+		Duration:  endTime.Sub(startTime),
+		Tags:      make(map[string]string, len(resourceTags)+len(otlpSpan.GetAttributes())+2),
+		Status:    domain.SpanStatusOK,
+	}
+
+	if !isZeroID(otlpSpan.GetParentSpanId()) {
+		parentID := domain.SpanID(hex.EncodeToString(otlpSpan.GetParentSpanId()))
+		span.ParentID = &parentID
+	}
+
+	// Resource attributes first so span attributes win on key collisions
+	for key, value := range resourceTags {
+		span.Tags[key] = value
+	}
+	for _, attr := range otlpSpan.GetAttributes() {
+		span.Tags[attr.GetKey()] = otlpValueToString(attr.GetValue())
+	}
+	if kind := otlpSpanKindName(otlpSpan.GetKind()); kind != "" {
+		span.Tags[domain.SpanKindTag] = kind
+	}
+	if scopeName != "" {
+		span.Tags[otlpScopeNameTag] = scopeName
+	}
+
+	if otlpSpan.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		span.Status = domain.SpanStatusError
+		if message := otlpSpan.GetStatus().GetMessage(); message != "" {
+			span.Tags[otlpStatusDescriptionTag] = message
+		}
+	}
+
+	for _, event := range otlpSpan.GetEvents() {
+		fields := make(map[string]string, len(event.GetAttributes()))
+		for _, attr := range event.GetAttributes() {
+			fields[attr.GetKey()] = otlpValueToString(attr.GetValue())
+		}
+		span.Logs = append(span.Logs, domain.Log{
+			Timestamp: time.Unix(0, int64(event.GetTimeUnixNano())).UTC(),
+			Message:   event.GetName(),
+			Fields:    fields,
+		})
+	}
+
+	return span, nil
+}
+
+// otlpSpanKindName maps an OTLP span kind to the lowercase name stored in span tags
+func otlpSpanKindName(kind tracepb.Span_SpanKind) string {
+	switch kind {
+	case tracepb.Span_SPAN_KIND_SERVER:
+		return "server"
+	case tracepb.Span_SPAN_KIND_CLIENT:
+		return "client"
+	case tracepb.Span_SPAN_KIND_PRODUCER:
+		return "producer"
+	case tracepb.Span_SPAN_KIND_CONSUMER:
+		return "consumer"
+	case tracepb.Span_SPAN_KIND_INTERNAL:
+		return "internal"
+	default:
+		return ""
+	}
+}
+
+// otlpValueToString renders an OTLP attribute value as a string tag
+func otlpValueToString(value *commonpb.AnyValue) string {
+	if value == nil {
+		return ""
+	}
+
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]string, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, otlpValueToString(item))
+		}
+		encoded, _ := json.Marshal(values)
+		return string(encoded)
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]string, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = otlpValueToString(kv.GetValue())
+		}
+		encoded, _ := json.Marshal(values)
+		return string(encoded)
+	default:
+		return ""
+	}
+}
+
+// isZeroID reports whether an OTLP trace or span ID is missing
+func isZeroID(id []byte) bool {
+	for _, b := range id {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeOTLPJSON rewrites the hex-encoded trace and span IDs mandated by
+// the OTLP/JSON spec into the base64 form expected by protojson. Numbers
+// are kept as written: 64-bit timestamps and integers do not fit a float64.
+func NormalizeOTLPJSON(body []byte) ([]byte, error) {
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP JSON: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("failed to parse OTLP JSON: unexpected data after the request")
+	}
+
+	for _, rs := range jsonObjects(payload["resourceSpans"]) {
+		for _, ss := range jsonObjects(rs["scopeSpans"]) {
+			for _, span := range jsonObjects(ss["spans"]) {
+				hexFieldsToBase64(span, "traceId", "spanId", "parentSpanId")
+				for _, link := range jsonObjects(span["links"]) {
+					hexFieldsToBase64(link, "traceId", "spanId")
+				}
+			}
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// jsonObjects returns the objects contained in a decoded JSON array
+func jsonObjects(value interface{}) []map[string]interface{} {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	objects := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// hexFieldsToBase64 converts hex string fields of a JSON object to base64
+func hexFieldsToBase64(object map[string]interface{}, keys ...string) {
+	for _, key := range keys {
+		value, ok := object[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		decoded, err := hex.DecodeString(strings.ToLower(value))
+		if err != nil || (len(decoded) != 16 && len(decoded) != 8) {
+			// Not hex; assume the client already used base64
+			continue
+		}
+		object[key] = base64.StdEncoding.EncodeToString(decoded)
+	}
+}
+
+// TracesToOTLP converts domain traces into OTLP resource spans, one per
+// service, keeping trace and span IDs, parent links, timestamps, tags,
+// logs and statuses. It is the inverse of OTLPToTraces.
+func TracesToOTLP(traces []*domain.Trace) []*tracepb.ResourceSpans {
+	var resourceSpans []*tracepb.ResourceSpans
+	byService := make(map[domain.ServiceName]*tracepb.ResourceSpans)
+	byScope := make(map[*tracepb.ResourceSpans]map[string]*tracepb.ScopeSpans)
+
+	for _, trace := range traces {
+		for _, span := range forwardedSpans(trace) {
+			rs, ok := byService[span.Service]
+			if !ok {
+				rs = &tracepb.ResourceSpans{
+					Resource: &resourcepb.Resource{
+						Attributes: []*commonpb.KeyValue{otlpStringAttribute(otlpServiceNameKey, string(span.Service))},
+					},
+				}
+				byService[span.Service] = rs
+				byScope[rs] = make(map[string]*tracepb.ScopeSpans)
+				resourceSpans = append(resourceSpans, rs)
+			}
+
+			scopeName := span.Tags[otlpScopeNameTag]
+			ss, ok := byScope[rs][scopeName]
+			if !ok {
+				ss = &tracepb.ScopeSpans{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+				byScope[rs][scopeName] = ss
+				rs.ScopeSpans = append(rs.ScopeSpans, ss)
+			}
+			ss.Spans = append(ss.Spans, domainSpanToOTLP(span))
+		}
+	}
+
+	return resourceSpans
+}
+
+// domainSpanToOTLP converts a single domain span into an OTLP span
+func domainSpanToOTLP(span domain.Span) *tracepb.Span {
+	traceID, spanID := otlpTraceID(span.TraceID), otlpSpanID(span.ID)
+	otlpSpan := &tracepb.Span{
+		TraceId:           traceID[:],
+		SpanId:            spanID[:],
+		Name:              string(span.Operation),
+		Kind:              otlpSpanKind(span.Tags[domain.SpanKindTag]),
+		StartTimeUnixNano: uint64(span.StartTime.UnixNano()),
+		EndTimeUnixNano:   uint64(span.EndTime.UnixNano()),
+		Status:            &tracepb.Status{},
+	}
+	if span.ParentID != nil {
+		parentID := otlpSpanID(*span.ParentID)
+		otlpSpan.ParentSpanId = parentID[:]
+	}
+
+	for _, key := range sortedKeys(span.Tags) {
+		switch key {
+		case domain.SpanKindTag, otlpScopeNameTag, otlpStatusDescriptionTag:
+			continue
+		}
+		otlpSpan.Attributes = append(otlpSpan.Attributes, otlpStringAttribute(key, span.Tags[key]))
+	}
+	if !isHexID(string(span.TraceID), len(traceID)) {
+		otlpSpan.Attributes = append(otlpSpan.Attributes, otlpStringAttribute(originalTraceIDKey, string(span.TraceID)))
+	}
+	if !isHexID(string(span.ID), len(spanID)) {
+		otlpSpan.Attributes = append(otlpSpan.Attributes, otlpStringAttribute(originalSpanIDKey, string(span.ID)))
+	}
+
+	if span.Status == domain.SpanStatusError {
+		otlpSpan.Status.Code = tracepb.Status_STATUS_CODE_ERROR
+		otlpSpan.Status.Message = span.Tags[otlpStatusDescriptionTag]
+	}
+
+	for _, log := range span.Logs {
+		event := &tracepb.Span_Event{
+			TimeUnixNano: uint64(log.Timestamp.UnixNano()),
+			Name:         log.Message,
+		}
+		for _, key := range sortedKeys(log.Fields) {
+			event.Attributes = append(event.Attributes, otlpStringAttribute(key, log.Fields[key]))
+		}
+		otlpSpan.Events = append(otlpSpan.Events, event)
+	}
+
+	return otlpSpan
+}
+
+// forwardedSpans returns the spans of a trace ready to be forwarded: the
+// trace's service fills in missing span services, end times are derived
+// from durations and root spans carry the trace tags. A trace without
+// spans is forwarded as a single span covering the trace.
+func forwardedSpans(trace *domain.Trace) []domain.Span {
+	if len(trace.Spans) == 0 {
+		status := domain.SpanStatusOK
+		if trace.Status == domain.TraceStatusError || trace.Status == domain.TraceStatusTimeout {
+			status = domain.SpanStatusError
+		}
+		return []domain.Span{{
+			ID:        domain.SpanID(trace.ID),
+			TraceID:   trace.ID,
+			Service:   trace.Service,
+			Operation: trace.Operation,
+			StartTime: trace.StartTime,
+			EndTime:   trace.EndTime,
+			Duration:  trace.Duration,
+			Tags:      trace.Tags,
+			Status:    status,
+		}}
+	}
+
+	spans := make([]domain.Span, len(trace.Spans))
+	for i, span := range trace.Spans {
+		if span.TraceID == "" {
+			span.TraceID = trace.ID
+		}
+		if span.Service == "" {
+			span.Service = trace.Service
+		}
+		if span.EndTime.IsZero() {
+			span.EndTime = span.StartTime.Add(span.Duration)
+		}
+		if span.ParentID == nil && len(trace.Tags) > 0 {
+			tags := make(map[string]string, len(trace.Tags)+len(span.Tags))
+			for key, value := range trace.Tags {
+				tags[key] = value
+			}
+			for key, value := range span.Tags {
+				tags[key] = value
+			}
+			span.Tags = tags
+		}
+		spans[i] = span
+	}
+	return spans
+}
+
+// otlpTraceID returns the 16 byte OTLP form of a trace ID. Hex IDs keep
+// their value, shorter ones zero-padded on the left as Jaeger does for
+// 64-bit IDs; other IDs are hashed.
+func otlpTraceID(id domain.TraceID) [16]byte {
+	var otlpID [16]byte
+	otlpIDBytes(string(id), otlpID[:])
+	return otlpID
+}
+
+// otlpSpanID returns the 8 byte OTLP form of a span ID, like otlpTraceID
+func otlpSpanID(id domain.SpanID) [8]byte {
+	var otlpID [8]byte
+	otlpIDBytes(string(id), otlpID[:])
+	return otlpID
+}
+
+// otlpIDBytes fills dst with the hex decoded ID, or with a hash of the ID
+// when it is not hex, too long or zero
+func otlpIDBytes(id string, dst []byte) {
+	if isHexID(id, len(dst)) {
+		decoded, _ := hex.DecodeString(strings.Repeat("0", 2*len(dst)-len(id)) + strings.ToLower(id))
+		copy(dst, decoded)
+		return
+	}
+	sum := sha256.Sum256([]byte(id))
+	copy(dst, sum[:])
+}
+
+// isHexID reports whether an ID is a non-zero hex string of at most size bytes
+func isHexID(id string, size int) bool {
+	if id == "" || len(id) > 2*size {
+		return false
+	}
+	zero := true
+	for _, c := range id {
+		switch {
+		case c == '0':
+		case c >= '1' && c <= '9', c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			zero = false
+		default:
+			return false
+		}
+	}
+	return !zero
+}
+
+// otlpSpanKind maps a span kind tag to its OTLP span kind
+func otlpSpanKind(kind string) tracepb.Span_SpanKind {
+	switch strings.ToLower(kind) {
+	case "server":
+		return tracepb.Span_SPAN_KIND_SERVER
+	case "client":
+		return tracepb.Span_SPAN_KIND_CLIENT
+	case "producer":
+		return tracepb.Span_SPAN_KIND_PRODUCER
+	case "consumer":
+		return tracepb.Span_SPAN_KIND_CONSUMER
+	case "internal":
+		return tracepb.Span_SPAN_KIND_INTERNAL
+	default:
+		return tracepb.Span_SPAN_KIND_UNSPECIFIED
+	}
+}
+
+// otlpStringAttribute returns a string OTLP attribute
+func otlpStringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// sortedKeys returns the keys of a string map in order, so converted
+// attributes are deterministic
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
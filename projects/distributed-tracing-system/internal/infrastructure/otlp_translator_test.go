@@ -0,0 +1,305 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func TestOTLPToTraces(t *testing.T) {
+	traceID := []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+	rootID := []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74}
+	childID := []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x73}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	resourceSpans := []*tracepb.ResourceSpans{
+		{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{
+					stringAttr("service.name", "checkout"),
+					stringAttr("host.name", "node-1"),
+				},
+			},
+			ScopeSpans: []*tracepb.ScopeSpans{
+				{
+					Scope: &commonpb.InstrumentationScope{Name: "net/http"},
+					Spans: []*tracepb.Span{
+						{
+							TraceId:           traceID,
+							SpanId:            rootID,
+							Name:              "POST /checkout",
+							Kind:              tracepb.Span_SPAN_KIND_SERVER,
+							StartTimeUnixNano: uint64(start.UnixNano()),
+							EndTimeUnixNano:   uint64(start.Add(300 * time.Millisecond).UnixNano()),
+							Attributes:        []*commonpb.KeyValue{stringAttr("http.method", "POST")},
+						},
+					},
+				},
+			},
+		},
+		{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{stringAttr("service.name", "payments")},
+			},
+			ScopeSpans: []*tracepb.ScopeSpans{
+				{
+					Spans: []*tracepb.Span{
+						{
+							TraceId:           traceID,
+							SpanId:            childID,
+							ParentSpanId:      rootID,
+							Name:              "charge",
+							Kind:              tracepb.Span_SPAN_KIND_CLIENT,
+							StartTimeUnixNano: uint64(start.Add(50 * time.Millisecond).UnixNano()),
+							EndTimeUnixNano:   uint64(start.Add(250 * time.Millisecond).UnixNano()),
+							Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "card declined"},
+							Events: []*tracepb.Span_Event{
+								{
+									TimeUnixNano: uint64(start.Add(100 * time.Millisecond).UnixNano()),
+									Name:         "retry",
+									Attributes:   []*commonpb.KeyValue{stringAttr("attempt", "2")},
+								},
+							},
+						},
+						{
+							// Missing trace ID, must be rejected
+							SpanId: []byte{1, 2, 3, 4, 5, 6, 7, 8},
+							Name:   "orphan",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	traces, rejected := OTLPToTraces(resourceSpans)
+
+	assert.Equal(t, 1, rejected)
+	require.Len(t, traces, 1)
+
+	trace := traces[0]
+	assert.Equal(t, domain.TraceID("5b8efff798038103d269b633813fc60c"), trace.ID)
+	assert.Equal(t, domain.ServiceName("checkout"), trace.Service)
+	assert.Equal(t, domain.OperationName("POST /checkout"), trace.Operation)
+	assert.Equal(t, start, trace.StartTime)
+	assert.Equal(t, 300*time.Millisecond, trace.Duration)
+	assert.Equal(t, domain.TraceStatusError, trace.Status)
+	require.Len(t, trace.Spans, 2)
+
+	root := trace.Spans[0]
+	assert.Equal(t, domain.SpanID("eee19b7ec3c1b174"), root.ID)
+	assert.Nil(t, root.ParentID)
+	assert.Equal(t, "server", root.Tags[domain.SpanKindTag])
+	assert.Equal(t, "node-1", root.Tags["host.name"])
+	assert.Equal(t, "POST", root.Tags["http.method"])
+	assert.Equal(t, "net/http", root.Tags["otel.scope.name"])
+
+	child := trace.Spans[1]
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, root.ID, *child.ParentID)
+	assert.Equal(t, domain.ServiceName("payments"), child.Service)
+	assert.Equal(t, domain.SpanStatusError, child.Status)
+	assert.Equal(t, "card declined", child.Tags["otel.status_description"])
+	require.Len(t, child.Logs, 1)
+	assert.Equal(t, "retry", child.Logs[0].Message)
+	assert.Equal(t, "2", child.Logs[0].Fields["attempt"])
+}
+
+func TestOTLPToTraces_MissingServiceName(t *testing.T) {
+	resourceSpans := []*tracepb.ResourceSpans{
+		{
+			ScopeSpans: []*tracepb.ScopeSpans{
+				{
+					Spans: []*tracepb.Span{
+						{
+							TraceId:           []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+							SpanId:            []byte{1, 2, 3, 4, 5, 6, 7, 8},
+							Name:              "work",
+							StartTimeUnixNano: 1000,
+							EndTimeUnixNano:   2000,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	traces, rejected := OTLPToTraces(resourceSpans)
+
+	assert.Equal(t, 0, rejected)
+	require.Len(t, traces, 1)
+	assert.Equal(t, domain.ServiceName(otlpUnknownService), traces[0].Service)
+	assert.Equal(t, domain.TraceStatusSuccess, traces[0].Status)
+}
+
+func TestNormalizeOTLPJSON(t *testing.T) {
+	body := []byte(`{
+		"resourceSpans": [{
+			"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}]},
+			"scopeSpans": [{
+				"spans": [{
+					"traceId": "5B8EFFF798038103D269B633813FC60C",
+					"spanId": "EEE19B7EC3C1B174",
+					"parentSpanId": "EEE19B7EC3C1B173",
+					"name": "work",
+					"kind": 2,
+					"startTimeUnixNano": "1544712660000000000",
+					"endTimeUnixNano": "1544712661000000000"
+				}]
+			}]
+		}]
+	}`)
+
+	normalized, err := NormalizeOTLPJSON(body)
+	require.NoError(t, err)
+
+	req := &coltracepb.ExportTraceServiceRequest{}
+	require.NoError(t, protojson.Unmarshal(normalized, req))
+
+	traces, rejected := OTLPToTraces(req.GetResourceSpans())
+	assert.Equal(t, 0, rejected)
+	require.Len(t, traces, 1)
+	assert.Equal(t, domain.TraceID("5b8efff798038103d269b633813fc60c"), traces[0].ID)
+	require.Len(t, traces[0].Spans, 1)
+	assert.Equal(t, domain.SpanID("eee19b7ec3c1b174"), traces[0].Spans[0].ID)
+	assert.Equal(t, "server", traces[0].Spans[0].Tags[domain.SpanKindTag])
+}
+
+func TestNormalizeOTLPJSON_KeepsNumericPrecision(t *testing.T) {
+	// Timestamps and integers may be JSON numbers, beyond float64 precision
+	body := []byte(`{
+		"resourceSpans": [{
+			"scopeSpans": [{
+				"spans": [{
+					"traceId": "5b8efff798038103d269b633813fc60c",
+					"spanId": "eee19b7ec3c1b174",
+					"name": "work",
+					"startTimeUnixNano": 1700000000123456789,
+					"endTimeUnixNano": 1700000000987654321,
+					"attributes": [{"key": "count", "value": {"intValue": 9007199254740993}}]
+				}]
+			}]
+		}]
+	}`)
+
+	normalized, err := NormalizeOTLPJSON(body)
+	require.NoError(t, err)
+
+	req := &coltracepb.ExportTraceServiceRequest{}
+	require.NoError(t, protojson.Unmarshal(normalized, req))
+
+	span := req.GetResourceSpans()[0].GetScopeSpans()[0].GetSpans()[0]
+	assert.Equal(t, uint64(1700000000123456789), span.GetStartTimeUnixNano())
+	assert.Equal(t, uint64(1700000000987654321), span.GetEndTimeUnixNano())
+	assert.Equal(t, int64(9007199254740993), span.GetAttributes()[0].GetValue().GetIntValue())
+}
+
+func TestNormalizeOTLPJSON_InvalidJSON(t *testing.T) {
+	_, err := NormalizeOTLPJSON([]byte("{not json"))
+	assert.Error(t, err)
+
+	_, err = NormalizeOTLPJSON([]byte(`{"resourceSpans": []} {}`))
+	assert.Error(t, err)
+}
+
+func TestTracesToOTLP_RoundTrip(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	parentID := domain.SpanID("eee19b7ec3c1b174")
+	trace := domain.BuildTrace("5b8efff798038103d269b633813fc60c", []domain.Span{
+		{
+			ID:        parentID,
+			TraceID:   "5b8efff798038103d269b633813fc60c",
+			Service:   "frontend",
+			Operation: "GET /checkout",
+			StartTime: start,
+			EndTime:   start.Add(100 * time.Millisecond),
+			Duration:  100 * time.Millisecond,
+			Tags:      map[string]string{domain.SpanKindTag: "server", "http.method": "GET"},
+			Status:    domain.SpanStatusOK,
+		},
+		{
+			ID:        "eee19b7ec3c1b173",
+			TraceID:   "5b8efff798038103d269b633813fc60c",
+			ParentID:  &parentID,
+			Service:   "payments",
+			Operation: "charge",
+			StartTime: start.Add(10 * time.Millisecond),
+			EndTime:   start.Add(60 * time.Millisecond),
+			Duration:  50 * time.Millisecond,
+			Tags:      map[string]string{otlpStatusDescriptionTag: "card declined"},
+			Logs: []domain.Log{
+				{Timestamp: start.Add(20 * time.Millisecond), Message: "retry", Fields: map[string]string{"attempt": "2"}},
+			},
+			Status: domain.SpanStatusError,
+		},
+	})
+
+	resourceSpans := TracesToOTLP([]*domain.Trace{trace})
+	require.Len(t, resourceSpans, 2)
+
+	traces, rejected := OTLPToTraces(resourceSpans)
+	assert.Equal(t, 0, rejected)
+	require.Len(t, traces, 1)
+	assert.Equal(t, trace.ID, traces[0].ID)
+	require.Len(t, traces[0].Spans, 2)
+
+	for i, want := range trace.Spans {
+		got := traces[0].Spans[i]
+		assert.Equal(t, want.ID, got.ID)
+		assert.Equal(t, want.ParentID, got.ParentID)
+		assert.Equal(t, want.Service, got.Service)
+		assert.Equal(t, want.Operation, got.Operation)
+		assert.True(t, want.StartTime.Equal(got.StartTime))
+		assert.True(t, want.EndTime.Equal(got.EndTime))
+		assert.Equal(t, want.Status, got.Status)
+		assert.Equal(t, want.Tags, got.Tags)
+	}
+	require.Len(t, traces[0].Spans[1].Logs, 1)
+	assert.Equal(t, "retry", traces[0].Spans[1].Logs[0].Message)
+	assert.Equal(t, "2", traces[0].Spans[1].Logs[0].Fields["attempt"])
+}
+
+func TestTracesToOTLP_NonHexIDs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := &domain.Trace{
+		ID:        "trace-1",
+		Service:   "checkout",
+		Operation: "pay",
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Duration:  time.Second,
+		Status:    domain.TraceStatusSuccess,
+		Spans: []domain.Span{
+			{ID: "root", Operation: "pay", StartTime: start, Duration: time.Second, Status: domain.SpanStatusOK},
+		},
+	}
+
+	resourceSpans := TracesToOTLP([]*domain.Trace{trace})
+	require.Len(t, resourceSpans, 1)
+	span := resourceSpans[0].ScopeSpans[0].Spans[0]
+
+	assert.Len(t, span.TraceId, 16)
+	assert.Len(t, span.SpanId, 8)
+	assert.Equal(t, uint64(start.Add(time.Second).UnixNano()), span.EndTimeUnixNano)
+	attributes := make(map[string]string)
+	for _, attr := range span.Attributes {
+		attributes[attr.GetKey()] = attr.GetValue().GetStringValue()
+	}
+	assert.Equal(t, "trace-1", attributes[originalTraceIDKey])
+	assert.Equal(t, "root", attributes[originalSpanIDKey])
+}
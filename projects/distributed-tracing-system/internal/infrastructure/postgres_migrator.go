@@ -0,0 +1,284 @@
+package infrastructure
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// migrationFiles holds the schema migrations, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock key that serializes migrations
+// across processes sharing a database
+const migrationLockID = 7261034512
+
+// migration is a versioned schema change with its revert
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+// postgresMigrator implements the SchemaMigrator interface, recording
+// applied versions in the schema_migrations table
+type postgresMigrator struct {
+	db         *sqlx.DB
+	migrations []migration
+}
+
+// NewPostgresMigrator creates a migrator for the embedded schema migrations
+func NewPostgresMigrator(db *sqlx.DB) (domain.SchemaMigrator, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+
+	migrations, err := loadMigrations(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresMigrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations reads the migrations of a directory in version order.
+// Every migration needs both an up and a down file.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		base, direction := strings.TrimSuffix(entry.Name(), ".sql"), ""
+		if i := strings.LastIndex(base, "."); i >= 0 {
+			base, direction = base[:i], base[i+1:]
+		}
+		versionPart, name, ok := strings.Cut(base, "_")
+		if !ok || name == "" || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q, expected <version>_<name>.<up|down>.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// Up applies the pending migrations in order, each in its own transaction
+func (m *postgresMigrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		versions, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := versions[mig.version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, mig, mig.up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.version, mig.name); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts up to steps applied migrations, newest first
+func (m *postgresMigrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive")
+	}
+
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		versions, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := versions[mig.version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, mig, mig.down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.version); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists the known migrations and when they were applied
+func (m *postgresMigrator) Status(ctx context.Context) ([]domain.MigrationStatus, error) {
+	versions := map[int64]time.Time{}
+
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check schema_migrations table: %w", err)
+	}
+	if exists {
+		var err error
+		if versions, err = appliedMigrations(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]domain.MigrationStatus, len(m.migrations))
+	for i, mig := range m.migrations {
+		statuses[i] = domain.MigrationStatus{Version: mig.version, Name: mig.name}
+		if appliedAt, ok := versions[mig.version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+
+	return statuses, nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration lock,
+// after making sure the schema_migrations table exists
+func (m *postgresMigrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// runMigration executes a migration script and records it in one transaction
+func runMigration(ctx context.Context, conn *sqlx.Conn, mig migration, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", mig.version, mig.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", mig.version, mig.name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %w", mig.version, mig.name, err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied versions with their apply times
+func appliedMigrations(ctx context.Context, q sqlx.QueryerContext) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+// pendingMigrations counts the known migrations that are not applied
+func pendingMigrations(ctx context.Context, migrator domain.SchemaMigrator) (int, error) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,142 @@
+package infrastructure
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":       {Data: []byte("CREATE INDEX")},
+		"0002_add_index.down.sql":     {Data: []byte("DROP INDEX")},
+		"0001_create_tables.up.sql":   {Data: []byte("CREATE TABLE")},
+		"0001_create_tables.down.sql": {Data: []byte("DROP TABLE")},
+		"README.md":                   {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, migration{version: 1, name: "create_tables", up: "CREATE TABLE", down: "DROP TABLE"}, migrations[0])
+	assert.Equal(t, migration{version: 2, name: "add_index", up: "CREATE INDEX", down: "DROP INDEX"}, migrations[1])
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_create_tables.up.sql": {Data: []byte("CREATE TABLE")},
+		},
+		"bad version": {
+			"first_create_tables.up.sql":   {Data: []byte("CREATE TABLE")},
+			"first_create_tables.down.sql": {Data: []byte("DROP TABLE")},
+		},
+		"bad direction": {
+			"0001_create_tables.sideways.sql": {Data: []byte("CREATE TABLE")},
+		},
+		"conflicting names": {
+			"0001_create_tables.up.sql": {Data: []byte("CREATE TABLE")},
+			"0001_drop_tables.down.sql": {Data: []byte("DROP TABLE")},
+		},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrator, err := NewPostgresMigrator(nil)
+	require.NoError(t, err)
+
+	migrations := migrator.(*postgresMigrator).migrations
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.version, "migration versions must be contiguous")
+	}
+}
+
+func TestPostgresMigrator_UpDown(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set, skipping test that requires PostgreSQL database")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := NewPostgresMigrator(db)
+	require.NoError(t, err)
+	total := len(migrator.(*postgresMigrator).migrations)
+
+	// Start from an empty schema
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	reverted, err := migrator.Down(ctx, total)
+	require.NoError(t, err)
+	assert.Equal(t, total, reverted)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt, "migration %d should be pending", status.Version)
+	}
+
+	// Rows written to the unpartitioned schema of the first migration move
+	// into daily partitions and back
+	pm := migrator.(*postgresMigrator)
+	require.NoError(t, pm.withLock(ctx, func(conn *sqlx.Conn) error {
+		first := pm.migrations[0]
+		return runMigration(ctx, conn, first, first.up,
+			`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, first.version, first.name)
+	}))
+	_, err = db.Exec(`
+		INSERT INTO traces (id, service, operation, start_time, end_time, duration, status)
+		VALUES ('trace-1', 'checkout', 'op', '2024-03-01 10:00:00', '2024-03-01 10:00:01', 1000000000, 'success');
+		INSERT INTO spans (id, trace_id, service, operation, start_time, end_time, duration, status)
+		VALUES ('span-1', 'trace-1', 'checkout', 'op', '2024-03-01 10:00:00', '2024-03-01 10:00:01', 1000000000, 'ok')
+	`)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, total-1, applied)
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM traces_p20240301`))
+	assert.Equal(t, 1, count)
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM spans_p20240301`))
+	assert.Equal(t, 1, count)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied, "up is idempotent")
+
+	reverted, err = migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM spans WHERE trace_id = 'trace-1'`))
+	assert.Equal(t, 1, count)
+
+	pending, err := pendingMigrations(ctx, migrator)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pending)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	_, err = migrator.Down(ctx, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// partitionedTables are range-partitioned by day on start_time; every day
+// gets one partition per table
+var partitionedTables = []string{"traces", "spans"}
+
+// partitionDayLayout formats the day suffix of partition names
+const partitionDayLayout = "20060102"
+
+// postgresPartitions creates and drops the daily partitions of the
+// partitioned tables
+type postgresPartitions struct {
+	db *sqlx.DB
+	// premakeDays is how many days after today get partitions in advance
+	premakeDays int
+	// retention drops a day once it ended longer than this ago; zero keeps
+	// every day
+	retention time.Duration
+	now       func() time.Time
+
+	mu sync.Mutex
+	// created caches the days known to have partitions
+	created map[time.Time]bool
+}
+
+// newPostgresPartitions creates a partition manager
+func newPostgresPartitions(db *sqlx.DB, premakeDays int, retention time.Duration) *postgresPartitions {
+	return &postgresPartitions{
+		db:          db,
+		premakeDays: premakeDays,
+		retention:   retention,
+		now:         time.Now,
+		created:     make(map[time.Time]bool),
+	}
+}
+
+// partitionDay returns the day holding t. Columns are TIMESTAMP without
+// time zone, which store the wall clock of the written time, so the day is
+// taken from the wall clock too and returned as midnight UTC.
+func partitionDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// partitionName returns the name of a table's partition for a day
+func partitionName(table string, day time.Time) string {
+	return table + "_p" + day.Format(partitionDayLayout)
+}
+
+// parsePartitionName returns the day of a partition of table, or false
+// when the name is not a daily partition of it
+func parsePartitionName(table, name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, table+"_p")
+	if !ok || len(suffix) != len(partitionDayLayout) {
+		return time.Time{}, false
+	}
+
+	day, err := time.Parse(partitionDayLayout, suffix)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// createPartitionQuery returns the DDL creating a table's partition for a day
+func createPartitionQuery(table string, day time.Time) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+		partitionName(table, day), table, day.Format("2006-01-02"), day.AddDate(0, 0, 1).Format("2006-01-02"))
+}
+
+// EnsureFor creates the partitions for the days of the given times that
+// are not known to exist yet
+func (p *postgresPartitions) EnsureFor(ctx context.Context, times ...time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range times {
+		day := partitionDay(t)
+		if p.created[day] {
+			continue
+		}
+		if err := p.create(ctx, day); err != nil {
+			return err
+		}
+		p.created[day] = true
+	}
+
+	return nil
+}
+
+// EnsureUpcoming creates the partitions from today up to premakeDays ahead
+func (p *postgresPartitions) EnsureUpcoming(ctx context.Context) error {
+	today := partitionDay(p.now())
+
+	times := make([]time.Time, 0, p.premakeDays+1)
+	for i := 0; i <= p.premakeDays; i++ {
+		times = append(times, today.AddDate(0, 0, i))
+	}
+
+	return p.EnsureFor(ctx, times...)
+}
+
+// create creates the partitions of every partitioned table for a day. A
+// concurrent creation by another process is not an error.
+func (p *postgresPartitions) create(ctx context.Context, day time.Time) error {
+	for _, table := range partitionedTables {
+		if _, err := p.db.ExecContext(ctx, createPartitionQuery(table, day)); err != nil {
+			var exists bool
+			if checkErr := p.db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, partitionName(table, day)).Scan(&exists); checkErr != nil || !exists {
+				return fmt.Errorf("failed to create partition %s: %w", partitionName(table, day), err)
+			}
+		}
+	}
+	return nil
+}
+
+// DropExpired drops the partitions of days that ended before the retention
+// window and returns how many partitions were dropped
+func (p *postgresPartitions) DropExpired(ctx context.Context) (int, error) {
+	if p.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := p.now().Add(-p.retention)
+	// Compare on the wall clock the days are derived from
+	cutoff = time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), cutoff.Hour(), cutoff.Minute(), cutoff.Second(), cutoff.Nanosecond(), time.UTC)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dropped := 0
+	for _, table := range partitionedTables {
+		names, err := p.list(ctx, table)
+		if err != nil {
+			return dropped, err
+		}
+
+		for _, name := range names {
+			day, ok := parsePartitionName(table, name)
+			if !ok || day.AddDate(0, 0, 1).After(cutoff) {
+				continue
+			}
+			if _, err := p.db.ExecContext(ctx, `DROP TABLE IF EXISTS `+name); err != nil {
+				return dropped, fmt.Errorf("failed to drop partition %s: %w", name, err)
+			}
+			delete(p.created, day)
+			dropped++
+		}
+	}
+
+	return dropped, nil
+}
+
+// list returns the names of a partitioned table's partitions
+func (p *postgresPartitions) list(ctx context.Context, table string) ([]string, error) {
+	query := `
+		SELECT child.relname
+		FROM pg_inherits
+		JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+		JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+		WHERE parent.oid = to_regclass($1)
+		ORDER BY child.relname
+	`
+
+	var names []string
+	if err := p.db.SelectContext(ctx, &names, query, table); err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", table, err)
+	}
+	return names, nil
+}
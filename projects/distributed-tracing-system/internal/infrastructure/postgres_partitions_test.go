@@ -0,0 +1,69 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionDay(t *testing.T) {
+	// The day follows the wall clock that TIMESTAMP columns store
+	cet := time.FixedZone("CET", 3600)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), partitionDay(time.Date(2024, 3, 1, 0, 30, 0, 0, cet)))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), partitionDay(time.Date(2024, 3, 1, 23, 59, 59, 0, time.UTC)))
+}
+
+func TestPartitionNames(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "traces_p20240301", partitionName("traces", day))
+	assert.Equal(t,
+		`CREATE TABLE IF NOT EXISTS spans_p20240301 PARTITION OF spans FOR VALUES FROM ('2024-03-01') TO ('2024-03-02')`,
+		createPartitionQuery("spans", day))
+
+	parsed, ok := parsePartitionName("traces", "traces_p20240301")
+	assert.True(t, ok)
+	assert.Equal(t, day, parsed)
+
+	for _, name := range []string{"spans_p20240301", "traces_p2024030", "traces_pmarch011", "traces_default"} {
+		_, ok := parsePartitionName("traces", name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestPostgresPartitions_EnsureAndDrop(t *testing.T) {
+	repo := newTestPostgresRepository(t).(*traceRepositoryPostgres)
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	partitions := newPostgresPartitions(repo.db, 2, 48*time.Hour)
+	partitions.now = func() time.Time { return now }
+
+	require.NoError(t, partitions.EnsureUpcoming(ctx))
+	require.NoError(t, partitions.EnsureFor(ctx, time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC)))
+
+	names, err := partitions.list(ctx, "traces")
+	require.NoError(t, err)
+	assert.Subset(t, names, []string{"traces_p20240307", "traces_p20240310", "traces_p20240311", "traces_p20240312"})
+
+	// Days that ended more than two days ago are dropped from both tables
+	dropped, err := partitions.DropExpired(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, dropped, 2)
+
+	for _, table := range partitionedTables {
+		names, err := partitions.list(ctx, table)
+		require.NoError(t, err)
+		assert.NotContains(t, names, partitionName(table, time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)))
+		assert.Contains(t, names, partitionName(table, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)))
+	}
+
+	// A dropped day is created again when data arrives for it
+	require.NoError(t, partitions.EnsureFor(ctx, time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC)))
+	names, err = partitions.list(ctx, "spans")
+	require.NoError(t, err)
+	assert.Contains(t, names, "spans_p20240307")
+}
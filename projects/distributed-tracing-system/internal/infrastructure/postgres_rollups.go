@@ -0,0 +1,171 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// rollupKey identifies a row of the trace_rollups table
+type rollupKey struct {
+	resolution  time.Duration
+	bucketStart time.Time
+	service     domain.ServiceName
+	operation   domain.OperationName
+}
+
+// mergeRollups adds up the rollups of the same bucket, service and
+// operation, keeping the order of first appearance
+func mergeRollups(rollups []*domain.TraceRollup) []*domain.TraceRollup {
+	index := make(map[rollupKey]*domain.TraceRollup, len(rollups))
+	merged := make([]*domain.TraceRollup, 0, len(rollups))
+	for _, rollup := range rollups {
+		key := rollupKey{rollup.Resolution, rollup.BucketStart, rollup.Service, rollup.Operation}
+		if total, ok := index[key]; ok {
+			total.Merge(rollup)
+			continue
+		}
+		total := domain.NewTraceRollup(rollup.Resolution, rollup.BucketStart, rollup.Service, rollup.Operation)
+		total.Merge(rollup)
+		index[key] = total
+		merged = append(merged, total)
+	}
+	return merged
+}
+
+// storedTraceRollups returns the rollups of the stored traces with the
+// given IDs, counting the spans stored for each
+func storedTraceRollups(ctx context.Context, tx *sqlx.Tx, ids []string) ([]*domain.TraceRollup, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT t.id, t.service, t.operation, t.start_time, t.duration, t.status,
+			(SELECT count(*) FROM spans s WHERE s.trace_id = t.id)
+		FROM traces t WHERE t.id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored traces: %w", err)
+	}
+	defer rows.Close()
+
+	var rollups []*domain.TraceRollup
+	for rows.Next() {
+		var trace domain.Trace
+		var spanCount int64
+		if err := rows.Scan(&trace.ID, &trace.Service, &trace.Operation, &trace.StartTime, &trace.Duration, &trace.Status, &spanCount); err != nil {
+			return nil, fmt.Errorf("failed to scan stored trace: %w", err)
+		}
+		for _, rollup := range domain.TraceRollups(&trace) {
+			rollup.SpanCount = spanCount
+			rollups = append(rollups, rollup)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stored traces: %w", err)
+	}
+
+	return rollups, nil
+}
+
+// saveRollups adds the rollups to the stored rollups of the same bucket,
+// service and operation
+func saveRollups(ctx context.Context, tx *sqlx.Tx, rollups []*domain.TraceRollup) error {
+	// Buckets are added element-wise; a shorter array counts as zeros
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO trace_rollups (resolution_seconds, bucket_start, service, operation, trace_count, span_count, error_count, duration_sum, duration_buckets)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (resolution_seconds, bucket_start, service, operation) DO UPDATE SET
+			trace_count = trace_rollups.trace_count + EXCLUDED.trace_count,
+			span_count = trace_rollups.span_count + EXCLUDED.span_count,
+			error_count = trace_rollups.error_count + EXCLUDED.error_count,
+			duration_sum = trace_rollups.duration_sum + EXCLUDED.duration_sum,
+			duration_buckets = ARRAY(
+				SELECT COALESCE(stored, 0) + COALESCE(added, 0)
+				FROM unnest(trace_rollups.duration_buckets, EXCLUDED.duration_buckets) WITH ORDINALITY AS b(stored, added, i)
+				ORDER BY i
+			)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare rollup upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rollup := range mergeRollups(rollups) {
+		_, err := stmt.ExecContext(ctx,
+			int64(rollup.Resolution/time.Second),
+			rollup.BucketStart,
+			rollup.Service,
+			rollup.Operation,
+			rollup.TraceCount,
+			rollup.SpanCount,
+			rollup.ErrorCount,
+			rollup.DurationSum.Nanoseconds(),
+			pq.Int64Array(rollup.Durations),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save rollup %s %s: %w", rollup.Service, rollup.Operation, err)
+		}
+	}
+
+	return nil
+}
+
+// FindRollups returns the rollups of a resolution whose bucket starts
+// within [query.Start, query.End), restricted to query.Service when set
+func (tr *traceRepositoryPostgres) FindRollups(ctx context.Context, resolution time.Duration, query domain.MetricsQuery) ([]*domain.TraceRollup, error) {
+	sqlQuery := `
+		SELECT bucket_start, service, operation, trace_count, span_count, error_count, duration_sum, duration_buckets
+		FROM trace_rollups
+		WHERE resolution_seconds = $1 AND bucket_start >= $2 AND bucket_start < $3
+	`
+	args := []interface{}{int64(resolution / time.Second), query.Start.UTC(), query.End.UTC()}
+	if query.Service != "" {
+		sqlQuery += ` AND service = $4`
+		args = append(args, query.Service)
+	}
+	sqlQuery += ` ORDER BY bucket_start, service, operation`
+
+	rows, err := tr.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rollups: %w", err)
+	}
+	defer rows.Close()
+
+	rollups := []*domain.TraceRollup{}
+	for rows.Next() {
+		rollup := domain.TraceRollup{Resolution: resolution}
+		var durationSum int64
+		var buckets pq.Int64Array
+		if err := rows.Scan(&rollup.BucketStart, &rollup.Service, &rollup.Operation, &rollup.TraceCount, &rollup.SpanCount, &rollup.ErrorCount, &durationSum, &buckets); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup: %w", err)
+		}
+		rollup.DurationSum = time.Duration(durationSum)
+		rollup.Durations = domain.LatencyHistogram(buckets)
+		rollups = append(rollups, &rollup)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rollups: %w", err)
+	}
+
+	return rollups, nil
+}
+
+// deleteExpiredRollups deletes the minute rollups older than the
+// partition retention. Hourly rollups are kept so metrics stay available
+// for dropped days.
+func (tr *traceRepositoryPostgres) deleteExpiredRollups(ctx context.Context) (int64, error) {
+	if tr.partitions.retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := tr.partitions.now().UTC().Add(-tr.partitions.retention)
+	result, err := tr.db.ExecContext(ctx,
+		`DELETE FROM trace_rollups WHERE resolution_seconds = $1 AND bucket_start < $2`,
+		int64(domain.RollupMinute/time.Second), cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired rollups: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -2,7 +2,9 @@ package infrastructure
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/streamforge/distributed-tracing-system/internal/domain"
 	"github.com/prometheus/client_golang/prometheus"
@@ -11,17 +13,68 @@ import (
 
 // prometheusExporter implements the PrometheusExporter interface
 type prometheusExporter struct {
-	server           *http.Server
-	registry         *prometheus.Registry
-	tracesReceived   *prometheus.CounterVec
-	tracesProcessed  *prometheus.CounterVec
-	traceDuration    *prometheus.HistogramVec
-	serviceLatency   *prometheus.HistogramVec
-	errorRate        *prometheus.GaugeVec
+	server                  *http.Server
+	registry                *prometheus.Registry
+	tracesReceived          *prometheus.CounterVec
+	tracesProcessed         *prometheus.CounterVec
+	traceDuration           *prometheus.HistogramVec
+	serviceLatency          *prometheus.HistogramVec
+	errorRate               *errorRateCollector
+	samplingPolicyDecisions *prometheus.CounterVec
+	samplingTraces          *prometheus.CounterVec
+	tracesPurged            *prometheus.CounterVec
+	retentionLag            prometheus.Gauge
+	writeBatchSize          prometheus.Histogram
+	writeBatchDuration      prometheus.Histogram
+	writeQueueDepth         prometheus.Gauge
+	writeRejected           prometheus.Counter
+	clockSkewAdjustments    *prometheus.HistogramVec
+	anomalies               *prometheus.CounterVec
+	anomalyScores           *prometheus.HistogramVec
+	exports                 *prometheus.CounterVec
+	exportDuration          *prometheus.HistogramVec
+	exportRetries           *prometheus.CounterVec
+	exportQueueDepth        *prometheus.GaugeVec
+	exportCircuitState      *prometheus.GaugeVec
+	spanMetrics             *spanMetrics
+
+	errorRateWindow   time.Duration
+	spanMetricsConfig *SpanMetricsConfig
+}
+
+// defaultErrorRateWindow is the sliding window of the error_rate gauge
+const defaultErrorRateWindow = time.Minute
+
+// PrometheusExporterOption configures optional Prometheus exporter behaviour
+type PrometheusExporterOption func(*prometheusExporter)
+
+// WithErrorRateWindow sets the sliding window the error_rate gauge is
+// computed over
+func WithErrorRateWindow(window time.Duration) PrometheusExporterOption {
+	return func(pe *prometheusExporter) {
+		if window > 0 {
+			pe.errorRateWindow = window
+		}
+	}
 }
 
-// NewPrometheusExporter creates a new Prometheus exporter
-func NewPrometheusExporter(port, path string) (domain.PrometheusExporter, error) {
+// WithSpanMetrics enables request, error and duration series derived from
+// every span
+func WithSpanMetrics(config SpanMetricsConfig) PrometheusExporterOption {
+	return func(pe *prometheusExporter) {
+		pe.spanMetricsConfig = &config
+	}
+}
+
+// NewPrometheusExporter creates a new Prometheus exporter. Metrics are
+// also served in the OpenMetrics format, which carries the trace ID
+// exemplars of the duration histograms.
+func NewPrometheusExporter(port, path string, opts ...PrometheusExporterOption) (domain.PrometheusExporter, error) {
+	exporter := &prometheusExporter{errorRateWindow: defaultErrorRateWindow}
+	for _, opt := range opts {
+		opt(exporter)
+	}
+
 	// Create custom registry
 	registry := prometheus.NewRegistry()
 
@@ -60,39 +113,199 @@ func NewPrometheusExporter(port, path string) (domain.PrometheusExporter, error)
 		[]string{"service", "operation"},
 	)
 
-	errorRate := prometheus.NewGaugeVec(
+	errorRate := newErrorRateCollector(exporter.errorRateWindow)
+
+	samplingPolicyDecisions := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tail_sampling_policy_decisions_total",
+			Help: "Tail sampling decisions per policy",
+		},
+		[]string{"policy", "decision"},
+	)
+
+	samplingTraces := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tail_sampling_traces_total",
+			Help: "Traces kept or dropped by tail sampling",
+		},
+		[]string{"decision"},
+	)
+
+	tracesPurged := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_traces_purged_total",
+			Help: "Traces deleted by data retention per rule",
+		},
+		[]string{"rule"},
+	)
+
+	retentionLag := prometheus.NewGauge(
 		prometheus.GaugeOpts{
-			Name: "error_rate",
-			Help: "Error rate per service",
+			Name: "retention_lag_seconds",
+			Help: "How long the oldest expired trace has been kept past its retention",
+		},
+	)
+
+	writeBatchSize := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "trace_write_batch_size",
+			Help:    "Traces written per storage batch",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+	)
+
+	writeBatchDuration := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "trace_write_batch_duration_seconds",
+			Help:    "Duration of storage batch writes",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	writeQueueDepth := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "trace_write_queue_depth",
+			Help: "Traces waiting in the write queue",
+		},
+	)
+
+	writeRejected := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "trace_write_rejected_total",
+			Help: "Traces refused because the write queue was full",
+		},
+	)
+
+	clockSkewAdjustments := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "clock_skew_adjustment_seconds",
+			Help:    "Absolute clock skew corrections applied to span subtrees per service",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
 		},
 		[]string{"service"},
 	)
 
+	anomalies := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "trace_anomalies_total",
+			Help: "Latency and error rate anomalies detected per service, operation and kind",
+		},
+		[]string{"service", "operation", "kind"},
+	)
+
+	anomalyScores := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "trace_anomaly_score",
+			Help:    "Absolute deviation scores of detected anomalies per kind",
+			Buckets: []float64{3, 4, 5, 7.5, 10, 20, 50},
+		},
+		[]string{"kind"},
+	)
+
+	exports := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "trace_exports_total",
+			Help: "Traces exported per backend and result",
+		},
+		[]string{"backend", "result"},
+	)
+
+	exportDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "trace_export_duration_seconds",
+			Help:    "Time spent exporting a trace to a backend, retries included",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"backend"},
+	)
+
+	exportRetries := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "trace_export_retries_total",
+			Help: "Export attempts retried after a backend error",
+		},
+		[]string{"backend"},
+	)
+
+	exportQueueDepth := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "trace_export_queue_depth",
+			Help: "Traces waiting in a backend's export queue",
+		},
+		[]string{"backend"},
+	)
+
+	exportCircuitState := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "trace_export_circuit_state",
+			Help: "Circuit breaker state per backend: 0 closed, 1 half open, 2 open",
+		},
+		[]string{"backend"},
+	)
+
 	// Register metrics
 	registry.MustRegister(tracesReceived)
 	registry.MustRegister(tracesProcessed)
 	registry.MustRegister(traceDuration)
 	registry.MustRegister(serviceLatency)
 	registry.MustRegister(errorRate)
+	registry.MustRegister(samplingPolicyDecisions)
+	registry.MustRegister(samplingTraces)
+	registry.MustRegister(tracesPurged)
+	registry.MustRegister(retentionLag)
+	registry.MustRegister(writeBatchSize)
+	registry.MustRegister(writeBatchDuration)
+	registry.MustRegister(writeQueueDepth)
+	registry.MustRegister(writeRejected)
+	registry.MustRegister(clockSkewAdjustments)
+	registry.MustRegister(anomalies)
+	registry.MustRegister(anomalyScores)
+	registry.MustRegister(exports)
+	registry.MustRegister(exportDuration)
+	registry.MustRegister(exportRetries)
+	registry.MustRegister(exportQueueDepth)
+	registry.MustRegister(exportCircuitState)
+
+	if exporter.spanMetricsConfig != nil {
+		spanMetrics, err := newSpanMetrics(registry, *exporter.spanMetricsConfig)
+		if err != nil {
+			return nil, err
+		}
+		exporter.spanMetrics = spanMetrics
+	}
 
 	// Create HTTP server
 	mux := http.NewServeMux()
-	mux.Handle(path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.Handle(path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
 
 	server := &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
 	}
 
-	exporter := &prometheusExporter{
-		server:          server,
-		registry:        registry,
-		tracesReceived:  tracesReceived,
-		tracesProcessed: tracesProcessed,
-		traceDuration:   traceDuration,
-		serviceLatency:  serviceLatency,
-		errorRate:       errorRate,
-	}
+	exporter.server = server
+	exporter.registry = registry
+	exporter.tracesReceived = tracesReceived
+	exporter.tracesProcessed = tracesProcessed
+	exporter.traceDuration = traceDuration
+	exporter.serviceLatency = serviceLatency
+	exporter.errorRate = errorRate
+	exporter.samplingPolicyDecisions = samplingPolicyDecisions
+	exporter.samplingTraces = samplingTraces
+	exporter.tracesPurged = tracesPurged
+	exporter.retentionLag = retentionLag
+	exporter.writeBatchSize = writeBatchSize
+	exporter.writeBatchDuration = writeBatchDuration
+	exporter.writeQueueDepth = writeQueueDepth
+	exporter.writeRejected = writeRejected
+	exporter.clockSkewAdjustments = clockSkewAdjustments
+	exporter.anomalies = anomalies
+	exporter.anomalyScores = anomalyScores
+	exporter.exports = exports
+	exporter.exportDuration = exportDuration
+	exporter.exportRetries = exportRetries
+	exporter.exportQueueDepth = exportQueueDepth
+	exporter.exportCircuitState = exportCircuitState
 
 	// Start server in background
 	go func() {
@@ -127,26 +340,109 @@ func (pe *prometheusExporter) RecordTraceMetrics(trace *domain.Trace) error {
 		string(trace.Status),
 	).Inc()
 
-	pe.traceDuration.WithLabelValues(
+	exemplar := traceExemplar(trace.ID)
+	observeWithExemplar(pe.traceDuration.WithLabelValues(
 		string(trace.Service),
 		string(trace.Operation),
-	).Observe(trace.Duration.Seconds())
+	), trace.Duration.Seconds(), exemplar)
 
-	pe.serviceLatency.WithLabelValues(
+	observeWithExemplar(pe.serviceLatency.WithLabelValues(
 		string(trace.Service),
 		string(trace.Operation),
-	).Observe(trace.Duration.Seconds())
+	), trace.Duration.Seconds(), exemplar)
 
-	// Calculate error rate
-	errorRate := 0.0
-	if trace.Status == domain.TraceStatusError {
-		errorRate = 1.0
+	// Error rate over the sliding window
+	pe.errorRate.Record(string(trace.Service), trace.Status == domain.TraceStatusError)
+
+	// Span-derived RED metrics
+	if pe.spanMetrics != nil {
+		pe.spanMetrics.record(trace)
 	}
-	pe.errorRate.WithLabelValues(string(trace.Service)).Set(errorRate)
 
 	return nil
 }
 
+// RecordSamplingDecision records the decision of a single tail sampling policy
+func (pe *prometheusExporter) RecordSamplingDecision(policy string, decision domain.SamplingDecision) {
+	pe.samplingPolicyDecisions.WithLabelValues(policy, string(decision)).Inc()
+}
+
+// RecordSampledTrace records the final tail sampling decision for a trace
+func (pe *prometheusExporter) RecordSampledTrace(decision domain.SamplingDecision) {
+	pe.samplingTraces.WithLabelValues(string(decision)).Inc()
+}
+
+// RecordTracesPurged records traces deleted by a retention rule
+func (pe *prometheusExporter) RecordTracesPurged(rule string, count int64) {
+	pe.tracesPurged.WithLabelValues(rule).Add(float64(count))
+}
+
+// RecordRetentionLag records how far retention is behind its policy
+func (pe *prometheusExporter) RecordRetentionLag(lag time.Duration) {
+	pe.retentionLag.Set(lag.Seconds())
+}
+
+// RecordWriteBatch records the size and duration of a storage batch write
+func (pe *prometheusExporter) RecordWriteBatch(size int, duration time.Duration) {
+	pe.writeBatchSize.Observe(float64(size))
+	pe.writeBatchDuration.Observe(duration.Seconds())
+}
+
+// RecordWriteQueueDepth records how many traces wait in the write queue
+func (pe *prometheusExporter) RecordWriteQueueDepth(depth int) {
+	pe.writeQueueDepth.Set(float64(depth))
+}
+
+// RecordWriteRejected records a trace refused by a full write queue
+func (pe *prometheusExporter) RecordWriteRejected() {
+	pe.writeRejected.Inc()
+}
+
+// RecordClockSkewAdjustment records a clock skew correction for a service
+func (pe *prometheusExporter) RecordClockSkewAdjustment(service string, adjustment time.Duration) {
+	if adjustment < 0 {
+		adjustment = -adjustment
+	}
+	pe.clockSkewAdjustments.WithLabelValues(service).Observe(adjustment.Seconds())
+}
+
+// RecordAnomaly records a detected anomaly
+func (pe *prometheusExporter) RecordAnomaly(anomaly *domain.Anomaly) {
+	pe.anomalies.WithLabelValues(string(anomaly.Service), string(anomaly.Operation), string(anomaly.Kind)).Inc()
+	pe.anomalyScores.WithLabelValues(string(anomaly.Kind)).Observe(math.Abs(anomaly.Score))
+}
+
+// RecordExport records the result of exporting a trace to a backend.
+// Dropped traces were never sent and have no duration.
+func (pe *prometheusExporter) RecordExport(backend string, result domain.ExportResult, duration time.Duration) {
+	pe.exports.WithLabelValues(backend, string(result)).Inc()
+	if result != domain.ExportResultDropped {
+		pe.exportDuration.WithLabelValues(backend).Observe(duration.Seconds())
+	}
+}
+
+// RecordExportRetry records a retried export attempt
+func (pe *prometheusExporter) RecordExportRetry(backend string) {
+	pe.exportRetries.WithLabelValues(backend).Inc()
+}
+
+// RecordExportQueueDepth records how many traces wait for a backend
+func (pe *prometheusExporter) RecordExportQueueDepth(backend string, depth int) {
+	pe.exportQueueDepth.WithLabelValues(backend).Set(float64(depth))
+}
+
+// RecordCircuitState records the circuit breaker state of a backend
+func (pe *prometheusExporter) RecordCircuitState(backend string, state domain.CircuitState) {
+	value := 0.0
+	switch state {
+	case domain.CircuitHalfOpen:
+		value = 1
+	case domain.CircuitOpen:
+		value = 2
+	}
+	pe.exportCircuitState.WithLabelValues(backend).Set(value)
+}
+
 // validateTrace validates a trace before recording metrics
 func (pe *prometheusExporter) validateTrace(trace *domain.Trace) error {
 	if trace.ID == "" {
@@ -0,0 +1,114 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunDependencyStoreContract runs the conformance suite for repositories
+// that implement domain.DependencyStore. Each case gets a fresh repository.
+func RunDependencyStoreContract(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, store domain.DependencyStore)
+	}{
+		{"SaveAndFindDependencies", testSaveAndFindDependencies},
+		{"SaveDependenciesMergesWindows", testSaveDependenciesMergesWindows},
+		{"FindDependenciesRange", testFindDependenciesRange},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			store, ok := repo.(domain.DependencyStore)
+			require.True(t, ok, "repository does not implement domain.DependencyStore")
+			tc.run(t, store)
+		})
+	}
+}
+
+// newLink returns a link with one call per duration, the first one failed
+func newLink(window time.Time, caller, callee domain.ServiceName, durations ...time.Duration) *domain.DependencyLink {
+	link := domain.NewDependencyLink(window, caller, callee)
+	for i, duration := range durations {
+		link.Record(duration, i == 0)
+	}
+	return link
+}
+
+func testSaveAndFindDependencies(t *testing.T, store domain.DependencyStore) {
+	ctx := context.Background()
+	link := newLink(baseTime, "checkout", "payments", 3*time.Millisecond, 40*time.Millisecond)
+	require.NoError(t, store.SaveDependencies(ctx, []*domain.DependencyLink{link}))
+
+	links, err := store.FindDependencies(ctx, baseTime, baseTime.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	found := links[0]
+	assert.True(t, baseTime.Equal(found.WindowStart))
+	assert.Equal(t, domain.ServiceName("checkout"), found.Caller)
+	assert.Equal(t, domain.ServiceName("payments"), found.Callee)
+	assert.Equal(t, int64(2), found.CallCount)
+	assert.Equal(t, int64(1), found.ErrorCount)
+	assert.Equal(t, 43*time.Millisecond, found.DurationSum)
+	assert.Equal(t, link.Latencies, found.Latencies)
+}
+
+func testSaveDependenciesMergesWindows(t *testing.T, store domain.DependencyStore) {
+	ctx := context.Background()
+	require.NoError(t, store.SaveDependencies(ctx, []*domain.DependencyLink{
+		newLink(baseTime, "checkout", "payments", 3*time.Millisecond),
+		newLink(baseTime, "checkout", "inventory", 3*time.Millisecond),
+	}))
+	require.NoError(t, store.SaveDependencies(ctx, []*domain.DependencyLink{
+		newLink(baseTime, "checkout", "payments", 40*time.Millisecond, 2*time.Second),
+	}))
+
+	links, err := store.FindDependencies(ctx, baseTime, baseTime.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+
+	merged := newLink(baseTime, "checkout", "payments", 3*time.Millisecond)
+	merged.Merge(newLink(baseTime, "checkout", "payments", 40*time.Millisecond, 2*time.Second))
+	for _, link := range links {
+		if link.Callee != "payments" {
+			assert.Equal(t, int64(1), link.CallCount)
+			continue
+		}
+		assert.Equal(t, int64(3), link.CallCount)
+		assert.Equal(t, int64(2), link.ErrorCount)
+		assert.Equal(t, merged.DurationSum, link.DurationSum)
+		assert.Equal(t, merged.Latencies, link.Latencies)
+	}
+}
+
+func testFindDependenciesRange(t *testing.T, store domain.DependencyStore) {
+	ctx := context.Background()
+	require.NoError(t, store.SaveDependencies(ctx, []*domain.DependencyLink{
+		newLink(baseTime.Add(-time.Minute), "checkout", "payments", time.Millisecond),
+		newLink(baseTime, "checkout", "payments", time.Millisecond),
+		newLink(baseTime.Add(time.Minute), "checkout", "payments", time.Millisecond),
+		newLink(baseTime.Add(2*time.Minute), "checkout", "payments", time.Millisecond),
+	}))
+
+	// The start is inclusive and the end exclusive
+	links, err := store.FindDependencies(ctx, baseTime, baseTime.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+
+	var windows []time.Time
+	for _, link := range links {
+		windows = append(windows, link.WindowStart.UTC())
+	}
+	assert.ElementsMatch(t, []time.Time{baseTime, baseTime.Add(time.Minute)}, windows)
+
+	links, err = store.FindDependencies(ctx, baseTime.Add(time.Hour), baseTime.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
@@ -0,0 +1,153 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTracePurgerContract runs the conformance suite for repositories that
+// implement domain.TracePurger. Each case gets a fresh repository.
+func RunTracePurgerContract(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, repo domain.TraceRepository, purger domain.TracePurger)
+	}{
+		{"PurgesOldestFirstInBatches", testPurgesOldestFirstInBatches},
+		{"PurgeSelection", testPurgeSelection},
+		{"PurgeStats", testPurgeStats},
+		{"PurgeRejectsInvalidCriteria", testPurgeRejectsInvalidCriteria},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			purger, ok := repo.(domain.TracePurger)
+			require.True(t, ok, "repository does not implement domain.TracePurger")
+			tc.run(t, repo, purger)
+		})
+	}
+}
+
+func testPurgesOldestFirstInBatches(t *testing.T, repo domain.TraceRepository, purger domain.TracePurger) {
+	ctx := context.Background()
+	saveAll(t, repo,
+		NewTrace("trace-1", "checkout", "op", baseTime, time.Second),
+		NewTrace("trace-2", "checkout", "op", baseTime.Add(time.Minute), time.Second),
+		NewTrace("trace-3", "checkout", "op", baseTime.Add(2*time.Minute), time.Second),
+		NewTrace("recent", "checkout", "op", baseTime.Add(time.Hour), time.Second),
+	)
+
+	criteria := domain.PurgeCriteria{Before: baseTime.Add(30 * time.Minute), Limit: 2}
+
+	deleted, err := purger.PurgeTraces(ctx, criteria)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	// The oldest traces go first, spans included
+	for _, id := range []domain.TraceID{"trace-1", "trace-2"} {
+		_, err := repo.FindByID(ctx, id)
+		assert.True(t, errors.Is(err, domain.ErrTraceNotFound), "trace %s should be purged", id)
+	}
+
+	deleted, err = purger.PurgeTraces(ctx, criteria)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	deleted, err = purger.PurgeTraces(ctx, criteria)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.TraceID{"recent"}, traceIDs(traces))
+
+	services, err := repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServiceName{"checkout"}, services)
+}
+
+func testPurgeSelection(t *testing.T, repo domain.TraceRepository, purger domain.TracePurger) {
+	ctx := context.Background()
+
+	checkoutError := NewTrace("checkout-error", "checkout", "op", baseTime, time.Second)
+	checkoutError.Status = domain.TraceStatusError
+	paymentsError := NewTrace("payments-error", "payments", "op", baseTime, time.Second)
+	paymentsError.Status = domain.TraceStatusError
+	audit := NewTrace("audit-error", "audit", "op", baseTime, time.Second)
+	audit.Status = domain.TraceStatusError
+	saveAll(t, repo, checkoutError, paymentsError, audit,
+		NewTrace("checkout-success", "checkout", "op", baseTime, time.Second),
+	)
+
+	// Errors, except those of audit and checkout which earlier rules govern
+	deleted, err := purger.PurgeTraces(ctx, domain.PurgeCriteria{
+		Status: domain.TraceStatusError,
+		Exclude: []domain.RetentionRule{
+			{Service: "audit"},
+			{Service: "checkout", Status: domain.TraceStatusError},
+		},
+		Before: baseTime.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{SortOrder: domain.SortAscending})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []domain.TraceID{"audit-error", "checkout-error", "checkout-success"}, traceIDs(traces))
+
+	// A catch-all exclusion leaves nothing to purge
+	deleted, err = purger.PurgeTraces(ctx, domain.PurgeCriteria{
+		Exclude: []domain.RetentionRule{{}},
+		Before:  baseTime.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	deleted, err = purger.PurgeTraces(ctx, domain.PurgeCriteria{Service: "checkout", Before: baseTime.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
+
+func testPurgeStats(t *testing.T, repo domain.TraceRepository, purger domain.TracePurger) {
+	ctx := context.Background()
+
+	stats, err := purger.PurgeStats(ctx, domain.PurgeCriteria{Before: baseTime.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Zero(t, stats.Count)
+	assert.True(t, stats.Oldest.IsZero())
+
+	saveAll(t, repo,
+		NewTrace("trace-2", "checkout", "op", baseTime.Add(time.Minute), time.Second),
+		NewTrace("trace-1", "checkout", "op", baseTime, time.Second),
+		NewTrace("recent", "checkout", "op", baseTime.Add(2*time.Hour), time.Second),
+	)
+
+	stats, err = purger.PurgeStats(ctx, domain.PurgeCriteria{Before: baseTime.Add(time.Hour), Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Count, "stats ignore the limit")
+	assert.True(t, baseTime.Equal(stats.Oldest), "oldest %s, got %s", baseTime, stats.Oldest)
+
+	// Counting does not delete
+	count, err := repo.Count(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func testPurgeRejectsInvalidCriteria(t *testing.T, repo domain.TraceRepository, purger domain.TracePurger) {
+	ctx := context.Background()
+
+	_, err := purger.PurgeTraces(ctx, domain.PurgeCriteria{})
+	assert.Error(t, err, "a missing cutoff must not purge everything")
+
+	_, err = purger.PurgeTraces(ctx, domain.PurgeCriteria{Before: baseTime, Limit: -1})
+	assert.Error(t, err)
+
+	_, err = purger.PurgeStats(ctx, domain.PurgeCriteria{})
+	assert.Error(t, err)
+}
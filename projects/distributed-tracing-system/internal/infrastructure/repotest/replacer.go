@@ -0,0 +1,87 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTraceReplacerContract runs the conformance suite for repositories that
+// implement domain.TraceReplacer. Each case gets a fresh repository.
+func RunTraceReplacerContract(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, repo domain.TraceRepository, replacer domain.TraceReplacer)
+	}{
+		{"ReplaceDropsPreviousSpans", testReplaceDropsPreviousSpans},
+		{"ReplaceSavesNewTrace", testReplaceSavesNewTrace},
+		{"ReplaceRejectsInvalidTraces", testReplaceRejectsInvalidTraces},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			replacer, ok := repo.(domain.TraceReplacer)
+			require.True(t, ok, "repository does not implement domain.TraceReplacer")
+			tc.run(t, repo, replacer)
+		})
+	}
+}
+
+func testReplaceDropsPreviousSpans(t *testing.T, repo domain.TraceRepository, replacer domain.TraceReplacer) {
+	ctx := context.Background()
+	saveAll(t, repo, NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second))
+
+	updated := NewTrace("trace-1", "checkout", "GET /cart", baseTime.Add(time.Minute), 2*time.Second)
+	updated.Tags = map[string]string{"env": "staging"}
+	updated.Spans = updated.Spans[:1]
+	require.NoError(t, replacer.Replace(ctx, updated))
+
+	found, err := repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationName("GET /cart"), found.Operation)
+	assert.Equal(t, 2*time.Second, found.Duration)
+	assert.Equal(t, "staging", found.Tags["env"])
+	assert.Len(t, found.Spans, 1, "spans of the previous save must not survive")
+
+	count, err := repo.Count(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Secondary lookups only see the new version
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{Tags: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+
+	operations, err := repo.GetOperations(ctx, "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.OperationName{"GET /cart"}, operations)
+}
+
+func testReplaceSavesNewTrace(t *testing.T, repo domain.TraceRepository, replacer domain.TraceReplacer) {
+	ctx := context.Background()
+	require.NoError(t, replacer.Replace(ctx, NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second)))
+
+	found, err := repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	assert.Len(t, found.Spans, 2)
+}
+
+func testReplaceRejectsInvalidTraces(t *testing.T, repo domain.TraceRepository, replacer domain.TraceReplacer) {
+	ctx := context.Background()
+	saveAll(t, repo, NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second))
+
+	invalid := NewTrace("trace-1", "", "POST /checkout", baseTime, time.Second)
+	assert.Error(t, replacer.Replace(ctx, nil))
+	assert.Error(t, replacer.Replace(ctx, invalid))
+
+	// The stored trace is left as it was
+	found, err := repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ServiceName("checkout"), found.Service)
+	assert.Len(t, found.Spans, 2)
+}
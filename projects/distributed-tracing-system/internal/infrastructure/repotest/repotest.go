@@ -0,0 +1,555 @@
+// Package repotest provides a conformance suite that every
+// domain.TraceRepository implementation is expected to pass.
+//
+// Backends run it from their own tests:
+//
+//	func TestTraceRepositoryMemory_Contract(t *testing.T) {
+//		repotest.RunTraceRepositoryContract(t, func(t *testing.T) domain.TraceRepository {
+//			repo, err := NewTraceRepositoryMemory(MemoryRepositoryConfig{}, nil)
+//			require.NoError(t, err)
+//			return repo
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns an empty repository for a single test case. Cleanup of
+// the repository should be registered on t.
+type Factory func(t *testing.T) domain.TraceRepository
+
+// baseTime is the start time of the fixtures, truncated to microseconds so
+// that backends with microsecond timestamp precision round-trip it exactly
+var baseTime = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// RunTraceRepositoryContract runs the conformance suite against repositories
+// created by newRepo. Each case gets a fresh repository.
+func RunTraceRepositoryContract(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, repo domain.TraceRepository)
+	}{
+		{"SaveAndFindByID", testSaveAndFindByID},
+		{"FindByIDNotFound", testFindByIDNotFound},
+		{"SaveRejectsInvalidTraces", testSaveRejectsInvalidTraces},
+		{"SaveMergesSpans", testSaveMergesSpans},
+		{"ReturnedTracesAreCopies", testReturnedTracesAreCopies},
+		{"SearchFilters", testSearchFilters},
+		{"SearchOrdering", testSearchOrdering},
+		{"SearchOffsetPagination", testSearchOffsetPagination},
+		{"SearchCursorPagination", testSearchCursorPagination},
+		{"SearchNoMatches", testSearchNoMatches},
+		{"SearchRejectsInvalidCriteria", testSearchRejectsInvalidCriteria},
+		{"Count", testCount},
+		{"ServicesAndOperations", testServicesAndOperations},
+		{"ConcurrentSaves", testConcurrentSaves},
+		{"CloseTwice", testCloseTwice},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newRepo(t))
+		})
+	}
+}
+
+// NewTrace returns a valid trace with a root span and one child span
+func NewTrace(id string, service domain.ServiceName, operation domain.OperationName, start time.Time, duration time.Duration) *domain.Trace {
+	rootID := domain.SpanID(id + "-root")
+	return &domain.Trace{
+		ID:        domain.TraceID(id),
+		Service:   service,
+		Operation: operation,
+		StartTime: start,
+		EndTime:   start.Add(duration),
+		Duration:  duration,
+		Status:    domain.TraceStatusSuccess,
+		Tags:      map[string]string{"env": "prod"},
+		Spans: []domain.Span{
+			{
+				ID:        rootID,
+				TraceID:   domain.TraceID(id),
+				Service:   service,
+				Operation: operation,
+				StartTime: start,
+				EndTime:   start.Add(duration),
+				Duration:  duration,
+				Status:    domain.SpanStatusOK,
+				Tags:      map[string]string{"span.kind": "server"},
+			},
+			{
+				ID:        domain.SpanID(id + "-db"),
+				TraceID:   domain.TraceID(id),
+				ParentID:  &rootID,
+				Service:   service,
+				Operation: "SELECT",
+				StartTime: start.Add(duration / 4),
+				EndTime:   start.Add(duration / 2),
+				Duration:  duration / 4,
+				Status:    domain.SpanStatusOK,
+				Tags:      map[string]string{"db.system": "postgresql"},
+			},
+		},
+	}
+}
+
+// traceIDs returns the IDs of traces in order
+func traceIDs(traces []*domain.Trace) []domain.TraceID {
+	ids := make([]domain.TraceID, len(traces))
+	for i, trace := range traces {
+		ids[i] = trace.ID
+	}
+	return ids
+}
+
+// saveAll saves traces, failing the test on the first error
+func saveAll(t *testing.T, repo domain.TraceRepository, traces ...*domain.Trace) {
+	t.Helper()
+	for _, trace := range traces {
+		require.NoError(t, repo.Save(context.Background(), trace), "saving trace %s", trace.ID)
+	}
+}
+
+func testSaveAndFindByID(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	trace := NewTrace("trace-1", "checkout", "POST /checkout", baseTime, 1500*time.Millisecond)
+	trace.Status = domain.TraceStatusError
+	trace.Spans[1].Status = domain.SpanStatusError
+	saveAll(t, repo, trace)
+
+	found, err := repo.FindByID(ctx, trace.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+
+	assert.Equal(t, trace.ID, found.ID)
+	assert.Equal(t, trace.Service, found.Service)
+	assert.Equal(t, trace.Operation, found.Operation)
+	assert.True(t, trace.StartTime.Equal(found.StartTime), "start time %s, got %s", trace.StartTime, found.StartTime)
+	assert.True(t, trace.EndTime.Equal(found.EndTime), "end time %s, got %s", trace.EndTime, found.EndTime)
+	assert.Equal(t, trace.Duration, found.Duration)
+	assert.Equal(t, trace.Status, found.Status)
+	assert.Equal(t, trace.Tags, found.Tags)
+
+	require.Len(t, found.Spans, 2)
+	spans := make(map[domain.SpanID]domain.Span)
+	for _, span := range found.Spans {
+		spans[span.ID] = span
+	}
+	child, ok := spans["trace-1-db"]
+	require.True(t, ok)
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, domain.SpanID("trace-1-root"), *child.ParentID)
+	assert.Equal(t, domain.OperationName("SELECT"), child.Operation)
+	assert.Equal(t, trace.Spans[1].Duration, child.Duration)
+	assert.Equal(t, domain.SpanStatusError, child.Status)
+	assert.Equal(t, map[string]string{"db.system": "postgresql"}, child.Tags)
+	assert.Nil(t, spans["trace-1-root"].ParentID)
+}
+
+func testFindByIDNotFound(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	saveAll(t, repo, NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second))
+
+	trace, err := repo.FindByID(ctx, "missing")
+	assert.Nil(t, trace)
+	assert.True(t, errors.Is(err, domain.ErrTraceNotFound), "expected ErrTraceNotFound, got %v", err)
+
+	// An empty ID is invalid input rather than a missing trace
+	_, err = repo.FindByID(ctx, "")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, domain.ErrTraceNotFound))
+}
+
+func testSaveRejectsInvalidTraces(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+
+	noID := NewTrace("", "checkout", "POST /checkout", baseTime, time.Second)
+	noService := NewTrace("trace-1", "", "POST /checkout", baseTime, time.Second)
+	noOperation := NewTrace("trace-2", "checkout", "", baseTime, time.Second)
+	reversed := NewTrace("trace-3", "checkout", "POST /checkout", baseTime, time.Second)
+	reversed.EndTime = baseTime.Add(-time.Second)
+
+	for name, trace := range map[string]*domain.Trace{
+		"nil":               nil,
+		"missing ID":        noID,
+		"missing service":   noService,
+		"missing operation": noOperation,
+		"end before start":  reversed,
+	} {
+		assert.Error(t, repo.Save(ctx, trace), name)
+	}
+
+	count, err := repo.Count(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testSaveMergesSpans(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	first := NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second)
+	first.Spans = first.Spans[:1]
+	saveAll(t, repo, first)
+
+	// The second part resends the root span, moved and failed, with the
+	// child span; the trace-level fields come from the last save
+	second := NewTrace("trace-1", "checkout", "GET /cart", baseTime.Add(time.Minute), 2*time.Second)
+	second.Tags = map[string]string{"env": "staging"}
+	second.Spans[0].Status = domain.SpanStatusError
+	saveAll(t, repo, second)
+
+	found, err := repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationName("GET /cart"), found.Operation)
+	assert.Equal(t, 2*time.Second, found.Duration)
+	assert.Equal(t, "staging", found.Tags["env"])
+
+	require.Len(t, found.Spans, 2, "spans of both saves must be kept once each")
+	spans := make(map[domain.SpanID]domain.Span)
+	for _, span := range found.Spans {
+		spans[span.ID] = span
+	}
+	root, ok := spans["trace-1-root"]
+	require.True(t, ok)
+	assert.Equal(t, domain.SpanStatusError, root.Status)
+	assert.True(t, second.Spans[0].StartTime.Equal(root.StartTime), "start time %s, got %s", second.Spans[0].StartTime, root.StartTime)
+	_, ok = spans["trace-1-db"]
+	assert.True(t, ok)
+
+	// A third part adds a span without touching the stored ones
+	third := NewTrace("trace-1", "checkout", "GET /cart", baseTime.Add(time.Minute), 2*time.Second)
+	third.Tags = map[string]string{"env": "staging"}
+	third.Spans = third.Spans[1:]
+	third.Spans[0].ID = "trace-1-cache"
+	saveAll(t, repo, third)
+
+	found, err = repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	assert.Len(t, found.Spans, 3)
+
+	count, err := repo.Count(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Secondary lookups only see the trace-level fields of the last save
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{Tags: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+
+	operations, err := repo.GetOperations(ctx, "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.OperationName{"GET /cart"}, operations)
+}
+
+func testReturnedTracesAreCopies(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	trace := NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second)
+	saveAll(t, repo, trace)
+
+	// Neither the saved value nor returned values alias stored state
+	trace.Tags["env"] = "mutated"
+	found, err := repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	found.Tags["env"] = "mutated"
+	found.Spans[0].Operation = "mutated"
+
+	again, err := repo.FindByID(ctx, "trace-1")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", again.Tags["env"])
+	for _, span := range again.Spans {
+		assert.NotEqual(t, domain.OperationName("mutated"), span.Operation)
+	}
+}
+
+func testSearchFilters(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+
+	slow := NewTrace("slow", "checkout", "POST /checkout", baseTime, 3*time.Second)
+	failed := NewTrace("failed", "checkout", "GET /cart", baseTime.Add(time.Minute), 200*time.Millisecond)
+	failed.Status = domain.TraceStatusError
+	failed.Tags = map[string]string{"env": "staging"}
+	failed.Spans[1].Service = "payments"
+	failed.Spans[1].Status = domain.SpanStatusError
+	other := NewTrace("other", "inventory", "GET /stock", baseTime.Add(2*time.Minute), time.Second)
+	saveAll(t, repo, slow, failed, other)
+
+	service := domain.ServiceName("checkout")
+	operation := domain.OperationName("GET /stock")
+	status := domain.TraceStatusError
+	from := baseTime.Add(30 * time.Second)
+	until := baseTime.Add(90 * time.Second)
+	minDuration := time.Second
+	maxDuration := 500 * time.Millisecond
+	spanService := domain.ServiceName("payments")
+	spanStatus := domain.SpanStatusError
+
+	tests := []struct {
+		name     string
+		criteria *domain.SearchCriteria
+		expected []domain.TraceID
+	}{
+		{"service", &domain.SearchCriteria{Service: &service}, []domain.TraceID{"failed", "slow"}},
+		{"operation", &domain.SearchCriteria{Operation: &operation}, []domain.TraceID{"other"}},
+		{"status", &domain.SearchCriteria{Status: &status}, []domain.TraceID{"failed"}},
+		{"time window", &domain.SearchCriteria{StartTime: &from, EndTime: &until}, []domain.TraceID{"failed"}},
+		{"start time inclusive", &domain.SearchCriteria{StartTime: &baseTime, EndTime: &baseTime}, []domain.TraceID{"slow"}},
+		{"min duration", &domain.SearchCriteria{MinDuration: &minDuration}, []domain.TraceID{"other", "slow"}},
+		{"max duration", &domain.SearchCriteria{MaxDuration: &maxDuration}, []domain.TraceID{"failed"}},
+		{"tags", &domain.SearchCriteria{Tags: map[string]string{"env": "prod"}}, []domain.TraceID{"other", "slow"}},
+		{"service and tags", &domain.SearchCriteria{Service: &service, Tags: map[string]string{"env": "prod"}}, []domain.TraceID{"slow"}},
+		{"unknown tag value", &domain.SearchCriteria{Tags: map[string]string{"env": "dev"}}, []domain.TraceID{}},
+		{
+			"span filter",
+			&domain.SearchCriteria{SpanFilters: []domain.SpanFilter{{Service: &spanService, Status: &spanStatus}}},
+			[]domain.TraceID{"failed"},
+		},
+		{
+			"span filter on one span",
+			&domain.SearchCriteria{SpanFilters: []domain.SpanFilter{{Service: &spanService, Tags: map[string]string{"span.kind": "server"}}}},
+			[]domain.TraceID{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := repo.Search(ctx, tt.criteria)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, traceIDs(traces))
+		})
+	}
+}
+
+func testSearchOrdering(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+
+	// b and c share a start time so the trace ID breaks the tie
+	saveAll(t, repo,
+		NewTrace("a", "checkout", "op", baseTime, 3*time.Second),
+		NewTrace("c", "checkout", "op", baseTime.Add(time.Minute), time.Second),
+		NewTrace("b", "checkout", "op", baseTime.Add(time.Minute), 2*time.Second),
+		NewTrace("d", "checkout", "op", baseTime.Add(2*time.Minute), 2*time.Second),
+	)
+
+	tests := []struct {
+		name     string
+		criteria *domain.SearchCriteria
+		expected []domain.TraceID
+	}{
+		{"default newest first", &domain.SearchCriteria{}, []domain.TraceID{"d", "c", "b", "a"}},
+		{"start time ascending", &domain.SearchCriteria{SortOrder: domain.SortAscending}, []domain.TraceID{"a", "b", "c", "d"}},
+		{"duration descending", &domain.SearchCriteria{SortBy: domain.SortByDuration}, []domain.TraceID{"a", "d", "b", "c"}},
+		{"duration ascending", &domain.SearchCriteria{SortBy: domain.SortByDuration, SortOrder: domain.SortAscending}, []domain.TraceID{"c", "b", "d", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := repo.Search(ctx, tt.criteria)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, traceIDs(traces))
+		})
+	}
+}
+
+func testSearchOffsetPagination(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		saveAll(t, repo, NewTrace(fmt.Sprintf("trace-%d", i), "checkout", "op", baseTime.Add(time.Duration(i)*time.Second), time.Second))
+	}
+
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.TraceID{"trace-3", "trace-2"}, traceIDs(traces))
+
+	traces, err = repo.Search(ctx, &domain.SearchCriteria{Limit: 2, Offset: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.TraceID{"trace-0"}, traceIDs(traces))
+
+	traces, err = repo.Search(ctx, &domain.SearchCriteria{Limit: 2, Offset: 10})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+}
+
+func testSearchCursorPagination(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+
+	// Pairs of traces share a start time to exercise the tie-breaker
+	var expected []domain.TraceID
+	for i := 6; i >= 0; i-- {
+		expected = append(expected, domain.TraceID(fmt.Sprintf("trace-%d", i)))
+	}
+	for i := 0; i < 7; i++ {
+		saveAll(t, repo, NewTrace(fmt.Sprintf("trace-%d", i), "checkout", "op", baseTime.Add(time.Duration(i/2)*time.Second), time.Second))
+	}
+
+	var seen []domain.TraceID
+	criteria := &domain.SearchCriteria{Limit: 3}
+	for page := 0; ; page++ {
+		require.Less(t, page, 10, "pagination did not terminate")
+
+		traces, err := repo.Search(ctx, criteria)
+		require.NoError(t, err)
+		seen = append(seen, traceIDs(traces)...)
+
+		criteria.Cursor = domain.NextTraceCursor(criteria, traces)
+		if criteria.Cursor == nil {
+			break
+		}
+	}
+
+	assert.Equal(t, expected, seen)
+
+	// A cursor issued for another sort order is rejected
+	cursor := domain.NewTraceCursor(NewTrace("trace-0", "checkout", "op", baseTime, time.Second), domain.SortByDuration, domain.SortAscending)
+	_, err := repo.Search(ctx, &domain.SearchCriteria{Limit: 3, Cursor: &cursor})
+	assert.Error(t, err)
+}
+
+func testSearchNoMatches(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+
+	service := domain.ServiceName("unknown")
+	saveAll(t, repo, NewTrace("trace-1", "checkout", "op", baseTime, time.Second))
+	traces, err = repo.Search(ctx, &domain.SearchCriteria{Service: &service})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+}
+
+func testSearchRejectsInvalidCriteria(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	later := baseTime.Add(time.Hour)
+	minDuration := 2 * time.Second
+	maxDuration := time.Second
+
+	tests := map[string]*domain.SearchCriteria{
+		"nil criteria":       nil,
+		"negative limit":     {Limit: -1},
+		"negative offset":    {Offset: -1},
+		"start after end":    {StartTime: &later, EndTime: &baseTime},
+		"min above max":      {MinDuration: &minDuration, MaxDuration: &maxDuration},
+		"empty span filter":  {SpanFilters: []domain.SpanFilter{{}}},
+		"unknown sort field": {SortBy: "size"},
+		"unknown sort order": {SortOrder: "sideways"},
+	}
+
+	for name, criteria := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.Search(ctx, criteria)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func testCount(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	saveAll(t, repo,
+		NewTrace("trace-1", "checkout", "op", baseTime, time.Second),
+		NewTrace("trace-2", "checkout", "op", baseTime.Add(time.Second), time.Second),
+		NewTrace("trace-3", "inventory", "op", baseTime.Add(2*time.Second), time.Second),
+	)
+
+	service := domain.ServiceName("checkout")
+
+	// Pagination does not change the total
+	count, err := repo.Count(ctx, &domain.SearchCriteria{Service: &service, Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.Count(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func testServicesAndOperations(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+
+	services, err := repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, services)
+
+	saveAll(t, repo,
+		NewTrace("trace-1", "payments", "charge", baseTime, time.Second),
+		NewTrace("trace-2", "checkout", "POST /checkout", baseTime, time.Second),
+		NewTrace("trace-3", "checkout", "GET /cart", baseTime, time.Second),
+		NewTrace("trace-4", "checkout", "GET /cart", baseTime, time.Second),
+	)
+
+	services, err = repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServiceName{"checkout", "payments"}, services)
+
+	operations, err := repo.GetOperations(ctx, "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.OperationName{"GET /cart", "POST /checkout"}, operations)
+
+	operations, err = repo.GetOperations(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+
+	_, err = repo.GetOperations(ctx, "")
+	assert.Error(t, err)
+}
+
+func testConcurrentSaves(t *testing.T, repo domain.TraceRepository) {
+	ctx := context.Background()
+	const workers = 8
+	const perWorker = 25
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				id := fmt.Sprintf("trace-%d-%d", worker, i)
+				trace := NewTrace(id, domain.ServiceName(fmt.Sprintf("service-%d", worker%2)), "op", baseTime.Add(time.Duration(i)*time.Millisecond), time.Second)
+				assert.NoError(t, repo.Save(ctx, trace))
+
+				// Re-saving the same trace concurrently must stay an upsert
+				if i%5 == 0 {
+					assert.NoError(t, repo.Save(ctx, trace))
+				}
+
+				_, err := repo.Search(ctx, &domain.SearchCriteria{Limit: 5})
+				assert.NoError(t, err)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	count, err := repo.Count(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(workers*perWorker), count)
+
+	services, err := repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServiceName{"service-0", "service-1"}, services)
+}
+
+// testCloseTwice checks that repositories holding resources can be closed
+// more than once, as the app and deferred cleanups may both close them
+func testCloseTwice(t *testing.T, repo domain.TraceRepository) {
+	closer, ok := repo.(io.Closer)
+	if !ok {
+		t.Skip("repository does not implement io.Closer")
+	}
+
+	require.NoError(t, closer.Close())
+	assert.NotPanics(t, func() {
+		assert.NoError(t, closer.Close())
+	})
+}
@@ -0,0 +1,99 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTraceRollupStoreContract runs the conformance suite for repositories
+// that implement domain.TraceRollupStore. Each case gets a fresh repository.
+func RunTraceRollupStoreContract(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, repo domain.TraceRepository, store domain.TraceRollupStore)
+	}{
+		{"ResaveCountsTraceOnce", testResaveCountsTraceOnce},
+		{"ConcurrentSavesCountTracesOnce", testConcurrentSavesCountTracesOnce},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			store, ok := repo.(domain.TraceRollupStore)
+			require.True(t, ok, "repository does not implement domain.TraceRollupStore")
+			tc.run(t, repo, store)
+		})
+	}
+}
+
+// rollupTotals sums the minute rollups of the hour after baseTime
+func rollupTotals(t *testing.T, store domain.TraceRollupStore) *domain.TraceRollup {
+	rollups, err := store.FindRollups(context.Background(), domain.RollupMinute, domain.MetricsQuery{
+		Start: baseTime,
+		End:   baseTime.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	total := domain.NewTraceRollup(domain.RollupMinute, baseTime, "", "")
+	for _, rollup := range rollups {
+		total.Merge(rollup)
+	}
+	return total
+}
+
+func testResaveCountsTraceOnce(t *testing.T, repo domain.TraceRepository, store domain.TraceRollupStore) {
+	ctx := context.Background()
+	trace := NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second)
+	saveAll(t, repo, trace)
+
+	failed := NewTrace("trace-1", "checkout", "POST /checkout", baseTime, time.Second)
+	failed.Status = domain.TraceStatusError
+	require.NoError(t, repo.Save(ctx, failed))
+
+	total := rollupTotals(t, store)
+	assert.Equal(t, int64(1), total.TraceCount)
+	assert.Equal(t, int64(2), total.SpanCount)
+	assert.Equal(t, int64(1), total.ErrorCount)
+	assert.Equal(t, int64(1), total.Durations.Count())
+}
+
+func testConcurrentSavesCountTracesOnce(t *testing.T, repo domain.TraceRepository, store domain.TraceRollupStore) {
+	ctx := context.Background()
+	const workers = 8
+	const traces = 10
+
+	// Every worker saves the same traces, so writers of one trace race
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			batch := make([]*domain.Trace, 0, traces)
+			for i := 0; i < traces; i++ {
+				batch = append(batch, NewTrace(fmt.Sprintf("trace-%d", i), "checkout", "POST /checkout", baseTime.Add(time.Duration(i)*time.Second), time.Second))
+			}
+
+			if saver, ok := repo.(domain.TraceBatchSaver); ok && worker%2 == 0 {
+				assert.NoError(t, saver.SaveBatch(ctx, batch))
+				return
+			}
+			for _, trace := range batch {
+				assert.NoError(t, repo.Save(ctx, trace))
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	total := rollupTotals(t, store)
+	assert.Equal(t, int64(traces), total.TraceCount)
+	assert.Equal(t, int64(2*traces), total.SpanCount)
+	assert.Equal(t, int64(traces), total.Durations.Count())
+	assert.Equal(t, time.Duration(traces)*time.Second, total.DurationSum)
+}
@@ -0,0 +1,212 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunSLOStoreContract runs the conformance suite for repositories that
+// implement domain.SLOStore. Each case gets a fresh repository.
+func RunSLOStoreContract(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, store domain.SLOStore)
+	}{
+		{"SaveAndFindSLO", testSaveAndFindSLO},
+		{"SaveSLOReplaces", testSaveSLOReplaces},
+		{"ListSLOsOrder", testListSLOsOrder},
+		{"DeleteSLO", testDeleteSLO},
+		{"AddSLOBucketsSums", testAddSLOBucketsSums},
+		{"DeleteSLOBuckets", testDeleteSLOBuckets},
+		{"DeleteSLORemovesBuckets", testDeleteSLORemovesBuckets},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			store, ok := repo.(domain.SLOStore)
+			require.True(t, ok, "repository does not implement domain.SLOStore")
+			tc.run(t, store)
+		})
+	}
+}
+
+// newSLO returns a valid SLO
+func newSLO(id, name string) *domain.SLO {
+	return &domain.SLO{
+		ID:        id,
+		Name:      name,
+		Service:   "checkout",
+		Operation: "pay",
+		Threshold: 300 * time.Millisecond,
+		Target:    0.99,
+		Window:    30 * 24 * time.Hour,
+		CreatedAt: baseTime,
+		UpdatedAt: baseTime.Add(time.Minute),
+	}
+}
+
+// assertSLO compares SLOs with timestamps compared as instants
+func assertSLO(t *testing.T, expected, actual *domain.SLO) {
+	t.Helper()
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "created at %s, want %s", actual.CreatedAt, expected.CreatedAt)
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt), "updated at %s, want %s", actual.UpdatedAt, expected.UpdatedAt)
+
+	e, a := *expected, *actual
+	e.CreatedAt, e.UpdatedAt, a.CreatedAt, a.UpdatedAt = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	assert.Equal(t, e, a)
+}
+
+func testSaveAndFindSLO(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	slo := newSLO("slo-1", "checkout latency")
+	require.NoError(t, store.SaveSLO(ctx, slo))
+
+	found, err := store.FindSLO(ctx, "slo-1")
+	require.NoError(t, err)
+	assertSLO(t, slo, found)
+
+	_, err = store.FindSLO(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrSLONotFound)
+}
+
+func testSaveSLOReplaces(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	slo := newSLO("slo-1", "checkout latency")
+	require.NoError(t, store.SaveSLO(ctx, slo))
+
+	// The stored SLO does not share memory with the saved one
+	slo.Target = 0.5
+
+	updated := newSLO("slo-1", "checkout latency")
+	updated.Operation = ""
+	updated.Threshold = time.Second
+	require.NoError(t, store.SaveSLO(ctx, updated))
+
+	found, err := store.FindSLO(ctx, "slo-1")
+	require.NoError(t, err)
+	assertSLO(t, updated, found)
+
+	slos, err := store.ListSLOs(ctx)
+	require.NoError(t, err)
+	assert.Len(t, slos, 1)
+}
+
+func testListSLOsOrder(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	slos, err := store.ListSLOs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, slos)
+
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-1", "payments")))
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-2", "checkout")))
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-0", "payments")))
+
+	slos, err = store.ListSLOs(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, slo := range slos {
+		ids = append(ids, slo.ID)
+	}
+	assert.Equal(t, []string{"slo-2", "slo-0", "slo-1"}, ids)
+}
+
+func testDeleteSLO(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-1", "checkout latency")))
+
+	require.NoError(t, store.DeleteSLO(ctx, "slo-1"))
+	_, err := store.FindSLO(ctx, "slo-1")
+	assert.ErrorIs(t, err, domain.ErrSLONotFound)
+
+	assert.ErrorIs(t, store.DeleteSLO(ctx, "slo-1"), domain.ErrSLONotFound)
+}
+
+// assertSLOBuckets compares buckets with starts compared as instants
+func assertSLOBuckets(t *testing.T, expected, actual []domain.SLOBucket) {
+	t.Helper()
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		assert.True(t, expected[i].Start.Equal(actual[i].Start), "bucket %d starts at %s, want %s", i, actual[i].Start, expected[i].Start)
+		assert.Equal(t, expected[i].Total, actual[i].Total, "bucket %d total", i)
+		assert.Equal(t, expected[i].Good, actual[i].Good, "bucket %d good", i)
+	}
+}
+
+func testAddSLOBucketsSums(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-1", "checkout latency")))
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-2", "payments latency")))
+
+	first, second := baseTime, baseTime.Add(domain.SLOBucketWidth)
+	require.NoError(t, store.AddSLOBuckets(ctx, "slo-1", []domain.SLOBucket{
+		{Start: second, Total: 3, Good: 2},
+		{Start: first, Total: 1, Good: 1},
+	}))
+	// Counts added by another instance are summed into the same buckets
+	require.NoError(t, store.AddSLOBuckets(ctx, "slo-1", []domain.SLOBucket{{Start: second, Total: 2, Good: 0}}))
+	require.NoError(t, store.AddSLOBuckets(ctx, "slo-2", []domain.SLOBucket{{Start: first, Total: 7, Good: 7}}))
+
+	buckets, err := store.FindSLOBuckets(ctx, "slo-1", first)
+	require.NoError(t, err)
+	assertSLOBuckets(t, []domain.SLOBucket{
+		{Start: first, Total: 1, Good: 1},
+		{Start: second, Total: 5, Good: 2},
+	}, buckets)
+
+	buckets, err = store.FindSLOBuckets(ctx, "slo-1", second)
+	require.NoError(t, err)
+	assertSLOBuckets(t, []domain.SLOBucket{{Start: second, Total: 5, Good: 2}}, buckets)
+
+	buckets, err = store.FindSLOBuckets(ctx, "missing", first)
+	require.NoError(t, err)
+	assert.Empty(t, buckets)
+}
+
+func testDeleteSLOBuckets(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-1", "checkout latency")))
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-2", "payments latency")))
+
+	var buckets []domain.SLOBucket
+	for i := 0; i < 3; i++ {
+		buckets = append(buckets, domain.SLOBucket{Start: baseTime.Add(time.Duration(i) * domain.SLOBucketWidth), Total: 1})
+	}
+	require.NoError(t, store.AddSLOBuckets(ctx, "slo-1", buckets))
+	require.NoError(t, store.AddSLOBuckets(ctx, "slo-2", buckets))
+
+	// Only the buckets starting before the cutoff are removed
+	require.NoError(t, store.DeleteSLOBuckets(ctx, "slo-1", buckets[2].Start))
+	found, err := store.FindSLOBuckets(ctx, "slo-1", baseTime)
+	require.NoError(t, err)
+	assertSLOBuckets(t, buckets[2:], found)
+
+	// The zero time removes every bucket of the SLO and no other
+	require.NoError(t, store.DeleteSLOBuckets(ctx, "slo-1", time.Time{}))
+	found, err = store.FindSLOBuckets(ctx, "slo-1", baseTime)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	found, err = store.FindSLOBuckets(ctx, "slo-2", baseTime)
+	require.NoError(t, err)
+	assertSLOBuckets(t, buckets, found)
+}
+
+func testDeleteSLORemovesBuckets(t *testing.T, store domain.SLOStore) {
+	ctx := context.Background()
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-1", "checkout latency")))
+	require.NoError(t, store.AddSLOBuckets(ctx, "slo-1", []domain.SLOBucket{{Start: baseTime, Total: 1, Good: 1}}))
+
+	require.NoError(t, store.DeleteSLO(ctx, "slo-1"))
+
+	// An SLO created again under the same ID starts without counts
+	require.NoError(t, store.SaveSLO(ctx, newSLO("slo-1", "checkout latency")))
+	buckets, err := store.FindSLOBuckets(ctx, "slo-1", baseTime)
+	require.NoError(t, err)
+	assert.Empty(t, buckets)
+}
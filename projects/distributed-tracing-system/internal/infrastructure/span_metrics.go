@@ -0,0 +1,209 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// spanMetricsOverflowLabel replaces label values beyond the cardinality limits
+const spanMetricsOverflowLabel = "__other__"
+
+// spanKindUnspecified labels spans that carry no span kind tag
+const spanKindUnspecified = "unspecified"
+
+// DefaultSpanMetricsBuckets are the span duration histogram bounds in seconds
+var DefaultSpanMetricsBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SpanMetricsConfig holds configuration for span-derived RED metrics
+type SpanMetricsConfig struct {
+	// Buckets are the upper bounds in seconds of the span duration histogram
+	Buckets []float64
+	// MaxServices bounds the distinct service labels; further services are
+	// reported as "__other__". Zero means no limit.
+	MaxServices int
+	// MaxOperations bounds the distinct operation labels per service;
+	// further operations are reported as "__other__". Zero means no limit.
+	MaxOperations int
+}
+
+// ParseHistogramBuckets parses comma-separated, strictly increasing bucket
+// bounds in seconds
+func ParseHistogramBuckets(value string) ([]float64, error) {
+	var buckets []float64
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %w", field, err)
+		}
+		if bound <= 0 {
+			return nil, fmt.Errorf("bucket %q must be positive", field)
+		}
+		if len(buckets) > 0 && bound <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("buckets must be strictly increasing")
+		}
+		buckets = append(buckets, bound)
+	}
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("at least one bucket is required")
+	}
+	return buckets, nil
+}
+
+// spanMetrics derives request, error and duration series from every span,
+// labelled by service, operation and span kind
+type spanMetrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	overflow prometheus.Counter
+
+	config SpanMetricsConfig
+
+	mu sync.Mutex
+	// operations holds the admitted operations of every admitted service
+	operations map[string]map[string]bool
+}
+
+// newSpanMetrics creates the span metrics and registers them
+func newSpanMetrics(registry prometheus.Registerer, config SpanMetricsConfig) (*spanMetrics, error) {
+	if len(config.Buckets) == 0 {
+		config.Buckets = DefaultSpanMetricsBuckets
+	}
+	for i := 1; i < len(config.Buckets); i++ {
+		if config.Buckets[i] <= config.Buckets[i-1] {
+			return nil, fmt.Errorf("span metrics buckets must be strictly increasing")
+		}
+	}
+	if config.MaxServices < 0 || config.MaxOperations < 0 {
+		return nil, fmt.Errorf("span metrics limits cannot be negative")
+	}
+
+	labels := []string{"service", "operation", "span_kind"}
+	m := &spanMetrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "span_requests_total",
+				Help: "Spans received per service, operation and span kind",
+			},
+			labels,
+		),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "span_errors_total",
+				Help: "Spans with error status per service, operation and span kind",
+			},
+			labels,
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "span_duration_seconds",
+				Help:    "Span duration per service, operation and span kind",
+				Buckets: config.Buckets,
+			},
+			labels,
+		),
+		overflow: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "span_metrics_overflow_total",
+				Help: "Spans reported under the __other__ label because a cardinality limit was reached",
+			},
+		),
+		config:     config,
+		operations: make(map[string]map[string]bool),
+	}
+
+	for _, collector := range []prometheus.Collector{m.requests, m.errors, m.duration, m.overflow} {
+		if err := registry.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register span metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// labels returns the service and operation labels of a span, replacing
+// values beyond the cardinality limits with the overflow label
+func (m *spanMetrics) labels(service, operation string) (string, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	operations, ok := m.operations[service]
+	if !ok {
+		if m.config.MaxServices > 0 && len(m.operations) >= m.config.MaxServices {
+			m.overflow.Inc()
+			return spanMetricsOverflowLabel, spanMetricsOverflowLabel
+		}
+		operations = make(map[string]bool)
+		m.operations[service] = operations
+	}
+
+	if !operations[operation] {
+		if m.config.MaxOperations > 0 && len(operations) >= m.config.MaxOperations {
+			m.overflow.Inc()
+			return service, spanMetricsOverflowLabel
+		}
+		operations[operation] = true
+	}
+	return service, operation
+}
+
+// record records the spans of a trace. The trace ID is attached as an
+// exemplar so dashboards can link a series to a concrete trace.
+func (m *spanMetrics) record(trace *domain.Trace) {
+	exemplar := traceExemplar(trace.ID)
+	for _, span := range trace.Spans {
+		service, operation := m.labels(string(span.Service), string(span.Operation))
+		kind := span.Tags[domain.SpanKindTag]
+		if kind == "" {
+			kind = spanKindUnspecified
+		}
+
+		duration := span.Duration
+		if duration == 0 && span.EndTime.After(span.StartTime) {
+			duration = span.EndTime.Sub(span.StartTime)
+		}
+
+		addWithExemplar(m.requests.WithLabelValues(service, operation, kind), exemplar)
+		if span.Status == domain.SpanStatusError {
+			addWithExemplar(m.errors.WithLabelValues(service, operation, kind), exemplar)
+		}
+		observeWithExemplar(m.duration.WithLabelValues(service, operation, kind), duration.Seconds(), exemplar)
+	}
+}
+
+// traceExemplar returns the exemplar labels pointing at a trace, or nil
+// when the trace ID cannot be used as an exemplar
+func traceExemplar(id domain.TraceID) prometheus.Labels {
+	const name = "trace_id"
+	if id == "" || !utf8.ValidString(string(id)) || utf8.RuneCountInString(name+string(id)) > prometheus.ExemplarMaxRunes {
+		return nil
+	}
+	return prometheus.Labels{name: string(id)}
+}
+
+// addWithExemplar increments a counter, attaching the exemplar when there is one
+func addWithExemplar(counter prometheus.Counter, exemplar prometheus.Labels) {
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		adder.AddWithExemplar(1, exemplar)
+		return
+	}
+	counter.Inc()
+}
+
+// observeWithExemplar observes a value, attaching the exemplar when there is one
+func observeWithExemplar(observer prometheus.Observer, value float64, exemplar prometheus.Labels) {
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		exemplarObserver.ObserveWithExemplar(value, exemplar)
+		return
+	}
+	observer.Observe(value)
+}
@@ -0,0 +1,130 @@
+package infrastructure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatherSeries returns the value of every counter and gauge series and
+// the sample count of every histogram series of a registry, keyed by
+// metric name and label values
+func gatherSeries(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	series := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var labels []string
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			key := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case metric.GetCounter() != nil:
+				series[key] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				series[key] = float64(metric.GetHistogram().GetSampleCount())
+			case metric.GetGauge() != nil:
+				series[key] = metric.GetGauge().GetValue()
+			}
+		}
+	}
+	return series
+}
+
+func spanMetricsTestTrace(id domain.TraceID, spans ...domain.Span) *domain.Trace {
+	return &domain.Trace{ID: id, Service: "checkout", Operation: "pay", Spans: spans}
+}
+
+func TestParseHistogramBuckets(t *testing.T) {
+	buckets, err := ParseHistogramBuckets("0.005, 0.05,0.5,5")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.005, 0.05, 0.5, 5}, buckets)
+
+	for _, value := range []string{"", "0.1,abc", "0.5,0.1", "0.1,0.1", "-1,1"} {
+		_, err := ParseHistogramBuckets(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestSpanMetrics_Record(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := newSpanMetrics(registry, SpanMetricsConfig{Buckets: []float64{0.01, 0.1, 1}})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	metrics.record(spanMetricsTestTrace("trace-1",
+		domain.Span{ID: "a", Service: "checkout", Operation: "pay", Duration: 50 * time.Millisecond, Status: domain.SpanStatusOK,
+			Tags: map[string]string{domain.SpanKindTag: "server"}},
+		domain.Span{ID: "b", Service: "payments", Operation: "charge", StartTime: start, EndTime: start.Add(200 * time.Millisecond), Status: domain.SpanStatusError},
+	))
+	metrics.record(spanMetricsTestTrace("trace-2",
+		domain.Span{ID: "a", Service: "payments", Operation: "charge", Duration: time.Millisecond, Status: domain.SpanStatusOK},
+	))
+
+	series := gatherSeries(t, registry)
+	assert.Equal(t, 1.0, series["span_requests_total{pay,checkout,server}"])
+	assert.Equal(t, 2.0, series["span_requests_total{charge,payments,unspecified}"])
+	assert.Equal(t, 1.0, series["span_errors_total{charge,payments,unspecified}"])
+	assert.NotContains(t, series, "span_errors_total{pay,checkout,server}")
+	assert.Equal(t, 2.0, series["span_duration_seconds{charge,payments,unspecified}"])
+
+	// Histogram buckets keep the trace of their last observation as exemplar
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	var exemplars []string
+	for _, family := range families {
+		if family.GetName() != "span_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				for _, label := range bucket.GetExemplar().GetLabel() {
+					exemplars = append(exemplars, label.GetName()+"="+label.GetValue())
+				}
+			}
+		}
+	}
+	assert.Contains(t, exemplars, "trace_id=trace-1")
+	assert.Contains(t, exemplars, "trace_id=trace-2")
+}
+
+func TestSpanMetrics_CardinalityLimits(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := newSpanMetrics(registry, SpanMetricsConfig{MaxServices: 1, MaxOperations: 1})
+	require.NoError(t, err)
+
+	metrics.record(spanMetricsTestTrace("trace-1",
+		domain.Span{ID: "a", Service: "checkout", Operation: "pay"},
+		domain.Span{ID: "b", Service: "checkout", Operation: "refund"},
+		domain.Span{ID: "c", Service: "payments", Operation: "charge"},
+		domain.Span{ID: "d", Service: "checkout", Operation: "pay"},
+	))
+
+	series := gatherSeries(t, registry)
+	assert.Equal(t, 2.0, series["span_requests_total{pay,checkout,unspecified}"])
+	assert.Equal(t, 1.0, series["span_requests_total{__other__,checkout,unspecified}"])
+	assert.Equal(t, 1.0, series["span_requests_total{__other__,__other__,unspecified}"])
+	assert.Equal(t, 2.0, series["span_metrics_overflow_total{}"])
+}
+
+func TestSpanMetrics_InvalidConfig(t *testing.T) {
+	_, err := newSpanMetrics(prometheus.NewRegistry(), SpanMetricsConfig{Buckets: []float64{1, 0.5}})
+	assert.Error(t, err)
+
+	_, err = newSpanMetrics(prometheus.NewRegistry(), SpanMetricsConfig{MaxServices: -1})
+	assert.Error(t, err)
+}
+
+func TestTraceExemplar(t *testing.T) {
+	assert.Equal(t, prometheus.Labels{"trace_id": "abc"}, traceExemplar("abc"))
+	assert.Nil(t, traceExemplar(""))
+	assert.Nil(t, traceExemplar(domain.TraceID(strings.Repeat("a", prometheus.ExemplarMaxRunes))))
+}
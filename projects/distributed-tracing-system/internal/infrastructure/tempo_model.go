@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// TempoTraceSummary is a trace in the search results of the Tempo API
+type TempoTraceSummary struct {
+	TraceID           string `json:"traceID"`
+	RootServiceName   string `json:"rootServiceName"`
+	RootTraceName     string `json:"rootTraceName"`
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	DurationMs        int64  `json:"durationMs"`
+}
+
+// TraceToTempoSummary summarizes a trace for Tempo search results. The
+// trace ID is kept as it is so that the trace can be fetched by it.
+func TraceToTempoSummary(trace *domain.Trace) TempoTraceSummary {
+	duration := trace.Duration
+	if duration == 0 {
+		duration = trace.EndTime.Sub(trace.StartTime)
+	}
+
+	return TempoTraceSummary{
+		TraceID:           string(trace.ID),
+		RootServiceName:   string(trace.Service),
+		RootTraceName:     string(trace.Operation),
+		StartTimeUnixNano: strconv.FormatInt(trace.StartTime.UnixNano(), 10),
+		DurationMs:        duration.Milliseconds(),
+	}
+}
+
+// TempoTraceProto encodes a trace as a Tempo trace message, the body of
+// Tempo's trace by ID API in protobuf. It shares the wire format of an OTLP
+// export request: the resource spans in field 1.
+func TempoTraceProto(trace *domain.Trace) ([]byte, error) {
+	body, err := proto.Marshal(tempoTraceMessage(trace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trace: %w", err)
+	}
+	return body, nil
+}
+
+// TempoTraceByIDProto encodes a trace as the protobuf body of Tempo's trace
+// by ID v2 API, a response holding the trace message in field 1
+func TempoTraceByIDProto(trace *domain.Trace) ([]byte, error) {
+	body, err := TempoTraceProto(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	response := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(response, body), nil
+}
+
+// TempoTraceByIDJSON encodes a trace as the JSON body of Tempo's trace by
+// ID v2 API. Like Tempo, IDs are encoded in base64.
+func TempoTraceByIDJSON(trace *domain.Trace) ([]byte, error) {
+	body, err := protojson.Marshal(tempoTraceMessage(trace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trace: %w", err)
+	}
+
+	return json.Marshal(struct {
+		Trace json.RawMessage `json:"trace"`
+	}{Trace: body})
+}
+
+// tempoTraceMessage returns the resource spans of a trace in a message with
+// the layout of a Tempo trace
+func tempoTraceMessage(trace *domain.Trace) *coltracepb.ExportTraceServiceRequest {
+	return &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: TracesToOTLP([]*domain.Trace{trace}),
+	}
+}
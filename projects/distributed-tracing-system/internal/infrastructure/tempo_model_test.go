@@ -0,0 +1,92 @@
+package infrastructure
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTraceToTempoSummary(t *testing.T) {
+	trace := testForwardTrace()
+
+	summary := TraceToTempoSummary(trace)
+
+	assert.Equal(t, string(trace.ID), summary.TraceID)
+	assert.Equal(t, string(trace.Service), summary.RootServiceName)
+	assert.Equal(t, string(trace.Operation), summary.RootTraceName)
+	assert.Equal(t, trace.EndTime.Sub(trace.StartTime).Milliseconds(), summary.DurationMs)
+
+	encoded, err := json.Marshal(summary)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"startTimeUnixNano":"`)
+}
+
+func TestTempoTraceProto(t *testing.T) {
+	trace := testForwardTrace()
+
+	body, err := TempoTraceProto(trace)
+	require.NoError(t, err)
+
+	var decoded coltracepb.ExportTraceServiceRequest
+	require.NoError(t, proto.Unmarshal(body, &decoded))
+	assert.True(t, proto.Equal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: TracesToOTLP([]*domain.Trace{trace}),
+	}, &decoded))
+}
+
+func TestTempoTraceByIDProto(t *testing.T) {
+	trace := testForwardTrace()
+	inner, err := TempoTraceProto(trace)
+	require.NoError(t, err)
+
+	body, err := TempoTraceByIDProto(trace)
+	require.NoError(t, err)
+
+	// The trace is the length-delimited field 1 of the response
+	number, wireType, n := protowire.ConsumeTag(body)
+	require.Greater(t, n, 0)
+	assert.Equal(t, protowire.Number(1), number)
+	assert.Equal(t, protowire.BytesType, wireType)
+	value, m := protowire.ConsumeBytes(body[n:])
+	require.Greater(t, m, 0)
+	assert.Equal(t, inner, value)
+	assert.Len(t, body, n+m)
+}
+
+func TestTempoTraceByIDJSON(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := &domain.Trace{
+		ID:        "463ac35c9f6413ad48485a3953bb6124",
+		Service:   "checkout",
+		Operation: "pay",
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Status:    domain.TraceStatusSuccess,
+		Spans: []domain.Span{
+			{ID: "a2fb4a1d1a96d312", Service: "checkout", Operation: "pay", StartTime: start, Duration: time.Second, Status: domain.SpanStatusOK},
+		},
+	}
+
+	body, err := TempoTraceByIDJSON(trace)
+	require.NoError(t, err)
+
+	var response struct {
+		Trace json.RawMessage `json:"trace"`
+	}
+	require.NoError(t, json.Unmarshal(body, &response))
+	var decoded coltracepb.ExportTraceServiceRequest
+	require.NoError(t, protojson.Unmarshal(response.Trace, &decoded))
+	require.Len(t, decoded.ResourceSpans, 1)
+	span := decoded.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "pay", span.Name)
+	assert.Equal(t, "463ac35c9f6413ad48485a3953bb6124", hex.EncodeToString(span.TraceId))
+}
@@ -0,0 +1,222 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported forwarding protocols
+const (
+	// ForwardProtocolOTLP posts OTLP/HTTP protobuf requests, e.g. to
+	// http://jaeger:4318/v1/traces or an OpenTelemetry collector
+	ForwardProtocolOTLP = "otlp"
+	// ForwardProtocolJaeger posts Jaeger thrift batches to a collector,
+	// e.g. http://jaeger:14268/api/traces
+	ForwardProtocolJaeger = "jaeger"
+)
+
+// otlpProtobufContentType is the content type of OTLP/HTTP protobuf requests
+const otlpProtobufContentType = "application/x-protobuf"
+
+// TraceForwarderConfig configures where stored traces are forwarded
+type TraceForwarderConfig struct {
+	// Protocol is "otlp" or "jaeger"
+	Protocol string
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// traceForwarder implements the JaegerExporter interface by shipping the
+// original spans of a trace, with their IDs, parents and timings, to a
+// Jaeger collector or an OTLP endpoint
+type traceForwarder struct {
+	config TraceForwarderConfig
+	client *http.Client
+	jaeger *jaeger.Exporter
+}
+
+// NewTraceForwarder creates a forwarder for the configured protocol
+func NewTraceForwarder(config TraceForwarderConfig) (domain.JaegerExporter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("forward endpoint is required")
+	}
+	if config.Timeout <= 0 {
+		return nil, fmt.Errorf("forward timeout must be positive")
+	}
+
+	forwarder := &traceForwarder{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	switch config.Protocol {
+	case ForwardProtocolOTLP:
+	case ForwardProtocolJaeger:
+		exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(
+			jaeger.WithEndpoint(config.Endpoint),
+			jaeger.WithHTTPClient(forwarder.client),
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Jaeger exporter: %w", err)
+		}
+		forwarder.jaeger = exporter
+	default:
+		return nil, fmt.Errorf("unsupported forward protocol %q", config.Protocol)
+	}
+
+	return forwarder, nil
+}
+
+// ExportTrace forwards the spans of a trace
+func (f *traceForwarder) ExportTrace(ctx context.Context, trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("invalid trace: trace cannot be nil")
+	}
+	if trace.ID == "" {
+		return fmt.Errorf("invalid trace: trace ID is required")
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("context cancelled: %w", ctx.Err())
+	default:
+	}
+
+	resourceSpans := TracesToOTLP([]*domain.Trace{trace})
+	if f.jaeger != nil {
+		if err := f.jaeger.ExportSpans(ctx, OTLPToReadOnlySpans(resourceSpans)); err != nil {
+			return fmt.Errorf("failed to export trace to Jaeger: %w", err)
+		}
+		return nil
+	}
+	return f.postOTLP(ctx, resourceSpans)
+}
+
+// postOTLP sends resource spans as an OTLP/HTTP protobuf request. Any
+// response other than 2xx is an error.
+func (f *traceForwarder) postOTLP(ctx context.Context, resourceSpans []*tracepb.ResourceSpans) error {
+	body, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: resourceSpans})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", otlpProtobufContentType)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send OTLP request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLPToReadOnlySpans converts OTLP resource spans into OpenTelemetry SDK
+// read-only spans, so they can be handed to any SDK span exporter with the
+// IDs, parents and timings they were recorded with
+func OTLPToReadOnlySpans(resourceSpans []*tracepb.ResourceSpans) []sdktrace.ReadOnlySpan {
+	var stubs tracetest.SpanStubs
+	for _, rs := range resourceSpans {
+		res := resource.NewSchemaless(otlpAttributesToOTel(rs.GetResource().GetAttributes())...)
+		for _, ss := range rs.GetScopeSpans() {
+			scope := instrumentation.Scope{Name: ss.GetScope().GetName(), Version: ss.GetScope().GetVersion()}
+			for _, otlpSpan := range ss.GetSpans() {
+				stubs = append(stubs, otlpSpanToStub(otlpSpan, res, scope))
+			}
+		}
+	}
+	return stubs.Snapshots()
+}
+
+// otlpSpanToStub converts a single OTLP span into an SDK span stub
+func otlpSpanToStub(otlpSpan *tracepb.Span, res *resource.Resource, scope instrumentation.Scope) tracetest.SpanStub {
+	var traceID oteltrace.TraceID
+	var spanID, parentID oteltrace.SpanID
+	copy(traceID[:], otlpSpan.GetTraceId())
+	copy(spanID[:], otlpSpan.GetSpanId())
+
+	stub := tracetest.SpanStub{
+		Name: otlpSpan.GetName(),
+		SpanContext: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: oteltrace.FlagsSampled,
+		}),
+		SpanKind:             otelSpanKind(otlpSpan.GetKind()),
+		StartTime:            time.Unix(0, int64(otlpSpan.GetStartTimeUnixNano())).UTC(),
+		EndTime:              time.Unix(0, int64(otlpSpan.GetEndTimeUnixNano())).UTC(),
+		Attributes:           otlpAttributesToOTel(otlpSpan.GetAttributes()),
+		Resource:             res,
+		InstrumentationScope: scope,
+	}
+	if len(otlpSpan.GetParentSpanId()) > 0 {
+		copy(parentID[:], otlpSpan.GetParentSpanId())
+		stub.Parent = oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     parentID,
+			TraceFlags: oteltrace.FlagsSampled,
+		})
+	}
+	if otlpSpan.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		stub.Status = sdktrace.Status{Code: codes.Error, Description: otlpSpan.GetStatus().GetMessage()}
+	}
+	for _, event := range otlpSpan.GetEvents() {
+		stub.Events = append(stub.Events, sdktrace.Event{
+			Name:       event.GetName(),
+			Time:       time.Unix(0, int64(event.GetTimeUnixNano())).UTC(),
+			Attributes: otlpAttributesToOTel(event.GetAttributes()),
+		})
+	}
+	return stub
+}
+
+// otlpAttributesToOTel converts OTLP attributes into OpenTelemetry string attributes
+func otlpAttributesToOTel(attributes []*commonpb.KeyValue) []attribute.KeyValue {
+	converted := make([]attribute.KeyValue, 0, len(attributes))
+	for _, attr := range attributes {
+		converted = append(converted, attribute.String(attr.GetKey(), otlpValueToString(attr.GetValue())))
+	}
+	return converted
+}
+
+// otelSpanKind maps an OTLP span kind to its OpenTelemetry span kind
+func otelSpanKind(kind tracepb.Span_SpanKind) oteltrace.SpanKind {
+	switch kind {
+	case tracepb.Span_SPAN_KIND_SERVER:
+		return oteltrace.SpanKindServer
+	case tracepb.Span_SPAN_KIND_CLIENT:
+		return oteltrace.SpanKindClient
+	case tracepb.Span_SPAN_KIND_PRODUCER:
+		return oteltrace.SpanKindProducer
+	case tracepb.Span_SPAN_KIND_CONSUMER:
+		return oteltrace.SpanKindConsumer
+	default:
+		return oteltrace.SpanKindInternal
+	}
+}
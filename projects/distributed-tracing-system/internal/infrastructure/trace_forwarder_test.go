@@ -0,0 +1,162 @@
+package infrastructure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func testForwardTrace() *domain.Trace {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rootID := domain.SpanID("eee19b7ec3c1b174")
+	return domain.BuildTrace("5b8efff798038103d269b633813fc60c", []domain.Span{
+		{
+			ID:        rootID,
+			TraceID:   "5b8efff798038103d269b633813fc60c",
+			Service:   "frontend",
+			Operation: "GET /checkout",
+			StartTime: start,
+			EndTime:   start.Add(100 * time.Millisecond),
+			Duration:  100 * time.Millisecond,
+			Tags:      map[string]string{domain.SpanKindTag: "server"},
+			Status:    domain.SpanStatusOK,
+		},
+		{
+			ID:        "eee19b7ec3c1b173",
+			TraceID:   "5b8efff798038103d269b633813fc60c",
+			ParentID:  &rootID,
+			Service:   "payments",
+			Operation: "charge",
+			StartTime: start.Add(10 * time.Millisecond),
+			EndTime:   start.Add(60 * time.Millisecond),
+			Duration:  50 * time.Millisecond,
+			Logs:      []domain.Log{{Timestamp: start.Add(20 * time.Millisecond), Message: "retry"}},
+			Status:    domain.SpanStatusError,
+		},
+	})
+}
+
+func TestNewTraceForwarder_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TraceForwarderConfig
+	}{
+		{name: "empty endpoint", config: TraceForwarderConfig{Protocol: ForwardProtocolOTLP, Timeout: time.Second}},
+		{name: "no timeout", config: TraceForwarderConfig{Protocol: ForwardProtocolOTLP, Endpoint: "http://localhost:4318/v1/traces"}},
+		{name: "unknown protocol", config: TraceForwarderConfig{Protocol: "zipkin", Endpoint: "http://localhost:9411", Timeout: time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarder, err := NewTraceForwarder(tt.config)
+			assert.Error(t, err)
+			assert.Nil(t, forwarder)
+		})
+	}
+}
+
+func TestTraceForwarder_OTLP(t *testing.T) {
+	var received coltracepb.ExportTraceServiceRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, otlpProtobufContentType, r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, proto.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	forwarder, err := NewTraceForwarder(TraceForwarderConfig{
+		Protocol: ForwardProtocolOTLP,
+		Endpoint: collector.URL,
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+
+	trace := testForwardTrace()
+	require.NoError(t, forwarder.ExportTrace(context.Background(), trace))
+
+	traces, rejected := OTLPToTraces(received.GetResourceSpans())
+	assert.Equal(t, 0, rejected)
+	require.Len(t, traces, 1)
+	assert.Equal(t, trace.ID, traces[0].ID)
+	require.Len(t, traces[0].Spans, 2)
+	assert.Equal(t, trace.Spans[1].ID, traces[0].Spans[1].ID)
+	assert.Equal(t, trace.Spans[1].ParentID, traces[0].Spans[1].ParentID)
+	assert.True(t, trace.Spans[1].StartTime.Equal(traces[0].Spans[1].StartTime))
+	assert.Equal(t, domain.SpanStatusError, traces[0].Spans[1].Status)
+}
+
+func TestTraceForwarder_OTLPErrorStatus(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	forwarder, err := NewTraceForwarder(TraceForwarderConfig{
+		Protocol: ForwardProtocolOTLP,
+		Endpoint: collector.URL,
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+
+	err = forwarder.ExportTrace(context.Background(), testForwardTrace())
+	assert.ErrorContains(t, err, "status 503")
+}
+
+func TestTraceForwarder_Jaeger(t *testing.T) {
+	var requests atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/x-thrift", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	forwarder, err := NewTraceForwarder(TraceForwarderConfig{
+		Protocol: ForwardProtocolJaeger,
+		Endpoint: collector.URL,
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, forwarder.ExportTrace(context.Background(), testForwardTrace()))
+	// One batch per service
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestOTLPToReadOnlySpans(t *testing.T) {
+	trace := testForwardTrace()
+
+	spans := OTLPToReadOnlySpans(TracesToOTLP([]*domain.Trace{trace}))
+	require.Len(t, spans, 2)
+
+	root, child := spans[0], spans[1]
+	assert.Equal(t, string(trace.ID), root.SpanContext().TraceID().String())
+	assert.Equal(t, string(trace.Spans[0].ID), root.SpanContext().SpanID().String())
+	assert.False(t, root.Parent().IsValid())
+	service, ok := root.Resource().Set().Value(otlpServiceNameKey)
+	assert.True(t, ok)
+	assert.Equal(t, "frontend", service.AsString())
+
+	assert.Equal(t, string(trace.ID), child.SpanContext().TraceID().String())
+	assert.Equal(t, root.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.True(t, trace.Spans[1].StartTime.Equal(child.StartTime()))
+	assert.True(t, trace.Spans[1].EndTime.Equal(child.EndTime()))
+	assert.Equal(t, codes.Error, child.Status().Code)
+	require.Len(t, child.Events(), 1)
+	assert.Equal(t, "retry", child.Events()[0].Name)
+}
@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"fmt"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// traceQueryCompiler compiles a trace query AST into a SQL condition over
+// the traces table, with span blocks becoming EXISTS subqueries on spans
+type traceQueryCompiler struct {
+	args     []interface{}
+	argIndex int
+}
+
+// compileTraceQuery compiles the query into a WHERE condition whose
+// placeholders start at argIndex
+func compileTraceQuery(query *domain.TraceQuery, argIndex int) (string, []interface{}, error) {
+	if query == nil || query.Root == nil {
+		return "", nil, fmt.Errorf("query is required")
+	}
+
+	compiler := &traceQueryCompiler{argIndex: argIndex}
+	condition, err := compiler.compile(query.Root, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return condition, compiler.args, nil
+}
+
+// compile compiles a node; alias is the spans table alias inside span blocks
+// and empty for trace-level conditions
+func (qc *traceQueryCompiler) compile(node domain.QueryNode, alias string) (string, error) {
+	switch n := node.(type) {
+	case *domain.QueryLogical:
+		left, err := qc.compile(n.Left, alias)
+		if err != nil {
+			return "", err
+		}
+		right, err := qc.compile(n.Right, alias)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, n.Operator, right), nil
+
+	case *domain.QueryNot:
+		operand, err := qc.compile(n.Operand, alias)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT %s", operand), nil
+
+	case *domain.QuerySpanMatch:
+		if alias != "" {
+			return "", fmt.Errorf("span blocks cannot be nested")
+		}
+		condition := "s.trace_id = traces.id"
+		if n.Condition != nil {
+			inner, err := qc.compile(n.Condition, "s")
+			if err != nil {
+				return "", err
+			}
+			condition += " AND " + inner
+		}
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM spans s WHERE %s)", condition), nil
+
+	case *domain.QueryComparison:
+		return qc.compileComparison(n, alias)
+
+	default:
+		return "", fmt.Errorf("unsupported query node %T", node)
+	}
+}
+
+// compileComparison compiles a single field comparison
+func (qc *traceQueryCompiler) compileComparison(c *domain.QueryComparison, alias string) (string, error) {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+
+	if c.Field == domain.QueryFieldDuration {
+		switch c.Operator {
+		case domain.QueryOpEqual, domain.QueryOpGreater, domain.QueryOpGreaterEqual,
+			domain.QueryOpLess, domain.QueryOpLessEqual:
+			return fmt.Sprintf("%sduration %s %s", prefix, c.Operator, qc.arg(c.Duration.Nanoseconds())), nil
+		case domain.QueryOpNotEqual:
+			return fmt.Sprintf("%sduration <> %s", prefix, qc.arg(c.Duration.Nanoseconds())), nil
+		default:
+			return "", fmt.Errorf("operator %s is not supported for duration", c.Operator)
+		}
+	}
+
+	var column string
+	switch c.Field {
+	case domain.QueryFieldService, domain.QueryFieldOperation, domain.QueryFieldStatus:
+		column = prefix + string(c.Field)
+	case domain.QueryFieldTag:
+		column = fmt.Sprintf("(%stags->>%s)", prefix, qc.arg(c.TagKey))
+	default:
+		return "", fmt.Errorf("unsupported field %q", c.Field)
+	}
+
+	// Missing tags compare as NULL; keep the in-memory semantics where a
+	// missing tag never equals a value and always differs from one
+	value := qc.arg(c.Value)
+	switch c.Operator {
+	case domain.QueryOpEqual:
+		return fmt.Sprintf("%s = %s", column, value), nil
+	case domain.QueryOpNotEqual:
+		return fmt.Sprintf("%s IS DISTINCT FROM %s", column, value), nil
+	case domain.QueryOpMatch:
+		return fmt.Sprintf("%s ~ %s", column, value), nil
+	case domain.QueryOpNotMatch:
+		return fmt.Sprintf("(%s IS NULL OR %s !~ %s)", column, column, value), nil
+	default:
+		return "", fmt.Errorf("operator %s is only supported for duration", c.Operator)
+	}
+}
+
+// arg registers a query argument and returns its placeholder
+func (qc *traceQueryCompiler) arg(value interface{}) string {
+	qc.args = append(qc.args, value)
+	placeholder := fmt.Sprintf("$%d", qc.argIndex)
+	qc.argIndex++
+	return placeholder
+}
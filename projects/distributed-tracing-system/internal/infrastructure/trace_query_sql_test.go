@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileTraceQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		condition string
+		args      []interface{}
+	}{
+		{
+			name:      "trace fields",
+			query:     `service=checkout AND duration>500ms`,
+			condition: "(service = $1 AND duration > $2)",
+			args:      []interface{}{"checkout", (500 * time.Millisecond).Nanoseconds()},
+		},
+		{
+			name:      "span block",
+			query:     `span{service="payments" status=error}`,
+			condition: "EXISTS (SELECT 1 FROM spans s WHERE s.trace_id = traces.id AND (s.service = $1 AND s.status = $2))",
+			args:      []interface{}{"payments", "error"},
+		},
+		{
+			name:      "tags",
+			query:     `tag.env=prod OR NOT tag.region!=eu`,
+			condition: "((tags->>$1) = $2 OR NOT (tags->>$3) IS DISTINCT FROM $4)",
+			args:      []interface{}{"env", "prod", "region", "eu"},
+		},
+		{
+			name:      "regex",
+			query:     `span{tag.http.url!~"^/health"}`,
+			condition: "EXISTS (SELECT 1 FROM spans s WHERE s.trace_id = traces.id AND ((s.tags->>$1) IS NULL OR (s.tags->>$1) !~ $2))",
+			args:      []interface{}{"http.url", "^/health"},
+		},
+		{
+			name:      "empty span block",
+			query:     `span{}`,
+			condition: "EXISTS (SELECT 1 FROM spans s WHERE s.trace_id = traces.id)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := domain.ParseTraceQuery(tt.query)
+			require.NoError(t, err)
+
+			condition, args, err := compileTraceQuery(query, 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.condition, condition)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
@@ -104,6 +104,18 @@ func (tr *traceRepository) Search(ctx context.Context, criteria *domain.SearchCr
 	return mockTraces[start:end], nil
 }
 
+// Count returns the number of traces matching the criteria
+func (tr *traceRepository) Count(ctx context.Context, criteria *domain.SearchCriteria) (int64, error) {
+	// Validate criteria
+	if err := tr.validateSearchCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	// In a real implementation, you would count in the database
+	// For now, match the number of mock traces returned by Search
+	return 2, nil
+}
+
 // GetServices returns all available services
 func (tr *traceRepository) GetServices(ctx context.Context) ([]domain.ServiceName, error) {
 	// In a real implementation, you would query the database
@@ -0,0 +1,1088 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket layout of the embedded store. Index keys end with the trace start
+// time (8 bytes, big endian) followed by the trace ID, so every index can be
+// range-scanned by time.
+var (
+	boltTracesBucket            = []byte("traces")
+	boltTimeIndexBucket         = []byte("idx_time")
+	boltServiceIndexBucket      = []byte("idx_service")
+	boltOperationIndexBucket    = []byte("idx_operation")
+	boltTagIndexBucket          = []byte("idx_tag")
+	boltExpiryBucket            = []byte("expiry")
+	boltServiceOperationsBucket = []byte("service_operations")
+	boltDependenciesBucket      = []byte("dependencies")
+	boltSLOsBucket              = []byte("slos")
+	boltSLOBucketsBucket        = []byte("slo_buckets")
+
+	boltBuckets = [][]byte{
+		boltTracesBucket,
+		boltTimeIndexBucket,
+		boltServiceIndexBucket,
+		boltOperationIndexBucket,
+		boltTagIndexBucket,
+		boltExpiryBucket,
+		boltServiceOperationsBucket,
+		boltDependenciesBucket,
+		boltSLOsBucket,
+		boltSLOBucketsBucket,
+	}
+)
+
+const (
+	// boltKeySeparator separates string components of index keys
+	boltKeySeparator = 0x00
+	// boltCompactTxMaxSize is the amount of data copied per transaction during compaction
+	boltCompactTxMaxSize = 4 * 1024 * 1024
+	// boltExpiryBatchSize bounds the number of traces deleted per transaction
+	boltExpiryBatchSize = 1000
+)
+
+// BoltRepositoryConfig holds configuration for the embedded trace repository
+type BoltRepositoryConfig struct {
+	// Path is the database file, created if it does not exist
+	Path string
+	// TTL is how long a trace is kept after it is saved; zero keeps traces forever
+	TTL time.Duration
+	// ExpiryInterval is how often expired traces are deleted; zero disables the sweep
+	ExpiryInterval time.Duration
+	// CompactionInterval is how often the database file is compacted to
+	// reclaim space freed by deletes; zero disables compaction
+	CompactionInterval time.Duration
+}
+
+// boltRecord is the stored form of a trace
+type boltRecord struct {
+	Trace *domain.Trace `json:"trace"`
+	// ExpiresAt is the expiry time in Unix nanoseconds, zero when the trace does not expire
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// traceRepositoryBolt implements the TraceRepository interface on an
+// embedded bbolt database, for deployments without PostgreSQL
+type traceRepositoryBolt struct {
+	jaegerExporter domain.JaegerExporter
+	config         BoltRepositoryConfig
+	now            func() time.Time
+
+	// mu guards db; compaction swaps the database file under the write lock
+	mu sync.RWMutex
+	db *bolt.DB
+	// open opens the database file when compaction swaps it
+	open func(path string) (*bolt.DB, error)
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewTraceRepositoryBolt opens or creates an embedded trace repository. The
+// Jaeger exporter is optional. Expired traces are swept and the file is
+// compacted in the background until Close is called.
+func NewTraceRepositoryBolt(config BoltRepositoryConfig, jaegerExporter domain.JaegerExporter) (domain.TraceRepository, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("database path is required")
+	}
+	if config.TTL < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative")
+	}
+	if config.ExpiryInterval < 0 || config.CompactionInterval < 0 {
+		return nil, fmt.Errorf("maintenance intervals cannot be negative")
+	}
+
+	db, err := openBoltDB(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &traceRepositoryBolt{
+		jaegerExporter: jaegerExporter,
+		config:         config,
+		now:            time.Now,
+		db:             db,
+		open:           openBoltDB,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	go tr.maintain()
+
+	return tr, nil
+}
+
+// openBoltDB opens the database file and creates the buckets
+func openBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Save saves a trace, merging its spans into the stored trace with the
+// same ID
+func (tr *traceRepositoryBolt) Save(ctx context.Context, trace *domain.Trace) error {
+	return tr.save(ctx, trace, true)
+}
+
+// Replace saves a trace, replacing any stored trace with the same ID
+func (tr *traceRepositoryBolt) Replace(ctx context.Context, trace *domain.Trace) error {
+	return tr.save(ctx, trace, false)
+}
+
+// save stores a trace in place of the stored trace with the same ID,
+// keeping the stored spans it does not replace when merge is set
+func (tr *traceRepositoryBolt) save(ctx context.Context, trace *domain.Trace, merge bool) error {
+	// Validate trace
+	if err := tr.validateTrace(trace); err != nil {
+		return fmt.Errorf("invalid trace: %w", err)
+	}
+
+	stored := *trace
+	record := boltRecord{Trace: &stored}
+	if tr.config.TTL > 0 {
+		record.ExpiresAt = tr.now().Add(tr.config.TTL).UnixNano()
+	}
+
+	tr.mu.RLock()
+	err := tr.db.Update(func(tx *bolt.Tx) error {
+		if merge {
+			existing, err := tr.loadRecord(tx, trace.ID)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				stored.Spans = domain.MergeSpans(existing.Trace.Spans, trace.Spans)
+			}
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal trace: %w", err)
+		}
+
+		if err := deleteBoltTrace(tx, trace.ID); err != nil {
+			return err
+		}
+		if err := tx.Bucket(boltTracesBucket).Put([]byte(trace.ID), data); err != nil {
+			return err
+		}
+		return indexBoltTrace(tx, &record)
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to save trace: %w", err)
+	}
+
+	// Export to Jaeger
+	if tr.jaegerExporter != nil {
+		if err := tr.jaegerExporter.ExportTrace(ctx, trace); err != nil {
+			// Log error but don't fail the operation
+			fmt.Printf("Failed to export trace to Jaeger: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// FindByID finds a trace by ID
+func (tr *traceRepositoryBolt) FindByID(ctx context.Context, id domain.TraceID) (*domain.Trace, error) {
+	if id == "" {
+		return nil, fmt.Errorf("trace ID is required")
+	}
+
+	var trace *domain.Trace
+	err := tr.view(func(tx *bolt.Tx) error {
+		record, err := tr.loadRecord(tx, id)
+		if err != nil {
+			return err
+		}
+		if record != nil {
+			trace = record.Trace
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", err)
+	}
+	if trace == nil {
+		return nil, domain.ErrTraceNotFound
+	}
+
+	return trace, nil
+}
+
+// Search searches for traces based on criteria
+func (tr *traceRepositoryBolt) Search(ctx context.Context, criteria *domain.SearchCriteria) ([]*domain.Trace, error) {
+	// Validate criteria
+	if err := validateMemorySearchCriteria(criteria); err != nil {
+		return nil, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	matches, err := tr.match(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search traces: %w", err)
+	}
+
+	domain.SortTraces(matches, criteria.SortField(), criteria.SortDirection())
+	return paginateTraces(matches, criteria.Limit, criteria.Offset), nil
+}
+
+// Count returns the number of traces matching the criteria, ignoring pagination
+func (tr *traceRepositoryBolt) Count(ctx context.Context, criteria *domain.SearchCriteria) (int64, error) {
+	if err := validateMemorySearchCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	unpaged := *criteria
+	unpaged.Cursor = nil
+
+	matches, err := tr.match(&unpaged)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	return int64(len(matches)), nil
+}
+
+// QueryTraces returns a page of the traces matching a trace query, newest first
+func (tr *traceRepositoryBolt) QueryTraces(ctx context.Context, query *domain.TraceQuery, page domain.TracePage) ([]*domain.Trace, error) {
+	matches, err := tr.queryMatches(query, page.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	domain.SortTraces(matches, domain.SortByStartTime, domain.SortDescending)
+	return paginateTraces(matches, page.Limit, page.Offset), nil
+}
+
+// CountQuery counts the traces matching a trace query
+func (tr *traceRepositoryBolt) CountQuery(ctx context.Context, query *domain.TraceQuery) (int64, error) {
+	matches, err := tr.queryMatches(query, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(matches)), nil
+}
+
+// queryMatches returns the live traces matching a trace query that sort
+// after the cursor, if any
+func (tr *traceRepositoryBolt) queryMatches(query *domain.TraceQuery, cursor *domain.TraceCursor) ([]*domain.Trace, error) {
+	if query == nil || query.Root == nil {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	var matches []*domain.Trace
+	err := tr.view(func(tx *bolt.Tx) error {
+		now := tr.now().UnixNano()
+		return tx.Bucket(boltTracesBucket).ForEach(func(_, data []byte) error {
+			var record boltRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fmt.Errorf("failed to unmarshal trace: %w", err)
+			}
+			if record.expired(now) || (cursor != nil && !cursor.IsAfter(record.Trace)) || !query.Matches(record.Trace) {
+				return nil
+			}
+			matches = append(matches, record.Trace)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	return matches, nil
+}
+
+// PurgeTraces deletes up to criteria.Limit expired traces, oldest first
+func (tr *traceRepositoryBolt) PurgeTraces(ctx context.Context, criteria domain.PurgeCriteria) (int64, error) {
+	if err := validatePurgeCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid purge criteria: %w", err)
+	}
+
+	var deleted int64
+	tr.mu.RLock()
+	err := tr.db.Update(func(tx *bolt.Tx) error {
+		ids, err := purgeableBoltTraces(tx, criteria)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := deleteBoltTrace(tx, id); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(ids))
+		return nil
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge traces: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// PurgeStats counts the traces selected by the purge criteria
+func (tr *traceRepositoryBolt) PurgeStats(ctx context.Context, criteria domain.PurgeCriteria) (domain.PurgeStats, error) {
+	if err := validatePurgeCriteria(criteria); err != nil {
+		return domain.PurgeStats{}, fmt.Errorf("invalid purge criteria: %w", err)
+	}
+
+	var stats domain.PurgeStats
+	err := tr.view(func(tx *bolt.Tx) error {
+		criteria.Limit = 0
+		ids, err := purgeableBoltTraces(tx, criteria)
+		if err != nil {
+			return err
+		}
+		stats.Count = int64(len(ids))
+		if len(ids) > 0 {
+			record, err := tr.loadRecord(tx, ids[0])
+			if err != nil {
+				return err
+			}
+			if record != nil {
+				stats.Oldest = record.Trace.StartTime
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.PurgeStats{}, fmt.Errorf("failed to count purgeable traces: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SaveDependencies adds the links to the stored links of the same window,
+// caller and callee. Dependency links do not expire with traces.
+func (tr *traceRepositoryBolt) SaveDependencies(ctx context.Context, links []*domain.DependencyLink) error {
+	tr.mu.RLock()
+	err := tr.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltDependenciesBucket)
+		for _, link := range links {
+			key := boltIndexKey(boltTimeKey(link.WindowStart.UnixNano()), boltKey(string(link.Caller), string(link.Callee)))
+
+			stored := domain.NewDependencyLink(link.WindowStart, link.Caller, link.Callee)
+			if data := bucket.Get(key); data != nil {
+				if err := json.Unmarshal(data, stored); err != nil {
+					return fmt.Errorf("failed to unmarshal dependency link: %w", err)
+				}
+			}
+			stored.Merge(link)
+
+			data, err := json.Marshal(stored)
+			if err != nil {
+				return fmt.Errorf("failed to marshal dependency link: %w", err)
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to save dependencies: %w", err)
+	}
+
+	return nil
+}
+
+// FindDependencies returns the stored links whose window starts within [start, end)
+func (tr *traceRepositoryBolt) FindDependencies(ctx context.Context, start, end time.Time) ([]*domain.DependencyLink, error) {
+	links := []*domain.DependencyLink{}
+	endKey := boltTimeKey(end.UnixNano())
+	err := tr.view(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltDependenciesBucket).Cursor()
+		for key, data := cursor.Seek(boltTimeKey(start.UnixNano())); key != nil && bytes.Compare(key[:8], endKey) < 0; key, data = cursor.Next() {
+			var link domain.DependencyLink
+			if err := json.Unmarshal(data, &link); err != nil {
+				return fmt.Errorf("failed to unmarshal dependency link: %w", err)
+			}
+			links = append(links, &link)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dependencies: %w", err)
+	}
+
+	return links, nil
+}
+
+// SaveSLO creates or replaces the SLO with the same ID
+func (tr *traceRepositoryBolt) SaveSLO(ctx context.Context, slo *domain.SLO) error {
+	data, err := json.Marshal(slo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slo: %w", err)
+	}
+
+	tr.mu.RLock()
+	err = tr.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSLOsBucket).Put([]byte(slo.ID), data)
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to save slo: %w", err)
+	}
+
+	return nil
+}
+
+// FindSLO returns the SLO with the given ID
+func (tr *traceRepositoryBolt) FindSLO(ctx context.Context, id string) (*domain.SLO, error) {
+	var slo *domain.SLO
+	err := tr.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltSLOsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		slo = &domain.SLO{}
+		return json.Unmarshal(data, slo)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find slo: %w", err)
+	}
+	if slo == nil {
+		return nil, domain.ErrSLONotFound
+	}
+
+	return slo, nil
+}
+
+// ListSLOs returns every SLO ordered by name
+func (tr *traceRepositoryBolt) ListSLOs(ctx context.Context) ([]*domain.SLO, error) {
+	slos := []*domain.SLO{}
+	err := tr.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSLOsBucket).ForEach(func(_, data []byte) error {
+			var slo domain.SLO
+			if err := json.Unmarshal(data, &slo); err != nil {
+				return fmt.Errorf("failed to unmarshal slo: %w", err)
+			}
+			slos = append(slos, &slo)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slos: %w", err)
+	}
+
+	domain.SortSLOs(slos)
+	return slos, nil
+}
+
+// DeleteSLO removes the SLO with the given ID
+func (tr *traceRepositoryBolt) DeleteSLO(ctx context.Context, id string) error {
+	found := false
+	tr.mu.RLock()
+	err := tr.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltSLOsBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return nil
+		}
+		found = true
+		if err := deleteBoltSLOBuckets(tx, id, time.Time{}); err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(id))
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to delete slo: %w", err)
+	}
+	if !found {
+		return domain.ErrSLONotFound
+	}
+
+	return nil
+}
+
+// AddSLOBuckets adds the counts of the buckets to the stored buckets of the
+// SLO with the same start
+func (tr *traceRepositoryBolt) AddSLOBuckets(ctx context.Context, id string, buckets []domain.SLOBucket) error {
+	tr.mu.RLock()
+	err := tr.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltSLOBucketsBucket)
+		for _, counts := range buckets {
+			key := boltIndexKey(boltKey(id), boltTimeKey(counts.Start.UnixNano()))
+
+			stored := domain.SLOBucket{Start: counts.Start.UTC()}
+			if data := bucket.Get(key); data != nil {
+				if err := json.Unmarshal(data, &stored); err != nil {
+					return fmt.Errorf("failed to unmarshal slo bucket: %w", err)
+				}
+			}
+			stored.Total += counts.Total
+			stored.Good += counts.Good
+
+			data, err := json.Marshal(stored)
+			if err != nil {
+				return fmt.Errorf("failed to marshal slo bucket: %w", err)
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to add slo buckets: %w", err)
+	}
+
+	return nil
+}
+
+// FindSLOBuckets returns the stored buckets of the SLO starting at or after
+// since, ordered by start
+func (tr *traceRepositoryBolt) FindSLOBuckets(ctx context.Context, id string, since time.Time) ([]domain.SLOBucket, error) {
+	buckets := []domain.SLOBucket{}
+	prefix := boltKey(id)
+	err := tr.view(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltSLOBucketsBucket).Cursor()
+		for key, data := cursor.Seek(boltIndexKey(prefix, boltTimeKey(since.UnixNano()))); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			var bucket domain.SLOBucket
+			if err := json.Unmarshal(data, &bucket); err != nil {
+				return fmt.Errorf("failed to unmarshal slo bucket: %w", err)
+			}
+			buckets = append(buckets, bucket)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find slo buckets: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// DeleteSLOBuckets removes the stored buckets of the SLO starting before
+// the given time, or every bucket for the zero time
+func (tr *traceRepositoryBolt) DeleteSLOBuckets(ctx context.Context, id string, before time.Time) error {
+	tr.mu.RLock()
+	err := tr.db.Update(func(tx *bolt.Tx) error {
+		return deleteBoltSLOBuckets(tx, id, before)
+	})
+	tr.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to delete slo buckets: %w", err)
+	}
+
+	return nil
+}
+
+// GetServices returns all available services
+func (tr *traceRepositoryBolt) GetServices(ctx context.Context) ([]domain.ServiceName, error) {
+	services := []domain.ServiceName{}
+	err := tr.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltServiceOperationsBucket).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			service := domain.ServiceName(k[:bytes.IndexByte(k, boltKeySeparator)])
+			if len(services) == 0 || services[len(services)-1] != service {
+				services = append(services, service)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service
+func (tr *traceRepositoryBolt) GetOperations(ctx context.Context, service domain.ServiceName) ([]domain.OperationName, error) {
+	if service == "" {
+		return nil, fmt.Errorf("service name is required")
+	}
+
+	operations := []domain.OperationName{}
+	err := tr.view(func(tx *bolt.Tx) error {
+		prefix := boltKey(string(service))
+		c := tx.Bucket(boltServiceOperationsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			operations = append(operations, domain.OperationName(k[len(prefix):]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations: %w", err)
+	}
+
+	return operations, nil
+}
+
+// DeleteExpired deletes traces whose TTL has passed and returns how many were deleted
+func (tr *traceRepositoryBolt) DeleteExpired(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		batch := 0
+		tr.mu.RLock()
+		err := tr.db.Update(func(tx *bolt.Tx) error {
+			expiry := tx.Bucket(boltExpiryBucket)
+			limit := boltTimeKey(tr.now().UnixNano())
+
+			var keys [][]byte
+			c := expiry.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k[:8], limit) <= 0 && len(keys) < boltExpiryBatchSize; k, _ = c.Next() {
+				keys = append(keys, append([]byte{}, k...))
+			}
+
+			for _, key := range keys {
+				if err := deleteBoltTrace(tx, domain.TraceID(key[8:])); err != nil {
+					return err
+				}
+				// Drop the entry even if the trace was already gone
+				if err := expiry.Delete(key); err != nil {
+					return err
+				}
+			}
+			batch = len(keys)
+			return nil
+		})
+		tr.mu.RUnlock()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete expired traces: %w", err)
+		}
+
+		deleted += batch
+		if batch < boltExpiryBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// Compact rewrites the database into a new file to return the space freed
+// by deleted traces to the filesystem. Writes are blocked while it runs.
+func (tr *traceRepositoryBolt) Compact() error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	compactPath := tr.config.Path + ".compact"
+	os.Remove(compactPath)
+
+	dst, err := bolt.Open(compactPath, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	if err := bolt.Compact(dst, tr.db, boltCompactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to close compacted database: %w", err)
+	}
+
+	// Keep a link to the original file until the compacted copy opens, so
+	// the repository can fall back to it
+	backupPath := tr.config.Path + ".orig"
+	os.Remove(backupPath)
+	if err := os.Link(tr.config.Path, backupPath); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to keep original database: %w", err)
+	}
+	defer os.Remove(backupPath)
+
+	if err := tr.db.Close(); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	renameErr := os.Rename(compactPath, tr.config.Path)
+	if renameErr != nil {
+		os.Remove(compactPath)
+	}
+
+	// Reopen whichever file is now in place so the repository stays usable,
+	// falling back to the original when the compacted copy cannot be opened
+	db, err := tr.open(tr.config.Path)
+	if err != nil && renameErr == nil {
+		if restoreErr := os.Rename(backupPath, tr.config.Path); restoreErr != nil {
+			return fmt.Errorf("failed to open compacted database: %w; failed to restore original: %v", err, restoreErr)
+		}
+		renameErr = fmt.Errorf("failed to open compacted database: %w", err)
+		db, err = tr.open(tr.config.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reopen database after compaction: %w", err)
+	}
+	tr.db = db
+
+	if renameErr != nil {
+		return fmt.Errorf("failed to replace database with compacted copy: %w", renameErr)
+	}
+
+	return nil
+}
+
+// Close stops background maintenance and closes the database. Later calls
+// return the result of the first one.
+func (tr *traceRepositoryBolt) Close() error {
+	tr.closeOnce.Do(func() {
+		close(tr.stop)
+		<-tr.done
+
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+
+		tr.closeErr = tr.db.Close()
+	})
+	return tr.closeErr
+}
+
+// maintain runs the expiry sweep and compaction until Close is called
+func (tr *traceRepositoryBolt) maintain() {
+	defer close(tr.done)
+
+	var expiry, compaction <-chan time.Time
+	if tr.config.TTL > 0 && tr.config.ExpiryInterval > 0 {
+		ticker := time.NewTicker(tr.config.ExpiryInterval)
+		defer ticker.Stop()
+		expiry = ticker.C
+	}
+	if tr.config.CompactionInterval > 0 {
+		ticker := time.NewTicker(tr.config.CompactionInterval)
+		defer ticker.Stop()
+		compaction = ticker.C
+	}
+
+	for {
+		select {
+		case <-tr.stop:
+			return
+		case <-expiry:
+			if _, err := tr.DeleteExpired(context.Background()); err != nil {
+				fmt.Printf("Failed to delete expired traces: %v\n", err)
+			}
+		case <-compaction:
+			if err := tr.Compact(); err != nil {
+				fmt.Printf("Failed to compact trace database: %v\n", err)
+			}
+		}
+	}
+}
+
+// view runs a read-only transaction against the current database
+func (tr *traceRepositoryBolt) view(fn func(tx *bolt.Tx) error) error {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	return tr.db.View(fn)
+}
+
+// loadRecord reads a stored trace, returning nil when it is missing or expired
+func (tr *traceRepositoryBolt) loadRecord(tx *bolt.Tx, id domain.TraceID) (*boltRecord, error) {
+	data := tx.Bucket(boltTracesBucket).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+
+	var record boltRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trace %s: %w", id, err)
+	}
+	if record.expired(tr.now().UnixNano()) {
+		return nil, nil
+	}
+
+	return &record, nil
+}
+
+// match returns the stored traces matching the criteria and cursor. The
+// most selective index is range-scanned by the criteria time window and
+// every candidate is checked against the full criteria.
+func (tr *traceRepositoryBolt) match(criteria *domain.SearchCriteria) ([]*domain.Trace, error) {
+	bucket, prefix := boltIndexFor(criteria)
+
+	from := prefix
+	if criteria.StartTime != nil {
+		from = append(append([]byte{}, prefix...), boltTimeKey(criteria.StartTime.UnixNano())...)
+	}
+	var until []byte
+	if criteria.EndTime != nil {
+		until = boltTimeKey(criteria.EndTime.UnixNano())
+	}
+
+	var matches []*domain.Trace
+	err := tr.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, _ := c.Seek(from); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			entry := k[len(prefix):]
+			if until != nil && bytes.Compare(entry[:8], until) > 0 {
+				break
+			}
+
+			record, err := tr.loadRecord(tx, domain.TraceID(entry[8:]))
+			if err != nil {
+				return err
+			}
+			if record == nil || !criteria.Matches(record.Trace) {
+				continue
+			}
+			if criteria.Cursor != nil && !criteria.Cursor.IsAfter(record.Trace) {
+				continue
+			}
+			matches = append(matches, record.Trace)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// purgeableBoltTraces returns the IDs of traces selected by the purge
+// criteria, oldest first, scanning the time index up to the cutoff
+func purgeableBoltTraces(tx *bolt.Tx, criteria domain.PurgeCriteria) ([]domain.TraceID, error) {
+	var ids []domain.TraceID
+	before := boltTimeKey(criteria.Before.UnixNano())
+	traces := tx.Bucket(boltTracesBucket)
+
+	c := tx.Bucket(boltTimeIndexBucket).Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k[:8], before) < 0; k, _ = c.Next() {
+		if criteria.Limit > 0 && len(ids) >= criteria.Limit {
+			break
+		}
+
+		id := domain.TraceID(k[8:])
+		data := traces.Get([]byte(id))
+		if data == nil {
+			continue
+		}
+
+		var record boltRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trace %s: %w", id, err)
+		}
+		if criteria.Matches(record.Trace) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// expired reports whether the record's TTL has passed at now (Unix nanoseconds)
+func (r *boltRecord) expired(now int64) bool {
+	return r.ExpiresAt > 0 && r.ExpiresAt <= now
+}
+
+// validateTrace validates a trace before saving
+func (tr *traceRepositoryBolt) validateTrace(trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("trace cannot be nil")
+	}
+	if trace.ID == "" {
+		return fmt.Errorf("trace ID is required")
+	}
+	if trace.Service == "" {
+		return fmt.Errorf("service name is required")
+	}
+	if trace.Operation == "" {
+		return fmt.Errorf("operation name is required")
+	}
+	if trace.StartTime.IsZero() {
+		return fmt.Errorf("start time is required")
+	}
+	if trace.EndTime.IsZero() {
+		return fmt.Errorf("end time is required")
+	}
+	if trace.StartTime.After(trace.EndTime) {
+		return fmt.Errorf("start time cannot be after end time")
+	}
+	return nil
+}
+
+// boltIndexFor picks the index bucket and key prefix to scan for the
+// criteria, preferring tags, then operation, then service, then time
+func boltIndexFor(criteria *domain.SearchCriteria) ([]byte, []byte) {
+	if len(criteria.Tags) > 0 {
+		keys := make([]string, 0, len(criteria.Tags))
+		for key := range criteria.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return boltTagIndexBucket, boltKey(keys[0], criteria.Tags[keys[0]])
+	}
+	if criteria.Operation != nil {
+		return boltOperationIndexBucket, boltKey(string(*criteria.Operation))
+	}
+	if criteria.Service != nil {
+		return boltServiceIndexBucket, boltKey(string(*criteria.Service))
+	}
+	return boltTimeIndexBucket, nil
+}
+
+// indexBoltTrace writes the index entries of a stored trace
+func indexBoltTrace(tx *bolt.Tx, record *boltRecord) error {
+	trace := record.Trace
+	entry := boltIndexEntry(trace)
+
+	if err := tx.Bucket(boltTimeIndexBucket).Put(entry, nil); err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltServiceIndexBucket).Put(boltIndexKey(boltKey(string(trace.Service)), entry), nil); err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltOperationIndexBucket).Put(boltIndexKey(boltKey(string(trace.Operation)), entry), nil); err != nil {
+		return err
+	}
+	for key, value := range trace.Tags {
+		if err := tx.Bucket(boltTagIndexBucket).Put(boltIndexKey(boltKey(key, value), entry), nil); err != nil {
+			return err
+		}
+	}
+	if record.ExpiresAt > 0 {
+		if err := tx.Bucket(boltExpiryBucket).Put(boltIndexKey(boltTimeKey(record.ExpiresAt), []byte(trace.ID)), nil); err != nil {
+			return err
+		}
+	}
+
+	return adjustBoltOperationCount(tx, trace, 1)
+}
+
+// deleteBoltTrace removes a stored trace and its index entries, if present
+func deleteBoltTrace(tx *bolt.Tx, id domain.TraceID) error {
+	traces := tx.Bucket(boltTracesBucket)
+	data := traces.Get([]byte(id))
+	if data == nil {
+		return nil
+	}
+
+	var record boltRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("failed to unmarshal trace %s: %w", id, err)
+	}
+	trace := record.Trace
+	entry := boltIndexEntry(trace)
+
+	if err := tx.Bucket(boltTimeIndexBucket).Delete(entry); err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltServiceIndexBucket).Delete(boltIndexKey(boltKey(string(trace.Service)), entry)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltOperationIndexBucket).Delete(boltIndexKey(boltKey(string(trace.Operation)), entry)); err != nil {
+		return err
+	}
+	for key, value := range trace.Tags {
+		if err := tx.Bucket(boltTagIndexBucket).Delete(boltIndexKey(boltKey(key, value), entry)); err != nil {
+			return err
+		}
+	}
+	if record.ExpiresAt > 0 {
+		if err := tx.Bucket(boltExpiryBucket).Delete(boltIndexKey(boltTimeKey(record.ExpiresAt), []byte(id))); err != nil {
+			return err
+		}
+	}
+	if err := adjustBoltOperationCount(tx, trace, -1); err != nil {
+		return err
+	}
+
+	return traces.Delete([]byte(id))
+}
+
+// deleteBoltSLOBuckets removes the buckets of an SLO starting before the
+// given time, or every bucket of the SLO for the zero time
+func deleteBoltSLOBuckets(tx *bolt.Tx, id string, before time.Time) error {
+	prefix := boltKey(id)
+	end := []byte(nil)
+	if !before.IsZero() {
+		end = boltIndexKey(prefix, boltTimeKey(before.UnixNano()))
+	}
+
+	cursor := tx.Bucket(boltSLOBucketsBucket).Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Seek(prefix) {
+		if end != nil && bytes.Compare(key, end) >= 0 {
+			break
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjustBoltOperationCount updates the number of traces stored for the
+// trace's service and operation, dropping the entry when it reaches zero
+func adjustBoltOperationCount(tx *bolt.Tx, trace *domain.Trace, delta int64) error {
+	bucket := tx.Bucket(boltServiceOperationsBucket)
+	key := append(boltKey(string(trace.Service)), trace.Operation...)
+
+	var count int64
+	if data := bucket.Get(key); len(data) == 8 {
+		count = int64(binary.BigEndian.Uint64(data))
+	}
+	count += delta
+
+	if count <= 0 {
+		return bucket.Delete(key)
+	}
+	return bucket.Put(key, binary.BigEndian.AppendUint64(nil, uint64(count)))
+}
+
+// boltIndexEntry returns the time-ordered suffix shared by all index keys of a trace
+func boltIndexEntry(trace *domain.Trace) []byte {
+	return boltIndexKey(boltTimeKey(trace.StartTime.UnixNano()), []byte(trace.ID))
+}
+
+// boltIndexKey concatenates a prefix and an entry into a new slice
+func boltIndexKey(prefix, entry []byte) []byte {
+	key := make([]byte, 0, len(prefix)+len(entry))
+	return append(append(key, prefix...), entry...)
+}
+
+// boltKey joins string components into a key prefix, each followed by the separator
+func boltKey(parts ...string) []byte {
+	var key []byte
+	for _, part := range parts {
+		key = append(key, part...)
+		key = append(key, boltKeySeparator)
+	}
+	return key
+}
+
+// boltTimeKey encodes Unix nanoseconds so that byte order matches time order
+func boltTimeKey(nanos int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(nanos)^(1<<63))
+}
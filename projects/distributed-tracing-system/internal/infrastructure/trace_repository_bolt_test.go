@@ -0,0 +1,316 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestBoltRepository(t *testing.T, config BoltRepositoryConfig) *traceRepositoryBolt {
+	if config.Path == "" {
+		config.Path = filepath.Join(t.TempDir(), "traces.db")
+	}
+
+	repo, err := NewTraceRepositoryBolt(config, &MockJaegerExporter{})
+	require.NoError(t, err)
+
+	boltRepo := repo.(*traceRepositoryBolt)
+	t.Cleanup(func() { boltRepo.Close() })
+	return boltRepo
+}
+
+func countBoltKeys(t *testing.T, repo *traceRepositoryBolt, bucket []byte) int {
+	count := 0
+	require.NoError(t, repo.view(func(tx *bolt.Tx) error {
+		count = tx.Bucket(bucket).Stats().KeyN
+		return nil
+	}))
+	return count
+}
+
+func TestTraceRepositoryBolt_Contract(t *testing.T) {
+	repotest.RunTraceRepositoryContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestBoltRepository(t, BoltRepositoryConfig{TTL: time.Hour})
+	})
+}
+
+func TestTraceRepositoryBolt_PurgerContract(t *testing.T) {
+	repotest.RunTracePurgerContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestBoltRepository(t, BoltRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryBolt_ReplacerContract(t *testing.T) {
+	repotest.RunTraceReplacerContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestBoltRepository(t, BoltRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryBolt_DependencyStoreContract(t *testing.T) {
+	repotest.RunDependencyStoreContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestBoltRepository(t, BoltRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryBolt_SLOStoreContract(t *testing.T) {
+	repotest.RunSLOStoreContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestBoltRepository(t, BoltRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryBolt_SaveAndFind(t *testing.T) {
+	repo := newTestBoltRepository(t, BoltRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	trace := newMemoryTestTrace("trace1", "checkout", "POST /checkout", start, time.Second)
+	trace.Tags = map[string]string{"env": "prod"}
+	require.NoError(t, repo.Save(ctx, trace))
+
+	found, err := repo.FindByID(ctx, "trace1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ServiceName("checkout"), found.Service)
+	assert.Equal(t, "prod", found.Tags["env"])
+	assert.True(t, start.Equal(found.StartTime))
+	require.Len(t, found.Spans, 1)
+	assert.Equal(t, time.Second, found.Spans[0].Duration)
+
+	_, err = repo.FindByID(ctx, "missing")
+	assert.Error(t, err)
+
+	// Re-saving updates the trace and its index entries
+	updated := newMemoryTestTrace("trace1", "checkout", "GET /cart", start.Add(time.Minute), time.Second)
+	require.NoError(t, repo.Save(ctx, updated))
+
+	operations, err := repo.GetOperations(ctx, "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.OperationName{"GET /cart"}, operations)
+
+	assert.Equal(t, 1, countBoltKeys(t, repo, boltTimeIndexBucket))
+	assert.Equal(t, 1, countBoltKeys(t, repo, boltOperationIndexBucket))
+	assert.Equal(t, 0, countBoltKeys(t, repo, boltTagIndexBucket))
+}
+
+func TestTraceRepositoryBolt_Search(t *testing.T) {
+	repo := newTestBoltRepository(t, BoltRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	slow := newMemoryTestTrace("slow", "checkout", "POST /checkout", start, 2*time.Second)
+	slow.Tags = map[string]string{"env": "prod"}
+	failed := newMemoryTestTrace("failed", "checkout", "POST /checkout", start.Add(time.Minute), 100*time.Millisecond)
+	failed.Status = domain.TraceStatusError
+	failed.Tags = map[string]string{"env": "staging"}
+	other := newMemoryTestTrace("other", "inventory", "GET /stock", start.Add(2*time.Minute), time.Second)
+
+	for _, trace := range []*domain.Trace{slow, failed, other} {
+		require.NoError(t, repo.Save(ctx, trace))
+	}
+
+	service := domain.ServiceName("checkout")
+	operation := domain.OperationName("GET /stock")
+	status := domain.TraceStatusError
+	from := start.Add(30 * time.Second)
+	until := start.Add(90 * time.Second)
+
+	tests := []struct {
+		name     string
+		criteria *domain.SearchCriteria
+		expected []domain.TraceID
+	}{
+		{
+			name:     "all newest first",
+			criteria: &domain.SearchCriteria{},
+			expected: []domain.TraceID{"other", "failed", "slow"},
+		},
+		{
+			name:     "by service",
+			criteria: &domain.SearchCriteria{Service: &service},
+			expected: []domain.TraceID{"failed", "slow"},
+		},
+		{
+			name:     "by operation",
+			criteria: &domain.SearchCriteria{Operation: &operation},
+			expected: []domain.TraceID{"other"},
+		},
+		{
+			name:     "by tag",
+			criteria: &domain.SearchCriteria{Tags: map[string]string{"env": "prod"}},
+			expected: []domain.TraceID{"slow"},
+		},
+		{
+			name:     "by time window",
+			criteria: &domain.SearchCriteria{StartTime: &from, EndTime: &until},
+			expected: []domain.TraceID{"failed"},
+		},
+		{
+			name:     "by service and status",
+			criteria: &domain.SearchCriteria{Service: &service, Status: &status},
+			expected: []domain.TraceID{"failed"},
+		},
+		{
+			name:     "by duration ascending",
+			criteria: &domain.SearchCriteria{SortBy: domain.SortByDuration, SortOrder: domain.SortAscending, Limit: 2},
+			expected: []domain.TraceID{"failed", "other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := repo.Search(ctx, tt.criteria)
+			require.NoError(t, err)
+
+			ids := make([]domain.TraceID, len(traces))
+			for i, trace := range traces {
+				ids[i] = trace.ID
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+
+	count, err := repo.Count(ctx, &domain.SearchCriteria{Service: &service, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	services, err := repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServiceName{"checkout", "inventory"}, services)
+}
+
+func TestTraceRepositoryBolt_Expiry(t *testing.T) {
+	repo := newTestBoltRepository(t, BoltRepositoryConfig{TTL: time.Hour})
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("old", "checkout", "op", now, time.Second)))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("new", "payments", "op", now, time.Second)))
+
+	// Expired traces are hidden before the sweep removes them
+	now = now.Add(45 * time.Minute)
+	_, err := repo.FindByID(ctx, "old")
+	assert.Error(t, err)
+
+	traces, err := repo.Search(ctx, &domain.SearchCriteria{})
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, domain.TraceID("new"), traces[0].ID)
+
+	deleted, err := repo.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	services, err := repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServiceName{"payments"}, services)
+	assert.Equal(t, 1, countBoltKeys(t, repo, boltExpiryBucket))
+	assert.Equal(t, 1, countBoltKeys(t, repo, boltServiceIndexBucket))
+}
+
+func TestTraceRepositoryBolt_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.db")
+	repo := newTestBoltRepository(t, BoltRepositoryConfig{Path: path, TTL: time.Hour})
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	for i := 0; i < 500; i++ {
+		trace := newMemoryTestTrace(fmt.Sprintf("trace%d", i), "checkout", "op", now, time.Second)
+		trace.Tags = map[string]string{"payload": fmt.Sprintf("%0512d", i)}
+		require.NoError(t, repo.Save(ctx, trace))
+	}
+	now = now.Add(45 * time.Minute)
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("kept", "checkout", "op", now, time.Second)))
+
+	now = now.Add(45 * time.Minute)
+	deleted, err := repo.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 500, deleted)
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Compact())
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	// The repository keeps working on the compacted file
+	found, err := repo.FindByID(ctx, "kept")
+	require.NoError(t, err)
+	assert.Equal(t, domain.TraceID("kept"), found.ID)
+}
+
+func TestTraceRepositoryBolt_CompactFallsBackToOriginal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.db")
+	repo := newTestBoltRepository(t, BoltRepositoryConfig{Path: path})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("trace1", "checkout", "op", start, time.Second)))
+
+	// The compacted copy cannot be opened
+	failed := false
+	repo.open = func(path string) (*bolt.DB, error) {
+		if !failed {
+			failed = true
+			return nil, fmt.Errorf("disk error")
+		}
+		return openBoltDB(path)
+	}
+
+	err := repo.Compact()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk error")
+
+	// The repository keeps working on the original file
+	found, err := repo.FindByID(ctx, "trace1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ServiceName("checkout"), found.Service)
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("trace2", "checkout", "op", start, time.Second)))
+
+	_, err = os.Stat(path + ".orig")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + ".compact")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestTraceRepositoryBolt_CloseTwice(t *testing.T) {
+	repo := newTestBoltRepository(t, BoltRepositoryConfig{})
+
+	require.NoError(t, repo.Close())
+	assert.NoError(t, repo.Close())
+}
+
+func TestTraceRepositoryBolt_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.db")
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	repo, err := NewTraceRepositoryBolt(BoltRepositoryConfig{Path: path}, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("trace1", "checkout", "op", start, time.Second)))
+	require.NoError(t, repo.(*traceRepositoryBolt).Close())
+
+	reopened := newTestBoltRepository(t, BoltRepositoryConfig{Path: path})
+	found, err := reopened.FindByID(ctx, "trace1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ServiceName("checkout"), found.Service)
+}
+
+func TestBoltTimeKey_Ordering(t *testing.T) {
+	times := []int64{-5, -1, 0, 1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()}
+	for i := 1; i < len(times); i++ {
+		assert.Less(t, string(boltTimeKey(times[i-1])), string(boltTimeKey(times[i])))
+	}
+}
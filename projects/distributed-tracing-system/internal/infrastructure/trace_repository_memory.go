@@ -0,0 +1,755 @@
+package infrastructure
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+const (
+	// Approximate fixed cost of a stored trace and span, on top of their strings
+	memoryTraceOverheadBytes = 256
+	memorySpanOverheadBytes  = 192
+)
+
+// MemoryRepositoryConfig holds configuration for the in-memory trace repository
+type MemoryRepositoryConfig struct {
+	// MaxTraces is the maximum number of stored traces; zero means unbounded
+	MaxTraces int
+	// MaxBytes is the approximate memory budget for stored traces; zero means unbounded
+	MaxBytes int64
+}
+
+// memoryEntry is a stored trace with its bookkeeping
+type memoryEntry struct {
+	trace   *domain.Trace
+	size    int64
+	element *list.Element
+}
+
+// traceRepositoryMemory implements the TraceRepository interface in memory.
+// Traces are evicted oldest-saved first, like a ring buffer, once the trace
+// count or byte budget is exceeded.
+type traceRepositoryMemory struct {
+	jaegerExporter domain.JaegerExporter
+	config         MemoryRepositoryConfig
+
+	mu                sync.RWMutex
+	traces            map[domain.TraceID]*memoryEntry
+	order             *list.List
+	bytes             int64
+	byService         map[domain.ServiceName]map[domain.TraceID]struct{}
+	byOperation       map[domain.OperationName]map[domain.TraceID]struct{}
+	byTag             map[string]map[string]map[domain.TraceID]struct{}
+	serviceOperations map[domain.ServiceName]map[domain.OperationName]int
+	dependencies      map[dependencyLinkKey]*domain.DependencyLink
+	slos              map[string]*domain.SLO
+	// sloBuckets holds the buckets of each SLO by start in Unix nanoseconds
+	sloBuckets map[string]map[int64]domain.SLOBucket
+}
+
+// dependencyLinkKey identifies a stored dependency link
+type dependencyLinkKey struct {
+	window int64
+	caller domain.ServiceName
+	callee domain.ServiceName
+}
+
+// NewTraceRepositoryMemory creates a new in-memory trace repository. The
+// Jaeger exporter is optional.
+func NewTraceRepositoryMemory(config MemoryRepositoryConfig, jaegerExporter domain.JaegerExporter) (domain.TraceRepository, error) {
+	if config.MaxTraces < 0 {
+		return nil, fmt.Errorf("max traces cannot be negative")
+	}
+	if config.MaxBytes < 0 {
+		return nil, fmt.Errorf("max bytes cannot be negative")
+	}
+
+	return &traceRepositoryMemory{
+		jaegerExporter:    jaegerExporter,
+		config:            config,
+		traces:            make(map[domain.TraceID]*memoryEntry),
+		order:             list.New(),
+		byService:         make(map[domain.ServiceName]map[domain.TraceID]struct{}),
+		byOperation:       make(map[domain.OperationName]map[domain.TraceID]struct{}),
+		byTag:             make(map[string]map[string]map[domain.TraceID]struct{}),
+		serviceOperations: make(map[domain.ServiceName]map[domain.OperationName]int),
+		dependencies:      make(map[dependencyLinkKey]*domain.DependencyLink),
+		slos:              make(map[string]*domain.SLO),
+		sloBuckets:        make(map[string]map[int64]domain.SLOBucket),
+	}, nil
+}
+
+// Save saves a trace, merging its spans into the stored trace with the
+// same ID
+func (tr *traceRepositoryMemory) Save(ctx context.Context, trace *domain.Trace) error {
+	return tr.save(ctx, trace, true)
+}
+
+// Replace saves a trace, replacing any stored trace with the same ID
+func (tr *traceRepositoryMemory) Replace(ctx context.Context, trace *domain.Trace) error {
+	return tr.save(ctx, trace, false)
+}
+
+// save stores a trace in place of the stored trace with the same ID,
+// keeping the stored spans it does not replace when merge is set
+func (tr *traceRepositoryMemory) save(ctx context.Context, trace *domain.Trace, merge bool) error {
+	// Validate trace
+	if err := tr.validateTrace(trace); err != nil {
+		return fmt.Errorf("invalid trace: %w", err)
+	}
+
+	stored := cloneTrace(trace)
+
+	tr.mu.Lock()
+	existing, ok := tr.traces[trace.ID]
+	if ok && merge {
+		stored.Spans = domain.MergeSpans(existing.trace.Spans, stored.Spans)
+	}
+
+	size := estimateTraceSize(stored)
+	if tr.config.MaxBytes > 0 && size > tr.config.MaxBytes {
+		tr.mu.Unlock()
+		return fmt.Errorf("trace %s of %d bytes exceeds the memory budget of %d bytes", trace.ID, size, tr.config.MaxBytes)
+	}
+
+	if ok {
+		tr.removeLocked(existing)
+	}
+
+	entry := &memoryEntry{trace: stored, size: size}
+	entry.element = tr.order.PushBack(trace.ID)
+	tr.traces[trace.ID] = entry
+	tr.bytes += size
+	tr.indexLocked(stored)
+
+	tr.evictLocked()
+	tr.mu.Unlock()
+
+	// Export to Jaeger
+	if tr.jaegerExporter != nil {
+		if err := tr.jaegerExporter.ExportTrace(ctx, trace); err != nil {
+			// Log error but don't fail the operation
+			fmt.Printf("Failed to export trace to Jaeger: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// FindByID finds a trace by ID
+func (tr *traceRepositoryMemory) FindByID(ctx context.Context, id domain.TraceID) (*domain.Trace, error) {
+	if id == "" {
+		return nil, fmt.Errorf("trace ID is required")
+	}
+
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	entry, ok := tr.traces[id]
+	if !ok {
+		return nil, domain.ErrTraceNotFound
+	}
+
+	return cloneTrace(entry.trace), nil
+}
+
+// Search searches for traces based on criteria
+func (tr *traceRepositoryMemory) Search(ctx context.Context, criteria *domain.SearchCriteria) ([]*domain.Trace, error) {
+	// Validate criteria
+	if err := validateMemorySearchCriteria(criteria); err != nil {
+		return nil, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	tr.mu.RLock()
+	matches := tr.matchLocked(criteria)
+	tr.mu.RUnlock()
+
+	domain.SortTraces(matches, criteria.SortField(), criteria.SortDirection())
+	page := paginateTraces(matches, criteria.Limit, criteria.Offset)
+
+	results := make([]*domain.Trace, len(page))
+	for i, trace := range page {
+		results[i] = cloneTrace(trace)
+	}
+	return results, nil
+}
+
+// Count returns the number of traces matching the criteria, ignoring pagination
+func (tr *traceRepositoryMemory) Count(ctx context.Context, criteria *domain.SearchCriteria) (int64, error) {
+	if err := validateMemorySearchCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	unpaged := *criteria
+	unpaged.Cursor = nil
+
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	return int64(len(tr.matchLocked(&unpaged))), nil
+}
+
+// QueryTraces returns a page of the traces matching a trace query, newest first
+func (tr *traceRepositoryMemory) QueryTraces(ctx context.Context, query *domain.TraceQuery, page domain.TracePage) ([]*domain.Trace, error) {
+	matches, err := tr.queryMatches(query, page.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	domain.SortTraces(matches, domain.SortByStartTime, domain.SortDescending)
+	matches = paginateTraces(matches, page.Limit, page.Offset)
+
+	results := make([]*domain.Trace, len(matches))
+	for i, trace := range matches {
+		results[i] = cloneTrace(trace)
+	}
+	return results, nil
+}
+
+// CountQuery counts the traces matching a trace query
+func (tr *traceRepositoryMemory) CountQuery(ctx context.Context, query *domain.TraceQuery) (int64, error) {
+	matches, err := tr.queryMatches(query, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(matches)), nil
+}
+
+// queryMatches returns the stored traces matching a trace query that sort
+// after the cursor, if any
+func (tr *traceRepositoryMemory) queryMatches(query *domain.TraceQuery, cursor *domain.TraceCursor) ([]*domain.Trace, error) {
+	if query == nil || query.Root == nil {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	var matches []*domain.Trace
+	for _, entry := range tr.traces {
+		if cursor != nil && !cursor.IsAfter(entry.trace) {
+			continue
+		}
+		if query.Matches(entry.trace) {
+			matches = append(matches, entry.trace)
+		}
+	}
+	return matches, nil
+}
+
+// PurgeTraces deletes up to criteria.Limit expired traces, oldest first
+func (tr *traceRepositoryMemory) PurgeTraces(ctx context.Context, criteria domain.PurgeCriteria) (int64, error) {
+	if err := validatePurgeCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid purge criteria: %w", err)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	expired := tr.purgeableLocked(criteria)
+	if criteria.Limit > 0 && len(expired) > criteria.Limit {
+		expired = expired[:criteria.Limit]
+	}
+	for _, entry := range expired {
+		tr.removeLocked(entry)
+	}
+
+	return int64(len(expired)), nil
+}
+
+// PurgeStats counts the traces selected by the purge criteria
+func (tr *traceRepositoryMemory) PurgeStats(ctx context.Context, criteria domain.PurgeCriteria) (domain.PurgeStats, error) {
+	if err := validatePurgeCriteria(criteria); err != nil {
+		return domain.PurgeStats{}, fmt.Errorf("invalid purge criteria: %w", err)
+	}
+
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	expired := tr.purgeableLocked(criteria)
+	stats := domain.PurgeStats{Count: int64(len(expired))}
+	if len(expired) > 0 {
+		stats.Oldest = expired[0].trace.StartTime
+	}
+
+	return stats, nil
+}
+
+// purgeableLocked returns the entries selected by the purge criteria, oldest
+// first. The caller must hold tr.mu.
+func (tr *traceRepositoryMemory) purgeableLocked(criteria domain.PurgeCriteria) []*memoryEntry {
+	var expired []*memoryEntry
+	for _, entry := range tr.traces {
+		if criteria.Matches(entry.trace) {
+			expired = append(expired, entry)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].trace.StartTime.Before(expired[j].trace.StartTime)
+	})
+	return expired
+}
+
+// SaveDependencies adds the links to the stored links of the same window,
+// caller and callee. Dependency links are not evicted with traces.
+func (tr *traceRepositoryMemory) SaveDependencies(ctx context.Context, links []*domain.DependencyLink) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for _, link := range links {
+		key := dependencyLinkKey{window: link.WindowStart.UnixNano(), caller: link.Caller, callee: link.Callee}
+		stored, ok := tr.dependencies[key]
+		if !ok {
+			stored = domain.NewDependencyLink(link.WindowStart, link.Caller, link.Callee)
+			tr.dependencies[key] = stored
+		}
+		stored.Merge(link)
+	}
+	return nil
+}
+
+// FindDependencies returns the stored links whose window starts within [start, end)
+func (tr *traceRepositoryMemory) FindDependencies(ctx context.Context, start, end time.Time) ([]*domain.DependencyLink, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	links := []*domain.DependencyLink{}
+	for _, link := range tr.dependencies {
+		if link.WindowStart.Before(start) || !link.WindowStart.Before(end) {
+			continue
+		}
+		links = append(links, link.Clone())
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].WindowStart.Before(links[j].WindowStart)
+	})
+	return links, nil
+}
+
+// SaveSLO creates or replaces the SLO with the same ID
+func (tr *traceRepositoryMemory) SaveSLO(ctx context.Context, slo *domain.SLO) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	stored := *slo
+	tr.slos[slo.ID] = &stored
+	return nil
+}
+
+// FindSLO returns the SLO with the given ID
+func (tr *traceRepositoryMemory) FindSLO(ctx context.Context, id string) (*domain.SLO, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	stored, ok := tr.slos[id]
+	if !ok {
+		return nil, domain.ErrSLONotFound
+	}
+	slo := *stored
+	return &slo, nil
+}
+
+// ListSLOs returns every SLO ordered by name
+func (tr *traceRepositoryMemory) ListSLOs(ctx context.Context) ([]*domain.SLO, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	slos := make([]*domain.SLO, 0, len(tr.slos))
+	for _, stored := range tr.slos {
+		slo := *stored
+		slos = append(slos, &slo)
+	}
+	domain.SortSLOs(slos)
+	return slos, nil
+}
+
+// DeleteSLO removes the SLO with the given ID
+func (tr *traceRepositoryMemory) DeleteSLO(ctx context.Context, id string) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if _, ok := tr.slos[id]; !ok {
+		return domain.ErrSLONotFound
+	}
+	delete(tr.slos, id)
+	delete(tr.sloBuckets, id)
+	return nil
+}
+
+// AddSLOBuckets adds the counts of the buckets to the stored buckets of the
+// SLO with the same start
+func (tr *traceRepositoryMemory) AddSLOBuckets(ctx context.Context, id string, buckets []domain.SLOBucket) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	stored, ok := tr.sloBuckets[id]
+	if !ok {
+		stored = make(map[int64]domain.SLOBucket)
+		tr.sloBuckets[id] = stored
+	}
+	for _, bucket := range buckets {
+		key := bucket.Start.UnixNano()
+		current, ok := stored[key]
+		if !ok {
+			current = domain.SLOBucket{Start: bucket.Start.UTC()}
+		}
+		current.Total += bucket.Total
+		current.Good += bucket.Good
+		stored[key] = current
+	}
+	return nil
+}
+
+// FindSLOBuckets returns the stored buckets of the SLO starting at or after
+// since, ordered by start
+func (tr *traceRepositoryMemory) FindSLOBuckets(ctx context.Context, id string, since time.Time) ([]domain.SLOBucket, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	buckets := []domain.SLOBucket{}
+	for _, bucket := range tr.sloBuckets[id] {
+		if !bucket.Start.Before(since) {
+			buckets = append(buckets, bucket)
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+	return buckets, nil
+}
+
+// DeleteSLOBuckets removes the stored buckets of the SLO starting before
+// the given time, or every bucket for the zero time
+func (tr *traceRepositoryMemory) DeleteSLOBuckets(ctx context.Context, id string, before time.Time) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if before.IsZero() {
+		delete(tr.sloBuckets, id)
+		return nil
+	}
+	for key, bucket := range tr.sloBuckets[id] {
+		if bucket.Start.Before(before) {
+			delete(tr.sloBuckets[id], key)
+		}
+	}
+	return nil
+}
+
+// GetServices returns all available services
+func (tr *traceRepositoryMemory) GetServices(ctx context.Context) ([]domain.ServiceName, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	services := make([]domain.ServiceName, 0, len(tr.byService))
+	for service := range tr.byService {
+		services = append(services, service)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i] < services[j] })
+
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service
+func (tr *traceRepositoryMemory) GetOperations(ctx context.Context, service domain.ServiceName) ([]domain.OperationName, error) {
+	if service == "" {
+		return nil, fmt.Errorf("service name is required")
+	}
+
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	operations := make([]domain.OperationName, 0, len(tr.serviceOperations[service]))
+	for operation := range tr.serviceOperations[service] {
+		operations = append(operations, operation)
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i] < operations[j] })
+
+	return operations, nil
+}
+
+// matchLocked returns the stored traces matching the criteria and cursor.
+// The caller must hold tr.mu.
+func (tr *traceRepositoryMemory) matchLocked(criteria *domain.SearchCriteria) []*domain.Trace {
+	var matches []*domain.Trace
+	consider := func(id domain.TraceID) {
+		entry, ok := tr.traces[id]
+		if !ok || !criteria.Matches(entry.trace) {
+			return
+		}
+		if criteria.Cursor != nil && !criteria.Cursor.IsAfter(entry.trace) {
+			return
+		}
+		matches = append(matches, entry.trace)
+	}
+
+	if candidates, ok := tr.candidatesLocked(criteria); ok {
+		for id := range candidates {
+			consider(id)
+		}
+		return matches
+	}
+
+	for id := range tr.traces {
+		consider(id)
+	}
+	return matches
+}
+
+// candidatesLocked returns the smallest index set that every match must
+// belong to, or false when no indexed filter is set. The caller must hold tr.mu.
+func (tr *traceRepositoryMemory) candidatesLocked(criteria *domain.SearchCriteria) (map[domain.TraceID]struct{}, bool) {
+	var best map[domain.TraceID]struct{}
+	found := false
+	consider := func(set map[domain.TraceID]struct{}) {
+		if !found || len(set) < len(best) {
+			best = set
+			found = true
+		}
+	}
+
+	if criteria.Service != nil {
+		consider(tr.byService[*criteria.Service])
+	}
+	if criteria.Operation != nil {
+		consider(tr.byOperation[*criteria.Operation])
+	}
+	for key, value := range criteria.Tags {
+		consider(tr.byTag[key][value])
+	}
+
+	return best, found
+}
+
+// indexLocked adds a trace to the secondary indexes. The caller must hold tr.mu.
+func (tr *traceRepositoryMemory) indexLocked(trace *domain.Trace) {
+	addToIndex(tr.byService, trace.Service, trace.ID)
+	addToIndex(tr.byOperation, trace.Operation, trace.ID)
+
+	operations, ok := tr.serviceOperations[trace.Service]
+	if !ok {
+		operations = make(map[domain.OperationName]int)
+		tr.serviceOperations[trace.Service] = operations
+	}
+	operations[trace.Operation]++
+
+	for key, value := range trace.Tags {
+		values, ok := tr.byTag[key]
+		if !ok {
+			values = make(map[string]map[domain.TraceID]struct{})
+			tr.byTag[key] = values
+		}
+		addToIndex(values, value, trace.ID)
+	}
+}
+
+// removeLocked removes a stored trace and its index entries. The caller must hold tr.mu.
+func (tr *traceRepositoryMemory) removeLocked(entry *memoryEntry) {
+	trace := entry.trace
+
+	delete(tr.traces, trace.ID)
+	tr.order.Remove(entry.element)
+	tr.bytes -= entry.size
+
+	removeFromIndex(tr.byService, trace.Service, trace.ID)
+	removeFromIndex(tr.byOperation, trace.Operation, trace.ID)
+
+	if operations, ok := tr.serviceOperations[trace.Service]; ok {
+		operations[trace.Operation]--
+		if operations[trace.Operation] <= 0 {
+			delete(operations, trace.Operation)
+		}
+		if len(operations) == 0 {
+			delete(tr.serviceOperations, trace.Service)
+		}
+	}
+
+	for key, value := range trace.Tags {
+		if values, ok := tr.byTag[key]; ok {
+			removeFromIndex(values, value, trace.ID)
+			if len(values) == 0 {
+				delete(tr.byTag, key)
+			}
+		}
+	}
+}
+
+// evictLocked drops the oldest traces until the repository is within its
+// limits. The caller must hold tr.mu.
+func (tr *traceRepositoryMemory) evictLocked() {
+	for tr.order.Len() > 0 {
+		overCount := tr.config.MaxTraces > 0 && len(tr.traces) > tr.config.MaxTraces
+		overBytes := tr.config.MaxBytes > 0 && tr.bytes > tr.config.MaxBytes
+		if !overCount && !overBytes {
+			return
+		}
+
+		oldest := tr.order.Front().Value.(domain.TraceID)
+		tr.removeLocked(tr.traces[oldest])
+	}
+}
+
+// validateTrace validates a trace before saving
+func (tr *traceRepositoryMemory) validateTrace(trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("trace cannot be nil")
+	}
+	if trace.ID == "" {
+		return fmt.Errorf("trace ID is required")
+	}
+	if trace.Service == "" {
+		return fmt.Errorf("service name is required")
+	}
+	if trace.Operation == "" {
+		return fmt.Errorf("operation name is required")
+	}
+	if trace.StartTime.IsZero() {
+		return fmt.Errorf("start time is required")
+	}
+	if trace.EndTime.IsZero() {
+		return fmt.Errorf("end time is required")
+	}
+	if trace.StartTime.After(trace.EndTime) {
+		return fmt.Errorf("start time cannot be after end time")
+	}
+	return nil
+}
+
+// validateMemorySearchCriteria validates search criteria with the same rules as the SQL backend
+func validateMemorySearchCriteria(criteria *domain.SearchCriteria) error {
+	if criteria == nil {
+		return fmt.Errorf("search criteria cannot be nil")
+	}
+	if criteria.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative")
+	}
+	if criteria.Offset < 0 {
+		return fmt.Errorf("offset cannot be negative")
+	}
+	if criteria.StartTime != nil && criteria.EndTime != nil {
+		if criteria.StartTime.After(*criteria.EndTime) {
+			return fmt.Errorf("start time cannot be after end time")
+		}
+	}
+	if err := validateDurationRange(criteria.MinDuration, criteria.MaxDuration); err != nil {
+		return err
+	}
+	if err := validateSortOptions(criteria); err != nil {
+		return err
+	}
+	for i, filter := range criteria.SpanFilters {
+		if filter.IsEmpty() {
+			return fmt.Errorf("span filter %d has no conditions", i)
+		}
+		if err := validateDurationRange(filter.MinDuration, filter.MaxDuration); err != nil {
+			return fmt.Errorf("span filter %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// paginateTraces applies offset and limit to sorted traces; a zero limit returns everything after the offset
+func paginateTraces(traces []*domain.Trace, limit, offset int) []*domain.Trace {
+	if offset >= len(traces) {
+		return []*domain.Trace{}
+	}
+	traces = traces[offset:]
+	if limit > 0 && limit < len(traces) {
+		traces = traces[:limit]
+	}
+	return traces
+}
+
+// addToIndex adds a trace ID to the index entry for key
+func addToIndex[K comparable](index map[K]map[domain.TraceID]struct{}, key K, id domain.TraceID) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[domain.TraceID]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+// removeFromIndex removes a trace ID from the index entry for key, dropping empty entries
+func removeFromIndex[K comparable](index map[K]map[domain.TraceID]struct{}, key K, id domain.TraceID) {
+	if ids, ok := index[key]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(index, key)
+		}
+	}
+}
+
+// cloneTrace returns a deep copy of a trace so stored data cannot be mutated by callers
+func cloneTrace(trace *domain.Trace) *domain.Trace {
+	clone := *trace
+	clone.Tags = cloneTags(trace.Tags)
+
+	if trace.Spans != nil {
+		clone.Spans = make([]domain.Span, len(trace.Spans))
+		for i, span := range trace.Spans {
+			spanClone := span
+			if span.ParentID != nil {
+				parentID := *span.ParentID
+				spanClone.ParentID = &parentID
+			}
+			spanClone.Tags = cloneTags(span.Tags)
+			if span.Logs != nil {
+				spanClone.Logs = make([]domain.Log, len(span.Logs))
+				for j, log := range span.Logs {
+					log.Fields = cloneTags(log.Fields)
+					spanClone.Logs[j] = log
+				}
+			}
+			clone.Spans[i] = spanClone
+		}
+	}
+
+	return &clone
+}
+
+// cloneTags copies a tag map, preserving nil
+func cloneTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(tags))
+	for key, value := range tags {
+		clone[key] = value
+	}
+	return clone
+}
+
+// estimateTraceSize approximates the memory held by a stored trace
+func estimateTraceSize(trace *domain.Trace) int64 {
+	size := int64(memoryTraceOverheadBytes + len(trace.ID) + len(trace.Service) + len(trace.Operation) + len(trace.Status))
+	size += tagsSize(trace.Tags)
+
+	for _, span := range trace.Spans {
+		size += int64(memorySpanOverheadBytes + len(span.ID) + len(span.TraceID) + len(span.Service) + len(span.Operation) + len(span.Status))
+		if span.ParentID != nil {
+			size += int64(len(*span.ParentID))
+		}
+		size += tagsSize(span.Tags)
+		for _, log := range span.Logs {
+			size += int64(len(log.Message)) + tagsSize(log.Fields)
+		}
+	}
+
+	return size
+}
+
+// tagsSize approximates the memory held by a tag map
+func tagsSize(tags map[string]string) int64 {
+	var size int64
+	for key, value := range tags {
+		size += int64(len(key) + len(value) + 32)
+	}
+	return size
+}
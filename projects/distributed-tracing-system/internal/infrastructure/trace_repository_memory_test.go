@@ -0,0 +1,325 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryTestTrace(id string, service domain.ServiceName, operation domain.OperationName, start time.Time, duration time.Duration) *domain.Trace {
+	return &domain.Trace{
+		ID:        domain.TraceID(id),
+		Service:   service,
+		Operation: operation,
+		StartTime: start,
+		EndTime:   start.Add(duration),
+		Duration:  duration,
+		Status:    domain.TraceStatusSuccess,
+		Spans: []domain.Span{
+			{
+				ID:        domain.SpanID(id + "-root"),
+				TraceID:   domain.TraceID(id),
+				Service:   service,
+				Operation: operation,
+				StartTime: start,
+				EndTime:   start.Add(duration),
+				Duration:  duration,
+				Status:    domain.SpanStatusOK,
+			},
+		},
+	}
+}
+
+func newTestMemoryRepository(t *testing.T, config MemoryRepositoryConfig) *traceRepositoryMemory {
+	repo, err := NewTraceRepositoryMemory(config, &MockJaegerExporter{})
+	require.NoError(t, err)
+	return repo.(*traceRepositoryMemory)
+}
+
+func TestTraceRepositoryMemory_Contract(t *testing.T) {
+	repotest.RunTraceRepositoryContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryMemory_PurgerContract(t *testing.T) {
+	repotest.RunTracePurgerContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryMemory_ReplacerContract(t *testing.T) {
+	repotest.RunTraceReplacerContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryMemory_DependencyStoreContract(t *testing.T) {
+	repotest.RunDependencyStoreContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryMemory_SLOStoreContract(t *testing.T) {
+	repotest.RunSLOStoreContract(t, func(t *testing.T) domain.TraceRepository {
+		return newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	})
+}
+
+func TestTraceRepositoryMemory_SaveAndFind(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	trace := newMemoryTestTrace("trace1", "checkout", "POST /checkout", start, time.Second)
+	trace.Tags = map[string]string{"env": "prod"}
+	require.NoError(t, repo.Save(ctx, trace))
+
+	// Stored traces are isolated from later changes by the caller
+	trace.Tags["env"] = "staging"
+
+	found, err := repo.FindByID(ctx, "trace1")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", found.Tags["env"])
+	assert.Len(t, found.Spans, 1)
+
+	_, err = repo.FindByID(ctx, "missing")
+	assert.Error(t, err)
+
+	// Re-saving updates the trace and its index entries
+	updated := newMemoryTestTrace("trace1", "checkout", "GET /cart", start, time.Second)
+	require.NoError(t, repo.Save(ctx, updated))
+
+	operations, err := repo.GetOperations(ctx, "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.OperationName{"GET /cart"}, operations)
+	assert.Len(t, repo.traces, 1)
+}
+
+func TestTraceRepositoryMemory_EvictsByCount(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{MaxTraces: 2})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("trace1", "checkout", "op", start, time.Second)))
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("trace2", "payments", "op", start, time.Second)))
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("trace3", "payments", "op", start, time.Second)))
+
+	_, err := repo.FindByID(ctx, "trace1")
+	assert.Error(t, err)
+
+	services, err := repo.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServiceName{"payments"}, services)
+}
+
+func TestTraceRepositoryMemory_EvictsByBytes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	size := estimateTraceSize(newMemoryTestTrace("trace1", "checkout", "op", start, time.Second))
+
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{MaxBytes: size*2 + size/2})
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, repo.Save(ctx, newMemoryTestTrace(fmt.Sprintf("trace%d", i), "checkout", "op", start, time.Second)))
+	}
+
+	assert.Len(t, repo.traces, 2)
+	assert.LessOrEqual(t, repo.bytes, repo.config.MaxBytes)
+	_, err := repo.FindByID(ctx, "trace1")
+	assert.Error(t, err)
+
+	// A single trace over the budget is rejected
+	small := newTestMemoryRepository(t, MemoryRepositoryConfig{MaxBytes: 10})
+	assert.Error(t, small.Save(ctx, newMemoryTestTrace("trace1", "checkout", "op", start, time.Second)))
+}
+
+func TestTraceRepositoryMemory_Search(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	slow := newMemoryTestTrace("slow", "checkout", "POST /checkout", start, 2*time.Second)
+	slow.Tags = map[string]string{"env": "prod"}
+	failed := newMemoryTestTrace("failed", "checkout", "POST /checkout", start.Add(time.Minute), 100*time.Millisecond)
+	failed.Status = domain.TraceStatusError
+	failed.Spans = append(failed.Spans, domain.Span{
+		ID: "failed-payment", TraceID: "failed", Service: "payments", Operation: "charge",
+		StartTime: start, EndTime: start, Status: domain.SpanStatusError,
+	})
+	other := newMemoryTestTrace("other", "inventory", "GET /stock", start.Add(2*time.Minute), time.Second)
+
+	for _, trace := range []*domain.Trace{slow, failed, other} {
+		require.NoError(t, repo.Save(ctx, trace))
+	}
+
+	service := domain.ServiceName("checkout")
+	minDuration := time.Second
+	spanService := domain.ServiceName("payments")
+	spanStatus := domain.SpanStatusError
+
+	tests := []struct {
+		name     string
+		criteria *domain.SearchCriteria
+		expected []domain.TraceID
+	}{
+		{
+			name:     "all newest first",
+			criteria: &domain.SearchCriteria{},
+			expected: []domain.TraceID{"other", "failed", "slow"},
+		},
+		{
+			name:     "by service",
+			criteria: &domain.SearchCriteria{Service: &service},
+			expected: []domain.TraceID{"failed", "slow"},
+		},
+		{
+			name:     "by duration and tag",
+			criteria: &domain.SearchCriteria{MinDuration: &minDuration, Tags: map[string]string{"env": "prod"}},
+			expected: []domain.TraceID{"slow"},
+		},
+		{
+			name: "by span filter",
+			criteria: &domain.SearchCriteria{SpanFilters: []domain.SpanFilter{
+				{Service: &spanService, Status: &spanStatus},
+			}},
+			expected: []domain.TraceID{"failed"},
+		},
+		{
+			name:     "by duration ascending with offset",
+			criteria: &domain.SearchCriteria{SortBy: domain.SortByDuration, SortOrder: domain.SortAscending, Limit: 2, Offset: 1},
+			expected: []domain.TraceID{"other", "slow"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := repo.Search(ctx, tt.criteria)
+			require.NoError(t, err)
+
+			ids := make([]domain.TraceID, len(traces))
+			for i, trace := range traces {
+				ids[i] = trace.ID
+			}
+			assert.Equal(t, tt.expected, ids)
+
+			count, err := repo.Count(ctx, tt.criteria)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, count, int64(len(ids)))
+		})
+	}
+}
+
+func TestTraceRepositoryMemory_CursorPagination(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Traces share start times so the ID tie-breaker is exercised
+	for i := 0; i < 7; i++ {
+		trace := newMemoryTestTrace(fmt.Sprintf("trace%d", i), "checkout", "op", start.Add(time.Duration(i/2)*time.Second), time.Second)
+		require.NoError(t, repo.Save(ctx, trace))
+	}
+
+	var seen []domain.TraceID
+	criteria := &domain.SearchCriteria{Limit: 3}
+	for {
+		page, err := repo.Search(ctx, criteria)
+		require.NoError(t, err)
+		for _, trace := range page {
+			seen = append(seen, trace.ID)
+		}
+
+		criteria.Cursor = domain.NextTraceCursor(criteria, page)
+		if criteria.Cursor == nil {
+			break
+		}
+	}
+
+	assert.Equal(t, []domain.TraceID{"trace6", "trace5", "trace4", "trace3", "trace2", "trace1", "trace0"}, seen)
+}
+
+func TestTraceRepositoryMemory_QueryTraces(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("fast", "checkout", "op", start, 10*time.Millisecond)))
+	require.NoError(t, repo.Save(ctx, newMemoryTestTrace("slow", "checkout", "op", start, time.Second)))
+
+	query, err := domain.ParseTraceQuery("service=checkout AND duration>500ms")
+	require.NoError(t, err)
+
+	traces, err := repo.QueryTraces(ctx, query, domain.TracePage{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, domain.TraceID("slow"), traces[0].ID)
+
+	count, err := repo.CountQuery(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTraceRepositoryMemory_QueryTraces_Cursor(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Save(ctx, newMemoryTestTrace(fmt.Sprintf("trace%d", i), "checkout", "op", start.Add(time.Duration(i)*time.Minute), time.Second)))
+	}
+
+	query, err := domain.ParseTraceQuery("service=checkout")
+	require.NoError(t, err)
+
+	// Walk all pages, newest first
+	var seen []domain.TraceID
+	criteria := &domain.SearchCriteria{Limit: 2}
+	page := domain.TracePage{Limit: 2}
+	for {
+		traces, err := repo.QueryTraces(ctx, query, page)
+		require.NoError(t, err)
+		for _, trace := range traces {
+			seen = append(seen, trace.ID)
+		}
+
+		page.Cursor = domain.NextTraceCursor(criteria, traces)
+		if page.Cursor == nil {
+			break
+		}
+	}
+
+	assert.Equal(t, []domain.TraceID{"trace4", "trace3", "trace2", "trace1", "trace0"}, seen)
+}
+
+func TestTraceRepositoryMemory_Concurrent(t *testing.T) {
+	repo := newTestMemoryRepository(t, MemoryRepositoryConfig{MaxTraces: 50})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("trace-%d-%d", worker, i)
+				assert.NoError(t, repo.Save(ctx, newMemoryTestTrace(id, "checkout", "op", start, time.Second)))
+				_, err := repo.Search(ctx, &domain.SearchCriteria{Limit: 10})
+				assert.NoError(t, err)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	assert.Len(t, repo.traces, 50)
+	assert.Equal(t, 50, repo.order.Len())
+	assert.Len(t, repo.byService["checkout"], 50)
+}
@@ -5,92 +5,158 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/streamforge/distributed-tracing-system/internal/domain"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// PostgresRepositoryConfig holds configuration for the PostgreSQL trace repository
+type PostgresRepositoryConfig struct {
+	DSN string
+	// AutoMigrate applies pending schema migrations on startup; otherwise
+	// the repository refuses to start while migrations are pending
+	AutoMigrate bool
+	// PartitionPremakeDays is how many days of partitions are created ahead of today
+	PartitionPremakeDays int
+	// PartitionRetention drops a day's partitions once the day ended longer
+	// than this ago; zero keeps all partitions
+	PartitionRetention time.Duration
+	// PartitionInterval is how often partitions are created and dropped;
+	// zero disables background maintenance
+	PartitionInterval time.Duration
+}
+
 // traceRepositoryPostgres implements the TraceRepository interface with PostgreSQL
 type traceRepositoryPostgres struct {
 	db             *sqlx.DB
 	jaegerExporter domain.JaegerExporter
+	partitions     *postgresPartitions
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
 }
 
-// NewTraceRepositoryPostgres creates a new PostgreSQL trace repository
-func NewTraceRepositoryPostgres(dsn string, jaegerExporter domain.JaegerExporter) (domain.TraceRepository, error) {
-	db, err := sqlx.Connect("postgres", dsn)
+// NewTraceRepositoryPostgres creates a new PostgreSQL trace repository.
+// Partitions are maintained in the background until Close is called.
+func NewTraceRepositoryPostgres(config PostgresRepositoryConfig, jaegerExporter domain.JaegerExporter) (domain.TraceRepository, error) {
+	if config.PartitionPremakeDays < 0 {
+		return nil, fmt.Errorf("partition premake days cannot be negative")
+	}
+	if config.PartitionRetention < 0 || config.PartitionInterval < 0 {
+		return nil, fmt.Errorf("partition retention and interval cannot be negative")
+	}
+
+	db, err := sqlx.Connect("postgres", config.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Create tables if they don't exist
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	if err := migrateSchema(context.Background(), db, config.AutoMigrate); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return &traceRepositoryPostgres{
+	tr := &traceRepositoryPostgres{
 		db:             db,
 		jaegerExporter: jaegerExporter,
-	}, nil
-}
-
-// createTables creates the necessary database tables
-func createTables(db *sqlx.DB) error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS traces (
-			id VARCHAR(255) PRIMARY KEY,
-			service VARCHAR(255) NOT NULL,
-			operation VARCHAR(255) NOT NULL,
-			start_time TIMESTAMP NOT NULL,
-			end_time TIMESTAMP NOT NULL,
-			duration BIGINT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			tags JSONB,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS spans (
-			id VARCHAR(255) PRIMARY KEY,
-			trace_id VARCHAR(255) NOT NULL,
-			parent_id VARCHAR(255),
-			service VARCHAR(255) NOT NULL,
-			operation VARCHAR(255) NOT NULL,
-			start_time TIMESTAMP NOT NULL,
-			end_time TIMESTAMP NOT NULL,
-			duration BIGINT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			tags JSONB,
-			logs JSONB,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (trace_id) REFERENCES traces(id) ON DELETE CASCADE
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_traces_service ON traces(service)`,
-		`CREATE INDEX IF NOT EXISTS idx_traces_operation ON traces(operation)`,
-		`CREATE INDEX IF NOT EXISTS idx_traces_start_time ON traces(start_time)`,
-		`CREATE INDEX IF NOT EXISTS idx_traces_status ON traces(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_spans_trace_id ON spans(trace_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_spans_service ON spans(service)`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute query %s: %w", query, err)
+		partitions:     newPostgresPartitions(db, config.PartitionPremakeDays, config.PartitionRetention),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	if err := tr.partitions.EnsureUpcoming(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	go tr.maintain(config.PartitionInterval)
+
+	return tr, nil
+}
+
+// migrateSchema applies pending migrations, or fails when some are pending
+// and automatic migration is disabled
+func migrateSchema(ctx context.Context, db *sqlx.DB, autoMigrate bool) error {
+	migrator, err := NewPostgresMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	if autoMigrate {
+		if _, err := migrator.Up(ctx); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
 		}
+		return nil
 	}
 
+	pending, err := pendingMigrations(ctx, migrator)
+	if err != nil {
+		return fmt.Errorf("failed to check schema migrations: %w", err)
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d schema migrations pending, run the migrate command", pending)
+	}
 	return nil
 }
 
-// Save saves a trace to the repository
+// Save saves a trace, merging its spans into the stored trace with the
+// same ID
 func (tr *traceRepositoryPostgres) Save(ctx context.Context, trace *domain.Trace) error {
-	// Validate trace
-	if err := tr.validateTrace(trace); err != nil {
-		return fmt.Errorf("invalid trace: %w", err)
+	return tr.SaveBatch(ctx, []*domain.Trace{trace})
+}
+
+// Replace saves a trace, replacing any stored trace with the same ID
+func (tr *traceRepositoryPostgres) Replace(ctx context.Context, trace *domain.Trace) error {
+	return tr.writeTraces(ctx, []*domain.Trace{trace}, true)
+}
+
+// SaveBatch saves traces in one transaction. Rows are streamed with COPY
+// into session staging tables and moved into the partitioned tables with
+// one upsert per table, so the cost no longer grows with a round trip per
+// span. Like Save, spans are merged into the stored traces, and a trace
+// saved more than once in the batch is merged as consecutive saves would be.
+func (tr *traceRepositoryPostgres) SaveBatch(ctx context.Context, traces []*domain.Trace) error {
+	return tr.writeTraces(ctx, traces, false)
+}
+
+// writeTraces upserts traces and their spans in one transaction, first
+// deleting the stored traces with the same IDs when replace is set
+func (tr *traceRepositoryPostgres) writeTraces(ctx context.Context, traces []*domain.Trace, replace bool) error {
+	for _, trace := range traces {
+		if err := tr.validateTrace(trace); err != nil {
+			return fmt.Errorf("invalid trace: %w", err)
+		}
+	}
+
+	traces = mergeTraces(traces)
+	if len(traces) == 0 {
+		return nil
+	}
+
+	// Make sure the daily partitions for the traces and their spans exist
+	ids := make([]string, 0, len(traces))
+	var times []time.Time
+	for _, trace := range traces {
+		ids = append(ids, string(trace.ID))
+		times = append(times, trace.StartTime)
+		for _, span := range trace.Spans {
+			times = append(times, span.StartTime)
+		}
+	}
+	if err := tr.partitions.EnsureFor(ctx, times...); err != nil {
+		return err
 	}
 
 	// Start transaction
@@ -100,87 +166,215 @@ func (tr *traceRepositoryPostgres) Save(ctx context.Context, trace *domain.Trace
 	}
 	defer tx.Rollback()
 
-	// Save trace
-	if err := tr.saveTrace(ctx, tx, trace); err != nil {
-		return fmt.Errorf("failed to save trace: %w", err)
+	// Serialize writers of the same traces, so two transactions never take
+	// back the same stored version or both add a new trace to the rollups
+	if err := lockTraces(ctx, tx, ids); err != nil {
+		return err
+	}
+
+	// Take back what the stored versions added to the rollups; what the
+	// written versions add is read back once they are written
+	stored, err := storedTraceRollups(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	rollups := make([]*domain.TraceRollup, 0, 2*len(stored))
+	for _, rollup := range stored {
+		rollups = append(rollups, rollup.Negate())
+	}
+
+	if replace {
+		if err := deleteTraces(ctx, tx, ids); err != nil {
+			return err
+		}
+	}
+
+	// Save traces
+	if err := tr.copyTraces(ctx, tx, traces); err != nil {
+		return fmt.Errorf("failed to save traces: %w", err)
 	}
 
 	// Save spans
-	if err := tr.saveSpans(ctx, tx, trace); err != nil {
+	if err := tr.copySpans(ctx, tx, traces); err != nil {
 		return fmt.Errorf("failed to save spans: %w", err)
 	}
 
+	// Update rollups
+	written, err := storedTraceRollups(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	rollups = append(rollups, written...)
+	if err := saveRollups(ctx, tx, rollups); err != nil {
+		return fmt.Errorf("failed to save rollups: %w", err)
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// Export to Jaeger
-	if err := tr.jaegerExporter.ExportTrace(ctx, trace); err != nil {
-		// Log error but don't fail the operation
-		fmt.Printf("Failed to export trace to Jaeger: %v\n", err)
+	for _, trace := range traces {
+		if err := tr.jaegerExporter.ExportTrace(ctx, trace); err != nil {
+			// Log error but don't fail the operation
+			fmt.Printf("Failed to export trace to Jaeger: %v\n", err)
+		}
 	}
 
 	return nil
 }
 
-// saveTrace saves a trace to the database
-func (tr *traceRepositoryPostgres) saveTrace(ctx context.Context, tx *sqlx.Tx, trace *domain.Trace) error {
+// lockTraces takes a transaction-level advisory lock on each trace ID. The
+// locks are taken in key order so concurrent batches cannot deadlock.
+func lockTraces(ctx context.Context, tx *sqlx.Tx, ids []string) error {
 	query := `
-		INSERT INTO traces (id, service, operation, start_time, end_time, duration, status, tags)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (id) DO UPDATE SET
-			service = EXCLUDED.service,
-			operation = EXCLUDED.operation,
-			start_time = EXCLUDED.start_time,
-			end_time = EXCLUDED.end_time,
-			duration = EXCLUDED.duration,
-			status = EXCLUDED.status,
-			tags = EXCLUDED.tags
+		SELECT pg_advisory_xact_lock(lock_key)
+		FROM (SELECT DISTINCT hashtext(id) AS lock_key FROM unnest($1::text[]) AS id ORDER BY lock_key) AS keys
 	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to lock traces: %w", err)
+	}
+	return nil
+}
 
-	tagsJSON, err := json.Marshal(trace.Tags)
+// deleteTraces deletes the stored traces with the given IDs and their spans
+func deleteTraces(ctx context.Context, tx *sqlx.Tx, ids []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM spans WHERE trace_id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to replace spans: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM traces WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to replace traces: %w", err)
+	}
+	return nil
+}
+
+// mergeTraces folds traces saved more than once into one, as consecutive
+// saves would: the last trace-level fields win and the spans are merged.
+// The order of first appearance is kept and the inputs are not modified.
+func mergeTraces(traces []*domain.Trace) []*domain.Trace {
+	index := make(map[domain.TraceID]int, len(traces))
+	merged := make([]*domain.Trace, 0, len(traces))
+	for _, trace := range traces {
+		if i, ok := index[trace.ID]; ok {
+			combined := *trace
+			combined.Spans = domain.MergeSpans(merged[i].Spans, trace.Spans)
+			merged[i] = &combined
+			continue
+		}
+		index[trace.ID] = len(merged)
+		merged = append(merged, trace)
+	}
+	return merged
+}
+
+// copyTraces streams traces into the staging table and upserts them into traces
+func (tr *traceRepositoryPostgres) copyTraces(ctx context.Context, tx *sqlx.Tx, traces []*domain.Trace) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE IF NOT EXISTS traces_staging (LIKE traces INCLUDING DEFAULTS) ON COMMIT DELETE ROWS`); err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("traces_staging",
+		"id", "service", "operation", "start_time", "end_time", "duration", "status", "tags"))
 	if err != nil {
-		return fmt.Errorf("failed to marshal tags: %w", err)
+		return fmt.Errorf("failed to start copy: %w", err)
 	}
+	defer stmt.Close()
 
-	_, err = tx.ExecContext(ctx, query,
-		trace.ID,
-		trace.Service,
-		trace.Operation,
-		trace.StartTime,
-		trace.EndTime,
-		trace.Duration.Nanoseconds(),
-		trace.Status,
-		tagsJSON,
-	)
+	for _, trace := range traces {
+		tagsJSON, err := json.Marshal(trace.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
 
-	return err
-}
+		// JSON is passed as text; COPY would encode []byte as bytea
+		_, err = stmt.ExecContext(ctx,
+			trace.ID,
+			trace.Service,
+			trace.Operation,
+			trace.StartTime,
+			trace.EndTime,
+			trace.Duration.Nanoseconds(),
+			trace.Status,
+			string(tagsJSON),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to copy trace %s: %w", trace.ID, err)
+		}
+	}
 
-// saveSpans saves spans to the database
-func (tr *traceRepositoryPostgres) saveSpans(ctx context.Context, tx *sqlx.Tx, trace *domain.Trace) error {
-	if len(trace.Spans) == 0 {
-		return nil
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush copy: %w", err)
+	}
+
+	// The primary key includes the start time, so a trace whose start time
+	// changed would be stored twice rather than updated
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM traces t USING traces_staging s
+		WHERE t.id = s.id AND t.start_time <> s.start_time
+	`); err != nil {
+		return fmt.Errorf("failed to move traces: %w", err)
 	}
 
 	query := `
-		INSERT INTO spans (id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (id) DO UPDATE SET
-			trace_id = EXCLUDED.trace_id,
-			parent_id = EXCLUDED.parent_id,
+		INSERT INTO traces (id, service, operation, start_time, end_time, duration, status, tags)
+		SELECT id, service, operation, start_time, end_time, duration, status, tags FROM traces_staging
+		ON CONFLICT (id, start_time) DO UPDATE SET
 			service = EXCLUDED.service,
 			operation = EXCLUDED.operation,
-			start_time = EXCLUDED.start_time,
 			end_time = EXCLUDED.end_time,
 			duration = EXCLUDED.duration,
 			status = EXCLUDED.status,
-			tags = EXCLUDED.tags,
-			logs = EXCLUDED.logs
+			tags = EXCLUDED.tags
 	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to upsert traces: %w", err)
+	}
+
+	return nil
+}
 
-	for _, span := range trace.Spans {
+// spanKey identifies a span of a trace
+type spanKey struct {
+	traceID domain.TraceID
+	id      domain.SpanID
+}
+
+// copySpans streams the spans of the traces into the staging table and
+// upserts them into spans, keeping stored spans that are not written again.
+// A span repeated within a trace keeps its last version, since one upsert
+// cannot touch the same row twice.
+func (tr *traceRepositoryPostgres) copySpans(ctx context.Context, tx *sqlx.Tx, traces []*domain.Trace) error {
+	var spans []*domain.Span
+	index := make(map[spanKey]int)
+	for _, trace := range traces {
+		for i := range trace.Spans {
+			span := &trace.Spans[i]
+			key := spanKey{traceID: span.TraceID, id: span.ID}
+			if j, ok := index[key]; ok {
+				spans[j] = span
+				continue
+			}
+			index[key] = len(spans)
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE IF NOT EXISTS spans_staging (LIKE spans INCLUDING DEFAULTS) ON COMMIT DELETE ROWS`); err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("spans_staging",
+		"id", "trace_id", "parent_id", "service", "operation", "start_time", "end_time", "duration", "status", "tags", "logs"))
+	if err != nil {
+		return fmt.Errorf("failed to start copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, span := range spans {
 		tagsJSON, err := json.Marshal(span.Tags)
 		if err != nil {
 			return fmt.Errorf("failed to marshal span tags: %w", err)
@@ -197,7 +391,7 @@ func (tr *traceRepositoryPostgres) saveSpans(ctx context.Context, tx *sqlx.Tx, t
 			parentID = &parentIDStr
 		}
 
-		_, err = tx.ExecContext(ctx, query,
+		_, err = stmt.ExecContext(ctx,
 			span.ID,
 			span.TraceID,
 			parentID,
@@ -207,15 +401,43 @@ func (tr *traceRepositoryPostgres) saveSpans(ctx context.Context, tx *sqlx.Tx, t
 			span.EndTime,
 			span.Duration.Nanoseconds(),
 			span.Status,
-			tagsJSON,
-			logsJSON,
+			string(tagsJSON),
+			string(logsJSON),
 		)
-
 		if err != nil {
-			return fmt.Errorf("failed to save span %s: %w", span.ID, err)
+			return fmt.Errorf("failed to copy span %s: %w", span.ID, err)
 		}
 	}
 
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush copy: %w", err)
+	}
+
+	// As with traces, a span whose start time changed replaces its old row
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM spans t USING spans_staging s
+		WHERE t.trace_id = s.trace_id AND t.id = s.id AND t.start_time <> s.start_time
+	`); err != nil {
+		return fmt.Errorf("failed to move spans: %w", err)
+	}
+
+	query := `
+		INSERT INTO spans (id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs)
+		SELECT id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs FROM spans_staging
+		ON CONFLICT (trace_id, id, start_time) DO UPDATE SET
+			parent_id = EXCLUDED.parent_id,
+			service = EXCLUDED.service,
+			operation = EXCLUDED.operation,
+			end_time = EXCLUDED.end_time,
+			duration = EXCLUDED.duration,
+			status = EXCLUDED.status,
+			tags = EXCLUDED.tags,
+			logs = EXCLUDED.logs
+	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to upsert spans: %w", err)
+	}
+
 	return nil
 }
 
@@ -243,7 +465,7 @@ func (tr *traceRepositoryPostgres) FindByID(ctx context.Context, id domain.Trace
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("trace not found")
+			return nil, domain.ErrTraceNotFound
 		}
 		return nil, fmt.Errorf("failed to query trace: %w", err)
 	}
@@ -329,7 +551,173 @@ func (tr *traceRepositoryPostgres) Search(ctx context.Context, criteria *domain.
 	}
 
 	// Build query
-	query := `SELECT id, service, operation, start_time, end_time, duration, status, tags FROM traces WHERE 1=1`
+	query, args, err := buildSearchQuery(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build search query: %w", err)
+	}
+
+	// Execute query
+	rows, err := tr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTraces(rows)
+}
+
+// QueryTraces returns a page of the traces matching a trace query, newest first
+func (tr *traceRepositoryPostgres) QueryTraces(ctx context.Context, query *domain.TraceQuery, page domain.TracePage) ([]*domain.Trace, error) {
+	sqlQuery, args, err := buildTraceQuery(query, page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tr.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTraces(rows)
+}
+
+// CountQuery counts the traces matching a trace query
+func (tr *traceRepositoryPostgres) CountQuery(ctx context.Context, query *domain.TraceQuery) (int64, error) {
+	condition, args, err := compileTraceQuery(query, 1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compile query: %w", err)
+	}
+
+	var count int64
+	if err := tr.db.QueryRowContext(ctx, `SELECT count(*) FROM traces WHERE `+condition, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count traces: %w", err)
+	}
+	return count, nil
+}
+
+// buildTraceQuery builds the SQL query selecting a page of the traces
+// matching a trace query. Like a default search, results are ordered by
+// start time and trace ID, newest first, so a cursor can resume after the
+// last trace of a page.
+func buildTraceQuery(query *domain.TraceQuery, page domain.TracePage) (string, []interface{}, error) {
+	condition, args, err := compileTraceQuery(query, 1)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to compile query: %w", err)
+	}
+
+	sqlQuery := `SELECT id, service, operation, start_time, end_time, duration, status, tags FROM traces WHERE ` + condition
+
+	if page.Cursor != nil {
+		sqlQuery += fmt.Sprintf(" AND (start_time, id) < ($%d, $%d)", len(args)+1, len(args)+2)
+		args = append(args, page.Cursor.StartTime(), page.Cursor.TraceID)
+	}
+
+	sqlQuery += " ORDER BY start_time DESC, id DESC"
+
+	if page.Limit > 0 {
+		sqlQuery += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, page.Limit)
+	}
+
+	if page.Offset > 0 {
+		sqlQuery += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, page.Offset)
+	}
+
+	return sqlQuery, args, nil
+}
+
+// scanTraces scans trace rows without their spans
+func scanTraces(rows *sql.Rows) ([]*domain.Trace, error) {
+	var traces []*domain.Trace
+	for rows.Next() {
+		var trace domain.Trace
+		var tagsJSON []byte
+
+		err := rows.Scan(
+			&trace.ID,
+			&trace.Service,
+			&trace.Operation,
+			&trace.StartTime,
+			&trace.EndTime,
+			&trace.Duration,
+			&trace.Status,
+			&tagsJSON,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trace: %w", err)
+		}
+
+		// Parse tags
+		if err := json.Unmarshal(tagsJSON, &trace.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+
+		traces = append(traces, &trace)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate traces: %w", err)
+	}
+
+	return traces, nil
+}
+
+// buildSearchQuery builds the trace search query and its arguments. Results
+// are ordered by the sort field with the trace ID as tie-breaker so that a
+// cursor can resume right after the last trace of a page.
+func buildSearchQuery(criteria *domain.SearchCriteria) (string, []interface{}, error) {
+	conditions, args, err := buildSearchConditions(criteria)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := `SELECT id, service, operation, start_time, end_time, duration, status, tags FROM traces WHERE ` + conditions
+	argIndex := len(args) + 1
+
+	sortColumn := "start_time"
+	if criteria.SortField() == domain.SortByDuration {
+		sortColumn = "duration"
+	}
+	direction, comparator := "DESC", "<"
+	if criteria.SortDirection() == domain.SortAscending {
+		direction, comparator = "ASC", ">"
+	}
+
+	// Keyset pagination: continue strictly after the cursor position
+	if criteria.Cursor != nil {
+		query += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", sortColumn, comparator, argIndex, argIndex+1)
+		if sortColumn == "duration" {
+			args = append(args, criteria.Cursor.Value)
+		} else {
+			args = append(args, criteria.Cursor.StartTime())
+		}
+		args = append(args, criteria.Cursor.TraceID)
+		argIndex += 2
+	}
+
+	// Add ordering and pagination
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", sortColumn, direction, direction)
+
+	if criteria.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, criteria.Limit)
+		argIndex++
+	}
+
+	if criteria.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, criteria.Offset)
+	}
+
+	return query, args, nil
+}
+
+// buildSearchConditions builds the WHERE conditions shared by search and count queries
+func buildSearchConditions(criteria *domain.SearchCriteria) (string, []interface{}, error) {
+	query := `1=1`
 	args := []interface{}{}
 	argIndex := 1
 
@@ -364,56 +752,482 @@ func (tr *traceRepositoryPostgres) Search(ctx context.Context, criteria *domain.
 		argIndex++
 	}
 
-	// Add ordering and pagination
-	query += " ORDER BY start_time DESC"
-	
+	if criteria.MinDuration != nil {
+		query += fmt.Sprintf(" AND duration >= $%d", argIndex)
+		args = append(args, criteria.MinDuration.Nanoseconds())
+		argIndex++
+	}
+
+	if criteria.MaxDuration != nil {
+		query += fmt.Sprintf(" AND duration <= $%d", argIndex)
+		args = append(args, criteria.MaxDuration.Nanoseconds())
+		argIndex++
+	}
+
+	// Tag containment is served by the GIN index on tags
+	if len(criteria.Tags) > 0 {
+		tagsJSON, err := json.Marshal(criteria.Tags)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		query += fmt.Sprintf(" AND tags @> $%d::jsonb", argIndex)
+		args = append(args, string(tagsJSON))
+		argIndex++
+	}
+
+	// Each span filter must be satisfied by at least one span of the trace
+	for _, filter := range criteria.SpanFilters {
+		conditions := []string{"s.trace_id = traces.id"}
+
+		if filter.Service != nil {
+			conditions = append(conditions, fmt.Sprintf("s.service = $%d", argIndex))
+			args = append(args, *filter.Service)
+			argIndex++
+		}
+
+		if filter.Operation != nil {
+			conditions = append(conditions, fmt.Sprintf("s.operation = $%d", argIndex))
+			args = append(args, *filter.Operation)
+			argIndex++
+		}
+
+		if filter.Status != nil {
+			conditions = append(conditions, fmt.Sprintf("s.status = $%d", argIndex))
+			args = append(args, *filter.Status)
+			argIndex++
+		}
+
+		if filter.MinDuration != nil {
+			conditions = append(conditions, fmt.Sprintf("s.duration >= $%d", argIndex))
+			args = append(args, filter.MinDuration.Nanoseconds())
+			argIndex++
+		}
+
+		if filter.MaxDuration != nil {
+			conditions = append(conditions, fmt.Sprintf("s.duration <= $%d", argIndex))
+			args = append(args, filter.MaxDuration.Nanoseconds())
+			argIndex++
+		}
+
+		if len(filter.Tags) > 0 {
+			tagsJSON, err := json.Marshal(filter.Tags)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to marshal span tags: %w", err)
+			}
+			conditions = append(conditions, fmt.Sprintf("s.tags @> $%d::jsonb", argIndex))
+			args = append(args, string(tagsJSON))
+			argIndex++
+		}
+
+		query += " AND EXISTS (SELECT 1 FROM spans s WHERE " + strings.Join(conditions, " AND ") + ")"
+	}
+
+	return query, args, nil
+}
+
+// Count returns the exact number of traces matching the criteria, ignoring pagination
+func (tr *traceRepositoryPostgres) Count(ctx context.Context, criteria *domain.SearchCriteria) (int64, error) {
+	if err := tr.validateSearchCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	conditions, args, err := buildSearchConditions(criteria)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build count query: %w", err)
+	}
+
+	var count int64
+	if err := tr.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM traces WHERE `+conditions, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	return count, nil
+}
+
+// EstimateCount returns the planner's row estimate for the criteria, which
+// avoids scanning large tables at the cost of accuracy
+func (tr *traceRepositoryPostgres) EstimateCount(ctx context.Context, criteria *domain.SearchCriteria) (int64, error) {
+	if err := tr.validateSearchCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid search criteria: %w", err)
+	}
+
+	conditions, args, err := buildSearchConditions(criteria)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build count query: %w", err)
+	}
+
+	var planJSON []byte
+	if err := tr.db.QueryRowContext(ctx, `EXPLAIN (FORMAT JSON) SELECT 1 FROM traces WHERE `+conditions, args...).Scan(&planJSON); err != nil {
+		return 0, fmt.Errorf("failed to estimate trace count: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(planJSON, &plans); err != nil || len(plans) == 0 {
+		return 0, fmt.Errorf("failed to parse query plan: %v", err)
+	}
+
+	return int64(plans[0].Plan.Rows), nil
+}
+
+// PurgeTraces deletes up to criteria.Limit expired traces with their
+// spans, oldest first. Whole expired days are dropped with their
+// partitions instead; this serves retention rules shorter than that.
+func (tr *traceRepositoryPostgres) PurgeTraces(ctx context.Context, criteria domain.PurgeCriteria) (int64, error) {
+	if err := validatePurgeCriteria(criteria); err != nil {
+		return 0, fmt.Errorf("invalid purge criteria: %w", err)
+	}
+
+	conditions, args := buildPurgeConditions(criteria)
+	selection := `SELECT id, start_time FROM traces WHERE ` + conditions + ` ORDER BY start_time`
 	if criteria.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		selection += fmt.Sprintf(" LIMIT $%d", len(args)+1)
 		args = append(args, criteria.Limit)
-		argIndex++
 	}
 
-	if criteria.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
-		args = append(args, criteria.Offset)
+	query := `
+		WITH purged AS (
+			DELETE FROM traces WHERE (id, start_time) IN (` + selection + `)
+			RETURNING id
+		), purged_spans AS (
+			DELETE FROM spans WHERE trace_id IN (SELECT id FROM purged)
+		)
+		SELECT COUNT(*) FROM purged
+	`
+
+	var deleted int64
+	if err := tr.db.QueryRowContext(ctx, query, args...).Scan(&deleted); err != nil {
+		return 0, fmt.Errorf("failed to purge traces: %w", err)
 	}
 
-	// Execute query
-	rows, err := tr.db.QueryContext(ctx, query, args...)
+	return deleted, nil
+}
+
+// PurgeStats counts the traces selected by the purge criteria
+func (tr *traceRepositoryPostgres) PurgeStats(ctx context.Context, criteria domain.PurgeCriteria) (domain.PurgeStats, error) {
+	if err := validatePurgeCriteria(criteria); err != nil {
+		return domain.PurgeStats{}, fmt.Errorf("invalid purge criteria: %w", err)
+	}
+
+	conditions, args := buildPurgeConditions(criteria)
+
+	var stats domain.PurgeStats
+	var oldest sql.NullTime
+	err := tr.db.QueryRowContext(ctx, `SELECT COUNT(*), MIN(start_time) FROM traces WHERE `+conditions, args...).Scan(&stats.Count, &oldest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query traces: %w", err)
+		return domain.PurgeStats{}, fmt.Errorf("failed to count purgeable traces: %w", err)
+	}
+	if oldest.Valid {
+		stats.Oldest = oldest.Time
+	}
+
+	return stats, nil
+}
+
+// buildPurgeConditions builds the WHERE conditions selecting traces for a purge
+func buildPurgeConditions(criteria domain.PurgeCriteria) (string, []interface{}) {
+	conditions := "start_time < $1"
+	args := []interface{}{criteria.Before}
+
+	if criteria.Service != "" {
+		args = append(args, criteria.Service)
+		conditions += fmt.Sprintf(" AND service = $%d", len(args))
+	}
+	if criteria.Status != "" {
+		args = append(args, criteria.Status)
+		conditions += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	for _, rule := range criteria.Exclude {
+		var match []string
+		if rule.Service != "" {
+			args = append(args, rule.Service)
+			match = append(match, fmt.Sprintf("service = $%d", len(args)))
+		}
+		if rule.Status != "" {
+			args = append(args, rule.Status)
+			match = append(match, fmt.Sprintf("status = $%d", len(args)))
+		}
+		if len(match) == 0 {
+			// A catch-all rule leaves nothing to select
+			match = append(match, "TRUE")
+		}
+		conditions += " AND NOT (" + strings.Join(match, " AND ") + ")"
+	}
+
+	return conditions, args
+}
+
+// SaveDependencies adds the links to the stored links of the same window,
+// caller and callee in one transaction
+func (tr *traceRepositoryPostgres) SaveDependencies(ctx context.Context, links []*domain.DependencyLink) error {
+	tx, err := tr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Buckets are added element-wise; a shorter array counts as zeros
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO dependencies (window_start, caller, callee, call_count, error_count, duration_sum, latency_buckets)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (window_start, caller, callee) DO UPDATE SET
+			call_count = dependencies.call_count + EXCLUDED.call_count,
+			error_count = dependencies.error_count + EXCLUDED.error_count,
+			duration_sum = dependencies.duration_sum + EXCLUDED.duration_sum,
+			latency_buckets = ARRAY(
+				SELECT COALESCE(stored, 0) + COALESCE(added, 0)
+				FROM unnest(dependencies.latency_buckets, EXCLUDED.latency_buckets) WITH ORDINALITY AS b(stored, added, i)
+				ORDER BY i
+			)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare dependency upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, link := range links {
+		_, err := stmt.ExecContext(ctx,
+			link.WindowStart,
+			link.Caller,
+			link.Callee,
+			link.CallCount,
+			link.ErrorCount,
+			link.DurationSum.Nanoseconds(),
+			pq.Int64Array(link.Latencies),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save dependency %s -> %s: %w", link.Caller, link.Callee, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// FindDependencies returns the stored links whose window starts within [start, end)
+func (tr *traceRepositoryPostgres) FindDependencies(ctx context.Context, start, end time.Time) ([]*domain.DependencyLink, error) {
+	query := `
+		SELECT window_start, caller, callee, call_count, error_count, duration_sum, latency_buckets
+		FROM dependencies
+		WHERE window_start >= $1 AND window_start < $2
+		ORDER BY window_start, caller, callee
+	`
+
+	rows, err := tr.db.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependencies: %w", err)
 	}
 	defer rows.Close()
 
-	var traces []*domain.Trace
+	links := []*domain.DependencyLink{}
 	for rows.Next() {
-		var trace domain.Trace
-		var tagsJSON []byte
+		var link domain.DependencyLink
+		var durationSum int64
+		var buckets pq.Int64Array
+		if err := rows.Scan(&link.WindowStart, &link.Caller, &link.Callee, &link.CallCount, &link.ErrorCount, &durationSum, &buckets); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		link.DurationSum = time.Duration(durationSum)
+		link.Latencies = domain.LatencyHistogram(buckets)
+		links = append(links, &link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dependencies: %w", err)
+	}
 
-		err := rows.Scan(
-			&trace.ID,
-			&trace.Service,
-			&trace.Operation,
-			&trace.StartTime,
-			&trace.EndTime,
-			&trace.Duration,
-			&trace.Status,
-			&tagsJSON,
-		)
+	return links, nil
+}
 
+// SaveSLO creates or replaces the SLO with the same ID
+func (tr *traceRepositoryPostgres) SaveSLO(ctx context.Context, slo *domain.SLO) error {
+	query := `
+		INSERT INTO slos (id, name, service, operation, threshold, target, objective_window, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			service = EXCLUDED.service,
+			operation = EXCLUDED.operation,
+			threshold = EXCLUDED.threshold,
+			target = EXCLUDED.target,
+			objective_window = EXCLUDED.objective_window,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := tr.db.ExecContext(ctx, query,
+		slo.ID,
+		slo.Name,
+		slo.Service,
+		slo.Operation,
+		slo.Threshold.Nanoseconds(),
+		slo.Target,
+		slo.Window.Nanoseconds(),
+		slo.CreatedAt.UTC(),
+		slo.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save slo: %w", err)
+	}
+
+	return nil
+}
+
+// sloColumns are the columns scanned by scanSLO
+const sloColumns = `id, name, service, operation, threshold, target, objective_window, created_at, updated_at`
+
+// scanSLO scans a row of sloColumns
+func scanSLO(row interface{ Scan(...interface{}) error }) (*domain.SLO, error) {
+	var slo domain.SLO
+	var threshold, window int64
+	if err := row.Scan(&slo.ID, &slo.Name, &slo.Service, &slo.Operation, &threshold, &slo.Target, &window, &slo.CreatedAt, &slo.UpdatedAt); err != nil {
+		return nil, err
+	}
+	slo.Threshold = time.Duration(threshold)
+	slo.Window = time.Duration(window)
+	return &slo, nil
+}
+
+// FindSLO returns the SLO with the given ID
+func (tr *traceRepositoryPostgres) FindSLO(ctx context.Context, id string) (*domain.SLO, error) {
+	slo, err := scanSLO(tr.db.QueryRowContext(ctx, `SELECT `+sloColumns+` FROM slos WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrSLONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find slo: %w", err)
+	}
+
+	return slo, nil
+}
+
+// ListSLOs returns every SLO ordered by name
+func (tr *traceRepositoryPostgres) ListSLOs(ctx context.Context) ([]*domain.SLO, error) {
+	rows, err := tr.db.QueryContext(ctx, `SELECT `+sloColumns+` FROM slos ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slos: %w", err)
+	}
+	defer rows.Close()
+
+	slos := []*domain.SLO{}
+	for rows.Next() {
+		slo, err := scanSLO(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan trace: %w", err)
+			return nil, fmt.Errorf("failed to scan slo: %w", err)
 		}
+		slos = append(slos, slo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read slos: %w", err)
+	}
 
-		// Parse tags
-		if err := json.Unmarshal(tagsJSON, &trace.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	return slos, nil
+}
+
+// DeleteSLO removes the SLO with the given ID
+func (tr *traceRepositoryPostgres) DeleteSLO(ctx context.Context, id string) error {
+	result, err := tr.db.ExecContext(ctx, `DELETE FROM slos WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete slo: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete slo: %w", err)
+	}
+	if deleted == 0 {
+		return domain.ErrSLONotFound
+	}
+
+	return nil
+}
+
+// AddSLOBuckets adds the counts of the buckets to the stored buckets of the
+// SLO with the same start
+func (tr *traceRepositoryPostgres) AddSLOBuckets(ctx context.Context, id string, buckets []domain.SLOBucket) error {
+	tx, err := tr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO slo_buckets (slo_id, bucket_start, total_count, good_count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (slo_id, bucket_start) DO UPDATE SET
+			total_count = slo_buckets.total_count + EXCLUDED.total_count,
+			good_count = slo_buckets.good_count + EXCLUDED.good_count
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare slo bucket upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, bucket := range buckets {
+		if _, err := stmt.ExecContext(ctx, id, bucket.Start.UTC(), bucket.Total, bucket.Good); err != nil {
+			return fmt.Errorf("failed to add slo bucket %s: %w", bucket.Start, err)
 		}
+	}
 
-		traces = append(traces, &trace)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return traces, nil
+	return nil
+}
+
+// FindSLOBuckets returns the stored buckets of the SLO starting at or after
+// since, ordered by start
+func (tr *traceRepositoryPostgres) FindSLOBuckets(ctx context.Context, id string, since time.Time) ([]domain.SLOBucket, error) {
+	query := `
+		SELECT bucket_start, total_count, good_count
+		FROM slo_buckets
+		WHERE slo_id = $1 AND bucket_start >= $2
+		ORDER BY bucket_start
+	`
+
+	rows, err := tr.db.QueryContext(ctx, query, id, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slo buckets: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []domain.SLOBucket{}
+	for rows.Next() {
+		var bucket domain.SLOBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Total, &bucket.Good); err != nil {
+			return nil, fmt.Errorf("failed to scan slo bucket: %w", err)
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read slo buckets: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// DeleteSLOBuckets removes the stored buckets of the SLO starting before
+// the given time, or every bucket for the zero time
+func (tr *traceRepositoryPostgres) DeleteSLOBuckets(ctx context.Context, id string, before time.Time) error {
+	var err error
+	if before.IsZero() {
+		_, err = tr.db.ExecContext(ctx, `DELETE FROM slo_buckets WHERE slo_id = $1`, id)
+	} else {
+		_, err = tr.db.ExecContext(ctx, `DELETE FROM slo_buckets WHERE slo_id = $1 AND bucket_start < $2`, id, before.UTC())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete slo buckets: %w", err)
+	}
+
+	return nil
 }
 
 // GetServices returns all available services
@@ -506,10 +1320,110 @@ func (tr *traceRepositoryPostgres) validateSearchCriteria(criteria *domain.Searc
 			return fmt.Errorf("start time cannot be after end time")
 		}
 	}
+	if err := validateDurationRange(criteria.MinDuration, criteria.MaxDuration); err != nil {
+		return err
+	}
+	if err := validateSortOptions(criteria); err != nil {
+		return err
+	}
+	for i, filter := range criteria.SpanFilters {
+		if filter.IsEmpty() {
+			return fmt.Errorf("span filter %d has no conditions", i)
+		}
+		if err := validateDurationRange(filter.MinDuration, filter.MaxDuration); err != nil {
+			return fmt.Errorf("span filter %d: %w", i, err)
+		}
+	}
 	return nil
 }
 
-// Close closes the database connection
+// validateSortOptions validates the sort field, order and cursor
+func validateSortOptions(criteria *domain.SearchCriteria) error {
+	switch criteria.SortField() {
+	case domain.SortByStartTime, domain.SortByDuration:
+	default:
+		return fmt.Errorf("unknown sort field %q", criteria.SortBy)
+	}
+	switch criteria.SortDirection() {
+	case domain.SortAscending, domain.SortDescending:
+	default:
+		return fmt.Errorf("unknown sort order %q", criteria.SortOrder)
+	}
+	if criteria.Cursor != nil {
+		if criteria.Cursor.SortBy != criteria.SortField() || criteria.Cursor.SortOrder != criteria.SortDirection() {
+			return fmt.Errorf("%w: cursor was issued for a different sort order", domain.ErrInvalidCursor)
+		}
+		if criteria.Offset > 0 {
+			return fmt.Errorf("offset cannot be combined with a cursor")
+		}
+	}
+	return nil
+}
+
+// validatePurgeCriteria validates purge criteria
+func validatePurgeCriteria(criteria domain.PurgeCriteria) error {
+	if criteria.Before.IsZero() {
+		return fmt.Errorf("purge cutoff is required")
+	}
+	if criteria.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative")
+	}
+	return nil
+}
+
+// validateDurationRange validates an optional duration range
+func validateDurationRange(min, max *time.Duration) error {
+	if min != nil && *min < 0 {
+		return fmt.Errorf("min duration cannot be negative")
+	}
+	if max != nil && *max < 0 {
+		return fmt.Errorf("max duration cannot be negative")
+	}
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("min duration cannot be greater than max duration")
+	}
+	return nil
+}
+
+// Close stops partition maintenance and closes the database connection.
+// Later calls return the result of the first one.
 func (tr *traceRepositoryPostgres) Close() error {
-	return tr.db.Close()
+	tr.closeOnce.Do(func() {
+		close(tr.stop)
+		<-tr.done
+
+		tr.closeErr = tr.db.Close()
+	})
+	return tr.closeErr
+}
+
+// maintain creates upcoming partitions and drops expired ones, along with
+// expired minute rollups, until Close is called
+func (tr *traceRepositoryPostgres) maintain(interval time.Duration) {
+	defer close(tr.done)
+
+	if interval <= 0 {
+		<-tr.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tr.stop:
+			return
+		case <-ticker.C:
+			if err := tr.partitions.EnsureUpcoming(context.Background()); err != nil {
+				fmt.Printf("Failed to create trace partitions: %v\n", err)
+			}
+			if _, err := tr.partitions.DropExpired(context.Background()); err != nil {
+				fmt.Printf("Failed to drop expired trace partitions: %v\n", err)
+			}
+			if _, err := tr.deleteExpiredRollups(context.Background()); err != nil {
+				fmt.Printf("Failed to delete expired trace rollups: %v\n", err)
+			}
+		}
+	}
 }
@@ -0,0 +1,189 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// The benchmarks need a scratch database in TEST_POSTGRES_DSN; `make
+// bench-storage` starts one. RowByRow is the write path before COPY
+// batching, kept here as the baseline.
+
+func newBenchPostgresRepository(b *testing.B) *traceRepositoryPostgres {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		b.Skip("TEST_POSTGRES_DSN not set, skipping benchmark that requires PostgreSQL database")
+	}
+
+	repo, err := NewTraceRepositoryPostgres(PostgresRepositoryConfig{DSN: dsn, AutoMigrate: true}, &MockJaegerExporter{})
+	require.NoError(b, err)
+
+	postgresRepo := repo.(*traceRepositoryPostgres)
+	b.Cleanup(func() { postgresRepo.Close() })
+
+	_, err = postgresRepo.db.Exec(`TRUNCATE traces, spans`)
+	require.NoError(b, err)
+	return postgresRepo
+}
+
+func newBenchTrace(id string, spans int) *domain.Trace {
+	start := time.Now().Add(-time.Minute)
+	trace := &domain.Trace{
+		ID:        domain.TraceID(id),
+		Service:   "checkout",
+		Operation: "POST /orders",
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Duration:  time.Second,
+		Status:    domain.TraceStatusSuccess,
+		Tags:      map[string]string{"env": "bench"},
+		Spans:     make([]domain.Span, spans),
+	}
+
+	for i := range trace.Spans {
+		trace.Spans[i] = domain.Span{
+			ID:        domain.SpanID(fmt.Sprintf("%s-%d", id, i)),
+			TraceID:   trace.ID,
+			Service:   "checkout",
+			Operation: "SELECT orders",
+			StartTime: start.Add(time.Duration(i) * time.Microsecond),
+			EndTime:   start.Add(time.Duration(i)*time.Microsecond + time.Millisecond),
+			Duration:  time.Millisecond,
+			Status:    domain.SpanStatusOK,
+			Tags:      map[string]string{"db.system": "postgresql"},
+		}
+	}
+	return trace
+}
+
+// saveRowByRow saves a trace with one INSERT per span, like Save did
+// before writes were batched
+func saveRowByRow(ctx context.Context, tr *traceRepositoryPostgres, trace *domain.Trace) error {
+	times := []time.Time{trace.StartTime}
+	for _, span := range trace.Spans {
+		times = append(times, span.StartTime)
+	}
+	if err := tr.partitions.EnsureFor(ctx, times...); err != nil {
+		return err
+	}
+
+	tx, err := tr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM traces WHERE id = $1`, trace.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM spans WHERE trace_id = $1`, trace.ID); err != nil {
+		return err
+	}
+
+	tagsJSON, err := json.Marshal(trace.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO traces (id, service, operation, start_time, end_time, duration, status, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, trace.ID, trace.Service, trace.Operation, trace.StartTime, trace.EndTime, trace.Duration.Nanoseconds(), trace.Status, tagsJSON)
+	if err != nil {
+		return err
+	}
+
+	for _, span := range trace.Spans {
+		tagsJSON, err := json.Marshal(span.Tags)
+		if err != nil {
+			return err
+		}
+		logsJSON, err := json.Marshal(span.Logs)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO spans (id, trace_id, parent_id, service, operation, start_time, end_time, duration, status, tags, logs)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (trace_id, id, start_time) DO NOTHING
+		`, span.ID, span.TraceID, nil, span.Service, span.Operation, span.StartTime, span.EndTime, span.Duration.Nanoseconds(), span.Status, tagsJSON, logsJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// benchmarkSpanWrites writes batches of traces with the given function and
+// reports the span throughput
+func benchmarkSpanWrites(b *testing.B, tracesPerOp, spansPerTrace int, write func(ctx context.Context, repo *traceRepositoryPostgres, traces []*domain.Trace) error) {
+	repo := newBenchPostgresRepository(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		traces := make([]*domain.Trace, tracesPerOp)
+		for i := range traces {
+			traces[i] = newBenchTrace(fmt.Sprintf("bench-%d-%d", n, i), spansPerTrace)
+		}
+		b.StartTimer()
+
+		if err := write(ctx, repo, traces); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(b.N*tracesPerOp*spansPerTrace)/b.Elapsed().Seconds(), "spans/s")
+}
+
+func BenchmarkTraceRepositoryPostgres_SpanWrites(b *testing.B) {
+	rowByRow := func(ctx context.Context, repo *traceRepositoryPostgres, traces []*domain.Trace) error {
+		for _, trace := range traces {
+			if err := saveRowByRow(ctx, repo, trace); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	save := func(ctx context.Context, repo *traceRepositoryPostgres, traces []*domain.Trace) error {
+		for _, trace := range traces {
+			if err := repo.Save(ctx, trace); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	saveBatch := func(ctx context.Context, repo *traceRepositoryPostgres, traces []*domain.Trace) error {
+		return repo.SaveBatch(ctx, traces)
+	}
+
+	shapes := []struct {
+		name          string
+		tracesPerOp   int
+		spansPerTrace int
+	}{
+		{name: "large_traces", tracesPerOp: 1, spansPerTrace: 2000},
+		{name: "burst", tracesPerOp: 200, spansPerTrace: 10},
+	}
+
+	for _, shape := range shapes {
+		b.Run(shape.name+"/RowByRow", func(b *testing.B) {
+			benchmarkSpanWrites(b, shape.tracesPerOp, shape.spansPerTrace, rowByRow)
+		})
+		b.Run(shape.name+"/Save", func(b *testing.B) {
+			benchmarkSpanWrites(b, shape.tracesPerOp, shape.spansPerTrace, save)
+		})
+		b.Run(shape.name+"/SaveBatch", func(b *testing.B) {
+			benchmarkSpanWrites(b, shape.tracesPerOp, shape.spansPerTrace, saveBatch)
+		})
+	}
+}
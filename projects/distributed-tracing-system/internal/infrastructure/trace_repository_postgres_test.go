@@ -2,10 +2,13 @@ package infrastructure
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure/repotest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,25 +20,235 @@ func (m *MockJaegerExporter) ExportTrace(ctx context.Context, trace *domain.Trac
 	return nil
 }
 
-func TestTraceRepositoryPostgres_NewTraceRepositoryPostgres(t *testing.T) {
-	// This test requires a real PostgreSQL database
-	// In a real test environment, you would use testcontainers or a test database
-	t.Skip("Skipping test that requires PostgreSQL database")
+// newTestPostgresRepository connects to the database in TEST_POSTGRES_DSN
+// (see `make test-contract`) and empties it, skipping the test when unset.
+// The database must be dedicated to tests.
+func newTestPostgresRepository(t *testing.T) domain.TraceRepository {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set, skipping test that requires PostgreSQL database")
+	}
+
+	repo, err := NewTraceRepositoryPostgres(PostgresRepositoryConfig{DSN: dsn, AutoMigrate: true}, &MockJaegerExporter{})
+	require.NoError(t, err)
+
+	postgresRepo := repo.(*traceRepositoryPostgres)
+	t.Cleanup(func() { postgresRepo.Close() })
+
+	_, err = postgresRepo.db.Exec(`TRUNCATE traces, spans, dependencies, trace_rollups, slos, slo_buckets`)
+	require.NoError(t, err)
+	return repo
 }
 
-func TestTraceRepositoryPostgres_Save(t *testing.T) {
-	// This test requires a real PostgreSQL database
-	t.Skip("Skipping test that requires PostgreSQL database")
+func TestTraceRepositoryPostgres_Contract(t *testing.T) {
+	repotest.RunTraceRepositoryContract(t, newTestPostgresRepository)
 }
 
-func TestTraceRepositoryPostgres_FindByID(t *testing.T) {
-	// This test requires a real PostgreSQL database
-	t.Skip("Skipping test that requires PostgreSQL database")
+func TestTraceRepositoryPostgres_PurgerContract(t *testing.T) {
+	repotest.RunTracePurgerContract(t, newTestPostgresRepository)
 }
 
-func TestTraceRepositoryPostgres_Search(t *testing.T) {
-	// This test requires a real PostgreSQL database
-	t.Skip("Skipping test that requires PostgreSQL database")
+func TestTraceRepositoryPostgres_ReplacerContract(t *testing.T) {
+	repotest.RunTraceReplacerContract(t, newTestPostgresRepository)
+}
+
+func TestTraceRepositoryPostgres_DependencyStoreContract(t *testing.T) {
+	repotest.RunDependencyStoreContract(t, newTestPostgresRepository)
+}
+
+func TestTraceRepositoryPostgres_SLOStoreContract(t *testing.T) {
+	repotest.RunSLOStoreContract(t, newTestPostgresRepository)
+}
+
+func TestTraceRepositoryPostgres_RollupStoreContract(t *testing.T) {
+	repotest.RunTraceRollupStoreContract(t, newTestPostgresRepository)
+}
+
+func TestBuildPurgeConditions(t *testing.T) {
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	conditions, args := buildPurgeConditions(domain.PurgeCriteria{
+		Status: domain.TraceStatusError,
+		Exclude: []domain.RetentionRule{
+			{Service: "audit"},
+			{Service: "checkout", Status: domain.TraceStatusError},
+			{},
+		},
+		Before: before,
+	})
+
+	assert.Equal(t, "start_time < $1 AND status = $2 AND NOT (service = $3) AND NOT (service = $4 AND status = $5) AND NOT (TRUE)", conditions)
+	assert.Equal(t, []interface{}{
+		before,
+		domain.TraceStatusError,
+		domain.ServiceName("audit"),
+		domain.ServiceName("checkout"),
+		domain.TraceStatusError,
+	}, args)
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	service := domain.ServiceName("checkout")
+	spanService := domain.ServiceName("payments")
+	spanStatus := domain.SpanStatusError
+	minDuration := 100 * time.Millisecond
+	maxDuration := 2 * time.Second
+
+	criteria := &domain.SearchCriteria{
+		Service:     &service,
+		MinDuration: &minDuration,
+		MaxDuration: &maxDuration,
+		Tags:        map[string]string{"env": "prod"},
+		SpanFilters: []domain.SpanFilter{
+			{Service: &spanService, Status: &spanStatus},
+		},
+		Limit:  20,
+		Offset: 40,
+	}
+
+	query, args, err := buildSearchQuery(criteria)
+	require.NoError(t, err)
+
+	assert.Contains(t, query, "service = $1")
+	assert.Contains(t, query, "duration >= $2")
+	assert.Contains(t, query, "duration <= $3")
+	assert.Contains(t, query, "tags @> $4::jsonb")
+	assert.Contains(t, query, "EXISTS (SELECT 1 FROM spans s WHERE s.trace_id = traces.id AND s.service = $5 AND s.status = $6)")
+	assert.Contains(t, query, "LIMIT $7")
+	assert.Contains(t, query, "OFFSET $8")
+	assert.Equal(t, []interface{}{
+		service,
+		minDuration.Nanoseconds(),
+		maxDuration.Nanoseconds(),
+		`{"env":"prod"}`,
+		spanService,
+		spanStatus,
+		20,
+		40,
+	}, args)
+}
+
+func TestBuildSearchQuery_Keyset(t *testing.T) {
+	service := domain.ServiceName("checkout")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	criteria := &domain.SearchCriteria{
+		Service: &service,
+		Cursor: &domain.TraceCursor{
+			SortBy:    domain.SortByStartTime,
+			SortOrder: domain.SortDescending,
+			Value:     start.UnixNano(),
+			TraceID:   "trace9",
+		},
+		Limit: 20,
+	}
+
+	query, args, err := buildSearchQuery(criteria)
+	require.NoError(t, err)
+	assert.Contains(t, query, "AND (start_time, id) < ($2, $3) ORDER BY start_time DESC, id DESC LIMIT $4")
+	assert.Equal(t, []interface{}{service, start, domain.TraceID("trace9"), 20}, args)
+
+	criteria = &domain.SearchCriteria{
+		SortBy:    domain.SortByDuration,
+		SortOrder: domain.SortAscending,
+		Cursor: &domain.TraceCursor{
+			SortBy:    domain.SortByDuration,
+			SortOrder: domain.SortAscending,
+			Value:     int64(time.Second),
+			TraceID:   "trace9",
+		},
+		Limit: 20,
+	}
+
+	query, args, err = buildSearchQuery(criteria)
+	require.NoError(t, err)
+	assert.Contains(t, query, "AND (duration, id) > ($1, $2) ORDER BY duration ASC, id ASC LIMIT $3")
+	assert.Equal(t, []interface{}{int64(time.Second), domain.TraceID("trace9"), 20}, args)
+}
+
+func TestBuildTraceQuery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	query, err := domain.ParseTraceQuery(`service=checkout OR status=error`)
+	require.NoError(t, err)
+
+	sqlQuery, args, err := buildTraceQuery(query, domain.TracePage{Limit: 20, Offset: 40})
+	require.NoError(t, err)
+	assert.Contains(t, sqlQuery, "WHERE (service = $1 OR status = $2) ORDER BY start_time DESC, id DESC LIMIT $3 OFFSET $4")
+	assert.Equal(t, []interface{}{"checkout", "error", 20, 40}, args)
+
+	// A cursor continues strictly after the last trace of the previous page
+	cursor := domain.NewTraceCursor(&domain.Trace{ID: "trace-1", StartTime: start}, domain.SortByStartTime, domain.SortDescending)
+	sqlQuery, args, err = buildTraceQuery(query, domain.TracePage{Limit: 20, Cursor: &cursor})
+	require.NoError(t, err)
+	assert.Contains(t, sqlQuery, "(service = $1 OR status = $2) AND (start_time, id) < ($3, $4) ORDER BY start_time DESC, id DESC LIMIT $5")
+	assert.Equal(t, []interface{}{"checkout", "error", start, domain.TraceID("trace-1"), 20}, args)
+}
+
+func TestTraceRepositoryPostgres_ValidateSearchCriteria(t *testing.T) {
+	repo := &traceRepositoryPostgres{}
+	short := time.Millisecond
+	long := time.Second
+	negative := -time.Second
+
+	tests := []struct {
+		name     string
+		criteria *domain.SearchCriteria
+		errMsg   string
+	}{
+		{
+			name:     "valid duration range",
+			criteria: &domain.SearchCriteria{MinDuration: &short, MaxDuration: &long},
+		},
+		{
+			name:     "inverted duration range",
+			criteria: &domain.SearchCriteria{MinDuration: &long, MaxDuration: &short},
+			errMsg:   "min duration cannot be greater than max duration",
+		},
+		{
+			name:     "negative duration",
+			criteria: &domain.SearchCriteria{MinDuration: &negative},
+			errMsg:   "min duration cannot be negative",
+		},
+		{
+			name:     "empty span filter",
+			criteria: &domain.SearchCriteria{SpanFilters: []domain.SpanFilter{{}}},
+			errMsg:   "span filter 0 has no conditions",
+		},
+		{
+			name: "cursor for another sort order",
+			criteria: &domain.SearchCriteria{
+				SortBy: domain.SortByDuration,
+				Cursor: &domain.TraceCursor{SortBy: domain.SortByStartTime, SortOrder: domain.SortDescending, TraceID: "t"},
+			},
+			errMsg: "cursor was issued for a different sort order",
+		},
+		{
+			name: "cursor with offset",
+			criteria: &domain.SearchCriteria{
+				Offset: 10,
+				Cursor: &domain.TraceCursor{SortBy: domain.SortByStartTime, SortOrder: domain.SortDescending, TraceID: "t"},
+			},
+			errMsg: "offset cannot be combined with a cursor",
+		},
+		{
+			name: "inverted span duration range",
+			criteria: &domain.SearchCriteria{SpanFilters: []domain.SpanFilter{
+				{MinDuration: &long, MaxDuration: &short},
+			}},
+			errMsg: "span filter 0: min duration cannot be greater than max duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.validateSearchCriteria(tt.criteria)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
 }
 
 func TestTraceRepositoryPostgres_GetServices(t *testing.T) {
@@ -57,7 +270,7 @@ func TestTraceRepositoryPostgres_Integration(t *testing.T) {
 	mockJaeger := &MockJaegerExporter{}
 	
 	// Create repository
-	repo, err := NewTraceRepositoryPostgres(dsn, mockJaeger)
+	repo, err := NewTraceRepositoryPostgres(PostgresRepositoryConfig{DSN: dsn, AutoMigrate: true}, mockJaeger)
 	if err != nil {
 		t.Skip("Skipping integration test - database not available")
 	}
@@ -136,3 +349,149 @@ func TestTraceRepositoryPostgres_Integration(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, operations, trace.Operation)
 }
+
+func TestMergeTraces(t *testing.T) {
+	first := &domain.Trace{ID: "a", Operation: "first", Spans: []domain.Span{{ID: "root"}, {ID: "db", Operation: "SELECT"}}}
+	other := &domain.Trace{ID: "b"}
+	second := &domain.Trace{ID: "a", Operation: "second", Spans: []domain.Span{{ID: "db", Operation: "UPDATE"}, {ID: "cache"}}}
+
+	merged := mergeTraces([]*domain.Trace{first, other, second})
+
+	require.Len(t, merged, 2)
+	assert.Equal(t, domain.OperationName("second"), merged[0].Operation)
+	assert.Equal(t, []domain.Span{{ID: "root"}, {ID: "db", Operation: "UPDATE"}, {ID: "cache"}}, merged[0].Spans)
+	assert.Same(t, other, merged[1])
+	assert.Len(t, second.Spans, 2, "inputs should not be modified")
+}
+
+func TestTraceRepositoryPostgres_SaveBatch(t *testing.T) {
+	repo := newTestPostgresRepository(t).(*traceRepositoryPostgres)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	newTrace := func(id domain.TraceID, operation domain.OperationName, spanIDs ...domain.SpanID) *domain.Trace {
+		trace := &domain.Trace{
+			ID:        id,
+			Service:   "checkout",
+			Operation: operation,
+			StartTime: start,
+			EndTime:   start.Add(time.Second),
+			Duration:  time.Second,
+			Status:    domain.TraceStatusSuccess,
+		}
+		for _, spanID := range spanIDs {
+			trace.Spans = append(trace.Spans, domain.Span{
+				ID:        spanID,
+				TraceID:   id,
+				Service:   "checkout",
+				Operation: "charge",
+				StartTime: start,
+				EndTime:   start.Add(time.Millisecond),
+				Duration:  time.Millisecond,
+				Status:    domain.SpanStatusOK,
+			})
+		}
+		return trace
+	}
+
+	// Spans are merged into a previous save and across the versions of a
+	// trace repeated in the batch, whose last version gives the trace fields
+	require.NoError(t, repo.Save(ctx, newTrace("trace-a", "old", "stale")))
+	err := repo.SaveBatch(ctx, []*domain.Trace{
+		newTrace("trace-a", "first", "span-1", "span-3"),
+		newTrace("trace-b", "other", "span-1", "span-2", "span-2"),
+		newTrace("trace-a", "second", "span-1", "span-2"),
+	})
+	require.NoError(t, err)
+
+	traceA, err := repo.FindByID(ctx, "trace-a")
+	require.NoError(t, err)
+	assert.Equal(t, domain.OperationName("second"), traceA.Operation)
+	assert.Len(t, traceA.Spans, 4)
+
+	traceB, err := repo.FindByID(ctx, "trace-b")
+	require.NoError(t, err)
+	assert.Len(t, traceB.Spans, 2)
+
+	// An invalid trace fails the whole batch
+	err = repo.SaveBatch(ctx, []*domain.Trace{newTrace("trace-c", "ok"), {ID: "trace-d"}})
+	assert.Error(t, err)
+	_, err = repo.FindByID(ctx, "trace-c")
+	assert.ErrorIs(t, err, domain.ErrTraceNotFound)
+}
+
+func TestMergeRollups(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	first := domain.NewTraceRollup(domain.RollupMinute, start, "checkout", "pay")
+	first.TraceCount = 1
+	second := domain.NewTraceRollup(domain.RollupMinute, start.Add(10*time.Second), "checkout", "pay")
+	second.TraceCount = 2
+	other := domain.NewTraceRollup(domain.RollupHour, start, "checkout", "pay")
+	other.TraceCount = 4
+
+	merged := mergeRollups([]*domain.TraceRollup{first, other, second})
+
+	require.Len(t, merged, 2)
+	assert.Equal(t, domain.RollupMinute, merged[0].Resolution)
+	assert.Equal(t, int64(3), merged[0].TraceCount)
+	assert.Equal(t, int64(1), first.TraceCount, "inputs should not be modified")
+	assert.Equal(t, int64(4), merged[1].TraceCount)
+}
+
+func TestTraceRepositoryPostgres_Rollups(t *testing.T) {
+	repo := newTestPostgresRepository(t).(*traceRepositoryPostgres)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)
+
+	newTrace := func(id domain.TraceID, status domain.TraceStatus, duration time.Duration, spans int) *domain.Trace {
+		trace := &domain.Trace{
+			ID:        id,
+			Service:   "checkout",
+			Operation: "pay",
+			StartTime: start,
+			EndTime:   start.Add(duration),
+			Duration:  duration,
+			Status:    status,
+		}
+		for i := 0; i < spans; i++ {
+			trace.Spans = append(trace.Spans, domain.Span{
+				ID:        domain.SpanID(fmt.Sprintf("span-%d", i)),
+				TraceID:   id,
+				Service:   "checkout",
+				Operation: "charge",
+				StartTime: start,
+				EndTime:   start.Add(time.Millisecond),
+				Duration:  time.Millisecond,
+				Status:    domain.SpanStatusOK,
+			})
+		}
+		return trace
+	}
+
+	require.NoError(t, repo.SaveBatch(ctx, []*domain.Trace{
+		newTrace("trace-a", domain.TraceStatusError, time.Second, 3),
+		newTrace("trace-b", domain.TraceStatusSuccess, 20*time.Millisecond, 1),
+	}))
+	// Saving a trace again replaces its contribution; its spans are merged,
+	// so all three stay counted
+	require.NoError(t, repo.Save(ctx, newTrace("trace-a", domain.TraceStatusSuccess, 10*time.Millisecond, 2)))
+
+	query := domain.MetricsQuery{Start: start.Truncate(time.Hour), End: start.Add(time.Hour)}
+	for _, resolution := range domain.RollupResolutions {
+		rollups, err := repo.FindRollups(ctx, resolution, query)
+		require.NoError(t, err)
+		require.Len(t, rollups, 1, "resolution %s", resolution)
+
+		rollup := rollups[0]
+		assert.Equal(t, start.Truncate(resolution), rollup.BucketStart.UTC())
+		assert.Equal(t, int64(2), rollup.TraceCount)
+		assert.Equal(t, int64(4), rollup.SpanCount)
+		assert.Equal(t, int64(0), rollup.ErrorCount)
+		assert.Equal(t, 30*time.Millisecond, rollup.DurationSum)
+		assert.Equal(t, int64(2), rollup.Durations.Count())
+	}
+
+	rollups, err := repo.FindRollups(ctx, domain.RollupMinute, domain.MetricsQuery{Start: query.Start, End: query.End, Service: "cart"})
+	require.NoError(t, err)
+	assert.Empty(t, rollups)
+}
@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// webhookAlertPublisher delivers SLO alerts as JSON POST requests
+type webhookAlertPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAlertPublisher creates a publisher that posts alerts to a URL
+func NewWebhookAlertPublisher(url string, timeout time.Duration) (domain.AlertPublisher, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL cannot be empty")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("webhook timeout must be positive")
+	}
+
+	return &webhookAlertPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// PublishAlert posts an SLO alert. Any response other than 2xx is an error.
+func (p *webhookAlertPublisher) PublishAlert(ctx context.Context, alert *domain.SLOAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slo alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver slo alert: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSLOAlert() *domain.SLOAlert {
+	return &domain.SLOAlert{
+		SLOID:        "slo-1",
+		SLOName:      "checkout latency",
+		Service:      "checkout",
+		State:        domain.AlertStateFiring,
+		Severity:     domain.AlertSeverityPage,
+		LongWindow:   time.Hour,
+		ShortWindow:  5 * time.Minute,
+		Threshold:    14.4,
+		LongBurnRate: 20,
+		Timestamp:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookAlertPublisher_PublishAlert(t *testing.T) {
+	var received domain.SLOAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	publisher, err := NewWebhookAlertPublisher(server.URL, time.Second)
+	require.NoError(t, err)
+
+	alert := testSLOAlert()
+	require.NoError(t, publisher.PublishAlert(context.Background(), alert))
+	assert.Equal(t, *alert, received)
+}
+
+func TestWebhookAlertPublisher_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	publisher, err := NewWebhookAlertPublisher(server.URL, time.Second)
+	require.NoError(t, err)
+
+	err = publisher.PublishAlert(context.Background(), testSLOAlert())
+	assert.ErrorContains(t, err, "status 500")
+}
+
+func TestNewWebhookAlertPublisher_InvalidConfig(t *testing.T) {
+	_, err := NewWebhookAlertPublisher("", time.Second)
+	assert.Error(t, err)
+
+	_, err = NewWebhookAlertPublisher("http://localhost", 0)
+	assert.Error(t, err)
+}
+
+func TestAlertMessage(t *testing.T) {
+	alert := testSLOAlert()
+	message, err := alertMessage(alert)
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte("slo-1"), message.Key)
+	assert.Equal(t, alert.Timestamp, message.Time)
+	headers := make(map[string]string)
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	assert.Equal(t, map[string]string{"service": "checkout", "severity": "page", "state": "firing"}, headers)
+
+	var decoded domain.SLOAlert
+	require.NoError(t, json.Unmarshal(message.Value, &decoded))
+	assert.Equal(t, *alert, decoded)
+
+	_, err = alertMessage(&domain.SLOAlert{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// zipkinExporter implements the JaegerExporter interface by posting the
+// spans of a trace to a Zipkin v2 collector
+type zipkinExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewZipkinExporter creates an exporter posting to a Zipkin v2 spans
+// endpoint, e.g. http://zipkin:9411/api/v2/spans
+func NewZipkinExporter(endpoint string, timeout time.Duration) (domain.JaegerExporter, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("zipkin endpoint is required")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("zipkin timeout must be positive")
+	}
+
+	return &zipkinExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// ExportTrace posts the spans of a trace as a Zipkin v2 JSON array. Any
+// response other than 2xx is an error.
+func (ze *zipkinExporter) ExportTrace(ctx context.Context, trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("invalid trace: trace cannot be nil")
+	}
+	if trace.ID == "" {
+		return fmt.Errorf("invalid trace: trace ID is required")
+	}
+
+	body, err := json.Marshal(TracesToZipkin([]*domain.Trace{trace}))
+	if err != nil {
+		return fmt.Errorf("failed to marshal zipkin spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ze.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create zipkin request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ze.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send zipkin request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("zipkin endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
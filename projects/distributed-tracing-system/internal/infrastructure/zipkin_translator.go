@@ -0,0 +1,311 @@
+package infrastructure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+const (
+	// zipkinErrorTag is the Zipkin tag marking a failed span; its value is
+	// the error message
+	zipkinErrorTag = "error"
+	// Span tags holding the addresses of the local and remote endpoints,
+	// named after the OpenTelemetry conventions
+	zipkinLocalIPTag     = "net.host.ip"
+	zipkinLocalPortTag   = "net.host.port"
+	zipkinPeerServiceTag = "peer.service"
+	zipkinPeerIPTag      = "net.peer.ip"
+	zipkinPeerPortTag    = "net.peer.port"
+	// zipkinSharedTag marks the server half of a shared span, which is
+	// stored as a child of the client half
+	zipkinSharedTag = "zipkin.shared"
+)
+
+// ZipkinSpan is a span in the Zipkin v2 JSON model
+type ZipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId,omitempty"`
+	Name           string             `json:"name,omitempty"`
+	Kind           string             `json:"kind,omitempty"`
+	Timestamp      int64              `json:"timestamp,omitempty"`
+	Duration       int64              `json:"duration,omitempty"`
+	LocalEndpoint  *ZipkinEndpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *ZipkinEndpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []ZipkinAnnotation `json:"annotations,omitempty"`
+	Tags           map[string]string  `json:"tags,omitempty"`
+	Debug          bool               `json:"debug,omitempty"`
+	Shared         bool               `json:"shared,omitempty"`
+}
+
+// ZipkinEndpoint is the network location of a Zipkin span's service
+type ZipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+// ZipkinAnnotation is a timestamped event of a Zipkin span
+type ZipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// ZipkinToTraces converts Zipkin v2 spans into domain traces grouped by
+// trace ID. It returns the assembled traces and the number of spans that
+// were rejected because they could not be mapped.
+func ZipkinToTraces(spans []ZipkinSpan) ([]*domain.Trace, int) {
+	spansByTrace := make(map[domain.TraceID][]domain.Span)
+	var order []domain.TraceID
+	rejected := 0
+
+	for _, zipkinSpan := range spans {
+		span, err := zipkinSpanToDomain(zipkinSpan)
+		if err != nil {
+			rejected++
+			continue
+		}
+
+		if _, ok := spansByTrace[span.TraceID]; !ok {
+			order = append(order, span.TraceID)
+		}
+		spansByTrace[span.TraceID] = append(spansByTrace[span.TraceID], span)
+	}
+
+	traces := make([]*domain.Trace, 0, len(order))
+	for _, traceID := range order {
+		traces = append(traces, domain.BuildTrace(traceID, spansByTrace[traceID]))
+	}
+
+	return traces, rejected
+}
+
+// zipkinSpanToDomain converts a single Zipkin span into a domain span
+func zipkinSpanToDomain(zipkinSpan ZipkinSpan) (domain.Span, error) {
+	if zipkinSpan.TraceID == "" || strings.Trim(zipkinSpan.TraceID, "0") == "" {
+		return domain.Span{}, fmt.Errorf("trace ID is required")
+	}
+	if zipkinSpan.ID == "" || strings.Trim(zipkinSpan.ID, "0") == "" {
+		return domain.Span{}, fmt.Errorf("span ID is required")
+	}
+
+	startTime := time.UnixMicro(zipkinSpan.Timestamp).UTC()
+	duration := time.Duration(zipkinSpan.Duration) * time.Microsecond
+	if duration < 0 {
+		duration = 0
+	}
+
+	service := domain.ServiceName(otlpUnknownService)
+	if zipkinSpan.LocalEndpoint != nil && zipkinSpan.LocalEndpoint.ServiceName != "" {
+		service = domain.ServiceName(zipkinSpan.LocalEndpoint.ServiceName)
+	}
+
+	span := domain.Span{
+		ID:        domain.SpanID(strings.ToLower(zipkinSpan.ID)),
+		TraceID:   domain.TraceID(strings.ToLower(zipkinSpan.TraceID)),
+		Service:   service,
+		Operation: domain.OperationName(zipkinSpan.Name),
+		StartTime: startTime,
+		EndTime:   startTime.Add(duration),
+		Duration:  duration,
+		Tags:      make(map[string]string, len(zipkinSpan.Tags)+6),
+		Status:    domain.SpanStatusOK,
+	}
+
+	if zipkinSpan.ParentID != "" && strings.Trim(zipkinSpan.ParentID, "0") != "" {
+		parentID := domain.SpanID(strings.ToLower(zipkinSpan.ParentID))
+		span.ParentID = &parentID
+	}
+
+	// In the shared span model the client and server halves of an RPC have
+	// the same ID; the server half gets an ID of its own under the client
+	if zipkinSpan.Shared {
+		clientID := span.ID
+		span.ID = zipkinSharedSpanID(clientID)
+		span.ParentID = &clientID
+		span.Tags[zipkinSharedTag] = "true"
+	}
+
+	for key, value := range zipkinSpan.Tags {
+		span.Tags[key] = value
+	}
+	if _, ok := zipkinSpan.Tags[zipkinErrorTag]; ok {
+		span.Status = domain.SpanStatusError
+	}
+	if zipkinSpan.Kind != "" {
+		span.Tags[domain.SpanKindTag] = strings.ToLower(zipkinSpan.Kind)
+	}
+	if local := zipkinSpan.LocalEndpoint; local != nil {
+		setEndpointTags(span.Tags, local, zipkinLocalIPTag, zipkinLocalPortTag)
+	}
+	if remote := zipkinSpan.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			span.Tags[zipkinPeerServiceTag] = remote.ServiceName
+		}
+		setEndpointTags(span.Tags, remote, zipkinPeerIPTag, zipkinPeerPortTag)
+	}
+
+	for _, annotation := range zipkinSpan.Annotations {
+		span.Logs = append(span.Logs, domain.Log{
+			Timestamp: time.UnixMicro(annotation.Timestamp).UTC(),
+			Message:   annotation.Value,
+			Fields:    map[string]string{},
+		})
+	}
+
+	return span, nil
+}
+
+// zipkinSharedSpanID derives the ID of the server half of a shared span
+// from the ID it shares with the client half
+func zipkinSharedSpanID(clientID domain.SpanID) domain.SpanID {
+	sum := sha256.Sum256([]byte("shared:" + string(clientID)))
+	return domain.SpanID(hex.EncodeToString(sum[:8]))
+}
+
+// setEndpointTags stores the address of an endpoint in span tags
+func setEndpointTags(tags map[string]string, endpoint *ZipkinEndpoint, ipTag, portTag string) {
+	if endpoint.IPv4 != "" {
+		tags[ipTag] = endpoint.IPv4
+	} else if endpoint.IPv6 != "" {
+		tags[ipTag] = endpoint.IPv6
+	}
+	if endpoint.Port != 0 {
+		tags[portTag] = strconv.Itoa(endpoint.Port)
+	}
+}
+
+// TracesToZipkin converts domain traces into Zipkin v2 spans, keeping
+// trace and span IDs, parent links, timestamps, tags and logs. It is the
+// inverse of ZipkinToTraces.
+func TracesToZipkin(traces []*domain.Trace) []ZipkinSpan {
+	var spans []ZipkinSpan
+	for _, trace := range traces {
+		forwarded := forwardedSpans(trace)
+		parents := make(map[domain.SpanID]*domain.SpanID, len(forwarded))
+		for _, span := range forwarded {
+			parents[span.ID] = span.ParentID
+		}
+
+		for _, span := range forwarded {
+			zipkinSpan := domainSpanToZipkin(span)
+			if span.Tags[zipkinSharedTag] == "true" && span.ParentID != nil {
+				// Shared server spans take back the ID and parent of the client half
+				zipkinSpan.ID = zipkinSpanID(*span.ParentID)
+				zipkinSpan.ParentID = ""
+				if parent := parents[*span.ParentID]; parent != nil {
+					zipkinSpan.ParentID = zipkinSpanID(*parent)
+				}
+				zipkinSpan.Shared = true
+			}
+			spans = append(spans, zipkinSpan)
+		}
+	}
+	return spans
+}
+
+// domainSpanToZipkin converts a single domain span into a Zipkin span
+func domainSpanToZipkin(span domain.Span) ZipkinSpan {
+	zipkinSpan := ZipkinSpan{
+		TraceID:       zipkinTraceID(span.TraceID),
+		ID:            zipkinSpanID(span.ID),
+		Name:          string(span.Operation),
+		Kind:          zipkinSpanKind(span.Tags[domain.SpanKindTag]),
+		Timestamp:     span.StartTime.UnixMicro(),
+		Duration:      span.EndTime.Sub(span.StartTime).Microseconds(),
+		LocalEndpoint: &ZipkinEndpoint{ServiceName: string(span.Service)},
+	}
+	if span.ParentID != nil {
+		zipkinSpan.ParentID = zipkinSpanID(*span.ParentID)
+	}
+
+	tags := make(map[string]string, len(span.Tags))
+	for key, value := range span.Tags {
+		switch key {
+		case domain.SpanKindTag, zipkinSharedTag:
+		case zipkinLocalIPTag, zipkinLocalPortTag:
+			applyEndpointTag(zipkinSpan.LocalEndpoint, key == zipkinLocalPortTag, value)
+		case zipkinPeerServiceTag, zipkinPeerIPTag, zipkinPeerPortTag:
+			if zipkinSpan.RemoteEndpoint == nil {
+				zipkinSpan.RemoteEndpoint = &ZipkinEndpoint{}
+			}
+			if key == zipkinPeerServiceTag {
+				zipkinSpan.RemoteEndpoint.ServiceName = value
+			} else {
+				applyEndpointTag(zipkinSpan.RemoteEndpoint, key == zipkinPeerPortTag, value)
+			}
+		default:
+			tags[key] = value
+		}
+	}
+	if span.Status == domain.SpanStatusError && tags[zipkinErrorTag] == "" {
+		tags[zipkinErrorTag] = "true"
+		if message := span.Tags[otlpStatusDescriptionTag]; message != "" {
+			tags[zipkinErrorTag] = message
+		}
+	}
+	if string(span.TraceID) != zipkinSpan.TraceID {
+		tags[originalTraceIDKey] = string(span.TraceID)
+	}
+	if string(span.ID) != zipkinSpan.ID {
+		tags[originalSpanIDKey] = string(span.ID)
+	}
+	if len(tags) > 0 {
+		zipkinSpan.Tags = tags
+	}
+
+	for _, log := range span.Logs {
+		zipkinSpan.Annotations = append(zipkinSpan.Annotations, ZipkinAnnotation{
+			Timestamp: log.Timestamp.UnixMicro(),
+			Value:     log.Message,
+		})
+	}
+
+	return zipkinSpan
+}
+
+// applyEndpointTag sets the port, or the IPv4 or IPv6 address, of an endpoint
+func applyEndpointTag(endpoint *ZipkinEndpoint, isPort bool, value string) {
+	switch {
+	case isPort:
+		endpoint.Port, _ = strconv.Atoi(value)
+	case strings.Contains(value, ":"):
+		endpoint.IPv6 = value
+	default:
+		endpoint.IPv4 = value
+	}
+}
+
+// zipkinTraceID returns a trace ID in Zipkin's 16 or 32 character hex form.
+// Other IDs are converted like OTLP IDs.
+func zipkinTraceID(id domain.TraceID) string {
+	if len(id) == 16 && isHexID(string(id), 8) {
+		return strings.ToLower(string(id))
+	}
+	otlpID := otlpTraceID(id)
+	return hex.EncodeToString(otlpID[:])
+}
+
+// zipkinSpanID returns a span ID in Zipkin's 16 character hex form
+func zipkinSpanID(id domain.SpanID) string {
+	otlpID := otlpSpanID(id)
+	return hex.EncodeToString(otlpID[:])
+}
+
+// zipkinSpanKind maps a span kind tag to its Zipkin kind; Zipkin has no
+// internal kind
+func zipkinSpanKind(kind string) string {
+	switch kind = strings.ToUpper(kind); kind {
+	case "CLIENT", "SERVER", "PRODUCER", "CONSUMER":
+		return kind
+	default:
+		return ""
+	}
+}
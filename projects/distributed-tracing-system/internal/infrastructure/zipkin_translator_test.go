@@ -0,0 +1,194 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testZipkinSpans() []ZipkinSpan {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).UnixMicro()
+	return []ZipkinSpan{
+		{
+			TraceID:       "463ac35c9f6413ad48485a3953bb6124",
+			ID:            "a2fb4a1d1a96d312",
+			Name:          "get /checkout",
+			Kind:          "SERVER",
+			Timestamp:     start,
+			Duration:      100000,
+			LocalEndpoint: &ZipkinEndpoint{ServiceName: "frontend", IPv4: "10.0.0.1", Port: 8080},
+			Tags:          map[string]string{"http.method": "GET"},
+		},
+		{
+			TraceID:        "463ac35c9f6413ad48485a3953bb6124",
+			ID:             "0020000000000001",
+			ParentID:       "a2fb4a1d1a96d312",
+			Name:           "charge",
+			Kind:           "CLIENT",
+			Timestamp:      start + 10000,
+			Duration:       50000,
+			LocalEndpoint:  &ZipkinEndpoint{ServiceName: "frontend"},
+			RemoteEndpoint: &ZipkinEndpoint{ServiceName: "payments", IPv6: "2001:db8::c001", Port: 9000},
+			Annotations:    []ZipkinAnnotation{{Timestamp: start + 20000, Value: "retry"}},
+			Tags:           map[string]string{"error": "card declined"},
+		},
+	}
+}
+
+func TestZipkinToTraces(t *testing.T) {
+	traces, rejected := ZipkinToTraces(testZipkinSpans())
+
+	assert.Equal(t, 0, rejected)
+	require.Len(t, traces, 1)
+	trace := traces[0]
+	assert.Equal(t, domain.TraceID("463ac35c9f6413ad48485a3953bb6124"), trace.ID)
+	assert.Equal(t, domain.ServiceName("frontend"), trace.Service)
+	assert.Equal(t, domain.TraceStatusError, trace.Status)
+	assert.Equal(t, 100*time.Millisecond, trace.Duration)
+	require.Len(t, trace.Spans, 2)
+
+	root := trace.Spans[0]
+	assert.Nil(t, root.ParentID)
+	assert.Equal(t, "server", root.Tags[domain.SpanKindTag])
+	assert.Equal(t, "10.0.0.1", root.Tags[zipkinLocalIPTag])
+	assert.Equal(t, "8080", root.Tags[zipkinLocalPortTag])
+
+	child := trace.Spans[1]
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, root.ID, *child.ParentID)
+	assert.Equal(t, domain.SpanStatusError, child.Status)
+	assert.Equal(t, "client", child.Tags[domain.SpanKindTag])
+	assert.Equal(t, "payments", child.Tags[zipkinPeerServiceTag])
+	assert.Equal(t, "2001:db8::c001", child.Tags[zipkinPeerIPTag])
+	assert.Equal(t, 50*time.Millisecond, child.Duration)
+	require.Len(t, child.Logs, 1)
+	assert.Equal(t, "retry", child.Logs[0].Message)
+	assert.True(t, root.StartTime.Add(20*time.Millisecond).Equal(child.Logs[0].Timestamp))
+}
+
+func TestZipkinToTraces_RejectsMissingIDs(t *testing.T) {
+	traces, rejected := ZipkinToTraces([]ZipkinSpan{
+		{ID: "a2fb4a1d1a96d312", Name: "no trace"},
+		{TraceID: "463ac35c9f6413ad", ID: "0000000000000000", Name: "zero span"},
+		{TraceID: "463ac35c9f6413ad", ID: "a2fb4a1d1a96d312", Name: "work", Duration: 1000},
+	})
+
+	assert.Equal(t, 2, rejected)
+	require.Len(t, traces, 1)
+	assert.Equal(t, domain.ServiceName(otlpUnknownService), traces[0].Service)
+}
+
+func TestTracesToZipkin_RoundTrip(t *testing.T) {
+	want := testZipkinSpans()
+	traces, _ := ZipkinToTraces(want)
+
+	got := TracesToZipkin(traces)
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i], got[i])
+	}
+}
+
+func TestZipkinToTraces_SharedSpans(t *testing.T) {
+	// Arrange: the client and server halves of one RPC share a span ID
+	spans := testZipkinSpans()
+	server := ZipkinSpan{
+		TraceID:       "463ac35c9f6413ad48485a3953bb6124",
+		ID:            "0020000000000001",
+		ParentID:      "a2fb4a1d1a96d312",
+		Name:          "charge",
+		Kind:          "SERVER",
+		Timestamp:     spans[1].Timestamp + 5000,
+		Duration:      40000,
+		LocalEndpoint: &ZipkinEndpoint{ServiceName: "payments"},
+		Shared:        true,
+	}
+	spans = append(spans, server)
+
+	// Act
+	traces, rejected := ZipkinToTraces(spans)
+
+	// Assert: the server half is a child of the client half
+	assert.Equal(t, 0, rejected)
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0].Spans, 3)
+	client, shared := traces[0].Spans[1], traces[0].Spans[2]
+	assert.Equal(t, domain.SpanID("0020000000000001"), client.ID)
+	assert.NotEqual(t, client.ID, shared.ID)
+	require.NotNil(t, shared.ParentID)
+	assert.Equal(t, client.ID, *shared.ParentID)
+	assert.Equal(t, domain.ServiceName("payments"), shared.Service)
+
+	// Both halves keep their shared ID when exported again
+	got := TracesToZipkin(traces)
+	require.Len(t, got, len(spans))
+	for i := range spans {
+		assert.Equal(t, spans[i], got[i])
+	}
+}
+
+func TestTracesToZipkin_NonHexIDs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := &domain.Trace{
+		ID:        "trace-1",
+		Service:   "checkout",
+		Operation: "pay",
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Status:    domain.TraceStatusSuccess,
+		Spans: []domain.Span{
+			{ID: "root", Operation: "pay", StartTime: start, Duration: time.Second, Tags: map[string]string{domain.SpanKindTag: "internal"}, Status: domain.SpanStatusOK},
+		},
+	}
+
+	spans := TracesToZipkin([]*domain.Trace{trace})
+
+	require.Len(t, spans, 1)
+	assert.Len(t, spans[0].TraceID, 32)
+	assert.Len(t, spans[0].ID, 16)
+	assert.Empty(t, spans[0].Kind)
+	assert.Equal(t, int64(1000000), spans[0].Duration)
+	assert.Equal(t, "checkout", spans[0].LocalEndpoint.ServiceName)
+	assert.Equal(t, "trace-1", spans[0].Tags[originalTraceIDKey])
+	assert.Equal(t, "root", spans[0].Tags[originalSpanIDKey])
+}
+
+func TestZipkinExporter_ExportTrace(t *testing.T) {
+	var received []ZipkinSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewZipkinExporter(collector.URL, time.Second)
+	require.NoError(t, err)
+
+	traces, _ := ZipkinToTraces(testZipkinSpans())
+	require.NoError(t, exporter.ExportTrace(context.Background(), traces[0]))
+	assert.Equal(t, testZipkinSpans(), received)
+}
+
+func TestZipkinExporter_ErrorStatus(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewZipkinExporter(collector.URL, time.Second)
+	require.NoError(t, err)
+
+	traces, _ := ZipkinToTraces(testZipkinSpans())
+	err = exporter.ExportTrace(context.Background(), traces[0])
+	assert.ErrorContains(t, err, "status 400")
+}
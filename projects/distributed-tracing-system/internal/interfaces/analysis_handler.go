@@ -0,0 +1,44 @@
+package interfaces
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// analyzeTrace handles span tree and critical path analysis requests (GET /api/v1/traces/:id/analysis)
+func (s *ServerWithTelemetry) analyzeTrace(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "analyze-trace")
+	defer span.End()
+
+	traceID := domain.TraceID(c.Param("id"))
+	span.SetAttributes(attribute.String("trace.id", string(traceID)))
+
+	analysis, err := s.traceService.AnalyzeTrace(ctx, traceID)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, domain.ErrTraceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "trace not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int("analysis.span_count", analysis.SpanCount),
+		attribute.Int("analysis.orphans", len(analysis.Orphans)),
+		attribute.Int("analysis.warnings", len(analysis.Warnings)),
+	)
+	span.SetStatus(codes.Ok, "Trace analyzed successfully")
+
+	c.JSON(http.StatusOK, analysis)
+}
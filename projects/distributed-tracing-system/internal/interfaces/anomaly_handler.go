@@ -0,0 +1,82 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// defaultAnomalyRange is the time range of the anomalies when no start is given
+const defaultAnomalyRange = 24 * time.Hour
+
+// getAnomalies handles detected anomaly requests
+// (GET /api/v1/anomalies?service=&operation=&kind=&start=&end=&limit=&offset=),
+// newest first. start and end bound the end of the detection window; end
+// defaults to now and start to a day before end.
+func (s *ServerWithTelemetry) getAnomalies(c *gin.Context) {
+	_, span := s.telemetryManager.StartSpan(c.Request.Context(), "get-anomalies")
+	defer span.End()
+
+	if s.anomalies == nil {
+		span.SetStatus(codes.Error, "Anomaly detection is not enabled")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "anomaly detection is not enabled",
+		})
+		return
+	}
+
+	query := domain.AnomalyQuery{
+		Service:   domain.ServiceName(c.Query("service")),
+		Operation: domain.OperationName(c.Query("operation")),
+		Kind:      domain.AnomalyKind(c.Query("kind")),
+	}
+	if query.Kind != "" && query.Kind != domain.AnomalyKindLatency && query.Kind != domain.AnomalyKindErrorRate {
+		span.SetStatus(codes.Error, "Invalid kind")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid kind: must be latency or error_rate",
+		})
+		return
+	}
+
+	var err error
+	if query.Start, query.End, err = parseTimeWindow(c, defaultAnomalyRange); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if query.Limit, query.Offset, err = parsePagination(c); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("anomalies.service", string(query.Service)),
+		attribute.String("anomalies.kind", string(query.Kind)),
+		attribute.String("anomalies.start", query.Start.Format(time.RFC3339)),
+		attribute.String("anomalies.end", query.End.Format(time.RFC3339)),
+	)
+
+	anomalies := s.anomalies.FindAnomalies(query)
+	if anomalies == nil {
+		anomalies = []*domain.Anomaly{}
+	}
+
+	span.SetAttributes(attribute.Int("anomalies.count", len(anomalies)))
+	span.SetStatus(codes.Ok, "Anomalies retrieved successfully")
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomalies": anomalies,
+		"count":     len(anomalies),
+		"limit":     query.Limit,
+		"offset":    query.Offset,
+	})
+}
@@ -0,0 +1,74 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// defaultDependencyRange is the time range of the dependency graph when no start is given
+const defaultDependencyRange = time.Hour
+
+// getDependencies handles service dependency graph requests
+// (GET /api/v1/dependencies?start=&end=&format=json|dot). start and end are
+// RFC3339 timestamps; end defaults to now and start to an hour before end.
+func (s *ServerWithTelemetry) getDependencies(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "get-dependencies")
+	defer span.End()
+
+	if s.dependencies == nil {
+		span.SetStatus(codes.Error, "Dependencies are not enabled")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "dependencies are not enabled",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" {
+		span.SetStatus(codes.Error, "Invalid format")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid format: must be json or dot",
+		})
+		return
+	}
+
+	start, end, err := parseTimeWindow(c, defaultDependencyRange)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("dependencies.start", start.Format(time.RFC3339)),
+		attribute.String("dependencies.end", end.Format(time.RFC3339)),
+		attribute.String("dependencies.format", format),
+	)
+
+	graph, err := s.dependencies.GetDependencies(ctx, start, end)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int("dependencies.nodes", len(graph.Nodes)),
+		attribute.Int("dependencies.edges", len(graph.Edges)),
+	)
+	span.SetStatus(codes.Ok, "Dependencies retrieved successfully")
+
+	if format == "dot" {
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(graph.DOT()))
+		return
+	}
+	c.JSON(http.StatusOK, graph)
+}
@@ -0,0 +1,243 @@
+package interfaces
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// backpressureRetryAfter is the Retry-After value, in seconds, sent when
+// traces are refused because the write queue is full
+const backpressureRetryAfter = "1"
+
+// Ingestion result statuses reported per item
+const (
+	ingestStatusAccepted = "accepted"
+	ingestStatusRejected = "rejected"
+	ingestStatusFailed   = "failed"
+)
+
+// ingestResult reports the outcome for a single trace in an ingestion batch
+type ingestResult struct {
+	Index   int            `json:"index"`
+	TraceID domain.TraceID `json:"trace_id,omitempty"`
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// ingestTraces handles native trace ingestion of a single trace, a JSON array
+// of traces, newline-delimited JSON traces or a JSON text sequence of traces
+func (s *ServerWithTelemetry) ingestTraces(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "ingest-traces")
+	defer span.End()
+
+	body, err := readRequestBody(c, int64(s.config.Ingest.MaxPayloadBytes))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(requestBodyStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	items, err := decodeTraceBatch(body, contentType)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if len(items) == 0 {
+		span.SetStatus(codes.Error, "Empty batch")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "request contains no traces",
+		})
+		return
+	}
+
+	if len(items) > s.config.Ingest.MaxBatchSize {
+		span.SetStatus(codes.Error, "Batch too large")
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("batch contains %d traces, maximum is %d", len(items), s.config.Ingest.MaxBatchSize),
+		})
+		return
+	}
+
+	span.SetAttributes(attribute.Int("ingest.batch_size", len(items)))
+
+	results := make([]ingestResult, 0, len(items))
+	accepted, rejected, failed := 0, 0, 0
+	backpressure := false
+	for i, item := range items {
+		result := ingestResult{Index: i}
+
+		var trace domain.Trace
+		if err := json.Unmarshal(item, &trace); err != nil {
+			result.Status = ingestStatusRejected
+			result.Error = fmt.Sprintf("failed to decode trace: %v", err)
+			rejected++
+			results = append(results, result)
+			continue
+		}
+		result.TraceID = trace.ID
+
+		if err := s.traceService.ValidateTrace(&trace); err != nil {
+			result.Status = ingestStatusRejected
+			result.Error = err.Error()
+			rejected++
+			results = append(results, result)
+			continue
+		}
+
+		if err := s.traceService.ProcessTrace(ctx, &trace); err != nil {
+			if errors.Is(err, domain.ErrInvalidTrace) {
+				result.Status = ingestStatusRejected
+				rejected++
+			} else {
+				result.Status = ingestStatusFailed
+				failed++
+				backpressure = backpressure || errors.Is(err, domain.ErrBackpressure)
+			}
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Status = ingestStatusAccepted
+		accepted++
+		results = append(results, result)
+	}
+
+	span.SetAttributes(
+		attribute.Int("ingest.accepted", accepted),
+		attribute.Int("ingest.rejected", rejected),
+		attribute.Int("ingest.failed", failed),
+	)
+
+	status := http.StatusOK
+	switch {
+	case accepted == len(items):
+		span.SetStatus(codes.Ok, "Traces ingested successfully")
+	case accepted > 0:
+		status = http.StatusMultiStatus
+		span.SetStatus(codes.Ok, "Traces partially ingested")
+	case failed > 0:
+		status = http.StatusServiceUnavailable
+		span.SetStatus(codes.Error, "Failed to store traces")
+		if backpressure {
+			c.Header("Retry-After", backpressureRetryAfter)
+		}
+	default:
+		status = http.StatusBadRequest
+		span.SetStatus(codes.Error, "All traces rejected")
+	}
+
+	c.JSON(status, gin.H{
+		"accepted": accepted,
+		"rejected": rejected,
+		"failed":   failed,
+		"results":  results,
+	})
+}
+
+// recordSeparator starts every record of a JSON text sequence (RFC 7464)
+const recordSeparator = 0x1E
+
+// decodeTraceBatch splits a request body into raw trace documents. NDJSON
+// bodies are split per line and JSON text sequences per record, so a
+// malformed item only rejects that item.
+func decodeTraceBatch(body []byte, contentType string) ([]json.RawMessage, error) {
+	switch contentType {
+	case "application/x-ndjson", "application/jsonl":
+		return splitNDJSON(body)
+	case "application/json-seq":
+		return splitJSONSeq(body)
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	if trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		return items, nil
+	}
+
+	// A single object, or concatenated/newline-delimited objects
+	var items []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("invalid JSON payload: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// splitNDJSON returns the non-empty lines of a newline-delimited JSON body
+func splitNDJSON(body []byte) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		item := make(json.RawMessage, len(line))
+		copy(item, line)
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON payload: %w", err)
+	}
+
+	return items, nil
+}
+
+// splitJSONSeq returns the non-empty records of a JSON text sequence, where
+// each record starts with a record separator and ends with a line feed
+func splitJSONSeq(body []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] != recordSeparator {
+		return nil, fmt.Errorf("invalid JSON text sequence: records must start with a record separator (0x1E)")
+	}
+
+	var items []json.RawMessage
+	for _, record := range bytes.Split(trimmed[1:], []byte{recordSeparator}) {
+		record = bytes.TrimSpace(record)
+		if len(record) == 0 {
+			continue
+		}
+		items = append(items, json.RawMessage(record))
+	}
+
+	return items, nil
+}
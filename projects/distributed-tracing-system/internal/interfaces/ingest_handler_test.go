@@ -0,0 +1,276 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/config"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	telemetryOnce    sync.Once
+	telemetryManager *telemetry.TelemetryManager
+	telemetryErr     error
+)
+
+// newTestTelemetryManager returns a telemetry manager shared by the tests,
+// since its Prometheus exporter can only be registered once
+func newTestTelemetryManager(t *testing.T) *telemetry.TelemetryManager {
+	telemetryOnce.Do(func() {
+		telemetryManager, telemetryErr = telemetry.NewTelemetryManager(&telemetry.TelemetryConfig{
+			ServiceName:    "distributed-tracing-system-test",
+			JaegerEndpoint: "http://localhost:14268/api/traces",
+		})
+	})
+	require.NoError(t, telemetryErr)
+	return telemetryManager
+}
+
+// fakeTraceService validates traces like the trace service and processes
+// them with a test function
+type fakeTraceService struct {
+	domain.TraceService
+	process func(ctx context.Context, trace *domain.Trace) error
+}
+
+func (s *fakeTraceService) ValidateTrace(trace *domain.Trace) error {
+	if trace.ID == "" {
+		return fmt.Errorf("%w: trace ID is required", domain.ErrInvalidTrace)
+	}
+	return nil
+}
+
+func (s *fakeTraceService) ProcessTrace(ctx context.Context, trace *domain.Trace) error {
+	return s.process(ctx, trace)
+}
+
+// newTestServer creates a server on top of the given trace service
+func newTestServer(t *testing.T, traceService domain.TraceService) *ServerWithTelemetry {
+	cfg := &config.Config{
+		Ingest: config.IngestConfig{MaxPayloadBytes: 64 * 1024, MaxBatchSize: 3},
+	}
+	server, err := NewServerWithTelemetry(cfg, traceService, newTestTelemetryManager(t))
+	require.NoError(t, err)
+	return server
+}
+
+// ingestResponse is the body of an ingestion response
+type ingestResponse struct {
+	Accepted int            `json:"accepted"`
+	Rejected int            `json:"rejected"`
+	Failed   int            `json:"failed"`
+	Results  []ingestResult `json:"results"`
+	Error    string         `json:"error"`
+}
+
+func postTraces(t *testing.T, server *ServerWithTelemetry, contentType, body string) (*httptest.ResponseRecorder, ingestResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/traces", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+
+	var response ingestResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response), recorder.Body.String())
+	return recorder, response
+}
+
+func testTraceJSON(id string) string {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	data, _ := json.Marshal(domain.Trace{
+		ID:        domain.TraceID(id),
+		Service:   "checkout",
+		Operation: "POST /orders",
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Status:    domain.TraceStatusSuccess,
+	})
+	return string(data)
+}
+
+func TestIngestTraces_AcceptsBatch(t *testing.T) {
+	// Arrange
+	var processed []domain.TraceID
+	server := newTestServer(t, &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		processed = append(processed, trace.ID)
+		return nil
+	}})
+
+	// Act
+	recorder, response := postTraces(t, server, "application/json", "["+testTraceJSON("trace-1")+","+testTraceJSON("trace-2")+"]")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 2, response.Accepted)
+	assert.Equal(t, []domain.TraceID{"trace-1", "trace-2"}, processed)
+}
+
+func TestIngestTraces_PartialSuccess(t *testing.T) {
+	// Arrange: one malformed, one invalid and one failing trace
+	server := newTestServer(t, &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		if trace.ID == "trace-3" {
+			return errors.New("database unavailable")
+		}
+		return nil
+	}})
+	body := strings.Join([]string{
+		testTraceJSON("trace-1"),
+		`{"id": `,
+		`{"service": "checkout"}`,
+	}, "\n")
+
+	// Act
+	recorder, response := postTraces(t, server, "application/x-ndjson", body)
+
+	// Assert
+	assert.Equal(t, http.StatusMultiStatus, recorder.Code)
+	assert.Equal(t, 1, response.Accepted)
+	assert.Equal(t, 2, response.Rejected)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, ingestStatusAccepted, response.Results[0].Status)
+	assert.Equal(t, ingestStatusRejected, response.Results[1].Status)
+	assert.Contains(t, response.Results[1].Error, "failed to decode trace")
+	assert.Equal(t, ingestStatusRejected, response.Results[2].Status)
+	assert.Contains(t, response.Results[2].Error, "trace ID is required")
+}
+
+func TestIngestTraces_Errors(t *testing.T) {
+	backpressure := &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		return fmt.Errorf("failed to save trace: %w", domain.ErrBackpressure)
+	}}
+	accepting := &fakeTraceService{process: func(ctx context.Context, trace *domain.Trace) error {
+		return nil
+	}}
+
+	tests := []struct {
+		name         string
+		service      domain.TraceService
+		contentType  string
+		body         string
+		status       int
+		retryAfter   string
+		errorMessage string
+	}{
+		{
+			name:         "malformed array",
+			service:      accepting,
+			contentType:  "application/json",
+			body:         `[{"id": "trace-1"`,
+			status:       http.StatusBadRequest,
+			errorMessage: "invalid JSON array",
+		},
+		{
+			name:         "empty batch",
+			service:      accepting,
+			contentType:  "application/json",
+			body:         `[]`,
+			status:       http.StatusBadRequest,
+			errorMessage: "request contains no traces",
+		},
+		{
+			name:         "batch too large",
+			service:      accepting,
+			contentType:  "application/x-ndjson",
+			body:         strings.Repeat(testTraceJSON("trace-1")+"\n", 4),
+			status:       http.StatusRequestEntityTooLarge,
+			errorMessage: "maximum is 3",
+		},
+		{
+			name:        "all rejected",
+			service:     accepting,
+			contentType: "application/json",
+			body:        `{"service": "checkout"}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "backpressure",
+			service:     backpressure,
+			contentType: "application/json",
+			body:        testTraceJSON("trace-1"),
+			status:      http.StatusServiceUnavailable,
+			retryAfter:  backpressureRetryAfter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, tt.service)
+
+			recorder, response := postTraces(t, server, tt.contentType, tt.body)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, tt.retryAfter, recorder.Header().Get("Retry-After"))
+			assert.Contains(t, response.Error, tt.errorMessage)
+		})
+	}
+}
+
+func TestDecodeTraceBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		items       []string
+		err         string
+	}{
+		{
+			name:        "single object",
+			contentType: "application/json",
+			body:        ` {"id": "a"} `,
+			items:       []string{`{"id": "a"}`},
+		},
+		{
+			name:        "array",
+			contentType: "application/json",
+			body:        `[{"id": "a"}, {"id": "b"}]`,
+			items:       []string{`{"id": "a"}`, `{"id": "b"}`},
+		},
+		{
+			name:        "ndjson keeps malformed lines as items",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\": \"a\"}\n\n{\"id\": \n",
+			items:       []string{`{"id": "a"}`, `{"id":`},
+		},
+		{
+			name:        "json text sequence",
+			contentType: "application/json-seq",
+			body:        "\x1e{\"id\": \"a\"}\n\x1e{\"id\":\n \"b\"}\n\x1e\n",
+			items:       []string{`{"id": "a"}`, "{\"id\":\n \"b\"}"},
+		},
+		{
+			name:        "json text sequence without record separator",
+			contentType: "application/json-seq",
+			body:        "{\"id\": \"a\"}\n",
+			err:         "record separator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := decodeTraceBatch([]byte(tt.body), tt.contentType)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			decoded := make([]string, len(items))
+			for i, item := range items {
+				decoded[i] = string(item)
+			}
+			assert.Equal(t, tt.items, decoded)
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// defaultJaegerLimit and defaultJaegerLookback match the defaults of the
+	// Jaeger query service
+	defaultJaegerLimit    = 20
+	defaultJaegerLookback = time.Hour
+	// defaultJaegerDependencyLookback is the dependency range when no lookback is given
+	defaultJaegerDependencyLookback = 24 * time.Hour
+	// jaegerErrorTag is the tag the Jaeger UI searches failed spans by
+	jaegerErrorTag = "error"
+)
+
+// jaegerResponse is the envelope of every Jaeger query API response
+type jaegerResponse struct {
+	Data   interface{}   `json:"data"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+	Errors []jaegerError `json:"errors"`
+}
+
+// jaegerError is an error reported in the Jaeger response envelope
+type jaegerError struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	TraceID string `json:"traceID,omitempty"`
+}
+
+// writeJaegerError writes an error response in the Jaeger envelope
+func writeJaegerError(c *gin.Context, status int, msg string) {
+	c.JSON(status, jaegerResponse{
+		Errors: []jaegerError{{Code: status, Msg: msg}},
+	})
+}
+
+// getJaegerServices handles Jaeger service list requests (GET /api/services)
+func (s *ServerWithTelemetry) getJaegerServices(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "jaeger-get-services")
+	defer span.End()
+
+	services, err := s.traceService.GetServices(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	names := make([]string, 0, len(services))
+	for _, service := range services {
+		names = append(names, string(service))
+	}
+
+	span.SetAttributes(attribute.Int("services.count", len(names)))
+	span.SetStatus(codes.Ok, "Services retrieved successfully")
+
+	c.JSON(http.StatusOK, jaegerResponse{Data: names, Total: len(names)})
+}
+
+// getJaegerOperations handles Jaeger operation list requests
+// (GET /api/services/:service/operations)
+func (s *ServerWithTelemetry) getJaegerOperations(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "jaeger-get-operations")
+	defer span.End()
+
+	service := domain.ServiceName(c.Param("service"))
+	span.SetAttributes(attribute.String("service.name", string(service)))
+
+	operations, err := s.traceService.GetOperations(ctx, service)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	names := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		names = append(names, string(operation))
+	}
+
+	span.SetAttributes(attribute.Int("operations.count", len(names)))
+	span.SetStatus(codes.Ok, "Operations retrieved successfully")
+
+	c.JSON(http.StatusOK, jaegerResponse{Data: names, Total: len(names)})
+}
+
+// findJaegerTraces handles Jaeger trace searches (GET /api/traces). With
+// traceID parameters the listed traces are returned; otherwise traces are
+// searched as described at parseJaegerSearch.
+func (s *ServerWithTelemetry) findJaegerTraces(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "jaeger-find-traces")
+	defer span.End()
+
+	if ids := c.QueryArray("traceID"); len(ids) > 0 {
+		traces := make([]infrastructure.JaegerTrace, 0, len(ids))
+		var missing []jaegerError
+		for _, id := range ids {
+			trace, err := s.traceService.GetTrace(ctx, domain.TraceID(id))
+			if err != nil && !errors.Is(err, domain.ErrTraceNotFound) {
+				span.SetStatus(codes.Error, err.Error())
+				writeJaegerError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if trace == nil {
+				missing = append(missing, jaegerError{Code: http.StatusNotFound, Msg: "trace not found", TraceID: id})
+				continue
+			}
+			traces = append(traces, infrastructure.TraceToJaeger(trace))
+		}
+
+		span.SetAttributes(attribute.Int("search.results_count", len(traces)))
+		span.SetStatus(codes.Ok, "Traces retrieved successfully")
+
+		c.JSON(http.StatusOK, jaegerResponse{Data: traces, Total: len(traces), Errors: missing})
+		return
+	}
+
+	criteria, err := parseJaegerSearch(c)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("search.service", string(*criteria.SpanFilters[0].Service)),
+		attribute.Int("search.limit", criteria.Limit),
+	)
+
+	found, err := s.traceService.SearchTraces(ctx, criteria)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	traces := make([]infrastructure.JaegerTrace, 0, len(found))
+	for _, trace := range found {
+		traces = append(traces, infrastructure.TraceToJaeger(trace))
+	}
+
+	span.SetAttributes(attribute.Int("search.results_count", len(traces)))
+	span.SetStatus(codes.Ok, "Search completed successfully")
+
+	c.JSON(http.StatusOK, jaegerResponse{Data: traces, Total: len(traces), Limit: criteria.Limit})
+}
+
+// getJaegerTrace handles Jaeger trace lookups (GET /api/traces/:id)
+func (s *ServerWithTelemetry) getJaegerTrace(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "jaeger-get-trace")
+	defer span.End()
+
+	traceID := domain.TraceID(c.Param("id"))
+	span.SetAttributes(attribute.String("trace.id", string(traceID)))
+
+	trace, err := s.traceService.GetTrace(ctx, traceID)
+	if err != nil && !errors.Is(err, domain.ErrTraceNotFound) {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if trace == nil {
+		span.SetStatus(codes.Error, "Trace not found")
+		writeJaegerError(c, http.StatusNotFound, "trace not found")
+		return
+	}
+
+	span.SetStatus(codes.Ok, "Trace retrieved successfully")
+
+	c.JSON(http.StatusOK, jaegerResponse{
+		Data:  []infrastructure.JaegerTrace{infrastructure.TraceToJaeger(trace)},
+		Total: 1,
+	})
+}
+
+// getJaegerDependencies handles Jaeger dependency requests
+// (GET /api/dependencies?endTs=&lookback=). endTs is in Unix milliseconds and
+// defaults to now; lookback is in milliseconds and defaults to a day.
+func (s *ServerWithTelemetry) getJaegerDependencies(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "jaeger-get-dependencies")
+	defer span.End()
+
+	if s.dependencies == nil {
+		span.SetStatus(codes.Error, "Dependencies are not enabled")
+		writeJaegerError(c, http.StatusServiceUnavailable, "dependencies are not enabled")
+		return
+	}
+
+	end, err := parseUnixParam(c, "endTs", time.Millisecond)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if end == nil {
+		now := time.Now()
+		end = &now
+	}
+
+	lookback := defaultJaegerDependencyLookback
+	if value := c.Query("lookback"); value != "" {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ms <= 0 {
+			span.SetStatus(codes.Error, "Invalid lookback")
+			writeJaegerError(c, http.StatusBadRequest, fmt.Sprintf("invalid lookback %q: must be a positive number of milliseconds", value))
+			return
+		}
+		lookback = time.Duration(ms) * time.Millisecond
+	}
+
+	start := end.Add(-lookback)
+	span.SetAttributes(
+		attribute.String("dependencies.start", start.Format(time.RFC3339)),
+		attribute.String("dependencies.end", end.Format(time.RFC3339)),
+	)
+
+	graph, err := s.dependencies.GetDependencies(ctx, start, *end)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeJaegerError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	links := infrastructure.DependencyGraphToJaeger(graph)
+	span.SetAttributes(attribute.Int("dependencies.edges", len(links)))
+	span.SetStatus(codes.Ok, "Dependencies retrieved successfully")
+
+	c.JSON(http.StatusOK, jaegerResponse{Data: links, Total: len(links)})
+}
+
+// parseJaegerSearch builds search criteria from the query parameters of a
+// Jaeger trace search. As in Jaeger, service, operation, minDuration,
+// maxDuration and tags must all match the same span, so they form a span
+// filter:
+//
+// service (required), operation, minDuration and maxDuration (durations such
+// as "1.2s" or "100ms"), tags (a JSON object of tag values) and tag=key:value
+// (repeatable). A tag error=true matches failed spans.
+//
+// start and end are Unix microseconds; end defaults to now and start to
+// lookback (a duration, default 1h) before end. limit defaults to 20.
+func parseJaegerSearch(c *gin.Context) (*domain.SearchCriteria, error) {
+	service := c.Query("service")
+	if service == "" {
+		return nil, fmt.Errorf("parameter 'service' is required")
+	}
+	serviceName := domain.ServiceName(service)
+	filter := domain.SpanFilter{Service: &serviceName}
+
+	if operation := c.Query("operation"); operation != "" {
+		operationName := domain.OperationName(operation)
+		filter.Operation = &operationName
+	}
+
+	var err error
+	if filter.MinDuration, filter.MaxDuration, err = parseDurationRange(c, "minDuration", "maxDuration"); err != nil {
+		return nil, err
+	}
+
+	if filter.Tags, err = parseJaegerTags(c); err != nil {
+		return nil, err
+	}
+	if filter.Tags[jaegerErrorTag] == "true" {
+		status := domain.SpanStatusError
+		filter.Status = &status
+		delete(filter.Tags, jaegerErrorTag)
+	}
+
+	criteria := &domain.SearchCriteria{
+		SpanFilters: []domain.SpanFilter{filter},
+		Limit:       defaultJaegerLimit,
+	}
+
+	if criteria.EndTime, err = parseUnixParam(c, "end", time.Microsecond); err != nil {
+		return nil, err
+	}
+	if criteria.EndTime == nil {
+		now := time.Now()
+		criteria.EndTime = &now
+	}
+	if criteria.StartTime, err = parseUnixParam(c, "start", time.Microsecond); err != nil {
+		return nil, err
+	}
+	if criteria.StartTime == nil {
+		lookback := defaultJaegerLookback
+		if value := c.Query("lookback"); value != "" && value != "custom" {
+			if lookback, err = time.ParseDuration(value); err != nil || lookback <= 0 {
+				return nil, fmt.Errorf("invalid lookback %q: must be a positive duration such as 1h", value)
+			}
+		}
+		start := criteria.EndTime.Add(-lookback)
+		criteria.StartTime = &start
+	}
+	if criteria.StartTime.After(*criteria.EndTime) {
+		return nil, fmt.Errorf("start cannot be after end")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxSearchLimit {
+			return nil, fmt.Errorf("invalid limit %q: must be an integer between 1 and %d", value, maxSearchLimit)
+		}
+		criteria.Limit = limit
+	}
+
+	return criteria, nil
+}
+
+// parseJaegerTags merges the tags JSON object with the repeated tag parameters
+func parseJaegerTags(c *gin.Context) (map[string]string, error) {
+	tags, err := parseTagParams(c, "tag")
+	if err != nil {
+		return nil, err
+	}
+
+	value := c.Query("tags")
+	if value == "" {
+		return tags, nil
+	}
+
+	var object map[string]string
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return nil, fmt.Errorf("invalid tags %q: must be a JSON object of string values", value)
+	}
+	if tags == nil {
+		tags = make(map[string]string, len(object))
+	}
+	for key, tagValue := range object {
+		tags[key] = tagValue
+	}
+	return tags, nil
+}
+
+// parseUnixParam parses an optional Unix timestamp parameter counted in unit
+func parseUnixParam(c *gin.Context, name string, unit time.Duration) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s %q: must be a Unix timestamp", name, value)
+	}
+	t := time.Unix(0, 0).Add(time.Duration(n) * unit).UTC()
+	return &t, nil
+}
@@ -0,0 +1,149 @@
+package interfaces
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// receiveOTLPTraces handles OTLP/HTTP trace exports in protobuf or JSON encoding
+func (s *ServerWithTelemetry) receiveOTLPTraces(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "receive-otlp-traces")
+	defer span.End()
+
+	contentType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || (contentType != contentTypeProtobuf && contentType != contentTypeJSON) {
+		span.SetStatus(codes.Error, "Unsupported content type")
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": fmt.Sprintf("unsupported content type %q, expected %s or %s", c.GetHeader("Content-Type"), contentTypeProtobuf, contentTypeJSON),
+		})
+		return
+	}
+	span.SetAttributes(attribute.String("otlp.encoding", contentType))
+
+	body, err := readRequestBody(c, int64(s.config.OTLP.MaxMessageBytes))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		s.writeOTLPError(c, contentType, requestBodyStatus(err), grpccodes.InvalidArgument, err.Error())
+		return
+	}
+
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if contentType == contentTypeJSON {
+		body, err = infrastructure.NormalizeOTLPJSON(body)
+		if err == nil {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+		}
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		s.writeOTLPError(c, contentType, http.StatusBadRequest, grpccodes.InvalidArgument, fmt.Sprintf("failed to decode OTLP request: %v", err))
+		return
+	}
+
+	response, err := s.otlpReceiver.export(ctx, req)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, domain.ErrBackpressure) {
+			c.Header("Retry-After", backpressureRetryAfter)
+		}
+		s.writeOTLPError(c, contentType, http.StatusServiceUnavailable, grpccodes.Unavailable, err.Error())
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("otlp.rejected_spans", response.GetPartialSuccess().GetRejectedSpans()))
+	span.SetStatus(codes.Ok, "OTLP traces received successfully")
+
+	s.writeOTLPMessage(c, contentType, http.StatusOK, response)
+}
+
+// writeOTLPError writes a google.rpc.Status body as required by OTLP/HTTP
+func (s *ServerWithTelemetry) writeOTLPError(c *gin.Context, contentType string, httpStatus int, code grpccodes.Code, message string) {
+	s.writeOTLPMessage(c, contentType, httpStatus, status.New(code, message).Proto())
+}
+
+// writeOTLPMessage encodes a protobuf message using the request encoding
+func (s *ServerWithTelemetry) writeOTLPMessage(c *gin.Context, contentType string, httpStatus int, message proto.Message) {
+	var (
+		data []byte
+		err  error
+	)
+	if contentType == contentTypeJSON {
+		data, err = protojson.Marshal(message)
+	} else {
+		data, err = proto.Marshal(message)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("failed to encode response: %v", err),
+		})
+		return
+	}
+
+	c.Data(httpStatus, contentType, data)
+}
+
+// errRequestTooLarge is returned when a request body exceeds the configured limit
+var errRequestTooLarge = errors.New("request body too large")
+
+// readRequestBody reads a possibly gzip-encoded request body, enforcing maxBytes
+// on both the wire size and the decompressed size
+func readRequestBody(c *gin.Context, maxBytes int64) ([]byte, error) {
+	var reader io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	switch c.GetHeader("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", c.GetHeader("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errRequestTooLarge
+		}
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, errRequestTooLarge
+	}
+
+	return body, nil
+}
+
+// requestBodyStatus maps a readRequestBody error to an HTTP status code
+func requestBodyStatus(err error) int {
+	if errors.Is(err, errRequestTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
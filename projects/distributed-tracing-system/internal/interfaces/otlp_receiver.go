@@ -0,0 +1,173 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/streamforge/distributed-tracing-system/internal/config"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // accept gzip-compressed OTLP exports
+	"google.golang.org/grpc/status"
+)
+
+// otlpReceiver converts OTLP export requests into domain traces and feeds
+// them to the trace service, or span by span to the span assembler when one
+// is set. It is shared by the HTTP and gRPC receivers.
+type otlpReceiver struct {
+	traceService domain.TraceService
+	assembler    domain.SpanAssembler
+}
+
+// newOTLPReceiver creates a new OTLP receiver; assembler may be nil
+func newOTLPReceiver(traceService domain.TraceService, assembler domain.SpanAssembler) *otlpReceiver {
+	return &otlpReceiver{
+		traceService: traceService,
+		assembler:    assembler,
+	}
+}
+
+// export processes an OTLP export request. Spans belonging to invalid traces
+// are reported through partial success; storage failures are returned as an
+// error so the client retries the whole batch (saves are idempotent upserts).
+func (r *otlpReceiver) export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	traces, rejected := infrastructure.OTLPToTraces(req.GetResourceSpans())
+
+	var rejectReasons []string
+	if rejected > 0 {
+		rejectReasons = append(rejectReasons, fmt.Sprintf("%d spans without valid trace or span ID", rejected))
+	}
+
+	for _, trace := range traces {
+		if r.assembler != nil {
+			invalid, err := assembleSpans(ctx, r.assembler, trace)
+			if invalid > 0 {
+				rejected += invalid
+				rejectReasons = append(rejectReasons, fmt.Sprintf("trace %s: %d invalid spans", trace.ID, invalid))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to assemble trace %s: %w", trace.ID, err)
+			}
+			continue
+		}
+
+		if err := r.traceService.ProcessTrace(ctx, trace); err != nil {
+			if errors.Is(err, domain.ErrInvalidTrace) {
+				rejected += len(trace.Spans)
+				rejectReasons = append(rejectReasons, fmt.Sprintf("trace %s: %v", trace.ID, err))
+				continue
+			}
+			return nil, fmt.Errorf("failed to process trace %s: %w", trace.ID, err)
+		}
+	}
+
+	response := &coltracepb.ExportTraceServiceResponse{}
+	if rejected > 0 {
+		response.PartialSuccess = &coltracepb.ExportTracePartialSuccess{
+			RejectedSpans: int64(rejected),
+			ErrorMessage:  joinReasons(rejectReasons),
+		}
+	}
+
+	return response, nil
+}
+
+// assembleSpans hands the spans of a trace to the span assembler, which
+// merges them with the spans of the trace received by other receivers. It
+// returns how many spans were rejected as invalid.
+func assembleSpans(ctx context.Context, assembler domain.SpanAssembler, trace *domain.Trace) (int, error) {
+	invalid := 0
+	for i := range trace.Spans {
+		if err := assembler.AddSpan(ctx, &trace.Spans[i]); err != nil {
+			if errors.Is(err, domain.ErrInvalidTrace) {
+				invalid++
+				continue
+			}
+			return invalid, err
+		}
+	}
+	return invalid, nil
+}
+
+// joinReasons joins rejection reasons into a single bounded message
+func joinReasons(reasons []string) string {
+	const maxReasons = 10
+
+	message := ""
+	for i, reason := range reasons {
+		if i == maxReasons {
+			message += fmt.Sprintf("; and %d more", len(reasons)-maxReasons)
+			break
+		}
+		if i > 0 {
+			message += "; "
+		}
+		message += reason
+	}
+	return message
+}
+
+// OTLPGRPCServer receives traces over the OTLP/gRPC protocol
+type OTLPGRPCServer struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	config   *config.Config
+	receiver *otlpReceiver
+	server   *grpc.Server
+}
+
+// NewOTLPGRPCServer creates a new OTLP gRPC receiver. When assembler is not
+// nil, spans are assembled into traces with the spans of other receivers.
+func NewOTLPGRPCServer(cfg *config.Config, traceService domain.TraceService, assembler domain.SpanAssembler) (*OTLPGRPCServer, error) {
+	if traceService == nil {
+		return nil, fmt.Errorf("trace service is required")
+	}
+
+	s := &OTLPGRPCServer{
+		config:   cfg,
+		receiver: newOTLPReceiver(traceService, assembler),
+		server:   grpc.NewServer(grpc.MaxRecvMsgSize(cfg.OTLP.MaxMessageBytes)),
+	}
+	coltracepb.RegisterTraceServiceServer(s.server, s)
+
+	return s, nil
+}
+
+// Export implements the OTLP TraceService Export RPC
+func (s *OTLPGRPCServer) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	response, err := s.receiver.export(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return response, nil
+}
+
+// Start starts the gRPC receiver and blocks until the context is cancelled
+func (s *OTLPGRPCServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", ":"+s.config.OTLP.GRPCPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", s.config.OTLP.GRPCPort, err)
+	}
+
+	log.Printf("Starting OTLP gRPC receiver on port %s", s.config.OTLP.GRPCPort)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.server.Serve(listener)
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down OTLP gRPC receiver...")
+		s.server.GracefulStop()
+		return nil
+	case err := <-serveErr:
+		return fmt.Errorf("OTLP gRPC receiver failed: %w", err)
+	}
+}
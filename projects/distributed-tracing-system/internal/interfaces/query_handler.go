@@ -0,0 +1,94 @@
+package interfaces
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// queryTraces handles trace query language requests (GET /api/v1/traces/query?q=...).
+// Results are paginated with limit and offset or cursor and counted on
+// request with count, like searches.
+func (s *ServerWithTelemetry) queryTraces(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "query-traces")
+	defer span.End()
+
+	rawQuery := c.Query("q")
+	if rawQuery == "" {
+		span.SetStatus(codes.Error, "Query is required")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "query parameter q is required",
+		})
+		return
+	}
+	span.SetAttributes(attribute.String("query.text", rawQuery))
+
+	query, err := domain.ParseTraceQuery(rawQuery)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		var syntaxErr *domain.QuerySyntaxError
+		if errors.As(err, &syntaxErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    err.Error(),
+				"message":  syntaxErr.Message,
+				"position": syntaxErr.Position,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	page, err := parseQueryPage(c)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("query.limit", page.Limit),
+		attribute.Int("query.offset", page.Offset),
+		attribute.Bool("query.cursor", page.Cursor != nil),
+	)
+
+	countMode, err := parseCountMode(c)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	traces, err := s.traceService.QueryTraces(ctx, query, page)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	count, err := s.traceService.CountQueryTraces(ctx, query, countMode)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetAttributes(attribute.Int("query.results_count", len(traces)))
+	span.SetStatus(codes.Ok, "Query completed successfully")
+
+	// Query results are ordered like a default search, whose cursor they share
+	c.JSON(http.StatusOK, newSearchResponse(&domain.SearchCriteria{Limit: page.Limit}, traces, count))
+}
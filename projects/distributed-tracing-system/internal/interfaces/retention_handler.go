@@ -0,0 +1,58 @@
+package interfaces
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// purgeTraces triggers a retention purge (POST /api/v1/admin/retention/purge?dry_run=true)
+func (s *ServerWithTelemetry) purgeTraces(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "purge-traces")
+	defer span.End()
+
+	if s.retention == nil {
+		span.SetStatus(codes.Error, "Retention is not enabled")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "retention is not enabled",
+		})
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			span.SetStatus(codes.Error, "Invalid dry_run")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid dry_run: must be true or false",
+			})
+			return
+		}
+		dryRun = parsed
+	}
+	span.SetAttributes(attribute.Bool("retention.dry_run", dryRun))
+
+	report, err := s.retention.Purge(ctx, dryRun)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrPurgeInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("retention.purged", report.Total))
+	span.SetStatus(codes.Ok, "Purge completed successfully")
+
+	c.JSON(http.StatusOK, report)
+}
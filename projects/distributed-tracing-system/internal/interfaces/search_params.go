@@ -0,0 +1,336 @@
+package interfaces
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 1000
+)
+
+// parseSearchCriteria builds search criteria from the query parameters of a
+// search request. Malformed values are reported instead of being ignored.
+//
+// Trace filters: service, operation, status, start_time and end_time
+// (RFC3339), min_duration and max_duration (Go durations such as "250ms"),
+// and tag=key:value (repeatable). A span filter matching traces with at least
+// one span satisfying all of its conditions is built from span.service,
+// span.operation, span.status, span.min_duration, span.max_duration and
+// span.tag=key:value (repeatable).
+//
+// Results are ordered by sort (start_time or duration) and order (desc or
+// asc); cursor continues from the next_cursor of a previous page.
+func parseSearchCriteria(c *gin.Context) (*domain.SearchCriteria, error) {
+	criteria := &domain.SearchCriteria{}
+
+	if service := c.Query("service"); service != "" {
+		serviceName := domain.ServiceName(service)
+		criteria.Service = &serviceName
+	}
+
+	if operation := c.Query("operation"); operation != "" {
+		operationName := domain.OperationName(operation)
+		criteria.Operation = &operationName
+	}
+
+	if status := c.Query("status"); status != "" {
+		traceStatus := domain.TraceStatus(status)
+		switch traceStatus {
+		case domain.TraceStatusSuccess, domain.TraceStatusError, domain.TraceStatusTimeout:
+			criteria.Status = &traceStatus
+		default:
+			return nil, fmt.Errorf("invalid status %q: must be one of success, error, timeout", status)
+		}
+	}
+
+	var err error
+	if criteria.StartTime, err = parseTimeParam(c, "start_time"); err != nil {
+		return nil, err
+	}
+	if criteria.EndTime, err = parseTimeParam(c, "end_time"); err != nil {
+		return nil, err
+	}
+	if criteria.StartTime != nil && criteria.EndTime != nil && criteria.StartTime.After(*criteria.EndTime) {
+		return nil, fmt.Errorf("start_time cannot be after end_time")
+	}
+
+	if criteria.MinDuration, criteria.MaxDuration, err = parseDurationRange(c, "min_duration", "max_duration"); err != nil {
+		return nil, err
+	}
+
+	if criteria.Tags, err = parseTagParams(c, "tag"); err != nil {
+		return nil, err
+	}
+
+	spanFilter, err := parseSpanFilter(c)
+	if err != nil {
+		return nil, err
+	}
+	if !spanFilter.IsEmpty() {
+		criteria.SpanFilters = []domain.SpanFilter{spanFilter}
+	}
+
+	if criteria.Limit, criteria.Offset, err = parsePagination(c); err != nil {
+		return nil, err
+	}
+
+	if err := parseSortParams(c, criteria); err != nil {
+		return nil, err
+	}
+
+	return criteria, nil
+}
+
+// parseSortParams parses the sort, order and cursor query parameters
+func parseSortParams(c *gin.Context, criteria *domain.SearchCriteria) error {
+	if sortBy := c.Query("sort"); sortBy != "" {
+		field := domain.TraceSortField(sortBy)
+		if field != domain.SortByStartTime && field != domain.SortByDuration {
+			return fmt.Errorf("invalid sort %q: must be one of start_time, duration", sortBy)
+		}
+		criteria.SortBy = field
+	}
+
+	if order := c.Query("order"); order != "" {
+		sortOrder := domain.SortOrder(order)
+		if sortOrder != domain.SortAscending && sortOrder != domain.SortDescending {
+			return fmt.Errorf("invalid order %q: must be one of asc, desc", order)
+		}
+		criteria.SortOrder = sortOrder
+	}
+
+	token := c.Query("cursor")
+	if token == "" {
+		return nil
+	}
+	if criteria.Offset > 0 {
+		return fmt.Errorf("cursor cannot be combined with offset")
+	}
+
+	cursor, err := domain.DecodeTraceCursor(token)
+	if err != nil {
+		return err
+	}
+	if cursor.SortBy != criteria.SortField() || cursor.SortOrder != criteria.SortDirection() {
+		return fmt.Errorf("%w: cursor was issued for a different sort order", domain.ErrInvalidCursor)
+	}
+	criteria.Cursor = cursor
+
+	return nil
+}
+
+// parseQueryPage parses the limit, offset and cursor query parameters of a
+// trace query, whose results are ordered newest first
+func parseQueryPage(c *gin.Context) (domain.TracePage, error) {
+	var page domain.TracePage
+	var err error
+	if page.Limit, page.Offset, err = parsePagination(c); err != nil {
+		return domain.TracePage{}, err
+	}
+
+	token := c.Query("cursor")
+	if token == "" {
+		return page, nil
+	}
+	if page.Offset > 0 {
+		return domain.TracePage{}, fmt.Errorf("cursor cannot be combined with offset")
+	}
+
+	cursor, err := domain.DecodeTraceCursor(token)
+	if err != nil {
+		return domain.TracePage{}, err
+	}
+	if cursor.SortBy != domain.SortByStartTime || cursor.SortOrder != domain.SortDescending {
+		return domain.TracePage{}, fmt.Errorf("%w: cursor was issued for a different sort order", domain.ErrInvalidCursor)
+	}
+	page.Cursor = cursor
+
+	return page, nil
+}
+
+// parseCountMode parses the count query parameter (none, exact or estimate)
+func parseCountMode(c *gin.Context) (domain.CountMode, error) {
+	mode := domain.CountMode(c.DefaultQuery("count", string(domain.CountNone)))
+	switch mode {
+	case domain.CountNone, domain.CountExact, domain.CountEstimate:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid count %q: must be one of none, exact, estimate", mode)
+	}
+}
+
+// newSearchResponse builds the search response body with the cursor for the
+// next page and, when requested, the total count
+func newSearchResponse(criteria *domain.SearchCriteria, traces []*domain.Trace, count *domain.TraceCount) gin.H {
+	if traces == nil {
+		traces = []*domain.Trace{}
+	}
+
+	response := gin.H{
+		"traces": traces,
+		"limit":  criteria.Limit,
+	}
+	if next := domain.NextTraceCursor(criteria, traces); next != nil {
+		response["next_cursor"] = next.Encode()
+	}
+	if count != nil {
+		response["total"] = count.Value
+		response["total_estimated"] = count.Estimated
+	}
+	return response
+}
+
+// parsePagination parses the limit and offset query parameters
+func parsePagination(c *gin.Context) (int, int, error) {
+	limit := defaultSearchLimit
+	offset := 0
+
+	if value := c.Query("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l <= 0 || l > maxSearchLimit {
+			return 0, 0, fmt.Errorf("invalid limit %q: must be an integer between 1 and %d", value, maxSearchLimit)
+		}
+		limit = l
+	}
+
+	if value := c.Query("offset"); value != "" {
+		o, err := strconv.Atoi(value)
+		if err != nil || o < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q: must be a non-negative integer", value)
+		}
+		offset = o
+	}
+
+	return limit, offset, nil
+}
+
+// parseSpanFilter builds a span filter from the span.* query parameters
+func parseSpanFilter(c *gin.Context) (domain.SpanFilter, error) {
+	var filter domain.SpanFilter
+
+	if service := c.Query("span.service"); service != "" {
+		serviceName := domain.ServiceName(service)
+		filter.Service = &serviceName
+	}
+
+	if operation := c.Query("span.operation"); operation != "" {
+		operationName := domain.OperationName(operation)
+		filter.Operation = &operationName
+	}
+
+	if status := c.Query("span.status"); status != "" {
+		spanStatus := domain.SpanStatus(status)
+		switch spanStatus {
+		case domain.SpanStatusOK, domain.SpanStatusError:
+			filter.Status = &spanStatus
+		default:
+			return filter, fmt.Errorf("invalid span.status %q: must be one of ok, error", status)
+		}
+	}
+
+	var err error
+	if filter.MinDuration, filter.MaxDuration, err = parseDurationRange(c, "span.min_duration", "span.max_duration"); err != nil {
+		return filter, err
+	}
+
+	if filter.Tags, err = parseTagParams(c, "span.tag"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam parses an optional RFC3339 timestamp parameter
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: must be an RFC3339 timestamp", name, value)
+	}
+	return &t, nil
+}
+
+// parseTimeWindow parses the start and end parameters of a time window.
+// end defaults to now and start to window before end.
+func parseTimeWindow(c *gin.Context, window time.Duration) (time.Time, time.Time, error) {
+	start, err := parseTimeParam(c, "start")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := parseTimeParam(c, "end")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if end == nil {
+		now := time.Now()
+		end = &now
+	}
+	if start == nil {
+		from := end.Add(-window)
+		start = &from
+	}
+	if !start.Before(*end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start must be before end")
+	}
+	return *start, *end, nil
+}
+
+// parseDurationRange parses an optional min/max duration parameter pair
+func parseDurationRange(c *gin.Context, minName, maxName string) (*time.Duration, *time.Duration, error) {
+	min, err := parseDurationParam(c, minName)
+	if err != nil {
+		return nil, nil, err
+	}
+	max, err := parseDurationParam(c, maxName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if min != nil && max != nil && *min > *max {
+		return nil, nil, fmt.Errorf("%s cannot be greater than %s", minName, maxName)
+	}
+	return min, max, nil
+}
+
+// parseDurationParam parses an optional non-negative duration parameter
+func parseDurationParam(c *gin.Context, name string) (*time.Duration, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return nil, fmt.Errorf("invalid %s %q: must be a non-negative duration such as 250ms or 2s", name, value)
+	}
+	return &d, nil
+}
+
+// parseTagParams parses repeated key:value tag parameters
+func parseTagParams(c *gin.Context, name string) (map[string]string, error) {
+	values := c.QueryArray(name)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, ok := strings.Cut(value, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid %s %q: must be in key:value form", name, value)
+		}
+		tags[key] = tagValue
+	}
+	return tags, nil
+}
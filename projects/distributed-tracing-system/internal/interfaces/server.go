@@ -2,7 +2,7 @@ package interfaces
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -120,35 +120,20 @@ func (s *Server) healthCheck(c *gin.Context) {
 // searchTraces handles trace search requests
 func (s *Server) searchTraces(c *gin.Context) {
 	// Parse query parameters
-	criteria := &domain.SearchCriteria{
-		Limit:  10,
-		Offset: 0,
-	}
-
-	// Get service filter
-	if service := c.Query("service"); service != "" {
-		serviceName := domain.ServiceName(service)
-		criteria.Service = &serviceName
-	}
-
-	// Get operation filter
-	if operation := c.Query("operation"); operation != "" {
-		operationName := domain.OperationName(operation)
-		criteria.Operation = &operationName
-	}
-
-	// Get limit
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := parseInt(limit); err == nil && l > 0 {
-			criteria.Limit = l
-		}
+	criteria, err := parseSearchCriteria(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	// Get offset
-	if offset := c.Query("offset"); offset != "" {
-		if o, err := parseInt(offset); err == nil && o >= 0 {
-			criteria.Offset = o
-		}
+	countMode, err := parseCountMode(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
 	// Search traces
@@ -160,10 +145,15 @@ func (s *Server) searchTraces(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"traces": traces,
-		"total":  len(traces),
-	})
+	count, err := s.traceService.CountTraces(c.Request.Context(), criteria, countMode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newSearchResponse(criteria, traces, count))
 }
 
 // getTrace handles get trace by ID requests
@@ -177,7 +167,7 @@ func (s *Server) getTrace(c *gin.Context) {
 	}
 
 	trace, err := s.traceService.GetTrace(c.Request.Context(), traceID)
-	if err != nil {
+	if err != nil && !errors.Is(err, domain.ErrTraceNotFound) {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -235,7 +225,16 @@ func (s *Server) getOperations(c *gin.Context) {
 
 // getMetrics handles get metrics requests
 func (s *Server) getMetrics(c *gin.Context) {
-	metrics, err := s.traceService.GetMetrics(c.Request.Context())
+	start, end, err := parseTimeWindow(c, defaultMetricsWindow)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	query := domain.MetricsQuery{Start: start, End: end, Service: domain.ServiceName(c.Query("service"))}
+	metrics, err := s.traceService.GetMetrics(c.Request.Context(), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -245,11 +244,3 @@ func (s *Server) getMetrics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, metrics)
 }
-
-// Helper function to parse integers
-func parseInt(s string) (int, error) {
-	var result int
-	_, err := fmt.Sscanf(s, "%d", &result)
-	return result, err
-}
-
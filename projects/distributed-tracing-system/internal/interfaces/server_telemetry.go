@@ -2,7 +2,7 @@ package interfaces
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -15,17 +15,73 @@ import (
 	"go.opentelemetry.io/otel/codes"
 )
 
+// defaultMetricsWindow is the window of the trace metrics when no start is given
+const defaultMetricsWindow = time.Hour
+
 // ServerWithTelemetry represents the HTTP server with OpenTelemetry instrumentation
 type ServerWithTelemetry struct {
 	config           *config.Config
 	traceService     domain.TraceService
 	telemetryManager *telemetry.TelemetryManager
+	otlpReceiver     *otlpReceiver
+	retention        domain.RetentionManager
+	dependencies     domain.DependencyService
+	slos             domain.SLOService
+	anomalies        domain.AnomalyDetector
+	tagCatalog       domain.TagCatalog
+	assembler        domain.SpanAssembler
 	router           *gin.Engine
 	server           *http.Server
 }
 
+// ServerOption configures optional features of ServerWithTelemetry
+type ServerOption func(*ServerWithTelemetry)
+
+// WithRetentionManager enables the retention admin endpoint
+func WithRetentionManager(manager domain.RetentionManager) ServerOption {
+	return func(s *ServerWithTelemetry) {
+		s.retention = manager
+	}
+}
+
+// WithDependencyService enables the service dependency graph endpoint
+func WithDependencyService(dependencies domain.DependencyService) ServerOption {
+	return func(s *ServerWithTelemetry) {
+		s.dependencies = dependencies
+	}
+}
+
+// WithSLOService enables the SLO endpoints
+func WithSLOService(slos domain.SLOService) ServerOption {
+	return func(s *ServerWithTelemetry) {
+		s.slos = slos
+	}
+}
+
+// WithAnomalyDetector enables the anomalies endpoint
+func WithAnomalyDetector(detector domain.AnomalyDetector) ServerOption {
+	return func(s *ServerWithTelemetry) {
+		s.anomalies = detector
+	}
+}
+
+// WithTagCatalog enables the Tempo tag search endpoints
+func WithTagCatalog(catalog domain.TagCatalog) ServerOption {
+	return func(s *ServerWithTelemetry) {
+		s.tagCatalog = catalog
+	}
+}
+
+// WithSpanAssembler makes the OTLP and Zipkin receivers hand spans to the
+// span assembler instead of processing each request's traces on their own
+func WithSpanAssembler(assembler domain.SpanAssembler) ServerOption {
+	return func(s *ServerWithTelemetry) {
+		s.assembler = assembler
+	}
+}
+
 // NewServerWithTelemetry creates a new server instance with telemetry
-func NewServerWithTelemetry(cfg *config.Config, traceService domain.TraceService, telemetryManager *telemetry.TelemetryManager) (*ServerWithTelemetry, error) {
+func NewServerWithTelemetry(cfg *config.Config, traceService domain.TraceService, telemetryManager *telemetry.TelemetryManager, opts ...ServerOption) (*ServerWithTelemetry, error) {
 	// Set Gin mode
 	if cfg.Logging.Level == "debug" {
 		gin.SetMode(gin.DebugMode)
@@ -63,6 +119,10 @@ func NewServerWithTelemetry(cfg *config.Config, traceService domain.TraceService
 		router:           router,
 		server:           server,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.otlpReceiver = newOTLPReceiver(traceService, s.assembler)
 
 	// Setup routes
 	s.setupRoutes()
@@ -103,14 +163,43 @@ func (s *ServerWithTelemetry) setupRoutes() {
 	// Health check
 	s.router.GET("/health", s.healthCheck)
 
+	// OTLP/HTTP receiver
+	s.router.POST("/v1/traces", s.receiveOTLPTraces)
+
+	// Zipkin v2 receiver
+	s.router.POST("/api/v2/spans", s.receiveZipkinSpans)
+
+	// Jaeger query API, for the Jaeger UI and Grafana's Jaeger data source
+	jaeger := s.router.Group("/api")
+	{
+		jaeger.GET("/services", s.getJaegerServices)
+		jaeger.GET("/services/:service/operations", s.getJaegerOperations)
+		jaeger.GET("/traces", s.findJaegerTraces)
+		jaeger.GET("/traces/:id", s.getTraceByID)
+		jaeger.GET("/dependencies", s.getJaegerDependencies)
+	}
+
+	// Tempo API, for Grafana's Tempo data source
+	tempo := s.router.Group("/api")
+	{
+		tempo.GET("/echo", s.tempoEcho)
+		tempo.GET("/search", s.searchTempoTraces)
+		tempo.GET("/search/tags", s.getTempoTagNames)
+		tempo.GET("/search/tag/:name/values", s.getTempoTagValues)
+		tempo.GET("/v2/traces/:id", s.getTempoTraceV2)
+	}
+
 	// API v1 routes
 	v1 := s.router.Group("/api/v1")
 	{
 		// Trace routes
 		traces := v1.Group("/traces")
 		{
+			traces.POST("", s.ingestTraces)
 			traces.GET("/search", s.searchTraces)
+			traces.GET("/query", s.queryTraces)
 			traces.GET("/:id", s.getTrace)
+			traces.GET("/:id/analysis", s.analyzeTrace)
 		}
 
 		// Service routes
@@ -120,11 +209,34 @@ func (s *ServerWithTelemetry) setupRoutes() {
 			services.GET("/:service/operations", s.getOperations)
 		}
 
+		// Dependency routes
+		v1.GET("/dependencies", s.getDependencies)
+
 		// Metrics routes
 		metrics := v1.Group("/metrics")
 		{
 			metrics.GET("", s.getMetrics)
 		}
+
+		// Anomaly routes
+		v1.GET("/anomalies", s.getAnomalies)
+
+		// SLO routes
+		slos := v1.Group("/slos")
+		{
+			slos.GET("", s.listSLOs)
+			slos.POST("", s.createSLO)
+			slos.GET("/:id", s.getSLO)
+			slos.PUT("/:id", s.updateSLO)
+			slos.DELETE("/:id", s.deleteSLO)
+			slos.GET("/:id/status", s.getSLOStatus)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		{
+			admin.POST("/retention/purge", s.purgeTraces)
+		}
 	}
 }
 
@@ -149,39 +261,38 @@ func (s *ServerWithTelemetry) searchTraces(c *gin.Context) {
 	defer span.End()
 
 	// Parse query parameters
-	criteria := &domain.SearchCriteria{
-		Limit:  10,
-		Offset: 0,
-	}
-
-	// Get service filter
-	if service := c.Query("service"); service != "" {
-		serviceName := domain.ServiceName(service)
-		criteria.Service = &serviceName
-		span.SetAttributes(attribute.String("search.service", service))
+	criteria, err := parseSearchCriteria(c)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	// Get operation filter
-	if operation := c.Query("operation"); operation != "" {
-		operationName := domain.OperationName(operation)
-		criteria.Operation = &operationName
-		span.SetAttributes(attribute.String("search.operation", operation))
+	if criteria.Service != nil {
+		span.SetAttributes(attribute.String("search.service", string(*criteria.Service)))
 	}
-
-	// Get limit
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := parseIntTelemetry(limit); err == nil && l > 0 {
-			criteria.Limit = l
-			span.SetAttributes(attribute.Int("search.limit", l))
-		}
+	if criteria.Operation != nil {
+		span.SetAttributes(attribute.String("search.operation", string(*criteria.Operation)))
 	}
+	span.SetAttributes(
+		attribute.Int("search.limit", criteria.Limit),
+		attribute.Int("search.offset", criteria.Offset),
+		attribute.Int("search.tag_filters", len(criteria.Tags)),
+		attribute.Int("search.span_filters", len(criteria.SpanFilters)),
+		attribute.String("search.sort", string(criteria.SortField())),
+		attribute.String("search.order", string(criteria.SortDirection())),
+		attribute.Bool("search.cursor", criteria.Cursor != nil),
+	)
 
-	// Get offset
-	if offset := c.Query("offset"); offset != "" {
-		if o, err := parseIntTelemetry(offset); err == nil && o >= 0 {
-			criteria.Offset = o
-			span.SetAttributes(attribute.Int("search.offset", o))
-		}
+	countMode, err := parseCountMode(c)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
 	// Search traces
@@ -194,13 +305,20 @@ func (s *ServerWithTelemetry) searchTraces(c *gin.Context) {
 		return
 	}
 
+	// Count matching traces when requested
+	count, err := s.traceService.CountTraces(ctx, criteria, countMode)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	span.SetAttributes(attribute.Int("search.results_count", len(traces)))
 	span.SetStatus(codes.Ok, "Search completed successfully")
 
-	c.JSON(http.StatusOK, gin.H{
-		"traces": traces,
-		"total":  len(traces),
-	})
+	c.JSON(http.StatusOK, newSearchResponse(criteria, traces, count))
 }
 
 // getTrace handles get trace by ID requests
@@ -221,7 +339,7 @@ func (s *ServerWithTelemetry) getTrace(c *gin.Context) {
 	span.SetAttributes(attribute.String("trace.id", string(traceID)))
 
 	trace, err := s.traceService.GetTrace(ctx, traceID)
-	if err != nil {
+	if err != nil && !errors.Is(err, domain.ErrTraceNotFound) {
 		span.SetStatus(codes.Error, err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -306,12 +424,30 @@ func (s *ServerWithTelemetry) getOperations(c *gin.Context) {
 }
 
 // getMetrics handles get metrics requests
+// (GET /api/v1/metrics?start=&end=&service=). start and end are RFC3339
+// timestamps; end defaults to now and start to an hour before end.
 func (s *ServerWithTelemetry) getMetrics(c *gin.Context) {
 	// Create a span for get metrics operation
 	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "get-metrics")
 	defer span.End()
 
-	metrics, err := s.traceService.GetMetrics(ctx)
+	start, end, err := parseTimeWindow(c, defaultMetricsWindow)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	query := domain.MetricsQuery{Start: start, End: end, Service: domain.ServiceName(c.Query("service"))}
+
+	span.SetAttributes(
+		attribute.String("metrics.start", start.Format(time.RFC3339)),
+		attribute.String("metrics.end", end.Format(time.RFC3339)),
+		attribute.String("metrics.service", string(query.Service)),
+	)
+
+	metrics, err := s.traceService.GetMetrics(ctx, query)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -324,10 +460,3 @@ func (s *ServerWithTelemetry) getMetrics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, metrics)
 }
-
-// Helper function to parse integers
-func parseIntTelemetry(s string) (int, error) {
-	var result int
-	_, err := fmt.Sscanf(s, "%d", &result)
-	return result, err
-}
@@ -0,0 +1,352 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sloResource is the API representation of an SLO. Durations are strings
+// such as "250ms" or "30d".
+type sloResource struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Service   string    `json:"service"`
+	Operation string    `json:"operation,omitempty"`
+	Threshold string    `json:"threshold"`
+	Target    float64   `json:"target"`
+	Window    string    `json:"window"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// burnRateResource is the API representation of a burn rate rule status
+type burnRateResource struct {
+	Severity      domain.AlertSeverity `json:"severity"`
+	LongWindow    string               `json:"long_window"`
+	ShortWindow   string               `json:"short_window"`
+	Threshold     float64              `json:"threshold"`
+	LongBurnRate  float64              `json:"long_burn_rate"`
+	ShortBurnRate float64              `json:"short_burn_rate"`
+	Firing        bool                 `json:"firing"`
+}
+
+// sloStatusResource is the API representation of an SLO evaluation
+type sloStatusResource struct {
+	SLO                  sloResource        `json:"slo"`
+	EvaluatedAt          time.Time          `json:"evaluated_at"`
+	TotalCount           int64              `json:"total_count"`
+	GoodCount            int64              `json:"good_count"`
+	SLI                  float64            `json:"sli"`
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"`
+	BurnRates            []burnRateResource `json:"burn_rates"`
+}
+
+// newSLOResource converts an SLO to its API representation
+func newSLOResource(slo *domain.SLO) sloResource {
+	return sloResource{
+		ID:        slo.ID,
+		Name:      slo.Name,
+		Service:   string(slo.Service),
+		Operation: string(slo.Operation),
+		Threshold: formatSLODuration(slo.Threshold),
+		Target:    slo.Target,
+		Window:    formatSLODuration(slo.Window),
+		CreatedAt: slo.CreatedAt,
+		UpdatedAt: slo.UpdatedAt,
+	}
+}
+
+// newSLOStatusResource converts an SLO evaluation to its API representation
+func newSLOStatusResource(status *domain.SLOStatus) sloStatusResource {
+	resource := sloStatusResource{
+		SLO:                  newSLOResource(status.SLO),
+		EvaluatedAt:          status.EvaluatedAt,
+		TotalCount:           status.TotalCount,
+		GoodCount:            status.GoodCount,
+		SLI:                  status.SLI,
+		ErrorBudgetRemaining: status.ErrorBudgetRemaining,
+		BurnRates:            make([]burnRateResource, 0, len(status.BurnRates)),
+	}
+	for _, rate := range status.BurnRates {
+		resource.BurnRates = append(resource.BurnRates, burnRateResource{
+			Severity:      rate.Severity,
+			LongWindow:    formatSLODuration(rate.LongWindow),
+			ShortWindow:   formatSLODuration(rate.ShortWindow),
+			Threshold:     rate.Threshold,
+			LongBurnRate:  rate.LongBurnRate,
+			ShortBurnRate: rate.ShortBurnRate,
+			Firing:        rate.Firing,
+		})
+	}
+	return resource
+}
+
+// toSLO converts the API representation of an SLO to a validated SLO
+func (r sloResource) toSLO() (*domain.SLO, error) {
+	threshold, err := parseSLODuration(r.Threshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold: %w", err)
+	}
+	window, err := parseSLODuration(r.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window: %w", err)
+	}
+
+	slo := &domain.SLO{
+		ID:        r.ID,
+		Name:      r.Name,
+		Service:   domain.ServiceName(r.Service),
+		Operation: domain.OperationName(r.Operation),
+		Threshold: threshold,
+		Target:    r.Target,
+		Window:    window,
+	}
+	if err := slo.Validate(); err != nil {
+		return nil, err
+	}
+	return slo, nil
+}
+
+// parseSLODuration parses a Go duration or a number of days such as "30d"
+func parseSLODuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// formatSLODuration formats whole days as "Nd" and other durations as Go durations
+func formatSLODuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d > 0 && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
+
+// slosEnabled responds with 503 when SLOs are not enabled
+func (s *ServerWithTelemetry) slosEnabled(c *gin.Context, span trace.Span) bool {
+	if s.slos != nil {
+		return true
+	}
+	span.SetStatus(codes.Error, "SLOs are not enabled")
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error": "slos are not enabled",
+	})
+	return false
+}
+
+// bindSLO decodes and validates the SLO of the request body, responding
+// with 400 when it is invalid
+func bindSLO(c *gin.Context, span trace.Span) (*domain.SLO, bool) {
+	var resource sloResource
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		span.SetStatus(codes.Error, "Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	slo, err := resource.toSLO()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	span.SetAttributes(
+		attribute.String("slo.service", string(slo.Service)),
+		attribute.String("slo.operation", string(slo.Operation)),
+	)
+	return slo, true
+}
+
+// sloError responds with 404 for missing SLOs and 500 otherwise
+func sloError(c *gin.Context, span trace.Span, err error) {
+	span.SetStatus(codes.Error, err.Error())
+	status := http.StatusInternalServerError
+	if errors.Is(err, domain.ErrSLONotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// listSLOs handles SLO list requests (GET /api/v1/slos)
+func (s *ServerWithTelemetry) listSLOs(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "list-slos")
+	defer span.End()
+
+	if !s.slosEnabled(c, span) {
+		return
+	}
+
+	slos, err := s.slos.ListSLOs(ctx)
+	if err != nil {
+		sloError(c, span, err)
+		return
+	}
+
+	resources := make([]sloResource, 0, len(slos))
+	for _, slo := range slos {
+		resources = append(resources, newSLOResource(slo))
+	}
+
+	span.SetAttributes(attribute.Int("slos.count", len(resources)))
+	span.SetStatus(codes.Ok, "SLOs retrieved successfully")
+
+	c.JSON(http.StatusOK, gin.H{
+		"slos":  resources,
+		"count": len(resources),
+	})
+}
+
+// createSLO handles SLO creation requests (POST /api/v1/slos)
+func (s *ServerWithTelemetry) createSLO(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "create-slo")
+	defer span.End()
+
+	if !s.slosEnabled(c, span) {
+		return
+	}
+
+	slo, ok := bindSLO(c, span)
+	if !ok {
+		return
+	}
+
+	created, err := s.slos.CreateSLO(ctx, slo)
+	if err != nil {
+		sloError(c, span, err)
+		return
+	}
+
+	span.SetAttributes(attribute.String("slo.id", created.ID))
+	span.SetStatus(codes.Ok, "SLO created successfully")
+
+	c.JSON(http.StatusCreated, newSLOResource(created))
+}
+
+// getSLO handles SLO requests (GET /api/v1/slos/:id)
+func (s *ServerWithTelemetry) getSLO(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "get-slo")
+	defer span.End()
+
+	if !s.slosEnabled(c, span) {
+		return
+	}
+
+	id := c.Param("id")
+	span.SetAttributes(attribute.String("slo.id", id))
+
+	slo, err := s.slos.GetSLO(ctx, id)
+	if err != nil {
+		sloError(c, span, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "SLO retrieved successfully")
+
+	c.JSON(http.StatusOK, newSLOResource(slo))
+}
+
+// updateSLO handles SLO replacement requests (PUT /api/v1/slos/:id)
+func (s *ServerWithTelemetry) updateSLO(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "update-slo")
+	defer span.End()
+
+	if !s.slosEnabled(c, span) {
+		return
+	}
+
+	id := c.Param("id")
+	span.SetAttributes(attribute.String("slo.id", id))
+
+	slo, ok := bindSLO(c, span)
+	if !ok {
+		return
+	}
+	slo.ID = id
+
+	updated, err := s.slos.UpdateSLO(ctx, slo)
+	if err != nil {
+		sloError(c, span, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "SLO updated successfully")
+
+	c.JSON(http.StatusOK, newSLOResource(updated))
+}
+
+// deleteSLO handles SLO deletion requests (DELETE /api/v1/slos/:id)
+func (s *ServerWithTelemetry) deleteSLO(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "delete-slo")
+	defer span.End()
+
+	if !s.slosEnabled(c, span) {
+		return
+	}
+
+	id := c.Param("id")
+	span.SetAttributes(attribute.String("slo.id", id))
+
+	if err := s.slos.DeleteSLO(ctx, id); err != nil {
+		sloError(c, span, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "SLO deleted successfully")
+
+	c.Status(http.StatusNoContent)
+}
+
+// getSLOStatus handles SLO evaluation requests (GET /api/v1/slos/:id/status)
+// reporting the SLI, the remaining error budget and every burn rate
+func (s *ServerWithTelemetry) getSLOStatus(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "get-slo-status")
+	defer span.End()
+
+	if !s.slosEnabled(c, span) {
+		return
+	}
+
+	id := c.Param("id")
+	span.SetAttributes(attribute.String("slo.id", id))
+
+	status, err := s.slos.GetStatus(ctx, id)
+	if err != nil {
+		sloError(c, span, err)
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Float64("slo.sli", status.SLI),
+		attribute.Float64("slo.error_budget_remaining", status.ErrorBudgetRemaining),
+	)
+	span.SetStatus(codes.Ok, "SLO status retrieved successfully")
+
+	c.JSON(http.StatusOK, newSLOStatusResource(status))
+}
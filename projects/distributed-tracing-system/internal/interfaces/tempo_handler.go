@@ -0,0 +1,319 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// contentTypeTempoProtobuf is the media type Grafana asks Tempo for traces in
+	contentTypeTempoProtobuf = "application/protobuf"
+	// defaultTempoLimit matches the default search limit of Tempo
+	defaultTempoLimit = 20
+	// Tempo search tags that match span fields instead of span tags
+	tempoNameTag   = "name"
+	tempoStatusTag = "status.code"
+)
+
+// acceptsProtobuf reports whether the client asks for a protobuf response
+func acceptsProtobuf(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	return strings.Contains(accept, contentTypeTempoProtobuf) || strings.Contains(accept, contentTypeProtobuf)
+}
+
+// tempoEcho answers the connection check of Grafana's Tempo data source (GET /api/echo)
+func (s *ServerWithTelemetry) tempoEcho(c *gin.Context) {
+	c.String(http.StatusOK, "echo")
+}
+
+// getTraceByID handles GET /api/traces/:id for both query APIs served at
+// that path: Grafana's Tempo data source asks for protobuf, while Jaeger
+// clients expect JSON
+func (s *ServerWithTelemetry) getTraceByID(c *gin.Context) {
+	if acceptsProtobuf(c) {
+		s.serveTempoTrace(c, "tempo-get-trace", false)
+		return
+	}
+	s.getJaegerTrace(c)
+}
+
+// getTempoTraceV2 handles Tempo v2 trace lookups (GET /api/v2/traces/:id)
+func (s *ServerWithTelemetry) getTempoTraceV2(c *gin.Context) {
+	s.serveTempoTrace(c, "tempo-get-trace-v2", true)
+}
+
+// serveTempoTrace writes a trace in the format of Tempo's trace by ID API.
+// The v1 API answers with a trace message in protobuf; the v2 API wraps it
+// in a response, in protobuf when asked for and in JSON otherwise.
+func (s *ServerWithTelemetry) serveTempoTrace(c *gin.Context, operation string, v2 bool) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), operation)
+	defer span.End()
+
+	traceID := domain.TraceID(c.Param("id"))
+	span.SetAttributes(attribute.String("trace.id", string(traceID)))
+
+	trace, err := s.traceService.GetTrace(ctx, traceID)
+	if err != nil && !errors.Is(err, domain.ErrTraceNotFound) {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if trace == nil {
+		span.SetStatus(codes.Error, "Trace not found")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "trace not found",
+		})
+		return
+	}
+
+	var body []byte
+	contentType := contentTypeTempoProtobuf
+	switch {
+	case !v2:
+		body, err = infrastructure.TempoTraceProto(trace)
+	case acceptsProtobuf(c):
+		body, err = infrastructure.TempoTraceByIDProto(trace)
+	default:
+		body, err = infrastructure.TempoTraceByIDJSON(trace)
+		contentType = contentTypeJSON
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	span.SetStatus(codes.Ok, "Trace retrieved successfully")
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// searchTempoTraces handles Tempo trace searches (GET /api/search). With q
+// the TraceQL-lite query is evaluated (start and end do not apply);
+// otherwise traces are searched by tags as described at parseTempoSearch.
+func (s *ServerWithTelemetry) searchTempoTraces(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "tempo-search-traces")
+	defer span.End()
+
+	limit := defaultTempoLimit
+	if value := c.Query("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l <= 0 || l > maxSearchLimit {
+			span.SetStatus(codes.Error, "Invalid limit")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("invalid limit %q: must be an integer between 1 and %d", value, maxSearchLimit),
+			})
+			return
+		}
+		limit = l
+	}
+	span.SetAttributes(attribute.Int("search.limit", limit))
+
+	var traces []*domain.Trace
+	if rawQuery := c.Query("q"); rawQuery != "" {
+		span.SetAttributes(attribute.String("query.text", rawQuery))
+
+		query, err := domain.ParseTraceQL(rawQuery)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if traces, err = s.traceService.QueryTraces(ctx, query, domain.TracePage{Limit: limit}); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	} else {
+		criteria, err := parseTempoSearch(c, limit)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if traces, err = s.traceService.SearchTraces(ctx, criteria); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	summaries := make([]infrastructure.TempoTraceSummary, 0, len(traces))
+	for _, trace := range traces {
+		summaries = append(summaries, infrastructure.TraceToTempoSummary(trace))
+	}
+
+	span.SetAttributes(attribute.Int("search.results_count", len(summaries)))
+	span.SetStatus(codes.Ok, "Search completed successfully")
+
+	c.JSON(http.StatusOK, gin.H{
+		"traces": summaries,
+	})
+}
+
+// getTempoTagNames handles Tempo tag name requests (GET /api/search/tags)
+func (s *ServerWithTelemetry) getTempoTagNames(c *gin.Context) {
+	_, span := s.telemetryManager.StartSpan(c.Request.Context(), "tempo-get-tag-names")
+	defer span.End()
+
+	if s.tagCatalog == nil {
+		span.SetStatus(codes.Error, "Tag catalog is not enabled")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "tag catalog is not enabled",
+		})
+		return
+	}
+
+	names := s.tagCatalog.TagNames()
+	span.SetAttributes(attribute.Int("tags.count", len(names)))
+	span.SetStatus(codes.Ok, "Tag names retrieved successfully")
+
+	c.JSON(http.StatusOK, gin.H{
+		"tagNames": names,
+	})
+}
+
+// getTempoTagValues handles Tempo tag value requests
+// (GET /api/search/tag/:name/values)
+func (s *ServerWithTelemetry) getTempoTagValues(c *gin.Context) {
+	_, span := s.telemetryManager.StartSpan(c.Request.Context(), "tempo-get-tag-values")
+	defer span.End()
+
+	if s.tagCatalog == nil {
+		span.SetStatus(codes.Error, "Tag catalog is not enabled")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "tag catalog is not enabled",
+		})
+		return
+	}
+
+	name := c.Param("name")
+	values := s.tagCatalog.TagValues(name)
+	span.SetAttributes(
+		attribute.String("tag.name", name),
+		attribute.Int("tag.values_count", len(values)),
+	)
+	span.SetStatus(codes.Ok, "Tag values retrieved successfully")
+
+	c.JSON(http.StatusOK, gin.H{
+		"tagValues": values,
+	})
+}
+
+// parseTempoSearch builds search criteria from the query parameters of a
+// Tempo tag search:
+//
+// tags holds logfmt key=value pairs (values may be double quoted) that must
+// all match the same span; service.name, name and status.code (error, ok or
+// unset) match the span's service, operation and status, other keys its tags.
+// minDuration and maxDuration bound the trace duration; start and end are
+// Unix seconds.
+func parseTempoSearch(c *gin.Context, limit int) (*domain.SearchCriteria, error) {
+	criteria := &domain.SearchCriteria{Limit: limit}
+
+	tags, err := parseLogfmt(c.Query("tags"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tags %q: %w", c.Query("tags"), err)
+	}
+
+	var filter domain.SpanFilter
+	for key, value := range tags {
+		switch key {
+		case domain.ServiceNameTag:
+			serviceName := domain.ServiceName(value)
+			filter.Service = &serviceName
+		case tempoNameTag:
+			operationName := domain.OperationName(value)
+			filter.Operation = &operationName
+		case tempoStatusTag:
+			status := domain.SpanStatusOK
+			switch value {
+			case "error":
+				status = domain.SpanStatusError
+			case "ok", "unset":
+			default:
+				return nil, fmt.Errorf("invalid %s %q: must be one of error, ok, unset", tempoStatusTag, value)
+			}
+			filter.Status = &status
+		default:
+			if filter.Tags == nil {
+				filter.Tags = make(map[string]string)
+			}
+			filter.Tags[key] = value
+		}
+	}
+	if !filter.IsEmpty() {
+		criteria.SpanFilters = []domain.SpanFilter{filter}
+	}
+
+	if criteria.MinDuration, criteria.MaxDuration, err = parseDurationRange(c, "minDuration", "maxDuration"); err != nil {
+		return nil, err
+	}
+
+	if criteria.StartTime, err = parseUnixParam(c, "start", time.Second); err != nil {
+		return nil, err
+	}
+	if criteria.EndTime, err = parseUnixParam(c, "end", time.Second); err != nil {
+		return nil, err
+	}
+	if criteria.StartTime != nil && criteria.EndTime != nil && criteria.StartTime.After(*criteria.EndTime) {
+		return nil, fmt.Errorf("start cannot be after end")
+	}
+
+	return criteria, nil
+}
+
+// parseLogfmt parses space separated key=value pairs; values containing
+// spaces are double quoted
+func parseLogfmt(input string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	rest := strings.TrimSpace(input)
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"") {
+			return nil, fmt.Errorf("expected key=value pairs")
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated value of %s", key)
+			}
+			pairs[key], _ = strconv.Unquote(quoted)
+			rest = value[len(quoted):]
+		} else {
+			end := strings.IndexAny(value, " \t")
+			if end < 0 {
+				end = len(value)
+			}
+			pairs[key] = value[:end]
+			rest = value[end:]
+		}
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	return pairs, nil
+}
@@ -0,0 +1,90 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/streamforge/distributed-tracing-system/internal/infrastructure"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// receiveZipkinSpans handles Zipkin v2 JSON span uploads. Like a Zipkin
+// collector it answers 202 once the spans are stored, or buffered when the
+// span assembler is enabled; spans and traces that cannot be mapped are
+// skipped.
+func (s *ServerWithTelemetry) receiveZipkinSpans(c *gin.Context) {
+	ctx, span := s.telemetryManager.StartSpan(c.Request.Context(), "receive-zipkin-spans")
+	defer span.End()
+
+	contentType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if c.GetHeader("Content-Type") != "" && (err != nil || contentType != contentTypeJSON) {
+		span.SetStatus(codes.Error, "Unsupported content type")
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": fmt.Sprintf("unsupported content type %q, expected %s", c.GetHeader("Content-Type"), contentTypeJSON),
+		})
+		return
+	}
+
+	body, err := readRequestBody(c, int64(s.config.Ingest.MaxPayloadBytes))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(requestBodyStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var zipkinSpans []infrastructure.ZipkinSpan
+	if err := json.Unmarshal(body, &zipkinSpans); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("failed to decode zipkin spans: %v", err),
+		})
+		return
+	}
+
+	traces, rejected := infrastructure.ZipkinToTraces(zipkinSpans)
+	for _, trace := range traces {
+		if s.assembler != nil {
+			invalid, err := assembleSpans(ctx, s.assembler, trace)
+			rejected += invalid
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error": fmt.Sprintf("failed to assemble trace %s: %v", trace.ID, err),
+				})
+				return
+			}
+			continue
+		}
+
+		if err := s.traceService.ProcessTrace(ctx, trace); err != nil {
+			if errors.Is(err, domain.ErrInvalidTrace) {
+				rejected += len(trace.Spans)
+				continue
+			}
+			span.SetStatus(codes.Error, err.Error())
+			if errors.Is(err, domain.ErrBackpressure) {
+				c.Header("Retry-After", backpressureRetryAfter)
+			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": fmt.Sprintf("failed to process trace %s: %v", trace.ID, err),
+			})
+			return
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("zipkin.spans", len(zipkinSpans)),
+		attribute.Int("zipkin.rejected_spans", rejected),
+	)
+	span.SetStatus(codes.Ok, "Zipkin spans received successfully")
+
+	c.Status(http.StatusAccepted)
+}
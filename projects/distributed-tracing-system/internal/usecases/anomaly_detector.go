@@ -0,0 +1,425 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+const (
+	// minSeasonalDays is how many days of an hour must be seen before the
+	// seasonal baseline is used
+	minSeasonalDays = 3
+	// minLatencyDeviation is the smallest latency deviation, relative to
+	// the baseline, so steady operations do not flag tiny changes
+	minLatencyDeviation = 0.1
+	// minErrorRate is the error rate assumed when estimating the deviation
+	// of operations that rarely fail
+	minErrorRate = 0.01
+	// madScale turns a median absolute deviation into a standard deviation
+	madScale = 1.4826
+)
+
+// AnomalyConfig holds configuration for the anomaly detector
+type AnomalyConfig struct {
+	// Window is the detection window traces are aggregated in
+	Window time.Duration
+	// Alpha is the weight of each window in the moving baselines
+	Alpha float64
+	// Threshold is the deviation score a window must reach to be flagged
+	Threshold float64
+	// MinTraces is the number of traces a window needs to be evaluated
+	MinTraces int
+	// WarmupWindows is the number of evaluated windows before an
+	// operation's anomalies are flagged
+	WarmupWindows int
+	// SeasonDays is how many days of hourly history the seasonal baseline
+	// keeps; zero disables it
+	SeasonDays int
+	// MaxExamples bounds the example trace IDs attached to an anomaly
+	MaxExamples int
+	// MaxSeries bounds the tracked service operations; traces of further
+	// operations are ignored
+	MaxSeries int
+	// MaxAnomalies bounds the anomalies kept for queries; the oldest are
+	// dropped first
+	MaxAnomalies int
+}
+
+// exampleTrace is a candidate example of a latency anomaly
+type exampleTrace struct {
+	id       domain.TraceID
+	duration time.Duration
+}
+
+// seasonalHistory holds the hourly values of a signal at each hour of day
+type seasonalHistory [24][]float64
+
+// add appends the value of an hour, keeping the latest days
+func (h *seasonalHistory) add(hour, days int, value float64) {
+	h[hour] = append(h[hour], value)
+	if len(h[hour]) > days {
+		h[hour] = h[hour][1:]
+	}
+}
+
+// anomalySeries is the window being aggregated and the baselines of one
+// service operation
+type anomalySeries struct {
+	service   domain.ServiceName
+	operation domain.OperationName
+
+	count      int64
+	errors     int64
+	latencySum float64
+	// slowest holds the slowest traces of the window, slowest first
+	slowest []exampleTrace
+	failed  []domain.TraceID
+
+	latency   domain.EWMA
+	errorRate domain.EWMA
+	windows   int
+
+	// hour aggregates the windows of the current hour for the seasonal history
+	hour            time.Time
+	hourCount       int64
+	hourErrors      int64
+	hourLatencySum  float64
+	seasonalLatency seasonalHistory
+	seasonalErrors  seasonalHistory
+}
+
+// anomalyDetector implements the AnomalyDetector interface. Baselines are
+// kept in memory, so every instance learns from the traces it ingested.
+type anomalyDetector struct {
+	metrics    domain.PrometheusExporter
+	publishers []domain.AnomalyPublisher
+	config     AnomalyConfig
+	now        func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	series      map[string]*anomalySeries
+	// anomalies holds the detected anomalies, oldest first
+	anomalies []*domain.Anomaly
+}
+
+// NewAnomalyDetector creates an anomaly detector that reports anomalies
+// as metrics and to every publisher
+func NewAnomalyDetector(metrics domain.PrometheusExporter, publishers []domain.AnomalyPublisher, config AnomalyConfig) (domain.AnomalyDetector, error) {
+	if config.Window <= 0 {
+		return nil, fmt.Errorf("anomaly window must be positive")
+	}
+	if config.Alpha <= 0 || config.Alpha > 1 {
+		return nil, fmt.Errorf("anomaly alpha must be in (0, 1]")
+	}
+	if config.Threshold <= 0 {
+		return nil, fmt.Errorf("anomaly threshold must be positive")
+	}
+	if config.MinTraces < 1 || config.WarmupWindows < 0 || config.SeasonDays < 0 {
+		return nil, fmt.Errorf("anomaly min traces must be positive and warmup and season days non-negative")
+	}
+	if config.MaxExamples < 0 || config.MaxSeries < 1 || config.MaxAnomalies < 1 {
+		return nil, fmt.Errorf("anomaly limits must be positive")
+	}
+
+	d := &anomalyDetector{
+		metrics:    metrics,
+		publishers: publishers,
+		config:     config,
+		now:        time.Now,
+		series:     make(map[string]*anomalySeries),
+	}
+	d.windowStart = d.now()
+	return d, nil
+}
+
+// Record counts a trace towards the current window of its operation
+func (d *anomalyDetector) Record(trace *domain.Trace) {
+	key := string(trace.Service) + "\x00" + string(trace.Operation)
+	failed := trace.Status == domain.TraceStatusError || trace.Status == domain.TraceStatusTimeout
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	series, ok := d.series[key]
+	if !ok {
+		if len(d.series) >= d.config.MaxSeries {
+			return
+		}
+		series = &anomalySeries{
+			service:   trace.Service,
+			operation: trace.Operation,
+			latency:   domain.EWMA{Alpha: d.config.Alpha},
+			errorRate: domain.EWMA{Alpha: d.config.Alpha},
+		}
+		d.series[key] = series
+	}
+
+	series.count++
+	series.latencySum += trace.Duration.Seconds()
+	if failed {
+		series.errors++
+		if len(series.failed) < d.config.MaxExamples {
+			series.failed = append(series.failed, trace.ID)
+		}
+	}
+	series.addExample(exampleTrace{id: trace.ID, duration: trace.Duration}, d.config.MaxExamples)
+}
+
+// FindAnomalies returns the detected anomalies matching the query, newest first
+func (d *anomalyDetector) FindAnomalies(query domain.AnomalyQuery) []*domain.Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var matched []*domain.Anomaly
+	skipped := 0
+	for i := len(d.anomalies) - 1; i >= 0; i-- {
+		anomaly := d.anomalies[i]
+		if !query.Matches(anomaly) {
+			continue
+		}
+		if skipped < query.Offset {
+			skipped++
+			continue
+		}
+		copied := *anomaly
+		matched = append(matched, &copied)
+		if query.Limit > 0 && len(matched) >= query.Limit {
+			break
+		}
+	}
+	return matched
+}
+
+// Start closes a detection window every configured window until the
+// context is cancelled
+func (d *anomalyDetector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := d.detect(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to publish anomalies: %v\n", err)
+			}
+		}
+	}
+}
+
+// detect closes the current window, compares every operation with its
+// baselines, then reports the anomalies found
+func (d *anomalyDetector) detect(ctx context.Context) error {
+	now := d.now()
+
+	var detected []*domain.Anomaly
+	d.mu.Lock()
+	start := d.windowStart
+	d.windowStart = now
+	for _, series := range d.series {
+		detected = append(detected, d.closeWindow(series, start, now)...)
+	}
+	d.anomalies = append(d.anomalies, detected...)
+	if excess := len(d.anomalies) - d.config.MaxAnomalies; excess > 0 {
+		d.anomalies = append([]*domain.Anomaly(nil), d.anomalies[excess:]...)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(detected, func(i, j int) bool {
+		if detected[i].Service != detected[j].Service {
+			return detected[i].Service < detected[j].Service
+		}
+		if detected[i].Operation != detected[j].Operation {
+			return detected[i].Operation < detected[j].Operation
+		}
+		return detected[i].Kind < detected[j].Kind
+	})
+
+	var errs []error
+	for _, anomaly := range detected {
+		d.metrics.RecordAnomaly(anomaly)
+		for _, publisher := range d.publishers {
+			if err := publisher.PublishAnomaly(ctx, anomaly); err != nil {
+				errs = append(errs, fmt.Errorf("anomaly %s: %w", anomaly.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeWindow evaluates the window of a series against its baselines,
+// folds it into them and resets it. Windows with too few traces only
+// count towards the seasonal history.
+func (d *anomalyDetector) closeWindow(series *anomalySeries, start, end time.Time) []*domain.Anomaly {
+	defer series.reset()
+	if series.count == 0 {
+		return nil
+	}
+
+	seasonalLatency, seasonalErrors := d.seasonalBaselines(series, start)
+	d.rollHour(series, start)
+	if series.count < int64(d.config.MinTraces) {
+		return nil
+	}
+
+	latency := series.latencySum / float64(series.count)
+	errorRate := float64(series.errors) / float64(series.count)
+
+	var anomalies []*domain.Anomaly
+	if series.windows >= d.config.WarmupWindows && series.latency.Count > 0 {
+		score, seasonal, flagged := d.latencyScore(series, latency, seasonalLatency)
+		if flagged {
+			anomaly := d.newAnomaly(series, domain.AnomalyKindLatency, start, end, latency, series.latency.Mean, score, seasonal)
+			for _, example := range series.slowest {
+				anomaly.ExampleTraceIDs = append(anomaly.ExampleTraceIDs, example.id)
+			}
+			anomalies = append(anomalies, anomaly)
+		}
+
+		score, seasonal, flagged = d.errorRateScore(series, errorRate, seasonalErrors)
+		if flagged {
+			anomaly := d.newAnomaly(series, domain.AnomalyKindErrorRate, start, end, errorRate, series.errorRate.Mean, score, seasonal)
+			anomaly.ExampleTraceIDs = append(anomaly.ExampleTraceIDs, series.failed...)
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	series.latency.Update(latency)
+	series.errorRate.Update(errorRate)
+	series.windows++
+	return anomalies
+}
+
+// latencyScore scores a window's mean latency in either direction. When
+// the seasonal baseline is known the window must also deviate from it.
+func (d *anomalyDetector) latencyScore(series *anomalySeries, latency float64, seasonal []float64) (float64, *float64, bool) {
+	deviation := math.Max(series.latency.StdDev(), minLatencyDeviation*series.latency.Mean)
+	score := deviationScore(latency, series.latency.Mean, deviation)
+	if math.Abs(score) < d.config.Threshold {
+		return score, nil, false
+	}
+
+	if len(seasonal) < minSeasonalDays {
+		return score, nil, true
+	}
+	median, mad := domain.MedianMAD(seasonal)
+	seasonalScore := deviationScore(latency, median, math.Max(madScale*mad, minLatencyDeviation*median))
+	flagged := math.Abs(seasonalScore) >= d.config.Threshold && (seasonalScore > 0) == (score > 0)
+	return score, &median, flagged
+}
+
+// errorRateScore scores a window's error rate; only increases are flagged.
+// The deviation is at least the sampling error of the window's error rate.
+func (d *anomalyDetector) errorRateScore(series *anomalySeries, errorRate float64, seasonal []float64) (float64, *float64, bool) {
+	baseline := series.errorRate.Mean
+	score := deviationScore(errorRate, baseline, math.Max(series.errorRate.StdDev(), samplingDeviation(baseline, series.count)))
+	if score < d.config.Threshold {
+		return score, nil, false
+	}
+
+	if len(seasonal) < minSeasonalDays {
+		return score, nil, true
+	}
+	median, mad := domain.MedianMAD(seasonal)
+	seasonalScore := deviationScore(errorRate, median, math.Max(madScale*mad, samplingDeviation(median, series.count)))
+	return score, &median, seasonalScore >= d.config.Threshold
+}
+
+// deviationScore returns how many deviations a value is from a baseline
+func deviationScore(value, baseline, deviation float64) float64 {
+	if deviation <= 0 {
+		return 0
+	}
+	return (value - baseline) / deviation
+}
+
+// samplingDeviation returns the standard deviation of the error rate of n
+// traces failing with probability rate, assuming at least minErrorRate
+func samplingDeviation(rate float64, n int64) float64 {
+	rate = math.Min(math.Max(rate, minErrorRate), 1-minErrorRate)
+	return math.Sqrt(rate * (1 - rate) / float64(n))
+}
+
+// seasonalBaselines returns the hourly values seen at the hour of day of
+// the window on previous days
+func (d *anomalyDetector) seasonalBaselines(series *anomalySeries, start time.Time) ([]float64, []float64) {
+	if d.config.SeasonDays == 0 {
+		return nil, nil
+	}
+	hour := start.UTC().Hour()
+	return series.seasonalLatency[hour], series.seasonalErrors[hour]
+}
+
+// rollHour adds the window to its hour, moving the previous hour into
+// the seasonal history once the window belongs to a new hour
+func (d *anomalyDetector) rollHour(series *anomalySeries, start time.Time) {
+	if d.config.SeasonDays == 0 {
+		return
+	}
+
+	hour := start.UTC().Truncate(time.Hour)
+	if !hour.Equal(series.hour) {
+		if series.hourCount > 0 {
+			h := series.hour.Hour()
+			series.seasonalLatency.add(h, d.config.SeasonDays, series.hourLatencySum/float64(series.hourCount))
+			series.seasonalErrors.add(h, d.config.SeasonDays, float64(series.hourErrors)/float64(series.hourCount))
+		}
+		series.hour = hour
+		series.hourCount, series.hourErrors, series.hourLatencySum = 0, 0, 0
+	}
+
+	series.hourCount += series.count
+	series.hourErrors += series.errors
+	series.hourLatencySum += series.latencySum
+}
+
+// newAnomaly returns an anomaly of a series' window
+func (d *anomalyDetector) newAnomaly(series *anomalySeries, kind domain.AnomalyKind, start, end time.Time, observed, baseline, score float64, seasonal *float64) *domain.Anomaly {
+	return &domain.Anomaly{
+		ID:               uuid.NewString(),
+		Service:          series.service,
+		Operation:        series.operation,
+		Kind:             kind,
+		WindowStart:      start,
+		WindowEnd:        end,
+		Observed:         observed,
+		Baseline:         baseline,
+		Score:            score,
+		SeasonalBaseline: seasonal,
+		TraceCount:       series.count,
+		ExampleTraceIDs:  []domain.TraceID{},
+	}
+}
+
+// addExample keeps the trace if it is among the slowest of the window
+func (s *anomalySeries) addExample(example exampleTrace, limit int) {
+	i := sort.Search(len(s.slowest), func(i int) bool {
+		return s.slowest[i].duration < example.duration
+	})
+	if i >= limit {
+		return
+	}
+	s.slowest = append(s.slowest, exampleTrace{})
+	copy(s.slowest[i+1:], s.slowest[i:])
+	s.slowest[i] = example
+	if len(s.slowest) > limit {
+		s.slowest = s.slowest[:limit]
+	}
+}
+
+// reset clears the window of a series
+func (s *anomalySeries) reset() {
+	s.count, s.errors, s.latencySum = 0, 0, 0
+	s.slowest = s.slowest[:0]
+	s.failed = s.failed[:0]
+}
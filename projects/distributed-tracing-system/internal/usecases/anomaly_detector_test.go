@@ -0,0 +1,261 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAnomalyPublisher is a mock implementation of AnomalyPublisher
+type MockAnomalyPublisher struct {
+	mock.Mock
+}
+
+func (m *MockAnomalyPublisher) PublishAnomaly(ctx context.Context, anomaly *domain.Anomaly) error {
+	args := m.Called(ctx, anomaly)
+	return args.Error(0)
+}
+
+var anomalyTestStart = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestAnomalyDetector(t *testing.T, metrics domain.PrometheusExporter, publishers ...domain.AnomalyPublisher) *anomalyDetector {
+	detector, err := NewAnomalyDetector(metrics, publishers, AnomalyConfig{
+		Window:        time.Minute,
+		Alpha:         0.1,
+		Threshold:     4,
+		MinTraces:     10,
+		WarmupWindows: 5,
+		SeasonDays:    7,
+		MaxExamples:   3,
+		MaxSeries:     10,
+		MaxAnomalies:  10,
+	})
+	require.NoError(t, err)
+	d := detector.(*anomalyDetector)
+	now := anomalyTestStart
+	d.now = func() time.Time { return now }
+	d.windowStart = now
+	return d
+}
+
+// recordWindow records n traces of checkout/pay of the given duration, the
+// first failed of them failing, then closes the window a minute later
+func recordWindow(t *testing.T, d *anomalyDetector, n, failed int, duration time.Duration) {
+	end := d.windowStart.Add(time.Minute)
+	for i := 0; i < n; i++ {
+		trace := &domain.Trace{
+			ID:        domain.TraceID(fmt.Sprintf("%s-%d", end.Format("1504"), i)),
+			Service:   "checkout",
+			Operation: "pay",
+			Duration:  duration + time.Duration(i)*time.Millisecond,
+			Status:    domain.TraceStatusSuccess,
+		}
+		if i < failed {
+			trace.Status = domain.TraceStatusError
+		}
+		d.Record(trace)
+	}
+	d.now = func() time.Time { return end }
+	require.NoError(t, d.detect(context.Background()))
+}
+
+func TestNewAnomalyDetector_InvalidConfig(t *testing.T) {
+	_, err := NewAnomalyDetector(new(MockPrometheusExporter), nil, AnomalyConfig{})
+	assert.Error(t, err)
+}
+
+func TestAnomalyDetector_FlagsLatencyShift(t *testing.T) {
+	metrics := new(MockPrometheusExporter)
+	publisher := new(MockAnomalyPublisher)
+	detector := newTestAnomalyDetector(t, metrics, publisher)
+	metrics.On("RecordAnomaly", mock.Anything).Return()
+	publisher.On("PublishAnomaly", mock.Anything, mock.Anything).Return(nil)
+
+	for i := 0; i < 5; i++ {
+		recordWindow(t, detector, 20, 0, 100*time.Millisecond)
+	}
+	assert.Empty(t, detector.FindAnomalies(domain.AnomalyQuery{}))
+
+	recordWindow(t, detector, 20, 0, 500*time.Millisecond)
+
+	anomalies := detector.FindAnomalies(domain.AnomalyQuery{})
+	require.Len(t, anomalies, 1)
+	anomaly := anomalies[0]
+	assert.Equal(t, domain.AnomalyKindLatency, anomaly.Kind)
+	assert.Equal(t, domain.ServiceName("checkout"), anomaly.Service)
+	assert.Equal(t, domain.OperationName("pay"), anomaly.Operation)
+	assert.Equal(t, anomalyTestStart.Add(5*time.Minute), anomaly.WindowStart)
+	assert.Equal(t, anomalyTestStart.Add(6*time.Minute), anomaly.WindowEnd)
+	assert.InDelta(t, 0.5095, anomaly.Observed, 1e-9)
+	assert.InDelta(t, 0.1095, anomaly.Baseline, 1e-9)
+	assert.Greater(t, anomaly.Score, 4.0)
+	assert.Nil(t, anomaly.SeasonalBaseline)
+	assert.Equal(t, int64(20), anomaly.TraceCount)
+	// The slowest traces of the window
+	assert.Equal(t, []domain.TraceID{"1006-19", "1006-18", "1006-17"}, anomaly.ExampleTraceIDs)
+
+	metrics.AssertNumberOfCalls(t, "RecordAnomaly", 1)
+	publisher.AssertNumberOfCalls(t, "PublishAnomaly", 1)
+}
+
+func TestAnomalyDetector_FlagsErrorSpike(t *testing.T) {
+	metrics := new(MockPrometheusExporter)
+	detector := newTestAnomalyDetector(t, metrics)
+	metrics.On("RecordAnomaly", mock.Anything).Return()
+
+	for i := 0; i < 5; i++ {
+		recordWindow(t, detector, 100, 1, 100*time.Millisecond)
+	}
+	recordWindow(t, detector, 100, 20, 100*time.Millisecond)
+
+	anomalies := detector.FindAnomalies(domain.AnomalyQuery{Kind: domain.AnomalyKindErrorRate})
+	require.Len(t, anomalies, 1)
+	assert.InDelta(t, 0.2, anomalies[0].Observed, 1e-9)
+	assert.InDelta(t, 0.01, anomalies[0].Baseline, 1e-9)
+	// The failed traces of the window
+	assert.Equal(t, []domain.TraceID{"1006-0", "1006-1", "1006-2"}, anomalies[0].ExampleTraceIDs)
+
+	// Fewer errors than the baseline are never flagged
+	recordWindow(t, detector, 100, 0, 100*time.Millisecond)
+	assert.Len(t, detector.FindAnomalies(domain.AnomalyQuery{Kind: domain.AnomalyKindErrorRate}), 1)
+}
+
+func TestAnomalyDetector_IgnoresSmallWindowsAndWarmup(t *testing.T) {
+	detector := newTestAnomalyDetector(t, new(MockPrometheusExporter))
+
+	// Still warming up
+	recordWindow(t, detector, 20, 0, 100*time.Millisecond)
+	recordWindow(t, detector, 20, 20, 5*time.Second)
+	for i := 0; i < 3; i++ {
+		recordWindow(t, detector, 20, 0, 100*time.Millisecond)
+	}
+	// Too few traces to be evaluated
+	recordWindow(t, detector, 5, 5, 5*time.Second)
+
+	assert.Empty(t, detector.FindAnomalies(domain.AnomalyQuery{}))
+	assert.Equal(t, 5, detector.series["checkout\x00pay"].windows)
+}
+
+func TestAnomalyDetector_SeasonalBaselineSuppressesExpectedShifts(t *testing.T) {
+	detector := newTestAnomalyDetector(t, new(MockPrometheusExporter))
+	for i := 0; i < 5; i++ {
+		recordWindow(t, detector, 20, 0, 100*time.Millisecond)
+	}
+
+	// Previous days were just as slow at this hour
+	series := detector.series["checkout\x00pay"]
+	series.seasonalLatency[10] = []float64{0.5, 0.52, 0.49}
+	recordWindow(t, detector, 20, 0, 500*time.Millisecond)
+
+	assert.Empty(t, detector.FindAnomalies(domain.AnomalyQuery{}))
+}
+
+func TestAnomalyDetector_SeasonalHistory(t *testing.T) {
+	detector := newTestAnomalyDetector(t, new(MockPrometheusExporter))
+	detector.windowStart = anomalyTestStart.Add(59 * time.Minute)
+
+	recordWindow(t, detector, 10, 0, 100*time.Millisecond)
+	recordWindow(t, detector, 10, 10, 100*time.Millisecond)
+
+	// The first window of 11:00 moved 10:00 into the history
+	series := detector.series["checkout\x00pay"]
+	require.Len(t, series.seasonalLatency[10], 1)
+	assert.InDelta(t, 0.1045, series.seasonalLatency[10][0], 1e-9)
+	assert.Equal(t, []float64{0}, series.seasonalErrors[10])
+	assert.Equal(t, int64(10), series.hourErrors)
+}
+
+func TestAnomalyDetector_FindAnomalies(t *testing.T) {
+	detector := newTestAnomalyDetector(t, new(MockPrometheusExporter))
+	for i := 0; i < 5; i++ {
+		detector.anomalies = append(detector.anomalies, &domain.Anomaly{
+			ID:        fmt.Sprint(i),
+			Service:   "checkout",
+			Kind:      domain.AnomalyKindLatency,
+			WindowEnd: anomalyTestStart.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	detector.anomalies[1].Service = "payments"
+
+	anomalies := detector.FindAnomalies(domain.AnomalyQuery{Service: "checkout", Limit: 2, Offset: 1})
+
+	require.Len(t, anomalies, 2)
+	assert.Equal(t, "3", anomalies[0].ID)
+	assert.Equal(t, "2", anomalies[1].ID)
+}
+
+func TestAnomalyDetector_LimitsSeriesAndAnomalies(t *testing.T) {
+	metrics := new(MockPrometheusExporter)
+	metrics.On("RecordAnomaly", mock.Anything).Return()
+	detector := newTestAnomalyDetector(t, metrics)
+	for i := 0; i < 20; i++ {
+		detector.Record(&domain.Trace{ID: "trace", Service: domain.ServiceName(fmt.Sprint(i)), Operation: "op"})
+	}
+	assert.Len(t, detector.series, 10)
+
+	detector.series = map[string]*anomalySeries{}
+	for i := 0; i < 12; i++ {
+		detector.anomalies = append(detector.anomalies, &domain.Anomaly{ID: fmt.Sprint(i)})
+	}
+	require.NoError(t, detector.detect(context.Background()))
+	require.Len(t, detector.anomalies, 10)
+	assert.Equal(t, "2", detector.anomalies[0].ID)
+}
+
+func TestAnomalyDetector_ReportsPublishErrors(t *testing.T) {
+	metrics := new(MockPrometheusExporter)
+	publisher := new(MockAnomalyPublisher)
+	detector := newTestAnomalyDetector(t, metrics, publisher)
+	metrics.On("RecordAnomaly", mock.Anything).Return()
+	publisher.On("PublishAnomaly", mock.Anything, mock.Anything).Return(errors.New("unreachable"))
+
+	for i := 0; i < 5; i++ {
+		recordWindow(t, detector, 20, 0, 100*time.Millisecond)
+	}
+	for i := 0; i < 20; i++ {
+		detector.Record(&domain.Trace{ID: "slow", Service: "checkout", Operation: "pay", Duration: time.Second})
+	}
+	detector.now = func() time.Time { return anomalyTestStart.Add(6 * time.Minute) }
+
+	err := detector.detect(context.Background())
+
+	assert.ErrorContains(t, err, "unreachable")
+	assert.Len(t, detector.FindAnomalies(domain.AnomalyQuery{}), 1)
+}
+
+func TestTraceService_ProcessTrace_FeedsAnomalyDetector(t *testing.T) {
+	// Arrange: the first save is refused by storage
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(domain.ErrBackpressure).Once()
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+	mockKafka.On("PublishTraceEvent", mock.Anything, mock.Anything).Return(nil)
+
+	detector := newTestAnomalyDetector(t, mockPrometheus)
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithAnomalyDetector(detector))
+
+	trace := &domain.Trace{
+		ID:        "1234567890abcdef",
+		Service:   "checkout",
+		Operation: "pay",
+		StartTime: anomalyTestStart,
+		EndTime:   anomalyTestStart.Add(time.Second),
+	}
+
+	// Act & Assert: the baselines only see the stored trace
+	require.ErrorIs(t, service.ProcessTrace(context.Background(), trace), domain.ErrBackpressure)
+	assert.NotContains(t, detector.series, "checkout\x00pay")
+
+	require.NoError(t, service.ProcessTrace(context.Background(), trace))
+	require.Contains(t, detector.series, "checkout\x00pay")
+	assert.Equal(t, int64(1), detector.series["checkout\x00pay"].count)
+}
@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// DependencyConfig holds configuration for the dependency service
+type DependencyConfig struct {
+	// Window is the time bucket calls are aggregated in before they are stored
+	Window time.Duration
+	// FlushInterval is how often recorded windows are persisted
+	FlushInterval time.Duration
+}
+
+// dependencyKey identifies the link of a caller and callee in one window
+type dependencyKey struct {
+	window time.Time
+	caller domain.ServiceName
+	callee domain.ServiceName
+}
+
+// dependencyService implements the DependencyService interface
+type dependencyService struct {
+	store  domain.DependencyStore
+	config DependencyConfig
+
+	mu      sync.Mutex
+	pending map[dependencyKey]*domain.DependencyLink
+}
+
+// NewDependencyService creates a dependency service that aggregates calls
+// in memory and persists them to the store on every flush
+func NewDependencyService(store domain.DependencyStore, config DependencyConfig) (domain.DependencyService, error) {
+	if config.Window <= 0 {
+		return nil, fmt.Errorf("dependency window must be positive")
+	}
+	if config.FlushInterval <= 0 {
+		return nil, fmt.Errorf("dependency flush interval must be positive")
+	}
+
+	return &dependencyService{
+		store:   store,
+		config:  config,
+		pending: make(map[dependencyKey]*domain.DependencyLink),
+	}, nil
+}
+
+// Record adds the cross-service calls of a trace to the current windows
+func (s *dependencyService) Record(trace *domain.Trace) {
+	calls := domain.ExtractDependencyCalls(trace)
+	if len(calls) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, call := range calls {
+		key := dependencyKey{
+			window: call.StartTime.UTC().Truncate(s.config.Window),
+			caller: call.Caller,
+			callee: call.Callee,
+		}
+		link, ok := s.pending[key]
+		if !ok {
+			link = domain.NewDependencyLink(key.window, key.caller, key.callee)
+			s.pending[key] = link
+		}
+		link.Record(call.Duration, call.Error)
+	}
+}
+
+// GetDependencies returns the graph of the calls that started within
+// [start, end), including the calls not persisted yet
+func (s *dependencyService) GetDependencies(ctx context.Context, start, end time.Time) (*domain.DependencyGraph, error) {
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+	start, end = start.UTC(), end.UTC()
+
+	// Widen the range to whole windows so the first one is not missed
+	links, err := s.store.FindDependencies(ctx, start.Truncate(s.config.Window), end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dependencies: %w", err)
+	}
+
+	s.mu.Lock()
+	for key, link := range s.pending {
+		if key.window.Before(end) && key.window.Add(s.config.Window).After(start) {
+			links = append(links, link.Clone())
+		}
+	}
+	s.mu.Unlock()
+
+	return domain.BuildDependencyGraph(start, end, links), nil
+}
+
+// flush persists the recorded windows. Windows that fail to persist are
+// kept for the next flush.
+func (s *dependencyService) flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[dependencyKey]*domain.DependencyLink)
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	links := make([]*domain.DependencyLink, 0, len(pending))
+	for _, link := range pending {
+		links = append(links, link)
+	}
+
+	if err := s.store.SaveDependencies(ctx, links); err != nil {
+		s.mu.Lock()
+		for key, link := range pending {
+			if current, ok := s.pending[key]; ok {
+				link.Merge(current)
+			}
+			s.pending[key] = link
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("failed to save dependencies: %w", err)
+	}
+	return nil
+}
+
+// Start persists the recorded windows periodically until the context is
+// cancelled, then persists what is left
+func (s *dependencyService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return s.flush(context.Background())
+		case <-ticker.C:
+			if err := s.flush(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to flush dependencies: %v\n", err)
+			}
+		}
+	}
+}
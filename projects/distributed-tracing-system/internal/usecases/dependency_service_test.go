@@ -0,0 +1,154 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDependencyStore is a mock implementation of DependencyStore
+type MockDependencyStore struct {
+	mock.Mock
+}
+
+func (m *MockDependencyStore) SaveDependencies(ctx context.Context, links []*domain.DependencyLink) error {
+	args := m.Called(ctx, links)
+	return args.Error(0)
+}
+
+func (m *MockDependencyStore) FindDependencies(ctx context.Context, start, end time.Time) ([]*domain.DependencyLink, error) {
+	args := m.Called(ctx, start, end)
+	return args.Get(0).([]*domain.DependencyLink), args.Error(1)
+}
+
+// newDependencyTestTrace returns a trace where checkout calls payments at
+// the given offset from skewTestStart
+func newDependencyTestTrace(id domain.TraceID, offset time.Duration, status domain.SpanStatus) *domain.Trace {
+	root := skewTestSpan("root", "", "checkout", 0, 100)
+	call := skewTestSpan("call", "root", "payments", 10, 40)
+	call.Status = status
+	trace := &domain.Trace{ID: id, Spans: []domain.Span{root, call}}
+	for i := range trace.Spans {
+		trace.Spans[i].StartTime = trace.Spans[i].StartTime.Add(offset)
+		trace.Spans[i].EndTime = trace.Spans[i].EndTime.Add(offset)
+	}
+	return trace
+}
+
+func newTestDependencyService(t *testing.T, store domain.DependencyStore) *dependencyService {
+	service, err := NewDependencyService(store, DependencyConfig{Window: time.Minute, FlushInterval: time.Minute})
+	require.NoError(t, err)
+	return service.(*dependencyService)
+}
+
+func TestDependencyService_FlushAggregatesWindows(t *testing.T) {
+	// Arrange
+	store := new(MockDependencyStore)
+	service := newTestDependencyService(t, store)
+
+	service.Record(newDependencyTestTrace("trace1", 0, domain.SpanStatusOK))
+	service.Record(newDependencyTestTrace("trace2", time.Second, domain.SpanStatusError))
+	service.Record(newDependencyTestTrace("trace3", time.Minute, domain.SpanStatusOK))
+
+	var saved []*domain.DependencyLink
+	store.On("SaveDependencies", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).([]*domain.DependencyLink)
+	}).Return(nil).Once()
+
+	// Act
+	require.NoError(t, service.flush(context.Background()))
+
+	// Assert: one link per window
+	require.Len(t, saved, 2)
+	counts := map[time.Time]int64{}
+	failed := map[time.Time]int64{}
+	for _, link := range saved {
+		assert.Equal(t, domain.ServiceName("checkout"), link.Caller)
+		assert.Equal(t, domain.ServiceName("payments"), link.Callee)
+		counts[link.WindowStart] = link.CallCount
+		failed[link.WindowStart] = link.ErrorCount
+	}
+	assert.Equal(t, map[time.Time]int64{skewTestStart: 2, skewTestStart.Add(time.Minute): 1}, counts)
+	assert.Equal(t, int64(1), failed[skewTestStart])
+
+	// Nothing is left to flush
+	require.NoError(t, service.flush(context.Background()))
+	store.AssertNumberOfCalls(t, "SaveDependencies", 1)
+}
+
+func TestDependencyService_FlushKeepsFailedWindows(t *testing.T) {
+	store := new(MockDependencyStore)
+	service := newTestDependencyService(t, store)
+	service.Record(newDependencyTestTrace("trace1", 0, domain.SpanStatusOK))
+
+	store.On("SaveDependencies", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+	assert.Error(t, service.flush(context.Background()))
+
+	// Calls recorded meanwhile are merged with the failed windows
+	service.Record(newDependencyTestTrace("trace2", 0, domain.SpanStatusOK))
+
+	var saved []*domain.DependencyLink
+	store.On("SaveDependencies", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).([]*domain.DependencyLink)
+	}).Return(nil).Once()
+	require.NoError(t, service.flush(context.Background()))
+
+	require.Len(t, saved, 1)
+	assert.Equal(t, int64(2), saved[0].CallCount)
+}
+
+func TestDependencyService_GetDependencies(t *testing.T) {
+	// Arrange: one window is stored and one is still pending
+	store := new(MockDependencyStore)
+	service := newTestDependencyService(t, store)
+
+	stored := domain.NewDependencyLink(skewTestStart, "checkout", "payments")
+	stored.Record(30*time.Millisecond, false)
+	start := skewTestStart.Add(30 * time.Second)
+	end := skewTestStart.Add(time.Hour)
+	store.On("FindDependencies", mock.Anything, skewTestStart, end).Return([]*domain.DependencyLink{stored}, nil)
+
+	service.Record(newDependencyTestTrace("trace1", time.Minute, domain.SpanStatusError))
+	service.Record(newDependencyTestTrace("outside", 2*time.Hour, domain.SpanStatusOK))
+
+	// Act
+	graph, err := service.GetDependencies(context.Background(), start, end)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, start, graph.Start)
+	require.Len(t, graph.Edges, 1)
+	assert.Equal(t, int64(2), graph.Edges[0].CallCount)
+	assert.Equal(t, int64(1), graph.Edges[0].ErrorCount)
+	assert.Len(t, graph.Nodes, 2)
+}
+
+func TestDependencyService_GetDependenciesInvalidRange(t *testing.T) {
+	service := newTestDependencyService(t, new(MockDependencyStore))
+
+	_, err := service.GetDependencies(context.Background(), skewTestStart, skewTestStart)
+	assert.Error(t, err)
+}
+
+func TestNewDependencyService_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config DependencyConfig
+	}{
+		{name: "zero window", config: DependencyConfig{FlushInterval: time.Minute}},
+		{name: "zero flush interval", config: DependencyConfig{Window: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDependencyService(new(MockDependencyStore), tt.config)
+			assert.Error(t, err)
+		})
+	}
+}
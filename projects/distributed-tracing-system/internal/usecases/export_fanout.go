@@ -0,0 +1,324 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// ExportConfig holds the queueing, retry and circuit breaker settings of
+// an export backend
+type ExportConfig struct {
+	// QueueSize bounds the traces waiting for the backend; beyond it
+	// traces are dropped according to DropPolicy
+	QueueSize  int
+	DropPolicy domain.DropPolicy
+	// Timeout bounds a single export attempt
+	Timeout time.Duration
+	// MaxRetries is how often a failed export is retried, waiting
+	// InitialBackoff and doubling up to MaxBackoff between attempts
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// FailureThreshold consecutive failed traces open the circuit, dropping
+	// traces for Cooldown; zero disables the circuit breaker
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// ExportBackend is a named backend traces are exported to
+type ExportBackend struct {
+	// Name identifies the backend in metrics
+	Name     string
+	Exporter domain.JaegerExporter
+	Config   ExportConfig
+}
+
+// exportFanout implements the ExportFanout interface
+type exportFanout struct {
+	workers []*exportWorker
+}
+
+// NewExportFanout creates a fan-out over the backends, each with its own
+// queue, retries and circuit breaker
+func NewExportFanout(backends []ExportBackend, metrics domain.PrometheusExporter) (domain.ExportFanout, error) {
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("at least one export backend is required")
+	}
+
+	names := make(map[string]bool, len(backends))
+	workers := make([]*exportWorker, 0, len(backends))
+	for _, backend := range backends {
+		if backend.Name == "" {
+			return nil, fmt.Errorf("export backend name is required")
+		}
+		if names[backend.Name] {
+			return nil, fmt.Errorf("duplicate export backend %q", backend.Name)
+		}
+		names[backend.Name] = true
+		if backend.Exporter == nil {
+			return nil, fmt.Errorf("export backend %q: exporter is required", backend.Name)
+		}
+		if err := validateExportConfig(backend.Config); err != nil {
+			return nil, fmt.Errorf("export backend %q: %w", backend.Name, err)
+		}
+
+		workers = append(workers, &exportWorker{
+			name:     backend.Name,
+			exporter: backend.Exporter,
+			config:   backend.Config,
+			metrics:  metrics,
+			queue:    make(chan *domain.Trace, backend.Config.QueueSize),
+			state:    domain.CircuitClosed,
+			now:      time.Now,
+		})
+	}
+
+	return &exportFanout{workers: workers}, nil
+}
+
+// validateExportConfig checks the settings of a backend
+func validateExportConfig(config ExportConfig) error {
+	if config.QueueSize <= 0 {
+		return fmt.Errorf("queue size must be positive")
+	}
+	switch config.DropPolicy {
+	case domain.DropNewest, domain.DropOldest:
+	default:
+		return fmt.Errorf("invalid drop policy %q", config.DropPolicy)
+	}
+	if config.Timeout <= 0 {
+		return fmt.Errorf("export timeout must be positive")
+	}
+	if config.MaxRetries < 0 {
+		return fmt.Errorf("max retries cannot be negative")
+	}
+	if config.MaxRetries > 0 && (config.InitialBackoff <= 0 || config.MaxBackoff < config.InitialBackoff) {
+		return fmt.Errorf("retry backoff must be positive and no larger than max backoff")
+	}
+	if config.FailureThreshold < 0 {
+		return fmt.Errorf("failure threshold cannot be negative")
+	}
+	if config.FailureThreshold > 0 && config.Cooldown <= 0 {
+		return fmt.Errorf("circuit cooldown must be positive")
+	}
+	return nil
+}
+
+// ExportTrace queues the trace for every backend without waiting
+func (f *exportFanout) ExportTrace(ctx context.Context, trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("trace cannot be nil")
+	}
+	for _, worker := range f.workers {
+		worker.enqueue(trace)
+	}
+	return nil
+}
+
+// Start exports queued traces until the context is cancelled
+func (f *exportFanout) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, worker := range f.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.run(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// exportWorker sends the queued traces of a single backend. The circuit
+// breaker state is only touched by the worker's goroutine.
+type exportWorker struct {
+	name     string
+	exporter domain.JaegerExporter
+	config   ExportConfig
+	metrics  domain.PrometheusExporter
+	queue    chan *domain.Trace
+
+	state    domain.CircuitState
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// enqueue queues a trace, dropping one according to the drop policy when
+// the queue is full
+func (w *exportWorker) enqueue(trace *domain.Trace) {
+	select {
+	case w.queue <- trace:
+		w.metrics.RecordExportQueueDepth(w.name, len(w.queue))
+		return
+	default:
+	}
+
+	if w.config.DropPolicy == domain.DropOldest {
+		select {
+		case <-w.queue:
+			w.metrics.RecordExport(w.name, domain.ExportResultDropped, 0)
+		default:
+		}
+		select {
+		case w.queue <- trace:
+			return
+		default:
+		}
+	}
+	w.metrics.RecordExport(w.name, domain.ExportResultDropped, 0)
+}
+
+// run exports traces until the context is cancelled, then sends the
+// traces still queued once each
+func (w *exportWorker) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			w.drain()
+			return
+		case trace := <-w.queue:
+			w.export(ctx, trace, true)
+			w.metrics.RecordExportQueueDepth(w.name, len(w.queue))
+		}
+	}
+}
+
+// drain exports the traces queued on shutdown without retries
+func (w *exportWorker) drain() {
+	for {
+		select {
+		case trace := <-w.queue:
+			w.export(context.Background(), trace, false)
+		default:
+			w.metrics.RecordExportQueueDepth(w.name, 0)
+			return
+		}
+	}
+}
+
+// export sends a trace unless the circuit is open and feeds the result
+// back into the circuit breaker
+func (w *exportWorker) export(ctx context.Context, trace *domain.Trace, retry bool) {
+	if !w.allow() {
+		w.metrics.RecordExport(w.name, domain.ExportResultDropped, 0)
+		return
+	}
+
+	// A half open circuit probes the backend with a single attempt
+	retry = retry && w.state == domain.CircuitClosed
+
+	start := time.Now()
+	err := w.send(ctx, trace, retry)
+	if err != nil {
+		w.metrics.RecordExport(w.name, domain.ExportResultFailed, time.Since(start))
+	} else {
+		w.metrics.RecordExport(w.name, domain.ExportResultSuccess, time.Since(start))
+	}
+	w.recordResult(err == nil)
+}
+
+// send exports a trace, retrying with exponential backoff. Attempts are
+// not tied to the caller's context, which only cuts the backoff short.
+func (w *exportWorker) send(ctx context.Context, trace *domain.Trace, retry bool) error {
+	backoff := w.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+		err := w.exporter.ExportTrace(attemptCtx, trace)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.config.MaxRetries {
+			return err
+		}
+
+		w.metrics.RecordExportRetry(w.name)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, w.config.MaxBackoff)
+	}
+}
+
+// allow reports whether a trace may be sent, moving an open circuit to
+// half open once the cooldown has passed
+func (w *exportWorker) allow() bool {
+	if w.state != domain.CircuitOpen {
+		return true
+	}
+	if w.now().Sub(w.openedAt) < w.config.Cooldown {
+		return false
+	}
+	w.setState(domain.CircuitHalfOpen)
+	return true
+}
+
+// recordResult closes the circuit on success and opens it after too many
+// consecutive failures or a failed probe
+func (w *exportWorker) recordResult(ok bool) {
+	if ok {
+		w.failures = 0
+		if w.state != domain.CircuitClosed {
+			w.setState(domain.CircuitClosed)
+		}
+		return
+	}
+
+	w.failures++
+	if w.config.FailureThreshold == 0 {
+		return
+	}
+	if w.state == domain.CircuitHalfOpen || w.failures >= w.config.FailureThreshold {
+		w.openedAt = w.now()
+		w.setState(domain.CircuitOpen)
+	}
+}
+
+// setState changes the circuit state and reports it
+func (w *exportWorker) setState(state domain.CircuitState) {
+	w.state = state
+	w.metrics.RecordCircuitState(w.name, state)
+}
+
+// ExportTarget is a backend kind and its endpoint, file path or topic
+type ExportTarget struct {
+	Kind   string
+	Target string
+}
+
+// ParseExportTargets parses export backends of the form
+// "kind=target,...", e.g. "otlp=http://collector:4318/v1/traces,file=traces.jsonl".
+// Each kind may appear once, since it names the backend in metrics.
+func ParseExportTargets(spec string) ([]ExportTarget, error) {
+	var targets []ExportTarget
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, target, ok := strings.Cut(entry, "=")
+		kind, target = strings.TrimSpace(kind), strings.TrimSpace(target)
+		if !ok || kind == "" || target == "" {
+			return nil, fmt.Errorf("export backend %q: expected kind=target", entry)
+		}
+		if seen[kind] {
+			return nil, fmt.Errorf("export backend %q: %s is configured twice", entry, kind)
+		}
+		seen[kind] = true
+
+		targets = append(targets, ExportTarget{Kind: kind, Target: target})
+	}
+	return targets, nil
+}
@@ -0,0 +1,262 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExporter records exported traces and fails while err is set
+type fakeExporter struct {
+	mu       sync.Mutex
+	err      error
+	block    chan struct{}
+	attempts int
+	exported []domain.TraceID
+}
+
+func (e *fakeExporter) ExportTrace(ctx context.Context, trace *domain.Trace) error {
+	if e.block != nil {
+		<-e.block
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.attempts++
+	if e.err != nil {
+		return e.err
+	}
+	e.exported = append(e.exported, trace.ID)
+	return nil
+}
+
+func (e *fakeExporter) setErr(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *fakeExporter) snapshot() (int, []domain.TraceID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.attempts, append([]domain.TraceID(nil), e.exported...)
+}
+
+func newExportTestMetrics() *MockPrometheusExporter {
+	metrics := new(MockPrometheusExporter)
+	metrics.On("RecordExport", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	metrics.On("RecordExportRetry", mock.Anything).Maybe()
+	metrics.On("RecordExportQueueDepth", mock.Anything, mock.Anything).Maybe()
+	metrics.On("RecordCircuitState", mock.Anything, mock.Anything).Maybe()
+	return metrics
+}
+
+func testExportConfig() ExportConfig {
+	return ExportConfig{
+		QueueSize:      10,
+		DropPolicy:     domain.DropNewest,
+		Timeout:        time.Second,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+	}
+}
+
+func exportWorkerOf(fanout domain.ExportFanout, name string) *exportWorker {
+	for _, worker := range fanout.(*exportFanout).workers {
+		if worker.name == name {
+			return worker
+		}
+	}
+	return nil
+}
+
+func TestNewExportFanout_InvalidConfig(t *testing.T) {
+	exporter := new(fakeExporter)
+	tests := []struct {
+		name     string
+		backends []ExportBackend
+	}{
+		{name: "no backends"},
+		{name: "missing name", backends: []ExportBackend{{Exporter: exporter, Config: testExportConfig()}}},
+		{name: "missing exporter", backends: []ExportBackend{{Name: "otlp", Config: testExportConfig()}}},
+		{name: "duplicate name", backends: []ExportBackend{
+			{Name: "otlp", Exporter: exporter, Config: testExportConfig()},
+			{Name: "otlp", Exporter: exporter, Config: testExportConfig()},
+		}},
+		{name: "zero queue size", backends: []ExportBackend{{Name: "otlp", Exporter: exporter, Config: ExportConfig{DropPolicy: domain.DropNewest, Timeout: time.Second}}}},
+		{name: "unknown drop policy", backends: []ExportBackend{{Name: "otlp", Exporter: exporter, Config: ExportConfig{QueueSize: 1, DropPolicy: "random", Timeout: time.Second}}}},
+		{name: "retries without backoff", backends: []ExportBackend{{Name: "otlp", Exporter: exporter, Config: ExportConfig{QueueSize: 1, DropPolicy: domain.DropNewest, Timeout: time.Second, MaxRetries: 1}}}},
+		{name: "breaker without cooldown", backends: []ExportBackend{{Name: "otlp", Exporter: exporter, Config: ExportConfig{QueueSize: 1, DropPolicy: domain.DropNewest, Timeout: time.Second, FailureThreshold: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExportFanout(tt.backends, newExportTestMetrics())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestExportFanout_SlowBackendDoesNotBlock(t *testing.T) {
+	// Arrange: one backend hangs until released
+	fast := new(fakeExporter)
+	slow := &fakeExporter{block: make(chan struct{})}
+	fanout, err := NewExportFanout([]ExportBackend{
+		{Name: "fast", Exporter: fast, Config: testExportConfig()},
+		{Name: "slow", Exporter: slow, Config: testExportConfig()},
+	}, newExportTestMetrics())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		fanout.Start(ctx)
+		close(stopped)
+	}()
+
+	// Act
+	for _, id := range []domain.TraceID{"trace-1", "trace-2", "trace-3"} {
+		require.NoError(t, fanout.ExportTrace(context.Background(), newQueueTestTrace(string(id))))
+	}
+
+	// Assert: the fast backend gets every trace while the slow one hangs
+	require.Eventually(t, func() bool {
+		_, exported := fast.snapshot()
+		return len(exported) == 3
+	}, time.Second, time.Millisecond)
+	_, exported := slow.snapshot()
+	assert.Empty(t, exported)
+
+	close(slow.block)
+	cancel()
+	<-stopped
+	_, exported = slow.snapshot()
+	assert.Equal(t, []domain.TraceID{"trace-1", "trace-2", "trace-3"}, exported)
+}
+
+func TestExportFanout_RetriesWithBackoff(t *testing.T) {
+	// Arrange
+	exporter := &fakeExporter{err: errors.New("unavailable")}
+	metrics := newExportTestMetrics()
+	fanout, err := NewExportFanout([]ExportBackend{{Name: "otlp", Exporter: exporter, Config: testExportConfig()}}, metrics)
+	require.NoError(t, err)
+	worker := exportWorkerOf(fanout, "otlp")
+
+	// Act: every attempt fails
+	worker.export(context.Background(), newQueueTestTrace("trace-1"), true)
+
+	// Assert: the first attempt and two retries
+	attempts, _ := exporter.snapshot()
+	assert.Equal(t, 3, attempts)
+	metrics.AssertNumberOfCalls(t, "RecordExportRetry", 2)
+	metrics.AssertCalled(t, "RecordExport", "otlp", domain.ExportResultFailed, mock.Anything)
+
+	// Act: the backend recovers
+	exporter.setErr(nil)
+	worker.export(context.Background(), newQueueTestTrace("trace-2"), true)
+
+	// Assert
+	_, exported := exporter.snapshot()
+	assert.Equal(t, []domain.TraceID{"trace-2"}, exported)
+	metrics.AssertCalled(t, "RecordExport", "otlp", domain.ExportResultSuccess, mock.Anything)
+}
+
+func TestExportFanout_CircuitBreaker(t *testing.T) {
+	// Arrange
+	exporter := &fakeExporter{err: errors.New("unavailable")}
+	config := testExportConfig()
+	config.MaxRetries = 0
+	config.FailureThreshold = 2
+	config.Cooldown = time.Minute
+	fanout, err := NewExportFanout([]ExportBackend{{Name: "otlp", Exporter: exporter, Config: config}}, newExportTestMetrics())
+	require.NoError(t, err)
+	worker := exportWorkerOf(fanout, "otlp")
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Act: two failures open the circuit
+	worker.export(ctx, newQueueTestTrace("trace-1"), true)
+	worker.export(ctx, newQueueTestTrace("trace-2"), true)
+	assert.Equal(t, domain.CircuitOpen, worker.state)
+
+	// Assert: while open, traces are dropped without an attempt
+	worker.export(ctx, newQueueTestTrace("trace-3"), true)
+	attempts, _ := exporter.snapshot()
+	assert.Equal(t, 2, attempts)
+
+	// Act: after the cooldown a failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	worker.export(ctx, newQueueTestTrace("trace-4"), true)
+	attempts, _ = exporter.snapshot()
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, domain.CircuitOpen, worker.state)
+
+	// Act: a successful probe closes it
+	now = now.Add(time.Minute)
+	exporter.setErr(nil)
+	worker.export(ctx, newQueueTestTrace("trace-5"), true)
+
+	// Assert
+	assert.Equal(t, domain.CircuitClosed, worker.state)
+	_, exported := exporter.snapshot()
+	assert.Equal(t, []domain.TraceID{"trace-5"}, exported)
+}
+
+func TestExportFanout_DropPolicy(t *testing.T) {
+	tests := []struct {
+		policy domain.DropPolicy
+		want   []domain.TraceID
+	}{
+		{policy: domain.DropNewest, want: []domain.TraceID{"trace-1", "trace-2"}},
+		{policy: domain.DropOldest, want: []domain.TraceID{"trace-2", "trace-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			// Arrange: without a running worker the queue fills up
+			config := testExportConfig()
+			config.QueueSize = 2
+			config.DropPolicy = tt.policy
+			metrics := newExportTestMetrics()
+			fanout, err := NewExportFanout([]ExportBackend{{Name: "otlp", Exporter: new(fakeExporter), Config: config}}, metrics)
+			require.NoError(t, err)
+
+			// Act
+			for _, id := range []string{"trace-1", "trace-2", "trace-3"} {
+				require.NoError(t, fanout.ExportTrace(context.Background(), newQueueTestTrace(id)))
+			}
+
+			// Assert
+			queue := exportWorkerOf(fanout, "otlp").queue
+			var queued []domain.TraceID
+			for len(queue) > 0 {
+				queued = append(queued, (<-queue).ID)
+			}
+			assert.Equal(t, tt.want, queued)
+			metrics.AssertCalled(t, "RecordExport", "otlp", domain.ExportResultDropped, time.Duration(0))
+		})
+	}
+}
+
+func TestParseExportTargets(t *testing.T) {
+	targets, err := ParseExportTargets(" otlp=http://collector:4318/v1/traces, file=/var/log/traces.jsonl ,")
+	require.NoError(t, err)
+	assert.Equal(t, []ExportTarget{
+		{Kind: "otlp", Target: "http://collector:4318/v1/traces"},
+		{Kind: "file", Target: "/var/log/traces.jsonl"},
+	}, targets)
+
+	for _, spec := range []string{"otlp", "=http://collector", "otlp=", "file=a,file=b"} {
+		_, err := ParseExportTargets(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,253 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// RetentionConfig holds configuration for the retention manager
+type RetentionConfig struct {
+	Policy domain.RetentionPolicy
+	// Interval is how often the background worker purges
+	Interval time.Duration
+	// BatchSize is the number of traces deleted per repository call, which
+	// keeps each delete transaction short
+	BatchSize int
+	// BatchPause is the delay between batches, leaving room for ingestion
+	BatchPause time.Duration
+	// MaxBatchesPerRule bounds the batches deleted per rule in one run;
+	// zero means no limit
+	MaxBatchesPerRule int
+}
+
+// retentionManager implements the RetentionManager interface
+type retentionManager struct {
+	purger  domain.TracePurger
+	metrics domain.PrometheusExporter
+	config  RetentionConfig
+	now     func() time.Time
+
+	// running is held while a purge deletes traces
+	running sync.Mutex
+}
+
+// NewRetentionManager creates a retention manager. The repository must
+// support purging.
+func NewRetentionManager(repo domain.TraceRepository, metrics domain.PrometheusExporter, config RetentionConfig) (domain.RetentionManager, error) {
+	purger, ok := repo.(domain.TracePurger)
+	if !ok {
+		return nil, fmt.Errorf("trace repository does not support purging")
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("retention interval must be positive")
+	}
+	if config.BatchSize <= 0 {
+		return nil, fmt.Errorf("retention batch size must be positive")
+	}
+	if config.BatchPause < 0 {
+		return nil, fmt.Errorf("retention batch pause cannot be negative")
+	}
+	if config.MaxBatchesPerRule < 0 {
+		return nil, fmt.Errorf("retention max batches cannot be negative")
+	}
+	if config.Policy.DefaultMaxAge < 0 {
+		return nil, fmt.Errorf("default retention cannot be negative")
+	}
+	for _, rule := range config.Policy.Rules {
+		if rule.MaxAge < 0 {
+			return nil, fmt.Errorf("retention for %s cannot be negative", rule.Name())
+		}
+	}
+
+	return &retentionManager{
+		purger:  purger,
+		metrics: metrics,
+		config:  config,
+		now:     time.Now,
+	}, nil
+}
+
+// Purge deletes the traces that are older than the retention policy
+// allows, or only reports them on a dry run
+func (m *retentionManager) Purge(ctx context.Context, dryRun bool) (*domain.PurgeReport, error) {
+	if !dryRun {
+		if !m.running.TryLock() {
+			return nil, domain.ErrPurgeInProgress
+		}
+		defer m.running.Unlock()
+	}
+
+	report := &domain.PurgeReport{
+		DryRun:    dryRun,
+		StartedAt: m.now(),
+		Rules:     []domain.PurgeRuleReport{},
+	}
+
+	var lag time.Duration
+	for _, target := range m.config.Policy.PurgeTargets(report.StartedAt) {
+		ruleReport := domain.PurgeRuleReport{Rule: target.Rule, Cutoff: target.Criteria.Before}
+
+		if dryRun {
+			stats, err := m.purger.PurgeStats(ctx, target.Criteria)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count expired traces for %s: %w", target.Rule, err)
+			}
+			ruleReport.Purged = stats.Count
+		} else {
+			purged, err := m.purgeTarget(ctx, target)
+			if err != nil {
+				return nil, fmt.Errorf("failed to purge traces for %s: %w", target.Rule, err)
+			}
+			ruleReport.Purged = purged
+
+			// Whatever is still selected was not reached in this run
+			stats, err := m.purger.PurgeStats(ctx, target.Criteria)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count expired traces for %s: %w", target.Rule, err)
+			}
+			ruleReport.Remaining = stats.Count
+			if stats.Count > 0 {
+				if overdue := target.Criteria.Before.Sub(stats.Oldest); overdue > lag {
+					lag = overdue
+				}
+			}
+		}
+
+		report.Total += ruleReport.Purged
+		report.Rules = append(report.Rules, ruleReport)
+	}
+
+	if !dryRun {
+		m.metrics.RecordRetentionLag(lag)
+	}
+	report.FinishedAt = m.now()
+
+	return report, nil
+}
+
+// purgeTarget deletes the expired traces of one rule in bounded batches
+func (m *retentionManager) purgeTarget(ctx context.Context, target domain.PurgeTarget) (int64, error) {
+	criteria := target.Criteria
+	criteria.Limit = m.config.BatchSize
+
+	var total int64
+	for batch := 0; m.config.MaxBatchesPerRule == 0 || batch < m.config.MaxBatchesPerRule; batch++ {
+		if batch > 0 && m.config.BatchPause > 0 {
+			select {
+			case <-ctx.Done():
+				return total, ctx.Err()
+			case <-time.After(m.config.BatchPause):
+			}
+		}
+
+		deleted, err := m.purger.PurgeTraces(ctx, criteria)
+		if err != nil {
+			return total, err
+		}
+		if deleted > 0 {
+			total += deleted
+			m.metrics.RecordTracesPurged(target.Rule, deleted)
+		}
+		if deleted < int64(criteria.Limit) {
+			break
+		}
+	}
+
+	return total, nil
+}
+
+// Start purges periodically until the context is cancelled
+func (m *retentionManager) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := m.Purge(ctx, false); err != nil && err != domain.ErrPurgeInProgress && ctx.Err() == nil {
+				fmt.Printf("Failed to purge expired traces: %v\n", err)
+			}
+		}
+	}
+}
+
+// ParseRetentionRules parses retention rules of the form
+// "service:status=age,...", where "*" matches any service or status and the
+// age is a Go duration or a number of days such as "30d"
+func ParseRetentionRules(spec string) ([]domain.RetentionRule, error) {
+	var rules []domain.RetentionRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		selector, age, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("retention rule %q: expected service:status=age", entry)
+		}
+		service, status, ok := strings.Cut(strings.TrimSpace(selector), ":")
+		if !ok {
+			return nil, fmt.Errorf("retention rule %q: expected service:status=age", entry)
+		}
+
+		rule := domain.RetentionRule{}
+		if service = strings.TrimSpace(service); service != "*" {
+			if service == "" {
+				return nil, fmt.Errorf("retention rule %q: service is required, use * for any", entry)
+			}
+			rule.Service = domain.ServiceName(service)
+		}
+		switch status = strings.TrimSpace(status); domain.TraceStatus(status) {
+		case "*":
+		case domain.TraceStatusSuccess, domain.TraceStatusError, domain.TraceStatusTimeout:
+			rule.Status = domain.TraceStatus(status)
+		default:
+			return nil, fmt.Errorf("retention rule %q: invalid trace status %q", entry, status)
+		}
+
+		maxAge, err := ParseRetentionAge(age)
+		if err != nil {
+			return nil, fmt.Errorf("retention rule %q: %w", entry, err)
+		}
+		rule.MaxAge = maxAge
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ParseRetentionAge parses a retention age given as a Go duration or a
+// number of days such as "30d"; zero keeps traces forever
+func ParseRetentionAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	var age time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention age %q", value)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention age %q", value)
+		}
+		age = parsed
+	}
+
+	if age < 0 {
+		return 0, fmt.Errorf("retention age %q cannot be negative", value)
+	}
+	return age, nil
+}
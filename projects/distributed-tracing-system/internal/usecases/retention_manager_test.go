@@ -0,0 +1,207 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPurgingRepository is a trace repository that supports purging
+type MockPurgingRepository struct {
+	MockTraceRepository
+}
+
+func (m *MockPurgingRepository) PurgeTraces(ctx context.Context, criteria domain.PurgeCriteria) (int64, error) {
+	args := m.Called(ctx, criteria)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPurgingRepository) PurgeStats(ctx context.Context, criteria domain.PurgeCriteria) (domain.PurgeStats, error) {
+	args := m.Called(ctx, criteria)
+	return args.Get(0).(domain.PurgeStats), args.Error(1)
+}
+
+func newTestRetentionManager(t *testing.T, repo domain.TraceRepository, metrics domain.PrometheusExporter, config RetentionConfig) *retentionManager {
+	if config.Interval == 0 {
+		config.Interval = time.Minute
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 2
+	}
+
+	manager, err := NewRetentionManager(repo, metrics, config)
+	require.NoError(t, err)
+
+	rm := manager.(*retentionManager)
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	rm.now = func() time.Time { return now }
+	return rm
+}
+
+func TestRetentionManager_PurgeInBatches(t *testing.T) {
+	// Arrange
+	repo := new(MockPurgingRepository)
+	metrics := new(MockPrometheusExporter)
+	errorsRule := domain.RetentionRule{Status: domain.TraceStatusError, MaxAge: 30 * 24 * time.Hour}
+	manager := newTestRetentionManager(t, repo, metrics, RetentionConfig{
+		Policy: domain.RetentionPolicy{
+			DefaultMaxAge: 24 * time.Hour,
+			Rules:         []domain.RetentionRule{errorsRule},
+		},
+		MaxBatchesPerRule: 2,
+	})
+
+	ctx := context.Background()
+	now := manager.now()
+	errorsCriteria := domain.PurgeCriteria{Status: domain.TraceStatusError, Exclude: []domain.RetentionRule{}, Before: now.Add(-30 * 24 * time.Hour)}
+	defaultCriteria := domain.PurgeCriteria{Exclude: []domain.RetentionRule{errorsRule}, Before: now.Add(-24 * time.Hour)}
+	batch := func(criteria domain.PurgeCriteria) domain.PurgeCriteria {
+		criteria.Limit = 2
+		return criteria
+	}
+
+	// The error rule drains in a short batch, the default stops at the batch limit
+	repo.On("PurgeTraces", ctx, batch(errorsCriteria)).Return(int64(2), nil).Once()
+	repo.On("PurgeTraces", ctx, batch(errorsCriteria)).Return(int64(1), nil).Once()
+	repo.On("PurgeStats", ctx, errorsCriteria).Return(domain.PurgeStats{}, nil)
+	repo.On("PurgeTraces", ctx, batch(defaultCriteria)).Return(int64(2), nil).Twice()
+	repo.On("PurgeStats", ctx, defaultCriteria).Return(domain.PurgeStats{Count: 5, Oldest: defaultCriteria.Before.Add(-time.Hour)}, nil)
+
+	metrics.On("RecordTracesPurged", "*:error", int64(2)).Once()
+	metrics.On("RecordTracesPurged", "*:error", int64(1)).Once()
+	metrics.On("RecordTracesPurged", "default", int64(2)).Twice()
+	metrics.On("RecordRetentionLag", time.Hour).Once()
+
+	// Act
+	report, err := manager.Purge(ctx, false)
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assert.Equal(t, int64(7), report.Total)
+	assert.Equal(t, []domain.PurgeRuleReport{
+		{Rule: "*:error", Cutoff: errorsCriteria.Before, Purged: 3},
+		{Rule: "default", Cutoff: defaultCriteria.Before, Purged: 4, Remaining: 5},
+	}, report.Rules)
+	repo.AssertExpectations(t)
+	metrics.AssertExpectations(t)
+}
+
+func TestRetentionManager_DryRun(t *testing.T) {
+	// Arrange
+	repo := new(MockPurgingRepository)
+	metrics := new(MockPrometheusExporter)
+	manager := newTestRetentionManager(t, repo, metrics, RetentionConfig{
+		Policy: domain.RetentionPolicy{
+			DefaultMaxAge: 7 * 24 * time.Hour,
+			// Traces kept forever are never counted
+			Rules: []domain.RetentionRule{{Service: "audit"}},
+		},
+	})
+
+	ctx := context.Background()
+	criteria := domain.PurgeCriteria{
+		Exclude: []domain.RetentionRule{{Service: "audit"}},
+		Before:  manager.now().Add(-7 * 24 * time.Hour),
+	}
+	repo.On("PurgeStats", ctx, criteria).Return(domain.PurgeStats{Count: 12}, nil)
+
+	// Act
+	report, err := manager.Purge(ctx, true)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(12), report.Total)
+	require.Len(t, report.Rules, 1)
+	assert.Equal(t, "default", report.Rules[0].Rule)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "PurgeTraces", mock.Anything, mock.Anything)
+	metrics.AssertNotCalled(t, "RecordRetentionLag", mock.Anything)
+}
+
+func TestRetentionManager_PurgeInProgress(t *testing.T) {
+	manager := newTestRetentionManager(t, new(MockPurgingRepository), new(MockPrometheusExporter), RetentionConfig{
+		Policy: domain.RetentionPolicy{DefaultMaxAge: time.Hour},
+	})
+
+	manager.running.Lock()
+	defer manager.running.Unlock()
+
+	_, err := manager.Purge(context.Background(), false)
+	assert.ErrorIs(t, err, domain.ErrPurgeInProgress)
+}
+
+func TestNewRetentionManager_Validation(t *testing.T) {
+	valid := RetentionConfig{Interval: time.Minute, BatchSize: 100}
+
+	_, err := NewRetentionManager(new(MockTraceRepository), new(MockPrometheusExporter), valid)
+	assert.Error(t, err, "repositories that cannot purge are rejected")
+
+	tests := map[string]func(*RetentionConfig){
+		"zero interval":        func(c *RetentionConfig) { c.Interval = 0 },
+		"zero batch size":      func(c *RetentionConfig) { c.BatchSize = 0 },
+		"negative batch pause": func(c *RetentionConfig) { c.BatchPause = -time.Second },
+		"negative max batches": func(c *RetentionConfig) { c.MaxBatchesPerRule = -1 },
+		"negative default age": func(c *RetentionConfig) { c.Policy.DefaultMaxAge = -time.Hour },
+		"negative rule age": func(c *RetentionConfig) {
+			c.Policy.Rules = []domain.RetentionRule{{Service: "checkout", MaxAge: -time.Hour}}
+		},
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := valid
+			modify(&config)
+			_, err := NewRetentionManager(new(MockPurgingRepository), new(MockPrometheusExporter), config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseRetentionRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []domain.RetentionRule
+		wantErr  bool
+	}{
+		{name: "empty", spec: "", expected: nil},
+		{
+			name: "wildcards and days",
+			spec: "*:error=30d, checkout:*=12h",
+			expected: []domain.RetentionRule{
+				{Status: domain.TraceStatusError, MaxAge: 30 * 24 * time.Hour},
+				{Service: "checkout", MaxAge: 12 * time.Hour},
+			},
+		},
+		{
+			name:     "zero keeps forever",
+			spec:     "audit:*=0",
+			expected: []domain.RetentionRule{{Service: "audit"}},
+		},
+		{name: "missing age", spec: "checkout:error", wantErr: true},
+		{name: "missing status", spec: "checkout=1h", wantErr: true},
+		{name: "empty service", spec: ":error=1h", wantErr: true},
+		{name: "unknown status", spec: "checkout:failed=1h", wantErr: true},
+		{name: "invalid age", spec: "checkout:error=soon", wantErr: true},
+		{name: "negative age", spec: "checkout:error=-1h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRetentionRules(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rules)
+		})
+	}
+}
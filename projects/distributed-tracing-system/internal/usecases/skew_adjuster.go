@@ -0,0 +1,203 @@
+package usecases
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// SkewAdjusterConfig holds configuration for clock skew adjustment
+type SkewAdjusterConfig struct {
+	// Services limits adjustment to these services; empty adjusts all
+	Services []domain.ServiceName
+	// ExcludeServices are never adjusted, e.g. because their hosts are
+	// known to have accurate clocks
+	ExcludeServices []domain.ServiceName
+	// MaxAdjustment leaves spans alone when correcting them would take a
+	// larger shift, which points at broken instrumentation rather than
+	// clock drift; zero allows any shift
+	MaxAdjustment time.Duration
+}
+
+// skewAdjuster implements the SkewAdjuster interface
+type skewAdjuster struct {
+	// services is nil when every service is adjusted
+	services      map[domain.ServiceName]bool
+	excluded      map[domain.ServiceName]bool
+	maxAdjustment time.Duration
+	metrics       domain.PrometheusExporter
+}
+
+// NewSkewAdjuster creates a clock skew adjuster
+func NewSkewAdjuster(config SkewAdjusterConfig, metrics domain.PrometheusExporter) (domain.SkewAdjuster, error) {
+	if config.MaxAdjustment < 0 {
+		return nil, fmt.Errorf("max skew adjustment cannot be negative")
+	}
+
+	a := &skewAdjuster{
+		excluded:      make(map[domain.ServiceName]bool, len(config.ExcludeServices)),
+		maxAdjustment: config.MaxAdjustment,
+		metrics:       metrics,
+	}
+	if len(config.Services) > 0 {
+		a.services = make(map[domain.ServiceName]bool, len(config.Services))
+		for _, service := range config.Services {
+			a.services[service] = true
+		}
+	}
+	for _, service := range config.ExcludeServices {
+		a.excluded[service] = true
+	}
+	return a, nil
+}
+
+// ParseServiceList parses a comma-separated list of service names
+func ParseServiceList(value string) []domain.ServiceName {
+	var services []domain.ServiceName
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			services = append(services, domain.ServiceName(name))
+		}
+	}
+	return services
+}
+
+// Adjust corrects spans that fall outside their parent's time window.
+// Clocks are assumed to be shared within a service: where a span's parent
+// belongs to another service, an offset is estimated that moves the span
+// inside its parent, and the span's same-service descendants are shifted
+// with it. Parents are adjusted before their children, so offsets
+// accumulate down the tree. Every moved span gets its total offset in the
+// domain.SkewAdjustmentTag tag. Spans adjusted by an earlier pass keep
+// that offset and pass it on, so spans merged into a stored trace are
+// shifted along with their already adjusted parents.
+func (a *skewAdjuster) Adjust(trace *domain.Trace) int {
+	spans := trace.Spans
+	index := make(map[domain.SpanID]int, len(spans))
+	for i, span := range spans {
+		if _, ok := index[span.ID]; !ok {
+			index[span.ID] = i
+		}
+	}
+
+	children := make(map[int][]int)
+	var roots []int
+	for i, span := range spans {
+		parent, ok := -1, false
+		if span.ParentID != nil {
+			parent, ok = index[*span.ParentID]
+		}
+		if !ok || parent == i || index[span.ID] != i {
+			roots = append(roots, i)
+			continue
+		}
+		children[parent] = append(children[parent], i)
+	}
+
+	// offsets hold the total shift of each span from its reported time.
+	// Spans caught in parent cycles are never reached and stay as they are.
+	offsets := make([]time.Duration, len(spans))
+	adjusted := 0
+	var walk func(i, parent int)
+	walk = func(i, parent int) {
+		span := &spans[i]
+		applied := appliedOffset(span)
+		offsets[i] = applied
+		if parent >= 0 && a.enabled(span.Service) {
+			if span.Service == spans[parent].Service {
+				offsets[i] = offsets[parent]
+			} else if offset := a.estimateOffset(span, &spans[parent]); offset != 0 {
+				offsets[i] = applied + offset
+				a.metrics.RecordClockSkewAdjustment(string(span.Service), offset)
+			}
+		}
+
+		if shift := offsets[i] - applied; shift != 0 {
+			shiftSpan(span, shift, offsets[i])
+			adjusted++
+		}
+
+		for _, child := range children[i] {
+			walk(child, i)
+		}
+	}
+	for _, root := range roots {
+		walk(root, -1)
+	}
+
+	return adjusted
+}
+
+// appliedOffset returns the offset an earlier pass recorded on a span
+func appliedOffset(span *domain.Span) time.Duration {
+	offset, err := time.ParseDuration(span.Tags[domain.SkewAdjustmentTag])
+	if err != nil {
+		return 0
+	}
+	return offset
+}
+
+// enabled reports whether spans of a service may be adjusted
+func (a *skewAdjuster) enabled(service domain.ServiceName) bool {
+	if a.excluded[service] {
+		return false
+	}
+	return a.services == nil || a.services[service]
+}
+
+// estimateOffset returns the shift that moves a span inside its (already
+// adjusted) parent, or zero when it fits or needs more than the maximum.
+// A span that fits in its parent is centred in it, splitting the unknown
+// network latency evenly between request and response; longer spans and
+// asynchronous consumers are aligned with the parent's start instead.
+func (a *skewAdjuster) estimateOffset(span, parent *domain.Span) time.Duration {
+	startsEarly := span.StartTime.Before(parent.StartTime)
+	endsLate := span.EndTime.After(parent.EndTime)
+	if !startsEarly && !endsLate {
+		return 0
+	}
+
+	spanDuration := span.EndTime.Sub(span.StartTime)
+	parentDuration := parent.EndTime.Sub(parent.StartTime)
+
+	var offset time.Duration
+	switch {
+	case span.Tags[domain.SpanKindTag] == "consumer":
+		// Consumers may legitimately outlive the producer
+		if !startsEarly {
+			return 0
+		}
+		offset = parent.StartTime.Sub(span.StartTime)
+	case spanDuration > parentDuration:
+		offset = parent.StartTime.Sub(span.StartTime)
+	default:
+		latency := (parentDuration - spanDuration) / 2
+		offset = parent.StartTime.Add(latency).Sub(span.StartTime)
+	}
+
+	if a.maxAdjustment > 0 && (offset > a.maxAdjustment || offset < -a.maxAdjustment) {
+		return 0
+	}
+	return offset
+}
+
+// shiftSpan moves a span and its logs by shift and records the total
+// offset from its reported time
+func shiftSpan(span *domain.Span, shift, total time.Duration) {
+	span.StartTime = span.StartTime.Add(shift)
+	span.EndTime = span.EndTime.Add(shift)
+	for i := range span.Logs {
+		span.Logs[i].Timestamp = span.Logs[i].Timestamp.Add(shift)
+	}
+
+	if total == 0 {
+		delete(span.Tags, domain.SkewAdjustmentTag)
+		return
+	}
+	if span.Tags == nil {
+		span.Tags = map[string]string{}
+	}
+	span.Tags[domain.SkewAdjustmentTag] = total.String()
+}
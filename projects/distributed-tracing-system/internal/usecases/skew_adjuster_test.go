@@ -0,0 +1,194 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var skewTestStart = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func skewTestSpan(id, parent domain.SpanID, service domain.ServiceName, from, to int) domain.Span {
+	span := domain.Span{
+		ID:        id,
+		TraceID:   "trace1",
+		Service:   service,
+		Operation: "op",
+		StartTime: skewTestStart.Add(time.Duration(from) * time.Millisecond),
+		EndTime:   skewTestStart.Add(time.Duration(to) * time.Millisecond),
+		Duration:  time.Duration(to-from) * time.Millisecond,
+	}
+	if parent != "" {
+		span.ParentID = &parent
+	}
+	return span
+}
+
+func newTestSkewAdjuster(t *testing.T, config SkewAdjusterConfig) (domain.SkewAdjuster, *MockPrometheusExporter) {
+	metrics := new(MockPrometheusExporter)
+	metrics.On("RecordClockSkewAdjustment", mock.Anything, mock.Anything).Maybe()
+
+	adjuster, err := NewSkewAdjuster(config, metrics)
+	require.NoError(t, err)
+	return adjuster, metrics
+}
+
+func TestSkewAdjuster_ShiftsServiceSubtree(t *testing.T) {
+	// Arrange: the payments hosts run 100ms behind, the database is accurate
+	adjuster, metrics := newTestSkewAdjuster(t, SkewAdjusterConfig{})
+	payments := skewTestSpan("payments", "root", "payments", -90, -30)
+	payments.Logs = []domain.Log{{Timestamp: skewTestStart.Add(-60 * time.Millisecond), Message: "charging"}}
+	trace := &domain.Trace{ID: "trace1", Spans: []domain.Span{
+		skewTestSpan("root", "", "checkout", 0, 100),
+		payments,
+		skewTestSpan("payments-db", "payments", "payments", -80, -40),
+		skewTestSpan("db", "payments-db", "postgres", 30, 50),
+	}}
+
+	// Act
+	adjusted := adjuster.Adjust(trace)
+
+	// Assert: payments is centred in its parent and its subtree moves along
+	assert.Equal(t, 2, adjusted)
+	offset := 110 * time.Millisecond
+	assert.Equal(t, skewTestStart.Add(20*time.Millisecond), trace.Spans[1].StartTime)
+	assert.Equal(t, skewTestStart.Add(80*time.Millisecond), trace.Spans[1].EndTime)
+	assert.Equal(t, skewTestStart.Add(50*time.Millisecond), trace.Spans[1].Logs[0].Timestamp)
+	assert.Equal(t, offset.String(), trace.Spans[1].Tags[domain.SkewAdjustmentTag])
+	assert.Equal(t, skewTestStart.Add(30*time.Millisecond), trace.Spans[2].StartTime)
+	assert.Equal(t, offset.String(), trace.Spans[2].Tags[domain.SkewAdjustmentTag])
+
+	// The root and the database already fit their parents
+	assert.Equal(t, skewTestStart, trace.Spans[0].StartTime)
+	assert.NotContains(t, trace.Spans[0].Tags, domain.SkewAdjustmentTag)
+	assert.Equal(t, skewTestStart.Add(30*time.Millisecond), trace.Spans[3].StartTime)
+	assert.NotContains(t, trace.Spans[3].Tags, domain.SkewAdjustmentTag)
+
+	metrics.AssertCalled(t, "RecordClockSkewAdjustment", "payments", offset)
+	metrics.AssertNumberOfCalls(t, "RecordClockSkewAdjustment", 1)
+}
+
+func TestSkewAdjuster_ShiftsLateSpansWithAdjustedParents(t *testing.T) {
+	// Arrange: a stored trace whose payments span was already adjusted
+	adjuster, metrics := newTestSkewAdjuster(t, SkewAdjusterConfig{})
+	trace := &domain.Trace{ID: "trace1", Spans: []domain.Span{
+		skewTestSpan("root", "", "checkout", 0, 100),
+		skewTestSpan("payments", "root", "payments", -90, -30),
+	}}
+	require.Equal(t, 1, adjuster.Adjust(trace))
+
+	// Act: a late payments span is merged in with its reported timestamps
+	trace.Spans = append(trace.Spans, skewTestSpan("payments-db", "payments", "payments", -80, -40))
+	adjusted := adjuster.Adjust(trace)
+
+	// Assert: only the late span moves, by the offset of its parent
+	assert.Equal(t, 1, adjusted)
+	offset := 110 * time.Millisecond
+	assert.Equal(t, skewTestStart.Add(20*time.Millisecond), trace.Spans[1].StartTime)
+	assert.Equal(t, skewTestStart.Add(30*time.Millisecond), trace.Spans[2].StartTime)
+	assert.Equal(t, skewTestStart.Add(70*time.Millisecond), trace.Spans[2].EndTime)
+	assert.Equal(t, offset.String(), trace.Spans[2].Tags[domain.SkewAdjustmentTag])
+	metrics.AssertNumberOfCalls(t, "RecordClockSkewAdjustment", 1)
+
+	// Adjusting again changes nothing
+	assert.Equal(t, 0, adjuster.Adjust(trace))
+	assert.Equal(t, skewTestStart.Add(20*time.Millisecond), trace.Spans[1].StartTime)
+	assert.Equal(t, offset.String(), trace.Spans[1].Tags[domain.SkewAdjustmentTag])
+}
+
+func TestSkewAdjuster_Adjust(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    SkewAdjusterConfig
+		child     domain.Span
+		wantStart int
+	}{
+		{
+			name:      "fits in parent",
+			child:     skewTestSpan("child", "root", "payments", 10, 90),
+			wantStart: 10,
+		},
+		{
+			name:      "ends after parent",
+			child:     skewTestSpan("child", "root", "payments", 60, 120),
+			wantStart: 20,
+		},
+		{
+			name:      "longer than parent is aligned with its start",
+			child:     skewTestSpan("child", "root", "payments", -50, 150),
+			wantStart: 0,
+		},
+		{
+			name: "consumer outliving its producer",
+			child: func() domain.Span {
+				span := skewTestSpan("child", "root", "payments", 50, 500)
+				span.Tags = map[string]string{domain.SpanKindTag: "consumer"}
+				return span
+			}(),
+			wantStart: 50,
+		},
+		{
+			name:      "excluded service",
+			config:    SkewAdjusterConfig{ExcludeServices: []domain.ServiceName{"payments"}},
+			child:     skewTestSpan("child", "root", "payments", -90, -30),
+			wantStart: -90,
+		},
+		{
+			name:      "service outside the allow list",
+			config:    SkewAdjusterConfig{Services: []domain.ServiceName{"emails"}},
+			child:     skewTestSpan("child", "root", "payments", -90, -30),
+			wantStart: -90,
+		},
+		{
+			name:      "shift above the maximum",
+			config:    SkewAdjusterConfig{MaxAdjustment: 50 * time.Millisecond},
+			child:     skewTestSpan("child", "root", "payments", -90, -30),
+			wantStart: -90,
+		},
+		{
+			name:      "same service shares the clock",
+			child:     skewTestSpan("child", "root", "checkout", -90, -30),
+			wantStart: -90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adjuster, _ := newTestSkewAdjuster(t, tt.config)
+			trace := &domain.Trace{ID: "trace1", Spans: []domain.Span{
+				skewTestSpan("root", "", "checkout", 0, 100),
+				tt.child,
+			}}
+
+			adjuster.Adjust(trace)
+
+			child := trace.Spans[1]
+			assert.Equal(t, skewTestStart.Add(time.Duration(tt.wantStart)*time.Millisecond), child.StartTime)
+			assert.Equal(t, tt.child.EndTime.Sub(tt.child.StartTime), child.EndTime.Sub(child.StartTime))
+		})
+	}
+}
+
+func TestSkewAdjuster_IgnoresParentCycles(t *testing.T) {
+	adjuster, _ := newTestSkewAdjuster(t, SkewAdjusterConfig{})
+	trace := &domain.Trace{ID: "trace1", Spans: []domain.Span{
+		skewTestSpan("a", "b", "checkout", 0, 100),
+		skewTestSpan("b", "a", "payments", -90, -30),
+	}}
+
+	assert.Equal(t, 0, adjuster.Adjust(trace))
+}
+
+func TestNewSkewAdjuster_InvalidConfig(t *testing.T) {
+	_, err := NewSkewAdjuster(SkewAdjusterConfig{MaxAdjustment: -time.Second}, new(MockPrometheusExporter))
+	assert.Error(t, err)
+}
+
+func TestParseServiceList(t *testing.T) {
+	assert.Equal(t, []domain.ServiceName{"checkout", "payments"}, ParseServiceList(" checkout, ,payments "))
+	assert.Empty(t, ParseServiceList(""))
+}
@@ -0,0 +1,420 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// SLOConfig holds configuration for the SLO service
+type SLOConfig struct {
+	// EvaluationInterval is how often burn rates are checked for alerts
+	EvaluationInterval time.Duration
+}
+
+// sloState holds the trace counts of an SLO and which of its burn rate
+// rules are firing
+type sloState struct {
+	slo *domain.SLO
+	// buckets counts the traces per bucket start
+	buckets map[time.Time]*domain.SLOBucket
+	// pending holds the counts recorded since they were last added to the
+	// store
+	pending map[time.Time]*domain.SLOBucket
+	// firing is indexed like the SLO's burn rate rules
+	firing []bool
+}
+
+// sloService implements the SLOService interface. Traces are counted in
+// memory and the counts are added to the store after every evaluation, so
+// they survive restarts. Every instance evaluates the counts stored when it
+// loaded an SLO plus the traces it ingested since.
+type sloService struct {
+	store      domain.SLOStore
+	publishers []domain.AlertPublisher
+	config     SLOConfig
+	now        func() time.Time
+
+	// flushMu serializes the writes of counts to the store, so counts being
+	// flushed are not stored again after an SLO is reset or deleted
+	flushMu sync.Mutex
+	mu      sync.Mutex
+	states  map[string]*sloState
+}
+
+// NewSLOService creates an SLO service that publishes alerts to every
+// publisher
+func NewSLOService(store domain.SLOStore, publishers []domain.AlertPublisher, config SLOConfig) (domain.SLOService, error) {
+	if config.EvaluationInterval <= 0 {
+		return nil, fmt.Errorf("slo evaluation interval must be positive")
+	}
+
+	return &sloService{
+		store:      store,
+		publishers: publishers,
+		config:     config,
+		now:        time.Now,
+		states:     make(map[string]*sloState),
+	}, nil
+}
+
+// CreateSLO stores a new SLO under a generated ID
+func (s *sloService) CreateSLO(ctx context.Context, slo *domain.SLO) (*domain.SLO, error) {
+	if err := slo.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid slo: %w", err)
+	}
+
+	created := *slo
+	created.ID = uuid.NewString()
+	created.CreatedAt = s.now().UTC()
+	created.UpdatedAt = created.CreatedAt
+	if err := s.store.SaveSLO(ctx, &created); err != nil {
+		return nil, fmt.Errorf("failed to save slo: %w", err)
+	}
+
+	s.mu.Lock()
+	s.states[created.ID] = newSLOState(&created, nil)
+	s.mu.Unlock()
+
+	return &created, nil
+}
+
+// UpdateSLO replaces the definition of an existing SLO. Counts are reset
+// when the traces it selects or its threshold change.
+func (s *sloService) UpdateSLO(ctx context.Context, slo *domain.SLO) (*domain.SLO, error) {
+	if err := slo.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid slo: %w", err)
+	}
+
+	existing, err := s.store.FindSLO(ctx, slo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *slo
+	updated.CreatedAt = existing.CreatedAt
+	updated.UpdatedAt = s.now().UTC()
+	if err := s.store.SaveSLO(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to save slo: %w", err)
+	}
+
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	if existing.Service != updated.Service || existing.Operation != updated.Operation || existing.Threshold != updated.Threshold {
+		s.mu.Lock()
+		s.states[updated.ID] = newSLOState(&updated, nil)
+		s.mu.Unlock()
+		if err := s.store.DeleteSLOBuckets(ctx, updated.ID, time.Time{}); err != nil {
+			return nil, fmt.Errorf("failed to reset slo counts: %w", err)
+		}
+		return &updated, nil
+	}
+
+	s.mu.Lock()
+	state, ok := s.states[updated.ID]
+	if ok {
+		state.slo = &updated
+		state.firing = make([]bool, len(domain.BurnRateRules(updated.Window)))
+	}
+	s.mu.Unlock()
+	if !ok {
+		if _, err := s.track(ctx, &updated); err != nil {
+			return nil, err
+		}
+	}
+
+	return &updated, nil
+}
+
+// DeleteSLO removes an SLO and its counts
+func (s *sloService) DeleteSLO(ctx context.Context, id string) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	if err := s.store.DeleteSLO(ctx, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.states, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// GetSLO returns an SLO by ID
+func (s *sloService) GetSLO(ctx context.Context, id string) (*domain.SLO, error) {
+	return s.store.FindSLO(ctx, id)
+}
+
+// ListSLOs returns every SLO
+func (s *sloService) ListSLOs(ctx context.Context) ([]*domain.SLO, error) {
+	return s.store.ListSLOs(ctx)
+}
+
+// GetStatus evaluates an SLO against the traces counted so far
+func (s *sloService) GetStatus(ctx context.Context, id string) (*domain.SLOStatus, error) {
+	slo, err := s.store.FindSLO(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.track(ctx, slo)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return domain.EvaluateSLO(state.slo, s.now(), state.bucketList()), nil
+}
+
+// Record counts a trace towards the SLOs it matches
+func (s *sloService) Record(trace *domain.Trace) {
+	start := trace.StartTime.UTC().Truncate(domain.SLOBucketWidth)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, state := range s.states {
+		if !state.slo.Matches(trace) {
+			continue
+		}
+		good := state.slo.Good(trace)
+		for _, buckets := range []map[time.Time]*domain.SLOBucket{state.buckets, state.pending} {
+			bucket, ok := buckets[start]
+			if !ok {
+				bucket = &domain.SLOBucket{Start: start}
+				buckets[start] = bucket
+			}
+			bucket.Total++
+			if good {
+				bucket.Good++
+			}
+		}
+	}
+}
+
+// Start loads the stored SLOs and their counts, then evaluates them
+// periodically, publishes an alert whenever a burn rate rule starts or stops
+// firing and stores the new counts, until the context is cancelled. The
+// counts recorded since the last evaluation are stored before returning.
+func (s *sloService) Start(ctx context.Context) error {
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.config.EvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.flush(context.Background()); err != nil {
+				fmt.Printf("Failed to store SLO counts: %v\n", err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := s.evaluate(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to publish SLO alerts: %v\n", err)
+			}
+			if err := s.flush(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to store SLO counts: %v\n", err)
+			}
+		}
+	}
+}
+
+// load tracks the stored SLOs not tracked yet
+func (s *sloService) load(ctx context.Context) error {
+	slos, err := s.store.ListSLOs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load slos: %w", err)
+	}
+
+	for _, slo := range slos {
+		if _, err := s.track(ctx, slo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// track returns the state of an SLO, starting from its stored counts
+// within the SLO window when it is not tracked yet
+func (s *sloService) track(ctx context.Context, slo *domain.SLO) (*sloState, error) {
+	s.mu.Lock()
+	state, ok := s.states[slo.ID]
+	s.mu.Unlock()
+	if ok {
+		return state, nil
+	}
+
+	stored, err := s.store.FindSLOBuckets(ctx, slo.ID, s.now().Add(-slo.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load counts of slo %s: %w", slo.ID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another call may have started tracking the SLO meanwhile
+	if state, ok := s.states[slo.ID]; ok {
+		return state, nil
+	}
+	state = newSLOState(slo, stored)
+	s.states[slo.ID] = state
+	return state, nil
+}
+
+// evaluate drops the buckets that left the SLO windows, evaluates every
+// SLO and publishes the alerts of the rules whose state changed
+func (s *sloService) evaluate(ctx context.Context) error {
+	now := s.now()
+
+	var alerts []*domain.SLOAlert
+	s.mu.Lock()
+	for _, state := range s.states {
+		cutoff := now.Add(-state.slo.Window)
+		for start := range state.buckets {
+			if start.Before(cutoff) {
+				delete(state.buckets, start)
+			}
+		}
+		for start := range state.pending {
+			if start.Before(cutoff) {
+				delete(state.pending, start)
+			}
+		}
+
+		status := domain.EvaluateSLO(state.slo, now, state.bucketList())
+		for i, rate := range status.BurnRates {
+			if rate.Firing == state.firing[i] {
+				continue
+			}
+			state.firing[i] = rate.Firing
+
+			alertState := domain.AlertStateResolved
+			if rate.Firing {
+				alertState = domain.AlertStateFiring
+			}
+			alerts = append(alerts, domain.NewSLOAlert(status, rate, alertState))
+		}
+	}
+	s.mu.Unlock()
+
+	return s.publish(ctx, alerts)
+}
+
+// flush adds the counts recorded since the last flush to the store and
+// drops the stored buckets that left the SLO windows. Counts that could not
+// be stored are kept for the next flush.
+func (s *sloService) flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	type flushed struct {
+		state   *sloState
+		buckets []domain.SLOBucket
+		cutoff  time.Time
+	}
+
+	now := s.now()
+	var batches []flushed
+	s.mu.Lock()
+	for _, state := range s.states {
+		batches = append(batches, flushed{
+			state:   state,
+			buckets: sortedBuckets(state.pending),
+			cutoff:  now.Add(-state.slo.Window),
+		})
+		state.pending = make(map[time.Time]*domain.SLOBucket)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, batch := range batches {
+		id := batch.state.slo.ID
+		if len(batch.buckets) > 0 {
+			if err := s.store.AddSLOBuckets(ctx, id, batch.buckets); err != nil {
+				errs = append(errs, fmt.Errorf("slo %s: %w", id, err))
+				s.restore(batch.state, batch.buckets)
+			}
+		}
+		if err := s.store.DeleteSLOBuckets(ctx, id, batch.cutoff); err != nil {
+			errs = append(errs, fmt.Errorf("slo %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restore adds counts that could not be stored back to the pending counts
+// of an SLO
+func (s *sloService) restore(state *sloState, buckets []domain.SLOBucket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, counts := range buckets {
+		bucket, ok := state.pending[counts.Start]
+		if !ok {
+			bucket = &domain.SLOBucket{Start: counts.Start}
+			state.pending[counts.Start] = bucket
+		}
+		bucket.Total += counts.Total
+		bucket.Good += counts.Good
+	}
+}
+
+// publish delivers the alerts to every publisher, trying every delivery
+// even when some fail
+func (s *sloService) publish(ctx context.Context, alerts []*domain.SLOAlert) error {
+	var errs []error
+	for _, alert := range alerts {
+		for _, publisher := range s.publishers {
+			if err := publisher.PublishAlert(ctx, alert); err != nil {
+				errs = append(errs, fmt.Errorf("slo %s: %w", alert.SLOID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newSLOState returns the state of an SLO starting from the stored counts
+func newSLOState(slo *domain.SLO, stored []domain.SLOBucket) *sloState {
+	state := &sloState{
+		slo:     slo,
+		buckets: make(map[time.Time]*domain.SLOBucket),
+		pending: make(map[time.Time]*domain.SLOBucket),
+		firing:  make([]bool, len(domain.BurnRateRules(slo.Window))),
+	}
+	for _, bucket := range stored {
+		bucket := bucket
+		bucket.Start = bucket.Start.UTC()
+		state.buckets[bucket.Start] = &bucket
+	}
+	return state
+}
+
+// bucketList returns copies of the buckets ordered by start
+func (st *sloState) bucketList() []domain.SLOBucket {
+	return sortedBuckets(st.buckets)
+}
+
+// sortedBuckets returns copies of the buckets ordered by start
+func sortedBuckets(buckets map[time.Time]*domain.SLOBucket) []domain.SLOBucket {
+	list := make([]domain.SLOBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		list = append(list, *bucket)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+	return list
+}
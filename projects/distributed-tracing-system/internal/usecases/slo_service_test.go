@@ -0,0 +1,375 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSLOStore is a mock implementation of SLOStore
+type MockSLOStore struct {
+	mock.Mock
+}
+
+func (m *MockSLOStore) SaveSLO(ctx context.Context, slo *domain.SLO) error {
+	args := m.Called(ctx, slo)
+	return args.Error(0)
+}
+
+func (m *MockSLOStore) FindSLO(ctx context.Context, id string) (*domain.SLO, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SLO), args.Error(1)
+}
+
+func (m *MockSLOStore) ListSLOs(ctx context.Context) ([]*domain.SLO, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.SLO), args.Error(1)
+}
+
+func (m *MockSLOStore) DeleteSLO(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSLOStore) AddSLOBuckets(ctx context.Context, id string, buckets []domain.SLOBucket) error {
+	args := m.Called(ctx, id, buckets)
+	return args.Error(0)
+}
+
+func (m *MockSLOStore) FindSLOBuckets(ctx context.Context, id string, since time.Time) ([]domain.SLOBucket, error) {
+	args := m.Called(ctx, id, since)
+	return args.Get(0).([]domain.SLOBucket), args.Error(1)
+}
+
+func (m *MockSLOStore) DeleteSLOBuckets(ctx context.Context, id string, before time.Time) error {
+	args := m.Called(ctx, id, before)
+	return args.Error(0)
+}
+
+// MockAlertPublisher is a mock implementation of AlertPublisher
+type MockAlertPublisher struct {
+	mock.Mock
+}
+
+func (m *MockAlertPublisher) PublishAlert(ctx context.Context, alert *domain.SLOAlert) error {
+	args := m.Called(ctx, alert)
+	return args.Error(0)
+}
+
+var sloTestNow = time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+func newSLOTestSLO() *domain.SLO {
+	return &domain.SLO{
+		ID:        "slo-1",
+		Name:      "checkout latency",
+		Service:   "checkout",
+		Threshold: 300 * time.Millisecond,
+		Target:    0.99,
+		Window:    30 * 24 * time.Hour,
+	}
+}
+
+func newSLOTestTrace(offset, duration time.Duration) *domain.Trace {
+	return &domain.Trace{
+		ID:        "1234567890abcdef",
+		Service:   "checkout",
+		Operation: "pay",
+		StartTime: sloTestNow.Add(offset),
+		Duration:  duration,
+	}
+}
+
+func newTestSLOService(t *testing.T, store domain.SLOStore, publishers ...domain.AlertPublisher) *sloService {
+	service, err := NewSLOService(store, publishers, SLOConfig{EvaluationInterval: time.Minute})
+	require.NoError(t, err)
+	s := service.(*sloService)
+	s.now = func() time.Time { return sloTestNow }
+	return s
+}
+
+func TestNewSLOService_InvalidConfig(t *testing.T) {
+	_, err := NewSLOService(new(MockSLOStore), nil, SLOConfig{})
+	assert.Error(t, err)
+}
+
+func TestSLOService_CreateSLO(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	store.On("SaveSLO", mock.Anything, mock.Anything).Return(nil).Once()
+
+	slo := newSLOTestSLO()
+	slo.ID = ""
+	created, err := service.CreateSLO(context.Background(), slo)
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, sloTestNow, created.CreatedAt)
+	assert.Contains(t, service.states, created.ID)
+	store.AssertExpectations(t)
+
+	// Invalid SLOs are not stored
+	_, err = service.CreateSLO(context.Background(), &domain.SLO{Name: "missing service"})
+	assert.Error(t, err)
+	store.AssertNumberOfCalls(t, "SaveSLO", 1)
+}
+
+func TestSLOService_RecordAndGetStatus(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	slo := newSLOTestSLO()
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{slo}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	store.On("FindSLO", mock.Anything, "slo-1").Return(slo, nil)
+	require.NoError(t, service.load(context.Background()))
+
+	service.Record(newSLOTestTrace(-2*time.Minute, 100*time.Millisecond))
+	service.Record(newSLOTestTrace(-2*time.Minute, time.Second))
+	service.Record(newSLOTestTrace(-time.Minute, 200*time.Millisecond))
+	// Traces of other services are not counted
+	other := newSLOTestTrace(-time.Minute, time.Second)
+	other.Service = "payments"
+	service.Record(other)
+
+	status, err := service.GetStatus(context.Background(), "slo-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.TotalCount)
+	assert.Equal(t, int64(2), status.GoodCount)
+	assert.Len(t, service.states["slo-1"].buckets, 2)
+}
+
+func TestSLOService_EvaluatePublishesTransitions(t *testing.T) {
+	store := new(MockSLOStore)
+	publisher := new(MockAlertPublisher)
+	service := newTestSLOService(t, store, publisher)
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{newSLOTestSLO()}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	require.NoError(t, service.load(context.Background()))
+
+	var alerts []*domain.SLOAlert
+	publisher.On("PublishAlert", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		alerts = append(alerts, args.Get(1).(*domain.SLOAlert))
+	}).Return(nil)
+
+	// Every trace misses the threshold: every rule fires
+	for i := 0; i < 10; i++ {
+		service.Record(newSLOTestTrace(-time.Minute, time.Second))
+	}
+	require.NoError(t, service.evaluate(context.Background()))
+	require.Len(t, alerts, 3)
+	for _, alert := range alerts {
+		assert.Equal(t, domain.AlertStateFiring, alert.State)
+		assert.Equal(t, "slo-1", alert.SLOID)
+	}
+
+	// Nothing changed: no new alerts
+	require.NoError(t, service.evaluate(context.Background()))
+	assert.Len(t, alerts, 3)
+
+	// An hour later the burning traces left the page windows but not the
+	// ticket window
+	service.now = func() time.Time { return sloTestNow.Add(time.Hour) }
+	for i := 0; i < 500; i++ {
+		service.Record(newSLOTestTrace(time.Hour, time.Millisecond))
+	}
+	require.NoError(t, service.evaluate(context.Background()))
+	require.Len(t, alerts, 5)
+	for _, alert := range alerts[3:] {
+		assert.Equal(t, domain.AlertStateResolved, alert.State)
+		assert.Equal(t, domain.AlertSeverityPage, alert.Severity)
+	}
+}
+
+func TestSLOService_EvaluateReportsPublishErrors(t *testing.T) {
+	store := new(MockSLOStore)
+	failing := new(MockAlertPublisher)
+	working := new(MockAlertPublisher)
+	service := newTestSLOService(t, store, failing, working)
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{newSLOTestSLO()}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	require.NoError(t, service.load(context.Background()))
+	failing.On("PublishAlert", mock.Anything, mock.Anything).Return(errors.New("unreachable"))
+	working.On("PublishAlert", mock.Anything, mock.Anything).Return(nil)
+
+	service.Record(newSLOTestTrace(-time.Minute, time.Second))
+	err := service.evaluate(context.Background())
+
+	assert.ErrorContains(t, err, "unreachable")
+	working.AssertNumberOfCalls(t, "PublishAlert", 3)
+}
+
+func TestSLOService_EvaluateDropsExpiredBuckets(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	slo := newSLOTestSLO()
+	slo.Window = time.Hour
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{slo}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	require.NoError(t, service.load(context.Background()))
+
+	service.Record(newSLOTestTrace(-2*time.Hour, time.Millisecond))
+	service.Record(newSLOTestTrace(-time.Minute, time.Millisecond))
+	require.NoError(t, service.evaluate(context.Background()))
+
+	assert.Len(t, service.states["slo-1"].buckets, 1)
+}
+
+func TestSLOService_CountsSurviveRestart(t *testing.T) {
+	// Arrange: the store already holds counts added before
+	store := new(MockSLOStore)
+	slo := newSLOTestSLO()
+	earlier := domain.SLOBucket{Start: sloTestNow.Add(-10 * time.Minute), Total: 4, Good: 4}
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{slo}, nil)
+	store.On("FindSLO", mock.Anything, "slo-1").Return(slo, nil)
+	store.On("FindSLOBuckets", mock.Anything, "slo-1", sloTestNow.Add(-slo.Window)).Return([]domain.SLOBucket{earlier}, nil).Once()
+	store.On("DeleteSLOBuckets", mock.Anything, "slo-1", sloTestNow.Add(-slo.Window)).Return(nil)
+
+	var stored []domain.SLOBucket
+	store.On("AddSLOBuckets", mock.Anything, "slo-1", mock.Anything).Run(func(args mock.Arguments) {
+		stored = append(stored, args.Get(2).([]domain.SLOBucket)...)
+	}).Return(nil)
+
+	service := newTestSLOService(t, store)
+	require.NoError(t, service.load(context.Background()))
+
+	// Act: count new traces and store them
+	service.Record(newSLOTestTrace(-time.Minute, 100*time.Millisecond))
+	service.Record(newSLOTestTrace(-time.Minute, time.Second))
+	require.NoError(t, service.flush(context.Background()))
+
+	// Assert: only the new counts are added to the store
+	start := sloTestNow.Add(-time.Minute)
+	assert.Equal(t, []domain.SLOBucket{{Start: start, Total: 2, Good: 1}}, stored)
+	status, err := service.GetStatus(context.Background(), "slo-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), status.TotalCount)
+
+	// Nothing new was recorded: nothing is added
+	require.NoError(t, service.flush(context.Background()))
+	store.AssertNumberOfCalls(t, "AddSLOBuckets", 1)
+
+	// A restarted service starts from every stored count
+	store.On("FindSLOBuckets", mock.Anything, "slo-1", sloTestNow.Add(-slo.Window)).Return(append([]domain.SLOBucket{earlier}, stored...), nil).Once()
+	restarted := newTestSLOService(t, store)
+	require.NoError(t, restarted.load(context.Background()))
+
+	status, err = restarted.GetStatus(context.Background(), "slo-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), status.TotalCount)
+	assert.Equal(t, int64(5), status.GoodCount)
+}
+
+func TestSLOService_FlushKeepsCountsTheStoreRefused(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{newSLOTestSLO()}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	store.On("DeleteSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, service.load(context.Background()))
+
+	start := sloTestNow.Add(-time.Minute)
+	store.On("AddSLOBuckets", mock.Anything, "slo-1", []domain.SLOBucket{{Start: start, Total: 1, Good: 1}}).Return(errors.New("database unavailable")).Once()
+	service.Record(newSLOTestTrace(-time.Minute, time.Millisecond))
+	assert.ErrorContains(t, service.flush(context.Background()), "database unavailable")
+
+	// The refused counts are stored with the ones recorded since
+	store.On("AddSLOBuckets", mock.Anything, "slo-1", []domain.SLOBucket{{Start: start, Total: 2, Good: 1}}).Return(nil).Once()
+	service.Record(newSLOTestTrace(-time.Minute, time.Second))
+	require.NoError(t, service.flush(context.Background()))
+	store.AssertExpectations(t)
+}
+
+func TestSLOService_UpdateSLO(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	existing := newSLOTestSLO()
+	existing.CreatedAt = sloTestNow.Add(-time.Hour)
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{existing}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	store.On("FindSLO", mock.Anything, "slo-1").Return(existing, nil)
+	store.On("SaveSLO", mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, service.load(context.Background()))
+	service.Record(newSLOTestTrace(-time.Minute, time.Millisecond))
+
+	// Renaming keeps the counts
+	renamed := newSLOTestSLO()
+	renamed.Name = "checkout p99"
+	updated, err := service.UpdateSLO(context.Background(), renamed)
+	require.NoError(t, err)
+	assert.Equal(t, existing.CreatedAt, updated.CreatedAt)
+	assert.Equal(t, sloTestNow, updated.UpdatedAt)
+	assert.Len(t, service.states["slo-1"].buckets, 1)
+	assert.Equal(t, "checkout p99", service.states["slo-1"].slo.Name)
+
+	// A new threshold resets them, also in the store
+	store.On("DeleteSLOBuckets", mock.Anything, "slo-1", time.Time{}).Return(nil).Once()
+	stricter := newSLOTestSLO()
+	stricter.Threshold = 100 * time.Millisecond
+	_, err = service.UpdateSLO(context.Background(), stricter)
+	require.NoError(t, err)
+	assert.Empty(t, service.states["slo-1"].buckets)
+	store.AssertExpectations(t)
+}
+
+func TestSLOService_UpdateMissingSLO(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	store.On("FindSLO", mock.Anything, "slo-1").Return(nil, domain.ErrSLONotFound)
+
+	_, err := service.UpdateSLO(context.Background(), newSLOTestSLO())
+
+	assert.ErrorIs(t, err, domain.ErrSLONotFound)
+	store.AssertNotCalled(t, "SaveSLO", mock.Anything, mock.Anything)
+}
+
+func TestSLOService_DeleteSLO(t *testing.T) {
+	store := new(MockSLOStore)
+	service := newTestSLOService(t, store)
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{newSLOTestSLO()}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	store.On("DeleteSLO", mock.Anything, "slo-1").Return(nil)
+	require.NoError(t, service.load(context.Background()))
+
+	require.NoError(t, service.DeleteSLO(context.Background(), "slo-1"))
+	assert.NotContains(t, service.states, "slo-1")
+}
+
+func TestTraceService_ProcessTrace_RecordsSLOs(t *testing.T) {
+	// Arrange: the first save is refused by storage
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(domain.ErrBackpressure).Once()
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+	mockKafka.On("PublishTraceEvent", mock.Anything, mock.Anything).Return(nil)
+
+	store := new(MockSLOStore)
+	slos := newTestSLOService(t, store)
+	store.On("ListSLOs", mock.Anything).Return([]*domain.SLO{newSLOTestSLO()}, nil)
+	store.On("FindSLOBuckets", mock.Anything, mock.Anything, mock.Anything).Return([]domain.SLOBucket{}, nil)
+	require.NoError(t, slos.load(context.Background()))
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithSLOService(slos))
+	trace := newSLOTestTrace(-time.Minute, time.Second)
+	trace.EndTime = trace.StartTime.Add(trace.Duration)
+
+	// Act & Assert: only the stored trace is counted
+	require.ErrorIs(t, service.ProcessTrace(context.Background(), trace), domain.ErrBackpressure)
+	assert.Empty(t, slos.states["slo-1"].buckets)
+
+	require.NoError(t, service.ProcessTrace(context.Background(), trace))
+	require.Len(t, slos.states["slo-1"].buckets, 1)
+	for _, bucket := range slos.states["slo-1"].buckets {
+		assert.Equal(t, int64(1), bucket.Total)
+	}
+}
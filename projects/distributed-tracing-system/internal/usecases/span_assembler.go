@@ -0,0 +1,373 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// SpanAssemblerConfig holds configuration for the span assembler
+type SpanAssemblerConfig struct {
+	// IdleTimeout is how long a trace may go without new spans before it is completed
+	IdleTimeout time.Duration
+	// MaxPendingTraces bounds the number of buffered traces; the oldest is completed early when exceeded
+	MaxPendingTraces int
+	// CompletedTTL is how long completed trace IDs are remembered so late spans of dropped traces are dropped too
+	CompletedTTL time.Duration
+}
+
+// finalFlushAttempts bounds how often the flush on shutdown retries traces
+// refused because storage is saturated
+const finalFlushAttempts = 5
+
+// pendingTrace holds the spans buffered for a single trace
+type pendingTrace struct {
+	spans      map[domain.SpanID]domain.Span
+	firstSeen  time.Time
+	lastUpdate time.Time
+	// acks are called once the trace is stored or dropped
+	acks []func()
+	// retry stores the trace again after the write queue refused it,
+	// without processing it a second time
+	retry func(ctx context.Context) error
+	// changed is set when spans arrived after retry was set
+	changed bool
+}
+
+// spanAssembler implements the SpanAssembler interface
+type spanAssembler struct {
+	traceService domain.TraceService
+	config       SpanAssemblerConfig
+
+	mu        sync.Mutex
+	pending   map[domain.TraceID]*pendingTrace
+	completed map[domain.TraceID]time.Time
+	now       func() time.Time
+}
+
+// NewSpanAssembler creates a new span assembler that completes traces
+// through the given trace service
+func NewSpanAssembler(traceService domain.TraceService, config SpanAssemblerConfig) (domain.SpanAssembler, error) {
+	if traceService == nil {
+		return nil, fmt.Errorf("trace service is required")
+	}
+	if config.IdleTimeout <= 0 {
+		return nil, fmt.Errorf("idle timeout must be positive")
+	}
+	if config.MaxPendingTraces <= 0 {
+		return nil, fmt.Errorf("max pending traces must be positive")
+	}
+
+	return &spanAssembler{
+		traceService: traceService,
+		config:       config,
+		pending:      make(map[domain.TraceID]*pendingTrace),
+		completed:    make(map[domain.TraceID]time.Time),
+		now:          time.Now,
+	}, nil
+}
+
+// AddSpan buffers a span until its trace goes idle
+func (a *spanAssembler) AddSpan(ctx context.Context, span *domain.Span) error {
+	return a.AddSpanWithAck(ctx, span, nil)
+}
+
+// AddSpanWithAck buffers a span until its trace goes idle and calls ack
+// once the trace is stored or dropped. When the buffer is full, the oldest
+// trace is completed first; if that fails the span is refused, so the
+// sender retries it later.
+func (a *spanAssembler) AddSpanWithAck(ctx context.Context, span *domain.Span, ack func()) error {
+	if err := a.validateSpan(span); err != nil {
+		return fmt.Errorf("%w: invalid span: %v", domain.ErrInvalidTrace, err)
+	}
+
+	if span.Duration == 0 {
+		span.Duration = span.EndTime.Sub(span.StartTime)
+	}
+
+	a.mu.Lock()
+	pending, ok := a.pending[span.TraceID]
+	if !ok && len(a.pending) >= a.config.MaxPendingTraces {
+		evictedID, evicted := a.oldestPendingLocked()
+		delete(a.pending, evictedID)
+		a.mu.Unlock()
+
+		// Buffer is full: complete the oldest trace early rather than dropping it
+		if err := a.completeTrace(ctx, evictedID, evicted); err != nil {
+			return fmt.Errorf("failed to complete evicted trace %s: %w", evictedID, err)
+		}
+
+		a.mu.Lock()
+		pending, ok = a.pending[span.TraceID]
+	}
+
+	now := a.now()
+	if !ok {
+		pending = &pendingTrace{
+			spans:     make(map[domain.SpanID]domain.Span),
+			firstSeen: now,
+		}
+		a.pending[span.TraceID] = pending
+	}
+	pending.spans[span.ID] = *span
+	pending.lastUpdate = now
+	pending.changed = pending.retry != nil
+	if ack != nil {
+		pending.acks = append(pending.acks, ack)
+	}
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Flush completes all buffered traces regardless of their idle time
+func (a *spanAssembler) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[domain.TraceID]*pendingTrace)
+	a.mu.Unlock()
+
+	return a.completeAll(ctx, pending)
+}
+
+// Start periodically completes idle traces until the context is cancelled,
+// then flushes whatever is still buffered
+func (a *spanAssembler) Start(ctx context.Context) error {
+	interval := a.config.IdleTimeout / 2
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return a.drain()
+		case <-ticker.C:
+			if err := a.flushIdle(ctx); err != nil {
+				fmt.Printf("Failed to complete idle traces: %v\n", err)
+			}
+		}
+	}
+}
+
+// drain flushes the buffered traces on shutdown, retrying while storage
+// applies backpressure. It uses a fresh context so the final flush is not
+// cancelled as well.
+func (a *spanAssembler) drain() error {
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := a.Flush(context.Background())
+		if err == nil || attempt == finalFlushAttempts || !errors.Is(err, domain.ErrBackpressure) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// flushIdle completes the traces that received no spans within the idle timeout
+func (a *spanAssembler) flushIdle(ctx context.Context) error {
+	a.mu.Lock()
+	now := a.now()
+	idle := make(map[domain.TraceID]*pendingTrace)
+	for traceID, pending := range a.pending {
+		if now.Sub(pending.lastUpdate) >= a.config.IdleTimeout {
+			idle[traceID] = pending
+			delete(a.pending, traceID)
+		}
+	}
+
+	// Forget completed traces once late spans are no longer expected
+	for traceID, completedAt := range a.completed {
+		if now.Sub(completedAt) > a.config.CompletedTTL {
+			delete(a.completed, traceID)
+		}
+	}
+	a.mu.Unlock()
+
+	return a.completeAll(ctx, idle)
+}
+
+// completeAll completes the given traces, collecting any errors
+func (a *spanAssembler) completeAll(ctx context.Context, traces map[domain.TraceID]*pendingTrace) error {
+	var errs []error
+	for traceID, pending := range traces {
+		if err := a.completeTrace(ctx, traceID, pending); err != nil {
+			errs = append(errs, fmt.Errorf("trace %s: %w", traceID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// completeTrace stores the buffered spans of a trace and acknowledges
+// them. A trace that cannot be stored is put back into the buffer and
+// retried once it goes idle again, unless it is invalid.
+func (a *spanAssembler) completeTrace(ctx context.Context, traceID domain.TraceID, pending *pendingTrace) error {
+	err := a.storeTrace(ctx, traceID, pending)
+	if err != nil && !errors.Is(err, domain.ErrInvalidTrace) {
+		a.requeue(traceID, pending, err)
+		return err
+	}
+
+	for _, ack := range pending.acks {
+		ack()
+	}
+	return err
+}
+
+// storeTrace builds a trace from its buffered spans and processes it.
+// When the trace is already stored, for example because some of its spans
+// arrived late, the buffered spans are merged into the stored trace and
+// saved as an update, so the trace is not passed on a second time. Late
+// spans of a completed trace that was not stored were dropped by the
+// sampler and are dropped as well.
+func (a *spanAssembler) storeTrace(ctx context.Context, traceID domain.TraceID, pending *pendingTrace) error {
+	if pending.retry != nil {
+		if err := pending.retry(ctx); err != nil {
+			return fmt.Errorf("failed to store assembled trace: %w", err)
+		}
+		pending.retry = nil
+		a.markCompleted(traceID)
+		if !pending.changed {
+			return nil
+		}
+		// Spans received since are merged into the now stored trace
+	}
+
+	spans := make([]domain.Span, 0, len(pending.spans))
+	for _, span := range pending.spans {
+		spans = append(spans, span)
+	}
+
+	stored, err := a.traceService.GetTrace(ctx, traceID)
+	if err != nil && !errors.Is(err, domain.ErrTraceNotFound) {
+		return fmt.Errorf("failed to get stored trace: %w", err)
+	}
+
+	a.mu.Lock()
+	_, seenBefore := a.completed[traceID]
+	a.mu.Unlock()
+
+	switch {
+	case stored != nil:
+		// Newly received spans win over stored ones with the same ID
+		trace := domain.BuildTrace(traceID, sortSpans(domain.MergeSpans(stored.Spans, spans)))
+		if err := a.traceService.UpdateTrace(ctx, trace); err != nil {
+			return fmt.Errorf("failed to update assembled trace: %w", err)
+		}
+	case seenBefore:
+		return nil
+	default:
+		trace := domain.BuildTrace(traceID, sortSpans(spans))
+		if err := a.traceService.ProcessTrace(ctx, trace); err != nil {
+			return fmt.Errorf("failed to process assembled trace: %w", err)
+		}
+	}
+
+	a.markCompleted(traceID)
+	return nil
+}
+
+// requeue puts a trace that could not be stored back into the buffer,
+// merging it with spans received in the meantime. When the write queue
+// refused it, only its save is retried.
+func (a *spanAssembler) requeue(traceID domain.TraceID, failed *pendingTrace, err error) {
+	var backpressure *domain.BackpressureError
+	if errors.As(err, &backpressure) {
+		failed.retry = backpressure.Retry
+		failed.changed = false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	failed.lastUpdate = a.now()
+	current, ok := a.pending[traceID]
+	if !ok {
+		a.pending[traceID] = failed
+		return
+	}
+
+	// Spans received in the meantime win over the failed ones with the same ID
+	for spanID, span := range failed.spans {
+		if _, ok := current.spans[spanID]; !ok {
+			current.spans[spanID] = span
+		}
+	}
+	if failed.firstSeen.Before(current.firstSeen) {
+		current.firstSeen = failed.firstSeen
+	}
+	current.acks = append(failed.acks, current.acks...)
+	current.retry = failed.retry
+	current.changed = failed.retry != nil
+}
+
+// markCompleted remembers that a trace was completed, so late spans can
+// tell whether it was stored
+func (a *spanAssembler) markCompleted(traceID domain.TraceID) {
+	a.mu.Lock()
+	a.completed[traceID] = a.now()
+	a.mu.Unlock()
+}
+
+// sortSpans orders spans by start time, then by ID
+func sortSpans(spans []domain.Span) []domain.Span {
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].StartTime.Equal(spans[j].StartTime) {
+			return spans[i].ID < spans[j].ID
+		}
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+	return spans
+}
+
+// oldestPendingLocked returns the buffered trace that was first seen earliest.
+// The caller must hold a.mu.
+func (a *spanAssembler) oldestPendingLocked() (domain.TraceID, *pendingTrace) {
+	var oldestID domain.TraceID
+	var oldest *pendingTrace
+	for traceID, pending := range a.pending {
+		if oldest == nil || pending.firstSeen.Before(oldest.firstSeen) {
+			oldestID = traceID
+			oldest = pending
+		}
+	}
+	return oldestID, oldest
+}
+
+// validateSpan validates an incoming span
+func (a *spanAssembler) validateSpan(span *domain.Span) error {
+	if span == nil {
+		return fmt.Errorf("span cannot be nil")
+	}
+	if span.ID == "" {
+		return fmt.Errorf("span ID is required")
+	}
+	if span.TraceID == "" {
+		return fmt.Errorf("trace ID is required")
+	}
+	if span.Service == "" {
+		return fmt.Errorf("service name is required")
+	}
+	if span.Operation == "" {
+		return fmt.Errorf("operation name is required")
+	}
+	if span.StartTime.IsZero() {
+		return fmt.Errorf("start time is required")
+	}
+	if span.EndTime.IsZero() {
+		return fmt.Errorf("end time is required")
+	}
+	if span.StartTime.After(span.EndTime) {
+		return fmt.Errorf("start time cannot be after end time")
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAssembler creates an assembler backed by mocks with a controllable clock
+func newTestAssembler(t *testing.T, config SpanAssemblerConfig, opts ...TraceServiceOption) (*spanAssembler, *MockTraceRepository, *MockKafkaProducer, *time.Time) {
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+	mockPrometheus.On("RecordSamplingDecision", mock.Anything, mock.Anything).Maybe()
+	mockPrometheus.On("RecordSampledTrace", mock.Anything).Maybe()
+	mockKafka.On("PublishTraceEvent", mock.Anything, mock.Anything).Return(nil)
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, opts...)
+	assembler, err := NewSpanAssembler(service, config)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sa := assembler.(*spanAssembler)
+	sa.now = func() time.Time { return now }
+
+	return sa, mockRepo, mockKafka, &now
+}
+
+// expectNotStored makes the repository report that a trace is not stored yet
+func expectNotStored(mockRepo *MockTraceRepository, traceID domain.TraceID) *mock.Call {
+	return mockRepo.On("FindByID", mock.Anything, traceID).Return((*domain.Trace)(nil), domain.ErrTraceNotFound)
+}
+
+func testSpan(traceID domain.TraceID, id domain.SpanID, parent *domain.SpanID, service domain.ServiceName, start time.Time, duration time.Duration) *domain.Span {
+	return &domain.Span{
+		ID:        id,
+		TraceID:   traceID,
+		ParentID:  parent,
+		Service:   service,
+		Operation: domain.OperationName("op-" + string(id)),
+		StartTime: start,
+		EndTime:   start.Add(duration),
+		Status:    domain.SpanStatusOK,
+	}
+}
+
+func TestSpanAssembler_CompletesIdleTraces(t *testing.T) {
+	assembler, mockRepo, _, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      5 * time.Second,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+	start := *now
+
+	var saved *domain.Trace
+	expectNotStored(mockRepo, "trace1")
+	mockRepo.On("Save", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*domain.Trace)
+	}).Return(nil)
+
+	rootID := domain.SpanID("root")
+	child := testSpan("trace1", "child", &rootID, "payments", start.Add(10*time.Millisecond), 50*time.Millisecond)
+	child.Status = domain.SpanStatusError
+
+	// Child arrives before its parent
+	require.NoError(t, assembler.AddSpan(ctx, child))
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "root", nil, "checkout", start, 100*time.Millisecond)))
+
+	// Not idle yet
+	*now = now.Add(4 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+
+	require.NotNil(t, saved)
+	assert.Equal(t, domain.TraceID("trace1"), saved.ID)
+	assert.Equal(t, domain.ServiceName("checkout"), saved.Service)
+	assert.Equal(t, domain.OperationName("op-root"), saved.Operation)
+	assert.Equal(t, start, saved.StartTime)
+	assert.Equal(t, start.Add(100*time.Millisecond), saved.EndTime)
+	assert.Equal(t, domain.TraceStatusError, saved.Status)
+	assert.Len(t, saved.Spans, 2)
+}
+
+func TestSpanAssembler_MergesLateSpans(t *testing.T) {
+	assembler, mockRepo, mockKafka, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Second,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+	start := *now
+
+	var saved []*domain.Trace
+	expectNotStored(mockRepo, "trace1").Once()
+	mockRepo.On("Save", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(1).(*domain.Trace))
+	}).Return(nil)
+
+	rootID := domain.SpanID("root")
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "root", nil, "checkout", start, 100*time.Millisecond)))
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+	require.Len(t, saved, 1)
+
+	mockRepo.On("FindByID", ctx, domain.TraceID("trace1")).Return(saved[0], nil)
+
+	// A late span for the already completed trace
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "late", &rootID, "inventory", start.Add(20*time.Millisecond), 10*time.Millisecond)))
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+
+	require.Len(t, saved, 2)
+	merged := saved[1]
+	assert.Equal(t, domain.ServiceName("checkout"), merged.Service)
+	assert.Equal(t, start, merged.StartTime)
+	assert.Len(t, merged.Spans, 2)
+
+	// The update is only saved, the trace is passed on once
+	mockKafka.AssertNumberOfCalls(t, "PublishTraceEvent", 1)
+}
+
+func TestSpanAssembler_MergesSpansOfStoredTraces(t *testing.T) {
+	// Arrange: the trace was stored by another receiver and is no longer
+	// remembered as completed
+	assembler, mockRepo, mockKafka, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Second,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+	start := *now
+
+	rootID := domain.SpanID("root")
+	stored := domain.BuildTrace("trace1", []domain.Span{
+		*testSpan("trace1", "root", nil, "checkout", start, 100*time.Millisecond),
+	})
+	mockRepo.On("FindByID", ctx, domain.TraceID("trace1")).Return(stored, nil)
+
+	var saved *domain.Trace
+	mockRepo.On("Save", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*domain.Trace)
+	}).Return(nil)
+
+	// Act
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "late", &rootID, "inventory", start.Add(20*time.Millisecond), 10*time.Millisecond)))
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+
+	// Assert: the root span still heads the trace
+	require.NotNil(t, saved)
+	assert.Equal(t, domain.ServiceName("checkout"), saved.Service)
+	assert.Equal(t, domain.OperationName("op-root"), saved.Operation)
+	assert.Len(t, saved.Spans, 2)
+	mockKafka.AssertNotCalled(t, "PublishTraceEvent", mock.Anything, mock.Anything)
+}
+
+func TestSpanAssembler_DropsLateSpansOfDroppedTraces(t *testing.T) {
+	// Arrange: the sampler only keeps failed traces
+	sampler, err := NewTailSampler([]SamplingPolicyConfig{{Type: PolicyTypeStatusCode}}, nil)
+	require.NoError(t, err)
+	assembler, mockRepo, _, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Second,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	}, WithTailSampler(sampler))
+	ctx := context.Background()
+	start := *now
+
+	expectNotStored(mockRepo, "trace1")
+
+	rootID := domain.SpanID("root")
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "root", nil, "checkout", start, 100*time.Millisecond)))
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+
+	// Act: a late failed span must not store the rest of the dropped trace
+	late := testSpan("trace1", "late", &rootID, "inventory", start.Add(20*time.Millisecond), 10*time.Millisecond)
+	late.Status = domain.SpanStatusError
+	require.NoError(t, assembler.AddSpan(ctx, late))
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+
+	// Assert
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	assert.Empty(t, assembler.pending)
+}
+
+func TestSpanAssembler_EvictsOldestWhenFull(t *testing.T) {
+	assembler, mockRepo, _, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Minute,
+		MaxPendingTraces: 1,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+
+	expectNotStored(mockRepo, "trace1")
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(trace *domain.Trace) bool {
+		return trace.ID == "trace1"
+	})).Return(nil)
+
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "a", nil, "checkout", *now, time.Millisecond)))
+	*now = now.Add(time.Second)
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace2", "b", nil, "checkout", *now, time.Millisecond)))
+
+	mockRepo.AssertNumberOfCalls(t, "Save", 1)
+	assert.Len(t, assembler.pending, 1)
+	assert.Contains(t, assembler.pending, domain.TraceID("trace2"))
+}
+
+func TestSpanAssembler_Flush(t *testing.T) {
+	assembler, mockRepo, _, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Minute,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+
+	expectNotStored(mockRepo, "trace1")
+	mockRepo.On("Save", ctx, mock.Anything).Return(errors.New("database unavailable"))
+
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "a", nil, "checkout", *now, time.Millisecond)))
+
+	err := assembler.Flush(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "database unavailable")
+
+	// The trace is kept to be retried
+	assert.Contains(t, assembler.pending, domain.TraceID("trace1"))
+}
+
+func TestSpanAssembler_RetriesTracesRefusedWithBackpressure(t *testing.T) {
+	// Arrange: storage refuses the first save
+	assembler, mockRepo, mockKafka, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Second,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+	start := *now
+
+	expectNotStored(mockRepo, "trace1").Once()
+	mockRepo.On("Save", ctx, mock.Anything).Return(domain.ErrBackpressure).Once()
+	var saved []*domain.Trace
+	stored := &domain.Trace{}
+	mockRepo.On("Save", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		trace := args.Get(1).(*domain.Trace)
+		saved = append(saved, trace)
+		*stored = *trace
+	}).Return(nil)
+	mockRepo.On("FindByID", ctx, domain.TraceID("trace1")).Return(stored, nil)
+
+	acks := 0
+	ack := func() { acks++ }
+	rootID := domain.SpanID("root")
+	require.NoError(t, assembler.AddSpanWithAck(ctx, testSpan("trace1", "root", nil, "checkout", start, 100*time.Millisecond), ack))
+
+	// Act: the refused trace is kept, and a span arrives before the retry
+	*now = now.Add(2 * time.Second)
+	err := assembler.flushIdle(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, domain.ErrBackpressure)
+	assert.Zero(t, acks)
+
+	require.NoError(t, assembler.AddSpanWithAck(ctx, testSpan("trace1", "child", &rootID, "inventory", start.Add(10*time.Millisecond), 10*time.Millisecond), ack))
+	*now = now.Add(2 * time.Second)
+	require.NoError(t, assembler.flushIdle(ctx))
+
+	// Assert: the refused save is retried, then the new span is merged in
+	require.Len(t, saved, 2)
+	assert.Len(t, saved[0].Spans, 1)
+	assert.Len(t, saved[1].Spans, 2)
+	assert.Equal(t, 2, acks)
+	assert.Empty(t, assembler.pending)
+	mockKafka.AssertNumberOfCalls(t, "PublishTraceEvent", 1)
+}
+
+func TestSpanAssembler_RefusesSpansWhenEvictionFails(t *testing.T) {
+	// Arrange
+	assembler, mockRepo, _, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Minute,
+		MaxPendingTraces: 1,
+		CompletedTTL:     time.Minute,
+	})
+	ctx := context.Background()
+
+	expectNotStored(mockRepo, "trace1")
+	mockRepo.On("Save", ctx, mock.Anything).Return(domain.ErrBackpressure)
+
+	require.NoError(t, assembler.AddSpan(ctx, testSpan("trace1", "a", nil, "checkout", *now, time.Millisecond)))
+
+	// Act
+	err := assembler.AddSpan(ctx, testSpan("trace2", "b", nil, "checkout", *now, time.Millisecond))
+
+	// Assert: the evicted trace is kept and the new span is refused
+	assert.ErrorIs(t, err, domain.ErrBackpressure)
+	assert.Len(t, assembler.pending, 1)
+	assert.Contains(t, assembler.pending, domain.TraceID("trace1"))
+}
+
+func TestSpanAssembler_AddSpan_Invalid(t *testing.T) {
+	assembler, _, _, now := newTestAssembler(t, SpanAssemblerConfig{
+		IdleTimeout:      time.Minute,
+		MaxPendingTraces: 10,
+		CompletedTTL:     time.Minute,
+	})
+
+	span := testSpan("", "a", nil, "checkout", *now, time.Millisecond)
+	err := assembler.AddSpan(context.Background(), span)
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, domain.ErrInvalidTrace)
+	assert.Contains(t, err.Error(), "trace ID is required")
+}
@@ -0,0 +1,132 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// TagCatalogConfig bounds the tags kept by the tag catalog
+type TagCatalogConfig struct {
+	// MaxTags bounds the tag names; tags first seen beyond it are ignored
+	MaxTags int
+	// MaxValuesPerTag bounds the values kept per tag, so that high
+	// cardinality tags such as request IDs do not grow without limit
+	MaxValuesPerTag int
+}
+
+// tagCatalog implements the TagCatalog interface in memory
+type tagCatalog struct {
+	config TagCatalogConfig
+
+	mu   sync.RWMutex
+	tags map[string]map[string]struct{}
+}
+
+// NewTagCatalog creates an empty tag catalog
+func NewTagCatalog(config TagCatalogConfig) (domain.TagCatalog, error) {
+	if config.MaxTags <= 0 {
+		return nil, fmt.Errorf("tag catalog max tags must be positive")
+	}
+	if config.MaxValuesPerTag <= 0 {
+		return nil, fmt.Errorf("tag catalog max values per tag must be positive")
+	}
+
+	return &tagCatalog{
+		config: config,
+		tags:   make(map[string]map[string]struct{}),
+	}, nil
+}
+
+// Record adds the tags of a trace and its spans, and the services of its
+// spans as ServiceNameTag
+func (c *tagCatalog) Record(trace *domain.Trace) {
+	if trace == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(domain.ServiceNameTag, string(trace.Service))
+	for key, value := range trace.Tags {
+		c.add(key, value)
+	}
+	for _, span := range trace.Spans {
+		c.add(domain.ServiceNameTag, string(span.Service))
+		for key, value := range span.Tags {
+			c.add(key, value)
+		}
+	}
+}
+
+// add records a tag value within the configured bounds
+func (c *tagCatalog) add(name, value string) {
+	if name == "" || value == "" {
+		return
+	}
+
+	values, ok := c.tags[name]
+	if !ok {
+		if len(c.tags) >= c.config.MaxTags {
+			return
+		}
+		values = make(map[string]struct{})
+		c.tags[name] = values
+	}
+	if _, ok := values[value]; ok || len(values) >= c.config.MaxValuesPerTag {
+		return
+	}
+	values[value] = struct{}{}
+}
+
+// TagNames returns the known tag names in alphabetical order
+func (c *tagCatalog) TagNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.tags))
+	for name := range c.tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TagValues returns the known values of a tag in alphabetical order
+func (c *tagCatalog) TagValues(name string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	values := make([]string, 0, len(c.tags[name]))
+	for value := range c.tags[name] {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// LoadTagCatalog records the most recent stored traces, up to limit, in the
+// catalog so that it is not empty after a restart. Search results may omit
+// spans; the span tags of such traces are learned as new traces are ingested.
+func LoadTagCatalog(ctx context.Context, repo domain.TraceRepository, catalog domain.TagCatalog, limit int) error {
+	for loaded := 0; loaded < limit; loaded += queryScanPageSize {
+		pageSize := min(queryScanPageSize, limit-loaded)
+		page, err := repo.Search(ctx, &domain.SearchCriteria{Limit: pageSize, Offset: loaded})
+		if err != nil {
+			return fmt.Errorf("failed to scan traces: %w", err)
+		}
+
+		for _, trace := range page {
+			catalog.Record(trace)
+		}
+
+		if len(page) < pageSize {
+			break
+		}
+	}
+	return nil
+}
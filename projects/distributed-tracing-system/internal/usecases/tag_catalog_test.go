@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newCatalogTestTrace(id string, tags map[string]string) *domain.Trace {
+	trace := newQueueTestTrace(id)
+	trace.Tags = map[string]string{"env": "prod"}
+	trace.Spans = []domain.Span{
+		{ID: "root", Service: "api", Operation: "GET /users", Tags: tags},
+		{ID: "db", Service: "postgres", Operation: "SELECT", Tags: map[string]string{"db.system": "postgresql"}},
+	}
+	return trace
+}
+
+func TestNewTagCatalog_InvalidConfig(t *testing.T) {
+	_, err := NewTagCatalog(TagCatalogConfig{MaxValuesPerTag: 1})
+	assert.Error(t, err)
+	_, err = NewTagCatalog(TagCatalogConfig{MaxTags: 1})
+	assert.Error(t, err)
+}
+
+func TestTagCatalog_Record(t *testing.T) {
+	// Arrange
+	catalog, err := NewTagCatalog(TagCatalogConfig{MaxTags: 10, MaxValuesPerTag: 2})
+	require.NoError(t, err)
+
+	// Act
+	catalog.Record(newCatalogTestTrace("trace-1", map[string]string{"http.method": "GET", "empty": ""}))
+	catalog.Record(newCatalogTestTrace("trace-2", map[string]string{"http.method": "POST"}))
+	catalog.Record(newCatalogTestTrace("trace-3", map[string]string{"http.method": "DELETE"}))
+	catalog.Record(nil)
+
+	// Assert: empty values are skipped and values beyond the bound ignored
+	assert.Equal(t, []string{"db.system", "env", "http.method", "service.name"}, catalog.TagNames())
+	assert.Equal(t, []string{"GET", "POST"}, catalog.TagValues("http.method"))
+	assert.Equal(t, []string{"api", "postgres"}, catalog.TagValues(domain.ServiceNameTag))
+	assert.Empty(t, catalog.TagValues("unknown"))
+}
+
+func TestTagCatalog_MaxTags(t *testing.T) {
+	catalog, err := NewTagCatalog(TagCatalogConfig{MaxTags: 2, MaxValuesPerTag: 10})
+	require.NoError(t, err)
+
+	catalog.Record(newCatalogTestTrace("trace-1", map[string]string{"http.method": "GET"}))
+
+	assert.Len(t, catalog.TagNames(), 2)
+	assert.Equal(t, []string{"api", "postgres"}, catalog.TagValues(domain.ServiceNameTag))
+}
+
+func TestLoadTagCatalog(t *testing.T) {
+	// Arrange: the repository holds two traces, scanned newest first
+	repo := new(MockTraceRepository)
+	repo.On("Search", mock.Anything, &domain.SearchCriteria{Limit: 5}).Return([]*domain.Trace{
+		newCatalogTestTrace("trace-1", map[string]string{"http.method": "GET"}),
+		newCatalogTestTrace("trace-2", map[string]string{"http.method": "POST"}),
+	}, nil)
+	catalog, err := NewTagCatalog(TagCatalogConfig{MaxTags: 10, MaxValuesPerTag: 10})
+	require.NoError(t, err)
+
+	// Act
+	err = LoadTagCatalog(context.Background(), repo, catalog, 5)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET", "POST"}, catalog.TagValues("http.method"))
+	repo.AssertNumberOfCalls(t, "Search", 1)
+}
+
+func TestTraceService_ProcessTrace_RecordsTagsOfStoredTraces(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+	mockKafka.On("PublishTraceEvent", mock.Anything, mock.Anything).Return(nil)
+	catalog, err := NewTagCatalog(TagCatalogConfig{MaxTags: 10, MaxValuesPerTag: 10})
+	require.NoError(t, err)
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithTagCatalog(catalog))
+
+	stored := newCatalogTestTrace("trace-1", map[string]string{"http.method": "GET"})
+	failed := newCatalogTestTrace("trace-2", map[string]string{"http.method": "POST"})
+	mockRepo.On("Save", mock.Anything, stored).Return(nil)
+	mockRepo.On("Save", mock.Anything, failed).Return(errors.New("database unavailable"))
+
+	// Act
+	require.NoError(t, service.ProcessTrace(context.Background(), stored))
+	require.Error(t, service.ProcessTrace(context.Background(), failed))
+
+	// Assert: only the stored trace is cataloged
+	assert.Equal(t, []string{"GET"}, catalog.TagValues("http.method"))
+}
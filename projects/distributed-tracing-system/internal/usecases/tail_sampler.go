@@ -0,0 +1,428 @@
+package usecases
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported sampling policy types
+const (
+	PolicyTypeAlwaysSample  = "always_sample"
+	PolicyTypeStatusCode    = "status_code"
+	PolicyTypeLatency       = "latency"
+	PolicyTypeProbabilistic = "probabilistic"
+	PolicyTypeRateLimiting  = "rate_limiting"
+	PolicyTypeTag           = "tag"
+	PolicyTypeAnd           = "and"
+)
+
+// SamplingPolicyFile is the on-disk format of a tail sampling policy file
+type SamplingPolicyFile struct {
+	Policies []SamplingPolicyConfig `yaml:"policies"`
+}
+
+// SamplingPolicyConfig configures a single sampling policy
+type SamplingPolicyConfig struct {
+	Name          string                     `yaml:"name"`
+	Type          string                     `yaml:"type"`
+	Latency       *LatencyPolicyConfig       `yaml:"latency,omitempty"`
+	Probabilistic *ProbabilisticPolicyConfig `yaml:"probabilistic,omitempty"`
+	RateLimiting  *RateLimitingPolicyConfig  `yaml:"rate_limiting,omitempty"`
+	Tag           *TagPolicyConfig           `yaml:"tag,omitempty"`
+	And           []SamplingPolicyConfig     `yaml:"and,omitempty"`
+}
+
+// LatencyPolicyConfig samples traces slower than a threshold
+type LatencyPolicyConfig struct {
+	// Threshold applies to operations without a specific entry; zero disables it
+	Threshold time.Duration `yaml:"threshold"`
+	// Operations maps operation names to their own threshold
+	Operations map[string]time.Duration `yaml:"operations,omitempty"`
+}
+
+// ProbabilisticPolicyConfig samples a fixed share of traces by trace ID
+type ProbabilisticPolicyConfig struct {
+	SamplingPercentage float64 `yaml:"sampling_percentage"`
+}
+
+// RateLimitingPolicyConfig samples up to a number of traces per second per service
+type RateLimitingPolicyConfig struct {
+	TracesPerSecond float64 `yaml:"traces_per_second"`
+}
+
+// TagPolicyConfig samples traces carrying a tag, on the trace or on any span
+type TagPolicyConfig struct {
+	Key    string   `yaml:"key"`
+	Values []string `yaml:"values,omitempty"`
+	Regex  string   `yaml:"regex,omitempty"`
+}
+
+// LoadSamplingPolicyFile reads tail sampling policies from a YAML (or JSON) file
+func LoadSamplingPolicyFile(path string) ([]SamplingPolicyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sampling policy file: %w", err)
+	}
+
+	var file SamplingPolicyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse sampling policy file: %w", err)
+	}
+
+	return file.Policies, nil
+}
+
+// tailSampler implements the TailSampler interface. A trace is kept when any
+// top-level policy decides to sample it; with no policies every trace is kept.
+type tailSampler struct {
+	policies []domain.SamplingPolicy
+	metrics  domain.PrometheusExporter
+}
+
+// NewTailSampler creates a tail sampler from policy configurations
+func NewTailSampler(configs []SamplingPolicyConfig, metrics domain.PrometheusExporter) (domain.TailSampler, error) {
+	policies := make([]domain.SamplingPolicy, 0, len(configs))
+	names := make(map[string]struct{}, len(configs))
+
+	for i, config := range configs {
+		policy, err := newSamplingPolicy(config)
+		if err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+		if _, exists := names[policy.Name()]; exists {
+			return nil, fmt.Errorf("policy %d: duplicate policy name %q", i, policy.Name())
+		}
+		names[policy.Name()] = struct{}{}
+		policies = append(policies, policy)
+	}
+
+	return &tailSampler{
+		policies: policies,
+		metrics:  metrics,
+	}, nil
+}
+
+// ShouldSample evaluates the policies that spend no budget first, so each
+// one's decision counter stays accurate. Policies that spend a budget, like
+// rate limiting, are then evaluated in order only until the trace is kept:
+// a trace kept for its errors or latency does not use up the budget of the
+// traffic the rate limit is meant to let through. Skipped policies record
+// no decision.
+func (ts *tailSampler) ShouldSample(trace *domain.Trace) bool {
+	if len(ts.policies) == 0 {
+		return true
+	}
+
+	sampled := false
+	for _, budgeted := range []bool{false, true} {
+		for _, policy := range ts.policies {
+			if spendsBudget(policy) != budgeted || (budgeted && sampled) {
+				continue
+			}
+
+			decision := policy.Evaluate(trace)
+			if ts.metrics != nil {
+				ts.metrics.RecordSamplingDecision(policy.Name(), decision)
+			}
+			if decision == domain.SamplingDecisionSample {
+				sampled = true
+			}
+		}
+	}
+
+	if ts.metrics != nil {
+		if sampled {
+			ts.metrics.RecordSampledTrace(domain.SamplingDecisionSample)
+		} else {
+			ts.metrics.RecordSampledTrace(domain.SamplingDecisionNotSample)
+		}
+	}
+
+	return sampled
+}
+
+// budgetedPolicy is implemented by policies whose evaluation may use up a
+// sampling budget
+type budgetedPolicy interface {
+	spendsBudget() bool
+}
+
+// spendsBudget reports whether evaluating a policy may use up a budget
+func spendsBudget(policy domain.SamplingPolicy) bool {
+	budgeted, ok := policy.(budgetedPolicy)
+	return ok && budgeted.spendsBudget()
+}
+
+// newSamplingPolicy builds a policy from its configuration
+func newSamplingPolicy(config SamplingPolicyConfig) (domain.SamplingPolicy, error) {
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+
+	switch config.Type {
+	case PolicyTypeAlwaysSample:
+		return &alwaysSamplePolicy{name: name}, nil
+
+	case PolicyTypeStatusCode:
+		return &statusCodePolicy{name: name}, nil
+
+	case PolicyTypeLatency:
+		if config.Latency == nil {
+			return nil, fmt.Errorf("latency policy %q requires a latency section", name)
+		}
+		if config.Latency.Threshold <= 0 && len(config.Latency.Operations) == 0 {
+			return nil, fmt.Errorf("latency policy %q requires a threshold or operation thresholds", name)
+		}
+		return &latencyPolicy{name: name, config: *config.Latency}, nil
+
+	case PolicyTypeProbabilistic:
+		if config.Probabilistic == nil {
+			return nil, fmt.Errorf("probabilistic policy %q requires a probabilistic section", name)
+		}
+		percentage := config.Probabilistic.SamplingPercentage
+		if percentage < 0 || percentage > 100 {
+			return nil, fmt.Errorf("probabilistic policy %q: sampling percentage must be between 0 and 100", name)
+		}
+		return &probabilisticPolicy{name: name, threshold: uint64(percentage / 100 * float64(^uint64(0)>>1))}, nil
+
+	case PolicyTypeRateLimiting:
+		if config.RateLimiting == nil || config.RateLimiting.TracesPerSecond <= 0 {
+			return nil, fmt.Errorf("rate limiting policy %q requires a positive traces_per_second", name)
+		}
+		return newRateLimitingPolicy(name, config.RateLimiting.TracesPerSecond), nil
+
+	case PolicyTypeTag:
+		if config.Tag == nil || config.Tag.Key == "" {
+			return nil, fmt.Errorf("tag policy %q requires a tag key", name)
+		}
+		policy := &tagPolicy{name: name, key: config.Tag.Key, values: make(map[string]struct{})}
+		for _, value := range config.Tag.Values {
+			policy.values[value] = struct{}{}
+		}
+		if config.Tag.Regex != "" {
+			re, err := regexp.Compile(config.Tag.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("tag policy %q: invalid regex: %w", name, err)
+			}
+			policy.regex = re
+		}
+		return policy, nil
+
+	case PolicyTypeAnd:
+		if len(config.And) == 0 {
+			return nil, fmt.Errorf("and policy %q requires sub-policies", name)
+		}
+		policy := &andPolicy{name: name}
+		for i, subConfig := range config.And {
+			sub, err := newSamplingPolicy(subConfig)
+			if err != nil {
+				return nil, fmt.Errorf("and policy %q, sub-policy %d: %w", name, i, err)
+			}
+			policy.policies = append(policy.policies, sub)
+		}
+		return policy, nil
+
+	default:
+		return nil, fmt.Errorf("unknown policy type %q", config.Type)
+	}
+}
+
+// alwaysSamplePolicy samples every trace
+type alwaysSamplePolicy struct {
+	name string
+}
+
+func (p *alwaysSamplePolicy) Name() string { return p.name }
+
+func (p *alwaysSamplePolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	return domain.SamplingDecisionSample
+}
+
+// statusCodePolicy samples every trace that ended in error
+type statusCodePolicy struct {
+	name string
+}
+
+func (p *statusCodePolicy) Name() string { return p.name }
+
+func (p *statusCodePolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	if trace.Status == domain.TraceStatusError {
+		return domain.SamplingDecisionSample
+	}
+	return domain.SamplingDecisionNotSample
+}
+
+// latencyPolicy samples traces slower than their operation's threshold
+type latencyPolicy struct {
+	name   string
+	config LatencyPolicyConfig
+}
+
+func (p *latencyPolicy) Name() string { return p.name }
+
+func (p *latencyPolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	threshold, ok := p.config.Operations[string(trace.Operation)]
+	if !ok {
+		threshold = p.config.Threshold
+	}
+	if threshold > 0 && trace.Duration >= threshold {
+		return domain.SamplingDecisionSample
+	}
+	return domain.SamplingDecisionNotSample
+}
+
+// probabilisticPolicy samples a share of traces using a hash of the trace ID,
+// so every instance makes the same decision for a given trace
+type probabilisticPolicy struct {
+	name      string
+	threshold uint64
+}
+
+func (p *probabilisticPolicy) Name() string { return p.name }
+
+func (p *probabilisticPolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(trace.ID))
+
+	// FNV alone clusters similar IDs in the high bits; finish with a 64-bit mixer
+	hash := hasher.Sum64()
+	hash ^= hash >> 33
+	hash *= 0xff51afd7ed558ccd
+	hash ^= hash >> 33
+	hash *= 0xc4ceb9fe1a85ec53
+	hash ^= hash >> 33
+
+	if hash>>1 < p.threshold {
+		return domain.SamplingDecisionSample
+	}
+	return domain.SamplingDecisionNotSample
+}
+
+// rateLimitingPolicy samples up to a number of traces per second per service
+// using a token bucket per service. Buckets hold at least one token, so
+// rates below one trace per second still sample.
+type rateLimitingPolicy struct {
+	name  string
+	rate  float64
+	now   func() time.Time
+	mu    sync.Mutex
+	state map[domain.ServiceName]*tokenBucket
+}
+
+// tokenBucket tracks the sampling budget of a single service
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newRateLimitingPolicy(name string, rate float64) *rateLimitingPolicy {
+	return &rateLimitingPolicy{
+		name:  name,
+		rate:  rate,
+		now:   time.Now,
+		state: make(map[domain.ServiceName]*tokenBucket),
+	}
+}
+
+func (p *rateLimitingPolicy) Name() string { return p.name }
+
+func (p *rateLimitingPolicy) spendsBudget() bool { return true }
+
+func (p *rateLimitingPolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	capacity := max(p.rate, 1)
+	now := p.now()
+	bucket, ok := p.state[trace.Service]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, lastRefill: now}
+		p.state[trace.Service] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.lastRefill).Seconds() * p.rate
+	if bucket.tokens > capacity {
+		bucket.tokens = capacity
+	}
+	bucket.lastRefill = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return domain.SamplingDecisionSample
+	}
+	return domain.SamplingDecisionNotSample
+}
+
+// tagPolicy samples traces where the trace or any span carries a matching tag
+type tagPolicy struct {
+	name   string
+	key    string
+	values map[string]struct{}
+	regex  *regexp.Regexp
+}
+
+func (p *tagPolicy) Name() string { return p.name }
+
+func (p *tagPolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	if p.matches(trace.Tags) {
+		return domain.SamplingDecisionSample
+	}
+	for _, span := range trace.Spans {
+		if p.matches(span.Tags) {
+			return domain.SamplingDecisionSample
+		}
+	}
+	return domain.SamplingDecisionNotSample
+}
+
+// matches reports whether the tags contain the key with an accepted value.
+// Without values or regex, the presence of the key is enough.
+func (p *tagPolicy) matches(tags map[string]string) bool {
+	value, ok := tags[p.key]
+	if !ok {
+		return false
+	}
+	if len(p.values) == 0 && p.regex == nil {
+		return true
+	}
+	if _, ok := p.values[value]; ok {
+		return true
+	}
+	return p.regex != nil && p.regex.MatchString(value)
+}
+
+// andPolicy samples only when all of its sub-policies sample. Sub-policies
+// are evaluated in order and short-circuit, so stateful policies such as
+// rate limiting should be listed last.
+type andPolicy struct {
+	name     string
+	policies []domain.SamplingPolicy
+}
+
+func (p *andPolicy) Name() string { return p.name }
+
+func (p *andPolicy) spendsBudget() bool {
+	for _, policy := range p.policies {
+		if spendsBudget(policy) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *andPolicy) Evaluate(trace *domain.Trace) domain.SamplingDecision {
+	for _, policy := range p.policies {
+		if policy.Evaluate(trace) != domain.SamplingDecisionSample {
+			return domain.SamplingDecisionNotSample
+		}
+	}
+	return domain.SamplingDecisionSample
+}
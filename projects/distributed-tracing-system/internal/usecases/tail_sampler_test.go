@@ -0,0 +1,314 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func sampledTrace(id string, modify func(*domain.Trace)) *domain.Trace {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := &domain.Trace{
+		ID:        domain.TraceID(id),
+		Service:   "checkout",
+		Operation: "POST /checkout",
+		StartTime: start,
+		EndTime:   start.Add(100 * time.Millisecond),
+		Duration:  100 * time.Millisecond,
+		Status:    domain.TraceStatusSuccess,
+		Spans: []domain.Span{
+			{ID: "span1", TraceID: domain.TraceID(id), Service: "checkout", Operation: "POST /checkout"},
+		},
+	}
+	if modify != nil {
+		modify(trace)
+	}
+	return trace
+}
+
+func TestTailSampler_Policies(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   SamplingPolicyConfig
+		trace    *domain.Trace
+		expected domain.SamplingDecision
+	}{
+		{
+			name:     "status code samples error traces",
+			config:   SamplingPolicyConfig{Type: PolicyTypeStatusCode},
+			trace:    sampledTrace("t1", func(tr *domain.Trace) { tr.Status = domain.TraceStatusError }),
+			expected: domain.SamplingDecisionSample,
+		},
+		{
+			name:     "status code drops successful traces",
+			config:   SamplingPolicyConfig{Type: PolicyTypeStatusCode},
+			trace:    sampledTrace("t1", nil),
+			expected: domain.SamplingDecisionNotSample,
+		},
+		{
+			name: "latency uses the operation threshold",
+			config: SamplingPolicyConfig{Type: PolicyTypeLatency, Latency: &LatencyPolicyConfig{
+				Threshold:  time.Second,
+				Operations: map[string]time.Duration{"POST /checkout": 50 * time.Millisecond},
+			}},
+			trace:    sampledTrace("t1", nil),
+			expected: domain.SamplingDecisionSample,
+		},
+		{
+			name: "latency falls back to the default threshold",
+			config: SamplingPolicyConfig{Type: PolicyTypeLatency, Latency: &LatencyPolicyConfig{
+				Threshold: time.Second,
+			}},
+			trace:    sampledTrace("t1", nil),
+			expected: domain.SamplingDecisionNotSample,
+		},
+		{
+			name: "tag matches span tags by value",
+			config: SamplingPolicyConfig{Type: PolicyTypeTag, Tag: &TagPolicyConfig{
+				Key: "customer.tier", Values: []string{"gold"},
+			}},
+			trace: sampledTrace("t1", func(tr *domain.Trace) {
+				tr.Spans[0].Tags = map[string]string{"customer.tier": "gold"}
+			}),
+			expected: domain.SamplingDecisionSample,
+		},
+		{
+			name: "tag matches trace tags by regex",
+			config: SamplingPolicyConfig{Type: PolicyTypeTag, Tag: &TagPolicyConfig{
+				Key: "http.url", Regex: "^/admin/",
+			}},
+			trace: sampledTrace("t1", func(tr *domain.Trace) {
+				tr.Tags = map[string]string{"http.url": "/admin/users"}
+			}),
+			expected: domain.SamplingDecisionSample,
+		},
+		{
+			name: "tag without a match",
+			config: SamplingPolicyConfig{Type: PolicyTypeTag, Tag: &TagPolicyConfig{
+				Key: "customer.tier", Values: []string{"gold"},
+			}},
+			trace:    sampledTrace("t1", func(tr *domain.Trace) { tr.Tags = map[string]string{"customer.tier": "free"} }),
+			expected: domain.SamplingDecisionNotSample,
+		},
+		{
+			name: "and requires every sub-policy",
+			config: SamplingPolicyConfig{Type: PolicyTypeAnd, And: []SamplingPolicyConfig{
+				{Type: PolicyTypeStatusCode},
+				{Type: PolicyTypeAlwaysSample},
+			}},
+			trace:    sampledTrace("t1", nil),
+			expected: domain.SamplingDecisionNotSample,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newSamplingPolicy(tt.config)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy.Evaluate(tt.trace))
+		})
+	}
+}
+
+func TestTailSampler_ProbabilisticIsDeterministic(t *testing.T) {
+	policy, err := newSamplingPolicy(SamplingPolicyConfig{
+		Type:          PolicyTypeProbabilistic,
+		Probabilistic: &ProbabilisticPolicyConfig{SamplingPercentage: 25},
+	})
+	require.NoError(t, err)
+
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		trace := sampledTrace(fmt.Sprintf("trace-%d", i), nil)
+		decision := policy.Evaluate(trace)
+		assert.Equal(t, decision, policy.Evaluate(trace))
+		if decision == domain.SamplingDecisionSample {
+			sampled++
+		}
+	}
+
+	assert.InDelta(t, 2500, sampled, 250)
+}
+
+func TestTailSampler_RateLimitingPerService(t *testing.T) {
+	policy := newRateLimitingPolicy("rate", 2)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy.now = func() time.Time { return now }
+
+	checkout := sampledTrace("t1", nil)
+	payments := sampledTrace("t2", func(tr *domain.Trace) { tr.Service = "payments" })
+
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(checkout))
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(checkout))
+	assert.Equal(t, domain.SamplingDecisionNotSample, policy.Evaluate(checkout))
+
+	// Other services have their own budget
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(payments))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(checkout))
+	assert.Equal(t, domain.SamplingDecisionNotSample, policy.Evaluate(checkout))
+}
+
+func TestTailSampler_RateLimitingBelowOnePerSecond(t *testing.T) {
+	policy := newRateLimitingPolicy("rate", 0.5)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy.now = func() time.Time { return now }
+	trace := sampledTrace("t1", nil)
+
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(trace))
+	assert.Equal(t, domain.SamplingDecisionNotSample, policy.Evaluate(trace))
+
+	// One trace every two seconds
+	now = now.Add(time.Second)
+	assert.Equal(t, domain.SamplingDecisionNotSample, policy.Evaluate(trace))
+	now = now.Add(time.Second)
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(trace))
+
+	// The bucket holds a single token however long it stays idle
+	now = now.Add(time.Minute)
+	assert.Equal(t, domain.SamplingDecisionSample, policy.Evaluate(trace))
+	assert.Equal(t, domain.SamplingDecisionNotSample, policy.Evaluate(trace))
+}
+
+func TestTailSampler_KeptTracesDoNotSpendRateLimit(t *testing.T) {
+	// Arrange: the rate limit is listed before the error policy
+	mockPrometheus := new(MockPrometheusExporter)
+	mockPrometheus.On("RecordSamplingDecision", mock.Anything, mock.Anything).Return()
+	mockPrometheus.On("RecordSampledTrace", mock.Anything).Return()
+
+	sampler, err := NewTailSampler([]SamplingPolicyConfig{
+		{Name: "baseline", Type: PolicyTypeAnd, And: []SamplingPolicyConfig{
+			{Type: PolicyTypeAlwaysSample},
+			{Type: PolicyTypeRateLimiting, RateLimiting: &RateLimitingPolicyConfig{TracesPerSecond: 1}},
+		}},
+		{Name: "errors", Type: PolicyTypeStatusCode},
+	}, mockPrometheus)
+	require.NoError(t, err)
+	failed := func(tr *domain.Trace) { tr.Status = domain.TraceStatusError }
+
+	// Act: failed traces are kept without evaluating the rate limit
+	for i := 0; i < 5; i++ {
+		assert.True(t, sampler.ShouldSample(sampledTrace(fmt.Sprintf("failed-%d", i), failed)))
+	}
+
+	// Assert: the budget is left for the rest of the traffic
+	assert.True(t, sampler.ShouldSample(sampledTrace("ok-1", nil)))
+	assert.False(t, sampler.ShouldSample(sampledTrace("ok-2", nil)))
+	mockPrometheus.AssertNumberOfCalls(t, "RecordSamplingDecision", 5+2*2)
+}
+
+func TestTailSampler_ShouldSample(t *testing.T) {
+	mockPrometheus := new(MockPrometheusExporter)
+	mockPrometheus.On("RecordSamplingDecision", "errors", domain.SamplingDecisionNotSample).Return()
+	mockPrometheus.On("RecordSamplingDecision", "slow", domain.SamplingDecisionSample).Return()
+	mockPrometheus.On("RecordSampledTrace", domain.SamplingDecisionSample).Return()
+
+	sampler, err := NewTailSampler([]SamplingPolicyConfig{
+		{Name: "errors", Type: PolicyTypeStatusCode},
+		{Name: "slow", Type: PolicyTypeLatency, Latency: &LatencyPolicyConfig{Threshold: 50 * time.Millisecond}},
+	}, mockPrometheus)
+	require.NoError(t, err)
+
+	assert.True(t, sampler.ShouldSample(sampledTrace("t1", nil)))
+	mockPrometheus.AssertExpectations(t)
+}
+
+func TestNewTailSampler_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []SamplingPolicyConfig
+		errMsg  string
+	}{
+		{
+			name:    "unknown type",
+			configs: []SamplingPolicyConfig{{Type: "unknown"}},
+			errMsg:  "unknown policy type",
+		},
+		{
+			name:    "duplicate names",
+			configs: []SamplingPolicyConfig{{Type: PolicyTypeStatusCode}, {Type: PolicyTypeStatusCode}},
+			errMsg:  "duplicate policy name",
+		},
+		{
+			name: "percentage out of range",
+			configs: []SamplingPolicyConfig{{Type: PolicyTypeProbabilistic,
+				Probabilistic: &ProbabilisticPolicyConfig{SamplingPercentage: 150}}},
+			errMsg: "sampling percentage must be between 0 and 100",
+		},
+		{
+			name:    "invalid regex",
+			configs: []SamplingPolicyConfig{{Type: PolicyTypeTag, Tag: &TagPolicyConfig{Key: "k", Regex: "("}}},
+			errMsg:  "invalid regex",
+		},
+		{
+			name:    "empty and",
+			configs: []SamplingPolicyConfig{{Type: PolicyTypeAnd}},
+			errMsg:  "requires sub-policies",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTailSampler(tt.configs, nil)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestLoadSamplingPolicyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yml")
+	content := `
+policies:
+  - name: errors
+    type: status_code
+  - name: slow
+    type: latency
+    latency:
+      threshold: 2s
+      operations:
+        "GET /health": 500ms
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	policies, err := LoadSamplingPolicyFile(path)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+	assert.Equal(t, PolicyTypeStatusCode, policies[0].Type)
+	require.NotNil(t, policies[1].Latency)
+	assert.Equal(t, 2*time.Second, policies[1].Latency.Threshold)
+	assert.Equal(t, 500*time.Millisecond, policies[1].Latency.Operations["GET /health"])
+
+	_, err = NewTailSampler(policies, nil)
+	assert.NoError(t, err)
+}
+
+func TestTraceService_ProcessTrace_NotSampled(t *testing.T) {
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+
+	sampler, err := NewTailSampler([]SamplingPolicyConfig{{Type: PolicyTypeStatusCode}}, nil)
+	require.NoError(t, err)
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithTailSampler(sampler))
+	trace := sampledTrace("t1", nil)
+
+	mockPrometheus.On("RecordTraceMetrics", trace).Return(nil)
+
+	err = service.ProcessTrace(context.Background(), trace)
+
+	assert.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mockKafka.AssertNotCalled(t, "PublishTraceEvent", mock.Anything, mock.Anything)
+	mockPrometheus.AssertExpectations(t)
+}
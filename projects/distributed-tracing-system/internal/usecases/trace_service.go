@@ -2,16 +2,94 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/streamforge/distributed-tracing-system/internal/domain"
 )
 
+const (
+	// queryScanLimit bounds how many recent traces are matched in memory
+	// when the repository cannot evaluate queries itself
+	queryScanLimit    = 10000
+	queryScanPageSize = 500
+	// metricsScanLimit bounds how many traces are aggregated in memory
+	// when the repository keeps no rollups
+	metricsScanLimit = 100000
+)
+
 // traceService implements the TraceService interface
 type traceService struct {
-	repo            domain.TraceRepository
+	repo               domain.TraceRepository
 	prometheusExporter domain.PrometheusExporter
-	kafkaProducer   domain.KafkaProducer
+	kafkaProducer      domain.KafkaProducer
+	sampler            domain.TailSampler
+	writeQueue         domain.TraceWriteQueue
+	skewAdjuster       domain.SkewAdjuster
+	dependencies       domain.DependencyService
+	slos               domain.SLOService
+	anomalies          domain.AnomalyDetector
+	tagCatalog         domain.TagCatalog
+}
+
+// TraceServiceOption configures optional trace service behaviour
+type TraceServiceOption func(*traceService)
+
+// WithTailSampler makes the service drop traces the sampler does not keep
+// before they reach the repository
+func WithTailSampler(sampler domain.TailSampler) TraceServiceOption {
+	return func(s *traceService) {
+		s.sampler = sampler
+	}
+}
+
+// WithWriteQueue makes the service save traces through the write queue,
+// batching them with the traces of concurrent callers
+func WithWriteQueue(queue domain.TraceWriteQueue) TraceServiceOption {
+	return func(s *traceService) {
+		s.writeQueue = queue
+	}
+}
+
+// WithSkewAdjuster makes the service correct clock skew between the spans
+// of a trace before it is sampled and stored
+func WithSkewAdjuster(adjuster domain.SkewAdjuster) TraceServiceOption {
+	return func(s *traceService) {
+		s.skewAdjuster = adjuster
+	}
+}
+
+// WithDependencyService makes the service record the calls between
+// services of every trace, sampled or not
+func WithDependencyService(dependencies domain.DependencyService) TraceServiceOption {
+	return func(s *traceService) {
+		s.dependencies = dependencies
+	}
+}
+
+// WithSLOService makes the service count every trace, sampled or not,
+// towards the SLOs it matches
+func WithSLOService(slos domain.SLOService) TraceServiceOption {
+	return func(s *traceService) {
+		s.slos = slos
+	}
+}
+
+// WithAnomalyDetector makes the service feed every trace, sampled or not,
+// to the anomaly detector
+func WithAnomalyDetector(detector domain.AnomalyDetector) TraceServiceOption {
+	return func(s *traceService) {
+		s.anomalies = detector
+	}
+}
+
+// WithTagCatalog makes the service record the tags of every stored trace
+// in the catalog
+func WithTagCatalog(catalog domain.TagCatalog) TraceServiceOption {
+	return func(s *traceService) {
+		s.tagCatalog = catalog
+	}
 }
 
 // NewTraceService creates a new trace service
@@ -19,19 +97,24 @@ func NewTraceService(
 	repo domain.TraceRepository,
 	prometheusExporter domain.PrometheusExporter,
 	kafkaProducer domain.KafkaProducer,
+	opts ...TraceServiceOption,
 ) domain.TraceService {
-	return &traceService{
+	s := &traceService{
 		repo:            repo,
 		prometheusExporter: prometheusExporter,
 		kafkaProducer:   kafkaProducer,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ProcessTrace processes a new trace
 func (s *traceService) ProcessTrace(ctx context.Context, trace *domain.Trace) error {
 	// Validate trace
 	if err := s.validateTrace(trace); err != nil {
-		return fmt.Errorf("invalid trace: %w", err)
+		return fmt.Errorf("%w: %v", domain.ErrInvalidTrace, err)
 	}
 
 	// Calculate duration if not set
@@ -39,11 +122,71 @@ func (s *traceService) ProcessTrace(ctx context.Context, trace *domain.Trace) er
 		trace.Duration = trace.EndTime.Sub(trace.StartTime)
 	}
 
+	// Correct clock skew between services
+	if s.skewAdjuster != nil {
+		s.skewAdjuster.Adjust(trace)
+	}
+
+	// Apply tail sampling; dropped traces still count towards metrics and
+	// are recorded like stored ones, so the recorders reflect all traffic
+	if s.sampler != nil && !s.sampler.ShouldSample(trace) {
+		s.record(trace)
+		if err := s.prometheusExporter.RecordTraceMetrics(trace); err != nil {
+			fmt.Printf("Failed to record metrics: %v\n", err)
+		}
+		return nil
+	}
+
+	return s.store(ctx, trace)
+}
+
+// UpdateTrace saves a new version of a stored trace, such as the trace with
+// late spans merged in. The stored version was already sampled and passed
+// on, so the update is only saved and cataloged.
+func (s *traceService) UpdateTrace(ctx context.Context, trace *domain.Trace) error {
+	if err := s.validateTrace(trace); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidTrace, err)
+	}
+
+	if trace.Duration == 0 {
+		trace.Duration = trace.EndTime.Sub(trace.StartTime)
+	}
+
+	if s.skewAdjuster != nil {
+		s.skewAdjuster.Adjust(trace)
+	}
+
+	if err := s.save(ctx, trace); err != nil {
+		return fmt.Errorf("failed to save trace: %w", err)
+	}
+
+	if s.tagCatalog != nil {
+		s.tagCatalog.Record(trace)
+	}
+
+	return nil
+}
+
+// store saves a processed trace and passes it on. When the write queue
+// refuses it, the returned domain.BackpressureError retries from here.
+func (s *traceService) store(ctx context.Context, trace *domain.Trace) error {
 	// Save trace to repository
-	if err := s.repo.Save(ctx, trace); err != nil {
+	if err := s.save(ctx, trace); err != nil {
+		if errors.Is(err, domain.ErrBackpressure) {
+			err = &domain.BackpressureError{Retry: func(ctx context.Context) error {
+				return s.store(ctx, trace)
+			}}
+		}
 		return fmt.Errorf("failed to save trace: %w", err)
 	}
 
+	s.record(trace)
+
+	// Only stored traces are cataloged, so every listed tag can be searched
+	if s.tagCatalog != nil {
+		s.tagCatalog.Record(trace)
+	}
+
 	// Export metrics to Prometheus
 	if err := s.prometheusExporter.RecordTraceMetrics(trace); err != nil {
 		// Log error but don't fail the operation
@@ -59,16 +202,190 @@ func (s *traceService) ProcessTrace(ctx context.Context, trace *domain.Trace) er
 	return nil
 }
 
+// record feeds a trace to the services that reflect all traffic. It runs
+// once the trace is stored or dropped by the sampler, so that a trace
+// refused by storage and sent again is not recorded twice.
+func (s *traceService) record(trace *domain.Trace) {
+	if s.dependencies != nil {
+		s.dependencies.Record(trace)
+	}
+	if s.slos != nil {
+		s.slos.Record(trace)
+	}
+	if s.anomalies != nil {
+		s.anomalies.Record(trace)
+	}
+}
+
+// save stores a trace through the write queue when one is configured
+func (s *traceService) save(ctx context.Context, trace *domain.Trace) error {
+	if s.writeQueue != nil {
+		return s.writeQueue.Save(ctx, trace)
+	}
+	return s.repo.Save(ctx, trace)
+}
+
+// ValidateTrace checks a trace without processing it
+func (s *traceService) ValidateTrace(trace *domain.Trace) error {
+	if trace == nil {
+		return fmt.Errorf("%w: trace cannot be nil", domain.ErrInvalidTrace)
+	}
+	if err := s.validateTrace(trace); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidTrace, err)
+	}
+	return nil
+}
+
 // SearchTraces searches for traces based on criteria
 func (s *traceService) SearchTraces(ctx context.Context, criteria *domain.SearchCriteria) ([]*domain.Trace, error) {
 	return s.repo.Search(ctx, criteria)
 }
 
+// CountTraces counts the traces matching the criteria. Estimates are used
+// when requested and supported by the repository, otherwise traces are
+// counted exactly.
+func (s *traceService) CountTraces(ctx context.Context, criteria *domain.SearchCriteria, mode domain.CountMode) (*domain.TraceCount, error) {
+	switch mode {
+	case domain.CountNone, "":
+		return nil, nil
+	case domain.CountEstimate:
+		if estimator, ok := s.repo.(domain.TraceCountEstimator); ok {
+			estimate, err := estimator.EstimateCount(ctx, criteria)
+			if err == nil {
+				return &domain.TraceCount{Value: estimate, Estimated: true}, nil
+			}
+			fmt.Printf("Failed to estimate trace count, counting exactly: %v\n", err)
+		}
+	case domain.CountExact:
+	default:
+		return nil, fmt.Errorf("unknown count mode %q", mode)
+	}
+
+	count, err := s.repo.Count(ctx, criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+	return &domain.TraceCount{Value: count}, nil
+}
+
+// QueryTraces returns a page of the traces matching a trace query, newest
+// first. Repositories that implement TraceQuerier evaluate the query
+// themselves; otherwise the most recent traces are scanned and matched in
+// memory.
+func (s *traceService) QueryTraces(ctx context.Context, query *domain.TraceQuery, page domain.TracePage) ([]*domain.Trace, error) {
+	if query == nil || query.Root == nil {
+		return nil, fmt.Errorf("query is required")
+	}
+	if page.Limit < 0 || page.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset cannot be negative")
+	}
+
+	if querier, ok := s.repo.(domain.TraceQuerier); ok {
+		return querier.QueryTraces(ctx, query, page)
+	}
+
+	matches := []*domain.Trace{}
+	skipped := 0
+	_, err := s.matchTraces(ctx, query, page.Cursor, func(trace *domain.Trace) bool {
+		if skipped < page.Offset {
+			skipped++
+			return true
+		}
+		matches = append(matches, trace)
+		return page.Limit <= 0 || len(matches) < page.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// CountQueryTraces counts the traces matching a trace query according to
+// the count mode. When the repository cannot evaluate queries, only the
+// scanned traces are counted and the count is marked as estimated if the
+// scan stopped before the oldest trace.
+func (s *traceService) CountQueryTraces(ctx context.Context, query *domain.TraceQuery, mode domain.CountMode) (*domain.TraceCount, error) {
+	switch mode {
+	case domain.CountNone, "":
+		return nil, nil
+	case domain.CountExact, domain.CountEstimate:
+	default:
+		return nil, fmt.Errorf("unknown count mode %q", mode)
+	}
+	if query == nil || query.Root == nil {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	if querier, ok := s.repo.(domain.TraceQuerier); ok {
+		count, err := querier.CountQuery(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count traces: %w", err)
+		}
+		return &domain.TraceCount{Value: count}, nil
+	}
+
+	var count int64
+	complete, err := s.matchTraces(ctx, query, nil, func(*domain.Trace) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TraceCount{Value: count, Estimated: !complete}, nil
+}
+
+// matchTraces scans the most recent traces after the cursor page by page,
+// matches them in memory and passes the matches to yield until it returns
+// false. It reports whether every trace was scanned.
+func (s *traceService) matchTraces(ctx context.Context, query *domain.TraceQuery, cursor *domain.TraceCursor, yield func(*domain.Trace) bool) (bool, error) {
+	for scanned := 0; scanned < queryScanLimit; scanned += queryScanPageSize {
+		page, err := s.repo.Search(ctx, &domain.SearchCriteria{Cursor: cursor, Limit: queryScanPageSize, Offset: scanned})
+		if err != nil {
+			return false, fmt.Errorf("failed to scan traces: %w", err)
+		}
+
+		for _, trace := range page {
+			// Search results may omit spans; span blocks need the full trace
+			if query.HasSpanMatch() && len(trace.Spans) == 0 {
+				full, err := s.repo.FindByID(ctx, trace.ID)
+				if errors.Is(err, domain.ErrTraceNotFound) {
+					// Deleted since the search page was read
+					continue
+				}
+				if err != nil {
+					return false, fmt.Errorf("failed to load trace %s: %w", trace.ID, err)
+				}
+				trace = full
+			}
+
+			if query.Matches(trace) && !yield(trace) {
+				return false, nil
+			}
+		}
+
+		if len(page) < queryScanPageSize {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // GetTrace retrieves a specific trace by ID
 func (s *traceService) GetTrace(ctx context.Context, id domain.TraceID) (*domain.Trace, error) {
 	return s.repo.FindByID(ctx, id)
 }
 
+// AnalyzeTrace retrieves a trace and analyzes its span tree
+func (s *traceService) AnalyzeTrace(ctx context.Context, id domain.TraceID) (*domain.TraceAnalysis, error) {
+	trace, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return domain.AnalyzeTrace(trace), nil
+}
+
 // GetServices retrieves all available services
 func (s *traceService) GetServices(ctx context.Context) ([]domain.ServiceName, error) {
 	return s.repo.GetServices(ctx)
@@ -79,18 +396,107 @@ func (s *traceService) GetOperations(ctx context.Context, service domain.Service
 	return s.repo.GetOperations(ctx, service)
 }
 
-// GetMetrics retrieves aggregated trace metrics
-func (s *traceService) GetMetrics(ctx context.Context) (*domain.TraceMetrics, error) {
-	// This would typically query Prometheus or calculate from the repository
-	// For now, return a basic implementation
-	return &domain.TraceMetrics{
-		TotalTraces:     0,
-		TotalSpans:      0,
-		AverageDuration: 0,
-		ErrorRate:       0.0,
-		Throughput:      0.0,
-		Services:       []domain.ServiceMetrics{},
-	}, nil
+// GetMetrics aggregates the traces that started within the query window.
+// Repositories that implement TraceRollupStore serve it from their
+// rollups; otherwise the traces of the window are scanned.
+func (s *traceService) GetMetrics(ctx context.Context, query domain.MetricsQuery) (*domain.TraceMetrics, error) {
+	if !query.Start.Before(query.End) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+
+	var rollups []*domain.TraceRollup
+	var err error
+	if store, ok := s.repo.(domain.TraceRollupStore); ok {
+		rollups, err = findRollups(ctx, store, query)
+	} else {
+		rollups, err = s.scanRollups(ctx, query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate metrics: %w", err)
+	}
+
+	return domain.BuildTraceMetrics(query, rollups), nil
+}
+
+// rollupRange is a time range served by rollups of one resolution
+type rollupRange struct {
+	resolution time.Duration
+	start      time.Time
+	end        time.Time
+}
+
+// rollupRanges covers [start, end) with hourly rollups where whole hours
+// fit and minute rollups at the edges. The edges are widened to whole
+// minutes, the finest resolution kept.
+func rollupRanges(start, end time.Time) []rollupRange {
+	start = start.UTC().Truncate(domain.RollupMinute)
+	if end = end.UTC(); !end.Truncate(domain.RollupMinute).Equal(end) {
+		end = end.Truncate(domain.RollupMinute).Add(domain.RollupMinute)
+	}
+
+	firstHour := start.Truncate(domain.RollupHour)
+	if firstHour.Before(start) {
+		firstHour = firstHour.Add(domain.RollupHour)
+	}
+	lastHour := end.Truncate(domain.RollupHour)
+	if !firstHour.Before(lastHour) {
+		return []rollupRange{{resolution: domain.RollupMinute, start: start, end: end}}
+	}
+
+	var ranges []rollupRange
+	if start.Before(firstHour) {
+		ranges = append(ranges, rollupRange{resolution: domain.RollupMinute, start: start, end: firstHour})
+	}
+	ranges = append(ranges, rollupRange{resolution: domain.RollupHour, start: firstHour, end: lastHour})
+	if lastHour.Before(end) {
+		ranges = append(ranges, rollupRange{resolution: domain.RollupMinute, start: lastHour, end: end})
+	}
+	return ranges
+}
+
+// findRollups reads the rollups covering the query window from the store
+func findRollups(ctx context.Context, store domain.TraceRollupStore, query domain.MetricsQuery) ([]*domain.TraceRollup, error) {
+	var rollups []*domain.TraceRollup
+	for _, r := range rollupRanges(query.Start, query.End) {
+		found, err := store.FindRollups(ctx, r.resolution, domain.MetricsQuery{Start: r.start, End: r.end, Service: query.Service})
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, found...)
+	}
+	return rollups, nil
+}
+
+// scanRollups builds minute rollups from the traces of the query window,
+// scanning them page by page
+func (s *traceService) scanRollups(ctx context.Context, query domain.MetricsQuery) ([]*domain.TraceRollup, error) {
+	criteria := &domain.SearchCriteria{StartTime: &query.Start, EndTime: &query.End, Limit: queryScanPageSize}
+	if query.Service != "" {
+		criteria.Service = &query.Service
+	}
+
+	var rollups []*domain.TraceRollup
+	for scanned := 0; scanned < metricsScanLimit; scanned += queryScanPageSize {
+		criteria.Offset = scanned
+		page, err := s.repo.Search(ctx, criteria)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan traces: %w", err)
+		}
+
+		for _, trace := range page {
+			// The search end time is inclusive
+			if !trace.StartTime.Before(query.End) {
+				continue
+			}
+			rollups = append(rollups, domain.TraceRollups(trace)[0])
+		}
+
+		if len(page) < queryScanPageSize {
+			break
+		}
+	}
+
+	return rollups, nil
 }
 
 // validateTrace validates a trace before processing
@@ -9,6 +9,7 @@ import (
 	"github.com/streamforge/distributed-tracing-system/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock implementations for testing
@@ -36,6 +37,11 @@ func (m *MockTraceRepository) GetServices(ctx context.Context) ([]domain.Service
 	return args.Get(0).([]domain.ServiceName), args.Error(1)
 }
 
+func (m *MockTraceRepository) Count(ctx context.Context, criteria *domain.SearchCriteria) (int64, error) {
+	args := m.Called(ctx, criteria)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockTraceRepository) GetOperations(ctx context.Context, service domain.ServiceName) ([]domain.OperationName, error) {
 	args := m.Called(ctx, service)
 	return args.Get(0).([]domain.OperationName), args.Error(1)
@@ -50,6 +56,58 @@ func (m *MockPrometheusExporter) RecordTraceMetrics(trace *domain.Trace) error {
 	return args.Error(0)
 }
 
+func (m *MockPrometheusExporter) RecordSamplingDecision(policy string, decision domain.SamplingDecision) {
+	m.Called(policy, decision)
+}
+
+func (m *MockPrometheusExporter) RecordSampledTrace(decision domain.SamplingDecision) {
+	m.Called(decision)
+}
+
+func (m *MockPrometheusExporter) RecordTracesPurged(rule string, count int64) {
+	m.Called(rule, count)
+}
+
+func (m *MockPrometheusExporter) RecordRetentionLag(lag time.Duration) {
+	m.Called(lag)
+}
+
+func (m *MockPrometheusExporter) RecordWriteBatch(size int, duration time.Duration) {
+	m.Called(size, duration)
+}
+
+func (m *MockPrometheusExporter) RecordWriteQueueDepth(depth int) {
+	m.Called(depth)
+}
+
+func (m *MockPrometheusExporter) RecordWriteRejected() {
+	m.Called()
+}
+
+func (m *MockPrometheusExporter) RecordClockSkewAdjustment(service string, adjustment time.Duration) {
+	m.Called(service, adjustment)
+}
+
+func (m *MockPrometheusExporter) RecordAnomaly(anomaly *domain.Anomaly) {
+	m.Called(anomaly)
+}
+
+func (m *MockPrometheusExporter) RecordExport(backend string, result domain.ExportResult, duration time.Duration) {
+	m.Called(backend, result, duration)
+}
+
+func (m *MockPrometheusExporter) RecordExportRetry(backend string) {
+	m.Called(backend)
+}
+
+func (m *MockPrometheusExporter) RecordExportQueueDepth(backend string, depth int) {
+	m.Called(backend, depth)
+}
+
+func (m *MockPrometheusExporter) RecordCircuitState(backend string, state domain.CircuitState) {
+	m.Called(backend, state)
+}
+
 type MockKafkaProducer struct {
 	mock.Mock
 }
@@ -158,6 +216,202 @@ func TestTraceService_ProcessTrace_RepositoryError(t *testing.T) {
 	mockKafka.AssertNotCalled(t, "PublishTraceEvent")
 }
 
+func TestTraceService_ProcessTrace_WriteQueueBackpressure(t *testing.T) {
+	// Arrange: a full queue without a running writer
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := newQueueTestMetrics()
+	mockKafka := new(MockKafkaProducer)
+
+	queue, err := NewTraceWriteQueue(mockRepo, mockPrometheus, WriteQueueConfig{Capacity: 1, BatchSize: 1})
+	require.NoError(t, err)
+	queue.(*traceWriteQueue).queue <- writeRequest{trace: newQueueTestTrace("queued"), done: make(chan error, 1)}
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithWriteQueue(queue))
+
+	// Act
+	err = service.ProcessTrace(context.Background(), newQueueTestTrace("refused"))
+
+	// Assert
+	assert.ErrorIs(t, err, domain.ErrBackpressure)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mockPrometheus.AssertNotCalled(t, "RecordTraceMetrics", mock.Anything)
+	mockKafka.AssertNotCalled(t, "PublishTraceEvent", mock.Anything, mock.Anything)
+
+	// The refused trace can be saved again without reprocessing it
+	var backpressure *domain.BackpressureError
+	require.True(t, errors.As(err, &backpressure))
+	<-queue.(*traceWriteQueue).queue
+	mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(trace *domain.Trace) bool {
+		return trace.ID == "refused"
+	})).Return(nil)
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+	mockKafka.On("PublishTraceEvent", mock.Anything, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Start(ctx)
+
+	require.NoError(t, backpressure.Retry(context.Background()))
+	mockRepo.AssertNumberOfCalls(t, "Save", 1)
+	mockKafka.AssertNumberOfCalls(t, "PublishTraceEvent", 1)
+}
+
+func TestTraceService_ProcessTrace_AdjustsClockSkew(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	adjuster, _ := newTestSkewAdjuster(t, SkewAdjusterConfig{})
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithSkewAdjuster(adjuster))
+
+	ctx := context.Background()
+	trace := &domain.Trace{
+		ID:        "1234567890abcdef",
+		Service:   "checkout",
+		Operation: "POST /orders",
+		StartTime: skewTestStart,
+		EndTime:   skewTestStart.Add(100 * time.Millisecond),
+		Spans: []domain.Span{
+			skewTestSpan("root", "", "checkout", 0, 100),
+			skewTestSpan("child", "root", "payments", -90, -30),
+		},
+	}
+
+	// The stored trace is already corrected
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(trace *domain.Trace) bool {
+		return trace.Spans[1].StartTime.Equal(skewTestStart.Add(20 * time.Millisecond))
+	})).Return(nil)
+	mockPrometheus.On("RecordTraceMetrics", trace).Return(nil)
+	mockKafka.On("PublishTraceEvent", ctx, trace).Return(nil)
+
+	// Act
+	err := service.ProcessTrace(ctx, trace)
+
+	// Assert
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTraceService_ProcessTrace_RecordsDependenciesOfDroppedTraces(t *testing.T) {
+	// Arrange: the sampler only keeps failed traces
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	mockPrometheus.On("RecordSamplingDecision", mock.Anything, mock.Anything).Maybe()
+	mockPrometheus.On("RecordSampledTrace", mock.Anything).Maybe()
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+
+	sampler, err := NewTailSampler([]SamplingPolicyConfig{{Name: "errors", Type: PolicyTypeStatusCode}}, mockPrometheus)
+	require.NoError(t, err)
+	store := new(MockDependencyStore)
+	dependencies := newTestDependencyService(t, store)
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithTailSampler(sampler), WithDependencyService(dependencies))
+
+	trace := newDependencyTestTrace("1234567890abcdef", 0, domain.SpanStatusOK)
+	trace.Service = "checkout"
+	trace.Operation = "POST /orders"
+	trace.StartTime = trace.Spans[0].StartTime
+	trace.EndTime = trace.Spans[0].EndTime
+
+	// Act
+	err = service.ProcessTrace(context.Background(), trace)
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	assert.Len(t, dependencies.pending, 1)
+}
+
+func TestTraceService_ProcessTrace_RecordsDependenciesOnceStored(t *testing.T) {
+	// Arrange: the first save is refused by storage
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(domain.ErrBackpressure).Once()
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil).Once()
+	mockPrometheus.On("RecordTraceMetrics", mock.Anything).Return(nil)
+	mockKafka.On("PublishTraceEvent", mock.Anything, mock.Anything).Return(nil)
+
+	store := new(MockDependencyStore)
+	dependencies := newTestDependencyService(t, store)
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithDependencyService(dependencies))
+
+	trace := newDependencyTestTrace("1234567890abcdef", 0, domain.SpanStatusOK)
+	trace.Service = "checkout"
+	trace.Operation = "POST /orders"
+	trace.StartTime = trace.Spans[0].StartTime
+	trace.EndTime = trace.Spans[0].EndTime
+
+	// Act & Assert: the refused trace is not recorded, the resent one once
+	err := service.ProcessTrace(context.Background(), trace)
+	require.ErrorIs(t, err, domain.ErrBackpressure)
+	assert.Empty(t, dependencies.pending)
+
+	require.NoError(t, service.ProcessTrace(context.Background(), trace))
+	require.Len(t, dependencies.pending, 1)
+	for _, link := range dependencies.pending {
+		assert.Equal(t, int64(1), link.CallCount)
+	}
+}
+
+func TestTraceService_UpdateTrace(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	mockPrometheus := new(MockPrometheusExporter)
+	mockKafka := new(MockKafkaProducer)
+	store := new(MockDependencyStore)
+	dependencies := newTestDependencyService(t, store)
+
+	service := NewTraceService(mockRepo, mockPrometheus, mockKafka, WithDependencyService(dependencies))
+
+	ctx := context.Background()
+	trace := newDependencyTestTrace("1234567890abcdef", 0, domain.SpanStatusOK)
+	trace.Service = "checkout"
+	trace.Operation = "POST /orders"
+	trace.StartTime = trace.Spans[0].StartTime
+	trace.EndTime = trace.Spans[0].EndTime
+
+	mockRepo.On("Save", ctx, trace).Return(nil)
+
+	// Act
+	err := service.UpdateTrace(ctx, trace)
+
+	// Assert: the update is saved but not passed on again
+	assert.NoError(t, err)
+	assert.Equal(t, trace.EndTime.Sub(trace.StartTime), trace.Duration)
+	mockRepo.AssertExpectations(t)
+	mockPrometheus.AssertNotCalled(t, "RecordTraceMetrics", mock.Anything)
+	mockKafka.AssertNotCalled(t, "PublishTraceEvent", mock.Anything, mock.Anything)
+	assert.Empty(t, dependencies.pending)
+
+	assert.ErrorIs(t, service.UpdateTrace(ctx, &domain.Trace{}), domain.ErrInvalidTrace)
+}
+
+func TestTraceService_ValidateTrace(t *testing.T) {
+	service := NewTraceService(new(MockTraceRepository), new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	validTrace := &domain.Trace{
+		ID:        "1234567890abcdef",
+		Service:   "test-service",
+		Operation: "test-operation",
+		StartTime: time.Now().Add(-time.Second),
+		EndTime:   time.Now(),
+		Status:    domain.TraceStatusSuccess,
+	}
+	assert.NoError(t, service.ValidateTrace(validTrace))
+
+	err := service.ValidateTrace(&domain.Trace{ID: "1234567890abcdef"})
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, domain.ErrInvalidTrace))
+	assert.Contains(t, err.Error(), "service name is required")
+
+	err = service.ValidateTrace(nil)
+	assert.True(t, errors.Is(err, domain.ErrInvalidTrace))
+}
+
 func TestTraceService_SearchTraces_Success(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockTraceRepository)
@@ -205,6 +459,188 @@ func TestTraceService_SearchTraces_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestTraceService_CountTraces(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	service := NewTraceService(mockRepo, new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	ctx := context.Background()
+	criteria := &domain.SearchCriteria{Limit: 10}
+	mockRepo.On("Count", ctx, criteria).Return(int64(42), nil)
+
+	// Act & Assert
+	count, err := service.CountTraces(ctx, criteria, domain.CountNone)
+	assert.NoError(t, err)
+	assert.Nil(t, count)
+
+	count, err = service.CountTraces(ctx, criteria, domain.CountExact)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.TraceCount{Value: 42}, count)
+
+	// Repositories without an estimator fall back to exact counts
+	count, err = service.CountTraces(ctx, criteria, domain.CountEstimate)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.TraceCount{Value: 42}, count)
+
+	_, err = service.CountTraces(ctx, criteria, "approximate")
+	assert.Error(t, err)
+}
+
+func TestTraceService_QueryTraces_InMemoryFallback(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	service := NewTraceService(mockRepo, new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	summaries := []*domain.Trace{
+		{ID: "trace1", Service: "checkout", Operation: "op", StartTime: start, EndTime: start.Add(time.Second), Status: domain.TraceStatusError},
+		{ID: "trace2", Service: "checkout", Operation: "op", StartTime: start, EndTime: start.Add(time.Second), Status: domain.TraceStatusError},
+		{ID: "trace3", Service: "inventory", Operation: "op", StartTime: start, EndTime: start.Add(time.Second), Status: domain.TraceStatusError},
+	}
+	full := *summaries[1]
+	full.Spans = []domain.Span{{ID: "span1", TraceID: "trace2", Service: "payments", Status: domain.SpanStatusError}}
+
+	mockRepo.On("Search", ctx, &domain.SearchCriteria{Limit: queryScanPageSize, Offset: 0}).Return(summaries, nil)
+	mockRepo.On("FindByID", ctx, domain.TraceID("trace1")).Return(summaries[0], nil)
+	mockRepo.On("FindByID", ctx, domain.TraceID("trace2")).Return(&full, nil)
+	mockRepo.On("FindByID", ctx, domain.TraceID("trace3")).Return(summaries[2], nil)
+
+	query, err := domain.ParseTraceQuery(`service=checkout AND span{service=payments status=error}`)
+	assert.NoError(t, err)
+
+	// Act
+	traces, err := service.QueryTraces(ctx, query, domain.TracePage{Limit: 10})
+	count, countErr := service.CountQueryTraces(ctx, query, domain.CountExact)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, traces, 1)
+	assert.Equal(t, domain.TraceID("trace2"), traces[0].ID)
+	require.NoError(t, countErr)
+	assert.Equal(t, &domain.TraceCount{Value: 1}, count)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTraceService_QueryTraces_InMemoryFallbackCursor(t *testing.T) {
+	// Arrange: the scan continues after the cursor
+	mockRepo := new(MockTraceRepository)
+	service := NewTraceService(mockRepo, new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	cursor := domain.NewTraceCursor(&domain.Trace{ID: "trace1", StartTime: start}, domain.SortByStartTime, domain.SortDescending)
+	older := []*domain.Trace{
+		{ID: "trace0", Service: "checkout", Operation: "op", StartTime: start.Add(-time.Second), EndTime: start, Status: domain.TraceStatusSuccess},
+	}
+
+	mockRepo.On("Search", ctx, &domain.SearchCriteria{Cursor: &cursor, Limit: queryScanPageSize, Offset: 0}).Return(older, nil)
+
+	query, err := domain.ParseTraceQuery(`service=checkout`)
+	require.NoError(t, err)
+
+	// Act
+	traces, err := service.QueryTraces(ctx, query, domain.TracePage{Limit: 10, Cursor: &cursor})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, domain.TraceID("trace0"), traces[0].ID)
+	mockRepo.AssertExpectations(t)
+}
+
+// MockRollupRepository is a repository that keeps trace rollups
+type MockRollupRepository struct {
+	MockTraceRepository
+}
+
+func (m *MockRollupRepository) FindRollups(ctx context.Context, resolution time.Duration, query domain.MetricsQuery) ([]*domain.TraceRollup, error) {
+	args := m.Called(ctx, resolution, query)
+	return args.Get(0).([]*domain.TraceRollup), args.Error(1)
+}
+
+func TestRollupRanges(t *testing.T) {
+	at := func(hour, minute, second int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, second, 0, time.UTC)
+	}
+
+	// Within an hour only minute rollups are read, widened to whole minutes
+	assert.Equal(t, []rollupRange{
+		{resolution: domain.RollupMinute, start: at(10, 5, 0), end: at(10, 31, 0)},
+	}, rollupRanges(at(10, 5, 30), at(10, 30, 10)))
+
+	assert.Equal(t, []rollupRange{
+		{resolution: domain.RollupMinute, start: at(9, 45, 0), end: at(10, 0, 0)},
+		{resolution: domain.RollupHour, start: at(10, 0, 0), end: at(12, 0, 0)},
+		{resolution: domain.RollupMinute, start: at(12, 0, 0), end: at(12, 20, 0)},
+	}, rollupRanges(at(9, 45, 0), at(12, 20, 0)))
+
+	assert.Equal(t, []rollupRange{
+		{resolution: domain.RollupHour, start: at(10, 0, 0), end: at(12, 0, 0)},
+	}, rollupRanges(at(10, 0, 0), at(12, 0, 0)))
+}
+
+func TestTraceService_GetMetrics_FromRollups(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRollupRepository)
+	service := NewTraceService(mockRepo, new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+
+	minute := domain.NewTraceRollup(domain.RollupMinute, start, "checkout", "pay")
+	minute.Merge(domain.TraceRollups(&domain.Trace{Service: "checkout", Operation: "pay", StartTime: start, Duration: 10 * time.Millisecond})[0])
+	hour := domain.NewTraceRollup(domain.RollupHour, end.Add(-time.Hour), "checkout", "pay")
+	hour.TraceCount = 8
+	hour.ErrorCount = 2
+
+	mockRepo.On("FindRollups", ctx, domain.RollupMinute, domain.MetricsQuery{Start: start, End: start.Add(30 * time.Minute), Service: "checkout"}).Return([]*domain.TraceRollup{minute}, nil)
+	mockRepo.On("FindRollups", ctx, domain.RollupHour, domain.MetricsQuery{Start: end.Add(-time.Hour), End: end, Service: "checkout"}).Return([]*domain.TraceRollup{hour}, nil)
+
+	// Act
+	metrics, err := service.GetMetrics(ctx, domain.MetricsQuery{Start: start, End: end, Service: "checkout"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), metrics.TotalTraces)
+	assert.InDelta(t, 2.0/9, metrics.ErrorRate, 1e-9)
+	assert.InDelta(t, 9.0/5400, metrics.Throughput, 1e-9)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.GetMetrics(ctx, domain.MetricsQuery{Start: end, End: start})
+	assert.Error(t, err)
+}
+
+func TestTraceService_GetMetrics_ScanFallback(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	service := NewTraceService(mockRepo, new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	traces := []*domain.Trace{
+		{ID: "trace1", Service: "checkout", Operation: "pay", StartTime: start, Duration: 20 * time.Millisecond, Status: domain.TraceStatusSuccess},
+		{ID: "trace2", Service: "checkout", Operation: "cart", StartTime: start.Add(time.Second), Duration: 40 * time.Millisecond, Status: domain.TraceStatusError},
+		// The search end time is inclusive, unlike the metrics window
+		{ID: "trace3", Service: "checkout", Operation: "pay", StartTime: end, Duration: time.Second, Status: domain.TraceStatusSuccess},
+	}
+	mockRepo.On("Search", ctx, &domain.SearchCriteria{StartTime: &start, EndTime: &end, Limit: queryScanPageSize}).Return(traces, nil)
+
+	// Act
+	metrics, err := service.GetMetrics(ctx, domain.MetricsQuery{Start: start, End: end})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), metrics.TotalTraces)
+	assert.Equal(t, 30*time.Millisecond, metrics.AverageDuration)
+	assert.InDelta(t, 0.5, metrics.ErrorRate, 1e-9)
+	assert.Len(t, metrics.Services, 1)
+	assert.Len(t, metrics.Services[0].Operations, 2)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestTraceService_GetTrace_Success(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockTraceRepository)
@@ -236,6 +672,32 @@ func TestTraceService_GetTrace_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestTraceService_AnalyzeTrace(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockTraceRepository)
+	service := NewTraceService(mockRepo, new(MockPrometheusExporter), new(MockKafkaProducer))
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+	trace := domain.BuildTrace("1234567890abcdef", []domain.Span{
+		{ID: "root", TraceID: "1234567890abcdef", Service: "test-service", Operation: "test-operation", StartTime: start, EndTime: start.Add(time.Second)},
+	})
+
+	mockRepo.On("FindByID", ctx, domain.TraceID("1234567890abcdef")).Return(trace, nil)
+	mockRepo.On("FindByID", ctx, domain.TraceID("missing")).Return((*domain.Trace)(nil), domain.ErrTraceNotFound)
+
+	// Act
+	analysis, err := service.AnalyzeTrace(ctx, "1234567890abcdef")
+	_, missingErr := service.AnalyzeTrace(ctx, "missing")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, analysis.SpanCount)
+	require.Len(t, analysis.CriticalPath, 1)
+	assert.Equal(t, time.Second, analysis.CriticalPath[0].Duration)
+	assert.ErrorIs(t, missingErr, domain.ErrTraceNotFound)
+}
+
 func TestTraceService_GetServices_Success(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockTraceRepository)
@@ -0,0 +1,190 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+)
+
+// errWriteQueueStopped is returned for traces saved after the queue stopped
+var errWriteQueueStopped = errors.New("trace write queue is stopped")
+
+// WriteQueueConfig holds configuration for the trace write queue
+type WriteQueueConfig struct {
+	// Capacity bounds the traces waiting to be written; saves beyond it
+	// fail with domain.ErrBackpressure
+	Capacity int
+	// BatchSize bounds the traces written per repository call
+	BatchSize int
+	// Linger is how long a batch waits to fill up after its first trace;
+	// zero writes whatever is queued right away, so batches only grow
+	// while the previous write is in progress
+	Linger time.Duration
+}
+
+// writeRequest is a queued trace and the channel receiving its result
+type writeRequest struct {
+	trace *domain.Trace
+	done  chan error
+}
+
+// traceWriteQueue implements the TraceWriteQueue interface
+type traceWriteQueue struct {
+	repo domain.TraceRepository
+	// batcher is nil when the repository cannot save batches
+	batcher domain.TraceBatchSaver
+	metrics domain.PrometheusExporter
+	config  WriteQueueConfig
+	queue   chan writeRequest
+	// stopped is closed once Start has written the last queued traces
+	stopped chan struct{}
+}
+
+// NewTraceWriteQueue creates a write queue in front of the repository.
+// Batches are saved with SaveBatch when the repository supports it.
+func NewTraceWriteQueue(repo domain.TraceRepository, metrics domain.PrometheusExporter, config WriteQueueConfig) (domain.TraceWriteQueue, error) {
+	if config.Capacity <= 0 {
+		return nil, fmt.Errorf("write queue capacity must be positive")
+	}
+	if config.BatchSize <= 0 {
+		return nil, fmt.Errorf("write batch size must be positive")
+	}
+	if config.Linger < 0 {
+		return nil, fmt.Errorf("write linger cannot be negative")
+	}
+
+	q := &traceWriteQueue{
+		repo:    repo,
+		metrics: metrics,
+		config:  config,
+		queue:   make(chan writeRequest, config.Capacity),
+		stopped: make(chan struct{}),
+	}
+	if batcher, ok := repo.(domain.TraceBatchSaver); ok {
+		q.batcher = batcher
+	}
+	return q, nil
+}
+
+// Save queues the trace and waits for its batch to be written
+func (q *traceWriteQueue) Save(ctx context.Context, trace *domain.Trace) error {
+	req := writeRequest{trace: trace, done: make(chan error, 1)}
+
+	select {
+	case <-q.stopped:
+		return errWriteQueueStopped
+	case q.queue <- req:
+	default:
+		q.metrics.RecordWriteRejected()
+		return domain.ErrBackpressure
+	}
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-q.stopped:
+		// The trace may have been written by the final drain
+		select {
+		case err := <-req.done:
+			return err
+		default:
+			return errWriteQueueStopped
+		}
+	case <-ctx.Done():
+		// The trace stays queued and may still be written
+		return ctx.Err()
+	}
+}
+
+// Start writes batches until the context is cancelled
+func (q *traceWriteQueue) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			q.drain()
+			close(q.stopped)
+			return nil
+		case req := <-q.queue:
+			q.write(q.collect(ctx, req))
+		}
+	}
+}
+
+// collect gathers a batch starting with first
+func (q *traceWriteQueue) collect(ctx context.Context, first writeRequest) []writeRequest {
+	batch := []writeRequest{first}
+
+	if q.config.Linger == 0 {
+		for len(batch) < q.config.BatchSize {
+			select {
+			case req := <-q.queue:
+				batch = append(batch, req)
+			default:
+				return batch
+			}
+		}
+		return batch
+	}
+
+	timer := time.NewTimer(q.config.Linger)
+	defer timer.Stop()
+	for len(batch) < q.config.BatchSize {
+		select {
+		case req := <-q.queue:
+			batch = append(batch, req)
+		case <-timer.C:
+			return batch
+		case <-ctx.Done():
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain writes the traces still queued on shutdown
+func (q *traceWriteQueue) drain() {
+	for {
+		select {
+		case req := <-q.queue:
+			q.write(q.collect(context.Background(), req))
+		default:
+			return
+		}
+	}
+}
+
+// write saves a batch and reports the result to every caller. Writes are
+// not tied to a caller's context, since the batch holds other callers'
+// traces.
+func (q *traceWriteQueue) write(batch []writeRequest) {
+	ctx := context.Background()
+	start := time.Now()
+
+	if q.batcher != nil {
+		traces := make([]*domain.Trace, len(batch))
+		for i, req := range batch {
+			traces[i] = req.trace
+		}
+
+		err := q.batcher.SaveBatch(ctx, traces)
+		if err == nil || len(batch) == 1 {
+			for _, req := range batch {
+				req.done <- err
+			}
+			q.metrics.RecordWriteBatch(len(batch), time.Since(start))
+			q.metrics.RecordWriteQueueDepth(len(q.queue))
+			return
+		}
+		// Fall through to saving traces one by one, so that a single bad
+		// trace does not fail the rest of the batch
+	}
+
+	for _, req := range batch {
+		req.done <- q.repo.Save(ctx, req.trace)
+	}
+	q.metrics.RecordWriteBatch(len(batch), time.Since(start))
+	q.metrics.RecordWriteQueueDepth(len(q.queue))
+}
@@ -0,0 +1,235 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamforge/distributed-tracing-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBatchRepository is a trace repository that saves batches
+type MockBatchRepository struct {
+	MockTraceRepository
+}
+
+func (m *MockBatchRepository) SaveBatch(ctx context.Context, traces []*domain.Trace) error {
+	args := m.Called(ctx, traces)
+	return args.Error(0)
+}
+
+func newQueueTestTrace(id string) *domain.Trace {
+	start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	return &domain.Trace{
+		ID:        domain.TraceID(id),
+		Service:   "api",
+		Operation: "GET /users",
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Duration:  time.Second,
+	}
+}
+
+func newQueueTestMetrics() *MockPrometheusExporter {
+	metrics := new(MockPrometheusExporter)
+	metrics.On("RecordWriteBatch", mock.Anything, mock.Anything).Maybe()
+	metrics.On("RecordWriteQueueDepth", mock.Anything).Maybe()
+	metrics.On("RecordWriteRejected").Maybe()
+	return metrics
+}
+
+// saveAll saves the traces concurrently and returns their errors in order
+func saveAll(queue domain.TraceWriteQueue, traces []*domain.Trace) []error {
+	errs := make([]error, len(traces))
+	var wg sync.WaitGroup
+	for i, trace := range traces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = queue.Save(context.Background(), trace)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestNewTraceWriteQueue_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config WriteQueueConfig
+	}{
+		{name: "zero capacity", config: WriteQueueConfig{BatchSize: 1}},
+		{name: "zero batch size", config: WriteQueueConfig{Capacity: 1}},
+		{name: "negative linger", config: WriteQueueConfig{Capacity: 1, BatchSize: 1, Linger: -time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTraceWriteQueue(new(MockTraceRepository), newQueueTestMetrics(), tt.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTraceWriteQueue_BatchesQueuedTraces(t *testing.T) {
+	// Arrange
+	repo := new(MockBatchRepository)
+	queue, err := NewTraceWriteQueue(repo, newQueueTestMetrics(), WriteQueueConfig{Capacity: 10, BatchSize: 3})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var batches [][]*domain.Trace
+	repo.On("SaveBatch", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, args.Get(1).([]*domain.Trace))
+	})
+
+	traces := make([]*domain.Trace, 5)
+	for i := range traces {
+		traces[i] = newQueueTestTrace(fmt.Sprintf("trace-%d", i))
+	}
+
+	// Act: queue everything before the writer starts
+	var errs []error
+	saved := make(chan struct{})
+	go func() {
+		errs = saveAll(queue, traces)
+		close(saved)
+	}()
+	require.Eventually(t, func() bool {
+		return len(queue.(*traceWriteQueue).queue) == len(traces)
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Start(ctx)
+	<-saved
+
+	// Assert
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 3)
+	assert.Len(t, batches[1], 2)
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestTraceWriteQueue_Backpressure(t *testing.T) {
+	// Arrange: without a running writer the queue fills up
+	repo := new(MockTraceRepository)
+	metrics := newQueueTestMetrics()
+	queue, err := NewTraceWriteQueue(repo, metrics, WriteQueueConfig{Capacity: 1, BatchSize: 1})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan error)
+	go func() {
+		waiting <- queue.Save(ctx, newQueueTestTrace("queued"))
+	}()
+	require.Eventually(t, func() bool {
+		return len(queue.(*traceWriteQueue).queue) == 1
+	}, time.Second, time.Millisecond)
+
+	// Act
+	err = queue.Save(context.Background(), newQueueTestTrace("refused"))
+
+	// Assert
+	assert.ErrorIs(t, err, domain.ErrBackpressure)
+	metrics.AssertCalled(t, "RecordWriteRejected")
+
+	// A cancelled caller stops waiting for its write
+	cancel()
+	assert.ErrorIs(t, <-waiting, context.Canceled)
+}
+
+func TestTraceWriteQueue_FallsBackToSingleSaves(t *testing.T) {
+	// Arrange
+	repo := new(MockBatchRepository)
+	queue, err := NewTraceWriteQueue(repo, newQueueTestMetrics(), WriteQueueConfig{Capacity: 10, BatchSize: 10})
+	require.NoError(t, err)
+
+	good, bad := newQueueTestTrace("good"), newQueueTestTrace("bad")
+	repo.On("SaveBatch", mock.Anything, mock.Anything).Return(errors.New("batch failed"))
+	repo.On("Save", mock.Anything, good).Return(nil)
+	repo.On("Save", mock.Anything, bad).Return(errors.New("invalid trace"))
+
+	var errs []error
+	saved := make(chan struct{})
+	go func() {
+		errs = saveAll(queue, []*domain.Trace{good, bad})
+		close(saved)
+	}()
+	require.Eventually(t, func() bool {
+		return len(queue.(*traceWriteQueue).queue) == 2
+	}, time.Second, time.Millisecond)
+
+	// Act
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Start(ctx)
+	<-saved
+
+	// Assert: only the bad trace fails
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "invalid trace")
+	repo.AssertNumberOfCalls(t, "SaveBatch", 1)
+}
+
+func TestTraceWriteQueue_WithoutBatchSaver(t *testing.T) {
+	// Arrange
+	repo := new(MockTraceRepository)
+	queue, err := NewTraceWriteQueue(repo, newQueueTestMetrics(), WriteQueueConfig{Capacity: 10, BatchSize: 10})
+	require.NoError(t, err)
+
+	repo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Start(ctx)
+
+	// Act
+	errs := saveAll(queue, []*domain.Trace{newQueueTestTrace("a"), newQueueTestTrace("b")})
+
+	// Assert
+	assert.Equal(t, []error{nil, nil}, errs)
+	repo.AssertNumberOfCalls(t, "Save", 2)
+}
+
+func TestTraceWriteQueue_DrainsOnShutdown(t *testing.T) {
+	// Arrange
+	repo := new(MockBatchRepository)
+	queue, err := NewTraceWriteQueue(repo, newQueueTestMetrics(), WriteQueueConfig{Capacity: 10, BatchSize: 10})
+	require.NoError(t, err)
+
+	repo.On("SaveBatch", mock.Anything, mock.Anything).Return(nil)
+
+	saved := make(chan error)
+	go func() {
+		saved <- queue.Save(context.Background(), newQueueTestTrace("pending"))
+	}()
+	require.Eventually(t, func() bool {
+		return len(queue.(*traceWriteQueue).queue) == 1
+	}, time.Second, time.Millisecond)
+
+	// Act: start with a cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, queue.Start(ctx))
+
+	// Assert
+	assert.NoError(t, <-saved)
+	repo.AssertNumberOfCalls(t, "SaveBatch", 1)
+
+	err = queue.Save(context.Background(), newQueueTestTrace("late"))
+	assert.ErrorIs(t, err, errWriteQueueStopped)
+}